@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/batch"
+	"sigs.k8s.io/hydrophone/pkg/hydrophone"
+	"sigs.k8s.io/hydrophone/pkg/log"
+)
+
+var (
+	batchOutputDir   string
+	batchConcurrency int
+)
+
+// batchLogFile is the file of the output directory of a run the output of
+// hydrophone is written to
+const batchLogFile = "hydrophone.log"
+
+var batchCmd = &cobra.Command{
+	Use:   "batch CONFIG...",
+	Short: "Run several configurations and summarize their results.",
+	Long: `Run several configurations and summarize their results.
+
+Each configuration is a hydrophone.yaml, as passed with --config, and is run
+by a separate hydrophone process. The results of a run are written to a
+directory of the output directory named after the configuration file, along
+with hydrophone.log holding the output of hydrophone. The configurations are
+run one after another unless --concurrency is given, in which case they have
+to target different clusters or namespaces. batch-summary.json records the
+exit code and duration of each run. The exit code is 1 if any run failed.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runs, err := batch.Load(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if batchConcurrency > 1 {
+			if err := batch.CheckConflicts(runs); err != nil {
+				log.Fatal(err)
+			}
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.MkdirAll(batchOutputDir, 0755); err != nil {
+			log.Fatalf("error creating output directory [%s] : %v", batchOutputDir, err)
+		}
+
+		summary := batch.Execute(runs, batchConcurrency, func(run batch.Run) batch.Result {
+			return runConfig(executable, run, batchConcurrency <= 1)
+		})
+		for _, result := range summary.Results {
+			switch {
+			case result.Error != "":
+				log.Printf("%s: not run: %s", result.Name, result.Error)
+			case result.ExitCode != 0:
+				log.Printf("%s: failed with exit code %d in %s, results in %s", result.Name, result.ExitCode, result.Duration.Round(time.Second), result.OutputDir)
+			default:
+				log.Printf("%s: passed in %s, results in %s", result.Name, result.Duration.Round(time.Second), result.OutputDir)
+			}
+		}
+		log.Printf("%d of %d configurations passed", summary.Passed, len(summary.Results))
+		if err := batch.WriteSummary(batchOutputDir, summary); err != nil {
+			log.Fatal(err)
+		}
+		if summary.Failed != 0 {
+			log.Exit(1)
+		}
+	},
+}
+
+// runConfig runs hydrophone with the configuration of the run and the output
+// directory of the run. The output of hydrophone is written to its log file,
+// and to stdout when stream is set.
+func runConfig(executable string, run batch.Run, stream bool) batch.Result {
+	result := batch.Result{Run: run, OutputDir: filepath.Join(batchOutputDir, run.Name)}
+	if err := os.MkdirAll(result.OutputDir, 0755); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	logFile, err := os.Create(filepath.Join(result.OutputDir, batchLogFile))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer logFile.Close()
+
+	var out io.Writer = logFile
+	if stream {
+		out = io.MultiWriter(logFile, os.Stdout)
+	}
+	log.Printf("%s: running %s", run.Name, run.Config)
+	start := time.Now()
+	res, err := hydrophone.New(
+		hydrophone.WithExecutable(executable),
+		hydrophone.WithOutputDir(result.OutputDir),
+		hydrophone.WithArgs("--config", run.Config),
+		hydrophone.WithOutput(out),
+	).Run(context.Background())
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.ExitCode = res.ExitCode
+	return result
+}
+
+func init() {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	batchCmd.Flags().StringVar(&batchOutputDir, "output-dir", workingDir, "directory the results of the runs and the batch summary are written to.")
+	batchCmd.Flags().IntVar(&batchConcurrency, "concurrency", 1, "number of configurations run at the same time.")
+
+	rootCmd.AddCommand(batchCmd)
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/batch"
+	"sigs.k8s.io/hydrophone/pkg/clusters"
+	"sigs.k8s.io/hydrophone/pkg/hydrophone"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+var (
+	clustersContexts    []string
+	clustersFile        string
+	clustersOutputDir   string
+	clustersConcurrency int
+)
+
+var clustersCmd = &cobra.Command{
+	Use:   "clusters [flags] [-- HYDROPHONE FLAGS]",
+	Short: "Run the tests against several clusters and compare their results.",
+	Long: `Run the tests against several clusters and compare their results.
+
+The clusters are the contexts of --context, of the kubeconfig of --kubeconfig
+or the default one, or the ones listed in --clusters-file. The tests selected
+with --focus, --skip or the hydrophone flags following -- are run against each
+cluster by a separate hydrophone process, all clusters at the same time unless
+--concurrency is given. The output of each run is prefixed with the name of its
+cluster, and its results are written to a directory of the output directory
+named after the cluster, along with hydrophone.log holding the output of
+hydrophone. clusters-report.json records the exit code and duration of each
+run and the tests whose outcome differs between the clusters. The exit code
+is 1 if any run failed.`,
+	Example: `  hydrophone clusters --context prod-eu,prod-us -- --conformance
+  hydrophone clusters --clusters-file clusters.yaml --focus 'Simple pod should contain last line of the log'`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var targets []clusters.Cluster
+		if clustersFile != "" {
+			loaded, err := clusters.Load(clustersFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			targets = loaded
+		} else {
+			var path string
+			if rootCmd.PersistentFlags().Changed("kubeconfig") {
+				path = settings.GetString("kubeconfig")
+			}
+			targets = clusters.FromContexts(path, clustersContexts)
+		}
+		if err := clusters.Validate(targets); err != nil {
+			log.Fatal(err)
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.MkdirAll(clustersOutputDir, 0755); err != nil {
+			log.Fatalf("error creating output directory [%s] : %v", clustersOutputDir, err)
+		}
+		// the kubeconfigs hold credentials, they are kept out of the output directory
+		kubeconfigDir, err := os.MkdirTemp("", "hydrophone-clusters")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer os.RemoveAll(kubeconfigDir)
+
+		runs := make([]batch.Run, len(targets))
+		for i, target := range targets {
+			runs[i] = batch.Run{Name: target.Name, Kubeconfig: filepath.Join(kubeconfigDir, target.Name)}
+			if err := clusters.WriteKubeconfig(target, runs[i].Kubeconfig); err != nil {
+				os.RemoveAll(kubeconfigDir)
+				log.Fatal(err)
+			}
+		}
+		concurrency := clustersConcurrency
+		if concurrency < 1 {
+			concurrency = len(runs)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		var mu sync.Mutex
+		summary := batch.Execute(runs, concurrency, func(run batch.Run) batch.Result {
+			return runCluster(ctx, executable, run, &mu, args)
+		})
+
+		report := clusters.Compare(summary)
+		for _, result := range report.Results {
+			switch {
+			case result.Error != "":
+				log.Printf("%s: not run: %s", result.Name, result.Error)
+			case result.ExitCode != 0:
+				log.Printf("%s: failed with exit code %d in %s, results in %s", result.Name, result.ExitCode, result.Duration.Round(time.Second), result.OutputDir)
+			default:
+				log.Printf("%s: passed in %s, results in %s", result.Name, result.Duration.Round(time.Second), result.OutputDir)
+			}
+		}
+		for _, difference := range report.Differences {
+			log.Printf("%s: %s", difference.Name, formatStatus(difference.Status, runs))
+		}
+		log.Printf("%d of %d clusters passed, %d tests differ between the clusters", report.Passed, len(report.Results), len(report.Differences))
+		if err := clusters.WriteReport(clustersOutputDir, report); err != nil {
+			log.Fatal(err)
+		}
+		if report.Failed != 0 {
+			os.RemoveAll(kubeconfigDir)
+			log.Exit(1)
+		}
+	},
+}
+
+// runCluster runs hydrophone against the cluster of the run. The output of
+// hydrophone is written to its log file, and to stdout prefixed with the name
+// of the cluster.
+func runCluster(ctx context.Context, executable string, run batch.Run, mu *sync.Mutex, args []string) batch.Result {
+	result := batch.Result{Run: run, OutputDir: filepath.Join(clustersOutputDir, run.Name)}
+	if err := os.MkdirAll(result.OutputDir, 0755); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	logFile, err := os.Create(filepath.Join(result.OutputDir, batchLogFile))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer logFile.Close()
+
+	out := clusters.NewPrefixWriter(os.Stdout, mu, fmt.Sprintf("[%s] ", run.Name))
+	defer out.Flush()
+	start := time.Now()
+	res, err := hydrophone.New(
+		hydrophone.WithExecutable(executable),
+		hydrophone.WithKubeconfig(run.Kubeconfig),
+		hydrophone.WithOutputDir(result.OutputDir),
+		hydrophone.WithFocus(settings.GetString("focus")),
+		hydrophone.WithSkip(settings.GetString("skip")),
+		hydrophone.WithConformanceImage(settings.GetString("conformance-image")),
+		hydrophone.WithArgs(args...),
+		hydrophone.WithOutput(io.MultiWriter(logFile, out)),
+	).Run(ctx)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		if res != nil {
+			result.ExitCode = res.ExitCode
+		}
+		return result
+	}
+	result.ExitCode = res.ExitCode
+	return result
+}
+
+// formatStatus returns the outcome of a test on each cluster, in the order of
+// the clusters
+func formatStatus(status map[string]string, runs []batch.Run) string {
+	var outcomes []string
+	for _, run := range runs {
+		outcome, ok := status[run.Name]
+		if !ok {
+			outcome = "missing"
+		}
+		outcomes = append(outcomes, run.Name+"="+outcome)
+	}
+	return strings.Join(outcomes, " ")
+}
+
+func init() {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	clustersCmd.Flags().StringSliceVar(&clustersContexts, "context", nil, "contexts of the kubeconfig to run the tests against, e.g. prod-eu,prod-us.")
+	clustersCmd.Flags().StringVar(&clustersFile, "clusters-file", "", "yaml file listing the clusters to run the tests against by name, kubeconfig and context.")
+	clustersCmd.Flags().StringVar(&clustersOutputDir, "output-dir", workingDir, "directory the results of the runs and the clusters report are written to.")
+	clustersCmd.Flags().IntVar(&clustersConcurrency, "concurrency", 0, "number of clusters the tests run against at the same time. 0 runs against all clusters at the same time.")
+	clustersCmd.MarkFlagsMutuallyExclusive("context", "clusters-file")
+	clustersCmd.MarkFlagsOneRequired("context", "clusters-file")
+
+	rootCmd.AddCommand(clustersCmd)
+}
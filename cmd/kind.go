@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/hydrophone"
+	"sigs.k8s.io/hydrophone/pkg/kind"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+var (
+	kindName              string
+	kindWorkers           int
+	kindKubernetesVersion string
+	kindNodeImage         string
+	kindWait              time.Duration
+	kindKeepCluster       bool
+	kindOutputDir         string
+)
+
+var kindCmd = &cobra.Command{
+	Use:   "kind [flags] [-- HYDROPHONE FLAGS]",
+	Short: "Run the tests against a throwaway kind cluster.",
+	Long: `Run the tests against a throwaway kind cluster.
+
+A kind cluster with a control plane node and --workers worker nodes is
+created with the kind binary found in PATH, the tests selected with --focus,
+--skip or the hydrophone flags following -- are run against it by a separate
+hydrophone process, and the cluster is deleted once the results are
+collected, unless --keep-cluster is given. The node image is the default one
+of kind unless --kubernetes-version or --node-image is given, e.g. a node
+image built from a Kubernetes patch with kind build node-image. The exit code
+is the one of the run.`,
+	Example: `  hydrophone kind --kubernetes-version v1.29.1 --focus 'Simple pod should contain last line of the log'
+  hydrophone kind --node-image kindest/node:latest -- --conformance --parallel 4`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster := &kind.Cluster{
+			Name:    kindName,
+			Workers: kindWorkers,
+			Image:   kindNodeImage,
+			Wait:    kindWait,
+			Output:  os.Stdout,
+		}
+		if kindKubernetesVersion != "" {
+			cluster.Image = kind.NodeImage(kindKubernetesVersion)
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			log.Fatal(err)
+		}
+		dir, err := os.MkdirTemp("", "hydrophone-kind")
+		if err != nil {
+			log.Fatal(err)
+		}
+		kubeconfig := filepath.Join(dir, "kubeconfig")
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		log.Printf("Creating kind cluster %s with %d worker nodes", cluster.Name, cluster.Workers)
+		createErr := cluster.Create(ctx, kubeconfig)
+		exitCode := 1
+		if createErr == nil {
+			exitCode = runKind(ctx, executable, kubeconfig, args)
+		}
+		if kindKeepCluster && createErr == nil {
+			log.Printf("Keeping kind cluster %s, delete it with kind delete cluster --name %s", cluster.Name, cluster.Name)
+		} else {
+			log.Printf("Deleting kind cluster %s", cluster.Name)
+			if err := cluster.Delete(context.Background()); err != nil {
+				log.Fatal(err)
+			}
+		}
+		os.RemoveAll(dir)
+		if createErr != nil {
+			log.Fatal(createErr)
+		}
+		log.Exit(exitCode)
+	},
+}
+
+// runKind runs hydrophone against the kind cluster of the kubeconfig and
+// returns its exit code
+func runKind(ctx context.Context, executable, kubeconfig string, args []string) int {
+	res, err := hydrophone.New(
+		hydrophone.WithExecutable(executable),
+		hydrophone.WithKubeconfig(kubeconfig),
+		hydrophone.WithOutputDir(kindOutputDir),
+		hydrophone.WithFocus(settings.GetString("focus")),
+		hydrophone.WithSkip(settings.GetString("skip")),
+		hydrophone.WithConformanceImage(settings.GetString("conformance-image")),
+		hydrophone.WithArgs(args...),
+		hydrophone.WithOutput(os.Stdout),
+	).Run(ctx)
+	if err != nil {
+		log.Printf("run against the kind cluster failed: %v", err)
+		return 1
+	}
+	log.Printf("Results of the run against the kind cluster are in %s", res.OutputDir)
+	return res.ExitCode
+}
+
+func init() {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	kindCmd.Flags().StringVar(&kindName, "name", "hydrophone", "name of the kind cluster.")
+	kindCmd.Flags().IntVar(&kindWorkers, "workers", 2, "number of worker nodes of the kind cluster, next to its control plane node.")
+	kindCmd.Flags().StringVar(&kindKubernetesVersion, "kubernetes-version", "", "Kubernetes version of the kind cluster, e.g. v1.29.1, run with the kindest/node image of the version.")
+	kindCmd.Flags().StringVar(&kindNodeImage, "node-image", "", "node image of the kind cluster, e.g. one built with kind build node-image.")
+	common.DurationVar(kindCmd.Flags(), &kindWait, "wait", 5*time.Minute, "time to wait for the control plane of the kind cluster to be ready.")
+	kindCmd.Flags().BoolVar(&kindKeepCluster, "keep-cluster", false, "keep the kind cluster after the run, e.g. to investigate failures.")
+	kindCmd.Flags().StringVar(&kindOutputDir, "output-dir", workingDir, "directory the results of the run are written to.")
+	kindCmd.MarkFlagsMutuallyExclusive("kubernetes-version", "node-image")
+
+	rootCmd.AddCommand(kindCmd)
+}
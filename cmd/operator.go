@@ -0,0 +1,326 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/hydrophone"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/operator"
+	"sigs.k8s.io/hydrophone/pkg/operator/controlpb"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+var (
+	operatorArtifactsDir   string
+	operatorMaxRuns        int
+	operatorInstallCRD     bool
+	operatorInstallRoles   bool
+	operatorWatchNamespace string
+	operatorGRPCAddress    string
+	operatorGRPCCertFile   string
+	operatorGRPCKeyFile    string
+
+	operatorGRPCTokenAuthFile  string
+	operatorGRPCTokenReview    bool
+	operatorGRPCTokenWebhook   string
+	operatorGRPCTokenAudiences []string
+	operatorGRPCPolicyFile     string
+)
+
+var operatorCmd = &cobra.Command{
+	Use:   "operator",
+	Short: "Run conformance runs declared as ConformanceRun resources.",
+	Long: `Run conformance runs declared as ConformanceRun resources.
+
+The operator installs the ConformanceRun CustomResourceDefinition
+(conformanceruns.hydrophone.k8s.io) and reconciles the ConformanceRuns of the
+cluster. Each ConformanceRun is run by a separate hydrophone process in its
+own namespace, hydrophone-<namespace>-<name>, and its artifacts are written to
+<artifacts-dir>/<namespace>/<name> along with hydrophone.log holding the
+output of hydrophone. The status of the ConformanceRun reports the phase,
+the progress of the specs, the exit code and the location of the artifacts.
+Deleting a ConformanceRun in progress aborts the run and deletes its
+resources. At most --max-runs runs are in progress at the same time, the
+others wait in the Pending phase. --grpc-address serves the RunControl gRPC
+service starting, watching and canceling ConformanceRuns and returning the
+location of their artifacts. Its callers pass a bearer token, authenticated
+with the tokens of --grpc-token-auth-file, by a TokenReview of the API server
+with --grpc-token-review or by the webhook of --grpc-token-webhook-config-file.
+Their identity is authorized to start, cancel and fetch runs by the rules of
+--grpc-policy-file or else by a SubjectAccessReview of the API server.
+
+The requests starting, canceling and fetching runs are authenticated and
+authorized by the API server. The operator installs the cluster roles
+hydrophone-conformancerun-runner and hydrophone-conformancerun-viewer to be
+bound to the users, groups and service accounts allowed to.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, clientSet := service.Init(settings.GetString("kubeconfig"))
+		dynamicClient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			log.Fatal(err)
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			log.Fatal(err)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if operatorInstallCRD {
+			if err := operator.InstallCRD(ctx, dynamicClient); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if operatorInstallRoles {
+			if err := operator.InstallRoles(ctx, dynamicClient); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if operatorGRPCAddress != "" {
+			stopControl, err := serveControl(dynamicClient, clientSet)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer stopControl()
+		}
+		runner := &execRunner{executable: executable, artifactsDir: operatorArtifactsDir}
+		if rootCmd.PersistentFlags().Changed("kubeconfig") {
+			runner.kubeconfig = settings.GetString("kubeconfig")
+		}
+		runner.kubeContext = settings.GetString("context")
+		runner.clientArgs = clientArgs()
+		controller := &operator.Controller{
+			Client:    dynamicClient,
+			Runner:    runner,
+			Namespace: operatorWatchNamespace,
+			MaxRuns:   operatorMaxRuns,
+		}
+		log.Printf("Reconciling ConformanceRuns, artifacts are written to %s", operatorArtifactsDir)
+		if err := controller.Run(ctx); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// execRunner runs a ConformanceRun with a hydrophone process
+type execRunner struct {
+	executable   string
+	artifactsDir string
+	kubeconfig   string
+	kubeContext  string
+	// clientArgs select the cluster, the proxy, the TLS settings and the
+	// user of the runs
+	clientArgs []string
+}
+
+// Run runs hydrophone with the flags of the spec of the run. The output of
+// hydrophone is written to its log file. Only the fields of the spec are
+// passed, so that a ConformanceRun can't make the operator run arbitrary
+// flags.
+func (r *execRunner) Run(ctx context.Context, run *operator.ConformanceRun, namespace string, progress func(operator.Progress)) (operator.Result, error) {
+	result := operator.Result{Artifacts: filepath.Join(r.artifactsDir, run.Namespace, run.Name)}
+	spec := run.Spec
+	opts := []hydrophone.Option{
+		hydrophone.WithExecutable(r.executable),
+		hydrophone.WithKubeconfig(r.kubeconfig),
+		hydrophone.WithContext(r.kubeContext),
+		hydrophone.WithNamespace(namespace),
+		hydrophone.WithOutputDir(result.Artifacts),
+		hydrophone.WithFocus(spec.Focus),
+		hydrophone.WithSkip(spec.Skip),
+		hydrophone.WithConformanceImage(spec.ConformanceImage),
+		hydrophone.WithParallel(spec.Parallel),
+		hydrophone.WithUpload(spec.Upload),
+		hydrophone.WithArgs("--record-history=false"),
+		hydrophone.WithProgress(func(p hydrophone.Progress) {
+			progress(operator.Progress(p))
+		}),
+	}
+	opts = append(opts, hydrophone.WithArgs(r.clientArgs...))
+	// the cluster-scoped resources of concurrent runs are kept apart by the
+	// ID of the run, the first group of the UID of the ConformanceRun
+	if id, _, _ := strings.Cut(string(run.UID), "-"); id != "" {
+		opts = append(opts, hydrophone.WithArgs("--run-id", id))
+	}
+	if spec.Conformance {
+		opts = append(opts, hydrophone.WithConformance())
+	}
+	if spec.Timeout != "" {
+		timeout, err := common.ParseDuration(spec.Timeout)
+		if err != nil {
+			return result, fmt.Errorf("invalid timeout: %w", err)
+		}
+		opts = append(opts, hydrophone.WithTimeout(timeout))
+	}
+
+	if err := os.MkdirAll(result.Artifacts, 0755); err != nil {
+		return result, err
+	}
+	logFile, err := os.Create(filepath.Join(result.Artifacts, batchLogFile))
+	if err != nil {
+		return result, err
+	}
+	defer logFile.Close()
+
+	res, err := hydrophone.New(append(opts, hydrophone.WithOutput(logFile))...).Run(ctx)
+	if res != nil {
+		result.ExitCode = res.ExitCode
+	}
+	return result, err
+}
+
+// serveControl serves the RunControl gRPC service on --grpc-address, over TLS
+// when a certificate is given, until the returned function is called
+func serveControl(client dynamic.Interface, clientSet kubernetes.Interface) (func(), error) {
+	control, err := newControlServer(client, clientSet)
+	if err != nil {
+		return nil, err
+	}
+	var opts []grpc.ServerOption
+	if operatorGRPCCertFile != "" || operatorGRPCKeyFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(operatorGRPCCertFile, operatorGRPCKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the certificate of the gRPC service: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	} else {
+		log.Println("WARNING: the gRPC service is served without TLS, the bearer tokens of its callers are sent in clear text")
+	}
+	listener, err := net.Listen("tcp", operatorGRPCAddress)
+	if err != nil {
+		return nil, err
+	}
+	server := grpc.NewServer(opts...)
+	controlpb.RegisterRunControlServer(server, control)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			log.Printf("the gRPC service stopped: %v", err)
+		}
+	}()
+	log.Printf("Serving the RunControl gRPC service on %s", listener.Addr())
+	return server.Stop, nil
+}
+
+// newControlServer returns the RunControl service authenticating its callers
+// with the tokens of --grpc-token-auth-file, --grpc-token-review and
+// --grpc-token-webhook-config-file, and authorizing them with the rules of
+// --grpc-policy-file or else with SubjectAccessReviews
+func newControlServer(client dynamic.Interface, clientSet kubernetes.Interface) (*operator.ControlServer, error) {
+	var authenticators operator.Authenticators
+	if operatorGRPCTokenAuthFile != "" {
+		tokens, err := operator.LoadStaticTokens(operatorGRPCTokenAuthFile)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, tokens)
+	}
+	if operatorGRPCTokenReview {
+		authenticators = append(authenticators, operator.NewTokenReview(clientSet, operatorGRPCTokenAudiences))
+	}
+	if operatorGRPCTokenWebhook != "" {
+		webhook, err := operator.NewTokenReviewWebhook(operatorGRPCTokenWebhook, operatorGRPCTokenAudiences)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, webhook)
+	}
+	if len(authenticators) == 0 {
+		return nil, fmt.Errorf("--grpc-address requires --grpc-token-auth-file, --grpc-token-review or --grpc-token-webhook-config-file to authenticate the callers of the gRPC service")
+	}
+
+	var authorizer operator.Authorizer = &operator.SubjectAccessReview{Client: clientSet}
+	if operatorGRPCPolicyFile != "" {
+		policy, err := operator.LoadPolicy(operatorGRPCPolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		authorizer = policy
+	}
+	return &operator.ControlServer{Client: client, Authenticator: authenticators, Authorizer: authorizer}, nil
+}
+
+// clientArgs returns the flags of the cluster, the proxy, the TLS settings
+// and the user to impersonate the operator was started with, passed on to the
+// hydrophone processes
+func clientArgs() []string {
+	var args []string
+	if cluster := settings.GetString("cluster"); cluster != "" {
+		args = append(args, "--cluster", cluster)
+	}
+	if proxyURL := settings.GetString("proxy-url"); proxyURL != "" {
+		args = append(args, "--proxy-url", proxyURL)
+	}
+	if caFile := settings.GetString("certificate-authority"); caFile != "" {
+		args = append(args, "--certificate-authority", caFile)
+	}
+	if serverName := settings.GetString("tls-server-name"); serverName != "" {
+		args = append(args, "--tls-server-name", serverName)
+	}
+	if settings.GetBool("insecure-skip-tls-verify") {
+		args = append(args, "--insecure-skip-tls-verify")
+	}
+	if user := settings.GetString("as"); user != "" {
+		args = append(args, "--as", user)
+		for _, group := range settings.GetStringSlice("as-group") {
+			args = append(args, "--as-group", group)
+		}
+		if uid := settings.GetString("as-uid"); uid != "" {
+			args = append(args, "--as-uid", uid)
+		}
+	}
+	return args
+}
+
+func init() {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	operatorCmd.Flags().StringVar(&operatorArtifactsDir, "artifacts-dir", workingDir, "directory the artifacts of the runs are written to, in <namespace>/<name> subdirectories.")
+	operatorCmd.Flags().IntVar(&operatorMaxRuns, "max-runs", 1, "number of ConformanceRuns in progress at the same time. runs against the same cluster share its nodes.")
+	operatorCmd.Flags().BoolVar(&operatorInstallCRD, "install-crd", true, "create or update the ConformanceRun CustomResourceDefinition at startup.")
+	operatorCmd.Flags().BoolVar(&operatorInstallRoles, "install-roles", true, "create or update the cluster roles hydrophone-conformancerun-runner, allowed to start, cancel and fetch ConformanceRuns, and hydrophone-conformancerun-viewer, allowed to fetch them, at startup.")
+	operatorCmd.Flags().StringVar(&operatorGRPCAddress, "grpc-address", "", "address the RunControl gRPC service starting, watching and canceling ConformanceRuns is served on, e.g. :9443. not served when empty.")
+	operatorCmd.Flags().StringVar(&operatorGRPCCertFile, "grpc-tls-cert-file", "", "certificate the gRPC service is served with over TLS, along with --grpc-tls-key-file.")
+	operatorCmd.Flags().StringVar(&operatorGRPCKeyFile, "grpc-tls-key-file", "", "private key of the certificate of --grpc-tls-cert-file.")
+	operatorCmd.Flags().StringVar(&operatorGRPCTokenAuthFile, "grpc-token-auth-file", "", "CSV file of the bearer tokens accepted by the gRPC service, one token,user,uid,\"group1,group2\" per line.")
+	operatorCmd.Flags().BoolVar(&operatorGRPCTokenReview, "grpc-token-review", false, "authenticate the bearer tokens of the gRPC service with TokenReviews of the API server, e.g. service account tokens.")
+	operatorCmd.Flags().StringVar(&operatorGRPCTokenWebhook, "grpc-token-webhook-config-file", "", "kubeconfig file of the webhook the bearer tokens of the gRPC service are sent to in TokenReviews.")
+	operatorCmd.Flags().StringSliceVar(&operatorGRPCTokenAudiences, "grpc-token-audience", nil, "audiences the bearer tokens reviewed with --grpc-token-review or --grpc-token-webhook-config-file are expected to be issued for.")
+	operatorCmd.Flags().StringVar(&operatorGRPCPolicyFile, "grpc-policy-file", "", "YAML file of the rules allowing users and groups to start, cancel and fetch runs through the gRPC service. SubjectAccessReviews of the API server on conformanceruns.hydrophone.k8s.io when empty.")
+	operatorCmd.Flags().StringVar(&operatorWatchNamespace, "watch-namespace", "", "namespace of the ConformanceRuns reconciled, all namespaces when empty.")
+
+	rootCmd.AddCommand(operatorCmd)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/operator"
+)
+
+func TestExecRunnerTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake hydrophone is a shell script")
+	}
+	tests := []struct {
+		name    string
+		timeout string
+		args    string
+		err     string
+	}{
+		{
+			name:    "hours",
+			timeout: "6h",
+			args:    "--timeout 6h0m0s",
+		},
+		{
+			name:    "days",
+			timeout: "1d",
+			args:    "--timeout 24h0m0s",
+		},
+		{
+			name:    "days and hours",
+			timeout: "1d12h",
+			args:    "--timeout 36h0m0s",
+		},
+		{
+			name:    "invalid",
+			timeout: "1w",
+			err:     `invalid timeout: invalid duration "1w"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// the fake hydrophone records its flags in its log file
+			executable := filepath.Join(dir, "hydrophone")
+			require.NoError(t, os.WriteFile(executable, []byte("#!/bin/sh\necho \"$@\"\n"), 0755))
+			runner := &execRunner{executable: executable, artifactsDir: dir}
+			run := &operator.ConformanceRun{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "conformance"},
+				Spec:       operator.Spec{Conformance: true, Timeout: tt.timeout},
+			}
+
+			result, err := runner.Run(context.Background(), run, "conformance", func(operator.Progress) {})
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 0, result.ExitCode)
+			output, err := os.ReadFile(filepath.Join(result.Artifacts, batchLogFile))
+			require.NoError(t, err)
+			assert.Contains(t, string(output), tt.args)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+#!/bin/bash
+
+# Copyright 2024 The Kubernetes Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# update-proto.sh regenerates the Go code of the protobuf services. It needs
+# protoc, protoc-gen-go v1.33.0 and protoc-gen-go-grpc v1.4.0 in PATH.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+KUBE_ROOT=$(dirname "${BASH_SOURCE}")/..
+cd "${KUBE_ROOT}"
+
+for proto in pkg/operator/controlpb/control.proto; do
+  protoc --go_out=. --go_opt=paths=source_relative \
+    --go-grpc_out=. --go-grpc_opt=paths=source_relative "${proto}"
+done
+
+# protoc copies the license of the proto files as line comments, replace it
+# with the header of the Go files
+for file in pkg/operator/controlpb/*.pb.go; do
+  { sed 's/YEAR/2024/' hack/boilerplate/boilerplate.go.txt; printf "\n\n"; sed '1,/^$/d' "${file}"; } > "${file}.tmp"
+  mv "${file}.tmp" "${file}"
+done
@@ -0,0 +1,152 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package batch runs a set of hydrophone configurations one after another or
+// with bounded parallelism and summarizes their outcome.
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// SummaryFile is the name of the file of the batch output directory holding the summary
+const SummaryFile = "batch-summary.json"
+
+// Run is a configuration of the batch
+type Run struct {
+	// Name identifies the run, its results are written to a directory of
+	// that name
+	Name string `json:"name"`
+	// Config is the path of the configuration file
+	Config string `json:"config"`
+	// Kubeconfig and Namespace are the cluster and the namespace the
+	// configuration targets, RunID the ID isolating its resources from the
+	// other runs on the cluster
+	Kubeconfig string `json:"-"`
+	Namespace  string `json:"-"`
+	RunID      string `json:"-"`
+}
+
+// Result is the outcome of a run of the batch
+type Result struct {
+	Run
+	OutputDir string        `json:"outputDir"`
+	ExitCode  int           `json:"exitCode"`
+	Duration  time.Duration `json:"duration"`
+	// Error is set when the run couldn't be started
+	Error string `json:"error,omitempty"`
+}
+
+// Summary is the outcome of all runs of the batch
+type Summary struct {
+	Results []Result `json:"results"`
+	Passed  int      `json:"passed"`
+	Failed  int      `json:"failed"`
+}
+
+// Load reads the cluster and namespace targeted by each configuration and
+// names the runs after their file, adding a suffix to names used more than once.
+func Load(paths []string) ([]Run, error) {
+	var runs []Run
+	used := map[string]int{}
+	for _, path := range paths {
+		v := viper.New()
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("error reading configuration %s: %w", path, err)
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		used[name]++
+		if used[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, used[name])
+		}
+		runs = append(runs, Run{
+			Name:       name,
+			Config:     path,
+			Kubeconfig: v.GetString("kubeconfig"),
+			Namespace:  v.GetString("namespace"),
+			RunID:      v.GetString("run-id"),
+		})
+	}
+	return runs, nil
+}
+
+// CheckConflicts fails when runs executed at the same time target the same
+// namespace of the same cluster with the same run ID, their resources would
+// collide.
+func CheckConflicts(runs []Run) error {
+	seen := map[[3]string]string{}
+	for _, run := range runs {
+		key := [3]string{run.Kubeconfig, run.Namespace, run.RunID}
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("configurations %s and %s target the same cluster and namespace, they can't run in parallel", other, run.Config)
+		}
+		seen[key] = run.Config
+	}
+	return nil
+}
+
+// Execute runs the configurations with at most concurrency of them at the
+// same time and returns their results in the order of the runs.
+func Execute(runs []Run, concurrency int, execute func(Run) Result) *Summary {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]Result, len(runs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, run := range runs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, run Run) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = execute(run)
+		}(i, run)
+	}
+	wg.Wait()
+
+	summary := &Summary{Results: results}
+	for _, result := range results {
+		if result.ExitCode == 0 && result.Error == "" {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+// WriteSummary writes the summary as indented JSON to the output directory.
+func WriteSummary(outputDir string, summary *Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding batch summary: %w", err)
+	}
+	path := filepath.Join(outputDir, SummaryFile)
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
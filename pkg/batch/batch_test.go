@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "staging"), 0755))
+	files := map[string]string{
+		"prod.yaml":         "kubeconfig: /kube/prod\nfocus: '\\[Conformance\\]'\n",
+		"staging/prod.yaml": "kubeconfig: /kube/staging\nnamespace: e2e\n",
+	}
+	var paths []string
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	paths = append(paths, filepath.Join(dir, "prod.yaml"), filepath.Join(dir, "staging/prod.yaml"))
+
+	runs, err := Load(paths)
+	require.NoError(t, err)
+	assert.Equal(t, []Run{
+		{Name: "prod", Config: paths[0], Kubeconfig: "/kube/prod"},
+		{Name: "prod-2", Config: paths[1], Kubeconfig: "/kube/staging", Namespace: "e2e"},
+	}, runs)
+	assert.NoError(t, CheckConflicts(runs))
+
+	runs[1].Kubeconfig, runs[1].Namespace = "/kube/prod", ""
+	assert.Error(t, CheckConflicts(runs))
+
+	runs[1].RunID = "a1b2c3d4"
+	assert.NoError(t, CheckConflicts(runs))
+
+	_, err = Load([]string{filepath.Join(dir, "missing.yaml")})
+	assert.Error(t, err)
+}
+
+func TestExecute(t *testing.T) {
+	runs := []Run{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+
+	var running, peak atomic.Int32
+	summary := Execute(runs, 2, func(run Run) Result {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		result := Result{Run: run}
+		switch run.Name {
+		case "b":
+			result.ExitCode = 1
+		case "c":
+			result.Error = "unable to start"
+		}
+		return result
+	})
+
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+	require.Len(t, summary.Results, 4)
+	for i, result := range summary.Results {
+		assert.Equal(t, runs[i].Name, result.Name)
+	}
+	assert.Equal(t, 2, summary.Passed)
+	assert.Equal(t, 2, summary.Failed)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/registry"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// validateAttest checks that the results can be attested before the tests run
+func validateAttest() error {
+	if !settings.GetBool("attest") {
+		return nil
+	}
+	if settings.GetString("compress") != common.CompressBundle {
+		return errors.New("--attest attests the results bundle and requires --compress=bundle")
+	}
+	if _, err := exec.LookPath("cosign"); err != nil {
+		return errors.New("--attest requires cosign in PATH")
+	}
+	return nil
+}
+
+// attestResults signs an attestation of the results bundle in the output
+// directory recording the metadata of the run, the digest of the conformance
+// image and the arguments of the run.
+func attestResults(outputDir string) error {
+	metadata, err := results.ReadMetadata(outputDir)
+	if err != nil {
+		return err
+	}
+	image := settings.GetString("conformance-image")
+	digest := ""
+	if _, pinned, ok := strings.Cut(image, "@"); ok {
+		digest = pinned
+	} else if digest, err = registry.NewChecker().Digest(image); err != nil {
+		log.Printf("unable to resolve the digest of %s, it isn't recorded in the attestation: %v", image, err)
+	}
+	tmp, err := os.MkdirTemp("", "hydrophone-attest-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	predicate, err := results.WritePredicate(tmp, results.NewPredicate(metadata, digest, invocation))
+	if err != nil {
+		return err
+	}
+	bundle := filepath.Join(outputDir, results.AttestationFile)
+	blob := filepath.Join(outputDir, results.BundleFile)
+	if err := registry.AttestBlob(blob, predicate, results.PredicateType, settings.GetString("attest-key"), bundle); err != nil {
+		return err
+	}
+	statement, err := results.ReadStatement(bundle)
+	if err != nil {
+		return err
+	}
+	sum, err := statement.Check(blob)
+	if err != nil {
+		return fmt.Errorf("%s: %w", bundle, err)
+	}
+	log.Printf("attested the results of the run with digest sha256:%s in %s", sum, bundle)
+	return nil
+}
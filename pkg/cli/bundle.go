@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/cncf"
+	"sigs.k8s.io/hydrophone/pkg/log"
+)
+
+var (
+	bundleCNCF     bool
+	bundleTarget   string
+	cncfSubmission = cncf.Submission{}
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Assemble the results of a run for a submission.",
+	Long: `Assemble the results of a run for a submission.
+
+With --cncf the files of a CNCF Certified Kubernetes submission are written in
+the layout of the k8s-conformance repository, e.g. v1.30/my-product:
+
+  PRODUCT.yaml  pre-filled from the product flags
+  README.md     the steps reproducing the results with hydrophone
+  e2e.log       joined and decompressed when written with --max-log-size or --compress
+  junit_01.xml
+
+The submission is then checked for completeness: every field of PRODUCT.yaml
+is required and all conformance tests have to pass. The command fails listing
+the problems when it isn't complete, the files are written nonetheless so that
+they can be completed by hand.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, problems, err := cncfSubmission.Write(bundleTarget)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("wrote the submission to %s", dir)
+		if len(problems) > 0 {
+			for _, problem := range problems {
+				fmt.Fprintln(stdout, problem)
+			}
+			log.Fatalf("the submission is incomplete, %d problems found", len(problems))
+		}
+	},
+}
+
+func init() {
+	bundleCmd.Flags().BoolVar(&bundleCNCF, "cncf", false, "assemble a CNCF Certified Kubernetes submission.")
+	bundleCmd.MarkFlagRequired("cncf")
+	bundleCmd.Flags().StringVar(&cncfSubmission.ResultsDir, "results-dir", ".", "output directory of the run.")
+	bundleCmd.Flags().StringVar(&bundleTarget, "target", "k8s-conformance", "directory the submission is written to, e.g. a checkout of the k8s-conformance repository.")
+	bundleCmd.Flags().StringVar(&cncfSubmission.KubernetesVersion, "kubernetes-version", "", "Kubernetes version the product is certified for, e.g. v1.30. defaults to the server version of the run.")
+
+	product := &cncfSubmission.Product
+	bundleCmd.Flags().StringVar(&product.Vendor, "vendor", "", "name of the vendor of the product.")
+	bundleCmd.Flags().StringVar(&product.Name, "product", "", "name of the product.")
+	bundleCmd.Flags().StringVar(&product.Version, "product-version", "", "version of the product.")
+	bundleCmd.Flags().StringVar(&product.WebsiteURL, "website-url", "", "URL of the website of the product.")
+	bundleCmd.Flags().StringVar(&product.RepoURL, "repo-url", "", "URL of the source repository of the product, if it is open source.")
+	bundleCmd.Flags().StringVar(&product.DocumentationURL, "documentation-url", "", "URL of the documentation of the product.")
+	bundleCmd.Flags().StringVar(&product.ProductLogoURL, "product-logo-url", "", "URL of the logo of the product, preferably in SVG format.")
+	bundleCmd.Flags().StringVar(&product.Type, "type", "", "type of the product: distribution, hosted platform or installer.")
+	bundleCmd.Flags().StringVar(&product.Description, "description", "", "one sentence describing the product.")
+	bundleCmd.Flags().StringVar(&product.ContactEmailAddress, "contact-email", "", "email address the CNCF contacts about the certification.")
+	bundleCmd.MarkFlagRequired("product")
+
+	rootCmd.AddCommand(bundleCmd)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"errors"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// leakGracePeriod is the time the tests are given to delete their namespaces
+// before looking for the resources they left behind
+const leakGracePeriod = 2 * time.Minute
+
+// validateCleanupFlags checks that --deep and --list-only come with the
+// cleanup they change
+func validateCleanupFlags() error {
+	if (settings.GetBool("deep") || settings.GetBool("list-only")) && !cleanup {
+		return errors.New("--deep and --list-only require --cleanup")
+	}
+	if settings.GetBool("list-only") && !settings.GetBool("deep") {
+		return errors.New("--list-only requires --deep")
+	}
+	if settings.GetBool("delete-leaks") && !settings.GetBool("detect-leaks") {
+		return errors.New("--delete-leaks requires --detect-leaks")
+	}
+	return nil
+}
+
+// deepCleanup deletes the resources the e2e tests of aborted runs left
+// behind, or only lists them with --list-only.
+func deepCleanup(clientset kubernetes.Interface) error {
+	leaked, err := service.FindLeakedResources(clientset)
+	if err != nil {
+		return err
+	}
+	if leaked.Empty() {
+		log.Printf("no leaked resources found")
+		return nil
+	}
+	leaked.Print()
+	if settings.GetBool("list-only") {
+		return nil
+	}
+	return service.DeleteLeakedResources(clientset, leaked, settings.GetInt("cleanup-concurrency"))
+}
+
+// checkHygiene looks for the resources the tests of the run left behind once
+// they completed, records them in the metadata of the run and deletes them
+// with --delete-leaks. Leaks don't fail the run.
+func checkHygiene(clientset kubernetes.Interface) {
+	// the leaks are looked for at the cluster scope
+	if !settings.GetBool("detect-leaks") || common.Restricted() {
+		return
+	}
+	if terminating, err := service.WaitForTestNamespaces(clientset, leakGracePeriod); err != nil {
+		log.Printf("unable to look for the resources the tests left behind: %v", err)
+		return
+	} else if terminating != 0 {
+		log.Printf("%d test namespaces are still being deleted after %s", terminating, leakGracePeriod)
+	}
+	leaked, err := service.FindLeakedResources(clientset)
+	if err != nil {
+		log.Printf("unable to look for the resources the tests left behind: %v", err)
+		return
+	}
+	hygiene := &results.Hygiene{Leaked: leaked.ByKind()}
+	if leaked.Empty() {
+		log.Printf("Cluster hygiene: the tests left no resources behind")
+	} else {
+		log.Printf("Cluster hygiene: the tests left resources behind")
+		leaked.Print()
+		if settings.GetBool("delete-leaks") {
+			if err := service.DeleteLeakedResources(clientset, leaked, settings.GetInt("cleanup-concurrency")); err != nil {
+				log.Printf("unable to delete the resources the tests left behind: %v", err)
+			} else {
+				hygiene.Deleted = true
+			}
+		}
+	}
+
+	outputDir := settings.GetString("output-dir")
+	metadata, err := results.ReadMetadata(outputDir)
+	if err != nil {
+		log.Printf("unable to record the cluster hygiene of the run: %v", err)
+		return
+	}
+	metadata.Hygiene = hygiene
+	if err := results.WriteMetadata(outputDir, metadata); err != nil {
+		log.Printf("unable to record the cluster hygiene of the run: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+	"sigs.k8s.io/hydrophone/pkg/wizard"
+)
+
+var (
+	configInitOutput string
+	configInitForce  bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the configuration of hydrophone.",
+	Long: `Manage the configuration of hydrophone.
+
+Every flag can be set in a config file, hydrophone.yaml of the working
+directory or of the config directory, or the file of --config, with the name
+of the flag as key, and with a HYDROPHONE_ environment variable, e.g.
+HYDROPHONE_PARALLEL for --parallel. Flags take precedence over environment
+variables, which take precedence over the config file and the defaults.`,
+}
+
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Print the effective configuration.",
+	Long: `Print the effective configuration.
+
+The configuration is printed as a config file, with the values of the config
+file, the HYDROPHONE_ environment variables and the persistent flags merged
+with the defaults. Credentials of URLs are redacted.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if file := settings.ConfigFileUsed(); file != "" {
+			fmt.Fprintf(stdout, "# config file: %s\n", file)
+		}
+		if err := common.WriteConfig(stdout); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a config file by answering a few questions.",
+	Long: `Write a config file by answering a few questions.
+
+The context of the kubeconfig, the output directory, the tests to run and the
+mirror registry of an air-gapped cluster are asked, and the answers are
+written to --output, by default hydrophone.yaml of the working directory,
+which is read by the next runs.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := os.Stat(configInitOutput); err == nil && !configInitForce {
+			log.Fatalf("%s already exists, pass --force to overwrite it", configInitOutput)
+		}
+
+		var opts wizard.Options
+		raw, err := client.LoadingRules(settings.GetString("kubeconfig")).Load()
+		if err != nil {
+			log.Printf("WARNING: can't load the kubeconfig, the context is not asked: %v", err)
+		} else {
+			for name := range raw.Contexts {
+				opts.Contexts = append(opts.Contexts, name)
+			}
+			sort.Strings(opts.Contexts)
+			opts.CurrentContext = raw.CurrentContext
+		}
+
+		config, err := wizard.New(os.Stdin, stdout).Run(opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		out, err := yaml.Marshal(config)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(configInitOutput, out, 0644); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Configuration written to %s, run it with hydrophone --config %s", configInitOutput, configInitOutput)
+	},
+}
+
+func init() {
+	configInitCmd.Flags().StringVarP(&configInitOutput, "output", "o", "hydrophone.yaml", "file the configuration is written to.")
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "overwrite --output if it exists.")
+
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configViewCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+var diagnoseOutputDir string
+
+var diagnoseCmd = &cobra.Command{
+	Use:   "diagnose",
+	Short: "Collect the diagnostics of the run into the output directory.",
+	Long: `Collect the diagnostics of the run into the output directory.
+
+The description and the events of the pods of the namespace of the run, the
+events of the namespace, the conditions of the nodes, and the events of the
+cluster reported by the scheduler and the kubelets are written to the
+diagnostics directory of the output directory. Failed and aborted runs collect
+them before their resources are deleted unless --diagnostics=false is set, use
+this command to collect them while a run is in progress or was kept with
+--on-interrupt=keep.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, clientSet := service.Init(settings.GetString("kubeconfig"))
+		common.SetDefaultNamespace()
+		dir, err := collectDiagnostics(clientSet, diagnoseOutputDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Diagnostics written to %s", dir)
+	},
+}
+
+// collectDiagnostics writes the diagnostics of the run and the log of
+// hydrophone to the diagnostics directory of the output directory, and
+// returns the directory.
+func collectDiagnostics(clientSet kubernetes.Interface, outputDir string) (string, error) {
+	dir := filepath.Join(outputDir, service.DiagnosticsDir)
+	err := service.CollectDiagnostics(clientSet, dir)
+	if _, statErr := os.Stat(dir); statErr != nil {
+		return dir, err
+	}
+	return dir, errors.Join(err, os.WriteFile(filepath.Join(dir, service.DiagnosticsLogFile), log.History(), 0644))
+}
+
+// diagnoseFailedRun collects the diagnostics of a failed run unless
+// --diagnostics is disabled. Failing to collect them doesn't fail the run.
+func diagnoseFailedRun(clientSet kubernetes.Interface) {
+	if !settings.GetBool("diagnostics") {
+		return
+	}
+	dir, err := collectDiagnostics(clientSet, settings.GetString("output-dir"))
+	if err != nil {
+		log.Printf("unable to collect all the diagnostics of the run: %v", err)
+	}
+	log.Printf("Diagnostics of the failed run written to %s", dir)
+}
+
+func init() {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	diagnoseCmd.Flags().StringVar(&diagnoseOutputDir, "output-dir", workingDir, "output directory the diagnostics directory is written to.")
+
+	rootCmd.AddCommand(diagnoseCmd)
+}
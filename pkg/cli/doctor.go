@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the environment hydrophone runs in.",
+	Long: `Diagnose the environment hydrophone runs in.
+
+Checks that the kubeconfig can be loaded, that the API server can be reached,
+that the user of the kubeconfig has the permissions hydrophone needs, that the
+clock of this machine agrees with the one of the API server, that the
+registries of the conformance and busybox images can be reached and have them,
+and that the output directory is writable. A remedy is printed for each
+problem found, and the command fails when there is one. Unlike preflight it
+doesn't check the readiness of the cluster for a run.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		common.SetDefaultNamespace()
+		checks := service.Doctor(settings.GetString("kubeconfig"))
+		if err := service.WriteDoctorChecks(stdout, checks); err != nil {
+			log.Fatal(err)
+		}
+		for _, check := range checks {
+			if check.Failed() {
+				log.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
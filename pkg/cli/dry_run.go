@@ -0,0 +1,141 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// renderManifests prints the resources a run would create and writes them to
+// the output directory, without connecting to the cluster, so that they can
+// be reviewed before granting hydrophone access to it.
+func renderManifests() error {
+	common.SetDefaultImages("")
+	if settings.GetString("conformance-image") == "" {
+		return errors.New("--dry-run doesn't query the version of the cluster, set the conformance image with --conformance-image")
+	}
+	if settings.GetString("parallel") == common.ParallelAuto {
+		return fmt.Errorf("--parallel=%s depends on the nodes of the cluster, set the number of parallel processes with --dry-run", common.ParallelAuto)
+	}
+	if err := resolveOutputDir(); err != nil {
+		return err
+	}
+	objects, err := buildManifests()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := service.WriteManifests(&buf, objects); err != nil {
+		return err
+	}
+	if _, err := stdout.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	path := filepath.Join(settings.GetString("output-dir"), common.ManifestsFile)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	log.Printf("Manifests written to %s", path)
+	return nil
+}
+
+// validateManifests submits the resources a run would create to the API
+// server with the server-side dry run option and prints whether each of them
+// was accepted, so that the admission webhooks and policies rejecting them
+// are found before a run.
+func validateManifests(clientset kubernetes.Interface) error {
+	if err := service.ResolveParallel(clientset); err != nil {
+		return err
+	}
+	if err := service.ResolveArchitecture(clientset); err != nil {
+		return err
+	}
+	objects, err := buildManifests()
+	if err != nil {
+		return err
+	}
+	results := service.DryRunServer(clientset, objects)
+	if err := service.WriteDryRunResults(stdout, results); err != nil {
+		return err
+	}
+	rejected := 0
+	for _, result := range results {
+		if result.Err != nil {
+			rejected++
+		}
+	}
+	if rejected != 0 {
+		return fmt.Errorf("the API server rejected %d of the %d resources of the run", rejected, len(results))
+	}
+	log.Printf("The API server accepted the %d resources of the run", len(results))
+	return nil
+}
+
+// buildManifests returns the resources a run would create with the flags,
+// logging the command of each conformance container.
+func buildManifests() ([]runtime.Object, error) {
+	if settings.GetString("suite-file") != "" {
+		return nil, errors.New("--dry-run doesn't support --suite-file")
+	}
+	if settings.GetBool("gate-smoke") {
+		return nil, errors.New("--dry-run doesn't support --gate-smoke")
+	}
+	if settings.GetBool("certified") {
+		if err := common.ValidateCertified(); err != nil {
+			return nil, err
+		}
+	}
+	if err := applySkipFile(); err != nil {
+		return nil, err
+	}
+	applyNodeOS()
+	applyIPFamily()
+	s, _, err := selectTests(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s != nil {
+		return nil, errors.New("the selected tests run in several chunks, which --dry-run doesn't render")
+	}
+	if err := common.ValidateArgs(); err != nil {
+		return nil, err
+	}
+
+	objects, err := service.Manifests()
+	if err != nil {
+		return nil, err
+	}
+	pods, err := service.Pods(settings.GetString("namespace"))
+	if err != nil {
+		return nil, err
+	}
+	service.LogConformanceCommands(pods)
+	return objects, nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/export"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+var (
+	exportJUnit   string
+	exportMapping string
+	testRail      = export.TestRail{}
+	xray          = export.Xray{}
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the results of a run to a test management system.",
+	Long: `Export the results of a run to a test management system.
+
+The e2e tests are mapped to test cases with a yaml mapping file whose keys are
+the test names and whose values are the test case IDs, e.g.
+
+  "[sig-node] Pods should be submitted and removed [NodeConformance] [Conformance]": C1234
+
+Tests that were skipped or that have no test case are not exported.`,
+}
+
+var exportTestRailCmd = &cobra.Command{
+	Use:   "testrail",
+	Short: "Add the results to a TestRail test run.",
+	Long: `Add the results to a TestRail test run.
+
+The credentials are read from the TESTRAIL_USER and TESTRAIL_API_KEY environment variables.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		testRail.User = os.Getenv("TESTRAIL_USER")
+		testRail.APIKey = os.Getenv("TESTRAIL_API_KEY")
+		if testRail.User == "" || testRail.APIKey == "" {
+			log.Fatal("TESTRAIL_USER and TESTRAIL_API_KEY have to be set")
+		}
+		rs := mappedResults()
+		if err := testRail.Export(rs); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("added %d results to TestRail run %d", len(rs), testRail.RunID)
+	},
+}
+
+var exportXrayCmd = &cobra.Command{
+	Use:   "xray",
+	Short: "Import the results as a Jira Xray test execution.",
+	Long: `Import the results as a Jira Xray test execution.
+
+The credentials of the Xray API key are read from the XRAY_CLIENT_ID and
+XRAY_CLIENT_SECRET environment variables.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		xray.ClientID = os.Getenv("XRAY_CLIENT_ID")
+		xray.ClientSecret = os.Getenv("XRAY_CLIENT_SECRET")
+		if xray.ClientID == "" || xray.ClientSecret == "" {
+			log.Fatal("XRAY_CLIENT_ID and XRAY_CLIENT_SECRET have to be set")
+		}
+		rs := mappedResults()
+		key, err := xray.Export(rs)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("imported %d results to Xray test execution %s", len(rs), key)
+	},
+}
+
+// mappedResults reads the junit report and returns the results of the mapped tests
+func mappedResults() []export.Result {
+	mapping, err := export.LoadMapping(exportMapping)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report, err := results.ReadJUnit(exportJUnit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rs := export.MappedResults(report, mapping)
+	if len(rs) == 0 {
+		log.Fatalf("none of the tests in %s is mapped to a test case", exportJUnit)
+	}
+	return rs
+}
+
+func init() {
+	exportCmd.PersistentFlags().StringVar(&exportJUnit, "junit", "junit_01.xml", "junit report of the run.")
+	exportCmd.PersistentFlags().StringVar(&exportMapping, "mapping", "", "yaml file mapping test names to test case IDs.")
+	exportCmd.MarkPersistentFlagRequired("mapping")
+
+	exportTestRailCmd.Flags().StringVar(&testRail.URL, "url", "", "URL of the TestRail instance, e.g. https://example.testrail.io.")
+	exportTestRailCmd.Flags().IntVar(&testRail.RunID, "run-id", 0, "ID of the test run the results are added to.")
+	exportTestRailCmd.MarkFlagRequired("url")
+	exportTestRailCmd.MarkFlagRequired("run-id")
+
+	exportXrayCmd.Flags().StringVar(&xray.URL, "url", export.DefaultXrayURL, "URL of the Xray API.")
+	exportXrayCmd.Flags().StringVar(&xray.ProjectKey, "project", "", "key of the Jira project the test execution is created in.")
+	exportXrayCmd.Flags().StringVar(&xray.TestPlanKey, "test-plan", "", "key of the test plan the test execution is linked to.")
+	exportXrayCmd.Flags().StringVar(&xray.Summary, "summary", "Kubernetes conformance", "summary of the test execution.")
+	exportXrayCmd.MarkFlagRequired("project")
+
+	exportCmd.AddCommand(exportTestRailCmd, exportXrayCmd)
+	rootCmd.AddCommand(exportCmd)
+}
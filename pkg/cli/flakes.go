@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+	"sigs.k8s.io/hydrophone/pkg/testgrid"
+)
+
+// failures returns the failed tests of the junit report in the output
+// directory. With --upstream-flakes each failure is annotated with how often
+// the test failed upstream, to tell broken clusters from flaky tests.
+func failures(outputDir string) []results.Failure {
+	report, err := results.ReadJUnit(filepath.Join(outputDir, "junit_01.xml"))
+	if err != nil {
+		log.Printf("unable to read the failed tests: %v", err)
+		return nil
+	}
+	names := results.FailedTests(report)
+	if len(names) == 0 {
+		return nil
+	}
+
+	failed := make([]results.Failure, len(names))
+	for i, name := range names {
+		failed[i].Name = name
+	}
+
+	upstream := settings.GetString("upstream-flakes")
+	if upstream == "" {
+		return failed
+	}
+	dashboard, tab, _ := strings.Cut(upstream, "/")
+	rates, err := testgrid.NewClient().FailureRates(dashboard, tab)
+	if err != nil {
+		log.Printf("WARNING: unable to fetch upstream failure rates: %v", err)
+		return failed
+	}
+	for i := range failed {
+		rate, ok := rates[failed[i].Name]
+		if !ok {
+			log.Printf("FAILED %s (not run upstream in %s)", failed[i].Name, upstream)
+			continue
+		}
+		failed[i].UpstreamRuns = rate.Runs
+		failed[i].UpstreamFailures = rate.Failures
+		log.Printf("FAILED %s (failed upstream in %d of the last %d runs, %.0f%%)",
+			failed[i].Name, rate.Failures, rate.Runs, 100*rate.Rate())
+	}
+	return failed
+}
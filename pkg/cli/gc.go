@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+var (
+	gcOlderThan time.Duration
+	gcListOnly  bool
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete the resources of past runs across the cluster.",
+	Long: fmt.Sprintf(`Delete the resources of past runs across the cluster.
+
+Every resource hydrophone creates is labeled %s=%s
+and, with --run-id, %s=<id>. The labeled namespaces, pods,
+jobs, config maps, secrets, service accounts, cluster roles and cluster role
+bindings created more than --older-than ago are deleted, the resources of a
+deleted namespace with it. Runs in progress are kept as long as they are
+younger than --older-than. With --run-id only the resources of that run are
+deleted.`, common.ManagedByLabel, common.ManagedBy, common.RunIDLabel),
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if gcOlderThan <= 0 {
+			log.Fatalf("expected --older-than to be positive, got %s", gcOlderThan)
+		}
+		_, clientSet := service.Init(settings.GetString("kubeconfig"))
+		garbage, err := service.FindGarbage(clientSet, gcOlderThan, time.Now())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(garbage) == 0 {
+			log.Printf("no resources of runs older than %s found", gcOlderThan)
+			return
+		}
+		for _, g := range garbage {
+			runID := g.RunID
+			if runID == "" {
+				runID = "none"
+			}
+			log.Printf("%s, run ID %s, created %s ago", g, runID, g.Age.Round(time.Minute))
+		}
+		if gcListOnly {
+			return
+		}
+		if err := service.DeleteGarbage(clientSet, garbage); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	common.DurationVar(gcCmd.Flags(), &gcOlderThan, "older-than", 24*time.Hour, "minimum age of the resources to delete.")
+	gcCmd.Flags().BoolVar(&gcListOnly, "list-only", false, "list the resources without deleting anything.")
+
+	rootCmd.AddCommand(gcCmd)
+}
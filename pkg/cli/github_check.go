@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/hydrophone/pkg/github"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/notify"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// githubCheck is the check run of --github-check, nil when the run isn't
+// reported to GitHub
+var githubCheck *github.Check
+
+// startGitHubCheck creates the check run of --github-check on the commit of
+// --github-sha. The repository and the commit default to the ones of the
+// GitHub Actions workflow running hydrophone.
+func startGitHubCheck() error {
+	if !settings.GetBool("github-check") {
+		return nil
+	}
+	check := &github.Check{
+		APIURL: os.Getenv("GITHUB_API_URL"),
+		Token:  os.Getenv("GITHUB_TOKEN"),
+		Repo:   settings.GetString("github-repo"),
+		SHA:    settings.GetString("github-sha"),
+		Name:   settings.GetString("github-check-name"),
+	}
+	if check.APIURL == "" {
+		check.APIURL = github.DefaultAPIURL
+	}
+	if check.Repo == "" {
+		check.Repo = os.Getenv("GITHUB_REPOSITORY")
+	}
+	if check.SHA == "" {
+		check.SHA = os.Getenv("GITHUB_SHA")
+	}
+	switch {
+	case check.Token == "":
+		return errors.New("--github-check requires a token in GITHUB_TOKEN")
+	case check.Repo == "" || check.SHA == "":
+		return errors.New("--github-check requires --github-repo and --github-sha outside of GitHub Actions")
+	}
+
+	output := github.Output{
+		Title:   "Setting up the conformance tests",
+		Summary: fmt.Sprintf("Running %s against the cluster.", settings.GetString("conformance-image")),
+	}
+	if err := check.Create(context.Background(), output); err != nil {
+		return err
+	}
+	log.Printf("created check run %q on %s@%s", check.Name, check.Repo, check.SHA)
+	githubCheck = check
+	return nil
+}
+
+// updateGitHubCheck reports the progress of the run in the check run,
+// failures are logged without failing the run.
+func updateGitHubCheck(title, summary string) {
+	if githubCheck == nil {
+		return
+	}
+	if err := githubCheck.Update(context.Background(), github.Output{Title: title, Summary: summary}); err != nil {
+		log.Printf("unable to report the progress to GitHub: %v", err)
+	}
+}
+
+// completeGitHubCheck completes the check run with the summary of the run
+// and annotations of the failed tests.
+func completeGitHubCheck(p *notify.Payload, annotations []github.Annotation) {
+	if githubCheck == nil {
+		return
+	}
+	if err := githubCheck.Complete(context.Background(), github.Conclusion(p.Status), github.Summary(p, annotations)); err != nil {
+		log.Printf("unable to report the results to GitHub: %v", err)
+		return
+	}
+	log.Printf("completed check run %q on %s@%s", githubCheck.Name, githubCheck.Repo, githubCheck.SHA)
+}
+
+// failureAnnotations returns the annotations of the tests failed in the
+// junit report of the output directory
+func failureAnnotations(outputDir string) []github.Annotation {
+	if githubCheck == nil {
+		return nil
+	}
+	var annotations []github.Annotation
+	for _, tc := range failedTests(outputDir) {
+		annotations = append(annotations, github.FailureAnnotation(tc.Name, failureMessage(tc)))
+	}
+	return annotations
+}
+
+// writeActionsErrors emits an ::error workflow command for each test failed
+// in the junit report of the output directory when hydrophone runs in a
+// GitHub Actions workflow, so that the failures show up as annotations of
+// the workflow run instead of only in its log.
+func writeActionsErrors(outputDir string) {
+	if !github.InActions() {
+		return
+	}
+	w := stdout
+	if streamOutput() {
+		w = stderr
+	}
+	for _, tc := range failedTests(outputDir) {
+		if err := github.WriteError(w, tc.Name, failureMessage(tc)); err != nil {
+			log.Printf("unable to annotate the failed tests: %v", err)
+			return
+		}
+	}
+}
+
+// failedTests returns the tests failed in the junit report of the output
+// directory, none when it can't be read
+func failedTests(outputDir string) []results.JUnitTestCase {
+	report, err := results.ReadJUnit(filepath.Join(outputDir, "junit_01.xml"))
+	if err != nil {
+		return nil
+	}
+	var failed []results.JUnitTestCase
+	for _, suite := range report.TestSuites {
+		for _, tc := range suite.TestCases {
+			if tc.Status == results.StatusFailed {
+				failed = append(failed, tc)
+			}
+		}
+	}
+	return failed
+}
+
+// failureMessage returns the message the test failed with
+func failureMessage(tc results.JUnitTestCase) string {
+	if tc.Failure != nil {
+		return tc.Failure.Message
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/redact"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// handleHang returns the handler debugging the specs running longer than
+// --hang-debug-after, nil when it is disabled. Each spec is debugged once per
+// pod, ginkgo reports it again at every --progress-report interval.
+func handleHang(c *client.Client) client.HangHandler {
+	// the flag was validated by ValidateArgs
+	after, _ := common.GetDuration("hang-debug-after")
+	if after <= 0 {
+		return nil
+	}
+	var mu sync.Mutex
+	debugged := map[[2]string]bool{}
+	return func(podName string, spec client.RunningSpec) {
+		if spec.Runtime < after {
+			return
+		}
+		mu.Lock()
+		key := [2]string{podName, spec.Name}
+		if debugged[key] {
+			mu.Unlock()
+			return
+		}
+		debugged[key] = true
+		n := len(debugged)
+		mu.Unlock()
+
+		log.Printf("spec running for %s in pod %s, debugging it: %s", spec.Runtime.Round(time.Second), podName, spec.Name)
+		output, err := service.DebugPod(c.ClientSet, podName, settings.GetString("hang-debug-command"))
+		if err != nil {
+			log.Printf("unable to debug pod %s: %v", podName, err)
+			return
+		}
+		dir := filepath.Join(settings.GetString("output-dir"), service.DiagnosticsDir)
+		path := filepath.Join(dir, fmt.Sprintf("hang-%s-%d.log", podName, n))
+		content := fmt.Sprintf("spec: %s\nruntime: %s\ncommand: %s\n\n%s", spec.Name, spec.Runtime.Round(time.Second), settings.GetString("hang-debug-command"), output)
+		if err := os.MkdirAll(dir, 0755); err == nil {
+			err = os.WriteFile(path, []byte(redact.String(content)), 0644)
+		}
+		if err != nil {
+			log.Printf("unable to write the debug output of pod %s: %v", podName, err)
+			return
+		}
+		log.Printf("debug output of pod %s written to %s", podName, path)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/history"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Manage the history of the runs.",
+	Long: `Manage the history of the runs.
+
+Unless --record-history=false is passed, every run copies its results.json,
+junit_01.xml and e2e.log to a directory of the history, named after the time
+the run ended.`,
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the runs of the history.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runs, err := history.Runs(settings.GetString("history-dir"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, run := range runs {
+			fmt.Fprintln(stdout, run)
+		}
+	},
+}
+
+var historyExportCmd = &cobra.Command{
+	Use:   "export FILE",
+	Short: "Write the history to a compressed archive.",
+	Long: `Write the history to a compressed archive.
+
+The archive is a gzip compressed tar file starting with a manifest.json that
+records the schema version of the archive. Pass - to write it to stdout.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var w io.Writer = stdout
+		if args[0] != "-" {
+			f, err := os.OpenFile(args[0], os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			w = f
+		}
+		n, err := history.Export(settings.GetString("history-dir"), w, time.Now())
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("exported %d runs", n)
+	},
+}
+
+var historyImportCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Add the runs of an archive to the history.",
+	Long: `Add the runs of an archive written by history export to the history.
+
+Runs that are already in the history are kept as they are. Pass - to read the
+archive from stdin.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var r io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			r = f
+		}
+		n, err := history.Import(settings.GetString("history-dir"), r)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("imported %d runs", n)
+	},
+}
+
+func init() {
+	historyCmd.AddCommand(historyListCmd, historyExportCmd, historyImportCmd)
+	rootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// handleInterrupts aborts the run when hydrophone receives SIGINT or SIGTERM,
+// or when ctx is canceled: the logs stop streaming, the artifacts written so
+// far are collected, the run is recorded as aborted and its resources are
+// deleted or kept according to --on-interrupt. A second signal exits right
+// away. The signals are left to the program running hydrophone in process.
+// The returned function stops handling the interrupts.
+func handleInterrupts(ctx context.Context, c *client.Client, config *rest.Config) (func(), error) {
+	policy := settings.GetString("on-interrupt")
+	switch policy {
+	case common.OnInterruptCleanup, common.OnInterruptKeep:
+	default:
+		return nil, fmt.Errorf("expected --on-interrupt to be %s or %s, got %q", common.OnInterruptCleanup, common.OnInterruptKeep, policy)
+	}
+
+	signals := make(chan os.Signal, 2)
+	if !inProcess {
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			go func() {
+				<-signals
+				log.Fatal("interrupted again, exiting without collecting the results")
+			}()
+			abortInterrupted(c, config, sig.String(), policy)
+		case <-ctx.Done():
+			abortInterrupted(c, config, "the cancellation of the run", policy)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}, nil
+}
+
+// abortInterrupted records the interrupted run and deletes or keeps its
+// resources according to the policy of --on-interrupt.
+func abortInterrupted(c *client.Client, config *rest.Config, cause, policy string) {
+	reason := fmt.Sprintf("interrupted by %s", cause)
+	log.Printf("Aborting the run, %s", reason)
+	c.StopStreaming()
+	recordAbortedRun(c, config, reason, false)
+	releaseInterrupted(c.ClientSet, policy)
+	log.Fatal("run interrupted")
+}
+
+// releaseInterrupted deletes the resources of the interrupted run, or keeps
+// them to be inspected when the policy of --on-interrupt is keep.
+func releaseInterrupted(clientSet kubernetes.Interface, policy string) {
+	if policy == common.OnInterruptKeep {
+		log.Printf("Keeping the resources of the run in namespace %s, delete them with --cleanup", settings.GetString("namespace"))
+		return
+	}
+	service.Cleanup(clientSet)
+}
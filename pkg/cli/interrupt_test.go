@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestHandleInterruptsPolicy(t *testing.T) {
+	defer settings.Set("on-interrupt", nil)
+	for _, policy := range []string{common.OnInterruptCleanup, common.OnInterruptKeep} {
+		settings.Set("on-interrupt", policy)
+		stop, err := handleInterrupts(context.Background(), &client.Client{}, nil)
+		require.NoError(t, err, policy)
+		stop()
+	}
+	for _, policy := range []string{"", "delete", "Keep"} {
+		settings.Set("on-interrupt", policy)
+		_, err := handleInterrupts(context.Background(), &client.Client{}, nil)
+		assert.ErrorContains(t, err, "expected --on-interrupt to be cleanup or keep", policy)
+	}
+}
+
+func TestReleaseInterrupted(t *testing.T) {
+	settings.Set("namespace", "conformance")
+	defer settings.Set("namespace", nil)
+	tests := []struct {
+		policy string
+		kept   bool
+	}{
+		{policy: common.OnInterruptKeep, kept: true},
+		{policy: common.OnInterruptCleanup, kept: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			clientSet := fake.NewSimpleClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "conformance"}},
+				&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+					Name:      common.PodName,
+					Namespace: "conformance",
+					Labels:    map[string]string{"component": "conformance"},
+				}},
+			)
+
+			releaseInterrupted(clientSet, tt.policy)
+
+			ctx := context.Background()
+			_, podErr := clientSet.CoreV1().Pods("conformance").Get(ctx, common.PodName, metav1.GetOptions{})
+			_, namespaceErr := clientSet.CoreV1().Namespaces().Get(ctx, "conformance", metav1.GetOptions{})
+			if tt.kept {
+				assert.NoError(t, podErr)
+				assert.NoError(t, namespaceErr)
+			} else {
+				assert.True(t, apierrors.IsNotFound(podErr), "pod not deleted: %v", podErr)
+				assert.True(t, apierrors.IsNotFound(namespaceErr), "namespace not deleted: %v", namespaceErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+var (
+	listOutput  string
+	listOffline bool
+)
+
+// testList is the JSON output of the list command
+type testList struct {
+	ConformanceImage string   `json:"conformanceImage"`
+	Focus            string   `json:"focus"`
+	Skip             string   `json:"skip,omitempty"`
+	Tests            []string `json:"tests"`
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the tests matching the focus and skip without running them.",
+	Long: `List the tests matching the focus and skip without running them.
+
+The conformance image is run in dry-run mode in a short-lived pod and the
+names of the selected tests are printed, one per line or as JSON.
+
+The tests of each conformance version are cached by the runs and the list
+command. With --offline the tests are selected from the cached tests of the
+version of --conformance-image, or of the version cached last, without
+connecting to the cluster or the registry.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if listOutput != "text" && listOutput != "json" {
+			log.Fatalf("expected --output to be text or json, got %q", listOutput)
+		}
+
+		if listOffline {
+			if err := listCachedTests(); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		c := client.NewClient()
+		config, clientSet := service.Init(settings.GetString("kubeconfig"))
+		c.ClientSet = clientSet
+		common.PrintInfo(c.ClientSet, config)
+		if err := service.ResolveArchitecture(c.ClientSet); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := applySkipFile(); err != nil {
+			log.Fatal(err)
+		}
+		tags, err := common.FocusFromTags(settings.GetStringSlice("sig"), settings.GetStringSlice("behavior"), false)
+		if err != nil {
+			log.Fatal(err)
+		}
+		focus := settings.GetString("focus")
+		listFocus := focus
+		if tags != "" {
+			listFocus = tags
+		} else if listFocus == "" {
+			listFocus = conformanceFocus
+		}
+
+		common.SetDefaultNamespace()
+		names, err := listTests(c, config, listFocus)
+		service.Cleanup(c.ClientSet)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// the focus and the tags both have to match, see selectTests
+		if focus != "" && tags != "" {
+			if names, _, err = common.FilterTests(names, []string{focus}, ""); err != nil {
+				log.Fatal(err)
+			}
+		}
+		list := testList{
+			ConformanceImage: settings.GetString("conformance-image"),
+			Focus:            listFocus,
+			Skip:             settings.GetString("skip"),
+			Tests:            names,
+		}
+		log.Printf("%d tests match the focus and skip", len(list.Tests))
+		// the names complete --focus and --skip
+		if err := common.WriteTestListCache(common.TestListCacheFile(), names); err != nil {
+			log.Printf("Failed to cache the names of the tests: %v", err)
+		}
+		if err := common.CacheTests(common.TestCacheDir(), common.ImageVersion(list.ConformanceImage), names, false); err != nil {
+			log.Printf("Failed to cache the names of the tests: %v", err)
+		}
+
+		if err := printTestList(list); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// printTestList prints the names of the tests one per line, or the list as
+// JSON with --output=json.
+func printTestList(list testList) error {
+	if listOutput == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+	for _, name := range list.Tests {
+		fmt.Fprintln(stdout, name)
+	}
+	return nil
+}
+
+// listCachedTests prints the cached tests of the conformance version
+// matching the focus, the tags and the skip.
+func listCachedTests() error {
+	dir := common.TestCacheDir()
+	version := common.ImageVersion(settings.GetString("conformance-image"))
+	if version == "" {
+		var err error
+		if version, err = common.LatestCachedVersion(dir); err != nil {
+			return err
+		}
+	}
+	cached, err := common.ReadCachedTests(dir, version)
+	if err != nil {
+		return err
+	}
+	if version == "" || cached == nil {
+		return errors.New("no tests of the conformance version are cached, run the tests or hydrophone list once with a connection to the cluster")
+	}
+	if !cached.Complete {
+		log.Printf("The cache of %s only holds the tests listed so far, run the tests once to cache all of them", version)
+	}
+
+	if err := applySkipFile(); err != nil {
+		return err
+	}
+	tags, err := common.FocusFromTags(settings.GetStringSlice("sig"), settings.GetStringSlice("behavior"), false)
+	if err != nil {
+		return err
+	}
+	focus := settings.GetString("focus")
+	listFocus := focus
+	if tags != "" {
+		listFocus = tags
+	} else if listFocus == "" {
+		listFocus = conformanceFocus
+	}
+	// the focus and the tags both have to match, see selectTests
+	names, _, err := common.FilterTests(cached.Tests, nonEmpty(listFocus, focus), settings.GetString("skip"))
+	if err != nil {
+		return err
+	}
+	list := testList{
+		ConformanceImage: settings.GetString("conformance-image"),
+		Focus:            listFocus,
+		Skip:             settings.GetString("skip"),
+		Tests:            names,
+	}
+	log.Printf("%d of the %d cached tests of %s match the focus and skip", len(names), len(cached.Tests), version)
+	return printTestList(list)
+}
+
+// cacheTests caches the tests of the junit report of the run, which lists
+// all the tests of the conformance image, for list --offline. Runs of other
+// images than the conformance image are left out.
+func cacheTests(outputDir string) {
+	if settings.GetString("plugin") != "" || len(settings.GetStringSlice("node")) != 0 {
+		return
+	}
+	report, err := results.ReadJUnit(filepath.Join(outputDir, "junit_01.xml"))
+	if err != nil {
+		// aborted runs have no report
+		return
+	}
+	if err := common.CacheTests(common.TestCacheDir(), common.ImageVersion(settings.GetString("conformance-image")), results.AllTests(report), true); err != nil {
+		log.Printf("Failed to cache the names of the tests: %v", err)
+	}
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "text", "output format, text or json.")
+	listCmd.Flags().BoolVar(&listOffline, "offline", false, "select the tests from the cached tests of the conformance version without connecting to the cluster or the registry.")
+
+	rootCmd.AddCommand(listCmd)
+}
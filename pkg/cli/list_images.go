@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+var (
+	listImagesOutput string
+	mirrorRegistry   string
+	includeRunner    bool
+)
+
+// imageMapping is the JSON output of the list-images command
+type imageMapping struct {
+	Source string `json:"source"`
+	Target string `json:"target,omitempty"`
+}
+
+var listImagesCmd = &cobra.Command{
+	Use:   "list-images",
+	Short: "List the container images required by the tests.",
+	Long: `List the container images required by the tests.
+
+The images are listed by the e2e test binary of the selected conformance image.
+By default the conformance and busybox images hydrophone runs are included, so
+that the list is complete to prepare an air-gapped registry.
+
+With --mirror-registry every line holds the source image and its reference in
+the mirror registry, separated by a space, e.g. to be passed to crane copy.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if listImagesOutput != "text" && listImagesOutput != "json" {
+			log.Fatalf("expected --output to be text or json, got %q", listImagesOutput)
+		}
+
+		c := client.NewClient()
+		config, clientSet := service.Init(settings.GetString("kubeconfig"))
+		c.ClientSet = clientSet
+		common.PrintInfo(c.ClientSet, config)
+		if err := service.ResolveArchitecture(c.ClientSet); err != nil {
+			log.Fatal(err)
+		}
+
+		images, err := service.ListImages(c.ClientSet)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if includeRunner {
+			images = append([]string{settings.GetString("conformance-image"), settings.GetString("busybox-image")}, images...)
+		}
+
+		mappings := make([]imageMapping, len(images))
+		for i, image := range images {
+			mappings[i].Source = image
+			if mirrorRegistry != "" {
+				mappings[i].Target = service.MirrorImage(image, mirrorRegistry)
+			}
+		}
+
+		if listImagesOutput == "json" {
+			enc := json.NewEncoder(stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(mappings); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		for _, m := range mappings {
+			if m.Target != "" {
+				fmt.Fprintln(stdout, m.Source, m.Target)
+			} else {
+				fmt.Fprintln(stdout, m.Source)
+			}
+		}
+	},
+}
+
+func init() {
+	listImagesCmd.Flags().StringVarP(&listImagesOutput, "output", "o", "text", "output format, text or json.")
+	listImagesCmd.Flags().StringVar(&mirrorRegistry, "mirror-registry", "", "registry the images are mirrored to, e.g. mirror.example.com. prints the reference of each image in the mirror next to it.")
+	listImagesCmd.Flags().BoolVar(&includeRunner, "include-runner", true, "include the conformance and busybox images run by hydrophone.")
+
+	rootCmd.AddCommand(listImagesCmd)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+)
+
+// defaultLogBackups is the number of rotated log files kept when a file sink
+// sets max-size without max-backups
+const defaultLogBackups = 5
+
+// addLogSinks sends the logs to the given sinks on top of stderr. Supported sinks are
+//
+//	syslog                               local syslog daemon
+//	syslog://host:514, syslog+tcp://...  remote syslog over udp or tcp
+//	journald                             systemd journal
+//	file:/path?max-size=10MiB&max-backups=3
+func addLogSinks(sinks []string) error {
+	for _, sink := range sinks {
+		h, err := newLogSink(sink)
+		if err != nil {
+			return fmt.Errorf("invalid log sink %q: %w", sink, err)
+		}
+		log.AddHandler(h)
+	}
+	return nil
+}
+
+func newLogSink(sink string) (slog.Handler, error) {
+	u, err := url.Parse(sink)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" {
+		u.Scheme = u.Path
+	}
+
+	switch u.Scheme {
+	case "syslog":
+		if u.Host == "" {
+			return log.NewSyslogHandler("", "")
+		}
+		return log.NewSyslogHandler("udp", u.Host)
+	case "syslog+tcp":
+		return log.NewSyslogHandler("tcp", u.Host)
+	case "journald":
+		return log.NewJournaldHandler()
+	case "file":
+		path := u.Path
+		if u.Opaque != "" {
+			path = u.Opaque
+		}
+		if path == "" {
+			return nil, fmt.Errorf("expected a path such as file:/var/log/hydrophone.log")
+		}
+		query := u.Query()
+		var maxSize common.ByteSize
+		if value := query.Get("max-size"); value != "" {
+			if maxSize, err = common.ParseByteSize(value); err != nil {
+				return nil, err
+			}
+		}
+		maxBackups := defaultLogBackups
+		if value := query.Get("max-backups"); value != "" {
+			if maxBackups, err = strconv.Atoi(value); err != nil || maxBackups < 0 {
+				return nil, fmt.Errorf("expected max-backups to be a non-negative number, got %q", value)
+			}
+		}
+		return log.NewFileHandler(path, int64(maxSize), maxBackups)
+	default:
+		return nil, fmt.Errorf("unknown sink, expected one of syslog, syslog+tcp, journald or file")
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/metrics"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+const (
+	// pushInterval is the interval at which the metrics are pushed to the
+	// Pushgateway of --pushgateway-url while the run is in progress
+	pushInterval = 30 * time.Second
+	// pushJob is the job the metrics are grouped by on the Pushgateway
+	pushJob = "hydrophone"
+)
+
+// runMetrics exports the metrics of the run, nil without --metrics-addr and
+// --pushgateway-url
+var runMetrics *metricsExporter
+
+// metricsExporter serves the metrics of the run and pushes them to the
+// Pushgateway
+type metricsExporter struct {
+	registry *metrics.Registry
+	gateway  string
+	// finished is the time the run finished at in unix nanoseconds, 0 while
+	// it runs
+	finished atomic.Int64
+	exitCode atomic.Int64
+	stopPush func()
+}
+
+// startMetrics starts serving the metrics of the run on --metrics-addr and
+// pushing them to --pushgateway-url.
+func startMetrics(c *client.Client) error {
+	addr := settings.GetString("metrics-addr")
+	gateway := settings.GetString("pushgateway-url")
+	if addr == "" && gateway == "" {
+		return nil
+	}
+	if gateway != "" {
+		if u, err := url.Parse(gateway); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("expected --pushgateway-url to be an http or https URL, got %q", gateway)
+		}
+	}
+
+	e := &metricsExporter{registry: &metrics.Registry{}, gateway: gateway, stopPush: func() {}}
+	r := e.registry
+	r.Gauge("hydrophone_run_start_time_seconds", "Unix time the run started at.", func() float64 {
+		return float64(runStarted.Unix())
+	})
+	r.Gauge("hydrophone_run_duration_seconds", "Duration of the run so far, of the whole run once it finished.", func() float64 {
+		end := time.Now()
+		if finished := e.finished.Load(); finished != 0 {
+			end = time.Unix(0, finished)
+		}
+		return end.Sub(runStarted).Seconds()
+	})
+	r.Gauge("hydrophone_run_finished", "1 once the run finished, 0 while it is in progress.", func() float64 {
+		if e.finished.Load() != 0 {
+			return 1
+		}
+		return 0
+	})
+	r.Gauge("hydrophone_run_exit_code", "Exit code of the run once it finished.", func() float64 {
+		return float64(e.exitCode.Load())
+	})
+	r.Gauge("hydrophone_specs_total", "Specs ginkgo runs.", func() float64 { return float64(c.Specs.Total.Load()) })
+	r.Gauge("hydrophone_specs_completed", "Specs that completed.", func() float64 { return float64(c.Specs.Completed()) })
+	r.Gauge("hydrophone_specs_passed", "Specs that passed.", func() float64 { return float64(c.Specs.Passed.Load()) })
+	r.Gauge("hydrophone_specs_failed", "Specs that failed.", func() float64 { return float64(c.Specs.Failed.Load()) })
+	r.Gauge("hydrophone_specs_skipped", "Specs that were skipped.", func() float64 { return float64(c.Specs.Skipped.Load()) })
+	r.Counter("hydrophone_reconnects_total", "Watches and log streams re-established after the API server closed them.", func() float64 {
+		return float64(c.Reconnects.Load())
+	})
+	r.Counter("hydrophone_pod_restarts_total", "Conformance pods lost and replaced by their job.", func() float64 {
+		return float64(c.PodRestarts.Load())
+	})
+
+	if addr != "" {
+		// the endpoint is served until hydrophone exits, so that the final
+		// values can be scraped
+		if _, err := r.Serve(addr); err != nil {
+			return fmt.Errorf("unable to serve the metrics on %s: %w", addr, err)
+		}
+		log.Printf("serving the metrics of the run on http://%s/metrics", addr)
+	}
+	if gateway != "" {
+		ticker := time.NewTicker(pushInterval)
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					e.push()
+				}
+			}
+		}()
+		e.stopPush = func() {
+			ticker.Stop()
+			close(done)
+		}
+		e.push()
+	}
+	runMetrics = e
+	return nil
+}
+
+// push pushes the metrics to the Pushgateway, failures are logged
+func (e *metricsExporter) push() {
+	if err := e.registry.Push(context.Background(), e.gateway, pushJob); err != nil {
+		log.Printf("unable to push the metrics: %v", err)
+	}
+}
+
+// finishMetrics records the end of the run in the metrics and pushes them a
+// last time.
+func finishMetrics(exitCode int) {
+	e := runMetrics
+	if e == nil || e.finished.Load() != 0 {
+		return
+	}
+	e.exitCode.Store(int64(exitCode))
+	e.finished.Store(time.Now().UnixNano())
+	if e.gateway != "" {
+		e.stopPush()
+		e.push()
+	}
+}
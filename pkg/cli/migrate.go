@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/sonobuoy"
+)
+
+var migrateOutput string
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate FILE",
+	Short: "Convert a Sonobuoy configuration to a hydrophone configuration.",
+	Long: `Convert a Sonobuoy configuration to a hydrophone configuration.
+
+The Sonobuoy run configuration, in JSON or YAML, is converted to the keys of
+hydrophone.yaml: the mode and the focus, skip and parallel of the e2e plugin
+select the tests, the conformance image override and the namespace are kept,
+and the environment overrides of the e2e plugin are mapped to their flags.
+Settings without equivalent in hydrophone, e.g. other plugins, are reported
+as warnings. The configuration is printed unless --output is given, use it
+with --config.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		config, warnings, err := sonobuoy.Convert(data)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, warning := range warnings {
+			log.Printf("WARNING: %s", warning)
+		}
+		out, err := yaml.Marshal(config)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if migrateOutput == "" {
+			if _, err := stdout.Write(out); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		if err := os.WriteFile(migrateOutput, out, 0644); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Configuration written to %s, run it with hydrophone --config %s", migrateOutput, migrateOutput)
+	},
+}
+
+func init() {
+	migrateCmd.Flags().StringVarP(&migrateOutput, "output", "o", "", "file the hydrophone configuration is written to.")
+
+	rootCmd.AddCommand(migrateCmd)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// validateNodes checks that the flags of the run apply to the node
+// conformance tests of --node
+func validateNodes() error {
+	if len(settings.GetStringSlice("node")) == 0 {
+		return nil
+	}
+	if settings.GetInt("shards") > 1 {
+		return fmt.Errorf("--shards splits the e2e tests and can't be used with --node")
+	}
+	if settings.GetString("workload") == common.WorkloadJob {
+		return fmt.Errorf("--workload=%s can't be used with --node, the pods are bound to their node", common.WorkloadJob)
+	}
+	if settings.GetString("focus") == "" {
+		settings.Set("focus", service.NodeConformanceFocus)
+	}
+	return nil
+}
+
+// runNodeConformance runs the node conformance tests on each node one after
+// another in the namespace, which has to be set up already. The artifacts of
+// each node are written to a node-<name> subdirectory of the output
+// directory, the junit reports of all nodes are merged into a combined report
+// with the tests prefixed by their node. It returns the exit code of the
+// first failed node.
+func runNodeConformance(config *rest.Config, clientSet kubernetes.Interface, nodes []string) int {
+	outputDir := settings.GetString("output-dir")
+	artifacts := settings.GetStringSlice("artifacts")
+	// the node test image writes its own reports rather than e2e.log
+	if len(artifacts) == 0 {
+		settings.Set("artifacts", []string{"**"})
+	}
+
+	exitCode := 0
+	summary := &results.Metadata{
+		ServerVersion: settings.GetString("server-git-version"),
+		Cluster:       clusterSnapshot,
+		Focus:         settings.GetString("focus"),
+		Skip:          settings.GetString("skip"),
+	}
+	var reports []*results.JUnitTestSuites
+	for i, node := range nodes {
+		log.Printf("Running the node conformance tests on node %d/%d: %s", i+1, len(nodes), node)
+		nodeDir := filepath.Join(outputDir, "node-"+node)
+		settings.Set("output-dir", nodeDir)
+
+		service.CreateNodeConformancePod(clientSet, node)
+		c := newRunClient()
+		c.ClientSet = clientSet
+		collectResults(c, config)
+		service.DeletePods(clientSet)
+		summary.Reconnects += c.Reconnects.Load()
+
+		status := results.PhasePassed
+		if c.ExitCode != 0 {
+			status = results.PhaseFailed
+			if exitCode == 0 {
+				exitCode = c.ExitCode
+			}
+		}
+		summary.Nodes = append(summary.Nodes, results.PhaseResult{Name: node, Status: status, ExitCode: c.ExitCode})
+
+		report, err := results.ReadJUnit(filepath.Join(nodeDir, "junit_01.xml"))
+		if err != nil {
+			log.Printf("unable to read junit report of node %s: %v", node, err)
+			continue
+		}
+		prefixTests(report, fmt.Sprintf("[node %s] ", node))
+		reports = append(reports, report)
+	}
+	settings.Set("output-dir", outputDir)
+	settings.Set("artifacts", artifacts)
+
+	if len(reports) != 0 {
+		log.Println("merging junit reports of all nodes to", filepath.Join(outputDir, "junit_01.xml"))
+		if err := results.WriteJUnit(filepath.Join(outputDir, "junit_01.xml"), results.MergeJUnit(reports...)); err != nil {
+			log.Fatal(err)
+		}
+	}
+	summary.ExitCode = exitCode
+	summary.Failures = failures(outputDir)
+	if err := results.WriteMetadata(outputDir, summary); err != nil {
+		log.Fatal(err)
+	}
+	for _, node := range summary.Nodes {
+		log.Printf("node %s: %s", node.Name, node.Status)
+	}
+	return exitCode
+}
+
+// prefixTests prefixes the names of the tests of the report, so that the
+// results of the same test on several nodes are kept apart
+func prefixTests(report *results.JUnitTestSuites, prefix string) {
+	for i := range report.TestSuites {
+		for j := range report.TestSuites[i].TestCases {
+			report.TestSuites[i].TestCases[j].Name = prefix + report.TestSuites[i].TestCases[j].Name
+		}
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/notify"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+const (
+	// notifySecretEnv holds the secret signing the payloads of --notify-webhook
+	notifySecretEnv = "HYDROPHONE_NOTIFY_SECRET"
+	// notifyBackoff is the delay before the first retry of a failed notification
+	notifyBackoff = 2 * time.Second
+)
+
+// runStarted is the time the run started at, reported to the webhook
+var runStarted = time.Now()
+
+// newWebhooks returns the webhooks of --notify-webhook, --notify-slack-webhook
+// and --notify-teams-webhook.
+func newWebhooks() ([]*notify.Webhook, error) {
+	if settings.GetInt("notify-retries") < 0 {
+		return nil, fmt.Errorf("expected --notify-retries to be at least 0, got %d", settings.GetInt("notify-retries"))
+	}
+	var webhooks []*notify.Webhook
+	for _, target := range []struct {
+		flag   string
+		format notify.Formatter
+	}{
+		{"notify-webhook", notify.JSON},
+		{"notify-slack-webhook", notify.Slack},
+		{"notify-teams-webhook", notify.Teams},
+	} {
+		webhook := settings.GetString(target.flag)
+		if webhook == "" {
+			continue
+		}
+		u, err := url.Parse(webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("expected --%s to be an http or https URL, got %q", target.flag, webhook)
+		}
+		w := &notify.Webhook{
+			URL:     webhook,
+			Format:  target.format,
+			Retries: settings.GetInt("notify-retries"),
+			Backoff: notifyBackoff,
+		}
+		// chat webhooks authenticate with the secret of their URL
+		if target.flag == "notify-webhook" {
+			w.Secret = os.Getenv(notifySecretEnv)
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, nil
+}
+
+// runPayload returns the payload describing the run of the metadata, the
+// tests are counted from the junit report of the output directory.
+func runPayload(outputDir string, metadata *results.Metadata) *notify.Payload {
+	p := &notify.Payload{
+		Status:        notify.StatusPassed,
+		Reason:        metadata.Aborted,
+		ExitCode:      metadata.ExitCode,
+		ServerVersion: metadata.ServerVersion,
+		Cluster:       metadata.Cluster,
+	}
+	if metadata.Hygiene != nil {
+		p.Leaked = metadata.Hygiene.Leaked
+	}
+	switch {
+	case metadata.Aborted != "":
+		p.Status = notify.StatusAborted
+	case metadata.ExitCode != 0:
+		p.Status = notify.StatusFailed
+	}
+	p.SetTimes(runStarted, time.Now())
+	p.Artifacts.OutputDir = outputDir
+	if abs, err := filepath.Abs(outputDir); err == nil {
+		p.Artifacts.OutputDir = abs
+	}
+	for _, failure := range metadata.Failures {
+		p.FailedTests = append(p.FailedTests, failure.Name)
+	}
+	if report, err := results.ReadJUnit(filepath.Join(outputDir, "junit_01.xml")); err == nil {
+		for _, suite := range report.TestSuites {
+			for _, tc := range suite.TestCases {
+				switch tc.Status {
+				case results.StatusPassed:
+					p.Counts.Passed++
+				case results.StatusFailed:
+					p.Counts.Failed++
+				case results.StatusSkipped, results.StatusPending:
+					p.Counts.Skipped++
+				}
+			}
+		}
+	}
+	return p
+}
+
+// notifyRun posts the payload to the webhooks, failures are logged without
+// failing the run.
+func notifyRun(p *notify.Payload) {
+	webhooks, err := newWebhooks()
+	if err != nil {
+		return
+	}
+	for _, webhook := range webhooks {
+		// the URLs of chat webhooks are secrets, they aren't logged
+		host := webhook.URL
+		if u, err := url.Parse(webhook.URL); err == nil {
+			host = u.Host
+		}
+		if err := webhook.Send(context.Background(), p); err != nil {
+			log.Printf("unable to notify %s: %v", host, err)
+			continue
+		}
+		log.Printf("notified %s that the run %s", host, p.Status)
+	}
+}
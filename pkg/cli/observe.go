@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+var (
+	observeSelector  string
+	observeOutputDir string
+)
+
+var observeCmd = &cobra.Command{
+	Use:   "observe",
+	Short: "Report on a conformance run started by other tooling.",
+	Long: `Report on a conformance run started by other tooling.
+
+The pod matching --selector that runs the conformance image is looked up in
+--namespace, or in all namespaces if none is given, e.g. the pod of a Job
+running the tests. Its logs are streamed until the tests complete and the
+same reports as for a run of hydrophone are written to the output directory:
+e2e.log, failures.log, results.json and, if another container of the pod
+still running shares the results directory, junit_01.xml. Nothing is created or changed in
+the cluster. The exit code is the one of the conformance container.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		c := client.NewClient()
+		config, clientSet := service.Init(settings.GetString("kubeconfig"))
+		c.ClientSet = clientSet
+
+		pod, err := client.FindObservedPod(clientSet, settings.GetString("namespace"), observeSelector)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Observing container %s of pod %s/%s running %s", pod.Container, pod.Namespace, pod.Name, pod.Image)
+
+		if err := os.MkdirAll(observeOutputDir, 0755); err != nil {
+			log.Fatalf("error creating output directory [%s] : %v", observeOutputDir, err)
+		}
+		if err := c.Observe(config, pod, observeOutputDir); err != nil {
+			log.Fatal(err)
+		}
+
+		parallel, _ := strconv.Atoi(pod.Env["E2E_PARALLEL"])
+		metadata := &results.Metadata{
+			ConformanceImage: pod.Image,
+			Focus:            pod.Env["E2E_FOCUS"],
+			Skip:             pod.Env["E2E_SKIP"],
+			Seed:             c.Seed,
+			Parallel:         parallel,
+			ExitCode:         c.ExitCode,
+			Reconnects:       c.Reconnects.Load(),
+			Failures:         failures(observeOutputDir),
+		}
+		if err := results.WriteMetadata(observeOutputDir, metadata); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Exiting with code: ", c.ExitCode)
+		log.Exit(c.ExitCode)
+	},
+}
+
+func init() {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	observeCmd.Flags().StringVar(&observeSelector, "selector", "", "label selector of the pod running the conformance image, e.g. app=my-conformance-job.")
+	observeCmd.MarkFlagRequired("selector")
+	observeCmd.Flags().StringVar(&observeOutputDir, "output-dir", workingDir, "directory the reports are written to.")
+
+	rootCmd.AddCommand(observeCmd)
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+	"sigs.k8s.io/hydrophone/pkg/suite"
+)
+
+// resumed is the checkpoint of the run being resumed
+var resumed *results.Checkpoint
+
+var resumeOutputDir string
+
+// resumeSuite runs the specs of the resumed run that didn't pass in chunks,
+// when focusing them by name doesn't fit in a single expression
+var resumeSuite *suite.Suite
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause the run in progress.",
+	Long: `Pause the run in progress.
+
+The tests of the conformance pods are interrupted: the spec in progress is
+stopped and cleaned up, and the report of the completed specs is written. The
+hydrophone process running the tests then saves a checkpoint to its output
+directory instead of reporting a failure, and removes the conformance pods.
+Use hydrophone resume to run the remaining specs. The interrupted spec is run
+again when resuming.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, clientSet := service.Init(settings.GetString("kubeconfig"))
+		common.SetDefaultNamespace()
+		if err := client.Pause(config, clientSet); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Pause requested, the running hydrophone will save a checkpoint once the tests stopped")
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume a paused run from its checkpoint.",
+	Long: `Resume a paused run from its checkpoint.
+
+The checkpoint is read from the output directory of the paused run. The run
+is started again with the same conformance image, namespace, focus, skip and
+arguments, skipping the specs that passed before the pause. The junit report
+of the paused run is merged into the new one.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkpoint, err := results.ReadCheckpoint(resumeOutputDir)
+		if err != nil {
+			log.Fatalf("unable to read the checkpoint of the paused run: %v", err)
+		}
+		resumed = checkpoint
+
+		settings.Set("output-dir", resumeOutputDir)
+		settings.Set("conformance-image", checkpoint.ConformanceImage)
+		settings.Set("namespace", checkpoint.Namespace)
+		settings.Set("run-id", checkpoint.RunID)
+		settings.Set("focus", checkpoint.Focus)
+		settings.Set("parallel", checkpoint.Parallel)
+		settings.Set("verbosity", checkpoint.Verbosity)
+		settings.Set("extra-args", checkpoint.ExtraArgs)
+		settings.Set("extra-ginkgo-args", checkpoint.ExtraGinkgoArgs)
+		addSkipRule("--skip", checkpoint.Skip)
+		focus, skip, s, err := resumeSelection(checkpoint, resumeOutputDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		settings.Set("focus", focus)
+		settings.Set("skip", skip)
+		resumeSuite = s
+		log.Printf("Resuming the run of %s, skipping %d specs that passed", resumeOutputDir, len(checkpoint.Passed))
+
+		c := newRunClient()
+		config, clientSet := service.Init(settings.GetString("kubeconfig"))
+		c.ClientSet = clientSet
+		common.PrintInfo(c.ClientSet, config)
+		runTests(cmd.Context(), c, config)
+		log.Println("Exiting with code: ", c.ExitCode)
+		log.Exit(c.ExitCode)
+	},
+}
+
+// resumeSelection returns the focus, the skip and, when the specs have to be
+// run in chunks, the suite of the resumed run. The specs that passed before
+// the pause are skipped by name. When the skip doesn't fit in MaxFocusLength,
+// the specs that didn't pass are focused by name instead, from the report of
+// the paused run, which lists all the specs.
+func resumeSelection(checkpoint *results.Checkpoint, outputDir string) (string, string, *suite.Suite, error) {
+	if len(checkpoint.Passed) == 0 {
+		return checkpoint.Focus, checkpoint.Skip, nil, nil
+	}
+	passed, err := common.SkipFromTestNames(checkpoint.Passed)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if skip := joinSkip(checkpoint.Skip, passed); len(skip) <= common.MaxFocusLength {
+		addSkipRule("passed before the pause", passed)
+		return checkpoint.Focus, skip, nil, nil
+	}
+
+	report, err := results.ReadJUnit(filepath.Join(outputDir, results.CheckpointJUnit))
+	if err != nil {
+		return "", "", nil, err
+	}
+	remaining, err := common.RemainingTests(results.AllTests(report), checkpoint.Focus, checkpoint.Skip, checkpoint.Passed)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if len(remaining) == 0 {
+		return "", "", nil, fmt.Errorf("all the specs of the run passed before the pause, nothing is left to resume")
+	}
+	focus, err := common.FocusFromTestNames(remaining, common.MaxFocusLength)
+	if err != nil {
+		return "", "", nil, err
+	}
+	log.Printf("Skipping the %d specs that passed takes more than %d bytes, focusing the %d other specs by name instead", len(checkpoint.Passed), common.MaxFocusLength, len(remaining))
+	if len(focus) == 1 {
+		return focus[0], checkpoint.Skip, nil, nil
+	}
+	log.Printf("Focus expression too long, running the specs in %d chunks", len(focus))
+	return checkpoint.Focus, checkpoint.Skip, suite.FromFocus(focus), nil
+}
+
+// handleCheckpoint merges the report of the paused run into the report of
+// the resumed run, and saves a checkpoint if the run was paused again.
+func handleCheckpoint(c *client.Client) error {
+	outputDir := settings.GetString("output-dir")
+	junit := filepath.Join(outputDir, "junit_01.xml")
+	checkpointJUnit := filepath.Join(outputDir, results.CheckpointJUnit)
+
+	if resumed != nil {
+		previous, err := results.ReadJUnit(checkpointJUnit)
+		if err != nil {
+			return err
+		}
+		current, err := results.ReadJUnit(junit)
+		if err != nil {
+			return err
+		}
+		// the specs skipped because they passed before the pause take the
+		// result of the paused run
+		if err := results.WriteJUnit(junit, results.MergeJUnit(current, previous)); err != nil {
+			return err
+		}
+		for _, name := range []string{checkpointJUnit, filepath.Join(outputDir, results.CheckpointFile)} {
+			if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+
+	if !client.PauseRequested(c.ClientSet) {
+		return nil
+	}
+	report, err := results.ReadJUnit(junit)
+	if err != nil {
+		return err
+	}
+	checkpoint := &results.Checkpoint{
+		ConformanceImage: settings.GetString("conformance-image"),
+		Namespace:        settings.GetString("namespace"),
+		RunID:            settings.GetString("run-id"),
+		Focus:            settings.GetString("focus"),
+		Skip:             settings.GetString("skip"),
+		Parallel:         settings.GetString("parallel"),
+		Verbosity:        settings.GetInt("verbosity"),
+		ExtraArgs:        settings.GetStringSlice("extra-args"),
+		ExtraGinkgoArgs:  settings.GetStringSlice("extra-ginkgo-args"),
+		Passed:           results.PassedTests(report),
+	}
+	// the selection of a resumed run stays the one of the paused run
+	if resumed != nil {
+		checkpoint.Focus = resumed.Focus
+		checkpoint.Skip = resumed.Skip
+	}
+	if err := os.Rename(junit, checkpointJUnit); err != nil {
+		return err
+	}
+	if err := results.WriteCheckpoint(outputDir, checkpoint); err != nil {
+		return err
+	}
+
+	metadata, err := results.ReadMetadata(outputDir)
+	if err != nil {
+		return err
+	}
+	metadata.Paused = true
+	metadata.ExitCode = 0
+	if err := results.WriteMetadata(outputDir, metadata); err != nil {
+		return err
+	}
+	c.ExitCode = 0
+	log.Printf("Run paused after %d passed specs, use hydrophone resume --output-dir %s to continue", len(checkpoint.Passed), outputDir)
+	return nil
+}
+
+func init() {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	resumeCmd.Flags().StringVar(&resumeOutputDir, "output-dir", workingDir, "output directory of the paused run, holding its checkpoint.")
+
+	rootCmd.AddCommand(pauseCmd, resumeCmd)
+}
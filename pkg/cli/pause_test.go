@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+// writeCheckpointJUnit writes the report of a paused run with the passed
+// specs and the specs it didn't run
+func writeCheckpointJUnit(t *testing.T, dir string, passed, notRun []string) {
+	var testCases []results.JUnitTestCase
+	for _, name := range passed {
+		testCases = append(testCases, results.JUnitTestCase{Name: "[It] " + name, Status: results.StatusPassed})
+	}
+	for _, name := range notRun {
+		testCases = append(testCases, results.JUnitTestCase{Name: "[It] " + name, Status: results.StatusSkipped, Skipped: &results.JUnitMessage{Message: "skipped"}})
+	}
+	report := &results.JUnitTestSuites{TestSuites: []results.JUnitTestSuite{{Name: "Kubernetes e2e suite", TestCases: testCases}}}
+	require.NoError(t, results.WriteJUnit(dir+"/"+results.CheckpointJUnit, report))
+}
+
+// specNames returns n names of specs, long enough for thousands of them not
+// to fit in a single expression
+func specNames(format string, n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf(format, i)
+	}
+	return names
+}
+
+func TestResumeSelection(t *testing.T) {
+	testCases := []struct {
+		name   string
+		passed int
+		notRun int
+		chunks int
+	}{
+		{name: "few passed specs", passed: 10, notRun: 300},
+		{name: "thousands of passed specs", passed: 5000, notRun: 300, chunks: 1},
+		{name: "thousands of passed and remaining specs", passed: 5000, notRun: 3000, chunks: 4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			passed := specNames("[sig-storage] In-tree Volumes [Driver: local] volume number %05d should store data that can be read back later", tc.passed)
+			notRun := specNames("[sig-storage] CSI Volumes [Driver: csi-hostpath] volume number %05d should be provisioned and mounted by the pods", tc.notRun)
+			// specs of the conformance image the run didn't select
+			other := append(specNames("[sig-network] Services number %05d should serve", 50), "[sig-storage] CSI Volumes flaky volume should be mounted")
+			writeCheckpointJUnit(t, dir, passed, append(notRun, other...))
+			checkpoint := &results.Checkpoint{Focus: `\[sig-storage\]`, Skip: `flaky`, Passed: passed}
+
+			focus, skip, s, err := resumeSelection(checkpoint, dir)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(focus), common.MaxFocusLength)
+			assert.LessOrEqual(t, len(skip), common.MaxFocusLength)
+
+			// the expressions of the phases replace the focus of the run
+			exprs := []string{focus}
+			if tc.chunks > 1 {
+				require.NotNil(t, s)
+				require.Len(t, s.Phases, tc.chunks)
+				exprs = nil
+				for _, phase := range s.Phases {
+					assert.LessOrEqual(t, len(phase.Focus), common.MaxFocusLength)
+					exprs = append(exprs, phase.Focus)
+				}
+			} else {
+				assert.Nil(t, s)
+			}
+			if tc.chunks == 0 {
+				assert.Equal(t, checkpoint.Focus, focus)
+			} else {
+				assert.Equal(t, checkpoint.Skip, skip)
+			}
+
+			// ginkgo runs exactly the specs that didn't pass
+			skipRegexp := regexp.MustCompile(skip)
+			focusRegexps := make([]*regexp.Regexp, len(exprs))
+			for i, expr := range exprs {
+				focusRegexps[i] = regexp.MustCompile(expr)
+			}
+			runs := func(name string) bool {
+				text := "Kubernetes e2e suite " + name
+				if skipRegexp.MatchString(text) {
+					return false
+				}
+				for _, r := range focusRegexps {
+					if r.MatchString(text) {
+						return true
+					}
+				}
+				return false
+			}
+			for _, name := range passed {
+				require.False(t, runs(name), name)
+			}
+			for _, name := range notRun {
+				require.True(t, runs(name), name)
+			}
+			for _, name := range other {
+				require.False(t, runs(name), name)
+			}
+		})
+	}
+}
+
+func TestResumeSelectionAllPassed(t *testing.T) {
+	dir := t.TempDir()
+	passed := specNames("[sig-storage] In-tree Volumes [Driver: local] volume number %05d should store data that can be read back later", 5000)
+	writeCheckpointJUnit(t, dir, passed, nil)
+
+	_, _, _, err := resumeSelection(&results.Checkpoint{Focus: `\[sig-storage\]`, Passed: passed}, dir)
+	assert.EqualError(t, err, "all the specs of the run passed before the pause, nothing is left to resume")
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/plugin"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// validatePlugin loads the plugin of --plugin and checks that the flags of
+// the run apply to it. It returns nil when the e2e tests are run.
+func validatePlugin() (*plugin.Plugin, error) {
+	p, err := service.Plugin()
+	if err != nil || p == nil {
+		return nil, err
+	}
+	if settings.GetInt("shards") > 1 {
+		return nil, fmt.Errorf("--shards splits the e2e tests and can't be used with --plugin")
+	}
+	if settings.GetBool("verify-signature") {
+		return nil, fmt.Errorf("--verify-signature verifies the conformance image and can't be used with --plugin")
+	}
+	log.Printf("Running plugin %s with image %s", p.Name, p.Image)
+	return p, nil
+}
+
+// pluginArtifacts returns the globs of the artifacts of the plugin of
+// --plugin, if any
+func pluginArtifacts() []string {
+	p, err := service.Plugin()
+	if err != nil || p == nil {
+		return nil
+	}
+	return p.Artifacts
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// postProcessors returns the post-processors of the run: the formats of
+// --results-format, then the binaries of --post-process, which are given the
+// exit code of the run in HYDROPHONE_EXIT_CODE.
+func postProcessors(exitCode int) []results.PostProcessor {
+	var processors []results.PostProcessor
+	for _, format := range settings.GetStringSlice("results-format") {
+		// the formats were validated by ValidateArgs
+		if processor, err := results.FormatProcessor(format, settings.GetInt("slowest")); err == nil {
+			processors = append(processors, processor)
+		}
+	}
+	for _, path := range settings.GetStringSlice("post-process") {
+		processor := &results.ExecProcessor{
+			Path:   path,
+			Env:    []string{fmt.Sprintf("HYDROPHONE_EXIT_CODE=%d", exitCode)},
+			Stdout: stdout,
+			Stderr: stderr,
+		}
+		if streamOutput() {
+			processor.Stdout = stderr
+		}
+		processors = append(processors, processor)
+	}
+	return processors
+}
+
+// postProcessResults runs the post-processors of the run on the output
+// directory. A failing post-processor doesn't fail the run.
+func postProcessResults(exitCode int) {
+	dir := settings.GetString("output-dir")
+	for _, processor := range postProcessors(exitCode) {
+		log.Printf("post-processing the results with %s", processor.Name())
+		if err := processor.Process(dir); err != nil {
+			log.Printf("unable to post-process the results with %s: %v", processor.Name(), err)
+		}
+	}
+}
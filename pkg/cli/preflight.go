@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+var preflightCmd = &cobra.Command{
+	Use:   "preflight",
+	Short: "Check that the cluster and the environment are ready for a run.",
+	Long: `Check that the cluster and the environment are ready for a run.
+
+The checks run before the tests unless --skip-preflight is set: the API server
+can be reached, all nodes are ready and enough of them are schedulable for
+--parallel, the conformance image matches the version of the cluster, the
+kubelets are within the supported version skew of the API server, the output
+directory has enough free space, and the namespace of the run can be used with
+its Pod Security level. All problems found are reported at once.
+The settings of the run are read from the config file.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, clientSet := service.Init(settings.GetString("kubeconfig"))
+		common.PrintInfo(clientSet, config)
+		if err := preflight(clientSet); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// preflight runs the preflight checks and logs the problems they found
+func preflight(clientset kubernetes.Interface) error {
+	common.SetDefaultNamespace()
+	problems := service.Preflight(clientset)
+	for _, problem := range problems {
+		log.Printf("preflight %s", problem)
+	}
+	if len(problems) != 0 {
+		return fmt.Errorf("preflight checks found %d problems, use --skip-preflight to run anyway", len(problems))
+	}
+	log.Printf("Preflight checks passed")
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(preflightCmd)
+}
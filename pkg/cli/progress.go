@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"sync"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// progressFinished is the phase of the progress snapshot once the run
+// finished
+const progressFinished = "finished"
+
+// progressCleanup is the phase of the run deleting its resources, the
+// heartbeats stop so that they don't create the config map again
+const progressCleanup = "cleanup"
+
+// runProgress writes the snapshots of the progress of the run, nil with
+// --progress-interval=0
+var runProgress *progressWriter
+
+// progressWriter rewrites the progress snapshot of the run in the output
+// directory every interval, and records it in the cluster with --heartbeat
+type progressWriter struct {
+	c        *client.Client
+	dir      string
+	interval time.Duration
+	mu       sync.Mutex
+	// phase is the step of the run, named after its span
+	phase string
+	stop  func()
+	// heartbeat records the snapshots in the cluster, heartbeatFailed is set
+	// once recording one failed, which is logged once
+	heartbeat       bool
+	heartbeatFailed bool
+}
+
+// startProgress starts writing the progress snapshot of the run to the output
+// directory every --progress-interval.
+func startProgress(c *client.Client) {
+	// the flag was validated by ValidateArgs
+	interval, _ := common.GetDuration("progress-interval")
+	if interval <= 0 {
+		return
+	}
+	p := &progressWriter{c: c, dir: settings.GetString("output-dir"), interval: interval, phase: "preflight", heartbeat: settings.GetBool("heartbeat")}
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				p.write(nil)
+			}
+		}
+	}()
+	p.stop = func() {
+		ticker.Stop()
+		close(done)
+	}
+	runProgress = p
+	p.write(nil)
+}
+
+// setPhase records the step the run is at and writes the snapshot right away
+func (p *progressWriter) setPhase(phase string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.phase = phase
+	p.mu.Unlock()
+	p.write(nil)
+}
+
+// write writes the snapshot, with the exit code once the run finished.
+// Failures are logged, the run goes on without the snapshot.
+func (p *progressWriter) write(exitCode *int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.phase == progressFinished {
+		return
+	}
+	snapshot := &results.Progress{
+		Phase:          p.phase,
+		CurrentTest:    p.c.CurrentSpec(),
+		Total:          p.c.Specs.Total.Load(),
+		Completed:      p.c.Specs.Completed(),
+		Passed:         p.c.Specs.Passed.Load(),
+		Failed:         p.c.Specs.Failed.Load(),
+		Skipped:        p.c.Specs.Skipped.Load(),
+		ElapsedSeconds: time.Since(runStarted).Round(time.Second).Seconds(),
+		ExitCode:       exitCode,
+	}
+	if exitCode != nil {
+		snapshot.Phase, snapshot.CurrentTest = progressFinished, ""
+		p.phase = progressFinished
+	}
+	if err := results.WriteProgress(p.dir, snapshot); err != nil {
+		log.Printf("unable to write the progress of the run: %v", err)
+	}
+	if p.heartbeat && p.c.ClientSet != nil && snapshot.Phase != progressCleanup && snapshot.Phase != progressFinished {
+		if err := service.Heartbeat(p.c.ClientSet, snapshot, p.interval, time.Now()); err != nil && !p.heartbeatFailed {
+			log.Printf("unable to record the heartbeat of the run in the cluster: %v", err)
+			p.heartbeatFailed = true
+		}
+	}
+}
+
+// finishProgress stops the snapshots and writes the last one with the exit
+// code of the run.
+func finishProgress(exitCode int) {
+	p := runProgress
+	if p == nil {
+		return
+	}
+	p.stop()
+	p.write(&exitCode)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/registry"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+const (
+	// resultsArtifactType is the artifact type of the results pushed with --push
+	resultsArtifactType = "application/vnd.sigs.k8s.io.hydrophone.results.v1+tar"
+	// bundleMediaType is the media type of the layer holding the results bundle
+	bundleMediaType = "application/vnd.oci.image.layer.v1.tar+gzip"
+	// attestationMediaType is the media type of the layer holding the
+	// attestation of --attest
+	attestationMediaType = "application/vnd.dev.sigstore.bundle.v0.3+json"
+	// serverVersionAnnotation records the version of the API server the
+	// results were produced against
+	serverVersionAnnotation = "sigs.k8s.io/hydrophone.server-version"
+)
+
+// pushReference returns the image reference of --push, empty when the
+// results aren't pushed
+func pushReference() (string, error) {
+	push := settings.GetString("push")
+	if push == "" {
+		return "", nil
+	}
+	image, ok := strings.CutPrefix(push, "oci://")
+	if !ok {
+		return "", fmt.Errorf("expected --push to be of oci://registry/repository:tag format, got %q", push)
+	}
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid --push: %w", err)
+	}
+	if strings.Contains(ref.Reference, ":") {
+		return "", fmt.Errorf("expected --push to have a tag, got a digest in %q", push)
+	}
+	return image, nil
+}
+
+// pushResults pushes the artifacts of the run in the output directory to the
+// registry as an OCI artifact holding the results bundle, the one of
+// --compress=bundle or one written for the push.
+func pushResults(image, outputDir string) error {
+	bundle := filepath.Join(outputDir, results.BundleFile)
+	if _, err := os.Stat(bundle); err != nil {
+		names, err := runArtifacts(outputDir)
+		if err != nil {
+			return err
+		}
+		f, err := os.CreateTemp("", "hydrophone-results-*.tar.gz")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		if err := results.WriteBundle(f, outputDir, names); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		bundle = f.Name()
+	}
+
+	log.Printf("pushing the results of the run to %s", image)
+	files := []registry.ArtifactFile{{Path: bundle, MediaType: bundleMediaType, Title: results.BundleFile}}
+	if attestation := filepath.Join(outputDir, results.AttestationFile); isFile(attestation) {
+		files = append(files, registry.ArtifactFile{Path: attestation, MediaType: attestationMediaType, Title: results.AttestationFile})
+	}
+	annotations := map[string]string{
+		"org.opencontainers.image.created": time.Now().UTC().Format(time.RFC3339),
+	}
+	if version := settings.GetString("server-git-version"); version != "" {
+		annotations[serverVersionAnnotation] = version
+	}
+	digest, err := registry.NewChecker().PushArtifact(image, resultsArtifactType, files, annotations)
+	if err != nil {
+		return fmt.Errorf("unable to push the results to %s: %w", image, err)
+	}
+	log.Printf("pushed the results of the run to %s", registry.PinDigest(image, digest))
+	return nil
+}
+
+// isFile returns whether the path exists
+func isFile(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// handlePodLost returns the handler of the conformance pods lost before their
+// tests completed according to --reschedule-policy, nil to fail the run.
+func handlePodLost(c *client.Client, config *rest.Config) client.PodLostHandler {
+	policy := settings.GetString("reschedule-policy")
+	switch policy {
+	case common.RescheduleAbort:
+		return func(podName string) (bool, error) {
+			abortPodLost(c, config, fmt.Sprintf("pod %s was lost before the tests completed", podName))
+			return false, nil
+		}
+	case common.RescheduleRecreate, common.RescheduleResume:
+		return func(podName string) (bool, error) {
+			if limit := settings.GetInt64("reschedule-limit"); c.PodRestarts.Load() >= limit {
+				return false, fmt.Errorf("pod %s was lost, the conformance pods were already recreated %d times, see --reschedule-limit", podName, limit)
+			}
+			skip := ""
+			if completed := c.CompletedSpecs(); policy == common.RescheduleResume && len(completed) != 0 {
+				var err error
+				skip, err = common.SkipFromTestNames(completed)
+				if err != nil {
+					return false, err
+				}
+				log.Printf("pod %s was lost, recreating it skipping the %d specs that completed", podName, len(completed))
+			} else {
+				log.Printf("pod %s was lost, recreating it", podName)
+			}
+			return true, service.RecreatePod(c.ClientSet, podName, skip)
+		}
+	default:
+		return nil
+	}
+}
+
+// abortPodLost records the run as aborted after losing a conformance pod and
+// deletes its resources.
+func abortPodLost(c *client.Client, config *rest.Config, reason string) {
+	log.Printf("Aborting the run, %s", reason)
+	c.StopStreaming()
+	recordAbortedRun(c, config, reason, false)
+	service.Cleanup(c.ClientSet)
+	log.Fatal("run aborted after losing a conformance pod")
+}
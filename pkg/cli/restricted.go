@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"path/filepath"
+	"sort"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+// restrictedResults returns the specs of the junit report of the output
+// directory that failed for lack of cluster-scoped permissions and logs the
+// permissions they miss, nil without --restricted.
+func restrictedResults(outputDir string) *results.Restricted {
+	if !common.Restricted() {
+		return nil
+	}
+	restricted := &results.Restricted{}
+	report, err := results.ReadJUnit(filepath.Join(outputDir, "junit_01.xml"))
+	if err != nil {
+		log.Printf("unable to read the tests denied cluster-scoped permissions: %v", err)
+		return restricted
+	}
+	restricted.Denied = results.DeniedSpecs(report)
+	if len(restricted.Denied) == 0 {
+		return restricted
+	}
+
+	counts := results.DeniedPermissions(restricted.Denied)
+	permissions := make([]string, 0, len(counts))
+	for permission := range counts {
+		permissions = append(permissions, permission)
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		if counts[permissions[i]] != counts[permissions[j]] {
+			return counts[permissions[i]] > counts[permissions[j]]
+		}
+		return permissions[i] < permissions[j]
+	})
+	log.Printf("%d specs can't run with the permissions of --restricted, recorded in %s. Cluster-scoped permissions they miss:", len(restricted.Denied), results.MetadataFile)
+	for _, permission := range permissions {
+		log.Printf("  %s: %d specs", permission, counts[permission])
+	}
+	return restricted
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+var (
+	resultsOutput  string
+	resultsSlowest int
+	mergePolicy    string
+	mergeOutput    string
+)
+
+var resultsCmd = &cobra.Command{
+	Use:   "results [PATH]",
+	Short: "Work with the results of the runs.",
+	Long: `Work with the results of the runs.
+
+Every run writes results.json next to its artifacts. The file follows a
+versioned JSON schema, its schemaVersion is only incremented on incompatible
+changes so that tooling built on top of hydrophone can rely on it.
+
+Given a path, the results of a run are summarized without running anything.
+The path is a junit report, an e2e.log, or a directory holding either, e.g.
+the output directory of hydrophone or the extracted results of sonobuoy. The
+summary is printed as text, as JSON in the format of results.json, or as an
+HTML page. e2e.log only names the specs ginkgo printed the names of, the
+failed and slow ones unless the tests ran in verbose mode.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Help()
+			return
+		}
+		if resultsOutput != "text" && resultsOutput != "json" && resultsOutput != "html" {
+			log.Fatalf("expected --output to be text, json or html, got %q", resultsOutput)
+		}
+		report, source, err := results.LoadReport(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		summary, err := results.Summarize(report, source, resultsSlowest)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// the run is identified by the results.json next to the report
+		if metadata, err := results.ReadMetadata(filepath.Dir(source)); err == nil {
+			summary.Labels, summary.CI = metadata.Labels, metadata.CI
+		}
+		switch resultsOutput {
+		case "json":
+			enc := json.NewEncoder(stdout)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(summary.Metadata())
+		case "html":
+			err = summary.WriteHTML(stdout)
+		default:
+			err = summary.WriteText(stdout)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var resultsValidateCmd = &cobra.Command{
+	Use:   "validate DIR",
+	Short: "Validate the results of a run against the schema.",
+	Long: `Validate the results of a run against the schema.
+
+results.json of the output directory of the run, or of its results.tar.gz, is
+checked against the schema of results, junit_01.xml and skipped.json are
+checked to be readable. The problems found are printed and the command fails
+when there are any.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		problems, err := results.Validate(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, problem := range problems {
+			fmt.Fprintln(stdout, problem)
+		}
+		if len(problems) > 0 {
+			log.Fatalf("the results in %s are invalid, %d problems found", args[0], len(problems))
+		}
+		log.Printf("the results in %s are valid for schema version %d", args[0], results.SchemaVersion)
+	},
+}
+
+var resultsSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON schema of results.json.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := stdout.Write(results.Schema); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var resultsMergeCmd = &cobra.Command{
+	Use:   "merge FILE...",
+	Short: "Merge junit reports into a single report.",
+	Long: `Merge junit reports into a single report.
+
+The junit reports of the shards of a run, of reruns of its failed tests or of
+runs retried as a whole are combined into a single report. Sharded runs
+report the specs they didn't select as skipped, the result of the report
+that ran a spec wins. Of a spec that ran in more than one report, the result
+chosen by --policy is kept: best keeps a passed result over a failed one,
+worst a failed result over a passed one, and last the result of the last
+report given.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var reports []*results.JUnitTestSuites
+		for _, path := range args {
+			report, err := results.ReadJUnit(path)
+			if err != nil {
+				log.Fatal(err)
+			}
+			reports = append(reports, report)
+		}
+		merged, err := results.MergeRetriedJUnit(mergePolicy, reports...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if mergeOutput == "" {
+			if err := results.EncodeJUnit(stdout, merged); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		if err := results.WriteJUnit(mergeOutput, merged); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("merged %d reports into %s: %d tests, %d failed", len(reports), mergeOutput, merged.Tests, merged.Failures+merged.Errors)
+	},
+}
+
+func init() {
+	resultsCmd.Flags().StringVarP(&resultsOutput, "output", "o", "text", "output format of the summary of the results, text, json or html.")
+	resultsCmd.Flags().IntVar(&resultsSlowest, "slowest", 10, "number of the slowest specs listed in the summary.")
+
+	resultsMergeCmd.Flags().StringVar(&mergePolicy, "policy", results.MergeBest, fmt.Sprintf("result kept of a spec that ran in more than one report, %s, %s or %s.", results.MergeBest, results.MergeWorst, results.MergeLast))
+	resultsMergeCmd.Flags().StringVar(&mergeOutput, "output-file", "", "file the merged report is written to, the report is printed when empty.")
+
+	resultsCmd.AddCommand(resultsValidateCmd, resultsSchemaCmd, resultsMergeCmd)
+	rootCmd.AddCommand(resultsCmd)
+}
@@ -0,0 +1,838 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/history"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/redact"
+	"sigs.k8s.io/hydrophone/pkg/registry"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+var (
+	cfgFile          string
+	parallel         string
+	verbosity        int
+	outputDir        string
+	cleanup          bool
+	listImages       bool
+	focus            string
+	skip             string
+	conformanceImage string
+	busyboxImage     string
+	namespace        string
+	dryRun           string
+	testRepoList     string
+	testRepo         string
+	seed             int64
+	shards           int
+	strictCompat     bool
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "hydrophone",
+	Short: "Hydrophone is a lightweight runner for kubernetes tests.",
+	Long: `Hydrophone is a lightweight runner for kubernetes tests.
+
+Every flag can also be set with an environment variable, HYDROPHONE_ followed
+by the name of the flag upper-cased with dashes replaced by underscores, e.g.
+HYDROPHONE_OUTPUT_DIR for --output-dir. Lists are separated by spaces. Flags
+take precedence over environment variables, which take precedence over the
+config file. --cleanup and --list-images are only taken from the command line.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if err := redact.Configure(settings.GetStringSlice("redact-pattern")); err != nil {
+			log.Fatal(err)
+		}
+		if err := common.ResolveRunID(); err != nil {
+			log.Fatal(err)
+		}
+		// the settings of hydrophone are read from the environment, the
+		// flags of the subcommands are set here
+		if !cmd.HasParent() {
+			return
+		}
+		flags := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+			if cmd.Root().PersistentFlags().Lookup(flag.Name) != flag {
+				flags.AddFlag(flag)
+			}
+		})
+		if err := common.SetFlagsFromEnv(flags); err != nil {
+			log.Fatal(err)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := validateCleanupFlags(); err != nil {
+			log.Fatal(err)
+		}
+		if err := common.ValidateRestricted(); err != nil {
+			log.Fatal(err)
+		}
+		if spec := settings.GetString("schedule"); spec != "" {
+			if err := runScheduled(spec); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		switch mode := settings.GetString("dry-run"); mode {
+		case common.DryRunNone, common.DryRunServer:
+		case common.DryRunClient:
+			if err := renderManifests(); err != nil {
+				log.Fatal(err)
+			}
+			return
+		default:
+			log.Fatalf("expected --dry-run to be %s, %s or %s, got %q", common.DryRunNone, common.DryRunClient, common.DryRunServer, mode)
+		}
+
+		client := newRunClient()
+		config, clientSet := service.Init(settings.GetString("kubeconfig"))
+		client.ClientSet = clientSet
+		common.PrintInfo(client.ClientSet, config)
+		if settings.GetString("dry-run") == common.DryRunServer {
+			if err := validateManifests(client.ClientSet); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		if cleanup {
+			common.SetDefaultNamespace()
+			if settings.GetBool("deep") {
+				// the test namespaces are among the leaked resources
+				if !settings.GetBool("list-only") {
+					service.Cleanup(client.ClientSet)
+				}
+				if err := deepCleanup(client.ClientSet); err != nil {
+					log.Fatal(err)
+				}
+			} else {
+				service.Cleanup(client.ClientSet)
+				// the test namespaces are listed at the cluster scope
+				if !common.Restricted() {
+					if err := service.CleanupTestNamespaces(client.ClientSet, settings.GetInt("cleanup-concurrency")); err != nil {
+						log.Fatal(err)
+					}
+				}
+			}
+		} else if listImages {
+			service.PrintListImages(client.ClientSet)
+		} else {
+			addSkipRule("--skip", settings.GetString("skip"))
+			runTests(cmd.Context(), client, config)
+		}
+		log.Println("Exiting with code: ", client.ExitCode)
+		log.Exit(client.ExitCode)
+	},
+}
+
+var (
+	// stdout and stderr are the standard output and error of the command,
+	// the writer of Run in process
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+	// invocation are the arguments of the command, the arguments of Run in
+	// process
+	invocation = os.Args[1:]
+	// inProcess is set during Run, the signals of the process are left to
+	// the program running hydrophone then
+	inProcess bool
+	// runMu serializes the runs in process, which share the flags, the
+	// settings and the state of the command
+	runMu sync.Mutex
+)
+
+// Execute adds all child commands to the root command and sets flags appropriately.
+// This is called by cmd.Execute(). It only needs to happen once to the rootCmd.
+func Execute() {
+	if err := rootCmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Command returns the hydrophone command, for cmd to add the commands running
+// several hydrophone runs.
+func Command() *cobra.Command {
+	return rootCmd
+}
+
+// Run runs the hydrophone command with the arguments in the current process
+// and returns its exit code, instead of exiting the process like Execute.
+// The output and the log of the command, the logs of the conformance pods
+// included, are written to w. The flags and the settings start from their
+// defaults, the HYDROPHONE_ environment variables and the config file are
+// read like by the command. Canceling ctx interrupts the run like SIGINT
+// interrupts the command, the signals of the process are left to the caller.
+// The runs in process don't overlap, Run waits for the previous one to end.
+func Run(ctx context.Context, args []string, w io.Writer) int {
+	runMu.Lock()
+	defer runMu.Unlock()
+
+	restore := settings.Isolate()
+	defer restore()
+	resetFlags(rootCmd)
+	resetRun()
+	stdout, stderr, invocation, inProcess = w, w, args, true
+	defer func() {
+		stdout, stderr, invocation, inProcess = os.Stdout, os.Stderr, os.Args[1:], false
+	}()
+	// nil arguments are the arguments of the process to cobra
+	rootCmd.SetArgs(append([]string{}, args...))
+	rootCmd.SetOut(w)
+	rootCmd.SetErr(w)
+	defer func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+	}()
+	return log.RunInProcess(w, func() {
+		if err := rootCmd.ExecuteContext(ctx); err != nil {
+			log.Fatal(err)
+		}
+	})
+}
+
+// resetFlags sets the flags of the command and of its subcommands set by the
+// previous run in process back to their defaults
+func resetFlags(cmd *cobra.Command) {
+	reset := func(flag *pflag.Flag) {
+		if !flag.Changed {
+			return
+		}
+		if value, ok := flag.Value.(pflag.SliceValue); ok {
+			var defaults []string
+			if values := strings.Trim(flag.DefValue, "[]"); values != "" {
+				defaults = strings.Split(values, ",")
+			}
+			_ = value.Replace(defaults)
+		} else {
+			_ = flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	}
+	cmd.PersistentFlags().VisitAll(reset)
+	cmd.Flags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// resetRun discards the state of the previous run in process
+func resetRun() {
+	// --config falls back to HYDROPHONE_CONFIG in initConfig
+	cfgFile = ""
+	runStarted = time.Now()
+	clusterSnapshot, skipRules = nil, nil
+	resumed, resumeSuite = nil, nil
+	githubCheck, runMetrics, runProgress = nil, nil, nil
+	runTracer, runSpan = nil, nil
+}
+
+// isTerminal reports whether w is a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && isatty.IsTerminal(f.Fd())
+}
+
+func init() {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cobra.OnInitialize(initConfig)
+
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", fmt.Sprintf("config file mapping the flags to their values. defaults to hydrophone.yaml of the working directory or of %s.", filepath.Join(xdg.ConfigHome, "hydrophone")))
+
+	rootCmd.PersistentFlags().String("profile", "", "profile of the config file whose settings take precedence over the other settings of the file, e.g. smoke or certified.")
+	settings.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+	rootCmd.PersistentFlags().String("kubeconfig", "", "path to the kubeconfig file, or a list of kubeconfig files merged like KUBECONFIG.")
+	settings.BindPFlag("kubeconfig", rootCmd.PersistentFlags().Lookup("kubeconfig"))
+	rootCmd.PersistentFlags().String("context", "", "context of the kubeconfig to use instead of its current context.")
+	settings.BindPFlag("context", rootCmd.PersistentFlags().Lookup("context"))
+	rootCmd.PersistentFlags().String("cluster", "", "cluster of the kubeconfig to use instead of the cluster of the context.")
+	settings.BindPFlag("cluster", rootCmd.PersistentFlags().Lookup("cluster"))
+	rootCmd.PersistentFlags().String("as", "", "user to impersonate for the requests to the API server.")
+	settings.BindPFlag("as", rootCmd.PersistentFlags().Lookup("as"))
+	rootCmd.PersistentFlags().StringSlice("as-group", []string{}, "group to impersonate for the requests to the API server, requires --as. can be repeated.")
+	settings.BindPFlag("as-group", rootCmd.PersistentFlags().Lookup("as-group"))
+	rootCmd.PersistentFlags().String("as-uid", "", "UID to impersonate for the requests to the API server, requires --as.")
+	settings.BindPFlag("as-uid", rootCmd.PersistentFlags().Lookup("as-uid"))
+	rootCmd.PersistentFlags().String("proxy-url", "", "proxy the requests to the API server are sent through, an http, https or socks5 URL, e.g. socks5://bastion:1080. replaces the proxy of HTTPS_PROXY and NO_PROXY.")
+	settings.BindPFlag("proxy-url", rootCmd.PersistentFlags().Lookup("proxy-url"))
+	rootCmd.PersistentFlags().String("certificate-authority", "", "CA bundle verifying the certificate of the API server, replacing the one of the kubeconfig.")
+	settings.BindPFlag("certificate-authority", rootCmd.PersistentFlags().Lookup("certificate-authority"))
+	rootCmd.PersistentFlags().String("tls-server-name", "", "server name the certificate of the API server is verified against, instead of the host of its URL.")
+	settings.BindPFlag("tls-server-name", rootCmd.PersistentFlags().Lookup("tls-server-name"))
+	rootCmd.PersistentFlags().Bool("insecure-skip-tls-verify", false, "don't verify the certificate of the API server. insecure, only use it with test clusters.")
+	settings.BindPFlag("insecure-skip-tls-verify", rootCmd.PersistentFlags().Lookup("insecure-skip-tls-verify"))
+	rootCmd.PersistentFlags().Float64("kube-api-qps", 5, "queries per second the client sends to the API server at most, averaged over time.")
+	settings.BindPFlag("kube-api-qps", rootCmd.PersistentFlags().Lookup("kube-api-qps"))
+	rootCmd.PersistentFlags().Int("kube-api-burst", 10, "queries the client sends to the API server at most in a burst.")
+	settings.BindPFlag("kube-api-burst", rootCmd.PersistentFlags().Lookup("kube-api-burst"))
+	common.DurationFlag(rootCmd.PersistentFlags(), "request-timeout", 0, "time after which a request to the API server is abandoned, e.g. 30s. log streams and watches reaching it are re-established. 0 disables the timeout.")
+	settings.BindPFlag("request-timeout", rootCmd.PersistentFlags().Lookup("request-timeout"))
+	rootCmd.PersistentFlags().Int("api-retries", 5, "number of times a pod creation or an exec into a pod failing with a transient error is retried, e.g. when the API server throttles requests, fails with a server error or resets the connection.")
+	settings.BindPFlag("api-retries", rootCmd.PersistentFlags().Lookup("api-retries"))
+
+	rootCmd.Flags().StringVar(&parallel, "parallel", "1", fmt.Sprintf("number of parallel threads in test framework. %q picks a value based on the number of schedulable nodes.", common.ParallelAuto))
+	settings.BindPFlag("parallel", rootCmd.Flags().Lookup("parallel"))
+
+	rootCmd.Flags().IntVar(&verbosity, "verbosity", 4, "verbosity of test framework.")
+	settings.BindPFlag("verbosity", rootCmd.Flags().Lookup("verbosity"))
+
+	rootCmd.Flags().Bool("verbose", false, "log the command line and the environment of the e2e tests in each conformance pod before creating it. --dry-run always logs them.")
+	settings.BindPFlag("verbose", rootCmd.Flags().Lookup("verbose"))
+
+	rootCmd.Flags().StringVar(&outputDir, "output-dir", workingDir, "directory for logs. {cluster}, {version} and {timestamp} are replaced with the name of the cluster in the kubeconfig, its version and the start of the run, e.g. ./results/{cluster}/{version}/{timestamp}. when it holds the artifacts of a previous run they are written to the first of <dir>-1, <dir>-2, ... that doesn't.")
+	settings.BindPFlag("output-dir", rootCmd.Flags().Lookup("output-dir"))
+
+	rootCmd.Flags().String("output", "", "- writes the artifacts of the run to stdout as a gzipped tarball at the end of the run, e.g. to pipe them to an object store. the logs of the tests are written to stderr instead.")
+	settings.BindPFlag("output", rootCmd.Flags().Lookup("output"))
+
+	rootCmd.Flags().Bool("force", false, "overwrite the artifacts of a previous run in --output-dir.")
+	settings.BindPFlag("force", rootCmd.Flags().Lookup("force"))
+
+	rootCmd.Flags().BoolVar(&cleanup, "cleanup", false, "cleanup resources (pods, namespaces etc).")
+
+	rootCmd.Flags().Int("cleanup-concurrency", 10, "number of namespaces left behind by the tests that --cleanup deletes at the same time.")
+	settings.BindPFlag("cleanup-concurrency", rootCmd.Flags().Lookup("cleanup-concurrency"))
+
+	rootCmd.Flags().Bool("deep", false, "with --cleanup also delete the resources the tests of aborted runs left behind: test namespaces, their persistent volume claims, persistent volumes, cluster roles, cluster role bindings and admission webhooks.")
+	settings.BindPFlag("deep", rootCmd.Flags().Lookup("deep"))
+
+	rootCmd.Flags().Bool("detect-leaks", true, "once the tests completed, look for the resources they left behind: test namespaces, their persistent volume claims, persistent volumes, cluster roles and bindings, and admission webhooks. they are listed in the cluster hygiene of results.json.")
+	settings.BindPFlag("detect-leaks", rootCmd.Flags().Lookup("detect-leaks"))
+
+	rootCmd.Flags().Bool("delete-leaks", false, "delete the resources the tests left behind found by --detect-leaks.")
+	settings.BindPFlag("delete-leaks", rootCmd.Flags().Lookup("delete-leaks"))
+
+	rootCmd.Flags().Bool("list-only", false, "with --cleanup --deep list the leaked resources without deleting anything.")
+	settings.BindPFlag("list-only", rootCmd.Flags().Lookup("list-only"))
+
+	rootCmd.Flags().BoolVar(&listImages, "list-images", false, "list all images that will be used during conformance tests.")
+	rootCmd.Flags().MarkDeprecated("list-images", "use the list-images command instead.")
+
+	rootCmd.Flags().Bool("conformance", false, "run conformance tests.")
+	settings.BindPFlag("conformance", rootCmd.Flags().Lookup("conformance"))
+
+	rootCmd.Flags().Bool("certified", false, "refuse to run unless the configuration is the one a CNCF Certified Kubernetes submission requires: all conformance tests, no skip, run serially with a conformance image of registry.k8s.io and without pod patches or extra args. recorded in results.json.")
+	settings.BindPFlag("certified", rootCmd.Flags().Lookup("certified"))
+
+	rootCmd.PersistentFlags().StringVar(&focus, "focus", "", "focus runs a specific e2e test. e.g. - sig-auth. allows regular expressions.")
+	settings.BindPFlag("focus", rootCmd.PersistentFlags().Lookup("focus"))
+
+	rootCmd.Flags().String("focus-file", "", "file with a newline-delimited list of exact test names to run. lines starting with # are ignored.")
+	settings.BindPFlag("focus-file", rootCmd.Flags().Lookup("focus-file"))
+
+	rootCmd.PersistentFlags().StringSlice("sig", []string{}, fmt.Sprintf("run the tests of the given SIGs, e.g. network. combined with --conformance only conformance tests are run. one of %s.", strings.Join(common.SIGs, ", ")))
+	settings.BindPFlag("sig", rootCmd.PersistentFlags().Lookup("sig"))
+
+	rootCmd.PersistentFlags().StringSlice("behavior", []string{}, fmt.Sprintf("run the tests with any of the given tags, e.g. Serial. one of %s or Feature:<name>.", strings.Join(common.Behaviors, ", ")))
+	settings.BindPFlag("behavior", rootCmd.PersistentFlags().Lookup("behavior"))
+
+	rootCmd.PersistentFlags().StringVar(&skip, "skip", "", "skip specific tests. allows regular expressions.")
+	settings.BindPFlag("skip", rootCmd.PersistentFlags().Lookup("skip"))
+
+	rootCmd.RegisterFlagCompletionFunc("focus", completeTestSelection)
+	rootCmd.RegisterFlagCompletionFunc("skip", completeTestSelection)
+
+	rootCmd.PersistentFlags().String("skip-file", "", "file with a newline-delimited list of regular expressions of tests to skip, merged with --skip. lines starting with # are ignored.")
+	settings.BindPFlag("skip-file", rootCmd.PersistentFlags().Lookup("skip-file"))
+
+	rootCmd.PersistentFlags().StringVar(&conformanceImage, "conformance-image", "", "specify a conformance container image of your choice, by tag or by digest.")
+	settings.BindPFlag("conformance-image", rootCmd.PersistentFlags().Lookup("conformance-image"))
+
+	rootCmd.PersistentFlags().String("version-policy", common.VersionPolicyMatchServer, fmt.Sprintf("how the tag of the default conformance image is derived from the server version. %s uses the server version, %s the newest patch of the minor version of the server published in the registry, for managed clusters running patches without a conformance image. %s derives nothing and requires --conformance-image.", common.VersionPolicyMatchServer, common.VersionPolicyLatestPatch, common.VersionPolicyPinned))
+	settings.BindPFlag("version-policy", rootCmd.PersistentFlags().Lookup("version-policy"))
+
+	rootCmd.PersistentFlags().StringVar(&busyboxImage, "busybox-image", "", "specify an alternate busybox container image, e.g. of a private mirror, optionally pinned by digest as image@sha256:.... the image is checked to exist before the tests and pinned to its digest.")
+	settings.BindPFlag("busybox-image", rootCmd.PersistentFlags().Lookup("busybox-image"))
+
+	rootCmd.PersistentFlags().StringVar(&namespace, "namespace", "", "the namespace where the conformance pod is created.")
+	settings.BindPFlag("namespace", rootCmd.PersistentFlags().Lookup("namespace"))
+	rootCmd.PersistentFlags().String("run-id", "", fmt.Sprintf("ID isolating the run from the other runs on the cluster: the namespace defaults to %s-<id>, the cluster role and its binding are suffixed with it and the resources of the run are labeled %s=<id>. %s generates one. pass the same ID to the commands targeting the run, e.g. --cleanup.", common.DefaultNamespace, common.RunIDLabel, common.RunIDAuto))
+	settings.BindPFlag("run-id", rootCmd.PersistentFlags().Lookup("run-id"))
+
+	rootCmd.PersistentFlags().StringSlice("redact-pattern", nil, "regular expression whose matches are replaced by [REDACTED] in the log, the streamed output and the text artifacts of the run, on top of the bearer tokens and the credentials of kubeconfigs and registry configs. can be repeated.")
+	settings.BindPFlag("redact-pattern", rootCmd.PersistentFlags().Lookup("redact-pattern"))
+
+	rootCmd.Flags().String("node-os", common.NodeOSLinux, fmt.Sprintf("operating system of the nodes targeted by the tests, %s or %s. with %s the conformance pod runs on a linux node and [LinuxOnly] tests are skipped.", common.NodeOSLinux, common.NodeOSWindows, common.NodeOSWindows))
+	settings.BindPFlag("node-os", rootCmd.Flags().Lookup("node-os"))
+	rootCmd.Flags().String("ip-family", "", fmt.Sprintf("IP family of the cluster, %s, %s or %s. single-stack clusters skip the tests of the other family and the dual-stack tests, with %s --focus defaults to the conformance and dual-stack tests.", common.IPFamilyIPv4, common.IPFamilyIPv6, common.IPFamilyDual, common.IPFamilyDual))
+	settings.BindPFlag("ip-family", rootCmd.Flags().Lookup("ip-family"))
+
+	rootCmd.PersistentFlags().String("arch", "", "architecture of the nodes the pods run on, e.g. arm64. by default the pods are only pinned to an architecture when some nodes can't run the conformance or busybox image.")
+	settings.BindPFlag("arch", rootCmd.PersistentFlags().Lookup("arch"))
+
+	rootCmd.Flags().StringSlice("artifacts", []string{}, "globs of the files of the results directory of the conformance pod to download instead of e2e.log, e.g. *.log or hostlogs/**. ** matches any number of directories. junit_01.xml is always downloaded.")
+	settings.BindPFlag("artifacts", rootCmd.Flags().Lookup("artifacts"))
+	rootCmd.Flags().String("artifact-transfer", common.ArtifactTransferSidecar, fmt.Sprintf("how the artifacts are fetched from the conformance pod, %s or %s. %s reads them through a busybox output container, %s streams them as a tar from the conformance container, leaving out the output container. %s requires a shell and tar in the conformance image.", common.ArtifactTransferSidecar, common.ArtifactTransferExec, common.ArtifactTransferSidecar, common.ArtifactTransferExec, common.ArtifactTransferExec))
+	settings.BindPFlag("artifact-transfer", rootCmd.Flags().Lookup("artifact-transfer"))
+
+	rootCmd.Flags().Int("artifact-retries", 3, "number of times fetching the artifacts of a conformance pod is retried with a backoff when it fails, e.g. when an exec times out. the log of the conformance container is then recovered from the log API as e2e.log and the results are marked as partial in results.json.")
+	settings.BindPFlag("artifact-retries", rootCmd.Flags().Lookup("artifact-retries"))
+
+	rootCmd.Flags().String("transfer-rate-limit", "", "maximum throughput of the log streams and artifact downloads of the run, e.g. 10MiB/s, shared by all transfers. empty or 0 doesn't limit the throughput.")
+	settings.BindPFlag("transfer-rate-limit", rootCmd.Flags().Lookup("transfer-rate-limit"))
+
+	rootCmd.Flags().String("compress", common.CompressNone, fmt.Sprintf("compress the artifacts of the run. %s gzips e2e.log to %s as it is downloaded, %s bundles all artifacts of the output directory into %s at the end of the run.", common.CompressGzip, client.E2ELogGzip, common.CompressBundle, results.BundleFile))
+	rootCmd.Flags().Lookup("compress").NoOptDefVal = common.CompressGzip
+	settings.BindPFlag("compress", rootCmd.Flags().Lookup("compress"))
+
+	rootCmd.Flags().StringSlice("results-format", nil, fmt.Sprintf("additional formats of the results of the tests, written next to the junit report once the run completed. %s writes %s with one row per test: its name, SIG, status, duration in seconds and the first line of its failure message. %s writes the summary of the run to %s.", common.ResultsFormatCSV, results.CSVFile, common.ResultsFormatHTML, results.HTMLFile))
+	settings.BindPFlag("results-format", rootCmd.Flags().Lookup("results-format"))
+
+	rootCmd.Flags().StringArray("post-process", nil, "binary run once the run completed with the output directory as argument and in HYDROPHONE_RESULTS_DIR, and the exit code of the run in HYDROPHONE_EXIT_CODE, e.g. to convert or upload the results. can be repeated, the binaries run in order after the formats of --results-format. their failures are logged without failing the run.")
+	settings.BindPFlag("post-process", rootCmd.Flags().Lookup("post-process"))
+
+	rootCmd.Flags().StringVar(&dryRun, "dry-run", common.DryRunNone, fmt.Sprintf("render the resources of the run without creating them. %s prints them and writes them to %s in the output directory without connecting to the cluster, %s submits them to the API server with the server-side dry run option so that its validation and admission webhooks check them.", common.DryRunClient, common.ManifestsFile, common.DryRunServer))
+	rootCmd.Flags().Lookup("dry-run").NoOptDefVal = common.DryRunClient
+	settings.BindPFlag("dry-run", rootCmd.Flags().Lookup("dry-run"))
+
+	rootCmd.Flags().Bool("ginkgo-dry-run", false, "run the conformance image in dry run mode, the selected tests are reported without running them.")
+	settings.BindPFlag("ginkgo-dry-run", rootCmd.Flags().Lookup("ginkgo-dry-run"))
+
+	rootCmd.Flags().StringVar(&testRepoList, "test-repo-list", "", "yaml file to override registries for test images, mounted into the conformance pod as KUBE_TEST_REPO_LIST.")
+	settings.BindPFlag("test-repo-list", rootCmd.Flags().Lookup("test-repo-list"))
+
+	rootCmd.Flags().String("storage-testdriver", "", "test driver manifest of a CSI driver for the external storage tests, mounted into the conformance pod and passed as --storage.testdriver. --focus defaults to the External.Storage tests.")
+	settings.BindPFlag("storage-testdriver", rootCmd.Flags().Lookup("storage-testdriver"))
+
+	rootCmd.Flags().StringVar(&testRepo, "test-repo", "", "alternate registry for test images, passed to the conformance pod as KUBE_TEST_REPO.")
+	settings.BindPFlag("test-repo", rootCmd.Flags().Lookup("test-repo"))
+
+	rootCmd.Flags().Bool("verify-images", false, "check that the conformance, busybox and test images exist in their registries, after applying --test-repo-list, before starting the tests.")
+	settings.BindPFlag("verify-images", rootCmd.Flags().Lookup("verify-images"))
+
+	rootCmd.Flags().Bool("verify-signature", false, "verify the cosign signature of the conformance image and pin it to the verified digest before creating the pods. requires cosign in PATH.")
+	settings.BindPFlag("verify-signature", rootCmd.Flags().Lookup("verify-signature"))
+
+	rootCmd.Flags().String("certificate-identity", registry.KubernetesReleaseIdentity, "identity expected in the signing certificate of the conformance image.")
+	settings.BindPFlag("certificate-identity", rootCmd.Flags().Lookup("certificate-identity"))
+
+	rootCmd.Flags().String("certificate-oidc-issuer", registry.KubernetesReleaseOIDCIssuer, "OIDC issuer expected in the signing certificate of the conformance image.")
+	settings.BindPFlag("certificate-oidc-issuer", rootCmd.Flags().Lookup("certificate-oidc-issuer"))
+
+	rootCmd.Flags().StringSlice("node-selector", []string{}, "label of the nodes the conformance pods run on, as key=value. can be repeated.")
+	settings.BindPFlag("node-selector", rootCmd.Flags().Lookup("node-selector"))
+
+	rootCmd.Flags().StringSlice("toleration", []string{}, "taint tolerated by the conformance pods, as key[=value][:effect]. can be repeated. replaces the default toleration of every taint.")
+	settings.BindPFlag("toleration", rootCmd.Flags().Lookup("toleration"))
+
+	rootCmd.Flags().String("affinity-file", "", "yaml file with the affinity of the conformance pods.")
+	settings.BindPFlag("affinity-file", rootCmd.Flags().Lookup("affinity-file"))
+
+	rootCmd.Flags().String("on-interrupt", common.OnInterruptCleanup, fmt.Sprintf("what happens to the resources of the run when hydrophone is interrupted with SIGINT or SIGTERM, after the partial results were collected. one of %s or %s.", common.OnInterruptCleanup, common.OnInterruptKeep))
+	settings.BindPFlag("on-interrupt", rootCmd.Flags().Lookup("on-interrupt"))
+
+	common.DurationFlag(rootCmd.Flags(), "startup-timeout", 0, "time within which the conformance pods have to be running, e.g. 15m. the run is aborted with the reason the pods are pending otherwise. 0 waits forever.")
+	settings.BindPFlag("startup-timeout", rootCmd.Flags().Lookup("startup-timeout"))
+
+	common.DurationFlag(rootCmd.Flags(), "timeout", 0, "deadline of the whole run, e.g. 6h. the run is aborted after collecting the partial logs and artifacts, and recorded as timed out in results.json. 0 disables the deadline.")
+	settings.BindPFlag("timeout", rootCmd.Flags().Lookup("timeout"))
+
+	rootCmd.Flags().Bool("fail-fast", false, "abort the run at the first failed test, like --max-failures=1. ginkgo stops running tests at the first failure as well.")
+	settings.BindPFlag("fail-fast", rootCmd.Flags().Lookup("fail-fast"))
+
+	rootCmd.Flags().Int("max-failures", 0, "abort the run once the given number of tests failed, after collecting the partial logs and artifacts. the run is recorded as aborted in results.json. 0 runs all tests.")
+	settings.BindPFlag("max-failures", rootCmd.Flags().Lookup("max-failures"))
+
+	rootCmd.Flags().Bool("skip-preflight", false, "start the run without the preflight checks of the cluster and the environment, see hydrophone preflight.")
+	settings.BindPFlag("skip-preflight", rootCmd.Flags().Lookup("skip-preflight"))
+
+	rootCmd.Flags().Bool("strict-skew", false, "fail the preflight checks when the kubelet of a node is outside of the supported version skew of the API server, instead of warning.")
+	settings.BindPFlag("strict-skew", rootCmd.Flags().Lookup("strict-skew"))
+
+	rootCmd.Flags().String("workload", common.WorkloadPod, fmt.Sprintf("how the conformance pods are run, %s creates bare pods, %s creates jobs that replace the pods when they are lost, e.g. because their node was recycled.", common.WorkloadPod, common.WorkloadJob))
+	settings.BindPFlag("workload", rootCmd.Flags().Lookup("workload"))
+
+	rootCmd.Flags().Int("job-backoff-limit", 2, "number of times a job of --workload=job replaces a lost pod before the run fails.")
+	settings.BindPFlag("job-backoff-limit", rootCmd.Flags().Lookup("job-backoff-limit"))
+
+	common.DurationFlag(rootCmd.Flags(), "job-active-deadline", 24*time.Hour, "time after which the jobs of --workload=job and their pods are stopped. 0 disables the deadline.")
+	settings.BindPFlag("job-active-deadline", rootCmd.Flags().Lookup("job-active-deadline"))
+
+	rootCmd.Flags().String("reschedule-policy", common.RescheduleNone, fmt.Sprintf("what happens when a conformance pod is lost before its tests completed, e.g. because its node failed. %s fails the run, %s collects the partial artifacts and records the run as aborted, %s recreates the pod on another node, %s recreates it skipping the tests that completed in the lost pod.", common.RescheduleNone, common.RescheduleAbort, common.RescheduleRecreate, common.RescheduleResume))
+	settings.BindPFlag("reschedule-policy", rootCmd.Flags().Lookup("reschedule-policy"))
+
+	rootCmd.Flags().Int("reschedule-limit", 3, "number of times lost conformance pods are recreated with --reschedule-policy before the run fails.")
+	settings.BindPFlag("reschedule-limit", rootCmd.Flags().Lookup("reschedule-limit"))
+
+	common.DurationFlag(rootCmd.Flags(), "node-lost-timeout", 5*time.Minute, "time after which a conformance pod whose node isn't ready is considered lost. 0 waits for the pod to fail.")
+	settings.BindPFlag("node-lost-timeout", rootCmd.Flags().Lookup("node-lost-timeout"))
+
+	rootCmd.Flags().StringSlice("namespace-label", []string{}, "label of the namespace of the run, as key=value. can be repeated. overrides the default pod-security.kubernetes.io labels allowing privileged pods.")
+	settings.BindPFlag("namespace-label", rootCmd.Flags().Lookup("namespace-label"))
+
+	rootCmd.Flags().StringSlice("namespace-annotation", []string{}, "annotation of the namespace of the run, as key=value. can be repeated.")
+	settings.BindPFlag("namespace-annotation", rootCmd.Flags().Lookup("namespace-annotation"))
+
+	rootCmd.Flags().String("service-account", "", "existing service account of --namespace the conformance pods run as. the namespace, the service account and its permissions are neither created nor deleted by hydrophone.")
+	settings.BindPFlag("service-account", rootCmd.Flags().Lookup("service-account"))
+
+	rootCmd.Flags().Bool("restricted", false, "avoid the cluster-scoped operations of hydrophone, for users whose permissions are limited to --namespace and --service-account created by the administrator of the cluster. the nodes aren't checked or watched, the resource usage and the leaked resources aren't collected. the specs failing because cluster-scoped requests were denied are listed in results.json.")
+	settings.BindPFlag("restricted", rootCmd.Flags().Lookup("restricted"))
+
+	rootCmd.Flags().StringSlice("image-pull-secret", []string{}, "existing secret of --namespace used to pull the images of the conformance pods. can be repeated.")
+	settings.BindPFlag("image-pull-secret", rootCmd.Flags().Lookup("image-pull-secret"))
+
+	rootCmd.Flags().String("docker-config", "", "docker config file, e.g. ~/.docker/config.json, the image pull secret of the conformance pods is created from. the secret is deleted at cleanup.")
+	settings.BindPFlag("docker-config", rootCmd.Flags().Lookup("docker-config"))
+
+	rootCmd.Flags().String("priority-class", "", "priority class of the conformance pods, e.g. to keep them from being preempted by other workloads.")
+	settings.BindPFlag("priority-class", rootCmd.Flags().Lookup("priority-class"))
+
+	rootCmd.Flags().String("runtime-class", "", "runtime class of the conformance pods, e.g. gvisor or kata, to certify the clusters running workloads with a sandboxed runtime. recorded in results.json.")
+	settings.BindPFlag("runtime-class", rootCmd.Flags().Lookup("runtime-class"))
+
+	rootCmd.Flags().Bool("host-network", false, "run the conformance pods on the network of their nodes, e.g. when the pod network can't reach the API server or the image registries.")
+	settings.BindPFlag("host-network", rootCmd.Flags().Lookup("host-network"))
+
+	rootCmd.Flags().String("dns-policy", "", "DNS policy of the conformance pods, one of ClusterFirst, ClusterFirstWithHostNet, Default or None. defaults to ClusterFirstWithHostNet with --host-network.")
+	settings.BindPFlag("dns-policy", rootCmd.Flags().Lookup("dns-policy"))
+
+	rootCmd.Flags().StringSlice("dns-nameserver", []string{}, "nameserver of the conformance pods, added to the ones of --dns-policy. can be repeated.")
+	settings.BindPFlag("dns-nameserver", rootCmd.Flags().Lookup("dns-nameserver"))
+
+	rootCmd.Flags().StringSlice("dns-search", []string{}, "DNS search domain of the conformance pods. can be repeated.")
+	settings.BindPFlag("dns-search", rootCmd.Flags().Lookup("dns-search"))
+
+	rootCmd.Flags().StringSlice("dns-option", []string{}, "resolver option of the conformance pods, as name[:value], e.g. ndots:2. can be repeated.")
+	settings.BindPFlag("dns-option", rootCmd.Flags().Lookup("dns-option"))
+
+	rootCmd.Flags().String("security-profile", common.SecurityRestricted, fmt.Sprintf("security context of the conformance pods, %s sets the RuntimeDefault seccomp profile, drops all capabilities, disallows privilege escalation and runs the output container as non-root, %s sets no security context.", common.SecurityRestricted, common.SecurityUnrestricted))
+	settings.BindPFlag("security-profile", rootCmd.Flags().Lookup("security-profile"))
+
+	rootCmd.Flags().Int64("run-as-user", 0, "user the conformance container runs as with --security-profile=restricted, which then requires it to run as non-root. 0 keeps the user of the image.")
+	settings.BindPFlag("run-as-user", rootCmd.Flags().Lookup("run-as-user"))
+
+	rootCmd.Flags().StringSlice("add-capability", []string{}, "capability added to the conformance container with --security-profile=restricted, e.g. NET_RAW. can be repeated.")
+	settings.BindPFlag("add-capability", rootCmd.Flags().Lookup("add-capability"))
+
+	rootCmd.Flags().StringSlice("conformance-requests", []string{}, "resource requests of the conformance container, as name=quantity, e.g. cpu=1,memory=2Gi.")
+	settings.BindPFlag("conformance-requests", rootCmd.Flags().Lookup("conformance-requests"))
+
+	rootCmd.Flags().StringSlice("conformance-limits", []string{}, "resource limits of the conformance container, as name=quantity, e.g. cpu=2,memory=4Gi.")
+	settings.BindPFlag("conformance-limits", rootCmd.Flags().Lookup("conformance-limits"))
+
+	rootCmd.Flags().StringSlice("output-requests", []string{}, "resource requests of the output container collecting the results, as name=quantity.")
+	settings.BindPFlag("output-requests", rootCmd.Flags().Lookup("output-requests"))
+
+	rootCmd.Flags().StringSlice("output-limits", []string{}, "resource limits of the output container collecting the results, as name=quantity.")
+	settings.BindPFlag("output-limits", rootCmd.Flags().Lookup("output-limits"))
+
+	rootCmd.Flags().StringArray("env", []string{}, "environment variable of the conformance container, as KEY=VALUE. can be repeated.")
+	settings.BindPFlag("env", rootCmd.Flags().Lookup("env"))
+
+	rootCmd.Flags().StringSlice("env-from-secret", []string{}, "secret of --namespace whose keys are set as environment variables of the conformance container. can be repeated.")
+	settings.BindPFlag("env-from-secret", rootCmd.Flags().Lookup("env-from-secret"))
+
+	rootCmd.Flags().StringSlice("env-from-configmap", []string{}, "config map of --namespace whose keys are set as environment variables of the conformance container. can be repeated.")
+	settings.BindPFlag("env-from-configmap", rootCmd.Flags().Lookup("env-from-configmap"))
+
+	rootCmd.Flags().String("provider", "", "cloud provider passed to the e2e tests, e.g. gce, aws or azure, to run the tests requiring a provider.")
+	settings.BindPFlag("provider", rootCmd.Flags().Lookup("provider"))
+
+	rootCmd.Flags().String("gce-project", "", "GCE project of the cluster, with --provider=gce or gke.")
+	settings.BindPFlag("gce-project", rootCmd.Flags().Lookup("gce-project"))
+
+	rootCmd.Flags().String("gce-zone", "", "GCE zone of the cluster, with --provider=gce or gke.")
+	settings.BindPFlag("gce-zone", rootCmd.Flags().Lookup("gce-zone"))
+
+	rootCmd.Flags().String("gce-region", "", "GCE region of the cluster, with --provider=gce or gke.")
+	settings.BindPFlag("gce-region", rootCmd.Flags().Lookup("gce-region"))
+
+	rootCmd.Flags().String("cloud-config-file", "", "cloud config file of the provider, mounted into the conformance container from a secret and passed to the e2e tests.")
+	settings.BindPFlag("cloud-config-file", rootCmd.Flags().Lookup("cloud-config-file"))
+
+	rootCmd.Flags().String("provider-credentials", "", "credentials file of the provider, mounted into the conformance container from a secret. GOOGLE_APPLICATION_CREDENTIALS or AWS_SHARED_CREDENTIALS_FILE points to it with --provider=gce, gke, aws or eks.")
+	settings.BindPFlag("provider-credentials", rootCmd.Flags().Lookup("provider-credentials"))
+
+	rootCmd.Flags().StringArray("volume", []string{}, "volume mounted into the conformance container, as TYPE:SOURCE:PATH[:ro] with TYPE one of configmap, secret, hostpath or emptydir, e.g. secret:certs:/etc/ssl/custom:ro. can be repeated.")
+	settings.BindPFlag("volume", rootCmd.Flags().Lookup("volume"))
+
+	rootCmd.Flags().String("pod-patch", "", "yaml or json file with a patch applied to the conformance pod before it is created, either a strategic merge patch or a list of JSON6902 operations.")
+	settings.BindPFlag("pod-patch", rootCmd.Flags().Lookup("pod-patch"))
+
+	rootCmd.Flags().StringSlice("node", []string{}, "run the node conformance tests on the node, one after another when repeated. the results of each node are written to a node-<name> directory of the output directory. --focus defaults to the NodeConformance tests.")
+	settings.BindPFlag("node", rootCmd.Flags().Lookup("node"))
+
+	rootCmd.Flags().String("node-test-image", common.NodeTestImage, "image running the node e2e tests with --node, built for the kubernetes version of the nodes.")
+	settings.BindPFlag("node-test-image", rootCmd.Flags().Lookup("node-test-image"))
+
+	rootCmd.Flags().String("plugin", "", "yaml file describing a test suite run in place of the e2e tests, with its image, command, environment, artifacts, junit report and rbac rules.")
+	settings.BindPFlag("plugin", rootCmd.Flags().Lookup("plugin"))
+
+	rootCmd.Flags().StringSlice("extra-args", []string{}, "Additional parameters to be provided to the conformance container, separated by commas. Each element is split like a shell command line into flags of the e2e test binary: --key=value, --key followed by its value or a bare boolean --key (e.g., --clean-start,--allowed-not-ready-nodes=2 or \"--dns-domain 'cluster local'\").")
+	settings.BindPFlag("extra-args", rootCmd.Flags().Lookup("extra-args"))
+
+	rootCmd.Flags().StringArray("extra-arg", []string{}, "argument of the e2e test binary passed as it is, e.g. --extra-arg=--non-blocking-taints=a,b. can be repeated, appended to --extra-args.")
+	settings.BindPFlag("extra-arg", rootCmd.Flags().Lookup("extra-arg"))
+
+	rootCmd.Flags().StringSlice("extra-ginkgo-args", []string{}, "arguments of the ginkgo CLI running the e2e test binary in the conformance container, e.g. --flake-attempts=2. split like --extra-args. flags of the e2e test binary are passed with --extra-args.")
+	settings.BindPFlag("extra-ginkgo-args", rootCmd.Flags().Lookup("extra-ginkgo-args"))
+
+	rootCmd.Flags().Bool("force-extra-args", false, "pass extra args that collide with settings managed by hydrophone, such as --report-dir or --ginkgo.focus, instead of rejecting them.")
+	settings.BindPFlag("force-extra-args", rootCmd.Flags().Lookup("force-extra-args"))
+
+	rootCmd.Flags().Int("slowest", 10, "number of the slowest specs listed at the end of the run and in the timing of results.json.")
+	settings.BindPFlag("slowest", rootCmd.Flags().Lookup("slowest"))
+
+	rootCmd.Flags().String("baseline", "", "output directory of a previous run to compare the durations of the specs with. the specs that slowed down beyond --baseline-threshold are listed at the end of the run and in results.json.")
+	settings.BindPFlag("baseline", rootCmd.Flags().Lookup("baseline"))
+
+	rootCmd.Flags().Float64("baseline-threshold", 50, fmt.Sprintf("slowdown in percent compared to --baseline beyond which a spec is reported. specs that slowed down by less than %ds aren't.", results.MinSlowdown))
+	settings.BindPFlag("baseline-threshold", rootCmd.Flags().Lookup("baseline-threshold"))
+
+	common.DurationFlag(rootCmd.Flags(), "progress-report", 0, "have ginkgo report the specs running longer than the duration, and report them again at the same interval while they run, with the step they are at. the specs are also logged by hydrophone. useful to find the tests that hang without output, e.g. 5m.")
+	settings.BindPFlag("progress-report", rootCmd.Flags().Lookup("progress-report"))
+
+	common.DurationFlag(rootCmd.Flags(), "hang-debug-after", 0, "attach an ephemeral debug container to the conformance pod once ginkgo reports a spec running longer than the duration, and write the output of --hang-debug-command to the diagnostics directory. requires --progress-report. 0 disables it.")
+	settings.BindPFlag("hang-debug-after", rootCmd.Flags().Lookup("hang-debug-after"))
+
+	rootCmd.Flags().String("hang-debug-command", common.DefaultHangDebugCommand, "shell command run in the busybox debug container of --hang-debug-after, which shares the processes of the conformance container.")
+	settings.BindPFlag("hang-debug-command", rootCmd.Flags().Lookup("hang-debug-command"))
+
+	rootCmd.Flags().Int64Var(&seed, "seed", 0, "random seed used by the test framework to order the specs. useful to reproduce ordering-dependent failures of a previous run.")
+	settings.BindPFlag("seed", rootCmd.Flags().Lookup("seed"))
+
+	rootCmd.Flags().IntVar(&shards, "shards", 1, fmt.Sprintf("number of pods the tests are split across. tests are assigned to shards by SIG, at most %d shards are supported.", common.MaxShards))
+	settings.BindPFlag("shards", rootCmd.Flags().Lookup("shards"))
+
+	rootCmd.Flags().Bool("gate-smoke", false, "run the tests of --smoke-focus in a smoke phase first and the selected tests in a full phase only if they passed, each phase writing its artifacts to a subdirectory of the output directory and the reports being combined.")
+	settings.BindPFlag("gate-smoke", rootCmd.Flags().Lookup("gate-smoke"))
+
+	rootCmd.Flags().String("smoke-focus", common.DefaultSmokeFocus, "focus of the smoke phase of --gate-smoke, the full phase skips its tests.")
+	settings.BindPFlag("smoke-focus", rootCmd.Flags().Lookup("smoke-focus"))
+
+	rootCmd.Flags().String("suite-file", "", "yaml file describing phases, each with its own focus, skip and extra-args, that are run one after another.")
+	settings.BindPFlag("suite-file", rootCmd.Flags().Lookup("suite-file"))
+
+	rootCmd.Flags().StringArray("junit-property", []string{}, "property added to the testsuite of the junit report, as name=value. can be repeated.")
+	settings.BindPFlag("junit-property", rootCmd.Flags().Lookup("junit-property"))
+
+	rootCmd.Flags().StringArray("label", []string{}, fmt.Sprintf("label of the run recorded in %s and shown by hydrophone results, as key=value. can be repeated.", results.MetadataFile))
+	settings.BindPFlag("label", rootCmd.Flags().Lookup("label"))
+
+	rootCmd.Flags().String("max-log-size", "", "maximum size of the saved e2e.log, e.g. 100MiB. larger logs are split at line boundaries into numbered chunks e2e.log, e2e.log.1, e2e.log.2 and so on. empty keeps e2e.log in one piece.")
+	settings.BindPFlag("max-log-size", rootCmd.Flags().Lookup("max-log-size"))
+
+	rootCmd.Flags().Int("max-reconnects", 10, "number of consecutive failed attempts to re-establish the log stream or the watch of a conformance pod after which the run fails. the attempts are spread with an exponential backoff.")
+	settings.BindPFlag("max-reconnects", rootCmd.Flags().Lookup("max-reconnects"))
+
+	rootCmd.Flags().String("max-spec-output", "1MiB", "maximum size of the output and failure message of a single spec in the junit report. the beginning and the end of larger outputs are kept. 0 disables the limit.")
+	settings.BindPFlag("max-spec-output", rootCmd.Flags().Lookup("max-spec-output"))
+
+	rootCmd.Flags().String("upstream-flakes", "", "TestGrid dashboard/tab of an upstream job, e.g. sig-release-1.29-blocking/gce-cos-k8sstable1-default. failed tests are annotated with how often they failed there.")
+	settings.BindPFlag("upstream-flakes", rootCmd.Flags().Lookup("upstream-flakes"))
+
+	rootCmd.Flags().String("verdict-script", "", fmt.Sprintf("script run at the end with the path of %s as argument and the exit code of the run in HYDROPHONE_EXIT_CODE. its exit code becomes the exit code of hydrophone.", results.MetadataFile))
+	settings.BindPFlag("verdict-script", rootCmd.Flags().Lookup("verdict-script"))
+
+	common.DurationFlag(rootCmd.Flags(), "usage-interval", 30*time.Second, "interval of sampling the resource usage of the conformance pods and of the pods created by the tests from the metrics API. 0 disables the sampling.")
+	settings.BindPFlag("usage-interval", rootCmd.Flags().Lookup("usage-interval"))
+
+	common.DurationFlag(rootCmd.Flags(), "progress-interval", 10*time.Second, fmt.Sprintf("interval of writing %s to the output directory with the phase of the run, the spec running, the counts of the specs and the elapsed time, for dashboards and CI jobs to poll. 0 disables the snapshots.", results.ProgressFile))
+	settings.BindPFlag("progress-interval", rootCmd.Flags().Lookup("progress-interval"))
+
+	rootCmd.Flags().Bool("heartbeat", true, fmt.Sprintf("annotate the namespace of the run every --progress-interval with the time hydrophone was last alive and the snapshot of %s, for observers with access to the cluster only. the %s config map of the namespace is annotated instead with --service-account.", results.ProgressFile, common.HeartbeatConfigMapName))
+	settings.BindPFlag("heartbeat", rootCmd.Flags().Lookup("heartbeat"))
+
+	rootCmd.Flags().Float64("cost-per-cpu-hour", 0, "price of a CPU core per hour, used to estimate the cost of the run.")
+	settings.BindPFlag("cost-per-cpu-hour", rootCmd.Flags().Lookup("cost-per-cpu-hour"))
+
+	rootCmd.Flags().Float64("cost-per-gib-hour", 0, "price of a GiB of memory per hour, used to estimate the cost of the run.")
+	settings.BindPFlag("cost-per-gib-hour", rootCmd.Flags().Lookup("cost-per-gib-hour"))
+
+	rootCmd.Flags().Bool("impact-guard", false, "abort the run when the workloads sharing the cluster degrade, i.e. pods outside of the test namespaces stay pending or restart, or nodes become not ready.")
+	settings.BindPFlag("impact-guard", rootCmd.Flags().Lookup("impact-guard"))
+
+	rootCmd.Flags().Int("impact-max-pending", 10, "number of additional pending pods outside of the test namespaces tolerated by --impact-guard.")
+	settings.BindPFlag("impact-max-pending", rootCmd.Flags().Lookup("impact-max-pending"))
+
+	rootCmd.Flags().Int("impact-max-restarts", 5, "number of container restarts outside of the test namespaces tolerated by --impact-guard.")
+	settings.BindPFlag("impact-max-restarts", rootCmd.Flags().Lookup("impact-max-restarts"))
+
+	common.DurationFlag(rootCmd.Flags(), "impact-guard-interval", 30*time.Second, "interval of the checks of --impact-guard. the run is aborted when the limits are exceeded for 3 consecutive checks.")
+	settings.BindPFlag("impact-guard-interval", rootCmd.Flags().Lookup("impact-guard-interval"))
+
+	rootCmd.Flags().String("version-mismatch", common.VersionMismatchWarn, fmt.Sprintf("what to do when the version of the conformance image doesn't match the version of the cluster, one of %s, %s or %s.", common.VersionMismatchFail, common.VersionMismatchWarn, common.VersionMismatchAllow))
+	settings.BindPFlag("version-mismatch", rootCmd.Flags().Lookup("version-mismatch"))
+
+	common.DurationFlag(rootCmd.Flags(), "expected-duration", 2*time.Hour, "expected duration of the run. the run is refused when a credential of the kubeconfig that can't be refreshed expires before its end, 0 only reports the expiry.")
+	settings.BindPFlag("expected-duration", rootCmd.Flags().Lookup("expected-duration"))
+
+	rootCmd.Flags().BoolVar(&strictCompat, "strict-compat", false, "fail instead of warning when hydrophone is not tested with the version of the cluster or the conformance image.")
+	settings.BindPFlag("strict-compat", rootCmd.Flags().Lookup("strict-compat"))
+
+	rootCmd.Flags().String("upload", "", "upload the artifacts of the run to remote storage at the end of the run, e.g. s3://bucket/prefix, gs://bucket/prefix or azblob://container/prefix. the credentials are read from the environment like the SDKs of the providers do.")
+	settings.BindPFlag("upload", rootCmd.Flags().Lookup("upload"))
+
+	rootCmd.Flags().Int("upload-retries", 3, "number of times a failed upload of a file is retried with an exponential backoff.")
+	settings.BindPFlag("upload-retries", rootCmd.Flags().Lookup("upload-retries"))
+
+	common.DurationFlag(rootCmd.Flags(), "upload-backoff", 2*time.Second, "delay before the first retry of a failed upload, doubled for every following retry.")
+	settings.BindPFlag("upload-backoff", rootCmd.Flags().Lookup("upload-backoff"))
+
+	rootCmd.Flags().String("push", "", "push the artifacts of the run to a registry as an OCI artifact at the end of the run, e.g. oci://registry.example.com/conformance/results:v1.30.0. the registry is authenticated with the credentials of the docker config.")
+	settings.BindPFlag("push", rootCmd.Flags().Lookup("push"))
+
+	rootCmd.Flags().Bool("attest", false, "sign an in-toto attestation of results.tar.gz recording the server version, the digest of the conformance image and the arguments of the run, written to attestation.sigstore.json. requires --compress=bundle and cosign in PATH.")
+	settings.BindPFlag("attest", rootCmd.Flags().Lookup("attest"))
+
+	rootCmd.Flags().String("attest-key", "", "key signing the attestation of --attest, a file or a KMS URI passed to cosign. the attestation is signed keyless with the OIDC identity of the environment when empty.")
+	settings.BindPFlag("attest-key", rootCmd.Flags().Lookup("attest-key"))
+
+	rootCmd.Flags().String("notify-webhook", "", "URL a JSON payload with the status, test counts, duration, artifact location and failed tests is POSTed to when the run finishes or aborts. signed with HMAC-SHA256 in X-Hydrophone-Signature-256 when HYDROPHONE_NOTIFY_SECRET is set.")
+	settings.BindPFlag("notify-webhook", rootCmd.Flags().Lookup("notify-webhook"))
+
+	rootCmd.Flags().String("metrics-addr", "", "address the Prometheus metrics of the run are served on at /metrics, e.g. :9090. the metrics include the duration, the progress of the specs and the reconnects of the run.")
+	settings.BindPFlag("metrics-addr", rootCmd.Flags().Lookup("metrics-addr"))
+
+	rootCmd.Flags().String("otlp-endpoint", "", "URL of an OpenTelemetry collector the trace of the run is exported to with OTLP over HTTP when it finishes, e.g. http://localhost:4318. headers of the export are read from OTEL_EXPORTER_OTLP_HEADERS.")
+	settings.BindPFlag("otlp-endpoint", rootCmd.Flags().Lookup("otlp-endpoint"))
+
+	rootCmd.Flags().Bool("trace-tests", false, "add a span for every test of the log stream to the trace of --otlp-endpoint.")
+	settings.BindPFlag("trace-tests", rootCmd.Flags().Lookup("trace-tests"))
+
+	rootCmd.Flags().String("schedule", "", "cron expression hydrophone runs the tests at until it is interrupted, e.g. \"0 2 * * *\" for 2am in the local time zone. the artifacts of each run are written to <output-dir>/{timestamp} unless --output-dir holds {timestamp}, and --metrics-addr serves the metrics of the schedule.")
+	settings.BindPFlag("schedule", rootCmd.Flags().Lookup("schedule"))
+
+	rootCmd.Flags().Int("schedule-keep", 0, "number of most recent runs of --schedule whose artifacts are kept in <output-dir>, 0 keeps them all.")
+	settings.BindPFlag("schedule-keep", rootCmd.Flags().Lookup("schedule-keep"))
+
+	rootCmd.Flags().String("pushgateway-url", "", "URL of a Prometheus Pushgateway the metrics of the run are pushed to every 30s and when it finishes, e.g. http://pushgateway:9091.")
+	settings.BindPFlag("pushgateway-url", rootCmd.Flags().Lookup("pushgateway-url"))
+
+	rootCmd.Flags().String("notify-slack-webhook", "", "URL of a Slack incoming webhook a summary of the run highlighting the failed tests is posted to when the run finishes or aborts.")
+	settings.BindPFlag("notify-slack-webhook", rootCmd.Flags().Lookup("notify-slack-webhook"))
+
+	rootCmd.Flags().String("notify-teams-webhook", "", "URL of a Microsoft Teams incoming webhook or workflow a summary of the run highlighting the failed tests is posted to as an adaptive card when the run finishes or aborts.")
+	settings.BindPFlag("notify-teams-webhook", rootCmd.Flags().Lookup("notify-teams-webhook"))
+
+	rootCmd.Flags().Int("notify-retries", 3, "number of times a failed notification of the webhooks is retried with an exponential backoff.")
+	settings.BindPFlag("notify-retries", rootCmd.Flags().Lookup("notify-retries"))
+
+	rootCmd.Flags().Bool("github-check", false, "report the run in a GitHub check run of the commit of --github-sha, created when the run starts and completed with the summary of the run and annotations of the failed tests. the token is read from GITHUB_TOKEN.")
+	settings.BindPFlag("github-check", rootCmd.Flags().Lookup("github-check"))
+
+	rootCmd.Flags().String("github-check-name", "hydrophone conformance", "name of the check run of --github-check.")
+	settings.BindPFlag("github-check-name", rootCmd.Flags().Lookup("github-check-name"))
+
+	rootCmd.Flags().String("github-repo", "", "repository of the check run of --github-check, e.g. example/clusters. defaults to GITHUB_REPOSITORY.")
+	settings.BindPFlag("github-repo", rootCmd.Flags().Lookup("github-repo"))
+
+	rootCmd.Flags().String("github-sha", "", "commit of the check run of --github-check. defaults to GITHUB_SHA.")
+	settings.BindPFlag("github-sha", rootCmd.Flags().Lookup("github-sha"))
+
+	rootCmd.Flags().Bool("diagnostics", true, "collect the description and the events of the pods, the events of the namespace, the conditions of the nodes and the log of hydrophone into <output-dir>/diagnostics when the run fails or aborts.")
+	settings.BindPFlag("diagnostics", rootCmd.Flags().Lookup("diagnostics"))
+
+	rootCmd.Flags().Bool("record-history", true, "copy results.json, junit_01.xml and e2e.log of the run to the history directory.")
+	settings.BindPFlag("record-history", rootCmd.Flags().Lookup("record-history"))
+
+	rootCmd.PersistentFlags().String("history-dir", history.DefaultDir(), "directory holding the history of the runs.")
+	settings.BindPFlag("history-dir", rootCmd.PersistentFlags().Lookup("history-dir"))
+
+	rootCmd.PersistentFlags().Bool("log-timestamps", false, "prefix the streamed lines of the conformance container with the timestamp the kubelet received them at, e.g. to find out where the tests hang.")
+	settings.BindPFlag("log-timestamps", rootCmd.PersistentFlags().Lookup("log-timestamps"))
+
+	common.DurationFlag(rootCmd.Flags(), "echo-interval", 0, "only echo the start and the summary of the run and the failed specs to the terminal, along with a line with the progress of the specs every interval, e.g. 1m. the downloaded e2e.log keeps the whole output.")
+	settings.BindPFlag("echo-interval", rootCmd.Flags().Lookup("echo-interval"))
+
+	rootCmd.Flags().Int("echo-sample", 0, "only echo one of every n lines of the output of the tests to the terminal, along with the start and the summary of the run and the failed specs. the downloaded e2e.log keeps the whole output. 0 echoes every line.")
+	settings.BindPFlag("echo-sample", rootCmd.Flags().Lookup("echo-sample"))
+
+	rootCmd.PersistentFlags().StringSlice("log-sink", []string{}, "additional destination of the logs, can be repeated. one of syslog, syslog://host:port, syslog+tcp://host:port, journald or file:/path?max-size=10MiB&max-backups=5.")
+	settings.BindPFlag("log-sink", rootCmd.PersistentFlags().Lookup("log-sink"))
+
+	// --conformance, --focus, --focus-file, --sig and --behavior can be combined, see selectTests
+	rootCmd.MarkFlagsMutuallyExclusive("conformance", "cleanup", "list-images")
+	rootCmd.MarkFlagsMutuallyExclusive("focus", "cleanup", "list-images")
+	rootCmd.MarkFlagsMutuallyExclusive("suite-file", "focus", "cleanup", "list-images")
+	rootCmd.MarkFlagsMutuallyExclusive("gate-smoke", "suite-file", "node", "cleanup", "list-images")
+	rootCmd.MarkFlagsMutuallyExclusive("focus-file", "suite-file", "cleanup", "list-images")
+	rootCmd.MarkFlagsMutuallyExclusive("sig", "suite-file", "cleanup", "list-images")
+	rootCmd.MarkFlagsMutuallyExclusive("behavior", "suite-file", "cleanup", "list-images")
+	rootCmd.MarkFlagsMutuallyExclusive("dry-run", "cleanup", "list-images")
+	rootCmd.MarkFlagsMutuallyExclusive("node", "plugin", "conformance", "focus-file", "sig", "behavior", "suite-file", "cleanup", "list-images")
+	rootCmd.MarkFlagsMutuallyExclusive("plugin", "conformance", "focus", "focus-file", "sig", "behavior", "suite-file", "cleanup", "list-images")
+}
+
+func initConfig() {
+	// flags take precedence over the HYDROPHONE_ environment variables, which
+	// take precedence over the config file and the defaults
+	settings.SetEnvPrefix(common.EnvPrefix)
+	settings.SetEnvKeyReplacer(common.EnvKeyReplacer)
+	settings.AutomaticEnv()
+
+	if cfgFile == "" {
+		cfgFile = os.Getenv(common.EnvName("config"))
+	}
+	if cfgFile != "" {
+		settings.SetConfigFile(cfgFile)
+		if err := settings.ReadInConfig(); err != nil {
+			log.Fatalf("error reading config file %s: %v", cfgFile, err)
+		}
+	} else {
+		// hydrophone.yaml of the working directory, or of the config
+		// directory, e.g. ~/.config/hydrophone/hydrophone.yaml on linux
+		settings.SetConfigName("hydrophone")
+		settings.AddConfigPath(".")
+		settings.AddConfigPath(filepath.Join(xdg.ConfigHome, "hydrophone"))
+		settings.AddConfigPath(xdg.ConfigHome)
+		if err := settings.ReadInConfig(); err != nil {
+			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+				log.Fatal(err)
+			}
+		}
+	}
+	if profile := settings.GetString("profile"); profile != "" {
+		if err := common.ApplyProfile(profile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	settings.Set("kubeconfig", service.GetKubeConfig(settings.GetString("kubeconfig")))
+	if err := common.MergeExtraArgs(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := addLogSinks(settings.GetStringSlice("log-sink")); err != nil {
+		log.Fatal(err)
+	}
+	// the log is part of the diagnostics of failed runs
+	log.KeepHistory()
+}
@@ -0,0 +1,763 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/history"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/notify"
+	"sigs.k8s.io/hydrophone/pkg/registry"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+	"sigs.k8s.io/hydrophone/pkg/suite"
+)
+
+// linuxOnlySkip skips the tests that are not expected to pass on windows nodes
+const linuxOnlySkip = `\[LinuxOnly\]`
+
+// ipFamilySkips skips the tests requiring an IP family the cluster lacks
+var ipFamilySkips = map[string]string{
+	common.IPFamilyIPv4: `\[Feature:Networking-IPv6\]|\[Feature:IPv6DualStack\]`,
+	common.IPFamilyIPv6: `\[Feature:Networking-IPv4\]|\[Feature:IPv6DualStack\]`,
+}
+
+// clusterSnapshot describes the cluster at the start of the run
+var clusterSnapshot *results.Cluster
+
+// runTests runs the selected tests, collects their results and removes the
+// resources created for the run. Canceling ctx interrupts the run.
+func runTests(ctx context.Context, c *client.Client, config *rest.Config) {
+	// a resumed run continues in the output directory of the paused run
+	if resumed == nil {
+		if err := resolveOutputDir(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := validateStreamOutput(); err != nil {
+		log.Fatal(err)
+	}
+	if err := identifyRun(); err != nil {
+		log.Fatal(err)
+	}
+	stopInterrupts, err := handleInterrupts(ctx, c, config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer stopInterrupts()
+	stopTimeout := startRunTimeout(c, config)
+	defer stopTimeout()
+	// the credentials of the upload are checked before the tests run
+	uploader, err := newUploader()
+	if err != nil {
+		log.Fatal(err)
+	}
+	pushImage, err := pushReference()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := newWebhooks(); err != nil {
+		log.Fatal(err)
+	}
+	if err := startGitHubCheck(); err != nil {
+		log.Fatal(err)
+	}
+	if err := startMetrics(c); err != nil {
+		log.Fatal(err)
+	}
+	startProgress(c)
+	if err := startTracing(); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateAttest(); err != nil {
+		log.Fatal(err)
+	}
+	p, err := validatePlugin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := validateNodes(); err != nil {
+		log.Fatal(err)
+	}
+	if settings.GetBool("certified") {
+		if err := common.ValidateCertified(); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Running the tests in the configuration of a CNCF Certified Kubernetes submission")
+	}
+	if common.Restricted() {
+		log.Printf("Running in namespace %s as service account %s without cluster-scoped operations: the nodes aren't checked or watched, and the resource usage and the resources left behind by the tests aren't collected",
+			settings.GetString("namespace"), settings.GetString("service-account"))
+	}
+	nodes := settings.GetStringSlice("node")
+	if err := common.ValidateCompatibility(); err != nil {
+		log.Fatal(err)
+	}
+	// the preflight checks include the version mismatch
+	span := traceStep("preflight")
+	if settings.GetBool("skip-preflight") {
+		if err := common.ValidateVersionMismatch(); err != nil {
+			log.Fatal(err)
+		}
+	} else if err := preflight(c.ClientSet); err != nil {
+		log.Fatal(err)
+	}
+	span.End(nil)
+	if !common.Restricted() {
+		captureClusterSnapshot(c.ClientSet)
+	}
+	expected, err := common.GetDuration("expected-duration")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := service.CheckCredentials(config, expected, time.Now()); err != nil {
+		log.Fatal(err)
+	}
+	if err := service.ResolveParallel(c.ClientSet); err != nil {
+		log.Fatal(err)
+	}
+	if err := service.CheckNodeOS(c.ClientSet); err != nil {
+		log.Fatal(err)
+	}
+	if err := service.ResolveArchitecture(c.ClientSet); err != nil {
+		log.Fatal(err)
+	}
+	if err := service.CheckPriorityClass(c.ClientSet); err != nil {
+		log.Fatal(err)
+	}
+	if err := service.CheckRuntimeClass(c.ClientSet); err != nil {
+		log.Fatal(err)
+	}
+	// the image of a plugin or the node test image is pulled in place of the
+	// conformance image
+	if p == nil && len(nodes) == 0 {
+		if err := service.CheckConformanceImage(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// the output container is left out with --artifact-transfer=exec
+	if common.ArtifactContainer() == common.OutputContainer {
+		if err := service.CheckBusyboxImage(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if settings.GetBool("verify-signature") {
+		if err := verifyConformanceImage(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if settings.GetBool("verify-images") {
+		if err := service.VerifyImages(c.ClientSet); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := applySkipFile(); err != nil {
+		log.Fatal(err)
+	}
+	applyNodeOS()
+	applyIPFamily()
+	// the pods are created after the other resources of the run, check that
+	// the flags customizing them are valid first
+	if _, err := service.Pods(settings.GetString("namespace")); err != nil {
+		log.Fatal(err)
+	}
+	s, err := testSuite()
+	if err != nil {
+		log.Fatal(err)
+	}
+	setUp := false
+	if s == nil {
+		if s, setUp, err = selectTests(c, config); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if settings.GetBool("gate-smoke") {
+		log.Printf("Running the smoke tests first, the selected tests only run if they pass")
+		s = suite.WithSmokeGate(s, settings.GetString("smoke-focus"), settings.GetString("focus"))
+	}
+	if settings.GetBool("impact-guard") {
+		stop := guardImpact(c.ClientSet)
+		defer stop()
+	}
+	if s != nil {
+		common.SetDefaultNamespace()
+		if !setUp {
+			span := traceStep("setup")
+			service.Setup(c.ClientSet)
+			span.End(nil)
+		}
+		span := traceStep("suite")
+		c.ExitCode = runSuite(config, c.ClientSet, s)
+		span.End(nil)
+		// the remaining specs of a resumed run may be run in chunks
+		if resumed != nil {
+			if err := handleCheckpoint(c); err != nil {
+				log.Fatal(err)
+			}
+		}
+	} else if len(nodes) != 0 {
+		if err := common.ValidateArgs(); err != nil {
+			log.Fatal(err)
+		}
+		if err := service.CheckNodes(c.ClientSet, nodes); err != nil {
+			log.Fatal(err)
+		}
+		if !setUp {
+			span := traceStep("setup")
+			service.Setup(c.ClientSet)
+			span.End(nil)
+		}
+		span := traceStep("node conformance")
+		c.ExitCode = runNodeConformance(config, c.ClientSet, nodes)
+		span.End(nil)
+	} else {
+		if err := common.ValidateArgs(); err != nil {
+			log.Fatal(err)
+		}
+
+		if !setUp {
+			span := traceStep("setup")
+			service.Setup(c.ClientSet)
+			span.End(nil)
+		}
+		span := traceStep("create pods")
+		service.CreatePods(c.ClientSet)
+		span.End(nil)
+		updateGitHubCheck("Running the conformance tests", fmt.Sprintf("The tests run in namespace %s.", settings.GetString("namespace")))
+		watchPods(c, config)
+		collectResults(c, config)
+		if err := handleCheckpoint(c); err != nil {
+			log.Fatal(err)
+		}
+	}
+	checkHygiene(c.ClientSet)
+	// the resources of the run are still there to be diagnosed
+	if c.ExitCode != 0 {
+		diagnoseFailedRun(c.ClientSet)
+	}
+	span = traceStep("cleanup")
+	service.Cleanup(c.ClientSet)
+	span.End(nil)
+
+	if settings.GetBool("record-history") {
+		id, err := history.Record(settings.GetString("history-dir"), settings.GetString("output-dir"), time.Now())
+		if err != nil {
+			log.Printf("unable to record the run in the history: %v", err)
+		} else {
+			log.Printf("recorded the run as %s in %s", id, settings.GetString("history-dir"))
+		}
+	}
+
+	cacheTests(settings.GetString("output-dir"))
+
+	if script := settings.GetString("verdict-script"); script != "" {
+		exitCode, err := runVerdictScript(script, c.ExitCode)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if exitCode != c.ExitCode {
+			log.Printf("verdict script changed the exit code from %d to %d", c.ExitCode, exitCode)
+		}
+		c.ExitCode = exitCode
+	}
+	postProcessResults(c.ExitCode)
+
+	finishMetrics(c.ExitCode)
+	finishProgress(c.ExitCode)
+	finishTracing(c.ExitCode, nil)
+
+	// the payload of the webhooks is read before the artifacts are bundled
+	var payload *notify.Payload
+	if metadata, err := results.ReadMetadata(settings.GetString("output-dir")); err == nil {
+		payload = runPayload(settings.GetString("output-dir"), metadata)
+		payload.ExitCode = c.ExitCode
+	}
+	annotations := failureAnnotations(settings.GetString("output-dir"))
+	writeActionsErrors(settings.GetString("output-dir"))
+
+	if settings.GetString("compress") == common.CompressBundle {
+		if err := bundleArtifacts(settings.GetString("output-dir")); err != nil {
+			log.Fatalf("unable to bundle the artifacts of the run: %v", err)
+		}
+	}
+	if settings.GetBool("attest") {
+		if err := attestResults(settings.GetString("output-dir")); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if streamOutput() {
+		if err := streamArtifacts(stdout, settings.GetString("output-dir")); err != nil {
+			log.Fatalf("unable to stream the artifacts of the run: %v", err)
+		}
+	}
+	if uploader != nil {
+		if err := uploadArtifacts(uploader, settings.GetString("output-dir")); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if pushImage != "" {
+		if err := pushResults(pushImage, settings.GetString("output-dir")); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if payload != nil {
+		payload.SetTimes(runStarted, time.Now())
+		if uploader != nil {
+			payload.Artifacts.Upload = uploader.Target.String()
+		}
+		if pushImage != "" {
+			payload.Artifacts.Push = "oci://" + pushImage
+		}
+		notifyRun(payload)
+		completeGitHubCheck(payload, annotations)
+	}
+}
+
+// identifyRun checks the labels of --label and adds them, along with the CI
+// job running hydrophone, to the properties of the junit report. They are
+// recorded in the metadata of the run as well.
+func identifyRun() error {
+	labels, err := results.ParseLabels(settings.GetStringSlice("label"))
+	if err != nil {
+		return err
+	}
+	ci := results.DetectCI(os.Getenv)
+	if ci != nil {
+		log.Printf("Recording the CI job of the run: %s", ci)
+	}
+	// the properties of --junit-property are set last to take precedence
+	var properties []string
+	for _, property := range results.IdentityProperties(labels, ci) {
+		properties = append(properties, property.Name+"="+property.Value)
+	}
+	settings.Set("junit-property", append(properties, settings.GetStringSlice("junit-property")...))
+	return nil
+}
+
+// captureClusterSnapshot describes the cluster in the metadata and in the
+// properties of the junit report of the run. The run goes on when it can't be
+// described.
+func captureClusterSnapshot(clientSet kubernetes.Interface) {
+	snapshot, err := service.ClusterSnapshot(clientSet)
+	if err != nil {
+		log.Printf("unable to describe the cluster in the results: %v", err)
+		return
+	}
+	clusterSnapshot = snapshot
+	// the properties of --junit-property are set last to take precedence
+	var properties []string
+	for _, property := range snapshot.JUnitProperties() {
+		properties = append(properties, property.Name+"="+property.Value)
+	}
+	settings.Set("junit-property", append(properties, settings.GetStringSlice("junit-property")...))
+}
+
+// resolveOutputDir expands the placeholders of --output-dir and, unless
+// --force is set, moves the run to a numbered directory next to it when it
+// holds the artifacts of a previous run.
+func resolveOutputDir() error {
+	values := common.OutputDirValues(settings.GetString("cluster-name"), settings.GetString("server-version"), settings.GetString("conformance-image"), runStarted)
+	dir, err := common.ExpandOutputDir(settings.GetString("output-dir"), values)
+	if err != nil {
+		return err
+	}
+	if !settings.GetBool("force") {
+		free, err := common.FreeOutputDir(dir)
+		if err != nil {
+			return err
+		}
+		if free != dir {
+			log.Printf("%s holds the artifacts of a previous run, writing the artifacts of this run to %s, use --force to overwrite them", dir, free)
+		}
+		dir = free
+	}
+	settings.Set("output-dir", dir)
+	return nil
+}
+
+// bundleArtifacts bundles the artifacts of the run in the output directory
+// into a single tarball. The checkpoint is kept next to it, a later run
+// resumes from it.
+func bundleArtifacts(outputDir string) error {
+	names, err := runArtifacts(outputDir)
+	if err != nil {
+		return err
+	}
+	if err := results.Bundle(outputDir, names); err != nil {
+		return err
+	}
+	log.Printf("bundled the artifacts of the run into %s", filepath.Join(outputDir, results.BundleFile))
+	return nil
+}
+
+// newRunClient returns a client of the conformance pods of the run, which
+// writes their logs to stdout, or to stderr when stdout carries the tarball
+// of the artifacts.
+func newRunClient() *client.Client {
+	c := client.NewClient()
+	c.LogOutput = stdout
+	if streamOutput() {
+		c.LogOutput = stderr
+	}
+	return c
+}
+
+// watchPods sets the handlers of the conformance pods lost with
+// --reschedule-policy and of the specs hanging past --hang-debug-after
+func watchPods(c *client.Client, config *rest.Config) {
+	c.OnPodLost = handlePodLost(c, config)
+	c.OnHang = handleHang(c)
+}
+
+// streamOutput reports whether the artifacts of the run are streamed to
+// stdout with --output -
+func streamOutput() bool {
+	return settings.GetString("output") == "-"
+}
+
+// validateStreamOutput checks that --output is - and that stdout isn't a
+// terminal the tarball would be written to
+func validateStreamOutput() error {
+	switch output := settings.GetString("output"); output {
+	case "":
+	case "-":
+		if isTerminal(stdout) {
+			return errors.New("--output - writes a tarball to stdout, redirect it to a file or a pipe")
+		}
+	default:
+		return fmt.Errorf("expected --output to be -, got %q", output)
+	}
+	return nil
+}
+
+// streamArtifacts writes the artifacts of the run in the output directory to
+// w as a gzipped tarball. The tarball of --compress=bundle is written as is.
+func streamArtifacts(w io.Writer, outputDir string) error {
+	if settings.GetString("compress") == common.CompressBundle {
+		f, err := os.Open(filepath.Join(outputDir, results.BundleFile))
+		if err == nil {
+			defer f.Close()
+			_, err = io.Copy(w, f)
+			return err
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	names, err := runArtifacts(outputDir)
+	if err != nil {
+		return err
+	}
+	return results.WriteBundle(w, outputDir, names)
+}
+
+// runArtifacts returns the names of the files and directories of the output
+// directory holding the artifacts of the run
+func runArtifacts(outputDir string) ([]string, error) {
+	if settings.GetString("compress") == common.CompressBundle {
+		if _, err := os.Stat(filepath.Join(outputDir, results.BundleFile)); err == nil {
+			names := []string{results.BundleFile}
+			if _, err := os.Stat(filepath.Join(outputDir, results.AttestationFile)); err == nil {
+				names = append(names, results.AttestationFile)
+			}
+			return names, nil
+		}
+	}
+	// e2e.log may be gzipped or split into chunks
+	patterns := []string{results.MetadataFile, "junit_01.xml", client.FailuresFile, results.SkippedFile, results.CSVFile, results.HTMLFile, "e2e.log*", "shard-*", service.DiagnosticsDir}
+	// the directories of the phases of a suite run and of the nodes of --node
+	if m, err := results.ReadMetadata(outputDir); err == nil {
+		for _, phase := range m.Phases {
+			patterns = append(patterns, phase.Name)
+		}
+		for _, node := range m.Nodes {
+			patterns = append(patterns, "node-"+node.Name)
+		}
+	}
+	// the top level entries of the files selected with --artifacts and the
+	// plugin
+	for _, artifact := range append(pluginArtifacts(), settings.GetStringSlice("artifacts")...) {
+		if top, _, _ := strings.Cut(artifact, "/"); top != "**" {
+			patterns = append(patterns, top)
+		}
+	}
+	var names []string
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(outputDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if name := filepath.Base(match); !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// guardImpact starts the impact guard, which aborts the run when the
+// workloads sharing the cluster degrade while the tests are running. The
+// returned function stops the guard.
+func guardImpact(clientSet kubernetes.Interface) context.CancelFunc {
+	// the flag was validated by ValidateArgs
+	interval, _ := common.GetDuration("impact-guard-interval")
+	if interval <= 0 {
+		log.Fatalf("expected --impact-guard-interval to be positive, got %s", interval)
+	}
+	common.SetDefaultNamespace()
+	ctx, cancel := context.WithCancel(context.Background())
+	limits := service.ImpactLimits{
+		Pending:  settings.GetInt("impact-max-pending"),
+		Restarts: settings.GetInt("impact-max-restarts"),
+	}
+	err := service.WatchImpact(ctx, clientSet, limits, interval, func(reason string) {
+		log.Printf("Aborting the run, the workloads sharing the cluster are degraded: %s", reason)
+		metadata := &results.Metadata{
+			ServerVersion:    settings.GetString("server-git-version"),
+			RunID:            settings.GetString("run-id"),
+			Labels:           runLabels(),
+			CI:               results.DetectCI(os.Getenv),
+			Cluster:          clusterSnapshot,
+			ConformanceImage: settings.GetString("conformance-image"),
+			BusyboxImage:     settings.GetString("busybox-image"),
+			RuntimeClass:     settings.GetString("runtime-class"),
+			Focus:            settings.GetString("focus"),
+			Skip:             settings.GetString("skip"),
+			Certified:        settings.GetBool("certified"),
+			ExitCode:         1,
+			Aborted:          reason,
+		}
+		if err := results.WriteMetadata(settings.GetString("output-dir"), metadata); err != nil {
+			log.Printf("unable to write the metadata of the aborted run: %v", err)
+		}
+		service.Cleanup(clientSet)
+		if err := service.CleanupTestNamespaces(clientSet, settings.GetInt("cleanup-concurrency")); err != nil {
+			log.Printf("unable to delete the test namespaces: %v", err)
+		}
+		log.Fatal("run aborted by the impact guard")
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	return cancel
+}
+
+// verifyConformanceImage pins the conformance image to the digest it
+// currently points to and verifies the signature of that digest, so that the
+// pod runs exactly the image that was verified.
+func verifyConformanceImage() error {
+	image := settings.GetString("conformance-image")
+	digest, err := registry.NewChecker().Digest(image)
+	if err != nil {
+		return err
+	}
+	pinned := registry.PinDigest(image, digest)
+	log.Printf("Verifying the signature of %s", pinned)
+	if err := registry.VerifySignature(pinned, settings.GetString("certificate-identity"), settings.GetString("certificate-oidc-issuer")); err != nil {
+		return err
+	}
+	log.Printf("Signature of %s verified", pinned)
+	settings.Set("conformance-image", pinned)
+	return nil
+}
+
+// runVerdictScript runs the script with the path of the run metadata as its
+// argument and the exit code of the run in HYDROPHONE_EXIT_CODE. The exit code
+// of the script becomes the exit code of hydrophone.
+func runVerdictScript(script string, exitCode int) (int, error) {
+	metadata := filepath.Join(settings.GetString("output-dir"), results.MetadataFile)
+	log.Printf("running verdict script %s %s", script, metadata)
+
+	cmd := exec.Command(script, metadata)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("HYDROPHONE_EXIT_CODE=%d", exitCode))
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if streamOutput() {
+		cmd.Stdout = stderr
+	}
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error running verdict script: %w", err)
+	}
+	return 0, nil
+}
+
+// testSuite returns the suite of the suite file, if one was given, or the
+// chunks of the specs left to run by a resumed run.
+func testSuite() (*suite.Suite, error) {
+	if resumeSuite != nil {
+		return resumeSuite, nil
+	}
+	if suiteFile := settings.GetString("suite-file"); suiteFile != "" {
+		return suite.Load(suiteFile)
+	}
+	return nil, nil
+}
+
+// applySkipFile merges the expressions of the skip file into --skip.
+func applySkipFile() error {
+	skipFile := settings.GetString("skip-file")
+	if skipFile == "" {
+		return nil
+	}
+	patterns, err := common.ReadList(skipFile)
+	if err != nil {
+		return err
+	}
+	fileSkip, err := common.SkipFromList(patterns)
+	if err != nil {
+		return fmt.Errorf("%s: %w", skipFile, err)
+	}
+	log.Printf("Skipping %d expressions from %s", len(patterns), skipFile)
+	for _, pattern := range patterns {
+		addSkipRule(skipFile, pattern)
+	}
+	settings.Set("skip", joinSkip(settings.GetString("skip"), fileSkip))
+	return nil
+}
+
+// applyNodeOS skips the tests that only pass on linux when targeting windows nodes.
+func applyNodeOS() {
+	if settings.GetString("node-os") != common.NodeOSWindows {
+		return
+	}
+	log.Printf("Skipping %s tests on %s nodes", linuxOnlySkip, common.NodeOSWindows)
+	addSkipRule("--node-os="+common.NodeOSWindows, linuxOnlySkip)
+	settings.Set("skip", joinSkip(settings.GetString("skip"), linuxOnlySkip))
+}
+
+// applyIPFamily skips the tests of the IP families a single-stack cluster lacks.
+func applyIPFamily() {
+	family := settings.GetString("ip-family")
+	skip, ok := ipFamilySkips[family]
+	if !ok {
+		return
+	}
+	log.Printf("Skipping %s tests on %s clusters", skip, family)
+	addSkipRule("--ip-family="+family, skip)
+	settings.Set("skip", joinSkip(settings.GetString("skip"), skip))
+}
+
+// collectResults streams the logs of the running conformance pods, downloads
+// their artifacts and writes the run metadata to the output directory.
+func collectResults(c *client.Client, config *rest.Config) {
+	var sampler *client.UsageSampler
+	// the test namespaces and the metrics of the pods are listed at the
+	// cluster scope
+	if interval, _ := common.GetDuration("usage-interval"); interval > 0 && !common.Restricted() {
+		sampler = c.StartUsageSampler(interval)
+	}
+	stopStartup := watchStartup(c, config)
+	stopFailures := watchFailures(c, config)
+	stopTrace := traceStream(c)
+	c.PrintE2ELogs()
+	stopFailures()
+	stopTrace()
+	stopStartup()
+	span := traceStep("fetch artifacts")
+	partial := c.FetchFiles(config, c.ClientSet, settings.GetString("output-dir"))
+	span.End(nil)
+	if partial {
+		log.Printf("WARNING: the artifacts of the run are partial, recorded in %s", results.MetadataFile)
+	}
+	c.FetchExitCode()
+	if c.Seed != 0 {
+		log.Printf("Specs were randomized with seed %d, use --seed=%d to reproduce the ordering", c.Seed, c.Seed)
+	}
+	metadata := runMetadata(c.Seed)
+	metadata.Restricted = restrictedResults(settings.GetString("output-dir"))
+	metadata.ExitCode = c.ExitCode
+	metadata.Reconnects = c.Reconnects.Load()
+	metadata.PodRestarts = c.PodRestarts.Load()
+	metadata.Partial = partial
+	metadata.Failures = failures(settings.GetString("output-dir"))
+	metadata.Skipped = skippedSpecs(settings.GetString("output-dir"), skipRules)
+	metadata.Timing = specTimings(settings.GetString("output-dir"))
+	metadata.Regressions = compareBaseline(settings.GetString("output-dir"))
+	if sampler != nil {
+		metadata.Usage = sampler.Stop()
+		logUsage(metadata.Usage)
+	}
+	if err := results.WriteMetadata(settings.GetString("output-dir"), metadata); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runMetadata returns the metadata describing the run itself, its settings
+// and the seed ginkgo ordered the specs with, shared by the results.json of
+// single runs and of suites
+func runMetadata(seed int64) *results.Metadata {
+	return &results.Metadata{
+		ServerVersion:    settings.GetString("server-git-version"),
+		RunID:            settings.GetString("run-id"),
+		Labels:           runLabels(),
+		CI:               results.DetectCI(os.Getenv),
+		Cluster:          clusterSnapshot,
+		ConformanceImage: settings.GetString("conformance-image"),
+		BusyboxImage:     settings.GetString("busybox-image"),
+		RuntimeClass:     settings.GetString("runtime-class"),
+		Focus:            settings.GetString("focus"),
+		Skip:             settings.GetString("skip"),
+		Certified:        settings.GetBool("certified"),
+		Seed:             seed,
+		Parallel:         settings.GetInt("parallel"),
+		ParallelAuto:     settings.GetBool("parallel-auto"),
+	}
+}
+
+// runLabels returns the labels of --label, checked when the run started
+func runLabels() map[string]string {
+	labels, _ := results.ParseLabels(settings.GetStringSlice("label"))
+	return labels
+}
+
+// logUsage estimates the cost of the usage and prints it
+func logUsage(usage *results.Usage) {
+	if usage == nil {
+		return
+	}
+	usage.Estimate(settings.GetFloat64("cost-per-cpu-hour"), settings.GetFloat64("cost-per-gib-hour"))
+	log.Printf("Resource usage: %.2f CPU core-hours, %.2f GiB-hours of memory, peak of %d pods",
+		usage.CPUCoreSeconds/3600, usage.MemoryGiBSeconds/3600, usage.PeakPods)
+	if usage.Cost != 0 {
+		log.Printf("Estimated cost: %.2f", usage.Cost)
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// writeFiles writes the files of the output directory of a run
+func writeFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(file), 0644))
+	}
+}
+
+// bundleEntries returns the names of the files of the gzipped tarball
+func bundleEntries(t *testing.T, r io.Reader) []string {
+	t.Helper()
+	gz, err := gzip.NewReader(r)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if header.Typeflag == tar.TypeReg {
+			names = append(names, header.Name)
+		}
+	}
+	// nothing follows the tarball
+	rest, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+	return names
+}
+
+func TestStreamOutputStdout(t *testing.T) {
+	settings.Set("output", "-")
+	defer settings.Set("output", "")
+	dir := t.TempDir()
+	writeFiles(t, dir, results.MetadataFile, "junit_01.xml", "e2e.log")
+
+	var buf bytes.Buffer
+	previous := stdout
+	stdout = &buf
+	defer func() { stdout = previous }()
+
+	// the output of a run writing the logs of its conformance pods, then
+	// streaming its artifacts
+	c := newRunClient()
+	log.PrintfAPI("API endpoint : %s", "https://127.0.0.1:6443")
+	fmt.Fprintln(c.LogOutput, "[sig-node] Pods should be submitted and removed")
+	require.NoError(t, streamArtifacts(stdout, dir))
+
+	assert.ElementsMatch(t, []string{results.MetadataFile, "junit_01.xml", "e2e.log"}, bundleEntries(t, &buf))
+}
+
+func TestRunArtifactsSuite(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, results.WriteMetadata(dir, &results.Metadata{
+		Phases: []results.PhaseResult{
+			{Name: "smoke", Status: results.PhasePassed},
+			{Name: "full", Status: results.PhaseFailed, ExitCode: 1},
+			{Name: "serial", Status: results.PhaseNotRun},
+		},
+		Nodes: []results.PhaseResult{{Name: "worker-1", Status: results.PhasePassed}},
+	}))
+	writeFiles(t, dir, "junit_01.xml", "smoke/junit_01.xml", "smoke/e2e.log", "full/shard-1/e2e.log",
+		"full/shard-2/junit_01.xml", "node-worker-1/e2e.log", "notes.txt")
+
+	names, err := runArtifacts(dir)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{results.MetadataFile, "junit_01.xml", "smoke", "full", "node-worker-1"}, names)
+
+	var buf bytes.Buffer
+	require.NoError(t, streamArtifacts(&buf, dir))
+	assert.ElementsMatch(t, []string{results.MetadataFile, "junit_01.xml", "smoke/junit_01.xml", "smoke/e2e.log",
+		"full/shard-1/e2e.log", "full/shard-2/junit_01.xml", "node-worker-1/e2e.log"}, bundleEntries(t, &buf))
+}
+
+func TestRunMetadata(t *testing.T) {
+	values := map[string]any{
+		"run-id":    "nightly-42",
+		"label":     []string{"team=storage", "env=staging"},
+		"certified": true,
+		"focus":     `\[Conformance\]`,
+		"skip":      `\[Serial\]`,
+		"parallel":  4,
+	}
+	for key, value := range values {
+		settings.Set(key, value)
+	}
+	defer func() {
+		for key := range values {
+			settings.Set(key, nil)
+		}
+	}()
+
+	metadata := runMetadata(1234)
+	assert.Equal(t, "nightly-42", metadata.RunID)
+	assert.Equal(t, map[string]string{"team": "storage", "env": "staging"}, metadata.Labels)
+	assert.Equal(t, results.DetectCI(os.Getenv), metadata.CI)
+	assert.True(t, metadata.Certified)
+	assert.Equal(t, `\[Conformance\]`, metadata.Focus)
+	assert.Equal(t, `\[Serial\]`, metadata.Skip)
+	assert.Equal(t, int64(1234), metadata.Seed)
+	assert.Equal(t, 4, metadata.Parallel)
+}
+
+func TestRunVerdictScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the verdict scripts are shell scripts")
+	}
+	tests := []struct {
+		name     string
+		script   string
+		exitCode int
+		verdict  int
+	}{
+		{
+			name:     "passed",
+			script:   "exit 0",
+			exitCode: 0,
+			verdict:  0,
+		},
+		{
+			name:     "failed tests accepted",
+			script:   "exit 0",
+			exitCode: 1,
+			verdict:  0,
+		},
+		{
+			name:     "passed run rejected",
+			script:   "exit 3",
+			exitCode: 0,
+			verdict:  3,
+		},
+		{
+			name:     "exit code kept",
+			script:   `exit "$HYDROPHONE_EXIT_CODE"`,
+			exitCode: 1,
+			verdict:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			settings.Set("output-dir", dir)
+			defer settings.Set("output-dir", nil)
+			// the script records its argument and the exit code of the run
+			script := filepath.Join(dir, "verdict.sh")
+			record := filepath.Join(dir, "verdict.txt")
+			content := fmt.Sprintf("#!/bin/sh\necho \"$1 $HYDROPHONE_EXIT_CODE\" > %s\n%s\n", record, tt.script)
+			require.NoError(t, os.WriteFile(script, []byte(content), 0755))
+
+			verdict, err := runVerdictScript(script, tt.exitCode)
+			require.NoError(t, err)
+			assert.Equal(t, tt.verdict, verdict)
+			recorded, err := os.ReadFile(record)
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("%s %d\n", filepath.Join(dir, results.MetadataFile), tt.exitCode), string(recorded))
+		})
+	}
+}
+
+func TestRunVerdictScriptErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the verdict scripts are shell scripts")
+	}
+	dir := t.TempDir()
+	settings.Set("output-dir", dir)
+	defer settings.Set("output-dir", nil)
+	notExecutable := filepath.Join(dir, "verdict.sh")
+	require.NoError(t, os.WriteFile(notExecutable, []byte("#!/bin/sh\nexit 0\n"), 0644))
+
+	for name, script := range map[string]string{
+		"missing":        filepath.Join(dir, "missing.sh"),
+		"not executable": notExecutable,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := runVerdictScript(script, 1)
+			assert.ErrorContains(t, err, "error running verdict script")
+		})
+	}
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/metrics"
+	"sigs.k8s.io/hydrophone/pkg/schedule"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// scheduler runs the tests with a hydrophone process at each time of
+// --schedule and reports the runs in its metrics
+type scheduler struct {
+	executable string
+	args       []string
+	runs       atomic.Int64
+	failed     atomic.Int64
+	running    atomic.Bool
+	// lastStart and lastEnd are the times the last run started and ended
+	// at in unix seconds, 0 before the first run
+	lastStart    atomic.Int64
+	lastEnd      atomic.Int64
+	lastExitCode atomic.Int64
+	next         atomic.Int64
+}
+
+// runScheduled keeps hydrophone running and runs the tests at each time the
+// cron expression of --schedule matches, until hydrophone receives SIGINT or
+// SIGTERM. Each run is a hydrophone process with the same flags writing to
+// its own directory: <output-dir>/{timestamp} unless --output-dir holds
+// {timestamp}, of which --schedule-keep keeps the most recent ones. A run
+// still in progress at the next time of the schedule delays it to the time
+// after the run. The metrics of the schedule are served on --metrics-addr.
+func runScheduled(spec string) error {
+	s, err := schedule.Parse(spec)
+	if err != nil {
+		return fmt.Errorf("expected --schedule to be a cron expression, got %q: %w", spec, err)
+	}
+	keep := settings.GetInt("schedule-keep")
+	if keep < 0 {
+		return fmt.Errorf("expected --schedule-keep to be 0 or more, got %d", keep)
+	}
+	if cleanup || listImages || settings.GetString("dry-run") != common.DryRunNone || settings.GetString("output") == "-" {
+		return errors.New("--schedule can't be combined with --cleanup, --list-images, --dry-run or --output -")
+	}
+	// the scheduled runs are hydrophone processes
+	if inProcess {
+		return errors.New("--schedule isn't supported in process, schedule the runs of the program instead")
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	base := settings.GetString("output-dir")
+	outputDir := base
+	if !strings.Contains(base, "{timestamp}") {
+		outputDir = filepath.Join(base, "{timestamp}")
+	} else {
+		keep = 0
+	}
+	sc := &scheduler{executable: executable, args: scheduledArgs(os.Args[1:], outputDir)}
+	if addr := settings.GetString("metrics-addr"); addr != "" {
+		if _, err := sc.registry().Serve(addr); err != nil {
+			return fmt.Errorf("unable to serve the metrics on %s: %w", addr, err)
+		}
+		log.Printf("serving the metrics of the schedule on http://%s/metrics", addr)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for {
+		next := s.Next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("--schedule %q never matches", spec)
+		}
+		sc.next.Store(next.Unix())
+		log.Printf("Next run at %s", next.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Println("Stopping the schedule")
+			return nil
+		case <-timer.C:
+		}
+
+		sc.run(ctx)
+		if ctx.Err() != nil {
+			log.Println("Stopping the schedule")
+			return nil
+		}
+		if keep > 0 {
+			if err := pruneScheduledRuns(base, keep); err != nil {
+				log.Printf("unable to delete the artifacts of the old runs: %v", err)
+			}
+		}
+	}
+}
+
+// scheduledArgs returns the arguments of a scheduled run: the arguments of
+// hydrophone followed by the flags keeping the run from scheduling runs of
+// its own and from serving the metrics on the address of the schedule,
+// including when they are set in the config file or the environment, and
+// the output directory of the run.
+func scheduledArgs(args []string, outputDir string) []string {
+	return append(slices.Clone(args), "--schedule=", "--metrics-addr=", "--output-dir="+outputDir)
+}
+
+// run runs the tests once. The run is interrupted, so that it collects its
+// results and deletes its resources, when ctx is canceled.
+func (sc *scheduler) run(ctx context.Context) {
+	start := time.Now()
+	sc.running.Store(true)
+	sc.lastStart.Store(start.Unix())
+	defer sc.running.Store(false)
+
+	log.Println("Starting the scheduled run")
+	cmd := exec.Command(sc.executable, sc.args...)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// the run is interrupted by the scheduler only, a second interrupt
+	// would abort it without collecting its results
+	detachProcessGroup(cmd)
+	exitCode := 0
+	if err := cmd.Start(); err != nil {
+		log.Printf("unable to start the scheduled run: %v", err)
+		exitCode = 1
+	} else {
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				if err := cmd.Process.Signal(os.Interrupt); err != nil {
+					_ = cmd.Process.Kill()
+				}
+			case <-done:
+			}
+		}()
+		err := cmd.Wait()
+		close(done)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		} else if err != nil {
+			log.Printf("scheduled run failed: %v", err)
+			exitCode = 1
+		}
+	}
+
+	sc.runs.Add(1)
+	if exitCode != 0 {
+		sc.failed.Add(1)
+	}
+	sc.lastExitCode.Store(int64(exitCode))
+	sc.lastEnd.Store(time.Now().Unix())
+	log.Printf("Scheduled run finished with exit code %d in %s", exitCode, time.Since(start).Round(time.Second))
+}
+
+// registry returns the metrics of the schedule
+func (sc *scheduler) registry() *metrics.Registry {
+	r := &metrics.Registry{}
+	r.Counter("hydrophone_schedule_runs_total", "Scheduled runs that finished.", func() float64 { return float64(sc.runs.Load()) })
+	r.Counter("hydrophone_schedule_failed_runs_total", "Scheduled runs that finished with a non-zero exit code.", func() float64 {
+		return float64(sc.failed.Load())
+	})
+	r.Gauge("hydrophone_schedule_running", "1 while a scheduled run is in progress.", func() float64 {
+		if sc.running.Load() {
+			return 1
+		}
+		return 0
+	})
+	r.Gauge("hydrophone_schedule_last_run_start_time_seconds", "Unix time the last scheduled run started at.", func() float64 {
+		return float64(sc.lastStart.Load())
+	})
+	r.Gauge("hydrophone_schedule_last_run_end_time_seconds", "Unix time the last scheduled run finished at.", func() float64 {
+		return float64(sc.lastEnd.Load())
+	})
+	r.Gauge("hydrophone_schedule_last_run_exit_code", "Exit code of the last scheduled run.", func() float64 {
+		return float64(sc.lastExitCode.Load())
+	})
+	r.Gauge("hydrophone_schedule_next_run_time_seconds", "Unix time of the next scheduled run.", func() float64 {
+		return float64(sc.next.Load())
+	})
+	return r
+}
+
+// pruneScheduledRuns deletes the directories of the scheduled runs in dir
+// but the keep most recent ones. The directories of the runs are named after
+// the time the run started at, other entries of dir are left alone.
+func pruneScheduledRuns(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var runs []string
+	for _, entry := range entries {
+		name, _, _ := strings.Cut(entry.Name(), "-")
+		if _, err := time.Parse(common.TimestampFormat, name); entry.IsDir() && err == nil {
+			runs = append(runs, entry.Name())
+		}
+	}
+	if len(runs) <= keep {
+		return nil
+	}
+	// the timestamps sort chronologically
+	slices.Sort(runs)
+	for _, name := range runs[:len(runs)-keep] {
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+		log.Printf("Deleted the artifacts of the scheduled run %s", name)
+	}
+	return nil
+}
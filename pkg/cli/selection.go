@@ -0,0 +1,203 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+	"sigs.k8s.io/hydrophone/pkg/suite"
+)
+
+// conformanceFocus selects the conformance tests
+const conformanceFocus = `\[Conformance\]`
+
+// selectTests combines the flags selecting the tests. Each of --focus,
+// --focus-file, the tags of --sig and --behavior, and --conformance narrows
+// the selection, so a test has to match all of them, and tests listed more
+// than once in the focus file run once. --skip and --skip-file remove the
+// tests matching any of their expressions.
+//
+// When a single flag selects the tests it becomes the focus of the run.
+// Otherwise hydrophone computes the selected tests, listing them with a dry
+// run, or from the tests of the conformance image cached by a run, if no
+// focus file was given, reports their number and focuses them by name. If
+// the names don't fit in a single focus expression, the returned suite runs
+// them in chunks, as it does a --focus too long to be passed to the
+// conformance container. Without client, selections that need to list the
+// tests fail unless a run cached the tests of the conformance image. setUp
+// reports whether the resources of the run were created to list the tests.
+func selectTests(c *client.Client, config *rest.Config) (s *suite.Suite, setUp bool, err error) {
+	conformance := settings.GetBool("conformance")
+	tags, err := common.FocusFromTags(settings.GetStringSlice("sig"), settings.GetStringSlice("behavior"), conformance)
+	if err != nil {
+		return nil, false, err
+	}
+	if tags == "" && conformance {
+		tags = conformanceFocus
+	}
+	focus := settings.GetString("focus")
+
+	var names []string
+	var filters []string
+	switch {
+	case settings.GetString("focus-file") != "":
+		if names, err = common.ReadList(settings.GetString("focus-file")); err != nil {
+			return nil, false, err
+		}
+		filters = nonEmpty(focus, tags)
+	case focus != "" && tags != "":
+		if names = completeCachedTests(); names != nil {
+			filters = []string{focus, tags}
+			break
+		}
+		if c == nil {
+			return nil, false, errors.New("combining --focus with --conformance, --sig or --behavior lists the tests in the cluster, which --dry-run doesn't connect to, unless a run cached the tests of the conformance image")
+		}
+		log.Printf("Listing the tests matching %s to intersect them with the focus", tags)
+		if names, err = listTests(c, config, tags); err != nil {
+			return nil, false, err
+		}
+		setUp = true
+		filters = []string{focus}
+	case tags != "":
+		settings.Set("focus", tags)
+		return nil, false, nil
+	case len(focus) > common.MaxFocusLength:
+		// the focus is passed to the conformance container in an
+		// environment variable, which exec refuses beyond the limit
+		focusChunks, err := common.SplitFocus(focus, common.MaxFocusLength)
+		if err != nil {
+			return nil, false, fmt.Errorf("--focus: %w", err)
+		}
+		log.Printf("--focus is %d bytes, longer than %d bytes, running the tests in %d chunks", len(focus), common.MaxFocusLength, len(focusChunks))
+		return suite.FromFocus(focusChunks), false, nil
+	default:
+		return nil, false, nil
+	}
+
+	selected, duplicates, err := common.FilterTests(names, filters, settings.GetString("skip"))
+	if err != nil {
+		return nil, setUp, err
+	}
+	if duplicates != 0 {
+		log.Printf("Ignoring %d tests listed more than once", duplicates)
+	}
+	if len(selected) == 0 {
+		return nil, setUp, fmt.Errorf("the combination of focus, focus file, tags and skip selects no test out of %d", len(names)-duplicates)
+	}
+	log.Printf("Selected %d of %d tests", len(selected), len(names)-duplicates)
+
+	focusChunks, err := common.FocusFromTestNames(selected, common.MaxFocusLength)
+	if err != nil {
+		return nil, setUp, err
+	}
+	if len(focusChunks) == 1 {
+		settings.Set("focus", focusChunks[0])
+		return nil, setUp, nil
+	}
+	log.Printf("Focus expression too long, running the tests in %d chunks", len(focusChunks))
+	return suite.FromFocus(focusChunks), setUp, nil
+}
+
+// completeCachedTests returns all the tests of the conformance image when a
+// run cached them, nil otherwise.
+func completeCachedTests() []string {
+	version := common.ImageVersion(settings.GetString("conformance-image"))
+	if version == "" {
+		return nil
+	}
+	cached, err := common.ReadCachedTests(common.TestCacheDir(), version)
+	if err != nil {
+		log.Printf("unable to read the cached tests of %s: %v", version, err)
+		return nil
+	}
+	if cached == nil || !cached.Complete {
+		return nil
+	}
+	log.Printf("Intersecting the focus with the %d cached tests of %s", len(cached.Tests), version)
+	return cached.Tests
+}
+
+// listTests runs the conformance image in ginkgo dry-run mode with the focus and
+// returns the names of the tests it selects. The resources of the run are
+// created and kept, only the conformance pods are deleted afterwards.
+func listTests(c *client.Client, config *rest.Config, focus string) ([]string, error) {
+	dir, err := os.MkdirTemp("", "hydrophone-list")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	saved := map[string]any{}
+	for key, value := range map[string]any{"focus": focus, "ginkgo-dry-run": true, "output-dir": dir, "shards": 1} {
+		saved[key] = settings.Get(key)
+		settings.Set(key, value)
+	}
+	defer func() {
+		for key, value := range saved {
+			settings.Set(key, value)
+		}
+	}()
+	if err := common.ValidateArgs(); err != nil {
+		return nil, err
+	}
+
+	service.Setup(c.ClientSet)
+	service.CreatePods(c.ClientSet)
+	c.FetchExitCode()
+	c.FetchFiles(config, c.ClientSet, dir)
+	service.DeletePods(c.ClientSet)
+	c.ExitCode = 0
+
+	report, err := results.ReadJUnit(filepath.Join(dir, "junit_01.xml"))
+	if err != nil {
+		return nil, err
+	}
+	return results.SelectedTests(report), nil
+}
+
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// completeTestSelection completes --focus and --skip with the tags of the
+// tests and the names cached by the last hydrophone list.
+func completeTestSelection(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names, err := common.ReadTestListCache(common.TestListCacheFile())
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+	}
+	return common.CompleteTestSelection(names, toComplete), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/update"
+	"sigs.k8s.io/hydrophone/pkg/version"
+)
+
+var updateOpts = update.Options{}
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update hydrophone to the latest release.",
+	Long: `Update hydrophone to the latest release.
+
+Downloads the release archive for the current platform, verifies it against the
+release checksums and replaces the running executable. The signature of the
+checksums file is verified with cosign, which has to be in PATH, unless
+--insecure-skip-signature is passed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		updateOpts.CurrentVersion = version.Get()
+		if err := update.SelfUpdate(updateOpts); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	selfUpdateCmd.Flags().BoolVar(&updateOpts.Force, "force", false, "reinstall the latest release even if it is the current version.")
+	selfUpdateCmd.Flags().BoolVar(&updateOpts.InsecureSkipSignature, "insecure-skip-signature", false, "install the release without verifying the signature of its checksums, e.g. when cosign is not installed. a tampered release is only detected by the signature.")
+	selfUpdateCmd.Flags().StringVar(&updateOpts.CertificateIdentity, "certificate-identity-regexp", update.DefaultCertificateIdentity, "regular expression the identity that signed the release has to match.")
+	selfUpdateCmd.Flags().StringVar(&updateOpts.CertificateOIDCIssuer, "certificate-oidc-issuer", update.DefaultCertificateOIDCIssuer, "OIDC issuer of the identity that signed the release.")
+
+	rootCmd.AddCommand(selfUpdateCmd)
+}
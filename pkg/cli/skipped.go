@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"path/filepath"
+	"sort"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// skipRules are the expressions merged into --skip along with where they come
+// from, in the order they are reported as the reason of a skipped spec
+var skipRules []results.SkipRule
+
+// addSkipRule records where a skip expression merged into --skip comes from
+func addSkipRule(source, expression string) {
+	if expression != "" {
+		skipRules = append(skipRules, results.SkipRule{Source: source, Expression: expression})
+	}
+}
+
+// skippedSpecs writes the skipped specs of the run along with the reason they
+// were skipped to skipped.json and returns the number of skipped specs of
+// each reason.
+func skippedSpecs(outputDir string, rules []results.SkipRule) map[string]int {
+	report, err := results.ReadJUnit(filepath.Join(outputDir, "junit_01.xml"))
+	if err != nil {
+		log.Printf("unable to read the skipped tests: %v", err)
+		return nil
+	}
+	skipped, err := results.ClassifySkipped(report, settings.GetString("focus"), rules)
+	if err != nil {
+		log.Printf("unable to classify the skipped tests: %v", err)
+		return nil
+	}
+	if err := results.WriteSkipped(outputDir, skipped); err != nil {
+		log.Printf("unable to write the skipped tests: %v", err)
+	}
+
+	counts := results.CountSkipped(skipped)
+	reasons := make([]string, 0, len(counts))
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		log.Printf("Skipped %d specs: %s", counts[reason], reason)
+	}
+	if counts[results.SkipReasonUnknown] != 0 || counts[results.SkipReasonRuntime] != 0 {
+		log.Printf("Review %s for the specs skipped at runtime or for an unknown reason", filepath.Join(outputDir, results.SkippedFile))
+	}
+	return counts
+}
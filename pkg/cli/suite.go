@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"path/filepath"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+	"sigs.k8s.io/hydrophone/pkg/suite"
+)
+
+// runSuite executes the phases of the suite file one after another in the
+// same namespace, which has to be set up already. The artifacts of each phase
+// are written to a subdirectory of the output directory, the junit reports of
+// all phases are merged into a combined report and the results.json of the
+// output directory describes the run like the one of a single run does. It
+// returns the exit code of the first failed phase.
+func runSuite(config *rest.Config, clientSet kubernetes.Interface, s *suite.Suite) int {
+	outputDir := settings.GetString("output-dir")
+	focus := settings.GetString("focus")
+	skip := settings.GetString("skip")
+	extraArgs := settings.GetStringSlice("extra-args")
+
+	exitCode := 0
+	summary := runMetadata(0)
+	summary.Focus = s.Focus()
+	// seed is the seed shared by the phases, -1 once they were ordered with
+	// different seeds
+	var seed int64
+	var reports []*results.JUnitTestSuites
+	// stopped is set once a phase failed that doesn't continue on failure
+	stopped := false
+	for i, phase := range s.Phases {
+		if stopped {
+			log.Printf("Skipping phase %s because a previous phase failed", phase.Name)
+			summary.Phases = append(summary.Phases, results.PhaseResult{Name: phase.Name, Status: results.PhaseNotRun})
+			continue
+		}
+		log.Printf("Running phase %d/%d: %s", i+1, len(s.Phases), phase.Name)
+
+		settings.Set("focus", phase.Focus)
+		settings.Set("skip", joinSkip(skip, phase.Skip))
+		rules := skipRules
+		addSkipRule("phase "+phase.Name, phase.Skip)
+		settings.Set("extra-args", extraArgs)
+		if len(phase.ExtraArgs) != 0 {
+			settings.Set("extra-args", phase.ExtraArgs)
+		}
+		settings.Set("output-dir", filepath.Join(outputDir, phase.Name))
+		if err := common.ValidateArgs(); err != nil {
+			log.Fatalf("phase %s: %v", phase.Name, err)
+		}
+
+		service.CreatePods(clientSet)
+
+		c := newRunClient()
+		c.ClientSet = clientSet
+		watchPods(c, config)
+		collectResults(c, config)
+		skipRules = rules
+		service.DeletePods(clientSet)
+		summary.Reconnects += c.Reconnects.Load()
+		summary.PodRestarts += c.PodRestarts.Load()
+		if seed == 0 {
+			seed = c.Seed
+		} else if seed != c.Seed {
+			seed = -1
+		}
+		if m, err := results.ReadMetadata(filepath.Join(outputDir, phase.Name)); err == nil && m.Usage != nil {
+			if summary.Usage == nil {
+				summary.Usage = &results.Usage{}
+			}
+			summary.Usage.Add(m.Usage)
+		}
+
+		status := results.PhasePassed
+		if c.ExitCode != 0 {
+			status = results.PhaseFailed
+			if exitCode == 0 {
+				exitCode = c.ExitCode
+			}
+			stopped = !phase.ContinueOnFailure
+		}
+		summary.Phases = append(summary.Phases, results.PhaseResult{Name: phase.Name, Status: status, ExitCode: c.ExitCode})
+
+		report, err := results.ReadJUnit(filepath.Join(outputDir, phase.Name, "junit_01.xml"))
+		if err != nil {
+			log.Printf("unable to read junit report of phase %s: %v", phase.Name, err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+	settings.Set("output-dir", outputDir)
+	settings.Set("focus", focus)
+	settings.Set("skip", skip)
+	settings.Set("extra-args", extraArgs)
+
+	if len(reports) != 0 {
+		log.Println("merging junit reports of all phases to", filepath.Join(outputDir, "junit_01.xml"))
+		if err := results.WriteJUnit(filepath.Join(outputDir, "junit_01.xml"), results.MergeJUnit(reports...)); err != nil {
+			log.Fatal(err)
+		}
+	}
+	summary.Seed = max(seed, 0)
+	summary.ExitCode = exitCode
+	if err := results.WriteMetadata(outputDir, summary); err != nil {
+		log.Fatal(err)
+	}
+	for _, phase := range summary.Phases {
+		log.Printf("phase %s: %s", phase.Name, phase.Status)
+	}
+	return exitCode
+}
+
+// joinSkip combines two skip expressions so that tests matching either are skipped
+func joinSkip(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	default:
+		return a + "|" + b
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// failuresCheckInterval is the interval at which the failed specs are
+// checked against the budget of --max-failures
+const failuresCheckInterval = time.Second
+
+// startRunTimeout aborts the run when it doesn't complete within --timeout.
+// The returned function stops the timer.
+func startRunTimeout(c *client.Client, config *rest.Config) func() {
+	// the flag was validated by ValidateArgs
+	timeout, _ := common.GetDuration("timeout")
+	if timeout <= 0 {
+		return func() {}
+	}
+	timer := time.AfterFunc(timeout, func() {
+		abortTimedOut(c, config, fmt.Sprintf("the run didn't complete within --timeout=%s", timeout))
+	})
+	return func() { timer.Stop() }
+}
+
+// watchStartup aborts the run when the conformance pods aren't running within
+// --startup-timeout, e.g. because they can't be scheduled. The returned
+// function stops watching.
+func watchStartup(c *client.Client, config *rest.Config) func() {
+	// the flag was validated by ValidateArgs
+	timeout, _ := common.GetDuration("startup-timeout")
+	if timeout <= 0 {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		err := c.WaitForPodsRunning(ctx, timeout)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		abortTimedOut(c, config, fmt.Sprintf("the conformance pods weren't running within --startup-timeout=%s: %v", timeout, err))
+	}()
+	return cancel
+}
+
+// watchFailures aborts the run once the number of failed specs reaches
+// --max-failures, or the first spec failed with --fail-fast. The returned
+// function stops watching.
+func watchFailures(c *client.Client, config *rest.Config) func() {
+	budget := common.FailureBudget()
+	if budget <= 0 {
+		return func() {}
+	}
+	ticker := time.NewTicker(failuresCheckInterval)
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if failed := c.Specs.Failed.Load(); failed >= budget {
+					abortFailed(c, config, fmt.Sprintf("%d tests failed, reaching the budget of %d failures", failed, budget))
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// abortFailed records the run as aborted and deletes its resources.
+func abortFailed(c *client.Client, config *rest.Config, reason string) {
+	log.Printf("Aborting the run, %s", reason)
+	c.StopStreaming()
+	recordAbortedRun(c, config, reason, false)
+	service.Cleanup(c.ClientSet)
+	log.Fatal("run aborted after too many failures")
+}
+
+// abortTimedOut records the run as timed out and deletes its resources.
+func abortTimedOut(c *client.Client, config *rest.Config, reason string) {
+	log.Printf("Aborting the run, %s", reason)
+	recordAbortedRun(c, config, reason, true)
+	service.Cleanup(c.ClientSet)
+	log.Fatal("run aborted after a timeout")
+}
+
+// recordAbortedRun collects what the conformance pods produced so far,
+// records the reason the run was aborted in the metadata and reports it to
+// the webhooks and the GitHub check run.
+func recordAbortedRun(c *client.Client, config *rest.Config, reason string, timedOut bool) {
+	outputDir := settings.GetString("output-dir")
+	c.FetchPartialFiles(config, outputDir)
+	diagnoseFailedRun(c.ClientSet)
+	metadata := &results.Metadata{
+		ServerVersion:    settings.GetString("server-git-version"),
+		RunID:            settings.GetString("run-id"),
+		Labels:           runLabels(),
+		CI:               results.DetectCI(os.Getenv),
+		Cluster:          clusterSnapshot,
+		ConformanceImage: settings.GetString("conformance-image"),
+		BusyboxImage:     settings.GetString("busybox-image"),
+		RuntimeClass:     settings.GetString("runtime-class"),
+		Focus:            settings.GetString("focus"),
+		Skip:             settings.GetString("skip"),
+		Certified:        settings.GetBool("certified"),
+		Restricted:       restrictedResults(outputDir),
+		ExitCode:         1,
+		Aborted:          reason,
+		TimedOut:         timedOut,
+		Failures:         failures(outputDir),
+	}
+	if err := results.WriteMetadata(outputDir, metadata); err != nil {
+		log.Printf("unable to write the metadata of the aborted run: %v", err)
+	}
+	finishMetrics(metadata.ExitCode)
+	finishProgress(metadata.ExitCode)
+	finishTracing(metadata.ExitCode, errors.New(reason))
+	payload := runPayload(outputDir, metadata)
+	notifyRun(payload)
+	completeGitHubCheck(payload, failureAnnotations(outputDir))
+	writeActionsErrors(outputDir)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"path/filepath"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// specTimings returns the time the specs of the run took, read from the
+// junit report, and logs the slowest specs and the time of each SIG.
+func specTimings(outputDir string) *results.Timing {
+	report, err := results.ReadJUnit(filepath.Join(outputDir, "junit_01.xml"))
+	if err != nil {
+		log.Printf("unable to read the durations of the tests: %v", err)
+		return nil
+	}
+	timing := results.Timings(report, settings.GetInt("slowest"))
+	// no spec ran
+	if len(timing.SIGs) == 0 {
+		return timing
+	}
+
+	log.Printf("Time spent in the specs: %s", results.FormatSeconds(timing.Seconds))
+	if len(timing.Slowest) != 0 {
+		log.Printf("Slowest specs:")
+	}
+	for _, spec := range timing.Slowest {
+		log.Printf("  %8s %s", results.FormatSeconds(spec.Seconds), spec.Name)
+	}
+	log.Printf("Time per SIG:")
+	for _, sig := range timing.SIGsByTime() {
+		share := 0.0
+		if timing.Seconds > 0 {
+			share = timing.SIGs[sig] * 100 / timing.Seconds
+		}
+		log.Printf("  %8s %3.0f%% %s", results.FormatSeconds(timing.SIGs[sig]), share, sig)
+	}
+	return timing
+}
+
+// compareBaseline compares the durations of the specs with the run given with
+// --baseline and logs the specs that slowed down beyond --baseline-threshold.
+func compareBaseline(outputDir string) *results.Regressions {
+	baseline := settings.GetString("baseline")
+	if baseline == "" {
+		return nil
+	}
+	report, err := results.ReadJUnit(filepath.Join(outputDir, "junit_01.xml"))
+	if err != nil {
+		log.Printf("unable to read the durations of the tests: %v", err)
+		return nil
+	}
+	baselineReport, err := results.ReadJUnit(filepath.Join(baseline, "junit_01.xml"))
+	if err != nil {
+		log.Printf("unable to read the durations of the tests of the baseline: %v", err)
+		return nil
+	}
+	regressions := results.CompareDurations(report, baselineReport, settings.GetFloat64("baseline-threshold"))
+	regressions.Baseline = baseline
+	if regressions.BaselineSeconds == 0 {
+		log.Printf("No spec ran in both this run and the baseline %s", baseline)
+		return regressions
+	}
+
+	if regressions.Regressed() {
+		log.Printf("WARNING: the specs took %s, %+.0f%% compared to %s in the baseline %s", results.FormatSeconds(regressions.Seconds),
+			regressions.Percent(), results.FormatSeconds(regressions.BaselineSeconds), baseline)
+	} else {
+		log.Printf("The specs took %s, %+.0f%% compared to %s in the baseline %s", results.FormatSeconds(regressions.Seconds),
+			regressions.Percent(), results.FormatSeconds(regressions.BaselineSeconds), baseline)
+	}
+	for _, spec := range regressions.Slower {
+		log.Printf("SLOWER %s: %s, was %s", spec.Name, results.FormatSeconds(spec.Seconds), results.FormatSeconds(spec.BaselineSeconds))
+	}
+	return regressions
+}
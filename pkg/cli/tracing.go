@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+	"sigs.k8s.io/hydrophone/pkg/trace"
+	"sigs.k8s.io/hydrophone/pkg/version"
+)
+
+var (
+	// runTracer records the spans of the run, nil without --otlp-endpoint
+	runTracer *trace.Tracer
+	// runSpan is the root span of the run, the steps of the run are its children
+	runSpan *trace.Span
+)
+
+// startTracing starts tracing the run to the OTLP endpoint of
+// --otlp-endpoint. Headers of the exports, e.g. to authenticate, are read
+// from OTEL_EXPORTER_OTLP_HEADERS.
+func startTracing() error {
+	endpoint := settings.GetString("otlp-endpoint")
+	if endpoint == "" {
+		return nil
+	}
+	headers, err := trace.ParseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		return fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS: %w", err)
+	}
+	tracer, err := trace.New(endpoint, headers, map[string]string{
+		"service.name":    "hydrophone",
+		"service.version": version.Get(),
+	})
+	if err != nil {
+		return fmt.Errorf("invalid --otlp-endpoint: %w", err)
+	}
+	runTracer = tracer
+	runSpan = tracer.StartAt("run", nil, runStarted)
+	runSpan.SetAttribute("conformance.image", settings.GetString("conformance-image"))
+	runSpan.SetAttribute("k8s.namespace.name", settings.GetString("namespace"))
+	return nil
+}
+
+// traceStep starts the span of a step of the run, the step is the phase of
+// the progress snapshot as well
+func traceStep(name string) *trace.Span {
+	runProgress.setPhase(name)
+	return runTracer.Start(name, runSpan)
+}
+
+// traceStream traces the streaming of the logs of the conformance pods: the
+// wait for the pods to be ready, the stream itself and, with --trace-tests,
+// the specs of the stream. The returned function ends the spans.
+func traceStream(c *client.Client) func() {
+	if runTracer == nil {
+		runProgress.setPhase("stream logs")
+		return func() {}
+	}
+	started := time.Now()
+	stream := traceStep("stream logs")
+	if settings.GetBool("trace-tests") {
+		c.OnSpec = func(name, status string, start, end time.Time) {
+			span := runTracer.StartAt(name, stream, start)
+			span.SetAttribute("test.status", status)
+			var err error
+			if status == results.StatusFailed {
+				err = errors.New("the test failed")
+			}
+			span.EndAt(end, err)
+		}
+	}
+	return func() {
+		c.OnSpec = nil
+		if first := c.StreamStarted(); !first.IsZero() {
+			runTracer.StartAt("wait for ready", stream, started).EndAt(first, nil)
+		}
+		stream.End(nil)
+	}
+}
+
+// finishTracing ends the span of the run, failed with the error if it isn't
+// nil, and exports the spans.
+func finishTracing(exitCode int, err error) {
+	if runTracer == nil || runSpan == nil {
+		return
+	}
+	runSpan.SetAttribute("exit.code", strconv.Itoa(exitCode))
+	runSpan.End(err)
+	runSpan = nil
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := runTracer.Flush(ctx); err != nil {
+		log.Printf("unable to export the trace of the run: %v", err)
+	}
+}
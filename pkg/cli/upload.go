@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+	"sigs.k8s.io/hydrophone/pkg/upload"
+)
+
+// newUploader returns the uploader of --upload, nil when the artifacts aren't
+// uploaded
+func newUploader() (*upload.Uploader, error) {
+	url := settings.GetString("upload")
+	if url == "" {
+		return nil, nil
+	}
+	target, err := upload.ParseTarget(url)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := upload.NewBackend(target)
+	if err != nil {
+		return nil, err
+	}
+	backoff, err := common.GetDuration("upload-backoff")
+	if err != nil {
+		return nil, err
+	}
+	return &upload.Uploader{
+		Backend: backend,
+		Target:  target,
+		Retries: settings.GetInt("upload-retries"),
+		Backoff: backoff,
+	}, nil
+}
+
+// uploadArtifacts uploads the artifacts of the run in the output directory
+func uploadArtifacts(uploader *upload.Uploader, outputDir string) error {
+	names, err := runArtifacts(outputDir)
+	if err != nil {
+		return err
+	}
+	log.Printf("uploading the artifacts of the run to %s", uploader.Target)
+	keys, err := uploader.Upload(context.Background(), outputDir, names)
+	if err != nil {
+		return err
+	}
+	log.Printf("uploaded %d files to %s", len(keys), uploader.Target)
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/service"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+	"sigs.k8s.io/hydrophone/pkg/version"
+)
+
+var (
+	versionOutput string
+	versionServer bool
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version of hydrophone and whether it supports the cluster.",
+	Long: `Print the version of hydrophone and whether it supports the cluster.
+
+With --server the cluster is contacted and its version, the conformance image
+that would be selected for it and whether hydrophone is tested with both are
+printed as well, the information to include in bug reports.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if versionOutput != "text" && versionOutput != "json" {
+			log.Fatalf("expected --output to be text or json, got %q", versionOutput)
+		}
+
+		if versionServer {
+			config, clientSet := service.Init(settings.GetString("kubeconfig"))
+			common.PrintInfo(clientSet, config)
+		}
+		report, err := common.NewVersionReport(version.Get(), settings.GetString("server-version"), settings.GetString("conformance-image"))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if versionOutput == "json" {
+			enc := json.NewEncoder(stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		fmt.Fprintf(stdout, "hydrophone: %s\n", report.Hydrophone)
+		if report.Server != "" {
+			fmt.Fprintf(stdout, "server: %s\n", report.Server)
+		}
+		if report.ConformanceImage != "" {
+			fmt.Fprintf(stdout, "conformance image: %s\n", report.ConformanceImage)
+		}
+		if report.Supported != nil {
+			fmt.Fprintf(stdout, "supported: %t\n", *report.Supported)
+		}
+		for _, warning := range report.Warnings {
+			fmt.Fprintf(stdout, "warning: %s\n", warning)
+		}
+	},
+}
+
+func init() {
+	versionCmd.Flags().StringVarP(&versionOutput, "output", "o", "text", "output format, text or json.")
+	versionCmd.Flags().BoolVar(&versionServer, "server", false, "contact the cluster and report its version, the conformance image selected for it and whether hydrophone supports them.")
+
+	rootCmd.AddCommand(versionCmd)
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"math"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+const (
+	// reconnectCap is the longest delay before re-establishing a watch or a
+	// log stream. Managed control planes close long-lived connections every
+	// few minutes, so the delay stays short enough to not lose much time
+	// while still spreading reconnects of many clients.
+	reconnectCap = 30 * time.Second
+	// defaultMaxReconnects is the number of consecutive failed attempts
+	// after which hydrophone gives up, unless --max-reconnects is set
+	defaultMaxReconnects = 10
+)
+
+// maxReconnectFailures returns the number of consecutive failed attempts to
+// re-establish a watch or a log stream after which hydrophone gives up
+func maxReconnectFailures() int {
+	if settings.IsSet("max-reconnects") {
+		return settings.GetInt("max-reconnects")
+	}
+	return defaultMaxReconnects
+}
+
+// reconnectBackoff returns a jittered exponential backoff for re-establishing
+// watches and log streams. A connection that stayed up for longer than
+// reconnectCap is considered healthy and starts over with a fresh backoff.
+func reconnectBackoff() wait.Backoff {
+	return wait.Backoff{
+		Duration: time.Second,
+		Factor:   2,
+		Jitter:   0.5,
+		Steps:    math.MaxInt32,
+		Cap:      reconnectCap,
+	}
+}
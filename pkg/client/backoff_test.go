@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestReconnectBackoff(t *testing.T) {
+	expected := []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second,
+		reconnectCap, reconnectCap, reconnectCap,
+	}
+
+	// without the jitter the delays double up to the cap and stay there
+	backoff := reconnectBackoff()
+	backoff.Jitter = 0
+	for i, delay := range expected {
+		assert.Equal(t, delay, backoff.Step(), "attempt %d", i+1)
+	}
+
+	// the jitter adds up to half of each delay
+	backoff = reconnectBackoff()
+	for i, delay := range expected {
+		step := backoff.Step()
+		assert.GreaterOrEqual(t, step, delay, "attempt %d", i+1)
+		assert.LessOrEqual(t, step, delay+delay/2, "attempt %d", i+1)
+	}
+}
+
+func TestMaxReconnectFailures(t *testing.T) {
+	defer settings.Set("max-reconnects", nil)
+
+	assert.Equal(t, defaultMaxReconnects, maxReconnectFailures())
+
+	settings.Set("max-reconnects", 3)
+	assert.Equal(t, 3, maxReconnectFailures())
+
+	// 0 gives up at the first failed attempt
+	settings.Set("max-reconnects", 0)
+	assert.Equal(t, 0, maxReconnectFailures())
+}
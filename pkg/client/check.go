@@ -17,21 +17,36 @@ limitations under the License.
 package client
 
 import (
-	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"time"
 
-	"github.com/spf13/viper"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 
 	"sigs.k8s.io/hydrophone/pkg/common"
 	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/redact"
+	"sigs.k8s.io/hydrophone/pkg/settings"
 )
 
+// jobCheckInterval is the interval at which waitForJobPod checks whether the
+// job failed
+const jobCheckInterval = 10 * time.Second
+
+// seedRegexp matches the line ginkgo prints with the seed used to randomize the specs
+var seedRegexp = regexp.MustCompile(`Random Seed: (\d+)`)
+
 // Contains all the necessary channels to transfer data
 type streamLogs struct {
 	logCh  chan string
@@ -39,89 +54,332 @@ type streamLogs struct {
 	doneCh chan bool
 }
 
-// PrintE2ELogs checks for Pod and start a go routine if new deployment added
+// PrintE2ELogs waits for the conformance pods to start and streams their logs.
+// When tests are split across shards each line is prefixed with the pod it comes from.
+// With --echo-interval or --echo-sample only part of the lines is printed.
+// The output of the failed specs is appended to failures.log in the output
+// directory as they complete.
 func (c *Client) PrintE2ELogs() {
-	informerFactory := informers.NewSharedInformerFactory(c.ClientSet, 10*time.Second)
+	namespace := settings.GetString("namespace")
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(c.ClientSet, 10*time.Second, informers.WithNamespace(namespace))
+	var nodeInformerFactory informers.SharedInformerFactory
+	if !common.Restricted() {
+		nodeInformerFactory = informers.NewSharedInformerFactory(c.ClientSet, 10*time.Second)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
 
 	podInformer := informerFactory.Core().V1().Pods()
+	changes := newPodChanges()
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { changes.notify() },
+		UpdateFunc: func(any, any) { changes.notify() },
+		DeleteFunc: func(any) { changes.notify() },
+	})
+	monitorPods(informerFactory, nodeInformerFactory)
 
-	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{})
+	informerFactory.Start(stop)
+	informerFactory.WaitForCacheSync(stop)
+	if nodeInformerFactory != nil {
+		nodeInformerFactory.Start(stop)
+		nodeInformerFactory.WaitForCacheSync(stop)
+	}
 
-	informerFactory.Start(wait.NeverStop)
-	informerFactory.WaitForCacheSync(wait.NeverStop)
+	stream := streamLogs{
+		logCh:  make(chan string),
+		errCh:  make(chan error),
+		doneCh: make(chan bool),
+	}
 
-	for {
-		pod, _ := podInformer.Lister().Pods(viper.GetString("namespace")).Get(common.PodName)
-		if pod.Status.Phase == v1.PodRunning {
-			var err error
-			stream := streamLogs{
-				logCh:  make(chan string),
-				errCh:  make(chan error),
-				doneCh: make(chan bool),
-			}
+	failuresPath := filepath.Join(settings.GetString("output-dir"), FailuresFile)
+	failuresFile, err := os.OpenFile(failuresPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		log.Fatalf("unable to create %s: %v", failuresPath, err)
+	}
+	defer failuresFile.Close()
 
-			go getPodLogs(c.ClientSet, stream)
+	podNames := common.PodNames()
+	var prefixes []string
+	// podOfPrefix maps the prefixes of the lines to the pods they come from
+	podOfPrefix := map[string]string{}
+	for _, podName := range podNames {
+		prefix := ""
+		if len(podNames) > 1 {
+			prefix = fmt.Sprintf("[%s] ", podName)
+			prefixes = append(prefixes, prefix)
+		}
+		podOfPrefix[prefix] = podName
+		go func(podName, prefix string) {
+			if !jobWorkload() {
+				// the pod whose logs were streamed last, its replacement
+				// has the same name
+				var streamed types.UID
+				for {
+					for {
+						next := changes.next()
+						pod, err := podInformer.Lister().Pods(namespace).Get(podName)
+						if err == nil && pod.UID != streamed && pod.Status.Phase != v1.PodPending {
+							streamed = pod.UID
+							break
+						}
+						<-next
+					}
+					if !c.getPodLogs(namespace, podName, common.ConformanceContainer, prefix, stream) {
+						return
+					}
+					recreated := false
+					if c.OnPodLost != nil {
+						var err error
+						if recreated, err = c.OnPodLost(podName); err != nil {
+							stream.errCh <- err
+							return
+						}
+					}
+					if !recreated {
+						stream.errCh <- fmt.Errorf("pod %s was lost before the tests completed", podName)
+						return
+					}
+					c.PodRestarts.Add(1)
+					log.Printf("pod %s was lost and recreated, the tests continue in its replacement", podName)
+				}
+			}
 
-		loop:
+			// the job replaces lost pods, follow the replacements until the
+			// tests of one of them complete
 			for {
-				select {
-				case err = <-stream.errCh:
-					log.Fatal(err)
-				case logStream := <-stream.logCh:
-					_, err = fmt.Print(logStream)
-					if err != nil {
-						log.Fatal(err)
-					}
-				case <-stream.doneCh:
-					break loop
+				current, err := c.waitForJobPod(podInformer.Lister(), changes, namespace, podName)
+				if err != nil {
+					stream.errCh <- err
+					return
+				}
+				if !c.getPodLogs(namespace, current, common.ConformanceContainer, prefix, stream) {
+					return
 				}
+				c.PodRestarts.Add(1)
+				log.Printf("pod %s of job %s was lost, the tests start over in its replacement", current, podName)
 			}
-			break
+		}(podName, prefix)
+	}
+
+	var output io.Writer = os.Stdout
+	if c.LogOutput != nil {
+		output = c.LogOutput
+	}
+	echo := newEchoThrottle()
+	writeOutput := func(line string) {
+		if _, err := io.WriteString(output, line); err != nil {
+			log.Fatal(err)
+		}
+	}
+	failures := newFailureLog(failuresFile, prefixes)
+	timer := newSpecTimer(prefixes, func(name, status string, start, end time.Time) {
+		c.specsMu.Lock()
+		c.completedSpecs = append(c.completedSpecs, name)
+		if c.currentSpec == name {
+			c.currentSpec = ""
+		}
+		c.specsMu.Unlock()
+		if c.OnSpec != nil {
+			c.OnSpec(name, status, start, end)
+		}
+		if line, ok := echo.failed(name, status); ok {
+			writeOutput(line)
+		}
+	})
+	timer.started = func(name string) {
+		c.specsMu.Lock()
+		c.currentSpec = name
+		c.specsMu.Unlock()
+	}
+	for done := 0; done < len(podNames); {
+		select {
+		case err := <-stream.errCh:
+			log.Fatal(err)
+		case logStream := <-stream.logCh:
+			if c.stopped.Load() {
+				continue
+			}
+			logStream = redact.String(logStream)
+			if c.Seed == 0 {
+				c.Seed = parseSeed(logStream)
+			}
+			c.streamStarted.CompareAndSwap(0, time.Now().UnixNano())
+			c.Specs.Add(logStream)
+			timer.add(logStream)
+			// the logs of the tests don't reach the log sinks, the specs
+			// that take long do
+			if spec, ok := parseProgressReport(prefixes, logStream); ok {
+				log.Printf("%sspec running for %s: %s", spec.Prefix, spec.Runtime.Round(time.Second), spec.Name)
+				if c.OnHang != nil {
+					go c.notifyHang(namespace, podOfPrefix[spec.Prefix], spec)
+				}
+			}
+			if echo.line(logStream) {
+				writeOutput(logStream)
+			}
+			if line, ok := echo.progress(&c.Specs); ok {
+				writeOutput(line)
+			}
+			if err := failures.add(logStream); err != nil {
+				log.Fatalf("unable to write %s: %v", failuresPath, err)
+			}
+		case <-stream.doneCh:
+			done++
 		}
 	}
+	if err := failures.flush(); err != nil {
+		log.Fatalf("unable to write %s: %v", failuresPath, err)
+	}
 }
 
-// FetchExitCode waits for pod to be in terminated state and get the exit code
-func (c *Client) FetchExitCode() {
-	// Watching the pod's status
-	watchInterface, err := c.ClientSet.CoreV1().Pods(viper.GetString("namespace")).Watch(context.TODO(), metav1.ListOptions{
-		FieldSelector: fmt.Sprintf("metadata.name=%s", common.PodName),
-	})
+// notifyHang passes the spec to OnHang with the name of the pod running it,
+// the current pod of the job with --workload=job
+func (c *Client) notifyHang(namespace, podName string, spec RunningSpec) {
+	podName, err := resolvePodName(c.ClientSet, namespace, podName)
+	if err != nil {
+		log.Printf("unable to find the pod running spec %s: %v", spec.Name, err)
+		return
+	}
+	c.OnHang(podName, spec)
+}
+
+// parseSeed returns the ginkgo random seed contained in the line, or 0 if there is none
+func parseSeed(line string) int64 {
+	match := seedRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return 0
+	}
+	seed, err := strconv.ParseInt(match[1], 10, 64)
 	if err != nil {
-		log.Fatal(err)
+		return 0
 	}
+	return seed
+}
 
-	log.Println("Waiting for pod to terminate...")
-	for event := range watchInterface.ResultChan() {
-		pod, ok := event.Object.(*v1.Pod)
-		if !ok {
-			log.Println("unexpected type")
-			c.ExitCode = -1
-			return
+// waitForJobPod waits for the job to have a pod that isn't pending and returns
+// its name. It fails when the job failed, which is checked every
+// jobCheckInterval.
+func (c *Client) waitForJobPod(lister corelisters.PodLister, changes *podChanges, namespace, jobName string) (string, error) {
+	ticker := time.NewTicker(jobCheckInterval)
+	defer ticker.Stop()
+	for {
+		next := changes.next()
+		pods, err := lister.Pods(namespace).List(jobSelector(jobName))
+		if err == nil {
+			if pod := currentJobPod(pods); pod != nil && pod.Status.Phase != v1.PodPending {
+				return pod.Name, nil
+			}
 		}
+		select {
+		case <-next:
+		case <-ticker.C:
+			if err := jobFailed(c.ClientSet, namespace, jobName); err != nil {
+				return "", err
+			}
+		}
+	}
+}
 
-		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
-			log.Println("Pod terminated.")
-			for _, containerStatus := range pod.Status.ContainerStatuses {
-				if containerStatus.Name == common.ConformanceContainer && containerStatus.State.Terminated != nil {
-					c.ExitCode = int(containerStatus.State.Terminated.ExitCode)
-				}
+// FetchExitCode waits for the conformance pods to be in terminated state and
+// gets the exit code. The first non-zero exit code of all shards wins.
+func (c *Client) FetchExitCode() {
+	for _, name := range common.PodNames() {
+		podName, err := resolvePodName(c.ClientSet, settings.GetString("namespace"), name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		exitCode := fetchPodExitCode(c, podName)
+		if c.ExitCode == 0 {
+			c.ExitCode = exitCode
+		}
+	}
+}
+
+// fetchPodExitCode waits for the pod to be in terminated state and returns
+// the exit code of the conformance container. The watch is re-established
+// with a backoff when the API server closes it.
+func fetchPodExitCode(c *Client, podName string) int {
+	pods := c.ClientSet.CoreV1().Pods(settings.GetString("namespace"))
+	backoff := reconnectBackoff()
+	failures := 0
+
+	log.Printf("Waiting for pod %s to terminate...", podName)
+	for {
+		// the state is read before watching so that no transition is missed
+		// between two watches
+		pod, err := pods.Get(ctx, podName, metav1.GetOptions{})
+		if err == nil {
+			if exitCode, terminated := podExitCode(pod); terminated {
+				return exitCode
 			}
-			break
-		} else if pod.Status.Phase == v1.PodRunning {
-			terminated := false
-			for _, containerStatus := range pod.Status.ContainerStatuses {
-				if containerStatus.State.Terminated != nil {
-					terminated = true
-					log.Printf("container %s terminated.\n", containerStatus.Name)
-					if containerStatus.Name == common.ConformanceContainer {
-						c.ExitCode = int(containerStatus.State.Terminated.ExitCode)
+
+			var watchInterface watch.Interface
+			watchInterface, err = pods.Watch(ctx, metav1.ListOptions{
+				FieldSelector:   fmt.Sprintf("metadata.name=%s", podName),
+				ResourceVersion: pod.ResourceVersion,
+			})
+			if err == nil {
+				failures = 0
+				start := time.Now()
+				for event := range watchInterface.ResultChan() {
+					pod, ok := event.Object.(*v1.Pod)
+					if !ok {
+						// e.g. an expired resource version, start over
+						break
 					}
+					if exitCode, terminated := podExitCode(pod); terminated {
+						watchInterface.Stop()
+						return exitCode
+					}
+				}
+				watchInterface.Stop()
+				if time.Since(start) > reconnectCap {
+					backoff = reconnectBackoff()
 				}
 			}
-			if terminated {
-				break
+		}
+
+		if err != nil {
+			failures++
+			if failures > maxReconnectFailures() {
+				log.Fatal(err)
+			}
+		}
+		delay := backoff.Step()
+		c.Reconnects.Add(1)
+		if errors.IsUnauthorized(err) {
+			log.Printf("watch of pod %s rejected the credentials, reconnecting with refreshed credentials in %s", podName, delay.Round(time.Millisecond))
+		} else {
+			log.Printf("watch of pod %s closed, reconnecting in %s", podName, delay.Round(time.Millisecond))
+		}
+		time.Sleep(delay)
+	}
+}
+
+// podExitCode returns the exit code of the conformance container and whether
+// the pod is done running the tests
+func podExitCode(pod *v1.Pod) (int, bool) {
+	exitCode := 0
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		log.Printf("Pod %s terminated.", pod.Name)
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if containerStatus.Name == common.ConformanceContainer && containerStatus.State.Terminated != nil {
+				exitCode = int(containerStatus.State.Terminated.ExitCode)
+			}
+		}
+		return exitCode, true
+	} else if pod.Status.Phase == v1.PodRunning {
+		terminated := false
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if containerStatus.State.Terminated != nil {
+				terminated = true
+				log.Printf("container %s terminated.\n", containerStatus.Name)
+				if containerStatus.Name == common.ConformanceContainer {
+					exitCode = int(containerStatus.State.Terminated.ExitCode)
+				}
 			}
 		}
+		return exitCode, terminated
 	}
+	return exitCode, false
 }
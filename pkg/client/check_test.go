@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSeed(t *testing.T) {
+	testCases := []struct {
+		name     string
+		line     string
+		expected int64
+	}{
+		{
+			name:     "seed line",
+			line:     "Random Seed: 1712345678 - will randomize all specs",
+			expected: 1712345678,
+		},
+		{
+			name:     "prefixed by the pod",
+			line:     "[e2e-conformance-test-1] Random Seed: 42",
+			expected: 42,
+		},
+		{
+			name: "no seed",
+			line: "Will run 402 of 7391 specs",
+		},
+		{
+			name: "empty line",
+			line: "",
+		},
+		{
+			name: "seed overflowing int64",
+			line: "Random Seed: 99999999999999999999",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseSeed(tc.line))
+		})
+	}
+}
@@ -17,16 +17,28 @@ limitations under the License.
 package client
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/spf13/viper"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
 	"sigs.k8s.io/hydrophone/pkg/common"
 	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/plugin"
+	"sigs.k8s.io/hydrophone/pkg/redact"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
 )
 
 var (
@@ -35,33 +47,283 @@ var (
 
 // Client is a struct that holds the clientset and exit code
 type Client struct {
-	ClientSet *kubernetes.Clientset
+	ClientSet kubernetes.Interface
 	ExitCode  int
+	// Seed is the random seed reported by ginkgo at the start of the run
+	Seed int64
+	// Reconnects counts how often watches and log streams were re-established
+	// after the API server closed them
+	Reconnects atomic.Int64
+	// PodRestarts counts the conformance pods lost and replaced by their job
+	// with --workload=job, or recreated by OnPodLost
+	PodRestarts atomic.Int64
+	// Specs counts the specs of the run as they complete
+	Specs SpecProgress
+	// OnSpec is notified of the specs of the log stream as they complete
+	OnSpec SpecObserver
+	// OnPodLost handles the conformance pods lost before their tests
+	// completed. Without it the run fails.
+	OnPodLost PodLostHandler
+	// OnHang is notified of the specs ginkgo reports as running for long,
+	// see --progress-report
+	OnHang HangHandler
+	// LogOutput receives the logs of the conformance pods, stdout when nil
+	LogOutput io.Writer
+	// completedSpecs holds the names of the specs that completed in the log
+	// stream
+	completedSpecs []string
+	// currentSpec is the name of the spec that started last, until it
+	// completes
+	currentSpec string
+	specsMu     sync.Mutex
+	// streamStarted is the time the first line of the logs was received at
+	// in unix nanoseconds
+	streamStarted atomic.Int64
+	// stopped is set when the logs of the pods stop being printed
+	stopped atomic.Bool
 }
 
-// FetchFiles downloads the e2e.log and junit_01.xml files from the pod
-// and writes them to the output directory
-func (c *Client) FetchFiles(config *rest.Config, clientset *kubernetes.Clientset, outputDir string) {
-	log.Println("downloading e2e.log to ", filepath.Join(outputDir, "e2e.log"))
-	e2eLogFile, err := os.OpenFile(filepath.Join(outputDir, "e2e.log"), os.O_WRONLY|os.O_CREATE, 0600)
-	if err != nil {
-		log.Fatalf("unable to create e2e.log: %v\n", err)
+// StreamStarted returns the time the first line of the logs of the
+// conformance pods was received at, the zero time before
+func (c *Client) StreamStarted() time.Time {
+	if started := c.streamStarted.Load(); started != 0 {
+		return time.Unix(0, started)
 	}
-	defer e2eLogFile.Close()
-	err = downloadFile(config, clientset, viper.GetString("namespace"), common.PodName, common.OutputContainer, "/tmp/results/e2e.log", e2eLogFile)
-	if err != nil {
-		log.Fatalf("unable to download e2e.log: %v\n", err)
+	return time.Time{}
+}
+
+// CompletedSpecs returns the names of the specs that completed in the log
+// stream so far
+func (c *Client) CompletedSpecs() []string {
+	c.specsMu.Lock()
+	defer c.specsMu.Unlock()
+	return append([]string{}, c.completedSpecs...)
+}
+
+// CurrentSpec returns the name of the spec that started last in the log
+// stream, empty once it completed
+func (c *Client) CurrentSpec() string {
+	c.specsMu.Lock()
+	defer c.specsMu.Unlock()
+	return c.currentSpec
+}
+
+// StopStreaming stops printing the logs of the conformance pods, e.g. when
+// the run is being aborted
+func (c *Client) StopStreaming() {
+	c.stopped.Store(true)
+}
+
+// FetchFiles downloads the e2e.log and junit_01.xml files from the pods
+// and writes them to the output directory. When tests are split across shards
+// the files of each shard are written to a shard-N subdirectory and the junit
+// reports are merged into a single junit_01.xml in the output directory. It
+// returns true when the artifacts of a pod couldn't be downloaded and only
+// what the log API returned was recovered, the results are partial.
+func (c *Client) FetchFiles(config *rest.Config, clientset kubernetes.Interface, outputDir string) bool {
+	var podNames []string
+	for _, name := range common.PodNames() {
+		podName, err := resolvePodName(clientset, settings.GetString("namespace"), name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		podNames = append(podNames, podName)
+	}
+	if len(podNames) == 1 {
+		return !fetchArtifacts(config, clientset, podNames[0], outputDir)
+	}
+
+	partial := false
+	var reports []*results.JUnitTestSuites
+	for shard, podName := range podNames {
+		shardDir := filepath.Join(outputDir, fmt.Sprintf("shard-%d", shard))
+		if err := os.MkdirAll(shardDir, 0755); err != nil {
+			log.Fatalf("error creating output directory [%s] : %v", shardDir, err)
+		}
+		if !fetchArtifacts(config, clientset, podName, shardDir) {
+			// the junit report of the shard is missing
+			partial = true
+			continue
+		}
+
+		report, err := results.ReadJUnit(filepath.Join(shardDir, "junit_01.xml"))
+		if err != nil {
+			log.Fatalf("unable to read junit report of shard %d: %v\n", shard, err)
+		}
+		reports = append(reports, report)
+	}
+	if len(reports) == 0 {
+		return partial
+	}
+
+	log.Println("merging junit reports to", filepath.Join(outputDir, "junit_01.xml"))
+	if err := results.WriteJUnit(filepath.Join(outputDir, "junit_01.xml"), results.MergeJUnit(reports...)); err != nil {
+		log.Fatalf("unable to write merged junit report: %v\n", err)
+	}
+	return partial
+}
+
+// downloadArtifacts downloads the e2e.log and junit_01.xml files of a single
+// pod. With --artifacts the files of the results directory matching the globs
+// are downloaded instead of e2e.log, junit_01.xml is always downloaded. With
+// --plugin the artifacts of the plugin are downloaded, along with its JUnit
+// report as junit_01.xml when it has one.
+func downloadArtifacts(config *rest.Config, clientset kubernetes.Interface, podName, outputDir string) error {
+	files := []string{"e2e.log"}
+	junitReport := "junit_01.xml"
+	patterns := settings.GetStringSlice("artifacts")
+	if pluginFile := settings.GetString("plugin"); pluginFile != "" {
+		p, err := plugin.Load(pluginFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		files, junitReport = nil, p.JUnit
+		patterns = append(p.Artifacts, patterns...)
+	}
+	if len(patterns) > 0 {
+		var err error
+		if files, err = selectArtifacts(config, clientset, podName, patterns); err != nil {
+			return fmt.Errorf("unable to select the artifacts of pod %s: %w", podName, err)
+		}
+	}
+	if settings.GetString("artifact-transfer") == common.ArtifactTransferExec {
+		files = slices.DeleteFunc(files, func(file string) bool { return file == junitReport })
+		if err := fetchArtifactsTar(config, clientset, podName, outputDir, append(files, junitReport)); err != nil {
+			return fmt.Errorf("unable to fetch the artifacts of pod %s: %w", podName, err)
+		}
+	} else if err := downloadPodArtifacts(config, clientset, podName, outputDir, files, junitReport); err != nil {
+		return err
+	}
+	if junitReport == "" {
+		return nil
+	}
+	if err := processJUnit(outputDir); err != nil {
+		log.Fatalf("unable to process junit_01.xml: %v\n", err)
+	}
+	return nil
+}
+
+// downloadPodArtifacts downloads the files and the junit report of the pod
+// one by one through the output container
+func downloadPodArtifacts(config *rest.Config, clientset kubernetes.Interface, podName, outputDir string, files []string, junitReport string) error {
+	for _, file := range files {
+		if file == junitReport {
+			continue
+		}
+		if err := downloadArtifact(config, clientset, podName, outputDir, file); err != nil {
+			return fmt.Errorf("unable to download %s: %w", file, err)
+		}
+	}
+	if junitReport == "" {
+		return nil
 	}
 	log.Println("downloading junit_01.xml to", filepath.Join(outputDir, "junit_01.xml"))
-	junitXMLFile, err := os.OpenFile(filepath.Join(outputDir, "junit_01.xml"), os.O_WRONLY|os.O_CREATE, 0600)
+	junitXMLFile, err := os.OpenFile(filepath.Join(outputDir, "junit_01.xml"), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		log.Fatalf("unable to create junit_01.xml: %v\n", err)
 	}
-	defer junitXMLFile.Close()
-	err = downloadFile(config, clientset, viper.GetString("namespace"), common.PodName, common.OutputContainer, "/tmp/results/junit_01.xml", junitXMLFile)
+	junitXML := redact.NewWriter(junitXMLFile)
+	defer junitXML.Close()
+	err = downloadFile(config, clientset, settings.GetString("namespace"), podName, common.OutputContainer, path.Join(defaultResultsDir, junitReport), junitXML)
 	if err != nil {
-		log.Fatalf("unable to download junit_01.xml: %v\n", err)
+		return fmt.Errorf("unable to download %s: %w", junitReport, err)
+	}
+	if err := junitXML.Close(); err != nil {
+		log.Fatalf("unable to write junit_01.xml: %v\n", err)
+	}
+	return nil
+}
+
+// downloadArtifact downloads the file of the results directory of the pod to
+// the same path relative to the output directory
+func downloadArtifact(config *rest.Config, clientset kubernetes.Interface, podName, outputDir, file string) error {
+	var w io.WriteCloser
+	var dst string
+	var err error
+	if file == "e2e.log" {
+		w, dst, err = createE2ELog(outputDir)
+	} else {
+		dst = filepath.Join(outputDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		w, err = os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	}
+	if err != nil {
+		return err
+	}
+	// the secrets in the text artifacts are scrubbed as they are written
+	if redact.IsText(file) {
+		w = redact.NewWriter(w)
+	}
+	log.Printf("downloading %s to %s", file, dst)
+	if err := downloadFile(config, clientset, settings.GetString("namespace"), podName, common.OutputContainer, path.Join(defaultResultsDir, file), w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// selectArtifacts returns the files of the results directory of the pod,
+// relative to it, matching any of the globs. junit_01.xml is left out, it is
+// always downloaded.
+func selectArtifacts(config *rest.Config, clientset kubernetes.Interface, podName string, patterns []string) ([]string, error) {
+	var stdout bytes.Buffer
+	err := execInContainer(config, clientset, settings.GetString("namespace"), podName, common.ArtifactContainer(), []string{"find", defaultResultsDir, "-type", "f"}, &stdout, nil)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		if file := strings.TrimPrefix(line, defaultResultsDir+"/"); file != line {
+			files = append(files, file)
+		}
+	}
+	return filterArtifacts(files, patterns)
+}
+
+// filterArtifacts returns the files matching any of the globs, except
+// junit_01.xml
+func filterArtifacts(files, patterns []string) ([]string, error) {
+	var selected []string
+	for _, file := range files {
+		if file == "junit_01.xml" {
+			continue
+		}
+		for _, pattern := range patterns {
+			ok, err := common.MatchGlob(pattern, file)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				selected = append(selected, file)
+				break
+			}
+		}
+	}
+	return selected, nil
+}
+
+// processJUnit caps the output of each spec in the junit report of the
+// directory to --max-spec-output so that huge outputs don't break the tools
+// ingesting it, and adds the properties given with --junit-property.
+func processJUnit(dir string) error {
+	maxSize, err := common.GetByteSize("max-spec-output")
+	if err != nil {
+		return err
+	}
+	properties, err := results.ParseJUnitProperties(settings.GetStringSlice("junit-property"))
+	if err != nil {
+		return err
+	}
+	processor := &results.JUnitProcessor{
+		MaxSpecOutput: int(maxSize),
+		Properties:    properties,
+		OnTruncate: func(count int) {
+			log.Printf("truncated the output of %d specs to %s", count, &maxSize)
+		},
 	}
+	return processor.Process(dir)
 }
 
 // NewClient returns a new client
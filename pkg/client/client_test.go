@@ -0,0 +1,36 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterArtifacts(t *testing.T) {
+	files := []string{"e2e.log", "junit_01.xml", "hostlogs/node-1/kubelet.log", "hostlogs/node-1/journal.txt", "progress.json"}
+
+	selected, err := filterArtifacts(files, []string{"*.log", "hostlogs/**"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e2e.log", "hostlogs/node-1/kubelet.log", "hostlogs/node-1/journal.txt"}, selected)
+
+	selected, err = filterArtifacts(files, []string{"*.xml"})
+	require.NoError(t, err)
+	assert.Empty(t, selected)
+}
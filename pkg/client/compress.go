@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// E2ELogGzip is the name of the e2e.log gzipped with --compress
+const E2ELogGzip = "e2e.log.gz"
+
+// gzipFile is a file written through a gzip writer
+type gzipFile struct {
+	*gzip.Writer
+	f *os.File
+}
+
+// Close flushes the gzip stream and closes the file
+func (g *gzipFile) Close() error {
+	if err := g.Writer.Close(); err != nil {
+		g.f.Close()
+		return err
+	}
+	return g.f.Close()
+}
+
+// createE2ELog creates the e2e.log of the directory and returns it along
+// with its path. With --compress=gzip the content is gzipped as it is written
+// to e2e.log.gz, with --max-log-size it is split into chunks of that size,
+// the path is the one of the first chunk.
+func createE2ELog(dir string) (io.WriteCloser, string, error) {
+	maxSize, err := common.GetByteSize("max-log-size")
+	if err != nil {
+		return nil, "", err
+	}
+	gzipped := settings.GetString("compress") == common.CompressGzip
+	create := func(chunk int) (io.WriteCloser, error) {
+		return createLogFile(filepath.Join(dir, e2eLogName(chunk, gzipped)), gzipped)
+	}
+	path := filepath.Join(dir, e2eLogName(0, gzipped))
+	if maxSize <= 0 {
+		w, err := create(0)
+		return w, path, err
+	}
+	w, err := newSplitWriter(int64(maxSize), create)
+	if err != nil {
+		return nil, "", err
+	}
+	return w, path, nil
+}
+
+// e2eLogName returns the name of a chunk of e2e.log
+func e2eLogName(chunk int, gzipped bool) string {
+	name := chunkName("e2e.log", chunk)
+	if gzipped {
+		name += ".gz"
+	}
+	return name
+}
+
+// createLogFile creates the file, gzipped if requested
+func createLogFile(path string, gzipped bool) (io.WriteCloser, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil || !gzipped {
+		return f, err
+	}
+	return &gzipFile{Writer: gzip.NewWriter(f), f: f}, nil
+}
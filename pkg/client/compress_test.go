@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestCreateE2ELog(t *testing.T) {
+	dir := t.TempDir()
+	settings.Set("compress", common.CompressGzip)
+	defer settings.Set("compress", "")
+
+	require.NoError(t, writePartialFile(dir, "e2e.log", strings.NewReader("Running Suite: Kubernetes e2e suite\n")))
+	assert.NoFileExists(t, filepath.Join(dir, "e2e.log"))
+
+	f, err := os.Open(filepath.Join(dir, E2ELogGzip))
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "Running Suite: Kubernetes e2e suite\n", string(data))
+
+	settings.Set("compress", common.CompressNone)
+	w, path, err := createE2ELog(dir)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Equal(t, filepath.Join(dir, "e2e.log"), path)
+}
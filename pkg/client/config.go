@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// LoadingRules returns the rules loading the kubeconfig the way kubectl does.
+// kubeconfig is a single file, which has to exist, or a list of files
+// separated like the paths of KUBECONFIG, which are merged with the first file
+// setting a value winning and missing files ignored.
+func LoadingRules(kubeconfig string) *clientcmd.ClientConfigLoadingRules {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	paths := filepath.SplitList(kubeconfig)
+	switch len(paths) {
+	case 0:
+	case 1:
+		rules.ExplicitPath = paths[0]
+	default:
+		rules.Precedence = paths
+	}
+	return rules
+}
+
+// LoadConfig returns the configuration of the cluster of the kubeconfig,
+// loaded with LoadingRules. A non-empty context replaces the current context
+// of the kubeconfig and a non-empty cluster replaces the cluster of the
+// context, like --context and --cluster of kubectl.
+func LoadConfig(kubeconfig, context, cluster string) (*rest.Config, error) {
+	rules := LoadingRules(kubeconfig)
+	raw, err := rules.Load()
+	if err != nil {
+		return nil, err
+	}
+	if context != "" {
+		if _, ok := raw.Contexts[context]; !ok {
+			return nil, fmt.Errorf("context %s not found in the kubeconfig", context)
+		}
+	}
+	if cluster != "" {
+		if _, ok := raw.Clusters[cluster]; !ok {
+			return nil, fmt.Errorf("cluster %s not found in the kubeconfig", cluster)
+		}
+	}
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: context,
+		Context:        clientcmdapi.Context{Cluster: cluster},
+	}
+	return clientcmd.NewNonInteractiveClientConfig(*raw, context, overrides, rules).ClientConfig()
+}
+
+// ClusterName returns the name of the cluster of the kubeconfig LoadConfig
+// connects to with the same arguments.
+func ClusterName(kubeconfig, context, cluster string) (string, error) {
+	if cluster != "" {
+		return cluster, nil
+	}
+	raw, err := LoadingRules(kubeconfig).Load()
+	if err != nil {
+		return "", err
+	}
+	if context == "" {
+		context = raw.CurrentContext
+	}
+	kubeContext, ok := raw.Contexts[context]
+	if !ok {
+		return "", fmt.Errorf("context %s not found in the kubeconfig", context)
+	}
+	return kubeContext.Cluster, nil
+}
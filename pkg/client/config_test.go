@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	prodKubeconfig = `apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: prod-admin
+users:
+- name: prod-admin
+  user:
+    token: prod-token
+`
+	stagingKubeconfig = `apiVersion: v1
+kind: Config
+current-context: staging
+clusters:
+- name: staging
+  cluster:
+    server: https://staging.example.com
+contexts:
+- name: staging
+  context:
+    cluster: staging
+    user: staging-admin
+users:
+- name: staging-admin
+  user:
+    token: staging-token
+`
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	prod := filepath.Join(dir, "prod")
+	staging := filepath.Join(dir, "staging")
+	require.NoError(t, os.WriteFile(prod, []byte(prodKubeconfig), 0600))
+	require.NoError(t, os.WriteFile(staging, []byte(stagingKubeconfig), 0600))
+	merged := strings.Join([]string{prod, staging, filepath.Join(dir, "missing")}, string(filepath.ListSeparator))
+
+	tests := []struct {
+		name       string
+		kubeconfig string
+		context    string
+		cluster    string
+		host       string
+		token      string
+		wantErr    bool
+	}{
+		{name: "single file", kubeconfig: staging, host: "https://staging.example.com", token: "staging-token"},
+		{name: "merged, current context of the first file", kubeconfig: merged, host: "https://prod.example.com", token: "prod-token"},
+		{name: "merged, context of the second file", kubeconfig: merged, context: "staging", host: "https://staging.example.com", token: "staging-token"},
+		{name: "cluster of the context replaced", kubeconfig: merged, cluster: "staging", host: "https://staging.example.com", token: "prod-token"},
+		{name: "unknown context", kubeconfig: merged, context: "dev", wantErr: true},
+		{name: "unknown cluster", kubeconfig: merged, cluster: "dev", wantErr: true},
+		{name: "missing file", kubeconfig: filepath.Join(dir, "missing"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := LoadConfig(tt.kubeconfig, tt.context, tt.cluster)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.host, config.Host)
+			assert.Equal(t, tt.token, config.BearerToken)
+		})
+	}
+}
+
+func TestClusterName(t *testing.T) {
+	dir := t.TempDir()
+	prod := filepath.Join(dir, "prod")
+	staging := filepath.Join(dir, "staging")
+	require.NoError(t, os.WriteFile(prod, []byte(prodKubeconfig), 0600))
+	require.NoError(t, os.WriteFile(staging, []byte(stagingKubeconfig), 0600))
+	merged := strings.Join([]string{prod, staging}, string(filepath.ListSeparator))
+
+	name, err := ClusterName(merged, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "prod", name)
+
+	name, err = ClusterName(merged, "staging", "")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", name)
+
+	name, err = ClusterName(merged, "prod", "staging")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", name)
+
+	_, err = ClusterName(merged, "dev", "")
+	assert.Error(t, err)
+}
@@ -17,8 +17,13 @@ limitations under the License.
 package client
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"path"
+	"strconv"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -27,9 +32,66 @@ import (
 	"k8s.io/client-go/tools/remotecommand"
 )
 
-func downloadFile(config *rest.Config, clientset *kubernetes.Clientset,
+// downloadFile streams the file of the container to the writer, without
+// holding it in memory, and logs the progress of long downloads.
+func downloadFile(config *rest.Config, clientset kubernetes.Interface,
 	namespace, podName, containerName, filePath string,
 	writer io.Writer) error {
+	// the size is only used to report the progress
+	size, _ := fileSize(config, clientset, namespace, podName, containerName, filePath)
+	progress := newProgressWriter(writer, path.Base(filePath), size)
+	if err := execInContainer(config, clientset, namespace, podName, containerName, []string{"cat", filePath}, limitWriter(progress), nil); err != nil {
+		return err
+	}
+	progress.done()
+	return nil
+}
+
+// fileSize returns the size of the file of the container
+func fileSize(config *rest.Config, clientset kubernetes.Interface,
+	namespace, podName, containerName, filePath string) (int64, error) {
+	var stdout bytes.Buffer
+	if err := execInContainer(config, clientset, namespace, podName, containerName, []string{"stat", "-c", "%s", filePath}, &stdout, nil); err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(stdout.String()), 10, 64)
+}
+
+// execInContainer runs the command in the container of the pod, streaming its
+// stdout and stderr to the writers. The command is retried on transient
+// errors as long as it hasn't written anything, the output of a retry would
+// repeat what was written already.
+func execInContainer(config *rest.Config, clientset kubernetes.Interface,
+	namespace, podName, containerName string, command []string,
+	stdout, stderr io.Writer) error {
+	var written countingWriter
+	if stdout != nil {
+		stdout = io.MultiWriter(stdout, &written)
+	}
+	if stderr != nil {
+		stderr = io.MultiWriter(stderr, &written)
+	}
+	return Retry(fmt.Sprintf("running %s in pod %s", command[0], podName), func() error {
+		err := streamExec(config, clientset, namespace, podName, containerName, command, stdout, stderr)
+		if err != nil && written > 0 {
+			return fmt.Errorf("%s in pod %s failed after %d bytes of output: %v", command[0], podName, int64(written), err)
+		}
+		return err
+	})
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+// streamExec runs the command in the container of the pod once
+func streamExec(config *rest.Config, clientset kubernetes.Interface,
+	namespace, podName, containerName string, command []string,
+	stdout, stderr io.Writer) error {
 	// Create an exec request
 	req := clientset.CoreV1().RESTClient().Post().
 		Resource("pods").
@@ -44,9 +106,9 @@ func downloadFile(config *rest.Config, clientset *kubernetes.Clientset,
 	}
 	// Configure exec options
 	option := &corev1.PodExecOptions{
-		Stdout:  true,
-		Stderr:  false,
-		Command: []string{"cat", filePath},
+		Stdout:  stdout != nil,
+		Stderr:  stderr != nil,
+		Command: command,
 	}
 	parameterCodec := runtime.NewParameterCodec(scheme)
 	req.VersionedParams(option, parameterCodec)
@@ -57,11 +119,11 @@ func downloadFile(config *rest.Config, clientset *kubernetes.Clientset,
 		return err
 	}
 
-	// Stream the file content from the container to the writer
+	// Stream the output of the command to the writers
 	return exec.StreamWithContext(
 		context.Background(),
 		remotecommand.StreamOptions{
-			Stdout: writer,
-			Stderr: nil,
+			Stdout: stdout,
+			Stderr: stderr,
 		})
 }
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// echoSummaryRegexp matches the lines ginkgo prints at the start and the end
+// of the run, which are always echoed
+var echoSummaryRegexp = regexp.MustCompile(`Will run \d+ of \d+ specs|Ran \d+ of \d+ Specs|(?:SUCCESS|FAIL)! -- \d+ Passed`)
+
+// echoThrottle decides which lines of the log stream are echoed to the
+// terminal with --echo-interval and --echo-sample, the downloaded e2e.log
+// keeps all of them. Without either flag every line is echoed. Otherwise the
+// terminal gets the start and the summary of the run, the failed specs,
+// every sample-th line and, every interval, a line with the progress of the
+// specs.
+type echoThrottle struct {
+	interval time.Duration
+	sample   int
+	now      func() time.Time
+	lines    int
+	// last is the time the last progress line was echoed at
+	last time.Time
+}
+
+func newEchoThrottle() *echoThrottle {
+	// the flag was validated by ValidateArgs
+	interval, _ := common.GetDuration("echo-interval")
+	return &echoThrottle{
+		interval: interval,
+		sample:   settings.GetInt("echo-sample"),
+		now:      time.Now,
+	}
+}
+
+// throttled reports whether only part of the log stream is echoed
+func (t *echoThrottle) throttled() bool {
+	return t.interval > 0 || t.sample > 0
+}
+
+// line reports whether the line of the log stream is echoed
+func (t *echoThrottle) line(line string) bool {
+	if !t.throttled() {
+		return true
+	}
+	t.lines++
+	if echoSummaryRegexp.MatchString(line) {
+		return true
+	}
+	return t.sample > 0 && t.lines%t.sample == 0
+}
+
+// progress returns the line reporting the progress of the specs once the
+// interval elapsed since the last one
+func (t *echoThrottle) progress(p *SpecProgress) (string, bool) {
+	if t.interval <= 0 {
+		return "", false
+	}
+	now := t.now()
+	if t.last.IsZero() {
+		t.last = now
+		return "", false
+	}
+	if now.Sub(t.last) < t.interval {
+		return "", false
+	}
+	t.last = now
+	completed := fmt.Sprintf("%d", p.Completed())
+	if total := p.Total.Load(); total > 0 {
+		completed += fmt.Sprintf("/%d", total)
+	}
+	return fmt.Sprintf("%s specs completed, %d passed, %d failed, %d skipped\n",
+		completed, p.Passed.Load(), p.Failed.Load(), p.Skipped.Load()), true
+}
+
+// failed returns the line echoed for a spec that failed
+func (t *echoThrottle) failed(name, status string) (string, bool) {
+	if !t.throttled() || status != results.StatusFailed {
+		return "", false
+	}
+	return fmt.Sprintf("[FAILED] %s\n", name), true
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+func TestEchoThrottle(t *testing.T) {
+	echo := &echoThrottle{now: time.Now}
+	assert.True(t, echo.line("STEP: Creating a kubernetes client"))
+	_, ok := echo.failed("[It] [sig-network] DNS should resolve", results.StatusFailed)
+	assert.False(t, ok)
+
+	echo = &echoThrottle{sample: 3, now: time.Now}
+	var echoed []bool
+	for _, line := range []string{
+		"Will run 2 of 7000 specs",
+		"STEP: Creating a kubernetes client",
+		"STEP: Building a namespace api object",
+		"• [0.100 seconds]",
+		"Ran 2 of 7000 Specs in 12.345 seconds",
+		"FAIL! -- 1 Passed | 1 Failed | 0 Pending | 6998 Skipped",
+	} {
+		echoed = append(echoed, echo.line(line))
+	}
+	assert.Equal(t, []bool{true, false, true, false, true, true}, echoed)
+
+	line, ok := echo.failed("[It] [sig-network] DNS should resolve", results.StatusFailed)
+	assert.True(t, ok)
+	assert.Equal(t, "[FAILED] [It] [sig-network] DNS should resolve\n", line)
+	_, ok = echo.failed("[It] [sig-network] DNS should resolve", results.StatusPassed)
+	assert.False(t, ok)
+}
+
+func TestEchoThrottleProgress(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	echo := &echoThrottle{interval: time.Minute, now: func() time.Time { return now }}
+	assert.False(t, echo.line("STEP: Creating a kubernetes client"))
+
+	var p SpecProgress
+	p.Add("Will run 4 of 7000 specs")
+	p.Add("• [0.100 seconds]")
+	p.Add("• [FAILED] [1.234 seconds]")
+	_, ok := echo.progress(&p)
+	assert.False(t, ok)
+
+	now = now.Add(30 * time.Second)
+	_, ok = echo.progress(&p)
+	assert.False(t, ok)
+
+	now = now.Add(30 * time.Second)
+	line, ok := echo.progress(&p)
+	assert.True(t, ok)
+	assert.Equal(t, "2/4 specs completed, 1 passed, 1 failed, 0 skipped\n", line)
+}
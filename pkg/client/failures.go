@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+// FailuresFile is the name of the file of the output directory the output of
+// the failed specs is appended to while the tests run
+const FailuresFile = "failures.log"
+
+// specSeparator is printed by ginkgo between the output of two specs
+const specSeparator = "------------------------------"
+
+// failureMarkers are printed by ginkgo in the output of a spec that didn't pass
+var failureMarkers = []string{"[FAILED]", "[PANICKED]", "[TIMEDOUT]", "[INTERRUPTED]"}
+
+// failureLog collects the output of each spec of the log stream and writes
+// the output of the specs that failed, so that failures can be followed
+// without the output of the passing specs. The output of the shards is
+// interleaved in the stream, it is collected separately for each prefix.
+type failureLog struct {
+	w        io.Writer
+	prefixes []string
+	specs    map[string]*strings.Builder
+}
+
+func newFailureLog(w io.Writer, prefixes []string) *failureLog {
+	return &failureLog{w: w, prefixes: prefixes, specs: map[string]*strings.Builder{}}
+}
+
+// add adds a line of the log stream. The output of the spec is written once
+// the separator following it is received.
+func (f *failureLog) add(line string) error {
+	prefix := ""
+	for _, p := range f.prefixes {
+		if strings.HasPrefix(line, p) {
+			prefix = p
+			break
+		}
+	}
+	spec, ok := f.specs[prefix]
+	if !ok {
+		spec = &strings.Builder{}
+		f.specs[prefix] = spec
+	}
+	if strings.HasPrefix(strings.TrimSpace(trimTimestamp(strings.TrimPrefix(line, prefix))), specSeparator) {
+		return f.write(spec)
+	}
+	spec.WriteString(line)
+	return nil
+}
+
+// trimTimestamp removes the timestamp of a line streamed with --log-timestamps
+func trimTimestamp(line string) string {
+	timestamp, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return line
+	}
+	if _, err := time.Parse(time.RFC3339Nano, timestamp); err != nil {
+		return line
+	}
+	return rest
+}
+
+// flush writes the output of the last spec of each prefix if it failed
+func (f *failureLog) flush() error {
+	for _, p := range append([]string{""}, f.prefixes...) {
+		if spec, ok := f.specs[p]; ok {
+			if err := f.write(spec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// write writes the output of the spec if it failed and resets it
+func (f *failureLog) write(spec *strings.Builder) error {
+	defer spec.Reset()
+	output := spec.String()
+	for _, marker := range failureMarkers {
+		if strings.Contains(output, marker) {
+			_, err := io.WriteString(f.w, specSeparator+"\n"+output)
+			return err
+		}
+	}
+	return nil
+}
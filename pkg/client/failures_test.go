@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureLog(t *testing.T) {
+	lines := []string{
+		"Running Suite: Kubernetes e2e suite\n",
+		"------------------------------\n",
+		"[a] • [0.100 seconds]\n",
+		"[a] [sig-apps] Deployment should pass\n",
+		"[b] • [FAILED] [12.000 seconds]\n",
+		"[a] ------------------------------\n",
+		"[b] [sig-network] Services should fail\n",
+		"[b]   Expected success\n",
+		"[a] • [TIMEDOUT] [900.000 seconds]\n",
+		"[b] ------------------------------\n",
+		"[a] [sig-node] Pods should time out\n",
+	}
+
+	var buf bytes.Buffer
+	f := newFailureLog(&buf, []string{"[a] ", "[b] "})
+	for _, line := range lines {
+		require.NoError(t, f.add(line))
+	}
+	assert.Equal(t, "------------------------------\n"+
+		"[b] • [FAILED] [12.000 seconds]\n"+
+		"[b] [sig-network] Services should fail\n"+
+		"[b]   Expected success\n", buf.String())
+
+	require.NoError(t, f.flush())
+	assert.Contains(t, buf.String(), "[a] • [TIMEDOUT] [900.000 seconds]\n[a] [sig-node] Pods should time out\n")
+	assert.NotContains(t, buf.String(), "Deployment should pass")
+}
+
+func TestFailureLogTimestamps(t *testing.T) {
+	lines := []string{
+		"2024-05-01T10:00:00.100000000Z • [FAILED] [1.000 seconds]\n",
+		"2024-05-01T10:00:00.200000000Z [sig-apps] Deployment should fail\n",
+		"2024-05-01T10:00:00.300000000Z ------------------------------\n",
+		"2024-05-01T10:00:00.400000000Z • [0.100 seconds]\n",
+	}
+
+	var buf bytes.Buffer
+	f := newFailureLog(&buf, nil)
+	for _, line := range lines {
+		require.NoError(t, f.add(line))
+	}
+	require.NoError(t, f.flush())
+	assert.Equal(t, "------------------------------\n"+lines[0]+lines[1], buf.String())
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/redact"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// fetchArtifacts downloads the artifacts of the pod. When they can't be
+// downloaded, e.g. because the pod was evicted, the log of the conformance
+// container is recovered as e2e.log from the log API instead and false is
+// returned, the artifacts are partial.
+func fetchArtifacts(config *rest.Config, clientset kubernetes.Interface, podName, outputDir string) bool {
+	err := retryFetch(podName, func() error {
+		return downloadArtifacts(config, clientset, podName, outputDir)
+	})
+	if err == nil {
+		return true
+	}
+	log.Printf("unable to fetch the artifacts of pod %s, recovering its log from the log API: %v", podName, err)
+	if err := fetchContainerLog(clientset, podName, outputDir); err != nil {
+		log.Printf("unable to recover the log of pod %s: %v", podName, err)
+	} else {
+		log.Printf("recovered e2e.log of pod %s, the junit report and the other artifacts are missing", podName)
+	}
+	return false
+}
+
+// retryFetch calls fetch until it succeeds, at most --artifact-retries more
+// times with a jittered exponential backoff. Unlike Retry every error is
+// retried, an exec can time out or its stream break without a transient API
+// error.
+func retryFetch(podName string, fetch func() error) error {
+	retries := settings.GetInt("artifact-retries")
+	backoff := wait.Backoff{
+		Duration: retryDelay,
+		Factor:   2,
+		Jitter:   0.5,
+		Steps:    retries,
+		Cap:      reconnectCap,
+	}
+	for retry := 1; ; retry++ {
+		err := fetch()
+		if err == nil || backoff.Steps < 1 {
+			return err
+		}
+		delay := backoff.Step()
+		log.Printf("fetching the artifacts of pod %s failed, retry %d of %d in %s: %v", podName, retry, retries, delay.Round(time.Millisecond), err)
+		time.Sleep(delay)
+	}
+}
+
+// fetchContainerLog writes the log of the conformance container of the pod
+// to e2e.log, as the log API returns it
+func fetchContainerLog(clientset kubernetes.Interface, podName, outputDir string) error {
+	req := clientset.CoreV1().Pods(settings.GetString("namespace")).GetLogs(podName, &v1.PodLogOptions{Container: common.ConformanceContainer})
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	w, _, err := createE2ELog(outputDir)
+	if err != nil {
+		return err
+	}
+	w = redact.NewWriter(w)
+	if _, err := io.Copy(w, limitReader(stream)); err != nil {
+		w.Close()
+		return fmt.Errorf("error reading the log: %w", err)
+	}
+	return w.Close()
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestRetryFetch(t *testing.T) {
+	retryDelay = time.Millisecond
+	defer func() { retryDelay = time.Second }()
+	settings.Set("artifact-retries", 2)
+	defer settings.Set("artifact-retries", 0)
+
+	timedOut := errors.New("exec timed out")
+	for _, tt := range []struct {
+		name  string
+		fails int
+		calls int
+		err   error
+	}{
+		{name: "succeeds", calls: 1},
+		{name: "succeeds after a retry", fails: 1, calls: 2},
+		{name: "gives up", fails: 5, calls: 3, err: timedOut},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := retryFetch("e2e-conformance-test", func() error {
+				calls++
+				if calls <= tt.fails {
+					return timedOut
+				}
+				return nil
+			})
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.calls, calls)
+		})
+	}
+}
+
+func TestFetchContainerLog(t *testing.T) {
+	settings.Set("namespace", "conformance")
+	defer settings.Set("namespace", "")
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "e2e-conformance-test", Namespace: "conformance"}}
+	dir := t.TempDir()
+
+	require.NoError(t, fetchContainerLog(fake.NewSimpleClientset(pod), pod.Name, dir))
+	// the fake clientset returns the same log for every container
+	data, err := os.ReadFile(filepath.Join(dir, "e2e.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "fake logs", string(data))
+}
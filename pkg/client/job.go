@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// jobWorkload reports whether the conformance pods are created by jobs
+func jobWorkload() bool {
+	return settings.GetString("workload") == common.WorkloadJob
+}
+
+// jobSelector selects the pods of the job
+func jobSelector(jobName string) labels.Selector {
+	return labels.SelectorFromSet(labels.Set{common.JobNameLabel: jobName})
+}
+
+// podLost reports whether the pod is gone for good before its tests
+// completed, e.g. because its node was drained or failed. The job replaces it.
+func podLost(pod *v1.Pod) bool {
+	return pod.DeletionTimestamp != nil || pod.Status.Phase == v1.PodFailed
+}
+
+// currentJobPod returns the most recent pod of a job that wasn't lost, or nil
+// if the job has no such pod, e.g. while the replacement of a lost pod is
+// being created.
+func currentJobPod(pods []*v1.Pod) *v1.Pod {
+	var current *v1.Pod
+	for _, pod := range pods {
+		if podLost(pod) {
+			continue
+		}
+		if current == nil || current.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			current = pod
+		}
+	}
+	return current
+}
+
+// resolvePodName returns the name of the pod running the tests of the given
+// conformance pod name. With --workload=job it is the current pod of the job
+// of that name.
+func resolvePodName(clientset kubernetes.Interface, namespace, name string) (string, error) {
+	if !jobWorkload() {
+		return name, nil
+	}
+	list, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: jobSelector(name).String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	pods := make([]*v1.Pod, len(list.Items))
+	for i := range list.Items {
+		pods[i] = &list.Items[i]
+	}
+	if pod := currentJobPod(pods); pod != nil {
+		return pod.Name, nil
+	}
+	return "", fmt.Errorf("job %s has no running pod", name)
+}
+
+// jobFailed returns an error when the job failed, e.g. because its pods were
+// lost more often than --job-backoff-limit or it exceeded
+// --job-active-deadline.
+func jobFailed(clientset kubernetes.Interface, namespace, name string) error {
+	job, err := clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		// the state of the job is checked again later
+		return nil
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == v1.ConditionTrue {
+			return fmt.Errorf("job %s failed: %s: %s", name, condition.Reason, condition.Message)
+		}
+	}
+	return nil
+}
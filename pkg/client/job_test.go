@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCurrentJobPod(t *testing.T) {
+	now := time.Now()
+	pod := func(name string, created time.Time, phase v1.PodPhase, deleted bool) *v1.Pod {
+		p := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+			Status:     v1.PodStatus{Phase: phase},
+		}
+		if deleted {
+			p.DeletionTimestamp = &metav1.Time{Time: now}
+		}
+		return p
+	}
+
+	tests := []struct {
+		name string
+		pods []*v1.Pod
+		want string
+	}{
+		{
+			name: "single pod",
+			pods: []*v1.Pod{pod("a", now, v1.PodRunning, false)},
+			want: "a",
+		},
+		{
+			name: "replacement of a failed pod",
+			pods: []*v1.Pod{
+				pod("a", now.Add(-time.Hour), v1.PodFailed, false),
+				pod("b", now, v1.PodPending, false),
+			},
+			want: "b",
+		},
+		{
+			name: "pod of a drained node being deleted",
+			pods: []*v1.Pod{
+				pod("b", now, v1.PodRunning, true),
+				pod("a", now.Add(-time.Hour), v1.PodFailed, false),
+			},
+		},
+		{
+			name: "most recent pod",
+			pods: []*v1.Pod{
+				pod("b", now, v1.PodRunning, false),
+				pod("a", now.Add(-time.Hour), v1.PodRunning, false),
+			},
+			want: "b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := currentJobPod(tt.pods)
+			if tt.want == "" {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.want, got.Name)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+)
+
+// waitingProblems are the reasons of a waiting container that keep the tests
+// from running until someone intervenes
+var waitingProblems = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// eventProblems maps the reasons of warning events of the conformance pods
+// to the reason they are reported with
+var eventProblems = map[string]string{
+	"FailedScheduling":       "Unschedulable",
+	"FailedMount":            "FailedMount",
+	"FailedAttachVolume":     "FailedAttachVolume",
+	"FailedCreatePodSandBox": "FailedCreatePodSandBox",
+	"Evicted":                "Evicted",
+	"Preempted":              "Preempted",
+}
+
+// diagnosis is a condition of a conformance pod that keeps the tests from
+// running or completing
+type diagnosis struct {
+	Reason  string
+	Message string
+}
+
+// diagnosePod returns the problems of the pod seen in its status: the reason
+// it isn't scheduled, containers waiting on something that won't resolve by
+// itself and containers killed for running out of memory.
+func diagnosePod(pod *v1.Pod) []diagnosis {
+	var problems []diagnosis
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionFalse && condition.Reason == v1.PodReasonUnschedulable {
+			problems = append(problems, diagnosis{Reason: "Unschedulable", Message: condition.Message})
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if waiting := status.State.Waiting; waiting != nil && waitingProblems[waiting.Reason] {
+			message := fmt.Sprintf("container %s", status.Name)
+			if waiting.Message != "" {
+				message += ": " + waiting.Message
+			}
+			problems = append(problems, diagnosis{Reason: waiting.Reason, Message: message})
+		}
+		for _, state := range []v1.ContainerState{status.State, status.LastTerminationState} {
+			if state.Terminated != nil && state.Terminated.Reason == "OOMKilled" {
+				problems = append(problems, diagnosis{
+					Reason:  "OOMKilled",
+					Message: fmt.Sprintf("container %s ran out of memory, consider raising its limits", status.Name),
+				})
+				break
+			}
+		}
+	}
+	return problems
+}
+
+// diagnoseEvent returns the problem reported by a warning event of a pod, if
+// it is one that keeps the tests from running
+func diagnoseEvent(event *v1.Event) (diagnosis, bool) {
+	if event.Type != v1.EventTypeWarning || event.InvolvedObject.Kind != "Pod" {
+		return diagnosis{}, false
+	}
+	reason, ok := eventProblems[event.Reason]
+	if !ok {
+		return diagnosis{}, false
+	}
+	return diagnosis{Reason: reason, Message: event.Message}, true
+}
+
+// nodeNotReady returns why the node isn't ready, and whether it isn't
+func nodeNotReady(node *v1.Node) (string, bool) {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			if condition.Status == v1.ConditionTrue {
+				return "", false
+			}
+			return condition.Message, true
+		}
+	}
+	return "", false
+}
+
+// podMonitor reports the problems of the conformance pods as soon as the
+// informers see them, each one once
+type podMonitor struct {
+	pods corelisters.PodLister
+
+	mu       sync.Mutex
+	reported map[string]bool
+	report   func(format string, args ...any)
+}
+
+// monitorPods reports the problems of the pods of the namespace, seen in
+// their status and events or in the nodes running them, through the
+// informers of the factories. The nodes aren't watched without nodeFactory.
+func monitorPods(factory informers.SharedInformerFactory, nodeFactory informers.SharedInformerFactory) {
+	m := &podMonitor{
+		pods:     factory.Core().V1().Pods().Lister(),
+		reported: map[string]bool{},
+		report:   log.Printf,
+	}
+	factory.Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    m.checkPod,
+		UpdateFunc: func(_, obj any) { m.checkPod(obj) },
+	})
+	factory.Core().V1().Events().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    m.checkEvent,
+		UpdateFunc: func(_, obj any) { m.checkEvent(obj) },
+	})
+	// nodes are cluster-scoped, they aren't watched with --restricted
+	if nodeFactory == nil {
+		return
+	}
+	nodeFactory.Core().V1().Nodes().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    m.checkNode,
+		UpdateFunc: func(_, obj any) { m.checkNode(obj) },
+	})
+}
+
+// once reports the message unless it was already reported under the key
+func (m *podMonitor) once(key, format string, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.reported[key] {
+		return
+	}
+	m.reported[key] = true
+	m.report(format, args...)
+}
+
+// forget allows the problem reported under the key to be reported again,
+// e.g. once a node that wasn't ready is ready again
+func (m *podMonitor) forget(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.reported, key)
+}
+
+func (m *podMonitor) checkPod(obj any) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	for _, problem := range diagnosePod(pod) {
+		m.once("pod/"+pod.Name+"/"+problem.Reason, "pod %s: %s: %s", pod.Name, problem.Reason, problem.Message)
+	}
+}
+
+func (m *podMonitor) checkEvent(obj any) {
+	event, ok := obj.(*v1.Event)
+	if !ok {
+		return
+	}
+	if problem, ok := diagnoseEvent(event); ok {
+		name := event.InvolvedObject.Name
+		m.once("pod/"+name+"/"+problem.Reason, "pod %s: %s: %s", name, problem.Reason, problem.Message)
+	}
+}
+
+func (m *podMonitor) checkNode(obj any) {
+	node, ok := obj.(*v1.Node)
+	if !ok {
+		return
+	}
+	key := "node/" + node.Name
+	message, notReady := nodeNotReady(node)
+	if !notReady {
+		m.forget(key)
+		return
+	}
+	pods, err := m.pods.List(labels.Everything())
+	if err != nil {
+		return
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == node.Name && pod.Status.Phase == v1.PodRunning {
+			m.once(key, "node %s running pod %s is NotReady: %s", node.Name, pod.Name, message)
+			return
+		}
+	}
+}
+
+// podChanges lets goroutines wait for the next change of a pod seen by an
+// informer instead of polling
+type podChanges struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func newPodChanges() *podChanges {
+	return &podChanges{ch: make(chan struct{})}
+}
+
+// notify wakes up everyone waiting for a change
+func (p *podChanges) notify() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	close(p.ch)
+	p.ch = make(chan struct{})
+}
+
+// next returns a channel closed on the next change. It has to be called
+// before reading the state it waits to change, so no change is missed.
+func (p *podChanges) next() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ch
+}
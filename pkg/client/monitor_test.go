@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+)
+
+func TestDiagnosePod(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   v1.PodStatus
+		expected []diagnosis
+	}{
+		{
+			name:   "running",
+			status: v1.PodStatus{Phase: v1.PodRunning},
+		},
+		{
+			name: "unschedulable",
+			status: v1.PodStatus{Conditions: []v1.PodCondition{{
+				Type:    v1.PodScheduled,
+				Status:  v1.ConditionFalse,
+				Reason:  v1.PodReasonUnschedulable,
+				Message: "0/3 nodes are available: 3 Insufficient cpu.",
+			}}},
+			expected: []diagnosis{{Reason: "Unschedulable", Message: "0/3 nodes are available: 3 Insufficient cpu."}},
+		},
+		{
+			name: "image pull back-off",
+			status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+				Name:  common.ConformanceContainer,
+				State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}},
+			}}},
+			expected: []diagnosis{{Reason: "ImagePullBackOff", Message: "container conformance-container: Back-off pulling image"}},
+		},
+		{
+			name: "container creating",
+			status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+				Name:  common.ConformanceContainer,
+				State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}},
+			}}},
+		},
+		{
+			name: "oom killed",
+			status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+				Name:                 common.OutputContainer,
+				State:                v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+				LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled"}},
+			}}},
+			expected: []diagnosis{{Reason: "OOMKilled", Message: "container output-container ran out of memory, consider raising its limits"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, diagnosePod(&v1.Pod{Status: tt.status}))
+		})
+	}
+}
+
+func TestDiagnoseEvent(t *testing.T) {
+	event := &v1.Event{
+		Type:           v1.EventTypeWarning,
+		Reason:         "FailedScheduling",
+		Message:        "0/3 nodes are available",
+		InvolvedObject: v1.ObjectReference{Kind: "Pod", Name: common.PodName},
+	}
+	problem, ok := diagnoseEvent(event)
+	assert.True(t, ok)
+	assert.Equal(t, diagnosis{Reason: "Unschedulable", Message: "0/3 nodes are available"}, problem)
+
+	event.Type = v1.EventTypeNormal
+	_, ok = diagnoseEvent(event)
+	assert.False(t, ok)
+
+	event.Type, event.Reason = v1.EventTypeWarning, "BackOff"
+	_, ok = diagnoseEvent(event)
+	assert.False(t, ok)
+}
+
+func TestPodMonitor(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: common.PodName, Namespace: "conformance"},
+		Spec:       v1.PodSpec{NodeName: "node-1"},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(pod), 0)
+	assert.NoError(t, factory.Core().V1().Pods().Informer().GetStore().Add(pod))
+
+	var reports []string
+	m := &podMonitor{
+		pods:     factory.Core().V1().Pods().Lister(),
+		reported: map[string]bool{},
+		report:   func(format string, args ...any) { reports = append(reports, fmt.Sprintf(format, args...)) },
+	}
+	notReady := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{Conditions: []v1.NodeCondition{{
+			Type:    v1.NodeReady,
+			Status:  v1.ConditionUnknown,
+			Message: "Kubelet stopped posting node status.",
+		}}},
+	}
+	ready := notReady.DeepCopy()
+	ready.Status.Conditions[0].Status = v1.ConditionTrue
+
+	m.checkNode(notReady)
+	m.checkNode(notReady)
+	m.checkNode(ready)
+	m.checkNode(notReady)
+	m.checkNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}, Status: notReady.Status})
+
+	oomKilled := pod.DeepCopy()
+	oomKilled.Status.ContainerStatuses = []v1.ContainerStatus{{
+		Name:  common.ConformanceContainer,
+		State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled"}},
+	}}
+	m.checkPod(oomKilled)
+	m.checkPod(oomKilled)
+
+	assert.Equal(t, []string{
+		"node node-1 running pod e2e-conformance-test is NotReady: Kubelet stopped posting node status.",
+		"node node-1 running pod e2e-conformance-test is NotReady: Kubelet stopped posting node status.",
+		"pod e2e-conformance-test: OOMKilled: container conformance-container ran out of memory, consider raising its limits",
+	}, reports)
+}
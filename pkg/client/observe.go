@@ -0,0 +1,246 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/redact"
+	"sigs.k8s.io/hydrophone/pkg/registry"
+)
+
+// defaultResultsDir is where the go-runner of the conformance image writes
+// its results unless RESULTS_DIR is set
+const defaultResultsDir = "/tmp/results"
+
+// ObservedPod is a pod running the conformance image that wasn't necessarily
+// created by hydrophone, e.g. by a hand-rolled Job
+type ObservedPod struct {
+	Namespace string
+	Name      string
+	// Container is the name of the container running the conformance image
+	Container string
+	// Image is the conformance image
+	Image string
+	// Env holds the environment variables of the container that are set to a value
+	Env map[string]string
+}
+
+// FindObservedPod returns the pod matching the label selector that runs the
+// conformance image. When several pods match, e.g. the retries of a Job, the
+// most recent one is returned. An empty namespace searches all namespaces.
+func FindObservedPod(clientset kubernetes.Interface, namespace, selector string) (*ObservedPod, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	candidates := []v1.Pod{}
+	for _, pod := range pods.Items {
+		if conformanceContainer(&pod) != nil {
+			candidates = append(candidates, pod)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no pod matching %q runs the conformance image", selector)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[j].CreationTimestamp.Before(&candidates[i].CreationTimestamp)
+	})
+	pod := candidates[0]
+	if len(candidates) > 1 {
+		log.Printf("%d pods matching %q run the conformance image, observing the most recent one", len(candidates), selector)
+	}
+
+	container := conformanceContainer(&pod)
+	env := map[string]string{}
+	for _, e := range container.Env {
+		if e.ValueFrom == nil {
+			env[e.Name] = e.Value
+		}
+	}
+	return &ObservedPod{
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		Container: container.Name,
+		Image:     container.Image,
+		Env:       env,
+	}, nil
+}
+
+// conformanceContainer returns the container of the pod running the
+// conformance image, nil if there is none
+func conformanceContainer(pod *v1.Pod) *v1.Container {
+	for i, container := range pod.Spec.Containers {
+		ref, err := registry.ParseReference(container.Image)
+		if err == nil && path.Base(ref.Repository) == "conformance" {
+			return &pod.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+// Observe streams the logs of the conformance container of the observed pod
+// to stdout and to e2e.log in the output directory until the container
+// terminates, and sets the exit code of the client to the one of the
+// container. The junit report is downloaded when another container of the pod
+// still running shares the results directory. Nothing is created or changed
+// in the cluster.
+func (c *Client) Observe(config *rest.Config, pod *ObservedPod, outputDir string) error {
+	pods := c.ClientSet.CoreV1().Pods(pod.Namespace)
+	log.Printf("Waiting for pod %s/%s to start...", pod.Namespace, pod.Name)
+	err := wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(ctx context.Context) (bool, error) {
+		current, err := pods.Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return current.Status.Phase != v1.PodPending, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	e2eLog, err := os.Create(filepath.Join(outputDir, "e2e.log"))
+	if err != nil {
+		return err
+	}
+	defer e2eLog.Close()
+	failuresLog, err := os.Create(filepath.Join(outputDir, FailuresFile))
+	if err != nil {
+		return err
+	}
+	defer failuresLog.Close()
+	failures := newFailureLog(failuresLog, nil)
+
+	stream := streamLogs{
+		logCh:  make(chan string),
+		errCh:  make(chan error),
+		doneCh: make(chan bool),
+	}
+	go func() {
+		if c.getPodLogs(pod.Namespace, pod.Name, pod.Container, "", stream) {
+			stream.errCh <- fmt.Errorf("pod %s/%s was lost before the tests completed", pod.Namespace, pod.Name)
+		}
+	}()
+	for done := false; !done; {
+		select {
+		case err := <-stream.errCh:
+			return err
+		case line := <-stream.logCh:
+			line = redact.String(line)
+			if c.Seed == 0 {
+				c.Seed = parseSeed(line)
+			}
+			fmt.Print(line)
+			if _, err := e2eLog.WriteString(line); err != nil {
+				return err
+			}
+			if err := failures.add(line); err != nil {
+				return err
+			}
+		case <-stream.doneCh:
+			done = true
+		}
+	}
+	if err := failures.flush(); err != nil {
+		return err
+	}
+
+	current, err := pods.Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	for _, status := range current.Status.ContainerStatuses {
+		if status.Name == pod.Container && status.State.Terminated != nil {
+			c.ExitCode = int(status.State.Terminated.ExitCode)
+		}
+	}
+	log.Printf("Container %s terminated with exit code %d", pod.Container, c.ExitCode)
+
+	resultsDir := pod.Env["RESULTS_DIR"]
+	if resultsDir == "" {
+		resultsDir = defaultResultsDir
+	}
+	container, dir, ok := resultsLocation(current, pod.Container, resultsDir)
+	if !ok {
+		log.Printf("WARNING: no running container of pod %s shares %s, the junit report can't be downloaded", pod.Name, resultsDir)
+		return nil
+	}
+	junitPath := filepath.Join(outputDir, "junit_01.xml")
+	log.Println("downloading junit_01.xml to", junitPath)
+	junitFile, err := os.Create(junitPath)
+	if err != nil {
+		return err
+	}
+	junit := redact.NewWriter(junitFile)
+	defer junit.Close()
+	if err := downloadFile(config, c.ClientSet, pod.Namespace, pod.Name, container, path.Join(dir, "junit_01.xml"), junit); err != nil {
+		return fmt.Errorf("unable to download junit_01.xml: %w", err)
+	}
+	if err := junit.Close(); err != nil {
+		return err
+	}
+	return processJUnit(outputDir)
+}
+
+// resultsLocation returns a running container of the pod, other than the
+// given one, mounting the volume that holds the results directory of the
+// given container, and the path of the directory in it.
+func resultsLocation(pod *v1.Pod, containerName, resultsDir string) (string, string, bool) {
+	running := map[string]bool{}
+	for _, status := range pod.Status.ContainerStatuses {
+		running[status.Name] = status.State.Running != nil
+	}
+	var mounts []v1.VolumeMount
+	for _, container := range pod.Spec.Containers {
+		if container.Name == containerName {
+			mounts = container.VolumeMounts
+		}
+	}
+
+	for _, mount := range mounts {
+		mountPath := strings.TrimSuffix(mount.MountPath, "/")
+		if resultsDir != mountPath && !strings.HasPrefix(resultsDir, mountPath+"/") {
+			continue
+		}
+		rel := strings.TrimPrefix(resultsDir, mountPath)
+		for _, other := range pod.Spec.Containers {
+			if other.Name == containerName || !running[other.Name] {
+				continue
+			}
+			for _, otherMount := range other.VolumeMounts {
+				if otherMount.Name == mount.Name && otherMount.SubPath == mount.SubPath {
+					return other.Name, path.Join(otherMount.MountPath, rel), true
+				}
+			}
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestConformanceContainer(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{
+		{Name: "proxy", Image: "docker.io/envoyproxy/envoy:v1.29.0"},
+		{Name: "e2e", Image: "registry.k8s.io/conformance:v1.29.0"},
+	}}}
+	assert.Equal(t, "e2e", conformanceContainer(pod).Name)
+
+	pod.Spec.Containers[1].Image = "mirror.example.com/k8s/conformance-tools:v1"
+	assert.Nil(t, conformanceContainer(pod))
+}
+
+func TestResultsLocation(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{Containers: []v1.Container{
+			{
+				Name:         "e2e",
+				VolumeMounts: []v1.VolumeMount{{Name: "config", MountPath: "/etc/e2e"}, {Name: "results", MountPath: "/tmp"}},
+			},
+			{
+				Name:         "uploader",
+				VolumeMounts: []v1.VolumeMount{{Name: "results", MountPath: "/data"}},
+			},
+		}},
+		Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{
+			{Name: "e2e", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}},
+			{Name: "uploader", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+		}},
+	}
+
+	container, dir, ok := resultsLocation(pod, "e2e", "/tmp/results")
+	assert.True(t, ok)
+	assert.Equal(t, "uploader", container)
+	assert.Equal(t, "/data/results", dir)
+
+	_, _, ok = resultsLocation(pod, "e2e", "/var/results")
+	assert.False(t, ok)
+
+	pod.Status.ContainerStatuses[1].State = v1.ContainerState{Terminated: &v1.ContainerStateTerminated{}}
+	_, _, ok = resultsLocation(pod, "e2e", "/tmp/results")
+	assert.False(t, ok)
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/redact"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// startupPollInterval is the interval of the checks of WaitForPodsRunning
+var startupPollInterval = 2 * time.Second
+
+// WaitForPodsRunning waits until the conformance pods left the Pending phase.
+// When they don't within the timeout, the error tells why a pod is pending,
+// e.g. because it can't be scheduled or its image can't be pulled.
+func (c *Client) WaitForPodsRunning(ctx context.Context, timeout time.Duration) error {
+	namespace := settings.GetString("namespace")
+	pending := ""
+	err := wait.PollUntilContextTimeout(ctx, startupPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		for _, name := range common.PodNames() {
+			podName, err := resolvePodName(c.ClientSet, namespace, name)
+			if err != nil {
+				pending = err.Error()
+				return false, nil
+			}
+			pod, err := c.ClientSet.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+			if err != nil {
+				pending = fmt.Sprintf("error getting pod %s: %v", podName, err)
+				return false, nil
+			}
+			if pod.Status.Phase == v1.PodPending {
+				pending = fmt.Sprintf("pod %s is pending%s", podName, pendingReason(pod))
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil && pending != "" {
+		return fmt.Errorf("%s", pending)
+	}
+	return err
+}
+
+// pendingReason describes why the pod is pending: the reason it isn't
+// scheduled, or the reason a container is waiting
+func pendingReason(pod *v1.Pod) string {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionFalse {
+			return fmt.Sprintf(", not scheduled: %s", condition.Message)
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if waiting := status.State.Waiting; waiting != nil && waiting.Reason != "" {
+			reason := fmt.Sprintf(", container %s is waiting: %s", status.Name, waiting.Reason)
+			if waiting.Message != "" {
+				reason += ": " + waiting.Message
+			}
+			return reason
+		}
+	}
+	return ""
+}
+
+// FetchPartialFiles downloads what the conformance pods produced so far to
+// the output directory, for runs ending before the tests completed. Unlike
+// FetchFiles it doesn't fail on pods that never started or on files that
+// weren't written yet, they are logged and skipped.
+func (c *Client) FetchPartialFiles(config *rest.Config, outputDir string) {
+	namespace := settings.GetString("namespace")
+	names := common.PodNames()
+	for shard, name := range names {
+		podName, err := resolvePodName(c.ClientSet, namespace, name)
+		if err != nil {
+			log.Printf("unable to collect the artifacts of %s: %v", name, err)
+			continue
+		}
+		dir := outputDir
+		if len(names) > 1 {
+			dir = filepath.Join(outputDir, fmt.Sprintf("shard-%d", shard))
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Printf("unable to create %s: %v", dir, err)
+			continue
+		}
+		for _, file := range []string{"e2e.log", "junit_01.xml"} {
+			if err := c.fetchPartialFile(config, namespace, podName, dir, file); err != nil {
+				log.Printf("unable to download %s of pod %s: %v", file, podName, err)
+				continue
+			}
+			log.Printf("downloaded %s of pod %s to %s", file, podName, dir)
+		}
+	}
+}
+
+// fetchPartialFile downloads the file of the results directory of the pod to
+// a temporary file of the directory first, so that a file that can't be
+// downloaded leaves nothing behind.
+func (c *Client) fetchPartialFile(config *rest.Config, namespace, podName, dir, file string) error {
+	tmp, err := os.CreateTemp(dir, "."+file+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := downloadFile(config, c.ClientSet, namespace, podName, common.ArtifactContainer(), "/tmp/results/"+file, tmp); err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return writePartialFile(dir, file, tmp)
+}
+
+// writePartialFile writes a downloaded file to the directory, e2e.log is
+// gzipped with --compress=gzip and split with --max-log-size
+func writePartialFile(dir, file string, r io.Reader) error {
+	var w io.WriteCloser
+	var err error
+	if file == "e2e.log" {
+		w, _, err = createE2ELog(dir)
+	} else {
+		w, err = os.OpenFile(filepath.Join(dir, file), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	}
+	if err != nil {
+		return err
+	}
+	// the secrets in the text artifacts are scrubbed as they are written
+	if redact.IsText(file) {
+		w = redact.NewWriter(w)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestWaitForPodsRunning(t *testing.T) {
+	settings.Set("namespace", "conformance")
+	defer settings.Set("namespace", "")
+	startupPollInterval = 10 * time.Millisecond
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: common.PodName, Namespace: "conformance"},
+		Status: v1.PodStatus{
+			Phase: v1.PodPending,
+			Conditions: []v1.PodCondition{{
+				Type:    v1.PodScheduled,
+				Status:  v1.ConditionFalse,
+				Message: "0/3 nodes are available: 3 Insufficient cpu.",
+			}},
+		},
+	}
+	c := NewClient()
+	c.ClientSet = fake.NewSimpleClientset(pod)
+	err := c.WaitForPodsRunning(context.Background(), 50*time.Millisecond)
+	assert.EqualError(t, err, "pod e2e-conformance-test is pending, not scheduled: 0/3 nodes are available: 3 Insufficient cpu.")
+
+	pod.Status = v1.PodStatus{Phase: v1.PodRunning}
+	c.ClientSet = fake.NewSimpleClientset(pod)
+	assert.NoError(t, c.WaitForPodsRunning(context.Background(), 50*time.Millisecond))
+}
+
+func TestPendingReason(t *testing.T) {
+	pod := &v1.Pod{Status: v1.PodStatus{
+		Phase: v1.PodPending,
+		ContainerStatuses: []v1.ContainerStatus{
+			{Name: common.OutputContainer, State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+			{Name: common.ConformanceContainer, State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{
+				Reason:  "ImagePullBackOff",
+				Message: "Back-off pulling image",
+			}}},
+		},
+	}}
+	assert.Equal(t, ", container conformance-container is waiting: ImagePullBackOff: Back-off pulling image", pendingReason(pod))
+	assert.Equal(t, "", pendingReason(&v1.Pod{}))
+}
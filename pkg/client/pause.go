@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// PauseAnnotation is set on the conformance pods when a pause is requested,
+// so that the hydrophone process running the tests writes a checkpoint
+// instead of reporting the interrupted run as failed.
+const PauseAnnotation = "hydrophone.k8s.io/pause-requested"
+
+// interruptScript sends SIGINT to the ginkgo process of the conformance
+// container, or to the e2e binary if it runs without ginkgo. Ginkgo then
+// interrupts the current spec, runs its cleanup and writes the report of the
+// specs that completed.
+const interruptScript = `pids=""
+for comm in ginkgo e2e.test; do
+  for d in /proc/[0-9]*; do
+    [ "$(cat $d/comm 2>/dev/null)" = "$comm" ] && pids="$pids ${d#/proc/}"
+  done
+  [ -n "$pids" ] && break
+done
+[ -n "$pids" ] || { echo "no running test process found" >&2; exit 1; }
+kill -INT $pids`
+
+// Pause marks the conformance pods of the namespace as paused and interrupts
+// the tests they are running.
+func Pause(config *rest.Config, clientset kubernetes.Interface) error {
+	namespace := settings.GetString("namespace")
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: common.ConformanceSelector()})
+	if err != nil {
+		return err
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("no conformance pod running in namespace %s", namespace)
+	}
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, PauseAnnotation)
+	for _, pod := range pods.Items {
+		// lost pods of --workload=job are replaced and no longer run tests
+		if podLost(&pod) {
+			continue
+		}
+		if _, err := clientset.CoreV1().Pods(namespace).Patch(ctx, pod.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("error marking pod %s as paused: %w", pod.Name, err)
+		}
+		stderr := &bytes.Buffer{}
+		command := []string{"/bin/sh", "-c", interruptScript}
+		if err := execInContainer(config, clientset, namespace, pod.Name, common.ConformanceContainer, command, nil, stderr); err != nil {
+			return fmt.Errorf("error interrupting the tests of pod %s: %w: %s", pod.Name, err, strings.TrimSpace(stderr.String()))
+		}
+		log.Printf("interrupted the tests of pod %s", pod.Name)
+	}
+	return nil
+}
+
+// PauseRequested reports whether a pause was requested for the conformance pods.
+func PauseRequested(clientset kubernetes.Interface) bool {
+	namespace := settings.GetString("namespace")
+	for _, name := range common.PodNames() {
+		podName, err := resolvePodName(clientset, namespace, name)
+		if err != nil {
+			log.Printf("unable to check whether pod %s was paused: %v", name, err)
+			continue
+		}
+		pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			log.Printf("unable to check whether pod %s was paused: %v", podName, err)
+			continue
+		}
+		if pod.Annotations[PauseAnnotation] == "true" {
+			return true
+		}
+	}
+	return false
+}
@@ -18,33 +18,241 @@ package client
 
 import (
 	"bufio"
+	"fmt"
+	"strings"
+	"time"
 
-	"github.com/spf13/viper"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
 )
 
-// List pod resource with the given namespace
-func getPodLogs(clientset *kubernetes.Clientset, stream streamLogs) {
-	podLogOpts := v1.PodLogOptions{
-		Container: common.ConformanceContainer,
-		Follow:    true,
+// getPodLogs streams the logs of the container of the given pod, prefixing
+// each line with prefix and, with --log-timestamps, its timestamp. When the stream is closed before the
+// container terminated it is re-established with a backoff, resuming after
+// the last line received. When the container restarted in the meantime, the
+// rest of the output of the previous instance is streamed first, followed by
+// a line marking the restart. It returns true when the pod was lost before the
+// container terminated, in which case nothing is sent on the done channel.
+func (c *Client) getPodLogs(namespace, podName, container, prefix string, stream streamLogs) bool {
+	pods := c.ClientSet.CoreV1().Pods(namespace)
+	backoff := reconnectBackoff()
+	failures := 0
+	var position logPosition
+	// restart count of the container the stream follows, -1 until known
+	restarts := int32(-1)
+
+	for {
+		if pod, err := pods.Get(ctx, podName, metav1.GetOptions{}); err == nil {
+			if status := containerStatus(pod, container); status != nil {
+				if restarts >= 0 && status.RestartCount > restarts {
+					c.streamPreviousLogs(namespace, podName, container, prefix, &position, stream)
+					stream.logCh <- prefix + restartMarker(container, status.RestartCount) + "\n"
+				}
+				restarts = status.RestartCount
+			}
+		}
+
+		podLogOpts := v1.PodLogOptions{
+			Container:  container,
+			Follow:     true,
+			Timestamps: true,
+		}
+		if !position.last.IsZero() {
+			podLogOpts.SinceTime = &metav1.Time{Time: position.last}
+		}
+		position.resume()
+
+		start := time.Now()
+		podLogs, err := pods.GetLogs(podName, &podLogOpts).Stream(ctx)
+		if err == nil {
+			failures = 0
+			reader := bufio.NewScanner(limitReader(podLogs))
+			for reader.Scan() {
+				timestamp, line, _ := strings.Cut(reader.Text(), " ")
+				// SinceTime has a precision of a second, skip the lines
+				// received before the stream was re-established
+				if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil && !position.advance(t) {
+					continue
+				}
+				// with --artifact-transfer=exec the container keeps running
+				// until its artifacts were fetched
+				if line == common.TestsDoneMarker {
+					podLogs.Close()
+					stream.doneCh <- true
+					return false
+				}
+				stream.logCh <- logLine(prefix, timestamp, line)
+			}
+			err = reader.Err()
+			podLogs.Close()
+
+			if err == nil && c.containerDone(namespace, podName, container) {
+				stream.doneCh <- true
+				return false
+			}
+			if time.Since(start) > reconnectCap {
+				backoff = reconnectBackoff()
+			}
+		} else {
+			failures++
+		}
+		// the stream of a pod whose node failed can't be re-established
+		if c.podLost(namespace, podName) {
+			return true
+		}
+		if failures > maxReconnectFailures() {
+			stream.errCh <- err
+			return false
+		}
+
+		delay := backoff.Step()
+		c.Reconnects.Add(1)
+		switch {
+		case errors.IsUnauthorized(err):
+			log.Printf("log stream of pod %s rejected the credentials, reconnecting with refreshed credentials in %s", podName, delay.Round(time.Millisecond))
+		case failures > 0:
+			log.Printf("log stream of pod %s failed, reconnecting in %s (attempt %d of %d): %v", podName, delay.Round(time.Millisecond), failures, maxReconnectFailures(), err)
+		default:
+			log.Printf("log stream of pod %s closed, reconnecting in %s", podName, delay.Round(time.Millisecond))
+		}
+		time.Sleep(delay)
 	}
+}
 
-	req := clientset.CoreV1().Pods(viper.GetString("namespace")).GetLogs(common.PodName, &podLogOpts)
-	podLogs, err := req.Stream(ctx)
+// logPosition is the position in the log of a container, the timestamp of
+// the last line received and the number of lines received with it. Lines can
+// share a timestamp, counting them keeps a re-established stream from
+// repeating or dropping any of them.
+type logPosition struct {
+	last  time.Time
+	lines int
+	// skip is the number of lines with the timestamp of the last line the
+	// re-established stream repeats
+	skip int
+}
+
+// resume is called when the stream is (re-)established since the timestamp
+// of the last line
+func (p *logPosition) resume() {
+	p.skip = p.lines
+}
+
+// advance moves the position to a line with the timestamp and reports
+// whether the line is new, i.e. wasn't received before the stream was
+// re-established
+func (p *logPosition) advance(t time.Time) bool {
+	switch {
+	case t.Before(p.last):
+		return false
+	case t.Equal(p.last):
+		if p.skip > 0 {
+			p.skip--
+			return false
+		}
+		p.lines++
+		return true
+	default:
+		p.last, p.lines, p.skip = t, 1, 0
+		return true
+	}
+}
+
+// podLost reports whether the pod was deleted or failed, or its node was
+// lost, in which case its log stream can't be re-established
+func (c *Client) podLost(namespace, podName string) bool {
+	pod, err := c.ClientSet.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return true
+	}
+	return err == nil && (podLost(pod) || c.podNodeLost(pod))
+}
+
+// streamPreviousLogs streams what the previous instance of the restarted
+// container logged after the last line received. It is best effort, the log
+// of the previous instance may be gone already.
+func (c *Client) streamPreviousLogs(namespace, podName, container, prefix string, position *logPosition, stream streamLogs) {
+	podLogOpts := v1.PodLogOptions{
+		Container:  container,
+		Previous:   true,
+		Timestamps: true,
+	}
+	if !position.last.IsZero() {
+		podLogOpts.SinceTime = &metav1.Time{Time: position.last}
+	}
+	position.resume()
+	podLogs, err := c.ClientSet.CoreV1().Pods(namespace).GetLogs(podName, &podLogOpts).Stream(ctx)
 	if err != nil {
-		stream.errCh <- err
+		log.Printf("unable to get the log of the previous instance of container %s of pod %s: %v", container, podName, err)
+		return
 	}
 	defer podLogs.Close()
+	reader := bufio.NewScanner(limitReader(podLogs))
+	for reader.Scan() {
+		timestamp, line, _ := strings.Cut(reader.Text(), " ")
+		if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil && !position.advance(t) {
+			continue
+		}
+		stream.logCh <- logLine(prefix, timestamp, line)
+	}
+}
 
-	reader := bufio.NewScanner(podLogs)
+// logLine formats a line of the log of a container, with the timestamp the
+// kubelet received it at when --log-timestamps is set
+func logLine(prefix, timestamp, line string) string {
+	if settings.GetBool("log-timestamps") {
+		return prefix + timestamp + " " + line + "\n"
+	}
+	return prefix + line + "\n"
+}
 
-	for reader.Scan() {
-		line := reader.Text()
-		stream.logCh <- line + "\n"
+// restartMarker returns the line separating the output of the instances of a
+// restarted container
+func restartMarker(container string, restartCount int32) string {
+	return fmt.Sprintf("----- container %s restarted (restart %d), the output of the new instance follows -----", container, restartCount)
+}
+
+// containerStatus returns the status of the container of the pod, or nil if
+// it has none yet
+func containerStatus(pod *v1.Pod, container string) *v1.ContainerStatus {
+	for i, status := range pod.Status.ContainerStatuses {
+		if status.Name == container {
+			return &pod.Status.ContainerStatuses[i]
+		}
+	}
+	return nil
+}
+
+// containerDone reports whether the container of the pod terminated for
+// good, in which case its log is complete
+func (c *Client) containerDone(namespace, podName, container string) bool {
+	pod, err := c.ClientSet.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return false
+	}
+	return containerTerminated(pod, container)
+}
+
+// containerTerminated reports whether the container of the pod terminated
+// and won't be restarted according to the restart policy of the pod
+func containerTerminated(pod *v1.Pod, container string) bool {
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return true
+	}
+	status := containerStatus(pod, container)
+	if status == nil || status.State.Terminated == nil {
+		return false
+	}
+	switch pod.Spec.RestartPolicy {
+	case v1.RestartPolicyAlways:
+		return false
+	case v1.RestartPolicyOnFailure:
+		return status.State.Terminated.ExitCode == 0
+	default:
+		return true
 	}
-	stream.doneCh <- true
 }
@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+)
+
+func TestLogPosition(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+
+	var position logPosition
+	position.resume()
+	// the first stream receives two lines sharing a timestamp
+	assert.True(t, position.advance(at(100)))
+	assert.True(t, position.advance(at(200)))
+	assert.True(t, position.advance(at(200)))
+
+	// the re-established stream starts at the beginning of the second and
+	// has a third line with the same timestamp
+	position.resume()
+	assert.False(t, position.advance(at(0)))
+	assert.False(t, position.advance(at(100)))
+	assert.False(t, position.advance(at(200)))
+	assert.False(t, position.advance(at(200)))
+	assert.True(t, position.advance(at(200)))
+	assert.True(t, position.advance(at(300)))
+
+	position.resume()
+	assert.False(t, position.advance(at(300)))
+	assert.True(t, position.advance(at(400)))
+}
+
+func TestContainerTerminated(t *testing.T) {
+	terminated := func(exitCode int32) []v1.ContainerStatus {
+		return []v1.ContainerStatus{{
+			Name:  common.ConformanceContainer,
+			State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: exitCode}},
+		}}
+	}
+	tests := []struct {
+		name          string
+		restartPolicy v1.RestartPolicy
+		status        v1.PodStatus
+		expected      bool
+	}{
+		{
+			name:   "running",
+			status: v1.PodStatus{Phase: v1.PodRunning},
+		},
+		{
+			name:     "pod succeeded",
+			status:   v1.PodStatus{Phase: v1.PodSucceeded},
+			expected: true,
+		},
+		{
+			name:          "never restarted",
+			restartPolicy: v1.RestartPolicyNever,
+			status:        v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: terminated(1)},
+			expected:      true,
+		},
+		{
+			name:          "restarted on failure",
+			restartPolicy: v1.RestartPolicyOnFailure,
+			status:        v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: terminated(1)},
+		},
+		{
+			name:          "succeeded without restart",
+			restartPolicy: v1.RestartPolicyOnFailure,
+			status:        v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: terminated(0)},
+			expected:      true,
+		},
+		{
+			name:          "always restarted",
+			restartPolicy: v1.RestartPolicyAlways,
+			status:        v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: terminated(0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &v1.Pod{Spec: v1.PodSpec{RestartPolicy: tt.restartPolicy}, Status: tt.status}
+			assert.Equal(t, tt.expected, containerTerminated(pod, common.ConformanceContainer))
+		})
+	}
+}
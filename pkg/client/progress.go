@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+)
+
+// progressInterval is the interval at which the progress of a download is
+// logged. Downloads completing quicker aren't reported.
+var progressInterval = 5 * time.Second
+
+// progressWriter counts the bytes of a download written through it and logs
+// the progress every progressInterval
+type progressWriter struct {
+	w    io.Writer
+	name string
+	// total is the size of the file, 0 if unknown
+	total    int64
+	written  int64
+	start    time.Time
+	last     time.Time
+	reported bool
+	now      func() time.Time
+}
+
+func newProgressWriter(w io.Writer, name string, total int64) *progressWriter {
+	now := time.Now()
+	return &progressWriter{w: w, name: name, total: total, start: now, last: now, now: time.Now}
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	if now := p.now(); now.Sub(p.last) >= progressInterval {
+		p.last = now
+		p.reported = true
+		if p.total > 0 {
+			log.Printf("downloading %s: %s of %s (%d%%)", p.name, mebibytes(p.written), mebibytes(p.total), p.written*100/p.total)
+		} else {
+			log.Printf("downloading %s: %s", p.name, mebibytes(p.written))
+		}
+	}
+	return n, err
+}
+
+// done logs the completion of a download whose progress was reported
+func (p *progressWriter) done() {
+	if p.reported {
+		log.Printf("downloaded %s: %s in %s", p.name, mebibytes(p.written), p.now().Sub(p.start).Round(time.Second))
+	}
+}
+
+// mebibytes formats the number of bytes in MiB
+func mebibytes(n int64) string {
+	return strconv.FormatFloat(float64(n)/(1<<20), 'f', 1, 64) + "MiB"
+}
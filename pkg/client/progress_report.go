@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// progressReportRegexp matches the line of a ginkgo progress report naming
+// the running spec and how long it has been running, e.g.
+// "[sig-apps] Deployment should proceed (Spec Runtime: 5m0.002s)"
+var progressReportRegexp = regexp.MustCompile(`^(.+) \(Spec Runtime: (\S+)\)$`)
+
+// RunningSpec is a spec a progress report of ginkgo found running
+type RunningSpec struct {
+	// Prefix is the prefix of the lines of the shard running the spec
+	Prefix  string
+	Name    string
+	Runtime time.Duration
+}
+
+// HangHandler is notified of the specs ginkgo reported as running for long,
+// with the name of the pod running them
+type HangHandler func(podName string, spec RunningSpec)
+
+// parseProgressReport returns the spec named by the line of the log stream
+// if it is the header of a progress report. Ginkgo emits the reports for the
+// specs running longer than --poll-progress-after, whether they print
+// anything or not.
+func parseProgressReport(prefixes []string, line string) (RunningSpec, bool) {
+	prefix := ""
+	for _, p := range prefixes {
+		if strings.HasPrefix(line, p) {
+			prefix = p
+			break
+		}
+	}
+	content := strings.TrimSpace(trimTimestamp(strings.TrimPrefix(line, prefix)))
+	match := progressReportRegexp.FindStringSubmatch(content)
+	if match == nil {
+		return RunningSpec{}, false
+	}
+	runtime, err := time.ParseDuration(match[2])
+	if err != nil {
+		return RunningSpec{}, false
+	}
+	return RunningSpec{Prefix: prefix, Name: match[1], Runtime: runtime}, true
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestParseProgressReport(t *testing.T) {
+	prefixes := []string{"[e2e-conformance-test-0] ", "[e2e-conformance-test-1] "}
+	tests := []struct {
+		name string
+		line string
+		spec RunningSpec
+		ok   bool
+	}{
+		{
+			name: "header",
+			line: "  [sig-apps] Deployment should proceed (Spec Runtime: 5m0.002s)\n",
+			spec: RunningSpec{Name: "[sig-apps] Deployment should proceed", Runtime: 5*time.Minute + 2*time.Millisecond},
+			ok:   true,
+		},
+		{
+			name: "shard and timestamp",
+			line: "[e2e-conformance-test-1] 2024-03-01T12:00:00.000000000Z   [sig-node] Pods should run (Spec Runtime: 1m30s)\n",
+			spec: RunningSpec{Prefix: "[e2e-conformance-test-1] ", Name: "[sig-node] Pods should run", Runtime: 90 * time.Second},
+			ok:   true,
+		},
+		{
+			name: "node runtime",
+			line: "    In [It] (Node Runtime: 5m0s)\n",
+		},
+		{
+			name: "output",
+			line: "STEP: creating a pod\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, ok := parseProgressReport(prefixes, tt.line)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.spec, spec)
+		})
+	}
+}
+
+func TestNotifyHang(t *testing.T) {
+	var hung []string
+	c := &Client{ClientSet: fake.NewSimpleClientset()}
+	c.OnHang = func(podName string, spec RunningSpec) {
+		hung = append(hung, podName+": "+spec.Name)
+	}
+	spec := RunningSpec{Name: "[sig-apps] Deployment should proceed", Runtime: 15 * time.Minute}
+
+	c.notifyHang("conformance", "e2e-conformance-test", spec)
+	assert.Equal(t, []string{"e2e-conformance-test: [sig-apps] Deployment should proceed"}, hung)
+
+	// the job has no pod to debug
+	settings.Set("workload", common.WorkloadJob)
+	defer settings.Set("workload", "")
+	c.notifyHang("conformance", "e2e-conformance-test", spec)
+	assert.Len(t, hung, 1)
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressWriter(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	p := newProgressWriter(&buf, "e2e.log", 4<<20)
+	p.start, p.last = now, now
+	p.now = func() time.Time { return now }
+
+	_, err := p.Write(make([]byte, 1<<20))
+	require.NoError(t, err)
+	assert.False(t, p.reported)
+
+	now = now.Add(progressInterval)
+	_, err = p.Write(make([]byte, 1<<20))
+	require.NoError(t, err)
+	assert.True(t, p.reported)
+	assert.Equal(t, now, p.last)
+	assert.Equal(t, int64(2<<20), p.written)
+	assert.Equal(t, 2<<20, buf.Len())
+	assert.Equal(t, "2.0MiB", mebibytes(p.written))
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"io"
+	"sync"
+
+	"golang.org/x/time/rate"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+)
+
+// maxTransferBurst caps the bytes a transfer can move at once, so that the
+// throughput stays smooth rather than a second's worth at a time
+const maxTransferBurst = 64 * 1024
+
+// transferLimit is the limiter shared by all log streams and artifact
+// downloads of the run, recreated when --transfer-rate-limit changes
+var transferLimit struct {
+	sync.Mutex
+	limit   common.ByteSize
+	limiter *rate.Limiter
+}
+
+// transferLimiter returns the limiter of --transfer-rate-limit, nil when the
+// throughput isn't limited
+func transferLimiter() *rate.Limiter {
+	// the flag was validated by ValidateArgs
+	limit, _ := common.GetRate("transfer-rate-limit")
+	transferLimit.Lock()
+	defer transferLimit.Unlock()
+	if limit <= 0 {
+		transferLimit.limit, transferLimit.limiter = 0, nil
+		return nil
+	}
+	if limit != transferLimit.limit {
+		transferLimit.limit = limit
+		transferLimit.limiter = newTransferLimiter(limit)
+	}
+	return transferLimit.limiter
+}
+
+// newTransferLimiter returns a limiter of the given bytes per second
+func newTransferLimiter(limit common.ByteSize) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(limit), int(min(limit, maxTransferBurst)))
+}
+
+// limitedReader waits for the limiter after each read
+type limitedReader struct {
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+// limitReader limits the throughput of the reader to --transfer-rate-limit
+func limitReader(r io.Reader) io.Reader {
+	if limiter := transferLimiter(); limiter != nil {
+		return &limitedReader{r: r, limiter: limiter}
+	}
+	return r
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if burst := l.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := l.r.Read(p)
+	if n > 0 {
+		if werr := l.limiter.WaitN(ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+// limitedWriter waits for the limiter before each write
+type limitedWriter struct {
+	w       io.Writer
+	limiter *rate.Limiter
+}
+
+// limitWriter limits the throughput of the writer to --transfer-rate-limit
+func limitWriter(w io.Writer) io.Writer {
+	if limiter := transferLimiter(); limiter != nil {
+		return &limitedWriter{w: w, limiter: limiter}
+	}
+	return w
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), l.limiter.Burst())]
+		if err := l.limiter.WaitN(ctx, len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := l.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
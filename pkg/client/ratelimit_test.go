@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestTransferLimiter(t *testing.T) {
+	assert.Nil(t, transferLimiter())
+	assert.Equal(t, io.Reader(bytes.NewReader(nil)), limitReader(bytes.NewReader(nil)))
+
+	settings.Set("transfer-rate-limit", "1KiB")
+	defer settings.Set("transfer-rate-limit", "")
+	limiter := transferLimiter()
+	require.NotNil(t, limiter)
+	assert.Equal(t, 1024, limiter.Burst())
+	assert.Same(t, limiter, transferLimiter(), "the transfers share the limiter")
+
+	settings.Set("transfer-rate-limit", "1MiB")
+	assert.Equal(t, maxTransferBurst, transferLimiter().Burst())
+
+	settings.Set("transfer-rate-limit", "0")
+	assert.Nil(t, transferLimiter())
+}
+
+func TestLimitedTransfer(t *testing.T) {
+	settings.Set("transfer-rate-limit", "1MiB")
+	defer settings.Set("transfer-rate-limit", "")
+	// the burst goes through at once, the remaining MiB takes a second
+	data := bytes.Repeat([]byte("e2e.log\n"), (1024*1024+maxTransferBurst)/8)
+
+	for name, copy := range map[string]func(w io.Writer, r io.Reader) (int64, error){
+		"reader": func(w io.Writer, r io.Reader) (int64, error) { return io.Copy(w, limitReader(r)) },
+		"writer": func(w io.Writer, r io.Reader) (int64, error) { return io.Copy(limitWriter(w), r) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			start := time.Now()
+			n, err := copy(&out, bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(data)), n)
+			assert.Equal(t, data, out.Bytes())
+			assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+)
+
+// PodLostHandler is called when the conformance pod of the given name is lost
+// before its tests completed, e.g. because its node failed. It returns
+// whether the pod was recreated, in which case the logs of the replacement
+// are streamed.
+type PodLostHandler func(podName string) (bool, error)
+
+// nodeLost reports whether the node has not been ready for longer than the
+// timeout. The conformance pods tolerate every taint, so they are not
+// evicted from a node that failed and have to be given up on.
+func nodeLost(node *v1.Node, timeout time.Duration, now time.Time) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status != v1.ConditionTrue && now.Sub(condition.LastTransitionTime.Time) > timeout
+		}
+	}
+	return false
+}
+
+// podNodeLost reports whether the node running the pod was lost according to
+// --node-lost-timeout. The pods of --workload=job are left to their job.
+func (c *Client) podNodeLost(pod *v1.Pod) bool {
+	// the flag was validated by ValidateArgs
+	timeout, _ := common.GetDuration("node-lost-timeout")
+	if timeout <= 0 || jobWorkload() || pod.Spec.NodeName == "" || common.Restricted() {
+		return false
+	}
+	node, err := c.ClientSet.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+	if err != nil {
+		return false
+	}
+	return nodeLost(node, timeout, time.Now())
+}
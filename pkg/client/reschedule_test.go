@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeLost(t *testing.T) {
+	now := time.Now()
+	node := func(status v1.ConditionStatus, since time.Duration) *v1.Node {
+		return &v1.Node{Status: v1.NodeStatus{Conditions: []v1.NodeCondition{{
+			Type:               v1.NodeReady,
+			Status:             status,
+			LastTransitionTime: metav1.NewTime(now.Add(-since)),
+		}}}}
+	}
+
+	tests := []struct {
+		name string
+		node *v1.Node
+		want bool
+	}{
+		{name: "ready", node: node(v1.ConditionTrue, time.Hour), want: false},
+		{name: "not ready for a short while", node: node(v1.ConditionUnknown, time.Minute), want: false},
+		{name: "not ready for longer than the timeout", node: node(v1.ConditionUnknown, 10*time.Minute), want: true},
+		{name: "no ready condition", node: &v1.Node{}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, nodeLost(tt.node, 5*time.Minute, now))
+		})
+	}
+}
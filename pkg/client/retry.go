@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// defaultAPIRetries is the number of times a failed API request is retried,
+// unless --api-retries is set
+const defaultAPIRetries = 5
+
+// retryDelay is the delay before the first retry of a failed API request
+var retryDelay = time.Second
+
+// apiRetries returns the number of times a request failing with a transient
+// error is retried
+func apiRetries() int {
+	if settings.IsSet("api-retries") {
+		return settings.GetInt("api-retries")
+	}
+	return defaultAPIRetries
+}
+
+// IsTransient reports whether the error of an API request is likely to go away
+// when the request is retried: the API server throttled the request, failed
+// with a server error or timed out, or the connection was refused or reset.
+func IsTransient(err error) bool {
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		code := status.Status().Code
+		return code == 429 || code >= 500 ||
+			apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err)
+	}
+	return utilnet.IsConnectionReset(err) || utilnet.IsConnectionRefused(err) ||
+		utilnet.IsProbableEOF(err) || utilnet.IsTimeout(err) || utilnet.IsHTTP2ConnectionLost(err)
+}
+
+// Retry calls fn until it succeeds or fails with an error that isn't
+// transient, at most --api-retries more times, with a jittered exponential
+// backoff. A throttled request is retried no earlier than the API server asks
+// for. A request rejected as unauthorized is retried once, with the
+// credentials client-go refreshed. Every retry is logged with what failed.
+func Retry(what string, fn func() error) error {
+	backoff := wait.Backoff{
+		Duration: retryDelay,
+		Factor:   2,
+		Jitter:   0.5,
+		Steps:    apiRetries(),
+		Cap:      reconnectCap,
+	}
+	refreshed := false
+	for retry := 1; ; retry++ {
+		err := fn()
+		if apierrors.IsUnauthorized(err) && !refreshed {
+			// client-go refreshes the credentials of exec plugins and auth
+			// providers when the API server rejects them, e.g. a token
+			// that expired during a long run, the retry uses the new ones
+			refreshed = true
+			log.Printf("%s failed with expired credentials, retrying with refreshed credentials: %v", what, err)
+			continue
+		}
+		if err == nil || !IsTransient(err) || backoff.Steps < 1 {
+			return err
+		}
+		delay := backoff.Step()
+		if seconds, ok := apierrors.SuggestsClientDelay(err); ok && time.Duration(seconds)*time.Second > delay {
+			delay = time.Duration(seconds) * time.Second
+		}
+		log.Printf("%s failed with a transient error, retry %d of %d in %s: %v", what, retry, apiRetries(), delay.Round(time.Millisecond), err)
+		time.Sleep(delay)
+	}
+}
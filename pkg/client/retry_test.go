@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestIsTransient(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{name: "throttled", err: apierrors.NewTooManyRequests("slow down", 1), transient: true},
+		{name: "internal error", err: apierrors.NewInternalError(errors.New("etcd")), transient: true},
+		{name: "unavailable", err: apierrors.NewServiceUnavailable("restarting"), transient: true},
+		{name: "server timeout", err: apierrors.NewServerTimeout(pods, "create", 1), transient: true},
+		{name: "connection reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), transient: true},
+		{name: "connection refused", err: fmt.Errorf("dial: %w", syscall.ECONNREFUSED), transient: true},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, transient: true},
+		{name: "not found", err: apierrors.NewNotFound(pods, "e2e-conformance-test")},
+		{name: "already exists", err: apierrors.NewAlreadyExists(pods, "e2e-conformance-test")},
+		{name: "forbidden", err: apierrors.NewForbidden(pods, "e2e-conformance-test", errors.New("denied"))},
+		{name: "other", err: errors.New("invalid pod")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.transient, IsTransient(tt.err))
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	retryDelay = time.Millisecond
+	defer func() { retryDelay = time.Second }()
+	settings.Set("api-retries", 2)
+	defer settings.Set("api-retries", nil)
+
+	unavailable := apierrors.NewServiceUnavailable("restarting")
+	unauthorized := apierrors.NewUnauthorized("token expired")
+	tests := []struct {
+		name  string
+		errs  []error
+		calls int
+		err   error
+	}{
+		{name: "succeeds", calls: 1},
+		{name: "succeeds after a retry", errs: []error{unavailable}, calls: 2},
+		{name: "gives up", errs: []error{unavailable, unavailable, unavailable, unavailable}, calls: 3, err: unavailable},
+		{name: "expired credentials refreshed", errs: []error{unauthorized}, calls: 2},
+		{name: "credentials rejected again", errs: []error{unauthorized, unauthorized}, calls: 2, err: unauthorized},
+		{name: "not transient", errs: []error{io.ErrShortWrite}, calls: 1, err: io.ErrShortWrite},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := Retry("creating pod", func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.calls, calls)
+		})
+	}
+}
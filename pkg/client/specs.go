@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+var (
+	// specCountRegexp matches the line ginkgo prints with the number of specs
+	// it is about to run, once per shard
+	specCountRegexp = regexp.MustCompile(`Will run (\d+) of \d+ specs`)
+	// specDoneRegexp matches the line ginkgo prints when a spec completed,
+	// e.g. "• [12.345 seconds]", "• [FAILED] [1.234 seconds]" or "S [SKIPPED]"
+	specDoneRegexp = regexp.MustCompile(`(?:^|\s)[•SP] \[(FAILED|PANICKED|TIMEDOUT|INTERRUPTED|SKIPPED|PENDING)?`)
+)
+
+// SpecProgress counts the specs of the run as they complete in the log stream
+type SpecProgress struct {
+	// Total is the number of specs ginkgo is going to run
+	Total   atomic.Int64
+	Passed  atomic.Int64
+	Failed  atomic.Int64
+	Skipped atomic.Int64
+}
+
+// Completed returns the number of specs that completed
+func (p *SpecProgress) Completed() int64 {
+	return p.Passed.Load() + p.Failed.Load() + p.Skipped.Load()
+}
+
+// Add counts the spec reported by the line of the log stream, if any
+func (p *SpecProgress) Add(line string) {
+	if match := specCountRegexp.FindStringSubmatch(line); match != nil {
+		if n, err := strconv.ParseInt(match[1], 10, 64); err == nil {
+			p.Total.Add(n)
+		}
+		return
+	}
+	match := specDoneRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+	switch specResult(match[1]) {
+	case results.StatusPassed:
+		p.Passed.Add(1)
+	case results.StatusSkipped:
+		p.Skipped.Add(1)
+	default:
+		p.Failed.Add(1)
+	}
+}
+
+// specResult returns the status of a spec from the state ginkgo reports
+// when it completes
+func specResult(state string) string {
+	switch state {
+	case "":
+		return results.StatusPassed
+	case "SKIPPED", "PENDING":
+		return results.StatusSkipped
+	default:
+		return results.StatusFailed
+	}
+}
+
+// SpecObserver is notified of each spec completing in the log stream with
+// its status and the times it started and ended at
+type SpecObserver func(name, status string, start, end time.Time)
+
+// runningSpec is a spec whose output is being streamed
+type runningSpec struct {
+	name  string
+	start time.Time
+}
+
+// specTimer times the specs of the log stream. Ginkgo prints the name of a
+// spec after the separator preceding its output, the spec ends with the line
+// reporting its state. The output of the shards is interleaved in the
+// stream, it is tracked separately for each prefix.
+type specTimer struct {
+	prefixes []string
+	observe  SpecObserver
+	// started is notified of the name of each spec starting, if set
+	started func(name string)
+	now     func() time.Time
+	// separated holds the prefixes whose last line was a separator
+	separated map[string]bool
+	running   map[string]runningSpec
+}
+
+func newSpecTimer(prefixes []string, observe SpecObserver) *specTimer {
+	return &specTimer{
+		prefixes:  prefixes,
+		observe:   observe,
+		now:       time.Now,
+		separated: map[string]bool{},
+		running:   map[string]runningSpec{},
+	}
+}
+
+// add times the spec the line of the log stream belongs to
+func (t *specTimer) add(line string) {
+	prefix := ""
+	for _, p := range t.prefixes {
+		if strings.HasPrefix(line, p) {
+			prefix = p
+			break
+		}
+	}
+	content := strings.TrimSpace(trimTimestamp(strings.TrimPrefix(line, prefix)))
+	switch {
+	case content == "":
+	case strings.HasPrefix(content, specSeparator):
+		t.separated[prefix] = true
+	case specDoneRegexp.MatchString(content):
+		if spec, ok := t.running[prefix]; ok {
+			t.observe(spec.name, specResult(specDoneRegexp.FindStringSubmatch(content)[1]), spec.start, t.now())
+			delete(t.running, prefix)
+		}
+		t.separated[prefix] = false
+	case t.separated[prefix] && strings.HasPrefix(content, "["):
+		t.running[prefix] = runningSpec{name: content, start: t.now()}
+		if t.started != nil {
+			t.started(content)
+		}
+		t.separated[prefix] = false
+	default:
+		t.separated[prefix] = false
+	}
+}
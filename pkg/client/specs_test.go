@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+func TestSpecProgress(t *testing.T) {
+	lines := []string{
+		"[shard-0] Will run 200 of 7000 specs",
+		"[shard-1] Will run 202 of 7000 specs",
+		"[shard-0] • [12.345 seconds]",
+		"[shard-1] 2024-05-01T10:00:00.123456789Z • [FAILED] [1.234 seconds]",
+		"[shard-0] S [SKIPPED] [0.000 seconds]",
+		"[shard-0] • [0.500 seconds]",
+		"[shard-1] STEP: Creating a kubernetes client",
+		"[shard-1] I0501 10:00:00.000000 DAS [x] isn't a spec",
+	}
+	p := &SpecProgress{}
+	for _, line := range lines {
+		p.Add(line)
+	}
+	assert.Equal(t, int64(402), p.Total.Load())
+	assert.Equal(t, int64(2), p.Passed.Load())
+	assert.Equal(t, int64(1), p.Failed.Load())
+	assert.Equal(t, int64(1), p.Skipped.Load())
+	assert.Equal(t, int64(4), p.Completed())
+}
+
+func TestSpecTimer(t *testing.T) {
+	type observed struct {
+		name, status string
+		duration     time.Duration
+	}
+	var specs []observed
+	var started []string
+	timer := newSpecTimer([]string{"[a] ", "[b] "}, func(name, status string, start, end time.Time) {
+		specs = append(specs, observed{name, status, end.Sub(start)})
+	})
+	timer.started = func(name string) { started = append(started, name) }
+	now := time.Unix(0, 0)
+	timer.now = func() time.Time { return now }
+
+	for _, line := range []string{
+		"[a] ------------------------------",
+		"[a] [sig-node] Pods should be submitted and removed [Conformance]",
+		"[b] ------------------------------",
+		"[b] 2024-05-01T10:00:00.123456789Z [sig-network] DNS should provide DNS for services [Conformance]",
+		"[a]   STEP: Creating a kubernetes client",
+		"+5s",
+		"[b] • [FAILED] [5.000 seconds]",
+		"[b] ------------------------------",
+		"+3s",
+		"[a] • [8.000 seconds]",
+		"[a] ------------------------------",
+		"[a] S [SKIPPED] [0.000 seconds]",
+	} {
+		if d, err := time.ParseDuration(line); err == nil {
+			now = now.Add(d)
+			continue
+		}
+		timer.add(line)
+	}
+	assert.Equal(t, []observed{
+		{"[sig-network] DNS should provide DNS for services [Conformance]", results.StatusFailed, 5 * time.Second},
+		{"[sig-node] Pods should be submitted and removed [Conformance]", results.StatusPassed, 8 * time.Second},
+	}, specs)
+	assert.Equal(t, []string{
+		"[sig-node] Pods should be submitted and removed [Conformance]",
+		"[sig-network] DNS should provide DNS for services [Conformance]",
+	}, started)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// splitWriter writes a log to numbered chunks of at most maxSize bytes,
+// split at line boundaries unless a single line is larger than a chunk. The
+// first chunk is named after the log, the following ones get the number of
+// the chunk appended, e.g. e2e.log, e2e.log.1, e2e.log.2.
+type splitWriter struct {
+	maxSize int64
+	// create creates the file of the chunk with the given number
+	create  func(chunk int) (io.WriteCloser, error)
+	current io.WriteCloser
+	chunk   int
+	size    int64
+	// pending holds the beginning of a line not terminated yet
+	pending []byte
+}
+
+func newSplitWriter(maxSize int64, create func(chunk int) (io.WriteCloser, error)) (*splitWriter, error) {
+	current, err := create(0)
+	if err != nil {
+		return nil, err
+	}
+	return &splitWriter{maxSize: maxSize, create: create, current: current}, nil
+}
+
+// chunkName returns the name of the chunk of a log split by splitWriter
+func chunkName(name string, chunk int) string {
+	if chunk == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s.%d", name, chunk)
+}
+
+func (w *splitWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.writeLine(w.pending[:i+1]); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[i+1:]
+	}
+	// a line larger than a chunk can't be kept in one piece
+	for int64(len(w.pending)) >= w.maxSize {
+		if err := w.writeLine(w.pending[:w.maxSize]); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[w.maxSize:]
+	}
+	return len(p), nil
+}
+
+// writeLine writes the line to the current chunk, or to a new one when it
+// doesn't fit
+func (w *splitWriter) writeLine(line []byte) error {
+	for len(line) > 0 {
+		if w.size > 0 && w.size+int64(len(line)) > w.maxSize {
+			if err := w.rotate(); err != nil {
+				return err
+			}
+		}
+		n := int64(len(line))
+		if n > w.maxSize {
+			n = w.maxSize
+		}
+		if _, err := w.current.Write(line[:n]); err != nil {
+			return err
+		}
+		w.size += n
+		line = line[n:]
+	}
+	return nil
+}
+
+// rotate closes the current chunk and starts the next one
+func (w *splitWriter) rotate() error {
+	if err := w.current.Close(); err != nil {
+		return err
+	}
+	w.chunk++
+	current, err := w.create(w.chunk)
+	if err != nil {
+		return err
+	}
+	w.current, w.size = current, 0
+	return nil
+}
+
+// Close writes the unterminated last line and closes the current chunk
+func (w *splitWriter) Close() error {
+	if len(w.pending) > 0 {
+		if err := w.writeLine(w.pending); err != nil {
+			w.current.Close()
+			return err
+		}
+		w.pending = nil
+	}
+	return w.current.Close()
+}
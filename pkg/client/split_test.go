@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+type chunkBuffer struct {
+	chunks *[]string
+	data   []byte
+}
+
+func (b *chunkBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
+func (b *chunkBuffer) Close() error {
+	*b.chunks = append(*b.chunks, string(b.data))
+	return nil
+}
+
+func TestSplitWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		writes   []string
+		expected []string
+	}{
+		{
+			name:     "fits",
+			writes:   []string{"one\n", "two\n"},
+			expected: []string{"one\ntwo\n"},
+		},
+		{
+			name:     "split at lines",
+			writes:   []string{"first\nsec", "ond\nthird\n"},
+			expected: []string{"first\n", "second\n", "third\n"},
+		},
+		{
+			name:     "line larger than a chunk",
+			writes:   []string{"a\n", "0123456789abcdef\n", "b"},
+			expected: []string{"a\n", "01234567", "89abcdef", "\nb"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var chunks []string
+			w, err := newSplitWriter(8, func(int) (io.WriteCloser, error) {
+				return &chunkBuffer{chunks: &chunks}, nil
+			})
+			require.NoError(t, err)
+			for _, s := range tt.writes {
+				_, err := w.Write([]byte(s))
+				require.NoError(t, err)
+			}
+			require.NoError(t, w.Close())
+			assert.Equal(t, tt.expected, chunks)
+		})
+	}
+}
+
+func TestCreateE2ELogChunks(t *testing.T) {
+	dir := t.TempDir()
+	settings.Set("max-log-size", "10")
+	defer settings.Set("max-log-size", "")
+
+	require.NoError(t, writePartialFile(dir, "e2e.log", strings.NewReader("line one\nline two\n")))
+	for name, expected := range map[string]string{"e2e.log": "line one\n", "e2e.log.1": "line two\n"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(data))
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/redact"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// fetchArtifactsTar streams a tar of the files of the results directory of
+// the conformance container and extracts it to the output directory, used
+// with --artifact-transfer=exec in place of the downloads through the output
+// container. Once done the conformance container is told it can exit.
+func fetchArtifactsTar(config *rest.Config, clientset kubernetes.Interface, podName, outputDir string, files []string) error {
+	namespace := settings.GetString("namespace")
+	log.Printf("streaming %v of pod %s to %s", files, podName, outputDir)
+	r, w := io.Pipe()
+	go func() {
+		command := append([]string{"tar", "-C", defaultResultsDir, "-cf", "-"}, files...)
+		w.CloseWithError(execInContainer(config, clientset, namespace, podName, common.ConformanceContainer, command, limitWriter(w), nil))
+	}()
+	if err := extractArtifacts(r, outputDir); err != nil {
+		r.CloseWithError(err)
+		return err
+	}
+	// drain the padding after the end of the archive, reporting the errors
+	// of tar
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return err
+	}
+	fetched := path.Join(defaultResultsDir, common.ArtifactsFetchedFile)
+	return execInContainer(config, clientset, namespace, podName, common.ConformanceContainer, []string{"touch", fetched}, nil, nil)
+}
+
+// extractArtifacts writes the files of the tar stream to the same paths
+// relative to the output directory, e2e.log through createE2ELog so that it
+// is compressed and split like a download.
+func extractArtifacts(r io.Reader, outputDir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error reading the artifacts: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		file := path.Clean(header.Name)
+		if !filepath.IsLocal(filepath.FromSlash(file)) {
+			return fmt.Errorf("artifact %s is outside of the results directory", header.Name)
+		}
+		if err := extractArtifact(tr, outputDir, file); err != nil {
+			return err
+		}
+	}
+}
+
+// extractArtifact writes the content of a single file of the tar stream
+func extractArtifact(r io.Reader, outputDir, file string) error {
+	var w io.WriteCloser
+	var dst string
+	var err error
+	if file == "e2e.log" {
+		w, dst, err = createE2ELog(outputDir)
+	} else {
+		dst = filepath.Join(outputDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		w, err = os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	}
+	if err != nil {
+		return err
+	}
+	// the secrets in the text artifacts are scrubbed as they are written
+	if redact.IsText(file) {
+		w = redact.NewWriter(w)
+	}
+	log.Printf("extracting %s to %s", file, dst)
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tarArchive returns a tar holding the files, directories end with a slash
+func tarArchive(t *testing.T, files map[string]string) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if name[len(name)-1] == '/' {
+			header = &tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		require.NoError(t, tw.WriteHeader(header))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return &buf
+}
+
+func TestExtractArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	archive := tarArchive(t, map[string]string{
+		"./e2e.log":              "Running Suite: Kubernetes e2e suite\n",
+		"./junit_01.xml":         "<testsuites></testsuites>",
+		"./hostlogs/":            "",
+		"./hostlogs/kubelet.log": "kubelet\n",
+	})
+	require.NoError(t, extractArtifacts(archive, dir))
+
+	for file, content := range map[string]string{
+		"e2e.log":              "Running Suite: Kubernetes e2e suite\n",
+		"junit_01.xml":         "<testsuites></testsuites>",
+		"hostlogs/kubelet.log": "kubelet\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		require.NoError(t, err, file)
+		assert.Equal(t, content, string(data), file)
+	}
+
+	archive = tarArchive(t, map[string]string{"../escape.log": "escape\n"})
+	assert.Error(t, extractArtifacts(archive, dir))
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(dir), "escape.log"))
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+const (
+	podMetricsPath = "/apis/metrics.k8s.io/v1beta1/pods"
+	gib            = 1 << 30
+)
+
+// podMetricsList is the subset of the PodMetricsList of the metrics API
+// needed to account the usage of a run
+type podMetricsList struct {
+	Items []struct {
+		metav1.ObjectMeta `json:"metadata"`
+		Containers        []struct {
+			Usage v1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// usageSample is the usage of the pods of a run at one point in time
+type usageSample struct {
+	cpuCores    float64
+	memoryBytes int64
+	pods        int
+}
+
+// UsageSampler periodically samples the metrics API for the resource usage
+// of the conformance pods and of the pods created by the e2e tests.
+type UsageSampler struct {
+	client *Client
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu    sync.Mutex
+	usage *results.Usage
+}
+
+// StartUsageSampler samples the usage every interval until Stop is called.
+func (c *Client) StartUsageSampler(interval time.Duration) *UsageSampler {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &UsageSampler{client: c, cancel: cancel, done: make(chan struct{}), usage: &results.Usage{}}
+	go s.run(ctx, interval)
+	return s
+}
+
+// Stop ends the sampling and returns the usage, or nil if the metrics API
+// could not be sampled.
+func (s *UsageSampler) Stop() *results.Usage {
+	s.cancel()
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.usage.Samples == 0 {
+		return nil
+	}
+	return s.usage
+}
+
+func (s *UsageSampler) run(ctx context.Context, interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			sample, err := s.client.sampleUsage(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("unable to sample resource usage, no usage will be reported: %v", err)
+				}
+				return
+			}
+			s.mu.Lock()
+			accumulate(s.usage, sample, now.Sub(last))
+			s.mu.Unlock()
+			last = now
+		}
+	}
+}
+
+// sampleUsage sums the usage of the pods in the namespace of hydrophone and
+// in the namespaces created by the e2e framework
+func (c *Client) sampleUsage(ctx context.Context) (usageSample, error) {
+	namespaces, err := c.ClientSet.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: common.E2ERunLabel})
+	if err != nil {
+		return usageSample{}, err
+	}
+	selected := map[string]bool{settings.GetString("namespace"): true}
+	for _, ns := range namespaces.Items {
+		selected[ns.Name] = true
+	}
+
+	data, err := c.ClientSet.Discovery().RESTClient().Get().AbsPath(podMetricsPath).DoRaw(ctx)
+	if err != nil {
+		return usageSample{}, err
+	}
+	metrics := podMetricsList{}
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return usageSample{}, err
+	}
+	return podUsage(metrics, selected), nil
+}
+
+// podUsage sums the usage of the pods of the selected namespaces
+func podUsage(metrics podMetricsList, namespaces map[string]bool) usageSample {
+	sample := usageSample{}
+	for _, pod := range metrics.Items {
+		if !namespaces[pod.Namespace] {
+			continue
+		}
+		sample.pods++
+		for _, container := range pod.Containers {
+			sample.cpuCores += container.Usage.Cpu().AsApproximateFloat64()
+			sample.memoryBytes += container.Usage.Memory().Value()
+		}
+	}
+	return sample
+}
+
+// accumulate adds a sample to the usage, assuming it held since the previous sample
+func accumulate(usage *results.Usage, sample usageSample, elapsed time.Duration) {
+	usage.Samples++
+	usage.CPUCoreSeconds += sample.cpuCores * elapsed.Seconds()
+	usage.MemoryGiBSeconds += float64(sample.memoryBytes) / gib * elapsed.Seconds()
+	usage.PeakCPUCores = max(usage.PeakCPUCores, sample.cpuCores)
+	usage.PeakMemoryBytes = max(usage.PeakMemoryBytes, sample.memoryBytes)
+	usage.PeakPods = max(usage.PeakPods, sample.pods)
+}
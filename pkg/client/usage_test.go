@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+func TestPodUsage(t *testing.T) {
+	data := `{"items": [
+		{"metadata": {"name": "e2e-conformance-test", "namespace": "conformance"}, "containers": [
+			{"usage": {"cpu": "500m", "memory": "512Mi"}},
+			{"usage": {"cpu": "0", "memory": "512Mi"}}
+		]},
+		{"metadata": {"name": "pod-1", "namespace": "pods-1234"}, "containers": [
+			{"usage": {"cpu": "250000000n", "memory": "1Gi"}}
+		]},
+		{"metadata": {"name": "coredns", "namespace": "kube-system"}, "containers": [
+			{"usage": {"cpu": "2", "memory": "1Gi"}}
+		]}
+	]}`
+	metrics := podMetricsList{}
+	require.NoError(t, json.Unmarshal([]byte(data), &metrics))
+
+	sample := podUsage(metrics, map[string]bool{"conformance": true, "pods-1234": true})
+	assert.Equal(t, usageSample{cpuCores: 0.75, memoryBytes: 2 << 30, pods: 2}, sample)
+}
+
+func TestAccumulate(t *testing.T) {
+	usage := &results.Usage{}
+	accumulate(usage, usageSample{cpuCores: 1, memoryBytes: 1 << 30, pods: 1}, time.Minute)
+	accumulate(usage, usageSample{cpuCores: 2, memoryBytes: 512 << 20, pods: 3}, 30*time.Second)
+
+	assert.Equal(t, &results.Usage{
+		Samples:          2,
+		CPUCoreSeconds:   120,
+		MemoryGiBSeconds: 75,
+		PeakCPUCores:     2,
+		PeakMemoryBytes:  1 << 30,
+		PeakPods:         3,
+	}, usage)
+
+	usage.Estimate(0.036, 0.0036)
+	assert.InDelta(t, 0.0012+0.000075, usage.Cost, 1e-9)
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clusters runs the conformance tests against several clusters at
+// the same time and compares their results.
+package clusters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/hydrophone/pkg/batch"
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+// ReportFile is the name of the file of the output directory holding the
+// comparison of the clusters
+const ReportFile = "clusters-report.json"
+
+// Cluster is a cluster the tests are run against
+type Cluster struct {
+	// Name identifies the cluster, its results are written to a directory
+	// of that name. It defaults to the context.
+	Name string `json:"name,omitempty"`
+	// Kubeconfig is the kubeconfig file of the cluster, the default one when
+	// empty
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	// Context is the context of the kubeconfig, its current context when
+	// empty
+	Context string `json:"context,omitempty"`
+}
+
+// File is the clusters file listing the clusters to run the tests against
+type File struct {
+	Clusters []Cluster `json:"clusters"`
+}
+
+// Load reads the clusters of the clusters file.
+func Load(path string) ([]Cluster, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing clusters file %s: %w", path, err)
+	}
+	return file.Clusters, nil
+}
+
+// FromContexts returns the clusters of the contexts of the kubeconfig.
+func FromContexts(kubeconfig string, contexts []string) []Cluster {
+	var clusters []Cluster
+	for _, context := range contexts {
+		clusters = append(clusters, Cluster{Kubeconfig: kubeconfig, Context: context})
+	}
+	return clusters
+}
+
+// Validate names the clusters after their context and fails when two
+// clusters share a name, their results would be written to the same
+// directory.
+func Validate(clusters []Cluster) error {
+	if len(clusters) == 0 {
+		return fmt.Errorf("no clusters given")
+	}
+	seen := map[string]bool{}
+	for i := range clusters {
+		if clusters[i].Name == "" {
+			clusters[i].Name = clusters[i].Context
+		}
+		name := clusters[i].Name
+		switch {
+		case name == "":
+			return fmt.Errorf("cluster %d has neither a name nor a context", i+1)
+		case strings.ContainsAny(name, `/\`) || name == "." || name == "..":
+			return fmt.Errorf("invalid cluster name %q", name)
+		case seen[name]:
+			return fmt.Errorf("cluster %s is given more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// WriteKubeconfig writes a kubeconfig holding only the context of the cluster,
+// with the credentials inlined, so that a hydrophone process run with it
+// targets the cluster whatever the current context of the kubeconfig.
+func WriteKubeconfig(c Cluster, path string) error {
+	config, err := client.LoadingRules(c.Kubeconfig).Load()
+	if err != nil {
+		return fmt.Errorf("error loading kubeconfig of cluster %s: %w", c.Name, err)
+	}
+	if c.Context != "" {
+		if _, ok := config.Contexts[c.Context]; !ok {
+			return fmt.Errorf("context %s of cluster %s not found in the kubeconfig", c.Context, c.Name)
+		}
+		config.CurrentContext = c.Context
+	}
+	if err := clientcmdapi.MinifyConfig(config); err != nil {
+		return fmt.Errorf("error selecting the context of cluster %s: %w", c.Name, err)
+	}
+	if err := clientcmdapi.FlattenConfig(config); err != nil {
+		return fmt.Errorf("error inlining the credentials of cluster %s: %w", c.Name, err)
+	}
+	return clientcmd.WriteToFile(*config, path)
+}
+
+// Difference is a test whose outcome differs between the clusters
+type Difference struct {
+	Name string `json:"name"`
+	// Status maps the name of a cluster to the outcome of the test,
+	// passed, failed or skipped, missing when it didn't run there
+	Status map[string]string `json:"status"`
+}
+
+// Report compares the results of the clusters
+type Report struct {
+	batch.Summary
+	// Differences are the tests that didn't have the same outcome on all
+	// clusters, sorted by name
+	Differences []Difference `json:"differences"`
+}
+
+// Compare reads the junit reports of the results and lists the tests whose
+// outcome differs between the clusters. Clusters without a junit report,
+// e.g. because the run couldn't start, are left out of the comparison.
+func Compare(summary *batch.Summary) *Report {
+	report := &Report{Summary: *summary, Differences: []Difference{}}
+	statuses := map[string]map[string]string{}
+	var compared []string
+	for _, result := range summary.Results {
+		suites, err := results.ReadJUnit(filepath.Join(result.OutputDir, "junit_01.xml"))
+		if err != nil {
+			continue
+		}
+		compared = append(compared, result.Name)
+		for _, s := range suites.TestSuites {
+			for _, tc := range s.TestCases {
+				name, ok := strings.CutPrefix(tc.Name, "[It] ")
+				if !ok {
+					continue
+				}
+				if statuses[name] == nil {
+					statuses[name] = map[string]string{}
+				}
+				statuses[name][result.Name] = status(tc)
+			}
+		}
+	}
+
+	for name, status := range statuses {
+		first := status[compared[0]]
+		for _, cluster := range compared[1:] {
+			if status[cluster] != first {
+				report.Differences = append(report.Differences, Difference{Name: name, Status: status})
+				break
+			}
+		}
+	}
+	sort.Slice(report.Differences, func(i, j int) bool {
+		return report.Differences[i].Name < report.Differences[j].Name
+	})
+	return report
+}
+
+// status returns the outcome of the test case
+func status(tc results.JUnitTestCase) string {
+	switch {
+	case tc.Failure != nil || tc.Error != nil:
+		return results.StatusFailed
+	case tc.Status == results.StatusPassed:
+		return results.StatusPassed
+	default:
+		return results.StatusSkipped
+	}
+}
+
+// WriteReport writes the report as indented JSON to the output directory.
+func WriteReport(outputDir string, report *Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding clusters report: %w", err)
+	}
+	path := filepath.Join(outputDir, ReportFile)
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// PrefixWriter writes complete lines to the underlying writer with a prefix,
+// so that the output of runs executed at the same time can be told apart.
+// Writers sharing a mutex don't interleave their lines.
+type PrefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+// NewPrefixWriter returns a writer prefixing the lines written to w.
+func NewPrefixWriter(w io.Writer, mu *sync.Mutex, prefix string) *PrefixWriter {
+	return &PrefixWriter{w: w, mu: mu, prefix: prefix}
+}
+
+func (p *PrefixWriter) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes the last line when it doesn't end with a newline.
+func (p *PrefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	err := p.writeLine(append(p.buf, '\n'))
+	p.buf = nil
+	return err
+}
+
+func (p *PrefixWriter) writeLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+	return err
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusters
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"sigs.k8s.io/hydrophone/pkg/batch"
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: staging
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+- name: staging
+  cluster:
+    server: https://staging.example.com
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: admin
+- name: staging
+  context:
+    cluster: staging
+    user: admin
+users:
+- name: admin
+  user:
+    token: secret
+`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clusters.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("clusters:\n- name: eu\n  kubeconfig: /kube/eu\n- context: us\n"), 0644))
+	clusters, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Cluster{{Name: "eu", Kubeconfig: "/kube/eu"}, {Context: "us"}}, clusters)
+
+	require.NoError(t, os.WriteFile(path, []byte("clusters:\n- cluster: eu\n"), 0644))
+	_, err = Load(path)
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		clusters []Cluster
+		names    []string
+		wantErr  bool
+	}{
+		{
+			name:     "named after context",
+			clusters: FromContexts("", []string{"prod", "staging"}),
+			names:    []string{"prod", "staging"},
+		},
+		{name: "none", wantErr: true},
+		{name: "unnamed", clusters: []Cluster{{Kubeconfig: "/kube/eu"}}, wantErr: true},
+		{name: "duplicate", clusters: []Cluster{{Context: "prod"}, {Name: "prod", Kubeconfig: "/kube/prod"}}, wantErr: true},
+		{name: "path", clusters: []Cluster{{Name: "../prod"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.clusters)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var names []string
+			for _, c := range tt.clusters {
+				names = append(names, c.Name)
+			}
+			assert.Equal(t, tt.names, names)
+		})
+	}
+}
+
+func TestWriteKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(path, []byte(kubeconfig), 0600))
+
+	out := filepath.Join(dir, "prod")
+	require.NoError(t, WriteKubeconfig(Cluster{Name: "prod", Kubeconfig: path, Context: "prod"}, out))
+	config, err := clientcmd.LoadFromFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", config.CurrentContext)
+	assert.Len(t, config.Clusters, 1)
+	assert.Equal(t, "https://prod.example.com", config.Clusters["prod"].Server)
+
+	assert.Error(t, WriteKubeconfig(Cluster{Name: "dev", Kubeconfig: path, Context: "dev"}, out))
+}
+
+func TestCompare(t *testing.T) {
+	dir := t.TempDir()
+	junit := func(name string, cases ...results.JUnitTestCase) batch.Result {
+		outputDir := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(outputDir, 0755))
+		suites := &results.JUnitTestSuites{TestSuites: []results.JUnitTestSuite{{TestCases: cases}}}
+		require.NoError(t, results.WriteJUnit(filepath.Join(outputDir, "junit_01.xml"), suites))
+		return batch.Result{Run: batch.Run{Name: name}, OutputDir: outputDir}
+	}
+	passed := func(name string) results.JUnitTestCase {
+		return results.JUnitTestCase{Name: "[It] " + name, Status: results.StatusPassed}
+	}
+	failed := func(name string) results.JUnitTestCase {
+		return results.JUnitTestCase{Name: "[It] " + name, Status: results.StatusFailed, Failure: &results.JUnitMessage{Message: "failed"}}
+	}
+
+	summary := &batch.Summary{Results: []batch.Result{
+		junit("prod", passed("a"), passed("b"), passed("c")),
+		junit("staging", passed("a"), failed("b")),
+		{Run: batch.Run{Name: "dev"}, OutputDir: filepath.Join(dir, "dev"), Error: "unable to start"},
+	}}
+	report := Compare(summary)
+	assert.Equal(t, []Difference{
+		{Name: "b", Status: map[string]string{"prod": "passed", "staging": "failed"}},
+		{Name: "c", Status: map[string]string{"prod": "passed"}},
+	}, report.Differences)
+
+	require.NoError(t, WriteReport(dir, report))
+	assert.FileExists(t, filepath.Join(dir, ReportFile))
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	w := NewPrefixWriter(&out, &mu, "[prod] ")
+	_, err := w.Write([]byte("first line\nsec"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("ond line\nlast"))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	assert.Equal(t, "[prod] first line\n[prod] second line\n[prod] last\n", out.String())
+}
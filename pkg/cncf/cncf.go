@@ -0,0 +1,285 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cncf assembles the submission of the results of a conformance run
+// to the CNCF Certified Kubernetes program, see
+// https://github.com/cncf/k8s-conformance/blob/master/instructions.md
+package cncf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+// Files of a submission
+const (
+	ProductFile = "PRODUCT.yaml"
+	ReadmeFile  = "README.md"
+	E2ELogFile  = "e2e.log"
+	JUnitFile   = "junit_01.xml"
+)
+
+// Types of the products accepted by the program
+var productTypes = []string{"distribution", "hosted platform", "installer"}
+
+// Product is the PRODUCT.yaml describing the certified product
+type Product struct {
+	Vendor              string `json:"vendor"`
+	Name                string `json:"name"`
+	Version             string `json:"version"`
+	WebsiteURL          string `json:"website_url"`
+	RepoURL             string `json:"repo_url,omitempty"`
+	DocumentationURL    string `json:"documentation_url"`
+	ProductLogoURL      string `json:"product_logo_url"`
+	Type                string `json:"type"`
+	Description         string `json:"description"`
+	ContactEmailAddress string `json:"contact_email_address"`
+}
+
+// missing returns the names of the required fields that aren't set
+func (p *Product) missing() []string {
+	var names []string
+	for _, field := range []struct{ name, value string }{
+		{"vendor", p.Vendor},
+		{"name", p.Name},
+		{"version", p.Version},
+		{"website_url", p.WebsiteURL},
+		{"documentation_url", p.DocumentationURL},
+		{"product_logo_url", p.ProductLogoURL},
+		{"type", p.Type},
+		{"description", p.Description},
+		{"contact_email_address", p.ContactEmailAddress},
+	} {
+		if field.value == "" {
+			names = append(names, field.name)
+		}
+	}
+	return names
+}
+
+// Submission is the submission of the results of a run
+type Submission struct {
+	// ResultsDir is the output directory of the run
+	ResultsDir string
+	// KubernetesVersion is the minor version the product is certified for,
+	// e.g. v1.30. It is derived from the server version of the run when empty.
+	KubernetesVersion string
+	Product           Product
+}
+
+var (
+	minorVersion = regexp.MustCompile(`^v?(\d+)\.(\d+)`)
+	nonSlug      = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Dir returns the directory of the submission in the k8s-conformance
+// repository, e.g. v1.30/my-product
+func (s *Submission) Dir() (string, error) {
+	version := s.KubernetesVersion
+	if version == "" {
+		metadata, err := results.ReadMetadata(s.ResultsDir)
+		if err != nil {
+			return "", fmt.Errorf("unable to read the server version of the run, pass the Kubernetes version: %w", err)
+		}
+		version = metadata.ServerVersion
+	}
+	m := minorVersion.FindStringSubmatch(version)
+	if m == nil {
+		return "", fmt.Errorf("invalid Kubernetes version %q", version)
+	}
+	name := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(s.Product.Name), "-"), "-")
+	if name == "" {
+		return "", errors.New("the name of the product is required")
+	}
+	return filepath.Join(fmt.Sprintf("v%s.%s", m[1], m[2]), name), nil
+}
+
+// Write writes the files of the submission to the directory of the
+// submission below root and returns it along with the problems that keep
+// the submission from being complete.
+func (s *Submission) Write(root string) (string, []string, error) {
+	rel, err := s.Dir()
+	if err != nil {
+		return "", nil, err
+	}
+	dir := filepath.Join(root, rel)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", nil, err
+	}
+
+	var problems []string
+	if err := s.writeE2ELog(filepath.Join(dir, E2ELogFile)); err != nil {
+		return "", nil, err
+	}
+	junit, err := s.copyJUnit(filepath.Join(dir, JUnitFile))
+	if err != nil {
+		return "", nil, err
+	}
+	problems = append(problems, junitProblems(junit)...)
+
+	product, err := yaml.Marshal(s.Product)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ProductFile), product, 0644); err != nil {
+		return "", nil, err
+	}
+	for _, name := range s.Product.missing() {
+		problems = append(problems, fmt.Sprintf("%s: %s is missing", ProductFile, name))
+	}
+	if s.Product.Type != "" && !slices.Contains(productTypes, s.Product.Type) {
+		problems = append(problems, fmt.Sprintf("%s: expected type to be one of %s, got %q", ProductFile, strings.Join(productTypes, ", "), s.Product.Type))
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ReadmeFile), s.readme(), 0644); err != nil {
+		return "", nil, err
+	}
+	return dir, problems, nil
+}
+
+// writeE2ELog writes the e2e.log of the run to path, decompressing and
+// joining it when it was written with --compress or --max-log-size
+func (s *Submission) writeE2ELog(path string) error {
+	var chunks []string
+	for chunk := 0; ; chunk++ {
+		name := E2ELogFile
+		if chunk > 0 {
+			name = fmt.Sprintf("%s.%d", E2ELogFile, chunk)
+		}
+		found := ""
+		for _, candidate := range []string{name, name + ".gz"} {
+			if _, err := os.Stat(filepath.Join(s.ResultsDir, candidate)); err == nil {
+				found = candidate
+				break
+			}
+		}
+		if found == "" {
+			break
+		}
+		chunks = append(chunks, found)
+	}
+	if len(chunks) == 0 {
+		return fmt.Errorf("%s not found in %s", E2ELogFile, s.ResultsDir)
+	}
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	for _, chunk := range chunks {
+		if err := appendFile(out, filepath.Join(s.ResultsDir, chunk)); err != nil {
+			return err
+		}
+	}
+	return out.Close()
+}
+
+// appendFile copies the file, gunzipped when it ends with .gz, to w
+func appendFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// copyJUnit copies the junit report of the run to path and returns it
+func (s *Submission) copyJUnit(path string) (*results.JUnitTestSuites, error) {
+	data, err := os.ReadFile(filepath.Join(s.ResultsDir, JUnitFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s not found in %s", JUnitFile, s.ResultsDir)
+	} else if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return nil, err
+	}
+	return results.ReadJUnit(path)
+}
+
+// junitProblems returns the reasons the report doesn't certify conformance
+func junitProblems(report *results.JUnitTestSuites) []string {
+	var passed, failed int
+	for _, suite := range report.TestSuites {
+		for _, tc := range suite.TestCases {
+			switch tc.Status {
+			case results.StatusPassed:
+				passed++
+			case results.StatusFailed:
+				failed++
+			}
+		}
+	}
+	var problems []string
+	if passed == 0 {
+		problems = append(problems, fmt.Sprintf("%s: no test passed", JUnitFile))
+	}
+	if failed > 0 {
+		problems = append(problems, fmt.Sprintf("%s: %d tests failed, all conformance tests have to pass", JUnitFile, failed))
+	}
+	return problems
+}
+
+// readme returns the README.md describing how to reproduce the results
+func (s *Submission) readme() []byte {
+	var b bytes.Buffer
+	name := s.Product.Name
+	if s.Product.Version != "" {
+		name += " " + s.Product.Version
+	}
+	fmt.Fprintf(&b, "# %s\n\n", name)
+	if s.Product.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", s.Product.Description)
+	}
+	b.WriteString("## To reproduce\n\n")
+	b.WriteString("Create a cluster of the product")
+	if s.Product.DocumentationURL != "" {
+		fmt.Fprintf(&b, " as documented in %s", s.Product.DocumentationURL)
+	}
+	b.WriteString(", then run the conformance tests with [hydrophone](https://github.com/kubernetes-sigs/hydrophone):\n\n")
+	b.WriteString("```\ngo install sigs.k8s.io/hydrophone@latest\nhydrophone --conformance")
+	if metadata, err := results.ReadMetadata(s.ResultsDir); err == nil && metadata.ConformanceImage != "" {
+		fmt.Fprintf(&b, " --conformance-image %s", metadata.ConformanceImage)
+	}
+	b.WriteString("\n```\n\n")
+	fmt.Fprintf(&b, "The results are written to %s and %s in the current directory.\n", E2ELogFile, JUnitFile)
+	return b.Bytes()
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cncf
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+func writeJUnit(t *testing.T, dir string, statuses ...string) {
+	suite := results.JUnitTestSuite{Name: "Kubernetes e2e suite"}
+	for _, status := range statuses {
+		suite.TestCases = append(suite.TestCases, results.JUnitTestCase{Name: "[Conformance] " + status, Status: status})
+	}
+	require.NoError(t, results.WriteJUnit(filepath.Join(dir, JUnitFile), &results.JUnitTestSuites{TestSuites: []results.JUnitTestSuite{suite}}))
+}
+
+func TestSubmissionWrite(t *testing.T) {
+	product := Product{
+		Vendor:              "Example",
+		Name:                "Example Kubernetes",
+		Version:             "1.2.0",
+		WebsiteURL:          "https://example.com",
+		DocumentationURL:    "https://example.com/docs",
+		ProductLogoURL:      "https://example.com/logo.svg",
+		Type:                "distribution",
+		Description:         "Kubernetes by Example.",
+		ContactEmailAddress: "k8s@example.com",
+	}
+
+	tests := []struct {
+		name     string
+		product  Product
+		statuses []string
+		problems []string
+	}{
+		{
+			name:     "complete",
+			product:  product,
+			statuses: []string{results.StatusPassed, results.StatusSkipped},
+		},
+		{
+			name:     "failed tests",
+			product:  product,
+			statuses: []string{results.StatusPassed, results.StatusFailed},
+			problems: []string{"junit_01.xml: 1 tests failed, all conformance tests have to pass"},
+		},
+		{
+			name:     "incomplete product",
+			product:  Product{Name: "Example Kubernetes", Type: "cloud"},
+			statuses: []string{results.StatusPassed},
+			problems: []string{
+				"PRODUCT.yaml: vendor is missing",
+				"PRODUCT.yaml: version is missing",
+				"PRODUCT.yaml: website_url is missing",
+				"PRODUCT.yaml: documentation_url is missing",
+				"PRODUCT.yaml: product_logo_url is missing",
+				"PRODUCT.yaml: description is missing",
+				"PRODUCT.yaml: contact_email_address is missing",
+				`PRODUCT.yaml: expected type to be one of distribution, hosted platform, installer, got "cloud"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resultsDir := t.TempDir()
+			require.NoError(t, results.WriteMetadata(resultsDir, &results.Metadata{ServerVersion: "v1.30.2-eks-1234"}))
+			require.NoError(t, os.WriteFile(filepath.Join(resultsDir, E2ELogFile), []byte("first\n"), 0600))
+			writeJUnit(t, resultsDir, tt.statuses...)
+
+			s := Submission{ResultsDir: resultsDir, Product: tt.product}
+			dir, problems, err := s.Write(t.TempDir())
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join("v1.30", "example-kubernetes"), filepath.Join(filepath.Base(filepath.Dir(dir)), filepath.Base(dir)))
+			assert.Equal(t, tt.problems, problems)
+			for _, name := range []string{ProductFile, ReadmeFile, E2ELogFile, JUnitFile} {
+				assert.FileExists(t, filepath.Join(dir, name))
+			}
+		})
+	}
+}
+
+func TestSubmissionJoinsE2ELog(t *testing.T) {
+	resultsDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(resultsDir, E2ELogFile), []byte("first\n"), 0600))
+	f, err := os.Create(filepath.Join(resultsDir, E2ELogFile+".1.gz"))
+	require.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	_, err = gz.Write([]byte("second\n"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+	writeJUnit(t, resultsDir, results.StatusPassed)
+
+	s := Submission{ResultsDir: resultsDir, KubernetesVersion: "1.31", Product: Product{Name: "Example"}}
+	dir, _, err := s.Write(t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, "v1.31", filepath.Base(filepath.Dir(dir)))
+	data, err := os.ReadFile(filepath.Join(dir, E2ELogFile))
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", string(data))
+}
+
+func TestSubmissionMissingFiles(t *testing.T) {
+	s := Submission{ResultsDir: t.TempDir(), KubernetesVersion: "v1.30", Product: Product{Name: "Example"}}
+	_, _, err := s.Write(t.TempDir())
+	assert.ErrorContains(t, err, "e2e.log not found")
+}
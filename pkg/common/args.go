@@ -17,47 +17,134 @@ limitations under the License.
 package common
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/blang/semver/v4"
-	"github.com/spf13/viper"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
 	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/registry"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
 )
 
 // PrintInfo prints the information about the cluster
-func PrintInfo(clientSet *kubernetes.Clientset, config *rest.Config) {
-	spinner := NewSpinner(os.Stdout)
+func PrintInfo(clientSet kubernetes.Interface, config *rest.Config) {
+	// the spinner shares the output of the logs so that stdout only carries
+	// the output of commands such as list
+	spinner := NewSpinner(log.Output())
 	spinner.Start()
 
 	time.Sleep(2 * time.Second)
-	serverVersion, err := clientSet.ServerVersion()
+	serverVersion, err := clientSet.Discovery().ServerVersion()
+	spinner.Stop()
 	if err != nil {
 		log.Fatalf("Error fetching server version: %v", err)
 	}
-	trimmedVersion, err := trimVersion(serverVersion.String())
+	if err := ValidateVersionPolicy(); err != nil {
+		log.Fatal(err)
+	}
+	trimmedVersion, err := SetServerVersion(serverVersion.String())
 	if err != nil {
 		log.Fatalf("Error trimming server version: %v", err)
 	}
-	if viper.Get("conformance-image") == "" {
-		viper.Set("conformance-image", fmt.Sprintf("registry.k8s.io/conformance:%s", trimmedVersion))
-	}
-	if viper.Get("busybox-image") == "" {
-		viper.Set("busybox-image", busyboxImage)
-	}
 
 	log.PrintfAPI("API endpoint : %s", config.Host)
 	log.Printf("Server version : %#v", *serverVersion)
+	if trimmedVersion != serverVersion.String() {
+		log.Printf("Server version %s maps to upstream version %s", serverVersion.String(), trimmedVersion)
+	}
+}
+
+// SetServerVersion records the version reported by the cluster along with
+// the upstream version it maps to, which it returns, and sets the default
+// images for it.
+func SetServerVersion(version string) (string, error) {
+	trimmedVersion, err := trimVersion(version)
+	if err != nil {
+		return "", err
+	}
+	settings.Set("server-version", trimmedVersion)
+	// keep the version reported by the cluster for the results, it names
+	// the distribution the upstream version was mapped from
+	settings.Set("server-git-version", version)
+	tag := trimmedVersion
+	if settings.Get("conformance-image") == "" && settings.GetString("version-policy") == VersionPolicyLatestPatch {
+		tag = latestPatch(trimmedVersion)
+	}
+	SetDefaultImages(tag)
+	return trimmedVersion, nil
+}
+
+// conformanceTags lists the tags of the conformance images
+var conformanceTags = func() ([]string, error) {
+	return registry.NewChecker().Tags(ConformanceRepository)
+}
+
+// latestPatch returns the tag of the newest conformance image of the minor
+// version of the server, which is used when the server runs a patch version
+// without a published conformance image. The server version is returned when
+// the registry can't be queried or has no image of its minor version.
+func latestPatch(version string) string {
+	tags, err := conformanceTags()
+	if err != nil {
+		log.Printf("WARNING: unable to list the tags of %s, using %s: %v", ConformanceRepository, version, err)
+		return version
+	}
+	latest := registry.LatestPatch(version, tags)
+	if latest == "" {
+		log.Printf("WARNING: %s has no image of the minor version of %s, using %s", ConformanceRepository, version, version)
+		return version
+	}
+	if latest != version {
+		log.Printf("Using the latest patch %s of the conformance images for server version %s", latest, version)
+	}
+	return latest
+}
+
+// ValidateVersionPolicy checks --version-policy. The pinned policy leaves the
+// conformance image to --conformance-image.
+func ValidateVersionPolicy() error {
+	switch policy := settings.GetString("version-policy"); policy {
+	case "", VersionPolicyMatchServer, VersionPolicyLatestPatch:
+	case VersionPolicyPinned:
+		if settings.GetString("conformance-image") == "" {
+			return fmt.Errorf("--version-policy=%s requires --conformance-image", VersionPolicyPinned)
+		}
+	default:
+		return fmt.Errorf("expected --version-policy to be one of %s, %s or %s, got %q", VersionPolicyMatchServer, VersionPolicyLatestPatch, VersionPolicyPinned, policy)
+	}
+	return nil
+}
+
+// SetDefaultImages sets the images that weren't given to their defaults. The
+// default conformance image matches the version of the cluster, it is left
+// unset when the version is unknown.
+func SetDefaultImages(serverVersion string) {
+	if settings.Get("conformance-image") == "" && serverVersion != "" {
+		settings.Set("conformance-image", fmt.Sprintf("%s:%s", ConformanceRepository, serverVersion))
+	}
+	if settings.Get("busybox-image") == "" {
+		settings.Set("busybox-image", DefaultBusyboxImage)
+	}
 }
 
+// SetDefaultNamespace sets the namespace that wasn't given to its default,
+// suffixed with the ID of --run-id.
 func SetDefaultNamespace() {
-	if viper.Get("namespace") == "" {
-		viper.Set("namespace", DefaultNamespace)
+	if settings.Get("namespace") == "" {
+		settings.Set("namespace", WithRunID(DefaultNamespace))
 	}
 }
 
@@ -65,37 +152,218 @@ func SetDefaultNamespace() {
 // and creates the output directory if it doesn't exist
 
 func ValidateArgs() error {
-	if viper.Get("namespace") == "" {
-		viper.Set("namespace", DefaultNamespace)
+	SetDefaultNamespace()
+	if settings.Get("focus") == "" {
+		if settings.GetString("storage-testdriver") != "" {
+			settings.Set("focus", StorageTestDriverFocus)
+		} else if settings.GetString("ip-family") == IPFamilyDual {
+			settings.Set("focus", DualStackFocus)
+		} else {
+			settings.Set("focus", "\\[Conformance\\]")
+		}
+	}
+
+	if err := validateExpression("focus", settings.GetString("focus")); err != nil {
+		return err
+	}
+	if err := validateExpression("skip", settings.GetString("skip")); err != nil {
+		return err
 	}
-	if viper.Get("focus") == "" {
-		viper.Set("focus", "\\[Conformance\\]")
+	// a long --focus is run in chunks, the tests can't be skipped in chunks
+	if skip := settings.GetString("skip"); len(skip) > MaxFocusLength {
+		return fmt.Errorf("expected --skip to be at most %d bytes, got %d bytes", MaxFocusLength, len(skip))
+	}
+	if settings.Get("skip") != "" {
+		log.Printf("Skipping tests : '%s'", settings.Get("skip"))
 	}
 
-	if viper.Get("skip") != "" {
-		log.Printf("Skipping tests : '%s'", viper.Get("skip"))
+	switch parallel := settings.GetString("parallel"); parallel {
+	case "", ParallelAuto:
+	default:
+		if n, err := strconv.Atoi(parallel); err != nil || n < 1 {
+			return fmt.Errorf("expected --parallel to be a positive number or %q, got %q", ParallelAuto, parallel)
+		}
 	}
 
-	if extraArgs := viper.GetStringSlice("extra-args"); len(extraArgs) != 0 {
-		for _, kv := range extraArgs {
-			keyValuePair := strings.SplitN(kv, "=", 2)
-			if len(keyValuePair) != 2 {
-				return fmt.Errorf("expected [%s] in [%s] to be of --key=value format", keyValuePair, extraArgs)
-			}
-			key := keyValuePair[0]
-			if !strings.HasPrefix(key, "--") && strings.Count(key, "--") != 1 {
-				return fmt.Errorf("expected key [%s] in [%s] to start with prefix --", key, extraArgs)
-			}
+	if shards := settings.GetInt("shards"); shards > 1 {
+		if shards > MaxShards {
+			return fmt.Errorf("expected at most %d shards, got %d", MaxShards, shards)
 		}
+		// all shards have to use the same seed, otherwise the run can't be reproduced
+		if settings.GetInt64("seed") == 0 {
+			settings.Set("seed", time.Now().Unix())
+		}
+		log.Printf("Splitting tests across %d shards", shards)
+	}
+
+	switch nodeOS := settings.GetString("node-os"); nodeOS {
+	case "", NodeOSLinux, NodeOSWindows:
+	default:
+		return fmt.Errorf("expected --node-os to be %s or %s, got %q", NodeOSLinux, NodeOSWindows, nodeOS)
+	}
+
+	switch family := settings.GetString("ip-family"); family {
+	case "", IPFamilyIPv4, IPFamilyIPv6, IPFamilyDual:
+	default:
+		return fmt.Errorf("expected --ip-family to be %s, %s or %s, got %q", IPFamilyIPv4, IPFamilyIPv6, IPFamilyDual, family)
+	}
+
+	switch transfer := settings.GetString("artifact-transfer"); transfer {
+	case "", ArtifactTransferSidecar:
+	case ArtifactTransferExec:
+		if len(settings.GetStringSlice("node")) != 0 || settings.GetString("plugin") != "" {
+			return fmt.Errorf("--artifact-transfer=%s is only supported with the conformance image, not with --node or --plugin", ArtifactTransferExec)
+		}
+	default:
+		return fmt.Errorf("expected --artifact-transfer to be %s or %s, got %q", ArtifactTransferSidecar, ArtifactTransferExec, transfer)
 	}
 
-	log.Printf("Using namespace : '%s'", viper.Get("namespace"))
-	log.Printf("Using conformance image : '%s'", viper.Get("conformance-image"))
-	log.Printf("Using busybox image : '%s'", viper.Get("busybox-image"))
-	log.Printf("Test framework will start '%d' threads and use verbosity '%d'",
-		viper.Get("parallel"), viper.Get("verbosity"))
+	switch workload := settings.GetString("workload"); workload {
+	case "", WorkloadPod, WorkloadJob:
+	default:
+		return fmt.Errorf("expected --workload to be %s or %s, got %q", WorkloadPod, WorkloadJob, workload)
+	}
+
+	switch policy := settings.GetString("reschedule-policy"); policy {
+	case "", RescheduleNone, RescheduleAbort:
+	case RescheduleRecreate, RescheduleResume:
+		if settings.GetString("workload") == WorkloadJob {
+			return fmt.Errorf("--reschedule-policy=%s can't be combined with --workload=%s, whose jobs replace lost pods themselves", policy, WorkloadJob)
+		}
+	default:
+		return fmt.Errorf("expected --reschedule-policy to be one of %s, %s, %s or %s, got %q", RescheduleNone, RescheduleAbort, RescheduleRecreate, RescheduleResume, policy)
+	}
+	if settings.GetInt("reschedule-limit") < 0 {
+		return fmt.Errorf("expected --reschedule-limit to be at least 0, got %d", settings.GetInt("reschedule-limit"))
+	}
+	if settings.GetInt("max-failures") < 0 {
+		return fmt.Errorf("expected --max-failures to be at least 0, got %d", settings.GetInt("max-failures"))
+	}
+	if settings.GetInt("echo-sample") < 0 {
+		return fmt.Errorf("expected --echo-sample to be at least 0, got %d", settings.GetInt("echo-sample"))
+	}
+	if settings.GetInt("slowest") < 0 {
+		return fmt.Errorf("expected --slowest to be at least 0, got %d", settings.GetInt("slowest"))
+	}
 
-	outputDir := viper.GetString("output-dir")
+	if baseline := settings.GetString("baseline"); baseline != "" {
+		if _, err := os.Stat(filepath.Join(baseline, "junit_01.xml")); err != nil {
+			return fmt.Errorf("expected --baseline to be the output directory of a run: %w", err)
+		}
+	}
+	if settings.GetFloat64("baseline-threshold") < 0 {
+		return fmt.Errorf("expected --baseline-threshold to be at least 0, got %g", settings.GetFloat64("baseline-threshold"))
+	}
+
+	switch profile := settings.GetString("security-profile"); profile {
+	case "", SecurityRestricted, SecurityUnrestricted:
+	default:
+		return fmt.Errorf("expected --security-profile to be %s or %s, got %q", SecurityRestricted, SecurityUnrestricted, profile)
+	}
+
+	switch compress := settings.GetString("compress"); compress {
+	case "", CompressNone, CompressGzip, CompressBundle:
+	default:
+		return fmt.Errorf("expected --compress to be %s, %s or %s, got %q", CompressNone, CompressGzip, CompressBundle, compress)
+	}
+
+	for _, format := range settings.GetStringSlice("results-format") {
+		if format != ResultsFormatCSV && format != ResultsFormatHTML {
+			return fmt.Errorf("expected --results-format to be %s or %s, got %q", ResultsFormatCSV, ResultsFormatHTML, format)
+		}
+	}
+	for _, path := range settings.GetStringSlice("post-process") {
+		if _, err := exec.LookPath(path); err != nil {
+			return fmt.Errorf("expected --post-process to be an executable, got %q: %w", path, err)
+		}
+	}
+
+	if image := settings.GetString("conformance-image"); image != "" {
+		if _, err := registry.ParseReference(image); err != nil {
+			return fmt.Errorf("invalid --conformance-image: %w", err)
+		}
+	}
+
+	if repoList := settings.GetString("test-repo-list"); repoList != "" {
+		if _, err := ReadTestRepoList(repoList); err != nil {
+			return err
+		}
+		log.Printf("Using test repo list : '%s'", repoList)
+	}
+
+	if testDriver := settings.GetString("storage-testdriver"); testDriver != "" {
+		driver, err := ReadStorageTestDriver(testDriver)
+		if err != nil {
+			return err
+		}
+		log.Printf("Using storage test driver : '%s' of %s", driver, testDriver)
+	}
+
+	if upstream := settings.GetString("upstream-flakes"); upstream != "" {
+		if dashboard, tab, ok := strings.Cut(upstream, "/"); !ok || dashboard == "" || tab == "" {
+			return fmt.Errorf("expected --upstream-flakes to be of dashboard/tab format, got %q", upstream)
+		}
+	}
+
+	if settings.GetBool("gate-smoke") {
+		if err := validateExpression("smoke-focus", settings.GetString("smoke-focus")); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range durationFlags {
+		if _, err := GetDuration(key); err != nil {
+			return err
+		}
+	}
+	after, _ := GetDuration("hang-debug-after")
+	if report, _ := GetDuration("progress-report"); after > 0 && report <= 0 {
+		return fmt.Errorf("--hang-debug-after requires --progress-report")
+	}
+
+	if settings.GetInt("max-reconnects") < 0 {
+		return fmt.Errorf("expected --max-reconnects to be at least 0, got %d", settings.GetInt("max-reconnects"))
+	}
+
+	for _, pattern := range settings.GetStringSlice("artifacts") {
+		if err := ValidateGlob(pattern); err != nil {
+			return fmt.Errorf("--artifacts: %w", err)
+		}
+	}
+
+	if _, err := GetByteSize("max-spec-output"); err != nil {
+		return err
+	}
+	if _, err := GetByteSize("max-log-size"); err != nil {
+		return err
+	}
+	if _, err := GetRate("transfer-rate-limit"); err != nil {
+		return err
+	}
+	if _, err := results.ParseJUnitProperties(settings.GetStringSlice("junit-property")); err != nil {
+		return err
+	}
+
+	if err := validateExtraArgs(settings.GetStringSlice("extra-args")); err != nil {
+		return err
+	}
+	if err := checkManagedArgs(settings.GetStringSlice("extra-args"), settings.GetBool("force-extra-args")); err != nil {
+		return err
+	}
+	if err := validateExtraArgs(settings.GetStringSlice("extra-ginkgo-args")); err != nil {
+		return err
+	}
+	if err := checkArgChannels(settings.GetStringSlice("extra-args"), settings.GetStringSlice("extra-ginkgo-args"), ImageVersion(settings.GetString("conformance-image")), settings.GetBool("force-extra-args")); err != nil {
+		return err
+	}
+
+	log.Printf("Using namespace : '%s'", settings.Get("namespace"))
+	log.Printf("Using conformance image : '%s'", settings.Get("conformance-image"))
+	log.Printf("Using busybox image : '%s'", settings.Get("busybox-image"))
+	log.Printf("Test framework will start '%s' threads and use verbosity '%d'",
+		settings.GetString("parallel"), settings.Get("verbosity"))
+
+	outputDir := settings.GetString("output-dir")
 	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
 		if err = os.MkdirAll(outputDir, 0755); err != nil {
 			return fmt.Errorf("error creating output directory [%s] : %v", outputDir, err)
@@ -104,13 +372,122 @@ func ValidateArgs() error {
 	return nil
 }
 
+// FailureBudget returns the number of failed specs the run is aborted at,
+// 1 with --fail-fast, or 0 when it runs to the end.
+func FailureBudget() int64 {
+	if settings.GetBool("fail-fast") {
+		return 1
+	}
+	return settings.GetInt64("max-failures")
+}
+
+// ArtifactContainer returns the name of the container the artifacts of the
+// conformance pods are read from, the conformance container itself with
+// --artifact-transfer=exec.
+func ArtifactContainer() string {
+	if settings.GetString("artifact-transfer") == ArtifactTransferExec {
+		return ConformanceContainer
+	}
+	return OutputContainer
+}
+
+// PodNames returns the names of the conformance pods, one for each shard.
+func PodNames() []string {
+	shards := settings.GetInt("shards")
+	if shards <= 1 {
+		return []string{PodName}
+	}
+	names := make([]string, shards)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s-%d", PodName, i)
+	}
+	return names
+}
+
+// durationFlags are the duration flags of a run, which accept the days of
+// ParseDuration from the command line, the environment and the config file
+var durationFlags = []string{
+	"request-timeout", "startup-timeout", "timeout", "job-active-deadline", "node-lost-timeout",
+	"progress-report", "hang-debug-after", "usage-interval", "progress-interval",
+	"impact-guard-interval", "expected-duration", "upload-backoff", "echo-interval",
+}
+
+// managedArgs are the arguments of the e2e test binary that hydrophone or the
+// conformance image already set, along with how to set them instead. Passing
+// them through --extra-args breaks the collection of the results or is
+// overridden.
+var managedArgs = map[string]string{
+	"report-dir":            "the results directory is collected by hydrophone",
+	"kubeconfig":            "the conformance pod uses its in-cluster configuration",
+	"ginkgo.focus":          "use --focus",
+	"ginkgo.skip":           "use --skip",
+	"ginkgo.seed":           "use --seed",
+	"ginkgo.dry-run":        "use --ginkgo-dry-run",
+	"ginkgo.dryRun":         "use --ginkgo-dry-run",
+	"ginkgo.parallel.total": "use --parallel",
+	"ginkgo.procs":          "use --parallel",
+	"nodes":                 "use --parallel",
+	"provider":              "use --provider",
+	"gce-project":           "use --gce-project",
+	"gce-zone":              "use --gce-zone",
+	"gce-region":            "use --gce-region",
+	"cloud-config-file":     "use --cloud-config-file, the file is mounted into the conformance pod",
+	"storage.testdriver":    "use --storage-testdriver, the file is mounted into the conformance pod",
+}
+
+// checkManagedArgs rejects the extra args colliding with the settings managed
+// by hydrophone. With force they are passed on with a warning.
+func checkManagedArgs(extraArgs []string, force bool) error {
+	for _, arg := range extraArgs {
+		key, _, _ := strings.Cut(arg, "=")
+		hint, ok := managedArgs[strings.TrimLeft(key, "-")]
+		if !ok {
+			continue
+		}
+		if !force {
+			return fmt.Errorf("extra arg %s collides with a setting managed by hydrophone, %s. use --force-extra-args to pass it anyway", key, hint)
+		}
+		log.Printf("WARNING: --force-extra-args passes %s overriding a setting managed by hydrophone (%s), the run may fail or its results may be incomplete", arg, hint)
+	}
+	return nil
+}
+
+// validateExpression compiles a focus or skip expression the way ginkgo does,
+// with the syntax of the regexp package, and points at the syntax error, so
+// that the run fails before the pods are created.
+func validateExpression(flag, expr string) error {
+	_, err := regexp.Compile(expr)
+	if err == nil {
+		return nil
+	}
+	var syntaxErr *syntax.Error
+	if errors.As(err, &syntaxErr) {
+		if i := strings.Index(expr, syntaxErr.Expr); i >= 0 {
+			return fmt.Errorf("invalid --%s expression, %s:\n  %s\n  %s^", flag, syntaxErr.Code, expr, strings.Repeat(" ", utf8.RuneCountInString(expr[:i])))
+		}
+	}
+	return fmt.Errorf("invalid --%s expression: %w", flag, err)
+}
+
+// versionPattern matches the upstream part of a server version: the release
+// and an upstream pre-release such as rc.1. Distributions append their own
+// suffix, e.g. v1.28.6-eks-1234, v1.28.6+rke2r1 or v1.28.6-gke.100.
+var versionPattern = regexp.MustCompile(`^v?(\d+\.\d+\.\d+)(?:-((?:alpha|beta|rc)\.\d+))?(?:[-+.].*)?$`)
+
+// trimVersion maps the version of the cluster to the tag of the upstream
+// conformance image, dropping the suffixes added by distributions and builds.
 func trimVersion(version string) (string, error) {
-	version = strings.TrimPrefix(version, "v")
+	match := versionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return "", fmt.Errorf("error parsing server version %q: expected vMAJOR.MINOR.PATCH", version)
+	}
 
-	parsedVersion, err := semver.Parse(version)
+	parsedVersion, err := semver.Parse(match[1])
 	if err != nil {
-		return "", fmt.Errorf("error parsing conformance image tag: %v", err)
+		return "", fmt.Errorf("error parsing server version %q: %v", version, err)
 	}
-
-	return "v" + parsedVersion.FinalizeVersion(), nil
+	if match[2] != "" {
+		return "v" + parsedVersion.String() + "-" + match[2], nil
+	}
+	return "v" + parsedVersion.String(), nil
 }
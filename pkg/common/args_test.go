@@ -17,16 +17,20 @@ limitations under the License.
 package common
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
-	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
 )
 
 func TestValidateArgs(t *testing.T) {
 	// Create a temporary output directory
 	tempDir := t.TempDir()
-	viper.Set("output-dir", tempDir)
+	settings.Set("output-dir", tempDir)
 
 	// Set up the test cases
 	testCases := []struct {
@@ -63,16 +67,16 @@ func TestValidateArgs(t *testing.T) {
 			extraArgs:     []string{"invalid-arg"},
 			expectedArgs:  []string{},
 			wantErr:       true,
-			expectedErr:   "expected [[invalid-arg]] in [[invalid-arg]] to be of --key=value format",
+			expectedErr:   "expected extra arg [invalid-arg] in [\"invalid-arg\"] to be a flag starting with -- or the value of the flag before it",
 		},
 		{
-			name:          "Extra args with missing values",
+			name:          "Extra args with boolean and separate values",
 			focus:         "",
 			expectedFocus: "\\[Conformance\\]",
-			extraArgs:     []string{"--key1=value1", "--key2"},
-			expectedArgs:  []string{},
-			wantErr:       true,
-			expectedErr:   "expected [[--key2]] in [[--key1=value1 --key2]] to be of --key=value format",
+			extraArgs:     []string{"--key1=value1", "--key2", "--key3", "value3", "--key1=a=b,c"},
+			expectedArgs:  []string{"--key1=value1", "--key2", "--key3", "value3", "--key1=a=b,c"},
+			wantErr:       false,
+			expectedErr:   "",
 		},
 		{
 			name:          "Extra args with invalid key format",
@@ -81,7 +85,16 @@ func TestValidateArgs(t *testing.T) {
 			extraArgs:     []string{"key1=value1", "--key2=value2"},
 			expectedArgs:  []string{},
 			wantErr:       true,
-			expectedErr:   "expected key [key1] in [[key1=value1 --key2=value2]] to start with prefix --",
+			expectedErr:   "expected extra arg [key1=value1] in [\"key1=value1\" \"--key2=value2\"] to be a flag starting with -- or the value of the flag before it",
+		},
+		{
+			name:          "Invalid focus",
+			focus:         "\\[sig-network\\]|[Conformance",
+			expectedFocus: "\\[sig-network\\]|[Conformance",
+			extraArgs:     []string{},
+			expectedArgs:  []string{},
+			wantErr:       true,
+			expectedErr:   "invalid --focus expression, missing closing ]:\n  \\[sig-network\\]|[Conformance\n                  ^",
 		},
 	}
 
@@ -89,8 +102,8 @@ func TestValidateArgs(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// Set up the test environment
-			viper.Set("focus", tc.focus)
-			viper.Set("extra-args", tc.extraArgs)
+			settings.Set("focus", tc.focus)
+			settings.Set("extra-args", tc.extraArgs)
 
 			// Call the function under test
 			err := ValidateArgs()
@@ -98,8 +111,168 @@ func TestValidateArgs(t *testing.T) {
 				assert.EqualError(t, err, tc.expectedErr)
 			} else {
 				assert.Nil(t, err)
-				assert.Equal(t, viper.GetString("focus"), tc.expectedFocus)
-				assert.Equal(t, viper.GetStringSlice("extra-args"), tc.expectedArgs)
+				assert.Equal(t, settings.GetString("focus"), tc.expectedFocus)
+				assert.Equal(t, settings.GetStringSlice("extra-args"), tc.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestValidateArgsIPFamily(t *testing.T) {
+	settings.Set("output-dir", t.TempDir())
+	defer settings.Set("ip-family", "")
+	defer settings.Set("focus", "")
+
+	settings.Set("ip-family", IPFamilyDual)
+	settings.Set("focus", "")
+	require.NoError(t, ValidateArgs())
+	assert.Equal(t, DualStackFocus, settings.GetString("focus"))
+
+	settings.Set("ip-family", IPFamilyIPv6)
+	settings.Set("focus", "")
+	require.NoError(t, ValidateArgs())
+	assert.Equal(t, "\\[Conformance\\]", settings.GetString("focus"))
+
+	settings.Set("ip-family", "ipv5")
+	assert.EqualError(t, ValidateArgs(), `expected --ip-family to be ipv4, ipv6 or dual, got "ipv5"`)
+}
+
+func TestValidateArgsLongExpressions(t *testing.T) {
+	settings.Set("output-dir", t.TempDir())
+	defer settings.Set("focus", "")
+	defer settings.Set("skip", "")
+
+	long := strings.Repeat("Pods|", MaxFocusLength/5) + "Services"
+	settings.Set("focus", long)
+	require.NoError(t, ValidateArgs())
+
+	settings.Set("focus", "Pods")
+	settings.Set("skip", long)
+	assert.EqualError(t, ValidateArgs(), fmt.Sprintf("expected --skip to be at most %d bytes, got %d bytes", MaxFocusLength, len(long)))
+}
+
+func TestValidateArgsArtifactTransfer(t *testing.T) {
+	settings.Set("output-dir", t.TempDir())
+	defer settings.Set("artifact-transfer", "")
+	defer settings.Set("plugin", "")
+
+	settings.Set("artifact-transfer", ArtifactTransferExec)
+	require.NoError(t, ValidateArgs())
+	assert.Equal(t, ConformanceContainer, ArtifactContainer())
+
+	settings.Set("plugin", "plugin.yaml")
+	assert.EqualError(t, ValidateArgs(), "--artifact-transfer=exec is only supported with the conformance image, not with --node or --plugin")
+
+	settings.Set("plugin", "")
+	settings.Set("artifact-transfer", "kubectl-cp")
+	assert.EqualError(t, ValidateArgs(), `expected --artifact-transfer to be sidecar or exec, got "kubectl-cp"`)
+	settings.Set("artifact-transfer", ArtifactTransferSidecar)
+	assert.Equal(t, OutputContainer, ArtifactContainer())
+}
+
+func TestValidateArgsPostProcess(t *testing.T) {
+	settings.Set("output-dir", t.TempDir())
+	defer settings.Set("results-format", nil)
+	defer settings.Set("post-process", nil)
+
+	settings.Set("results-format", []string{ResultsFormatCSV, ResultsFormatHTML})
+	settings.Set("post-process", []string{"sh"})
+	require.NoError(t, ValidateArgs())
+
+	settings.Set("results-format", []string{"xml"})
+	assert.EqualError(t, ValidateArgs(), `expected --results-format to be csv or html, got "xml"`)
+
+	settings.Set("results-format", nil)
+	settings.Set("post-process", []string{"./missing-post-process"})
+	assert.ErrorContains(t, ValidateArgs(), `expected --post-process to be an executable, got "./missing-post-process"`)
+}
+
+func TestValidateExpression(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expr        string
+		expectedErr string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "tags",
+			expr: `\[sig-storage\].*\[Slow\]`,
+		},
+		{
+			name:        "missing parenthesis",
+			expr:        `(Pods|Services`,
+			expectedErr: "invalid --skip expression, missing closing ):\n  (Pods|Services\n  ^",
+		},
+		{
+			name:        "invalid repeat",
+			expr:        `Pods|*Slow`,
+			expectedErr: "invalid --skip expression, missing argument to repetition operator:\n  Pods|*Slow\n       ^",
+		},
+		{
+			name:        "unsupported lookahead",
+			expr:        `Pods(?!Slow)`,
+			expectedErr: "invalid --skip expression, invalid or unsupported Perl syntax:\n  Pods(?!Slow)\n      ^",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateExpression("skip", tc.expr)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestCheckManagedArgs(t *testing.T) {
+	testCases := []struct {
+		name      string
+		extraArgs []string
+		force     bool
+		expectErr bool
+	}{
+		{
+			name:      "unmanaged args",
+			extraArgs: []string{"--allowed-not-ready-nodes=1", "--clean-start=true"},
+		},
+		{
+			name:      "report dir",
+			extraArgs: []string{"--report-dir=/tmp/other"},
+			expectErr: true,
+		},
+		{
+			name:      "ginkgo focus",
+			extraArgs: []string{"--allowed-not-ready-nodes=1", "--ginkgo.focus=Pods"},
+			expectErr: true,
+		},
+		{
+			name:      "single dash",
+			extraArgs: []string{"-kubeconfig=/root/.kube/config"},
+			expectErr: true,
+		},
+		{
+			name:      "provider",
+			extraArgs: []string{"--provider=gce"},
+			expectErr: true,
+		},
+		{
+			name:      "forced",
+			extraArgs: []string{"--nodes=4"},
+			force:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkManagedArgs(tc.extraArgs, tc.force)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
 			}
 		})
 	}
@@ -145,6 +318,36 @@ func TestTrimVersion(t *testing.T) {
 			version:         "1.28.6",
 			expectedVersion: "v1.28.6",
 		},
+		{
+			name:            "eks version",
+			version:         "v1.28.6-eks-1234",
+			expectedVersion: "v1.28.6",
+		},
+		{
+			name:            "rke2 version",
+			version:         "v1.28.6+rke2r1",
+			expectedVersion: "v1.28.6",
+		},
+		{
+			name:            "gke version",
+			version:         "v1.28.6-gke.100",
+			expectedVersion: "v1.28.6",
+		},
+		{
+			name:            "distro suffix that isn't valid semver",
+			version:         "v1.27.3-gke.0100",
+			expectedVersion: "v1.27.3",
+		},
+		{
+			name:            "release candidate",
+			version:         "v1.30.0-rc.1",
+			expectedVersion: "v1.30.0-rc.1",
+		},
+		{
+			name:            "development build of an alpha",
+			version:         "v1.30.0-alpha.3.123+0fb426",
+			expectedVersion: "v1.30.0-alpha.3",
+		},
 	}
 
 	// Run the test cases
@@ -160,3 +363,51 @@ func TestTrimVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestSetServerVersionPolicy(t *testing.T) {
+	defer func(list func() ([]string, error)) { conformanceTags = list }(conformanceTags)
+	conformanceTags = func() ([]string, error) {
+		return []string{"v1.29.0", "v1.29.4", "v1.30.0"}, nil
+	}
+	defer settings.Set("conformance-image", "")
+	defer settings.Set("version-policy", "")
+	defer settings.Set("busybox-image", "")
+	defer settings.Set("server-version", "")
+	defer settings.Set("server-git-version", "")
+
+	tests := []struct {
+		name    string
+		policy  string
+		image   string
+		version string
+		want    string
+	}{
+		{name: "match server", policy: VersionPolicyMatchServer, version: "v1.29.2-eks-1234", want: ConformanceRepository + ":v1.29.2"},
+		{name: "latest patch", policy: VersionPolicyLatestPatch, version: "v1.29.2-eks-1234", want: ConformanceRepository + ":v1.29.4"},
+		{name: "latest patch of an unpublished minor", policy: VersionPolicyLatestPatch, version: "v1.31.1", want: ConformanceRepository + ":v1.31.1"},
+		{name: "given image", policy: VersionPolicyLatestPatch, image: "mirror.example.com/conformance:v1.29.2", version: "v1.29.2", want: "mirror.example.com/conformance:v1.29.2"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			settings.Set("version-policy", tc.policy)
+			settings.Set("conformance-image", tc.image)
+			_, err := SetServerVersion(tc.version)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, settings.GetString("conformance-image"))
+		})
+	}
+}
+
+func TestValidateVersionPolicy(t *testing.T) {
+	defer settings.Set("conformance-image", "")
+	defer settings.Set("version-policy", "")
+
+	settings.Set("version-policy", VersionPolicyLatestPatch)
+	assert.NoError(t, ValidateVersionPolicy())
+	settings.Set("version-policy", VersionPolicyPinned)
+	assert.ErrorContains(t, ValidateVersionPolicy(), "requires --conformance-image")
+	settings.Set("conformance-image", ConformanceRepository+":v1.29.0")
+	assert.NoError(t, ValidateVersionPolicy())
+	settings.Set("version-policy", "newest")
+	assert.ErrorContains(t, ValidateVersionPolicy(), "expected --version-policy")
+}
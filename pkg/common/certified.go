@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// CertifiedFocus is the focus of a run for a CNCF Certified Kubernetes
+// submission
+const CertifiedFocus = `\[Conformance\]`
+
+// certifiedSelection are the flags selecting other tests than the
+// conformance tests
+var certifiedSelection = []string{"focus-file", "sig", "behavior", "suite-file", "node", "plugin", "storage-testdriver"}
+
+// certifiedExecution are the flags changing how the conformance tests run
+var certifiedExecution = []string{"pod-patch", "extra-args", "extra-arg", "extra-ginkgo-args", "force-extra-args", "ginkgo-dry-run", "gate-smoke"}
+
+// ValidateCertified checks that the run has the configuration a CNCF
+// Certified Kubernetes submission requires: all the conformance tests run,
+// serially, with the conformance image of a Kubernetes release and the
+// e2e test binary left as it is, up to the end of the run. The error lists
+// every setting that doesn't comply.
+func ValidateCertified() error {
+	var problems []string
+	if focus := settings.GetString("focus"); focus != "" && focus != CertifiedFocus {
+		problems = append(problems, fmt.Sprintf("--focus %q selects other tests than %s", focus, CertifiedFocus))
+	}
+	for _, name := range certifiedSelection {
+		if isSet(name) {
+			problems = append(problems, fmt.Sprintf("--%s selects other tests than the conformance tests", name))
+		}
+	}
+	if settings.GetString("ip-family") == IPFamilyDual {
+		problems = append(problems, fmt.Sprintf("--ip-family=%s adds the dual-stack tests to the conformance tests", IPFamilyDual))
+	}
+	for _, name := range []string{"skip", "skip-file"} {
+		if isSet(name) {
+			problems = append(problems, fmt.Sprintf("--%s skips conformance tests", name))
+		}
+	}
+	if settings.GetString("node-os") == NodeOSWindows {
+		problems = append(problems, fmt.Sprintf("--node-os=%s skips the [LinuxOnly] conformance tests", NodeOSWindows))
+	}
+	if parallel := settings.GetString("parallel"); parallel != "" && parallel != "1" {
+		problems = append(problems, fmt.Sprintf("--parallel %s runs the tests in parallel, they have to run serially", parallel))
+	}
+	if shards := settings.GetInt("shards"); shards > 1 {
+		problems = append(problems, fmt.Sprintf("--shards %d splits the tests across pods", shards))
+	}
+	for _, name := range certifiedExecution {
+		if isSet(name) {
+			problems = append(problems, fmt.Sprintf("--%s changes how the tests run", name))
+		}
+	}
+	if settings.GetBool("fail-fast") || settings.GetInt("max-failures") > 0 {
+		problems = append(problems, "--fail-fast and --max-failures abort the run before all tests ran")
+	}
+	if image := settings.GetString("conformance-image"); image != "" && !isConformanceRelease(image) {
+		problems = append(problems, fmt.Sprintf("--conformance-image %s isn't a conformance image of %s", image, ConformanceRepository))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("--certified refuses the run: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// isSet reports whether the setting has a value other than the zero value of
+// its flag, whether it comes from a flag, the environment or the config file
+func isSet(name string) bool {
+	switch value := settings.Get(name).(type) {
+	case nil:
+		return false
+	case string:
+		return value != ""
+	case bool:
+		return value
+	case []string:
+		return len(value) != 0
+	case []any:
+		return len(value) != 0
+	default:
+		return settings.GetString(name) != ""
+	}
+}
+
+// isConformanceRelease reports whether the image is a conformance image of
+// a Kubernetes release, by tag or by digest
+func isConformanceRelease(image string) bool {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if version := ImageVersion(image); version != "" {
+		image = strings.TrimSuffix(image, ":"+version)
+	}
+	return image == ConformanceRepository
+}
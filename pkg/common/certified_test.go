@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestValidateCertified(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]any
+		err      string
+	}{
+		{
+			name:     "defaults",
+			settings: map[string]any{},
+		},
+		{
+			name: "conformance focus and release image",
+			settings: map[string]any{
+				"focus":             CertifiedFocus,
+				"parallel":          "1",
+				"conformance-image": "registry.k8s.io/conformance:v1.30.2@sha256:0123",
+				"sig":               []string{},
+			},
+		},
+		{
+			name:     "skip",
+			settings: map[string]any{"skip": "Serial"},
+			err:      "--certified refuses the run: --skip skips conformance tests",
+		},
+		{
+			name: "several problems",
+			settings: map[string]any{
+				"focus":             "sig-network",
+				"parallel":          "4",
+				"pod-patch":         "patch.yaml",
+				"extra-args":        []string{"--allowed-not-ready-nodes=1"},
+				"conformance-image": "example.com/conformance:v1.30.2",
+			},
+			err: `--certified refuses the run: --focus "sig-network" selects other tests than \[Conformance\]; ` +
+				"--parallel 4 runs the tests in parallel, they have to run serially; " +
+				"--pod-patch changes how the tests run; --extra-args changes how the tests run; " +
+				"--conformance-image example.com/conformance:v1.30.2 isn't a conformance image of registry.k8s.io/conformance",
+		},
+		{
+			name:     "selection from the config file",
+			settings: map[string]any{"sig": []any{"network"}, "node-os": NodeOSWindows},
+			err:      "--certified refuses the run: --sig selects other tests than the conformance tests; --node-os=windows skips the [LinuxOnly] conformance tests",
+		},
+		{
+			name:     "aborted early",
+			settings: map[string]any{"max-failures": 1},
+			err:      "--certified refuses the run: --fail-fast and --max-failures abort the run before all tests ran",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.settings {
+				settings.Set(key, value)
+				defer settings.Set(key, nil)
+			}
+			err := ValidateCertified()
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestIsConformanceRelease(t *testing.T) {
+	assert.True(t, isConformanceRelease("registry.k8s.io/conformance:v1.30.2"))
+	assert.True(t, isConformanceRelease("registry.k8s.io/conformance@sha256:0123"))
+	assert.False(t, isConformanceRelease("registry.k8s.io/conformance-custom:v1.30.2"))
+	assert.False(t, isConformanceRelease("localhost:5000/conformance:v1.30.2"))
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+	"sigs.k8s.io/hydrophone/pkg/version"
+)
+
+//go:embed compat.yaml
+var compatData []byte
+
+// compatEntry lists the Kubernetes minor versions a hydrophone minor release is tested against
+type compatEntry struct {
+	Hydrophone string `json:"hydrophone"`
+	Kubernetes struct {
+		Min string `json:"min"`
+		Max string `json:"max"`
+	} `json:"kubernetes"`
+}
+
+// CheckCompatibility compares the hydrophone version against the versions of
+// the cluster and of the conformance image using the embedded compatibility
+// matrix. It returns a warning for every untested combination.
+func CheckCompatibility(hydrophoneVersion, serverVersion, conformanceImage string) ([]string, error) {
+	var matrix []compatEntry
+	if err := yaml.Unmarshal(compatData, &matrix); err != nil {
+		return nil, fmt.Errorf("error parsing compatibility matrix: %w", err)
+	}
+
+	hydrophone, err := semver.ParseTolerant(hydrophoneVersion)
+	if err != nil {
+		// development builds don't carry a version
+		return nil, nil
+	}
+	var entry *compatEntry
+	for i := range matrix {
+		v, err := semver.ParseTolerant(matrix[i].Hydrophone)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing compatibility matrix: %w", err)
+		}
+		if v.Major == hydrophone.Major && v.Minor == hydrophone.Minor {
+			entry = &matrix[i]
+			break
+		}
+	}
+	if entry == nil {
+		return []string{fmt.Sprintf("no compatibility information for hydrophone %s", hydrophoneVersion)}, nil
+	}
+
+	var warnings []string
+	checks := []struct {
+		what    string
+		version string
+	}{
+		{"cluster", serverVersion},
+		{"conformance image", ImageVersion(conformanceImage)},
+	}
+	for _, check := range checks {
+		if check.version == "" {
+			continue
+		}
+		tested, err := inMinorRange(check.version, entry.Kubernetes.Min, entry.Kubernetes.Max)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("unable to check compatibility of %s version %s: %v", check.what, check.version, err))
+		} else if !tested {
+			warnings = append(warnings, fmt.Sprintf("hydrophone %s is not tested with %s version %s, tested versions are %s to %s",
+				hydrophoneVersion, check.what, check.version, entry.Kubernetes.Min, entry.Kubernetes.Max))
+		}
+	}
+	return warnings, nil
+}
+
+// ImageVersion returns the tag of the image reference, or an empty string if
+// the reference doesn't have a tag.
+func ImageVersion(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+	return image[i+1:]
+}
+
+// inMinorRange reports whether the minor version of version lies between min and max
+func inMinorRange(version, min, max string) (bool, error) {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false, err
+	}
+	lower, err := semver.ParseTolerant(min)
+	if err != nil {
+		return false, err
+	}
+	upper, err := semver.ParseTolerant(max)
+	if err != nil {
+		return false, err
+	}
+	minor := semver.Version{Major: v.Major, Minor: v.Minor}
+	return minor.GE(lower) && minor.LE(upper), nil
+}
+
+// KubeletSkew compares the version of a kubelet with the version of the API
+// server and describes the skew if it exceeds the version skew policy of
+// Kubernetes, or returns an empty string. Kubelets may be up to three minor
+// versions older than the API server from v1.28 on, two before, and must not
+// be newer.
+func KubeletSkew(serverVersion, kubeletVersion string) (string, error) {
+	server, err := semver.ParseTolerant(serverVersion)
+	if err != nil {
+		return "", fmt.Errorf("error parsing server version %s: %w", serverVersion, err)
+	}
+	trimmed, err := trimVersion(kubeletVersion)
+	if err != nil {
+		return "", fmt.Errorf("error parsing kubelet version %s: %w", kubeletVersion, err)
+	}
+	kubelet, err := semver.ParseTolerant(trimmed)
+	if err != nil {
+		return "", fmt.Errorf("error parsing kubelet version %s: %w", kubeletVersion, err)
+	}
+	if kubelet.Major != server.Major {
+		return fmt.Sprintf("kubelet %s and API server %s differ in their major version", kubeletVersion, serverVersion), nil
+	}
+	if kubelet.Minor > server.Minor {
+		return fmt.Sprintf("kubelet %s is newer than API server %s", kubeletVersion, serverVersion), nil
+	}
+	maxSkew := uint64(3)
+	if server.Minor < 28 {
+		maxSkew = 2
+	}
+	if server.Minor-kubelet.Minor > maxSkew {
+		return fmt.Sprintf("kubelet %s is %d minor versions older than API server %s, at most %d are supported",
+			kubeletVersion, server.Minor-kubelet.Minor, serverVersion, maxSkew), nil
+	}
+	return "", nil
+}
+
+// Policies of --version-mismatch
+const (
+	VersionMismatchFail  = "fail"
+	VersionMismatchWarn  = "warn"
+	VersionMismatchAllow = "allow"
+)
+
+// VersionMismatch compares the minor version of the conformance image with
+// the minor version of the cluster and describes the mismatch, or returns an
+// empty string if they match or the image has no version tag.
+func VersionMismatch(serverVersion, conformanceImage string) (string, error) {
+	tag := ImageVersion(conformanceImage)
+	if tag == "" || serverVersion == "" {
+		return "", nil
+	}
+	image, err := semver.ParseTolerant(tag)
+	if err != nil {
+		// custom builds may be tagged with anything
+		return "", nil
+	}
+	server, err := semver.ParseTolerant(serverVersion)
+	if err != nil {
+		return "", fmt.Errorf("error parsing server version %s: %w", serverVersion, err)
+	}
+	if image.Major == server.Major && image.Minor == server.Minor {
+		return "", nil
+	}
+	return fmt.Sprintf("conformance image %s is for Kubernetes v%d.%d but the cluster runs %s, tests may be skipped or fail because of the version difference",
+		conformanceImage, image.Major, image.Minor, serverVersion), nil
+}
+
+// ValidateVersionMismatch applies the --version-mismatch policy to the
+// versions of the conformance image and of the cluster.
+func ValidateVersionMismatch() error {
+	policy := settings.GetString("version-mismatch")
+	switch policy {
+	case "", VersionMismatchFail, VersionMismatchWarn, VersionMismatchAllow:
+	default:
+		return fmt.Errorf("expected --version-mismatch to be one of %s, %s or %s, got %q", VersionMismatchFail, VersionMismatchWarn, VersionMismatchAllow, policy)
+	}
+
+	mismatch, err := VersionMismatch(settings.GetString("server-version"), settings.GetString("conformance-image"))
+	if err != nil || mismatch == "" || policy == VersionMismatchAllow {
+		return err
+	}
+	if policy == VersionMismatchFail {
+		return fmt.Errorf("%s, use --version-mismatch=%s to run anyway", mismatch, VersionMismatchWarn)
+	}
+	log.Printf("WARNING: %s", mismatch)
+	return nil
+}
+
+// ValidateCompatibility logs the untested version combinations of the run and
+// fails on them when --strict-compat is set.
+func ValidateCompatibility() error {
+	warnings, err := CheckCompatibility(version.Get(), settings.GetString("server-version"), settings.GetString("conformance-image"))
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		log.Printf("WARNING: %s", warning)
+	}
+	if len(warnings) != 0 && settings.GetBool("strict-compat") {
+		return fmt.Errorf("refusing to run an untested combination of versions because of --strict-compat")
+	}
+	return nil
+}
+
+// VersionReport describes the versions of hydrophone, of the cluster and of
+// the conformance image selected for it, and whether they are supported
+// together.
+type VersionReport struct {
+	Hydrophone       string `json:"hydrophone"`
+	Server           string `json:"server,omitempty"`
+	ConformanceImage string `json:"conformanceImage,omitempty"`
+	// Supported is only set when the version of the cluster is known
+	Supported *bool    `json:"supported,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// NewVersionReport checks the combination of versions against the
+// compatibility matrix and the version of the cluster against the version of
+// the conformance image. The server version may be empty when the cluster
+// wasn't contacted.
+func NewVersionReport(hydrophoneVersion, serverVersion, conformanceImage string) (*VersionReport, error) {
+	report := &VersionReport{
+		Hydrophone:       hydrophoneVersion,
+		Server:           serverVersion,
+		ConformanceImage: conformanceImage,
+	}
+	warnings, err := CheckCompatibility(hydrophoneVersion, serverVersion, conformanceImage)
+	if err != nil {
+		return nil, err
+	}
+	mismatch, err := VersionMismatch(serverVersion, conformanceImage)
+	if err != nil {
+		return nil, err
+	}
+	if mismatch != "" {
+		warnings = append(warnings, mismatch)
+	}
+	report.Warnings = warnings
+	if serverVersion != "" {
+		supported := len(warnings) == 0
+		report.Supported = &supported
+	}
+	return report, nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	testCases := []struct {
+		name             string
+		hydrophone       string
+		server           string
+		image            string
+		expectedWarnings int
+	}{
+		{
+			name:       "development build",
+			hydrophone: "dev",
+			server:     "v1.10.0",
+			image:      "registry.k8s.io/conformance:v1.10.0",
+		},
+		{
+			name:       "tested versions",
+			hydrophone: "v0.5.1",
+			server:     "v1.29.2",
+			image:      "registry.k8s.io/conformance:v1.29.2",
+		},
+		{
+			name:             "untested cluster",
+			hydrophone:       "v0.5.1",
+			server:           "v1.31.0",
+			image:            "registry.k8s.io/conformance:v1.29.2",
+			expectedWarnings: 1,
+		},
+		{
+			name:             "untested cluster and image",
+			hydrophone:       "v0.5.1",
+			server:           "v1.20.0",
+			image:            "localhost:5001/conformance:v1.20.0",
+			expectedWarnings: 2,
+		},
+		{
+			name:       "image by digest",
+			hydrophone: "v0.5.1",
+			server:     "v1.29.0",
+			image:      "registry.k8s.io/conformance@sha256:0123",
+		},
+		{
+			name:             "unknown release",
+			hydrophone:       "v9.0.0",
+			server:           "v1.29.0",
+			expectedWarnings: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings, err := CheckCompatibility(tc.hydrophone, tc.server, tc.image)
+			assert.NoError(t, err)
+			assert.Len(t, warnings, tc.expectedWarnings)
+		})
+	}
+}
+
+func TestNewVersionReport(t *testing.T) {
+	report, err := NewVersionReport("v0.5.1", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, &VersionReport{Hydrophone: "v0.5.1"}, report)
+
+	report, err = NewVersionReport("v0.5.1", "v1.29.2", "registry.k8s.io/conformance:v1.29.2")
+	assert.NoError(t, err)
+	if assert.NotNil(t, report.Supported) {
+		assert.True(t, *report.Supported)
+	}
+	assert.Empty(t, report.Warnings)
+
+	report, err = NewVersionReport("v0.5.1", "v1.29.2", "registry.k8s.io/conformance:v1.28.0")
+	assert.NoError(t, err)
+	if assert.NotNil(t, report.Supported) {
+		assert.False(t, *report.Supported)
+	}
+	assert.Len(t, report.Warnings, 1)
+}
+
+func TestImageVersion(t *testing.T) {
+	assert.Equal(t, "v1.29.0", ImageVersion("registry.k8s.io/conformance:v1.29.0"))
+	assert.Equal(t, "v1.29.0", ImageVersion("localhost:5001/conformance:v1.29.0"))
+	assert.Equal(t, "", ImageVersion("localhost:5001/conformance"))
+	assert.Equal(t, "", ImageVersion("registry.k8s.io/conformance@sha256:0123"))
+}
+
+func TestVersionMismatch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		server   string
+		image    string
+		mismatch bool
+	}{
+		{name: "same minor", server: "v1.29.2", image: "registry.k8s.io/conformance:v1.29.0"},
+		{name: "older image", server: "v1.30.1", image: "registry.k8s.io/conformance:v1.29.0", mismatch: true},
+		{name: "newer image", server: "v1.28.0", image: "localhost:5001/conformance:v1.29.0@sha256:0123", mismatch: true},
+		{name: "custom tag", server: "v1.29.2", image: "localhost:5001/conformance:my-build"},
+		{name: "digest only", server: "v1.29.2", image: "registry.k8s.io/conformance@sha256:0123"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mismatch, err := VersionMismatch(tc.server, tc.image)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.mismatch, mismatch != "", mismatch)
+		})
+	}
+}
+
+func TestKubeletSkew(t *testing.T) {
+	testCases := []struct {
+		name    string
+		server  string
+		kubelet string
+		skew    bool
+	}{
+		{name: "same version", server: "v1.29.2", kubelet: "v1.29.2"},
+		{name: "older patch", server: "v1.29.2", kubelet: "v1.29.0-eks-5e0fdde"},
+		{name: "three minors", server: "v1.29.2", kubelet: "v1.26.4+k3s1"},
+		{name: "four minors", server: "v1.29.2", kubelet: "v1.25.4", skew: true},
+		{name: "three minors before v1.28", server: "v1.27.3", kubelet: "v1.24.1", skew: true},
+		{name: "newer kubelet", server: "v1.28.6", kubelet: "v1.29.0-gke.100", skew: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			skew, err := KubeletSkew(tc.server, tc.kubelet)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.skew, skew != "", skew)
+		})
+	}
+}
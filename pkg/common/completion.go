@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/adrg/xdg"
+)
+
+// TestListCacheFile returns the file the list command caches the names of
+// the listed tests in, to complete --focus and --skip.
+func TestListCacheFile() string {
+	return filepath.Join(xdg.CacheHome, "hydrophone", "tests.txt")
+}
+
+// WriteTestListCache caches the names of the tests, one per line.
+func WriteTestListCache(file string, names []string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(file, []byte(strings.Join(names, "\n")+"\n"), 0600)
+}
+
+// ReadTestListCache returns the cached names of the tests, none when nothing
+// was cached yet.
+func ReadTestListCache(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
+
+// CompleteTestSelection returns the completions of a --focus or --skip
+// expression: the tags of the e2e tests, e.g. \[Conformance\], \[Serial\] or
+// \[sig-network\], and the names of the tests, up to the next word, quoted
+// as regular expressions. The last alternative of the expression is
+// completed, so that \[Slow\]|\[Se completes to \[Slow\]|\[Serial\].
+func CompleteTestSelection(names []string, toComplete string) []string {
+	prefix, word := "", toComplete
+	if i := strings.LastIndex(toComplete, "|"); i != -1 {
+		prefix, word = toComplete[:i+1], toComplete[i+1:]
+	}
+
+	candidates := []string{`\[Conformance\]`}
+	for _, behavior := range Behaviors {
+		candidates = append(candidates, `\[`+behavior+`\]`)
+	}
+	for _, sig := range SIGs {
+		candidates = append(candidates, `\[sig-`+sig+`\]`)
+	}
+	for _, name := range names {
+		quoted := regexp.QuoteMeta(name)
+		if !strings.HasPrefix(quoted, word) {
+			continue
+		}
+		// complete one word at a time, the names are too long to list
+		if rest := quoted[len(word):]; len(rest) > 1 {
+			if i := strings.Index(rest[1:], " "); i != -1 {
+				quoted = quoted[:len(word)+1+i]
+			}
+		}
+		candidates = append(candidates, quoted)
+	}
+
+	var completions []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) && !slices.Contains(completions, prefix+candidate) {
+			completions = append(completions, prefix+candidate)
+		}
+	}
+	return completions
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompleteTestSelection(t *testing.T) {
+	names := []string{
+		"[sig-apps] Daemon set [Serial] should rollback without unnecessary restarts [Conformance]",
+		"[sig-apps] Deployment should run the lifecycle of a Deployment [Conformance]",
+		"[sig-network] DNS should provide DNS for services [Conformance]",
+	}
+	tests := []struct {
+		name       string
+		toComplete string
+		want       []string
+	}{
+		{
+			name:       "tags",
+			toComplete: `\[Se`,
+			want:       []string{`\[Serial\]`},
+		},
+		{
+			name:       "sig tag and test names",
+			toComplete: `\[sig-apps`,
+			want:       []string{`\[sig-apps\]`},
+		},
+		{
+			name:       "next word of the test names",
+			toComplete: `\[sig-apps\]`,
+			want:       []string{`\[sig-apps\]`, `\[sig-apps\] Daemon`, `\[sig-apps\] Deployment`},
+		},
+		{
+			name:       "last alternative",
+			toComplete: `\[Slow\]|\[sig-network\] D`,
+			want:       []string{`\[Slow\]|\[sig-network\] DNS`},
+		},
+		{
+			name:       "nothing matches",
+			toComplete: "xyz",
+			want:       nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CompleteTestSelection(names, tt.toComplete))
+		})
+	}
+}
+
+func TestTestListCache(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "hydrophone", "tests.txt")
+
+	names, err := ReadTestListCache(file)
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	require.NoError(t, WriteTestListCache(file, []string{"[sig-apps] a", "[sig-node] b"}))
+	names, err = ReadTestListCache(file)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"[sig-apps] a", "[sig-node] b"}, names)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// EnvPrefix is the prefix of the environment variables setting the flags
+const EnvPrefix = "HYDROPHONE"
+
+// EnvKeyReplacer maps the name of a flag to its environment variable, after EnvPrefix
+var EnvKeyReplacer = strings.NewReplacer("-", "_")
+
+// redactedSettings are the settings whose URL may hold credentials
+var redactedSettings = []string{"proxy-url"}
+
+// WriteConfig writes the effective configuration, the flags merged with the
+// HYDROPHONE_ environment variables, the config file and the defaults, as a
+// config file. Credentials of URLs are redacted.
+func WriteConfig(w io.Writer) error {
+	values := settings.AllSettings()
+	for _, key := range redactedSettings {
+		value, ok := values[key].(string)
+		if !ok || value == "" {
+			continue
+		}
+		if u, err := url.Parse(value); err == nil {
+			values[key] = u.Redacted()
+		}
+	}
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// ApplyProfile merges the settings of the named profile, a key of profiles of
+// the config file, over the other settings of the file. Flags and the
+// HYDROPHONE_ environment variables keep taking precedence.
+func ApplyProfile(name string) error {
+	profiles := settings.GetStringMap("profiles")
+	profile, ok := profiles[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("profile %s not found, the config file has no profiles", name)
+		}
+		return fmt.Errorf("profile %s not found in the config file, profiles: %s", name, strings.Join(names, ", "))
+	}
+	values, ok := profile.(map[string]any)
+	if !ok {
+		return fmt.Errorf("profile %s must map settings to their values", name)
+	}
+	return settings.MergeConfigMap(values)
+}
+
+// EnvName returns the environment variable setting the flag, e.g.
+// HYDROPHONE_OUTPUT_DIR for output-dir.
+func EnvName(flag string) string {
+	return EnvPrefix + "_" + strings.ToUpper(EnvKeyReplacer.Replace(flag))
+}
+
+// SetFlagsFromEnv sets the flags that weren't passed on the command line to
+// the value of their environment variable, for flags that aren't bound to
+// the settings. Lists are separated by spaces, like the lists of the bound
+// settings.
+func SetFlagsFromEnv(flags *pflag.FlagSet) error {
+	var errs []error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed {
+			return
+		}
+		value, ok := os.LookupEnv(EnvName(flag.Name))
+		if !ok {
+			return
+		}
+		var err error
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			err = slice.Replace(strings.Fields(value))
+			flag.Changed = true
+		} else {
+			err = flags.Set(flag.Name, value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid value %q of %s: %w", value, EnvName(flag.Name), err))
+		}
+	})
+	return errors.Join(errs...)
+}
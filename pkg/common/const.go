@@ -16,9 +16,14 @@ limitations under the License.
 
 package common
 
+import "sigs.k8s.io/hydrophone/pkg/results"
+
 const (
-	// busyboxImage is the image used to extract the e2e logs
-	busyboxImage = "registry.k8s.io/e2e-test-images/busybox:1.36.1-1"
+	// DefaultBusyboxImage is the image used to extract the e2e logs
+	DefaultBusyboxImage = "registry.k8s.io/e2e-test-images/busybox:1.36.1-1"
+	// ConformanceRepository is the repository of the conformance images of
+	// the Kubernetes releases
+	ConformanceRepository = "registry.k8s.io/conformance"
 	// DefaultNamespace is the default namespace where the conformance pod is created
 	DefaultNamespace = "conformance"
 	// PodName is the name of the conformance pod
@@ -33,4 +38,130 @@ const (
 	ConformanceContainer = "conformance-container"
 	// OutputContainer is the name of the busybox container
 	OutputContainer = "output-container"
+	// ArtifactTransferSidecar and ArtifactTransferExec are the modes of
+	// --artifact-transfer. With ArtifactTransferExec the pod has no output
+	// container, the artifacts are streamed as a tar from the conformance
+	// container.
+	ArtifactTransferSidecar = "sidecar"
+	ArtifactTransferExec    = "exec"
+	// TestsDoneMarker is the line the conformance container prints once the
+	// tests completed with --artifact-transfer=exec
+	TestsDoneMarker = "hydrophone: tests completed, waiting for the artifacts to be fetched"
+	// ArtifactsFetchedFile is the file of the results directory created once
+	// the artifacts were fetched with --artifact-transfer=exec, letting the
+	// conformance container exit
+	ArtifactsFetchedFile = ".artifacts-fetched"
+	// DefaultHangDebugCommand is the default --hang-debug-command, listing
+	// the processes of the conformance container and having ginkgo print a
+	// progress report of the running spec to the log of the tests
+	DefaultHangDebugCommand = "ps -o pid,ppid,etime,args; pkill -USR1 e2e.test"
+	// DefaultSmokeFocus is the default --smoke-focus, a few quick conformance
+	// tests of the pods, the DNS, the services and the deployments failing on
+	// obviously broken clusters
+	DefaultSmokeFocus = `\[sig-node\] Pods should be submitted and removed|\[sig-network\] DNS should provide DNS for the cluster|\[sig-network\] Services should serve a basic endpoint from pods|\[sig-apps\] Deployment RollingUpdateDeployment should delete old pods and create new ones`
+	// RepoListConfigMapName is the name of the config map holding the test repo list
+	RepoListConfigMapName = "repo-list-config"
+	// StorageTestDriverConfigMapName is the name of the config map holding the test driver manifest of --storage-testdriver
+	StorageTestDriverConfigMapName = "storage-testdriver"
+	// HeartbeatConfigMapName is the name of the config map annotated with the
+	// heartbeat of the run when hydrophone doesn't manage the namespace
+	HeartbeatConfigMapName = "hydrophone-heartbeat"
+	// PullSecretName is the name of the image pull secret created from --docker-config
+	PullSecretName = "conformance-pull-secret"
+	// ProviderSecretName is the name of the secret holding the files of --cloud-config-file and --provider-credentials
+	ProviderSecretName = "conformance-provider"
+	// RepoListPath is the path the test repo list is mounted at in the conformance container
+	RepoListPath = "/tmp/repo-list/repo-list.yaml"
+	// ParallelAuto is the --parallel value selecting the parallelism from the cluster size
+	ParallelAuto = "auto"
+	// ParallelPerNode is the number of parallel test processes started for each schedulable node
+	ParallelPerNode = 2
+	// MaxAutoParallel caps the parallelism selected by --parallel=auto
+	MaxAutoParallel = 16
+	// NodeOSLinux and NodeOSWindows are the operating systems --node-os can target
+	NodeOSLinux   = "linux"
+	NodeOSWindows = "windows"
+	// IPFamilyIPv4, IPFamilyIPv6 and IPFamilyDual are the IP families of the
+	// cluster --ip-family can hint at
+	IPFamilyIPv4 = "ipv4"
+	IPFamilyIPv6 = "ipv6"
+	IPFamilyDual = "dual"
+	// DualStackFocus is the default focus of --ip-family=dual, adding the
+	// dual-stack tests to the conformance tests
+	DualStackFocus = `\[Conformance\]|\[Feature:IPv6DualStack\]`
+	// DryRunNone, DryRunClient and DryRunServer are the modes of --dry-run.
+	// With DryRunClient the resources of the run are rendered without
+	// connecting to the cluster, with DryRunServer they are submitted to the
+	// API server with the server-side dry run option.
+	DryRunNone   = "none"
+	DryRunClient = "client"
+	DryRunServer = "server"
+	// ManifestsFile is the file of the output directory the resources rendered by --dry-run are written to
+	ManifestsFile = "manifests.yaml"
+	// WorkloadPod and WorkloadJob are the values of --workload. With
+	// WorkloadJob the conformance pods are created by jobs, which replace
+	// them when they are lost.
+	WorkloadPod = "pod"
+	WorkloadJob = "job"
+	// SecurityRestricted and SecurityUnrestricted are the values of
+	// --security-profile. With SecurityUnrestricted the conformance pods are
+	// created without security contexts.
+	SecurityRestricted   = "restricted"
+	SecurityUnrestricted = "unrestricted"
+	// CompressNone, CompressGzip and CompressBundle are the values of
+	// --compress. With CompressGzip e2e.log is gzipped as it is downloaded,
+	// with CompressBundle the artifacts of the run are bundled into a tarball.
+	CompressNone   = "none"
+	CompressGzip   = "gzip"
+	CompressBundle = "bundle"
+	// ResultsFormatCSV and ResultsFormatHTML are the values of
+	// --results-format writing the results of the tests as CSV and the
+	// summary of the run as HTML next to the junit report
+	ResultsFormatCSV  = results.FormatCSV
+	ResultsFormatHTML = results.FormatHTML
+	// OnInterruptCleanup and OnInterruptKeep are the values of --on-interrupt,
+	// whether the resources of an interrupted run are deleted or kept
+	OnInterruptCleanup = "cleanup"
+	OnInterruptKeep    = "keep"
+	// RescheduleNone, RescheduleAbort, RescheduleRecreate and
+	// RescheduleResume are the values of --reschedule-policy, what happens
+	// when a conformance pod is lost before its tests completed
+	RescheduleNone     = "none"
+	RescheduleAbort    = "abort"
+	RescheduleRecreate = "recreate"
+	RescheduleResume   = "resume"
+	// JobNameLabel is set by the job controller on the pods of a job
+	JobNameLabel = "job-name"
+	// E2ERunLabel is set by the e2e framework on the namespaces it creates
+	E2ERunLabel = "e2e-run"
+	// NodeTestImage is the default image of the node conformance tests of --node
+	NodeTestImage = "registry.k8s.io/node-test:0.2"
+	// VersionPolicyMatchServer, VersionPolicyLatestPatch and
+	// VersionPolicyPinned are the policies of --version-policy deriving the
+	// tag of the default conformance image from the server version
+	VersionPolicyMatchServer = "match-server"
+	VersionPolicyLatestPatch = "latest-patch"
+	VersionPolicyPinned      = "pinned"
 )
+
+// SIGs lists the SIGs owning e2e tests, in descending order of their rough
+// share of the conformance suite. Tests are split across shards by SIG.
+var SIGs = []string{
+	"api-machinery",
+	"node",
+	"apps",
+	"network",
+	"storage",
+	"cli",
+	"auth",
+	"scheduling",
+	"instrumentation",
+	"architecture",
+	"autoscaling",
+	"cluster-lifecycle",
+	"cloud-provider",
+	"windows",
+}
+
+// MaxShards is the maximum number of shards tests can be split across
+var MaxShards = len(SIGs)
@@ -0,0 +1,255 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+//go:embed ginkgo_flags.yaml
+var ginkgoFlagsData []byte
+
+// managedGinkgoArgs are the flags of the ginkgo CLI that hydrophone already
+// sets, along with how to set them instead. Passing them through
+// --extra-ginkgo-args widens the selection of the tests or is overridden.
+var managedGinkgoArgs = map[string]string{
+	"focus":      "use --focus",
+	"focus-file": "use --focus-file",
+	"skip":       "use --skip",
+	"skip-file":  "use --skip-file",
+	"procs":      "use --parallel",
+	"p":          "use --parallel",
+	"nodes":      "use --parallel",
+	"seed":       "use --seed",
+	"dry-run":    "use --ginkgo-dry-run",
+}
+
+// e2eGinkgoFlags are the flags of the ginkgo CLI that the e2e test binary
+// defines as well, e.g. -v, the verbosity of its logs
+var e2eGinkgoFlags = map[string]bool{"v": true}
+
+// SplitArgs splits a command line into its arguments like a POSIX shell:
+// arguments are separated by whitespace, single quotes keep their content as
+// it is, double quotes keep whitespace and a backslash escapes the next
+// character, within double quotes only ", \, $ and `.
+func SplitArgs(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune("\"\\$`", r) {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("unterminated escape at the end of [%s]", line)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in [%s]", quote, line)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// MergeExtraArgs merges the arguments of --extra-args and --extra-arg into
+// extra-args, the arguments passed to the e2e test binary. Each element of
+// --extra-args, or its value when set with a single string by the config
+// file or the environment, is split like a shell command line, e.g.
+// --extra-args '--clean-start --dns-domain="a b"'. The values of the
+// repeatable --extra-arg are single arguments, taken as they are, e.g.
+// --extra-arg=--non-blocking-taints=a,b.
+// --extra-ginkgo-args is split the same way.
+func MergeExtraArgs() error {
+	args, err := splitArgsSetting("extra-args")
+	if err != nil {
+		return err
+	}
+	ginkgoArgs, err := splitArgsSetting("extra-ginkgo-args")
+	if err != nil {
+		return err
+	}
+	settings.Set("extra-ginkgo-args", ginkgoArgs)
+	if arg, ok := settings.Get("extra-arg").(string); ok {
+		args = append(args, arg)
+	} else {
+		args = append(args, settings.GetStringSlice("extra-arg")...)
+	}
+	settings.Set("extra-args", args)
+	settings.Set("extra-arg", []string{})
+	return nil
+}
+
+// splitArgsSetting splits the elements of a list of command lines, or a
+// single command line set by the config file or the environment.
+func splitArgsSetting(key string) ([]string, error) {
+	var lines []string
+	if line, ok := settings.Get(key).(string); ok {
+		lines = []string{line}
+	} else {
+		lines = settings.GetStringSlice(key)
+	}
+	var args []string
+	for _, line := range lines {
+		split, err := SplitArgs(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s: %w", key, err)
+		}
+		args = append(args, split...)
+	}
+	return args, nil
+}
+
+// validateExtraArgs checks that the extra args are flags, --key=value, a bare
+// boolean --key or --key followed by its value, as the flag package of the e2e
+// test binary parses them. Flags can be repeated.
+func validateExtraArgs(args []string) error {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-") && strings.TrimLeft(arg, "-") != "" {
+			continue
+		}
+		// the value of the flag before it, e.g. --dns-domain cluster.local
+		if i > 0 && strings.HasPrefix(args[i-1], "-") && !strings.Contains(args[i-1], "=") {
+			continue
+		}
+		return fmt.Errorf("expected extra arg [%s] in %q to be a flag starting with -- or the value of the flag before it", arg, args)
+	}
+	return nil
+}
+
+// checkArgChannels checks that the ginkgo flags are passed with
+// --extra-ginkgo-args and the flags of the e2e test binary with --extra-args,
+// where ginkgo flags are only accepted with the ginkgo. prefix. Ginkgo flags
+// unknown to the ginkgo release of the conformance image of the given
+// version, or set by hydrophone itself, are rejected as well. With force the
+// problems are only logged as warnings.
+func checkArgChannels(extraArgs, ginkgoArgs []string, imageVersion string, force bool) error {
+	var known map[string]string
+	if err := yaml.Unmarshal(ginkgoFlagsData, &known); err != nil {
+		return fmt.Errorf("error parsing the ginkgo flags: %w", err)
+	}
+	image, imageErr := semver.ParseTolerant(imageVersion)
+	// checkGinkgo describes why a ginkgo flag can't be passed, if it can't
+	checkGinkgo := func(name string) string {
+		since, ok := known[name]
+		if !ok {
+			return "unknown to ginkgo"
+		}
+		if since != "" && imageErr == nil {
+			if v, err := semver.ParseTolerant(since); err == nil && (image.Major < v.Major || image.Major == v.Major && image.Minor < v.Minor) {
+				return fmt.Sprintf("only known to the ginkgo of the conformance images of %s and later", since)
+			}
+		}
+		if hint, ok := managedGinkgoArgs[name]; ok {
+			return "set by hydrophone, " + hint
+		}
+		return ""
+	}
+
+	var problems []string
+	for _, arg := range flagNames(extraArgs) {
+		if name, ok := strings.CutPrefix(arg, "ginkgo."); ok {
+			// the managed ones are checked by checkManagedArgs
+			if _, managed := managedArgs[arg]; !managed {
+				if problem := checkGinkgo(name); problem != "" {
+					problems = append(problems, fmt.Sprintf("extra arg --%s is %s", arg, problem))
+				}
+			}
+			continue
+		}
+		if _, ok := known[arg]; ok && !e2eGinkgoFlags[arg] {
+			problems = append(problems, fmt.Sprintf("extra arg --%s is a flag of ginkgo, not of the e2e test binary, pass it with --extra-ginkgo-args", arg))
+		}
+	}
+	for _, arg := range flagNames(ginkgoArgs) {
+		if name, ok := strings.CutPrefix(arg, "ginkgo."); ok {
+			problems = append(problems, fmt.Sprintf("extra ginkgo arg --%s is passed to ginkgo itself, drop the ginkgo. prefix: --%s", arg, name))
+			continue
+		}
+		if problem := checkGinkgo(arg); problem != "" {
+			if problem == "unknown to ginkgo" {
+				problem += ", flags of the e2e test binary are passed with --extra-args"
+			}
+			problems = append(problems, fmt.Sprintf("extra ginkgo arg --%s is %s", arg, problem))
+		}
+	}
+
+	for _, problem := range problems {
+		if !force {
+			return fmt.Errorf("%s. use --force-extra-args to pass it anyway", problem)
+		}
+		log.Printf("WARNING: --force-extra-args passes the argument anyway: %s", problem)
+	}
+	return nil
+}
+
+// flagNames returns the names of the flags of the arguments, without their
+// dashes and values
+func flagNames(args []string) []string {
+	var names []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		names = append(names, name)
+	}
+	return names
+}
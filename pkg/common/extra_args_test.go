@@ -0,0 +1,209 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "key value pairs",
+			line: "--clean-start=true  --allowed-not-ready-nodes=2",
+			want: []string{"--clean-start=true", "--allowed-not-ready-nodes=2"},
+		},
+		{
+			name: "empty",
+			line: " ",
+		},
+		{
+			name: "quotes",
+			line: `--dns-domain "cluster local" --non-blocking-taints='a b' --empty=""`,
+			want: []string{"--dns-domain", "cluster local", "--non-blocking-taints=a b", "--empty="},
+		},
+		{
+			name: "escapes",
+			line: `--a=x\ y "--b=\"q\" \d" '--c=\n'`,
+			want: []string{"--a=x y", `--b="q" \d`, `--c=\n`},
+		},
+		{
+			name:    "unterminated quote",
+			line:    `--dns-domain "cluster local`,
+			wantErr: true,
+		},
+		{
+			name:    "unterminated escape",
+			line:    `--a=b\`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitArgs(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMergeExtraArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		env        string
+		want       []string
+		wantGinkgo []string
+		wantErr    bool
+	}{
+		{
+			name:       "flags",
+			args:       []string{"--extra-args", `--clean-start,--dns-domain 'cluster local'`, "--extra-arg=--non-blocking-taints=a,b", "--extra-arg", "--x=y z", "--extra-ginkgo-args", "--flake-attempts=2 --no-color"},
+			want:       []string{"--clean-start", "--dns-domain", "cluster local", "--non-blocking-taints=a,b", "--x=y z"},
+			wantGinkgo: []string{"--flake-attempts=2", "--no-color"},
+		},
+		{
+			name: "environment",
+			env:  `--clean-start --dns-domain "cluster local"`,
+			want: []string{"--clean-start", "--dns-domain", "cluster local"},
+		},
+		{
+			name: "none",
+		},
+		{
+			name:    "unterminated quote",
+			args:    []string{"--extra-args", `--dns-domain='cluster`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings.Reset()
+			defer settings.Reset()
+
+			flags := pflag.NewFlagSet("hydrophone", pflag.ContinueOnError)
+			flags.StringSlice("extra-args", []string{}, "")
+			flags.StringArray("extra-arg", []string{}, "")
+			flags.StringSlice("extra-ginkgo-args", []string{}, "")
+			for _, name := range []string{"extra-args", "extra-arg", "extra-ginkgo-args"} {
+				require.NoError(t, settings.BindPFlag(name, flags.Lookup(name)))
+			}
+			require.NoError(t, flags.Parse(tt.args))
+			if tt.env != "" {
+				t.Setenv("HYDROPHONE_EXTRA_ARGS", tt.env)
+				settings.SetEnvPrefix(EnvPrefix)
+				settings.SetEnvKeyReplacer(EnvKeyReplacer)
+				settings.AutomaticEnv()
+			}
+
+			err := MergeExtraArgs()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, settings.GetStringSlice("extra-args"))
+			assert.Equal(t, tt.wantGinkgo, settings.GetStringSlice("extra-ginkgo-args"))
+			assert.Empty(t, settings.GetStringSlice("extra-arg"))
+		})
+	}
+}
+
+func TestCheckArgChannels(t *testing.T) {
+	tests := []struct {
+		name       string
+		extraArgs  []string
+		ginkgoArgs []string
+		version    string
+		force      bool
+		wantErr    string
+	}{
+		{
+			name:       "valid",
+			extraArgs:  []string{"--allowed-not-ready-nodes=1", "--ginkgo.flake-attempts=2", "--v=4", "--dns-domain", "cluster.local"},
+			ginkgoArgs: []string{"--flake-attempts=2", "--no-color", "-v"},
+			version:    "v1.29.0",
+		},
+		{
+			name:      "ginkgo flag in the e2e args",
+			extraArgs: []string{"--flake-attempts=2"},
+			wantErr:   "extra arg --flake-attempts is a flag of ginkgo, not of the e2e test binary, pass it with --extra-ginkgo-args. use --force-extra-args to pass it anyway",
+		},
+		{
+			name:      "unknown ginkgo flag in the e2e args",
+			extraArgs: []string{"--ginkgo.flake-attempt=2"},
+			wantErr:   "extra arg --ginkgo.flake-attempt is unknown to ginkgo. use --force-extra-args to pass it anyway",
+		},
+		{
+			name:       "e2e flag in the ginkgo args",
+			ginkgoArgs: []string{"--allowed-not-ready-nodes=1"},
+			wantErr:    "extra ginkgo arg --allowed-not-ready-nodes is unknown to ginkgo, flags of the e2e test binary are passed with --extra-args. use --force-extra-args to pass it anyway",
+		},
+		{
+			name:       "prefixed ginkgo args",
+			ginkgoArgs: []string{"--ginkgo.flake-attempts=2"},
+			wantErr:    "extra ginkgo arg --ginkgo.flake-attempts is passed to ginkgo itself, drop the ginkgo. prefix: --flake-attempts. use --force-extra-args to pass it anyway",
+		},
+		{
+			name:       "managed ginkgo args",
+			ginkgoArgs: []string{"--procs", "4"},
+			wantErr:    "extra ginkgo arg --procs is set by hydrophone, use --parallel. use --force-extra-args to pass it anyway",
+		},
+		{
+			name:       "newer ginkgo flag",
+			ginkgoArgs: []string{"--fail-on-empty"},
+			version:    "v1.29.3",
+			wantErr:    "extra ginkgo arg --fail-on-empty is only known to the ginkgo of the conformance images of v1.30 and later. use --force-extra-args to pass it anyway",
+		},
+		{
+			name:       "newer ginkgo flag with a custom image",
+			ginkgoArgs: []string{"--fail-on-empty"},
+			version:    "latest",
+		},
+		{
+			name:       "forced",
+			extraArgs:  []string{"--flake-attempts=2"},
+			ginkgoArgs: []string{"--focus=Pods"},
+			force:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkArgChannels(tt.extraArgs, tt.ginkgoArgs, tt.version, tt.force)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
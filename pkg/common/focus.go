@@ -0,0 +1,273 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// MaxFocusLength is the maximum length of a single focus expression. Linux
+// limits every argument and environment variable of a process to 128KiB,
+// some room is left for the rest of the variable.
+const MaxFocusLength = 120 * 1024
+
+// ReadList reads a newline-delimited list from the file at path. Surrounding
+// whitespace, blank lines and lines starting with # are ignored.
+func ReadList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxFocusLength)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return lines, nil
+}
+
+// FocusFromTestNames converts exact test names into focus expressions matching
+// only those tests. Ginkgo matches the expressions against the description of
+// the suite followed by the text of the spec, so the names are matched after
+// a space up to the end of the text, optionally followed by the labels junit
+// reports append to the names. The names are escaped, the labels of names
+// copied from junit reports are left out, and whitespace is matched with \s so
+// the expression never needs to be quoted. When the expression would exceed
+// maxLength it is split into several expressions which together match all
+// the tests.
+func FocusFromTestNames(names []string, maxLength int) ([]string, error) {
+	var chunks []string
+	var current []string
+	length := len(namesPrefix) + len(namesSuffix)
+	for _, name := range names {
+		// names copied from junit reports carry the ginkgo node type
+		name = trimLabels(strings.TrimPrefix(name, "[It] "))
+		expr := strings.ReplaceAll(regexp.QuoteMeta(name), " ", `\s`)
+		if len(namesPrefix)+len(expr)+len(namesSuffix) > maxLength {
+			return nil, fmt.Errorf("test name [%s] is too long to be focused", name)
+		}
+		if len(current) != 0 && length+1+len(expr) > maxLength {
+			chunks = append(chunks, namesAlternation(current))
+			current, length = nil, len(namesPrefix)+len(namesSuffix)
+		}
+		if len(current) != 0 {
+			length++
+		}
+		current = append(current, expr)
+		length += len(expr)
+	}
+	if len(current) != 0 {
+		chunks = append(chunks, namesAlternation(current))
+	}
+	return chunks, nil
+}
+
+// namesPrefix and namesSuffix surround the names of a focus expression, the
+// suffix matches the labels of a junit test case name, e.g. [Conformance]
+const (
+	namesPrefix = `\s(`
+	namesSuffix = `)(\s\[[^\]]*\])?$`
+)
+
+func namesAlternation(exprs []string) string {
+	return namesPrefix + strings.Join(exprs, "|") + namesSuffix
+}
+
+// trimLabels removes the labels junit appends to the name of a test case.
+// The e2e framework tags the text of a spec with each of its labels, so the
+// last bracket of the name holds labels when each of them tags the name.
+func trimLabels(name string) string {
+	start := strings.LastIndex(name, " [")
+	if start == -1 || !strings.HasSuffix(name, "]") {
+		return name
+	}
+	text := name[:start]
+	for _, label := range strings.Split(name[start+2:len(name)-1], ", ") {
+		if !strings.Contains(text, "["+label+"]") {
+			return name
+		}
+	}
+	return text
+}
+
+// SplitFocus splits a focus expression longer than maxLength into several
+// expressions which together match the same tests, at the alternatives of
+// the expression. An expression without alternatives is split at the
+// alternatives of its first group, keeping what surrounds the group, e.g.
+// ^(a|b)$ into ^(a)$ and ^(b)$. Tests matching alternatives of different
+// expressions match each of them.
+func SplitFocus(expr string, maxLength int) ([]string, error) {
+	if len(expr) <= maxLength {
+		return []string{expr}, nil
+	}
+	prefix, suffix := "", ""
+	alternatives := topLevelAlternatives(expr)
+	if len(alternatives) == 1 {
+		start, end := firstGroup(expr)
+		if start == -1 || end == -1 {
+			return nil, fmt.Errorf("focus expression of %d bytes is longer than %d bytes and has no alternatives to split it at", len(expr), maxLength)
+		}
+		open := "("
+		if strings.HasPrefix(expr[start:], "(?:") {
+			open = "(?:"
+		}
+		// a repeated group can't be split, neither can a group setting flags
+		if strings.HasPrefix(expr[start:], "(?") && open == "(" || strings.ContainsAny(expr[end+1:min(end+2, len(expr))], "?*+{") {
+			return nil, fmt.Errorf("focus expression of %d bytes is longer than %d bytes and its first group can't be split", len(expr), maxLength)
+		}
+		prefix = expr[:start+len(open)]
+		suffix = expr[end:]
+		alternatives = topLevelAlternatives(expr[start+len(open) : end])
+	}
+
+	var chunks []string
+	var current []string
+	length := len(prefix) + len(suffix)
+	for _, alternative := range alternatives {
+		if len(prefix)+len(alternative)+len(suffix) > maxLength {
+			return nil, fmt.Errorf("an alternative of %d bytes of the focus expression is longer than %d bytes", len(alternative), maxLength)
+		}
+		if len(current) != 0 && length+1+len(alternative) > maxLength {
+			chunks = append(chunks, prefix+strings.Join(current, "|")+suffix)
+			current, length = nil, len(prefix)+len(suffix)
+		}
+		if len(current) != 0 {
+			length++
+		}
+		current = append(current, alternative)
+		length += len(alternative)
+	}
+	chunks = append(chunks, prefix+strings.Join(current, "|")+suffix)
+	for _, chunk := range chunks {
+		if _, err := regexp.Compile(chunk); err != nil {
+			return nil, fmt.Errorf("unable to split the focus expression: %w", err)
+		}
+	}
+	return chunks, nil
+}
+
+// walkGroups calls visit with the index and the nesting depth of the
+// parentheses and the | of the expression, skipping the escaped characters
+// and the character classes. visit stops the walk by returning false.
+func walkGroups(expr string, visit func(i, depth int) bool) {
+	depth := 0
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '\\':
+			i++
+		case '[':
+			// a ] right after [ or [^ is part of the class
+			i++
+			if strings.HasPrefix(expr[i:], "^") {
+				i++
+			}
+			if strings.HasPrefix(expr[i:], "]") {
+				i++
+			}
+			for i < len(expr) && expr[i] != ']' {
+				if expr[i] == '\\' {
+					i++
+				}
+				i++
+			}
+		case '(':
+			depth++
+			if !visit(i, depth) {
+				return
+			}
+		case ')':
+			depth--
+			if !visit(i, depth) {
+				return
+			}
+		case '|':
+			if !visit(i, depth) {
+				return
+			}
+		}
+	}
+}
+
+// topLevelAlternatives splits the expression at the | outside of groups
+func topLevelAlternatives(expr string) []string {
+	var alternatives []string
+	start := 0
+	walkGroups(expr, func(i, depth int) bool {
+		if expr[i] == '|' && depth == 0 {
+			alternatives = append(alternatives, expr[start:i])
+			start = i + 1
+		}
+		return true
+	})
+	return append(alternatives, expr[start:])
+}
+
+// firstGroup returns the indexes of the parentheses opening and closing the
+// first group of the expression, -1 when there is none or it isn't closed
+func firstGroup(expr string) (int, int) {
+	start, end := -1, -1
+	walkGroups(expr, func(i, depth int) bool {
+		switch {
+		case expr[i] == '(' && depth == 1 && start == -1:
+			start = i
+		case expr[i] == ')' && depth == 0:
+			end = i
+			return false
+		}
+		return true
+	})
+	return start, end
+}
+
+// SkipFromTestNames returns an expression skipping exactly the named tests,
+// e.g. the tests that passed before. A skip expression can't be run in
+// chunks, so the expression isn't split. Like --skip it has to fit in
+// MaxFocusLength to be passed to the conformance container, callers focus the
+// other tests by name with RemainingTests and FocusFromTestNames otherwise.
+func SkipFromTestNames(names []string) (string, error) {
+	exprs, err := FocusFromTestNames(names, math.MaxInt32)
+	if err != nil || len(exprs) == 0 {
+		return "", err
+	}
+	return exprs[0], nil
+}
+
+// SkipFromList combines a list of regular expressions into a single skip
+// expression matching any of them.
+func SkipFromList(patterns []string) (string, error) {
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return "", fmt.Errorf("invalid skip expression [%s]: %w", pattern, err)
+		}
+	}
+	return strings.Join(patterns, "|"), nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	content := "# known failures\n\n[sig-node] first test\n  [sig-apps] second test  \n#[sig-apps] disabled\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	lines, err := ReadList(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"[sig-node] first test", "[sig-apps] second test"}, lines)
+}
+
+func TestFocusFromTestNames(t *testing.T) {
+	names := []string{
+		"[sig-node] Pods should be submitted and removed [NodeConformance] [Conformance]",
+		"[It] [sig-apps] Deployment should run the lifecycle of a Deployment [Conformance]",
+		"[sig-cli] Kubectl client Simple pod should contain last line of the log",
+	}
+
+	chunks, err := FocusFromTestNames(names, MaxFocusLength)
+	assert.NoError(t, err)
+	assert.Len(t, chunks, 1)
+	focus := regexp.MustCompile(chunks[0])
+	assert.True(t, focus.MatchString("Kubernetes e2e suite [sig-node] Pods should be submitted and removed [NodeConformance] [Conformance]"))
+	assert.True(t, focus.MatchString("Kubernetes e2e suite [sig-apps] Deployment should run the lifecycle of a Deployment [Conformance]"))
+	assert.False(t, focus.MatchString("Kubernetes e2e suite [sig-node] Pods should be submitted and removed [NodeConformance]"))
+	assert.NotContains(t, chunks[0], " ")
+
+	// every chunk stays within the limit and each name is matched by exactly one chunk
+	chunks, err = FocusFromTestNames(names, 150)
+	assert.NoError(t, err)
+	assert.Len(t, chunks, 3)
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, len(chunk), 150)
+	}
+
+	_, err = FocusFromTestNames(names, 20)
+	assert.Error(t, err)
+}
+
+func TestFocusFromTestNamesGinkgoMatching(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		junit   string
+		matches bool
+	}{
+		{
+			name:    "[sig-node] Pods should be submitted and removed [NodeConformance] [Conformance]",
+			text:    "[sig-node] Pods should be submitted and removed [NodeConformance] [Conformance]",
+			junit:   "[It] [sig-node] Pods should be submitted and removed [NodeConformance] [Conformance] [NodeConformance, Conformance]",
+			matches: true,
+		},
+		{
+			// names copied from junit reports carry the labels
+			name:    "[sig-apps] Deployment should proceed [Conformance] [Conformance]",
+			text:    "[sig-apps] Deployment should proceed [Conformance]",
+			junit:   "[It] [sig-apps] Deployment should proceed [Conformance] [Conformance]",
+			matches: true,
+		},
+		{
+			name:    "[sig-cli] Kubectl logs",
+			text:    "[sig-cli] Kubectl logs",
+			junit:   "[It] [sig-cli] Kubectl logs",
+			matches: true,
+		},
+		{
+			name:  "[sig-cli] Kubectl logs",
+			text:  "[sig-cli] Kubectl logs should be filtered",
+			junit: "[It] [sig-cli] Kubectl logs should be filtered",
+		},
+		{
+			name:  "Kubectl logs",
+			text:  "[sig-cli] Kubectl logs with a prefix",
+			junit: "[It] [sig-cli] Kubectl logs with a prefix",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := FocusFromTestNames([]string{tt.name}, MaxFocusLength)
+			require.NoError(t, err)
+			focus := regexp.MustCompile(chunks[0])
+			// ginkgo matches the description of the suite and the text of the spec
+			assert.Equal(t, tt.matches, focus.MatchString("Kubernetes e2e suite "+tt.text))
+			assert.Equal(t, tt.matches, focus.MatchString(tt.junit))
+		})
+	}
+}
+
+func TestSplitFocus(t *testing.T) {
+	tests := []struct {
+		name      string
+		expr      string
+		maxLength int
+		chunks    []string
+		err       bool
+	}{
+		{name: "short", expr: `\[sig-apps\]|\[sig-node\]`, maxLength: 100, chunks: []string{`\[sig-apps\]|\[sig-node\]`}},
+		{name: "alternatives", expr: `\[sig-apps\]|\[sig-node\]|\[sig-cli\]`, maxLength: 25,
+			chunks: []string{`\[sig-apps\]|\[sig-node\]`, `\[sig-cli\]`}},
+		{name: "anchored group", expr: `^(Pods\sshould\srun|DNS\s\(a\|b\)|Kubectl\s[|(]logs)$`, maxLength: 40,
+			chunks: []string{`^(Pods\sshould\srun|DNS\s\(a\|b\))$`, `^(Kubectl\s[|(]logs)$`}},
+		{name: "non-capturing group", expr: `(?:sig-apps|sig-node|sig-cli)`, maxLength: 22,
+			chunks: []string{`(?:sig-apps|sig-node)`, `(?:sig-cli)`}},
+		{name: "nested alternative too long", expr: `(sig-apps|sig-node)\sshould|sig-cli`, maxLength: 20, err: true},
+		{name: "no alternative", expr: `Pods\sshould\srun\swith\sa\slong\sname`, maxLength: 20, err: true},
+		{name: "group not spanning", expr: `\s(sig-apps|sig-node)\sshould$`, maxLength: 25,
+			chunks: []string{`\s(sig-apps)\sshould$`, `\s(sig-node)\sshould$`}},
+		{name: "repeated group", expr: `(sig-apps|sig-node)+\sshould`, maxLength: 20, err: true},
+		{name: "alternative too long", expr: `^(sig-apps|Pods\sshould\srun\swith\sa\slong\sname)$`, maxLength: 30, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := SplitFocus(tt.expr, tt.maxLength)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.chunks, chunks)
+			for _, chunk := range chunks {
+				assert.LessOrEqual(t, len(chunk), tt.maxLength)
+			}
+		})
+	}
+
+	// the chunks of a list of names match the same tests as the expression
+	names := []string{"[sig-node] Pods should run", "[sig-apps] Deployment should proceed", "[sig-cli] Kubectl logs"}
+	focus, err := FocusFromTestNames(names, MaxFocusLength)
+	require.NoError(t, err)
+	chunks, err := SplitFocus(focus[0], 70)
+	require.NoError(t, err)
+	assert.Len(t, chunks, 3)
+	for i, name := range names {
+		assert.Regexp(t, chunks[i], "Kubernetes e2e suite "+name)
+	}
+}
+
+func TestSkipFromList(t *testing.T) {
+	skip, err := SkipFromList([]string{`\[Serial\]`, `should proxy .* through a service`})
+	assert.NoError(t, err)
+	assert.Equal(t, `\[Serial\]|should proxy .* through a service`, skip)
+
+	_, err = SkipFromList([]string{`\[sig-network`, `(unbalanced`})
+	assert.ErrorContains(t, err, "(unbalanced")
+}
+
+func TestSkipFromTestNames(t *testing.T) {
+	// the names of the specs that passed, as read from the junit report of
+	// a paused run
+	passed := []string{
+		"[sig-node] Pods should be submitted and removed [NodeConformance] [Conformance] [NodeConformance, Conformance]",
+		"[sig-cli] Kubectl logs",
+	}
+	skip, err := SkipFromTestNames(passed)
+	require.NoError(t, err)
+	// the skip of a resumed run is joined with --skip
+	expr := regexp.MustCompile(`\[Serial\]|` + skip)
+	for text, skipped := range map[string]bool{
+		"[sig-node] Pods should be submitted and removed [NodeConformance] [Conformance]": true,
+		"[sig-cli] Kubectl logs":                  true,
+		"[sig-cli] Kubectl logs should be sorted": false,
+		"[sig-apps] Deployment should proceed":    false,
+		"[sig-apps] Deployment [Serial] restarts": true,
+	} {
+		assert.Equal(t, skipped, expr.MatchString("Kubernetes e2e suite "+text), text)
+	}
+
+	skip, err = SkipFromTestNames(nil)
+	require.NoError(t, err)
+	assert.Empty(t, skip)
+}
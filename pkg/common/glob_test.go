@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{pattern: "*.xml", name: "junit_01.xml", expected: true},
+		{pattern: "*.xml", name: "reports/junit_01.xml"},
+		{pattern: "e2e.log", name: "e2e.log", expected: true},
+		{pattern: "hostlogs/**", name: "hostlogs/node-1/kubelet.log", expected: true},
+		{pattern: "hostlogs/**", name: "e2e.log"},
+		{pattern: "**/*.log", name: "e2e.log", expected: true},
+		{pattern: "**/*.log", name: "hostlogs/node-1/kubelet.log", expected: true},
+		{pattern: "hostlogs/*/kubelet.log", name: "hostlogs/node-1/kubelet.log", expected: true},
+		{pattern: "hostlogs/*.log", name: "hostlogs/node-1/kubelet.log"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			ok, err := MatchGlob(tt.pattern, tt.name)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, ok)
+		})
+	}
+
+	_, err := MatchGlob("[", "e2e.log")
+	assert.Error(t, err)
+	assert.NoError(t, ValidateGlob("hostlogs/**/*.log"))
+	assert.EqualError(t, ValidateGlob("hostlogs/["), `invalid glob "hostlogs/[": syntax error in pattern`)
+}
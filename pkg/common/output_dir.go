@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+// TimestampFormat is the format of the {timestamp} placeholder of
+// --output-dir
+const TimestampFormat = "20060102T150405Z"
+
+// placeholderRegexp matches the placeholders of --output-dir, e.g. {cluster}
+var placeholderRegexp = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// unsafePathChars matches the characters a placeholder value can't hold in a
+// directory name
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._+-]`)
+
+// OutputDirValues returns the values of the placeholders of --output-dir:
+// {cluster}, the name of the cluster in the kubeconfig, {version}, the
+// version of the cluster or, when it is unknown, of the conformance image,
+// and {timestamp}, the start of the run in UTC.
+func OutputDirValues(cluster, serverVersion, conformanceImage string, now time.Time) map[string]string {
+	version := serverVersion
+	if version == "" {
+		version = ImageVersion(conformanceImage)
+	}
+	values := map[string]string{
+		"cluster":   cluster,
+		"version":   version,
+		"timestamp": now.UTC().Format(TimestampFormat),
+	}
+	for name, value := range values {
+		if value == "" {
+			values[name] = "unknown"
+		}
+	}
+	return values
+}
+
+// ExpandOutputDir replaces the placeholders of the --output-dir template with
+// their values. Characters of the values that don't belong in a directory
+// name, e.g. a slash, are replaced with an underscore.
+func ExpandOutputDir(template string, values map[string]string) (string, error) {
+	var unknown []string
+	dir := placeholderRegexp.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := strings.Trim(placeholder, "{}")
+		value, ok := values[name]
+		if !ok {
+			unknown = append(unknown, placeholder)
+			return placeholder
+		}
+		return unsafePathChars.ReplaceAllString(value, "_")
+	})
+	if len(unknown) != 0 {
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, "{"+name+"}")
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("unknown placeholder %s in --output-dir, expected one of %s", strings.Join(unknown, ", "), strings.Join(names, ", "))
+	}
+	return dir, nil
+}
+
+// HoldsRun reports whether the directory holds the artifacts of a run.
+func HoldsRun(dir string) (bool, error) {
+	for _, name := range []string{results.MetadataFile, "junit_01.xml", "e2e.log", "e2e.log.gz"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// FreeOutputDir returns the directory, or when it holds the artifacts of a
+// previous run the first of dir-1, dir-2 and so on that doesn't, so that a
+// run doesn't overwrite the artifacts of another one.
+func FreeOutputDir(dir string) (string, error) {
+	free := dir
+	for i := 1; ; i++ {
+		used, err := HoldsRun(free)
+		if err != nil {
+			return "", err
+		}
+		if !used {
+			return free, nil
+		}
+		free = fmt.Sprintf("%s-%d", filepath.Clean(dir), i)
+	}
+}
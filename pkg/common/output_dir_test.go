@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandOutputDir(t *testing.T) {
+	now := time.Date(2024, 3, 1, 2, 3, 4, 0, time.FixedZone("CET", 3600))
+	values := OutputDirValues("kind/dev", "", "registry.k8s.io/conformance:v1.29.2", now)
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{name: "no placeholders", template: "./results", want: "./results"},
+		{name: "placeholders", template: "./results/{cluster}/{version}/{timestamp}", want: "./results/kind_dev/v1.29.2/20240301T010304Z"},
+		{name: "unknown placeholder", template: "./results/{context}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ExpandOutputDir(tt.template, values)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, dir)
+		})
+	}
+
+	assert.Equal(t, "unknown", OutputDirValues("", "", "", now)["version"])
+}
+
+func TestFreeOutputDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "results")
+
+	free, err := FreeOutputDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, dir, free)
+
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0600))
+	free, err = FreeOutputDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, dir, free, "files other than artifacts don't count")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "junit_01.xml"), nil, 0600))
+	require.NoError(t, os.MkdirAll(dir+"-1", 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir+"-1", "results.json"), nil, 0600))
+	free, err = FreeOutputDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, dir+"-2", free)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+)
+
+// DefaultRegistries are the registries of the e2e test images that can be
+// overridden by KUBE_TEST_REPO_LIST, keyed by their name in the repo list.
+// See test/utils/image/manifest.go in kubernetes/kubernetes.
+var DefaultRegistries = map[string]string{
+	"buildImageRegistry":       "registry.k8s.io/build-image",
+	"cloudProviderGcpRegistry": "registry.k8s.io/cloud-provider-gcp",
+	"dockerGluster":            "docker.io/gluster",
+	"dockerLibraryRegistry":    "docker.io/library",
+	"e2eRegistry":              "gcr.io/kubernetes-e2e-test-images",
+	"e2eVolumeRegistry":        "gcr.io/kubernetes-e2e-test-images/volume",
+	"gcAuthenticatedRegistry":  "gcr.io/authenticated-image-pulling",
+	"gcEtcdRegistry":           "registry.k8s.io",
+	"gcRegistry":               "registry.k8s.io",
+	"gcrReleaseRegistry":       "gcr.io/gke-release",
+	"invalidRegistry":          "invalid.registry.k8s.io/invalid",
+	"microsoftRegistry":        "mcr.microsoft.com",
+	"privateRegistry":          "gcr.io/k8s-authenticated-test",
+	"promoterE2eRegistry":      "registry.k8s.io/e2e-test-images",
+	"sigStorageRegistry":       "registry.k8s.io/sig-storage",
+}
+
+// unpullableRegistries hold images tests expect to fail pulling
+var unpullableRegistries = []string{"gcAuthenticatedRegistry", "invalidRegistry", "privateRegistry"}
+
+// ReadTestRepoList reads a KUBE_TEST_REPO_LIST file mapping the registries of
+// the test images to their mirror. Keys unknown to hydrophone are reported
+// with a warning since newer Kubernetes versions may add registries.
+func ReadTestRepoList(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	repoList := map[string]string{}
+	if err := yaml.Unmarshal(data, &repoList); err != nil {
+		return nil, fmt.Errorf("error parsing test repo list %s: %w", path, err)
+	}
+	if len(repoList) == 0 {
+		return nil, fmt.Errorf("test repo list %s doesn't override any registry", path)
+	}
+
+	var keys []string
+	for key := range repoList {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, ok := DefaultRegistries[key]; !ok {
+			log.Printf("WARNING: unknown registry %s in test repo list %s", key, path)
+		}
+	}
+	return repoList, nil
+}
+
+// MirrorTestImage returns the reference of the test image after applying the
+// overrides of the repo list, the way the e2e framework does. It returns false
+// for images the tests expect to fail pulling.
+func MirrorTestImage(image string, repoList map[string]string) (string, bool) {
+	key, prefix := "", ""
+	for k, registry := range DefaultRegistries {
+		if !strings.HasPrefix(image, registry+"/") || len(registry) < len(prefix) {
+			continue
+		}
+		// gcRegistry and gcEtcdRegistry share their default, etcd images use the latter
+		if len(registry) == len(prefix) && (k == "gcEtcdRegistry") != strings.HasPrefix(image, registry+"/etcd") {
+			continue
+		}
+		key, prefix = k, registry
+	}
+	if key == "" {
+		return image, true
+	}
+	if slices.Contains(unpullableRegistries, key) {
+		return image, false
+	}
+	if mirror, ok := repoList[key]; ok {
+		return mirror + strings.TrimPrefix(image, prefix), true
+	}
+	return image, true
+}
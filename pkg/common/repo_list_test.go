@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadTestRepoList(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			content: "promoterE2eRegistry: mirror.example.com/e2e-test-images\nsigStorageRegistry: mirror.example.com/sig-storage\n",
+			want: map[string]string{
+				"promoterE2eRegistry": "mirror.example.com/e2e-test-images",
+				"sigStorageRegistry":  "mirror.example.com/sig-storage",
+			},
+		},
+		{
+			name:    "empty",
+			content: "# nothing yet\n",
+			wantErr: true,
+		},
+		{
+			name:    "not a mapping",
+			content: "- mirror.example.com\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "repo-list.yaml")
+			assert.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+
+			got, err := ReadTestRepoList(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMirrorTestImage(t *testing.T) {
+	repoList := map[string]string{
+		"promoterE2eRegistry": "mirror.example.com/e2e-test-images",
+		"gcRegistry":          "mirror.example.com",
+		"gcEtcdRegistry":      "etcd.example.com",
+	}
+	tests := []struct {
+		image    string
+		want     string
+		pullable bool
+	}{
+		{"registry.k8s.io/e2e-test-images/agnhost:2.47", "mirror.example.com/e2e-test-images/agnhost:2.47", true},
+		{"registry.k8s.io/pause:3.9", "mirror.example.com/pause:3.9", true},
+		{"registry.k8s.io/etcd:3.5.10-0", "etcd.example.com/etcd:3.5.10-0", true},
+		{"registry.k8s.io/sig-storage/nfs-provisioner:v4.0.8", "registry.k8s.io/sig-storage/nfs-provisioner:v4.0.8", true},
+		{"quay.io/some/image:1.0", "quay.io/some/image:1.0", true},
+		{"invalid.registry.k8s.io/invalid/alpine:3.1", "invalid.registry.k8s.io/invalid/alpine:3.1", false},
+	}
+	for _, tt := range tests {
+		got, pullable := MirrorTestImage(tt.image, repoList)
+		assert.Equal(t, tt.want, got, tt.image)
+		assert.Equal(t, tt.pullable, pullable, tt.image)
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"errors"
+	"fmt"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// Restricted reports whether the run avoids the cluster-scoped operations of
+// hydrophone with --restricted, so that it can be run by users whose
+// permissions are limited to the namespace of the run. The namespace and the
+// service account of the conformance pods are created by an administrator.
+func Restricted() bool {
+	return settings.GetBool("restricted")
+}
+
+// ValidateRestricted checks that --restricted is given a namespace and a
+// service account, and refuses the flags that can't do without
+// cluster-scoped permissions.
+func ValidateRestricted() error {
+	if !Restricted() {
+		return nil
+	}
+	if settings.GetString("namespace") == "" || settings.GetString("service-account") == "" {
+		return errors.New("--restricted requires the --namespace and the --service-account created by the administrator of the cluster")
+	}
+	for _, conflict := range []struct {
+		set    bool
+		flag   string
+		reason string
+	}{
+		{settings.GetBool("impact-guard"), "--impact-guard", "it watches the pods and the nodes of the cluster"},
+		{settings.GetString("parallel") == ParallelAuto, "--parallel=" + ParallelAuto, "it counts the nodes of the cluster"},
+		{settings.GetString("node-os") == NodeOSWindows, "--node-os=" + NodeOSWindows, "it looks for windows nodes"},
+		{len(settings.GetStringSlice("node")) != 0, "--node", "it checks the nodes"},
+		{settings.GetBool("deep"), "--deep", "it deletes cluster-scoped resources"},
+	} {
+		if conflict.set {
+			return fmt.Errorf("%s isn't supported with --restricted, %s", conflict.flag, conflict.reason)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestValidateRestricted(t *testing.T) {
+	defer settings.Set("restricted", false)
+	defer settings.Set("namespace", "")
+	defer settings.Set("service-account", "")
+	defer settings.Set("parallel", "")
+	defer settings.Set("impact-guard", false)
+
+	assert.NoError(t, ValidateRestricted())
+
+	settings.Set("restricted", true)
+	assert.EqualError(t, ValidateRestricted(), "--restricted requires the --namespace and the --service-account created by the administrator of the cluster")
+	settings.Set("namespace", "team-a")
+	assert.Error(t, ValidateRestricted())
+	settings.Set("service-account", "e2e")
+	assert.NoError(t, ValidateRestricted())
+	assert.True(t, Restricted())
+
+	settings.Set("parallel", ParallelAuto)
+	assert.EqualError(t, ValidateRestricted(), "--parallel=auto isn't supported with --restricted, it counts the nodes of the cluster")
+	settings.Set("parallel", "")
+	settings.Set("impact-guard", true)
+	assert.EqualError(t, ValidateRestricted(), "--impact-guard isn't supported with --restricted, it watches the pods and the nodes of the cluster")
+}
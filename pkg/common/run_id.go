@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+const (
+	// RunIDLabel is the label of the resources of a run holding its --run-id
+	RunIDLabel = "hydrophone.k8s.io/run-id"
+	// ManagedByLabel is the label of every resource hydrophone creates, set
+	// to ManagedBy, which hydrophone gc looks for
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	// ManagedBy is the value of ManagedByLabel
+	ManagedBy = "hydrophone"
+	// RunIDAuto makes hydrophone generate the ID of the run
+	RunIDAuto = "auto"
+	// maxRunIDLength keeps the default namespace of a run a valid name
+	maxRunIDLength = 63 - len(DefaultNamespace) - 1
+)
+
+// runIDRegexp matches the valid IDs of runs, DNS labels
+var runIDRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// ResolveRunID checks the ID of --run-id, generating one when it is
+// RunIDAuto.
+func ResolveRunID() error {
+	id := settings.GetString("run-id")
+	switch {
+	case id == "":
+		return nil
+	case id == RunIDAuto:
+		b := make([]byte, 4)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		id = hex.EncodeToString(b)
+		settings.Set("run-id", id)
+		log.Printf("Run ID %s, pass --run-id=%s to the commands targeting this run", id, id)
+	case len(id) > maxRunIDLength || !runIDRegexp.MatchString(id):
+		return fmt.Errorf("expected --run-id to be %s or at most %d lowercase letters, digits and dashes, got %q", RunIDAuto, maxRunIDLength, id)
+	}
+	return nil
+}
+
+// WithRunID returns the name of a resource of the run, suffixed with the ID of
+// --run-id so that concurrent runs use their own resources.
+func WithRunID(name string) string {
+	if id := settings.GetString("run-id"); id != "" {
+		return name + "-" + id
+	}
+	return name
+}
+
+// ConformanceLabels returns the labels of the resources of the run
+func ConformanceLabels() map[string]string {
+	labels := map[string]string{"component": "conformance", ManagedByLabel: ManagedBy}
+	if id := settings.GetString("run-id"); id != "" {
+		labels[RunIDLabel] = id
+	}
+	return labels
+}
+
+// ConformanceSelector returns the label selector of the resources of the run
+func ConformanceSelector() string {
+	selector := "component=conformance"
+	if id := settings.GetString("run-id"); id != "" {
+		selector += "," + RunIDLabel + "=" + id
+	}
+	return selector
+}
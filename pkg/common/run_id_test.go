@@ -0,0 +1,54 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestResolveRunID(t *testing.T) {
+	defer settings.Set("run-id", "")
+
+	settings.Set("run-id", "")
+	require.NoError(t, ResolveRunID())
+	assert.Equal(t, "", settings.GetString("run-id"))
+	assert.Equal(t, DefaultNamespace, WithRunID(DefaultNamespace))
+	assert.Equal(t, map[string]string{"component": "conformance", ManagedByLabel: ManagedBy}, ConformanceLabels())
+	assert.Equal(t, "component=conformance", ConformanceSelector())
+
+	settings.Set("run-id", RunIDAuto)
+	require.NoError(t, ResolveRunID())
+	assert.Regexp(t, `^[0-9a-f]{8}$`, settings.GetString("run-id"))
+
+	settings.Set("run-id", "nightly-1")
+	require.NoError(t, ResolveRunID())
+	assert.Equal(t, "nightly-1", settings.GetString("run-id"))
+	assert.Equal(t, DefaultNamespace+"-nightly-1", WithRunID(DefaultNamespace))
+	assert.Equal(t, map[string]string{"component": "conformance", ManagedByLabel: ManagedBy, RunIDLabel: "nightly-1"}, ConformanceLabels())
+	assert.Equal(t, "component=conformance,"+RunIDLabel+"=nightly-1", ConformanceSelector())
+
+	for _, id := range []string{"Nightly", "-nightly", "nightly_1", strings.Repeat("a", maxRunIDLength+1)} {
+		settings.Set("run-id", id)
+		assert.ErrorContains(t, ResolveRunID(), "expected --run-id", id)
+	}
+}
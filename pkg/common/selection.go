@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FilterTests returns the test names matching all of the focus expressions
+// and none of the skip expression, in their original order. Names listed
+// more than once are returned once, duplicates counts how many were dropped.
+func FilterTests(names, focus []string, skip string) (selected []string, duplicates int, err error) {
+	focusRegexps := make([]*regexp.Regexp, len(focus))
+	for i, expr := range focus {
+		if focusRegexps[i], err = regexp.Compile(expr); err != nil {
+			return nil, 0, fmt.Errorf("invalid focus expression [%s]: %w", expr, err)
+		}
+	}
+	var skipRegexp *regexp.Regexp
+	if skip != "" {
+		if skipRegexp, err = regexp.Compile(skip); err != nil {
+			return nil, 0, fmt.Errorf("invalid skip expression [%s]: %w", skip, err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		// names copied from junit reports carry the ginkgo node type
+		name = strings.TrimPrefix(name, "[It] ")
+		if seen[name] {
+			duplicates++
+			continue
+		}
+		seen[name] = true
+		if skipRegexp != nil && skipRegexp.MatchString(name) {
+			continue
+		}
+		if matchesAll(focusRegexps, name) {
+			selected = append(selected, name)
+		}
+	}
+	return selected, duplicates, nil
+}
+
+func matchesAll(regexps []*regexp.Regexp, name string) bool {
+	for _, r := range regexps {
+		if !r.MatchString(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// suiteDescription is the description of the suite of the e2e tests, which
+// ginkgo matches the focus and skip expressions against along with the text
+// of the specs
+const suiteDescription = "Kubernetes e2e suite"
+
+// RemainingTests returns the tests ginkgo selects with the focus and skip
+// expressions that aren't in passed, in their original order. The names
+// are matched the way ginkgo matches them, so that expressions focusing
+// tests by name select them.
+func RemainingTests(names []string, focus, skip string, passed []string) ([]string, error) {
+	var focusRegexp, skipRegexp *regexp.Regexp
+	var err error
+	if focus != "" {
+		if focusRegexp, err = regexp.Compile(focus); err != nil {
+			return nil, fmt.Errorf("invalid focus expression [%s]: %w", focus, err)
+		}
+	}
+	if skip != "" {
+		if skipRegexp, err = regexp.Compile(skip); err != nil {
+			return nil, fmt.Errorf("invalid skip expression [%s]: %w", skip, err)
+		}
+	}
+	done := map[string]bool{}
+	for _, name := range passed {
+		done[strings.TrimPrefix(name, "[It] ")] = true
+	}
+
+	var remaining []string
+	for _, name := range names {
+		name = strings.TrimPrefix(name, "[It] ")
+		if done[name] {
+			continue
+		}
+		done[name] = true
+		text := suiteDescription + " " + trimLabels(name)
+		if focusRegexp != nil && !focusRegexp.MatchString(text) || skipRegexp != nil && skipRegexp.MatchString(text) {
+			continue
+		}
+		remaining = append(remaining, name)
+	}
+	return remaining, nil
+}
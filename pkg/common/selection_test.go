@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterTests(t *testing.T) {
+	names := []string{
+		"[sig-apps] Deployment should run the lifecycle of a Deployment [Conformance]",
+		"[It] [sig-apps] Deployment should run the lifecycle of a Deployment [Conformance]",
+		"[sig-apps] Daemon set [Serial] should rollback without unnecessary restarts [Conformance]",
+		"[sig-network] DNS should provide DNS for services [Conformance]",
+		"[sig-network] Netpol should enforce policy [Feature:NetworkPolicy]",
+	}
+
+	testCases := []struct {
+		name       string
+		focus      []string
+		skip       string
+		selected   []string
+		duplicates int
+	}{
+		{
+			name: "deduplicates",
+			selected: []string{
+				"[sig-apps] Deployment should run the lifecycle of a Deployment [Conformance]",
+				"[sig-apps] Daemon set [Serial] should rollback without unnecessary restarts [Conformance]",
+				"[sig-network] DNS should provide DNS for services [Conformance]",
+				"[sig-network] Netpol should enforce policy [Feature:NetworkPolicy]",
+			},
+			duplicates: 1,
+		},
+		{
+			name:       "intersects the focus expressions",
+			focus:      []string{`\[sig-(apps)\]`, `\[Conformance\]`, `Serial`},
+			selected:   []string{"[sig-apps] Daemon set [Serial] should rollback without unnecessary restarts [Conformance]"},
+			duplicates: 1,
+		},
+		{
+			name:       "skips",
+			focus:      []string{`\[Conformance\]`},
+			skip:       `Serial|DNS`,
+			selected:   []string{"[sig-apps] Deployment should run the lifecycle of a Deployment [Conformance]"},
+			duplicates: 1,
+		},
+		{
+			name:       "nothing selected",
+			focus:      []string{`\[sig-network\]`, `\[Serial\]`},
+			duplicates: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, duplicates, err := FilterTests(names, tc.focus, tc.skip)
+			require.NoError(t, err)
+			assert.Equal(t, tc.selected, selected)
+			assert.Equal(t, tc.duplicates, duplicates)
+		})
+	}
+
+	_, _, err := FilterTests(names, []string{"[unclosed"}, "")
+	assert.Error(t, err)
+}
+
+func TestFilterTestsFocus(t *testing.T) {
+	names := []string{
+		"[sig-apps] Deployment should run the lifecycle of a Deployment [Conformance]",
+		"[sig-apps] Deployment should proceed",
+		"[sig-network] DNS should provide DNS for services [Conformance]",
+	}
+	// --focus Deployment with --conformance
+	selected, _, err := FilterTests(names, []string{"Deployment", `\[Conformance\]`}, "")
+	require.NoError(t, err)
+	focus, err := FocusFromTestNames(selected, MaxFocusLength)
+	require.NoError(t, err)
+	require.Len(t, focus, 1)
+
+	// ginkgo runs the selected tests and only them
+	expr := regexp.MustCompile(focus[0])
+	for i, name := range names {
+		assert.Equal(t, i == 0, expr.MatchString("Kubernetes e2e suite "+name), name)
+	}
+}
+
+func TestRemainingTests(t *testing.T) {
+	names := []string{
+		"[It] [sig-apps] Deployment should run the lifecycle of a Deployment [Conformance]",
+		"[It] [sig-apps] Deployment should proceed",
+		"[It] [sig-network] DNS should provide DNS for services [Conformance]",
+		"[It] [sig-network] DNS should resolve DNS of partial qualified names [Conformance]",
+		"[It] [sig-node] Pods should be submitted and removed [NodeConformance] [Conformance] [NodeConformance, Conformance]",
+	}
+
+	remaining, err := RemainingTests(names, `\[Conformance\]`, `partial`, []string{"[sig-apps] Deployment should run the lifecycle of a Deployment [Conformance]"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"[sig-network] DNS should provide DNS for services [Conformance]",
+		"[sig-node] Pods should be submitted and removed [NodeConformance] [Conformance] [NodeConformance, Conformance]",
+	}, remaining)
+
+	// the expressions of a run focusing its tests by name select them
+	focus, err := FocusFromTestNames(names[2:4], MaxFocusLength)
+	require.NoError(t, err)
+	remaining, err = RemainingTests(names, focus[0], "", names[3:4])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"[sig-network] DNS should provide DNS for services [Conformance]"}, remaining)
+
+	_, err = RemainingTests(names, "(", "", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// StorageTestDriverFocus selects the external storage tests
+const StorageTestDriverFocus = `External.Storage`
+
+// ReadStorageTestDriver reads the test driver manifest of the external
+// storage tests, see test/e2e/storage/external in kubernetes/kubernetes, and
+// returns the name of the CSI driver it describes.
+func ReadStorageTestDriver(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var driver struct {
+		DriverInfo struct {
+			Name string `json:"Name"`
+		} `json:"DriverInfo"`
+	}
+	if err := yaml.Unmarshal(data, &driver); err != nil {
+		return "", fmt.Errorf("error parsing storage test driver %s: %w", path, err)
+	}
+	if driver.DriverInfo.Name == "" {
+		return "", fmt.Errorf("storage test driver %s has no DriverInfo.Name", path)
+	}
+	return driver.DriverInfo.Name, nil
+}
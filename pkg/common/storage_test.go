@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadStorageTestDriver(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		driver  string
+		wantErr bool
+	}{
+		{
+			name: "csi hostpath",
+			content: `StorageClass:
+  FromName: true
+DriverInfo:
+  Name: hostpath.csi.k8s.io
+  Capabilities:
+    persistence: true
+    block: true
+`,
+			driver: "hostpath.csi.k8s.io",
+		},
+		{name: "no driver name", content: "StorageClass:\n  FromName: true\n", wantErr: true},
+		{name: "invalid yaml", content: "DriverInfo: [", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "driver.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+			driver, err := ReadStorageTestDriver(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.driver, driver)
+		})
+	}
+}
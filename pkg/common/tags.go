@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Behaviors lists the tags describing how e2e tests behave, e.g. whether they
+// can run in parallel. Feature:<name> tags are accepted as well.
+var Behaviors = []string{
+	"Serial",
+	"Slow",
+	"Disruptive",
+	"LinuxOnly",
+	"NodeConformance",
+	"Flaky",
+}
+
+// FocusFromTags builds a focus expression selecting the tests of any of the
+// SIGs which carry any of the behavior tags. e2e test names start with the
+// SIG tag and end with the other tags, e.g.
+//
+//	[sig-apps] Daemon set [Serial] should rollback without unnecessary restarts [Conformance]
+//
+// so the tags are matched in that order. When conformance is set, only
+// conformance tests are selected.
+func FocusFromTags(sigs, behaviors []string, conformance bool) (string, error) {
+	var parts []string
+	if len(sigs) != 0 {
+		names := make([]string, len(sigs))
+		for i, sig := range sigs {
+			names[i] = strings.TrimPrefix(strings.ToLower(sig), "sig-")
+			if !slices.Contains(SIGs, names[i]) {
+				return "", fmt.Errorf("unknown SIG [%s], expected one of %s", sig, strings.Join(SIGs, ", "))
+			}
+		}
+		parts = append(parts, `\[sig-(`+strings.Join(names, "|")+`)\]`)
+	}
+	if len(behaviors) != 0 {
+		tags := make([]string, len(behaviors))
+		for i, behavior := range behaviors {
+			if !slices.Contains(Behaviors, behavior) && !strings.HasPrefix(behavior, "Feature:") {
+				return "", fmt.Errorf("unknown behavior [%s], expected one of %s or Feature:<name>", behavior, strings.Join(Behaviors, ", "))
+			}
+			tags[i] = regexp.QuoteMeta(behavior)
+		}
+		parts = append(parts, `\[(`+strings.Join(tags, "|")+`)\]`)
+	}
+	if len(parts) != 0 && conformance {
+		parts = append(parts, `\[Conformance\]`)
+	}
+	return strings.Join(parts, ".*"), nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFocusFromTags(t *testing.T) {
+	tests := []struct {
+		name        string
+		sigs        []string
+		behaviors   []string
+		conformance bool
+		want        string
+		matches     []string
+		wantErr     bool
+	}{
+		{
+			name: "no tags",
+			want: "",
+		},
+		{
+			name:    "sigs",
+			sigs:    []string{"network", "sig-Apps"},
+			want:    `\[sig-(network|apps)\]`,
+			matches: []string{"[sig-network] Services should serve a basic endpoint from pods [Conformance]"},
+		},
+		{
+			name:        "sigs and behaviors of conformance tests",
+			sigs:        []string{"apps"},
+			behaviors:   []string{"Serial", "Feature:Example"},
+			conformance: true,
+			want:        `\[sig-(apps)\].*\[(Serial|Feature:Example)\].*\[Conformance\]`,
+			matches:     []string{"[sig-apps] Daemon set [Serial] should rollback without unnecessary restarts [Conformance]"},
+		},
+		{
+			name:    "unknown sig",
+			sigs:    []string{"netwrk"},
+			wantErr: true,
+		},
+		{
+			name:      "unknown behavior",
+			behaviors: []string{"serial"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FocusFromTags(tt.sigs, tt.behaviors, tt.conformance)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			for _, name := range tt.matches {
+				assert.Regexp(t, regexp.MustCompile(got), name)
+			}
+		})
+	}
+}
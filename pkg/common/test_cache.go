@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/adrg/xdg"
+)
+
+// CachedTests are the names of the tests of a conformance version, cached
+// by the runs and the list command to select tests offline
+type CachedTests struct {
+	Version string `json:"version"`
+	// Complete is set once a run cached the names, its junit report lists
+	// every test of the conformance image. Otherwise only the tests listed
+	// so far are known.
+	Complete bool     `json:"complete"`
+	Tests    []string `json:"tests"`
+}
+
+// TestCacheDir returns the directory caching the tests of each conformance
+// version.
+func TestCacheDir() string {
+	return filepath.Join(xdg.CacheHome, "hydrophone", "tests")
+}
+
+// testCacheFile returns the file caching the tests of the version
+func testCacheFile(dir, version string) string {
+	return filepath.Join(dir, version+".json")
+}
+
+// ReadCachedTests returns the cached tests of the conformance version, nil
+// when none were cached.
+func ReadCachedTests(dir, version string) (*CachedTests, error) {
+	data, err := os.ReadFile(testCacheFile(dir, version))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	cached := &CachedTests{}
+	if err := json.Unmarshal(data, cached); err != nil {
+		return nil, err
+	}
+	return cached, nil
+}
+
+// CacheTests adds the names of the tests to the cache of the conformance
+// version. complete tells that they are all the tests of the version, which
+// replace the cached ones.
+func CacheTests(dir, version string, names []string, complete bool) error {
+	if version == "" || strings.ContainsAny(version, `/\`) {
+		return errors.New("the conformance image has no tag to cache its tests by")
+	}
+	// a cache that can't be read is replaced
+	cached, err := ReadCachedTests(dir, version)
+	if err != nil || cached == nil || complete {
+		cached = &CachedTests{Version: version}
+	}
+	cached.Complete = cached.Complete || complete
+	for _, name := range names {
+		if !slices.Contains(cached.Tests, name) {
+			cached.Tests = append(cached.Tests, name)
+		}
+	}
+	slices.Sort(cached.Tests)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cached, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(testCacheFile(dir, version), data, 0600)
+}
+
+// LatestCachedVersion returns the conformance version whose tests were
+// cached last, empty when none were.
+func LatestCachedVersion(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	var latest string
+	var latestInfo fs.FileInfo
+	for _, entry := range entries {
+		version, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return "", err
+		}
+		if latestInfo == nil || info.ModTime().After(latestInfo.ModTime()) {
+			latest, latestInfo = version, info
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedTests(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tests")
+
+	cached, err := ReadCachedTests(dir, "v1.30.0")
+	require.NoError(t, err)
+	assert.Nil(t, cached)
+	version, err := LatestCachedVersion(dir)
+	require.NoError(t, err)
+	assert.Empty(t, version)
+
+	// the listed tests add up
+	require.NoError(t, CacheTests(dir, "v1.30.0", []string{"[sig-node] b"}, false))
+	require.NoError(t, CacheTests(dir, "v1.30.0", []string{"[sig-apps] a", "[sig-node] b"}, false))
+	cached, err = ReadCachedTests(dir, "v1.30.0")
+	require.NoError(t, err)
+	assert.Equal(t, &CachedTests{Version: "v1.30.0", Tests: []string{"[sig-apps] a", "[sig-node] b"}}, cached)
+
+	// the tests of a run replace them
+	require.NoError(t, CacheTests(dir, "v1.30.0", []string{"[sig-apps] a", "[sig-cli] c"}, true))
+	cached, err = ReadCachedTests(dir, "v1.30.0")
+	require.NoError(t, err)
+	assert.Equal(t, &CachedTests{Version: "v1.30.0", Complete: true, Tests: []string{"[sig-apps] a", "[sig-cli] c"}}, cached)
+	// and stay complete
+	require.NoError(t, CacheTests(dir, "v1.30.0", []string{"[sig-apps] a"}, false))
+	cached, err = ReadCachedTests(dir, "v1.30.0")
+	require.NoError(t, err)
+	assert.True(t, cached.Complete)
+
+	require.NoError(t, CacheTests(dir, "v1.29.4", []string{"[sig-apps] a"}, false))
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "v1.30.0.json"), old, old))
+	version, err = LatestCachedVersion(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.29.4", version)
+
+	assert.Error(t, CacheTests(dir, "", []string{"[sig-apps] a"}, false))
+}
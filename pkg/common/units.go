@@ -0,0 +1,224 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// byteUnits maps the lower-cased unit suffixes accepted by ParseByteSize to
+// their multiplier. Decimal units use powers of 1000 and binary units powers
+// of 1024, following the convention of Kubernetes resource quantities.
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1e6,
+	"mb":  1e6,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1e9,
+	"gb":  1e9,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+	"t":   1e12,
+	"tb":  1e12,
+	"ti":  1 << 40,
+	"tib": 1 << 40,
+}
+
+// ByteSize is a number of bytes. It implements pflag.Value so it can be used
+// directly as a flag accepting human friendly values such as 512Ki, 10MiB or 1.5GB.
+type ByteSize int64
+
+// ParseByteSize parses a size such as 10MiB. The parsing doesn't depend on
+// the locale, the decimal separator is always a dot.
+func ParseByteSize(s string) (ByteSize, error) {
+	value := strings.TrimSpace(s)
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(value)
+	}
+	number, unit := value[:i], strings.ToLower(strings.TrimSpace(value[i:]))
+
+	multiplier, ok := byteUnits[unit]
+	if number == "" || !ok {
+		return 0, fmt.Errorf("invalid size %q: expected a value such as 512Ki, 10MiB or 1.5GB", s)
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: expected a value such as 512Ki, 10MiB or 1.5GB", s)
+	}
+	return ByteSize(n * multiplier), nil
+}
+
+// ParseRate parses a transfer rate such as 10MiB/s and returns the number of bytes per second.
+func ParseRate(s string) (ByteSize, error) {
+	rate, err := ParseByteSize(strings.TrimSuffix(strings.TrimSpace(s), "/s"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q: expected a value such as 512Ki/s or 10MiB/s", s)
+	}
+	return rate, nil
+}
+
+// String returns the size using the largest binary unit that represents it exactly.
+func (b *ByteSize) String() string {
+	n := int64(*b)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}} {
+		if n != 0 && n%unit.size == 0 {
+			return fmt.Sprintf("%d%s", n/unit.size, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// Set parses the flag value.
+func (b *ByteSize) Set(s string) error {
+	size, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// Type returns the type name displayed in the flag usage.
+func (b *ByteSize) Type() string {
+	return "size"
+}
+
+// Duration is a time.Duration implementing pflag.Value. On top of the units
+// understood by time.ParseDuration it accepts days, e.g. 1d12h.
+type Duration time.Duration
+
+// ParseDuration parses a duration such as 2h30m or 1d. Unlike
+// time.ParseDuration a missing unit is reported with an example of the
+// expected format.
+func ParseDuration(s string) (time.Duration, error) {
+	value := strings.TrimSpace(s)
+	var days time.Duration
+	if i := strings.Index(value, "d"); i >= 0 {
+		n, err := strconv.ParseUint(value[:i], 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: expected a value such as 90s, 2h30m or 1d", s)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		value = value[i+1:]
+		if value == "" {
+			return days, nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q: expected a value such as 90s, 2h30m or 1d", s)
+	}
+	return days + d, nil
+}
+
+// DurationFlag defines a flag of the set accepting the durations of
+// ParseDuration, with the given default, for the flags bound to the settings.
+func DurationFlag(flags *pflag.FlagSet, name string, value time.Duration, usage string) {
+	DurationVar(flags, new(time.Duration), name, value, usage)
+}
+
+// DurationVar defines a flag of the set accepting the durations of
+// ParseDuration, stored in p, with the given default.
+func DurationVar(flags *pflag.FlagSet, p *time.Duration, name string, value time.Duration, usage string) {
+	*p = value
+	flags.Var((*Duration)(p), name, usage)
+}
+
+// String returns the duration in the format of time.Duration. A zero duration
+// is 0, which the usage of the flags leaves out as the default.
+func (d *Duration) String() string {
+	if *d == 0 {
+		return "0"
+	}
+	return time.Duration(*d).String()
+}
+
+// Set parses the flag value.
+func (d *Duration) Set(s string) error {
+	duration, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Type returns the type name displayed in the flag usage.
+func (d *Duration) Type() string {
+	return "duration"
+}
+
+// GetDuration parses the duration stored under the key in the configuration.
+// Unset keys yield a zero duration.
+func GetDuration(key string) (time.Duration, error) {
+	value := settings.GetString(key)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("--%s: %w", key, err)
+	}
+	return d, nil
+}
+
+// GetRate parses the transfer rate stored under the key in the configuration.
+// Unset keys yield a zero rate.
+func GetRate(key string) (ByteSize, error) {
+	value := settings.GetString(key)
+	if value == "" {
+		return 0, nil
+	}
+	rate, err := ParseRate(value)
+	if err != nil {
+		return 0, fmt.Errorf("--%s: %w", key, err)
+	}
+	return rate, nil
+}
+
+// GetByteSize parses the size stored under the key in the configuration.
+// Unset keys yield a zero size.
+func GetByteSize(key string) (ByteSize, error) {
+	value := settings.GetString(key)
+	if value == "" {
+		return 0, nil
+	}
+	size, err := ParseByteSize(value)
+	if err != nil {
+		return 0, fmt.Errorf("--%s: %w", key, err)
+	}
+	return size, nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestParseByteSize(t *testing.T) {
+	testCases := []struct {
+		name      string
+		value     string
+		expected  ByteSize
+		expectErr bool
+	}{
+		{name: "plain bytes", value: "512", expected: 512},
+		{name: "bytes suffix", value: "512B", expected: 512},
+		{name: "decimal unit", value: "10MB", expected: 10 * 1000 * 1000},
+		{name: "binary unit", value: "10MiB", expected: 10 << 20},
+		{name: "kubernetes quantity", value: "512Ki", expected: 512 << 10},
+		{name: "lower case", value: "1gib", expected: 1 << 30},
+		{name: "fraction", value: "1.5GiB", expected: 3 << 29},
+		{name: "unknown unit", value: "10XB", expectErr: true},
+		{name: "comma separator", value: "1,5GiB", expectErr: true},
+		{name: "missing number", value: "MiB", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			size, err := ParseByteSize(tc.value)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, size)
+			}
+		})
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	rate, err := ParseRate("10MiB/s")
+	assert.NoError(t, err)
+	assert.Equal(t, ByteSize(10<<20), rate)
+
+	_, err = ParseRate("fast")
+	assert.EqualError(t, err, `invalid rate "fast": expected a value such as 512Ki/s or 10MiB/s`)
+}
+
+func TestParseDuration(t *testing.T) {
+	testCases := []struct {
+		name      string
+		value     string
+		expected  time.Duration
+		expectErr bool
+	}{
+		{name: "go duration", value: "2h30m", expected: 150 * time.Minute},
+		{name: "days", value: "1d", expected: 24 * time.Hour},
+		{name: "days and hours", value: "1d12h", expected: 36 * time.Hour},
+		{name: "missing unit", value: "90", expectErr: true},
+		{name: "negative", value: "-1h", expectErr: true},
+		{name: "invalid days", value: "xd", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := ParseDuration(tc.value)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, d)
+			}
+		})
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	size := ByteSize(10 << 20)
+	assert.Equal(t, "10MiB", size.String())
+	size = ByteSize(1500)
+	assert.Equal(t, "1500B", size.String())
+}
+
+func TestDurationFlag(t *testing.T) {
+	settings.Reset()
+	defer settings.Reset()
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	DurationFlag(flags, "timeout", 0, "")
+	DurationFlag(flags, "expected-duration", 2*time.Hour, "")
+	DurationFlag(flags, "echo-interval", 0, "")
+	for _, name := range []string{"timeout", "expected-duration", "echo-interval"} {
+		assert.NoError(t, settings.BindPFlag(name, flags.Lookup(name)))
+	}
+	assert.NoError(t, flags.Parse([]string{"--timeout=1d"}))
+	t.Setenv("HYDROPHONE_EXPECTED_DURATION", "1d12h")
+	settings.SetEnvPrefix(EnvPrefix)
+	settings.SetEnvKeyReplacer(EnvKeyReplacer)
+	settings.AutomaticEnv()
+	settings.SetConfigType("yaml")
+	assert.NoError(t, settings.ReadConfig(strings.NewReader("echo-interval: 2d\n")))
+
+	timeout, err := GetDuration("timeout")
+	assert.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, timeout)
+	expected, err := GetDuration("expected-duration")
+	assert.NoError(t, err)
+	assert.Equal(t, 36*time.Hour, expected)
+	interval, err := GetDuration("echo-interval")
+	assert.NoError(t, err)
+	assert.Equal(t, 48*time.Hour, interval)
+
+	assert.Error(t, flags.Parse([]string{"--timeout=1x"}))
+	assert.Equal(t, "0", flags.Lookup("echo-interval").DefValue)
+	assert.Equal(t, "2h0m0s", flags.Lookup("expected-duration").DefValue)
+}
+
+func TestDurationVar(t *testing.T) {
+	var wait time.Duration
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	DurationVar(flags, &wait, "wait", 5*time.Minute, "")
+	assert.Equal(t, 5*time.Minute, wait)
+	assert.NoError(t, flags.Parse([]string{"--wait=1d"}))
+	assert.Equal(t, 24*time.Hour, wait)
+}
+
+func TestGetRate(t *testing.T) {
+	settings.Reset()
+	defer settings.Reset()
+
+	settings.Set("transfer-rate-limit", "10MiB/s")
+	rate, err := GetRate("transfer-rate-limit")
+	assert.NoError(t, err)
+	assert.Equal(t, ByteSize(10<<20), rate)
+
+	settings.Set("transfer-rate-limit", "2MiB")
+	rate, err = GetRate("transfer-rate-limit")
+	assert.NoError(t, err)
+	assert.Equal(t, ByteSize(2<<20), rate)
+
+	settings.Set("transfer-rate-limit", "fast")
+	_, err = GetRate("transfer-rate-limit")
+	assert.EqualError(t, err, `--transfer-rate-limit: invalid rate "fast": expected a value such as 512Ki/s or 10MiB/s`)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package export pushes the results of a conformance run to test management
+// systems.
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+// Mapping maps the names of e2e tests to the IDs of the test cases in the
+// test management system.
+type Mapping map[string]string
+
+// Result is the outcome of a mapped test case
+type Result struct {
+	// CaseID is the ID of the test case in the test management system
+	CaseID string
+	// Name is the name of the e2e test
+	Name    string
+	Status  string
+	Time    time.Duration
+	Message string
+}
+
+var httpClient = &http.Client{Timeout: time.Minute}
+
+// LoadMapping reads a yaml file mapping test names to test case IDs.
+func LoadMapping(path string) (Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := Mapping{}
+	if err := yaml.UnmarshalStrict(data, &m); err != nil {
+		return nil, fmt.Errorf("error parsing mapping file %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// MappedResults returns the results of the executed tests of the report that
+// have a test case ID in the mapping. Skipped tests and tests without a test
+// case are left out.
+func MappedResults(report *results.JUnitTestSuites, mapping Mapping) []Result {
+	var mapped []Result
+	for _, s := range report.TestSuites {
+		for _, tc := range s.TestCases {
+			// names in junit reports carry the ginkgo node type
+			name := strings.TrimPrefix(tc.Name, "[It] ")
+			caseID, ok := mapping[name]
+			if !ok || tc.Status == results.StatusSkipped || tc.Status == results.StatusPending {
+				continue
+			}
+			r := Result{
+				CaseID: caseID,
+				Name:   name,
+				Status: tc.Status,
+				Time:   time.Duration(tc.Time * float64(time.Second)),
+			}
+			for _, m := range []*results.JUnitMessage{tc.Failure, tc.Error} {
+				if m != nil {
+					r.Status = results.StatusFailed
+					r.Message = m.Message
+					break
+				}
+			}
+			mapped = append(mapped, r)
+		}
+	}
+	return mapped
+}
+
+// postJSON sends body as JSON and decodes the JSON response into out, if not nil.
+func postJSON(req *http.Request, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
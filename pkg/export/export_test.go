@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+var report = &results.JUnitTestSuites{TestSuites: []results.JUnitTestSuite{{
+	TestCases: []results.JUnitTestCase{
+		{Name: "[It] [sig-node] passing test", Status: results.StatusPassed, Time: 1.2},
+		{Name: "[It] [sig-node] failing test", Status: results.StatusFailed, Failure: &results.JUnitMessage{Message: "timed out"}},
+		{Name: "[It] [sig-node] skipped test", Status: results.StatusSkipped},
+		{Name: "[It] [sig-node] unmapped test", Status: results.StatusPassed},
+	},
+}}}
+
+var mapping = Mapping{
+	"[sig-node] passing test": "C1",
+	"[sig-node] failing test": "C2",
+	"[sig-node] skipped test": "C3",
+}
+
+func TestMappedResults(t *testing.T) {
+	assert.Equal(t, []Result{
+		{CaseID: "C1", Name: "[sig-node] passing test", Status: results.StatusPassed, Time: 1200 * time.Millisecond},
+		{CaseID: "C2", Name: "[sig-node] failing test", Status: results.StatusFailed, Message: "timed out"},
+	}, MappedResults(report, mapping))
+}
+
+func TestTestRailExport(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, key, _ := r.BasicAuth()
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "key", key)
+		assert.Equal(t, "/api/v2/add_results_for_cases/42", r.URL.RawQuery)
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	tr := &TestRail{URL: server.URL, User: "user", APIKey: "key", RunID: 42}
+	require.NoError(t, tr.Export(MappedResults(report, mapping)))
+	assert.JSONEq(t, `{"results": [
+		{"case_id": 1, "status_id": 1, "elapsed": "2s"},
+		{"case_id": 2, "status_id": 5, "comment": "timed out"}
+	]}`, string(body))
+}
+
+func TestXrayExport(t *testing.T) {
+	var execution xrayExecution
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/authenticate":
+			w.Write([]byte(`"token"`))
+		case "/api/v2/import/execution":
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&execution))
+			w.Write([]byte(`{"key": "CONF-7"}`))
+		}
+	}))
+	defer server.Close()
+
+	mapping := Mapping{"[sig-node] passing test": "CONF-1", "[sig-node] failing test": "CONF-2"}
+	x := &Xray{URL: server.URL, ProjectKey: "CONF"}
+	key, err := x.Export(MappedResults(report, mapping))
+	require.NoError(t, err)
+	assert.Equal(t, "CONF-7", key)
+	assert.Equal(t, []xrayTest{
+		{TestKey: "CONF-1", Status: "PASSED"},
+		{TestKey: "CONF-2", Status: "FAILED", Comment: "timed out"},
+	}, execution.Tests)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+// TestRail status IDs of the default statuses
+const (
+	testRailPassed = 1
+	testRailFailed = 5
+)
+
+// TestRail exports results to a test run of TestRail
+type TestRail struct {
+	// URL is the base URL of the TestRail instance, e.g. https://example.testrail.io
+	URL string
+	// User and APIKey authenticate against the API
+	User   string
+	APIKey string
+	// RunID is the ID of the test run the results are added to
+	RunID int
+}
+
+type testRailResult struct {
+	CaseID   int    `json:"case_id"`
+	StatusID int    `json:"status_id"`
+	Comment  string `json:"comment,omitempty"`
+	Elapsed  string `json:"elapsed,omitempty"`
+}
+
+// Export adds the results to the test run. Case IDs can be given with or
+// without the C prefix TestRail displays.
+func (t *TestRail) Export(rs []Result) error {
+	var payload struct {
+		Results []testRailResult `json:"results"`
+	}
+	for _, r := range rs {
+		caseID, err := strconv.Atoi(strings.TrimPrefix(r.CaseID, "C"))
+		if err != nil {
+			return fmt.Errorf("invalid TestRail case ID [%s] of test [%s]", r.CaseID, r.Name)
+		}
+		result := testRailResult{CaseID: caseID, StatusID: testRailPassed, Comment: r.Message}
+		if r.Status == results.StatusFailed {
+			result.StatusID = testRailFailed
+		}
+		// TestRail rejects elapsed times below one second
+		if seconds := int(math.Ceil(r.Time.Seconds())); seconds > 0 {
+			result.Elapsed = fmt.Sprintf("%ds", seconds)
+		}
+		payload.Results = append(payload.Results, result)
+	}
+
+	url := fmt.Sprintf("%s/index.php?/api/v2/add_results_for_cases/%d", strings.TrimSuffix(t.URL, "/"), t.RunID)
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.User, t.APIKey)
+	if err := postJSON(req, payload, nil); err != nil {
+		return fmt.Errorf("error adding results to TestRail run %d: %w", t.RunID, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+// DefaultXrayURL is the API endpoint of Xray cloud
+const DefaultXrayURL = "https://xray.cloud.getxray.app"
+
+// Xray exports results as a new test execution of Jira Xray cloud
+type Xray struct {
+	// URL is the base URL of the Xray API
+	URL string
+	// ClientID and ClientSecret are the credentials of an Xray API key
+	ClientID     string
+	ClientSecret string
+	// ProjectKey is the Jira project the test execution is created in
+	ProjectKey string
+	// TestPlanKey optionally links the test execution to a test plan
+	TestPlanKey string
+	// Summary is the summary of the test execution
+	Summary string
+}
+
+type xrayExecution struct {
+	Info struct {
+		Project     string `json:"project,omitempty"`
+		Summary     string `json:"summary,omitempty"`
+		TestPlanKey string `json:"testPlanKey,omitempty"`
+	} `json:"info"`
+	Tests []xrayTest `json:"tests"`
+}
+
+type xrayTest struct {
+	TestKey string `json:"testKey"`
+	Status  string `json:"status"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// Export creates a test execution holding the results and returns its key.
+func (x *Xray) Export(rs []Result) (string, error) {
+	baseURL := strings.TrimSuffix(x.URL, "/")
+
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/api/v2/authenticate", nil)
+	if err != nil {
+		return "", err
+	}
+	var token string
+	credentials := map[string]string{"client_id": x.ClientID, "client_secret": x.ClientSecret}
+	if err := postJSON(req, credentials, &token); err != nil {
+		return "", fmt.Errorf("error authenticating to Xray: %w", err)
+	}
+
+	execution := xrayExecution{}
+	execution.Info.Project = x.ProjectKey
+	execution.Info.Summary = x.Summary
+	execution.Info.TestPlanKey = x.TestPlanKey
+	for _, r := range rs {
+		test := xrayTest{TestKey: r.CaseID, Status: "PASSED", Comment: r.Message}
+		if r.Status == results.StatusFailed {
+			test.Status = "FAILED"
+		}
+		execution.Tests = append(execution.Tests, test)
+	}
+
+	req, err = http.NewRequest(http.MethodPost, baseURL+"/api/v2/import/execution", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := postJSON(req, execution, &created); err != nil {
+		return "", fmt.Errorf("error importing test execution to Xray: %w", err)
+	}
+	return created.Key, nil
+}
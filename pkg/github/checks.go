@@ -0,0 +1,271 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package github surfaces the results of runs in GitHub check runs and in the
+// annotations of GitHub Actions workflows.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/notify"
+)
+
+// DefaultAPIURL is the URL of the API of github.com
+const DefaultAPIURL = "https://api.github.com"
+
+// Limits of the API on the output of check runs
+const (
+	maxAnnotations = 50
+	maxSummary     = 65535
+	maxTitle       = 255
+	maxMessage     = 64 * 1024
+)
+
+// annotationPath is the file the failed tests are annotated on, the tests
+// aren't part of the repository of the check run
+const annotationPath = "e2e.log"
+
+// Output is the output of a check run shown on the pull request
+type Output struct {
+	Title       string       `json:"title"`
+	Summary     string       `json:"summary"`
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+// Annotation highlights a failed test in the check run
+type Annotation struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Level     string `json:"annotation_level"`
+	Title     string `json:"title,omitempty"`
+	Message   string `json:"message"`
+}
+
+// FailureAnnotation returns the annotation of a failed test with the message
+// it failed with
+func FailureAnnotation(test, message string) Annotation {
+	if message == "" {
+		message = "the test failed, see e2e.log"
+	}
+	return Annotation{
+		Path:      annotationPath,
+		StartLine: 1,
+		EndLine:   1,
+		Level:     "failure",
+		Title:     truncate(test, maxTitle),
+		Message:   truncate(message, maxMessage),
+	}
+}
+
+// Check is a check run of a commit of a repository
+type Check struct {
+	APIURL string
+	Token  string
+	// Repo is the repository, e.g. kubernetes-sigs/hydrophone
+	Repo   string
+	SHA    string
+	Name   string
+	Client *http.Client
+
+	id int64
+}
+
+// Create creates the check run in progress
+func (c *Check) Create(ctx context.Context, output Output) error {
+	body := map[string]any{
+		"name":       c.Name,
+		"head_sha":   c.SHA,
+		"status":     "in_progress",
+		"started_at": time.Now().UTC().Format(time.RFC3339),
+		"output":     output,
+	}
+	var created struct {
+		ID      int64  `json:"id"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/check-runs", c.Repo), body, &created); err != nil {
+		return fmt.Errorf("unable to create check run %q on %s@%s: %w", c.Name, c.Repo, c.SHA, err)
+	}
+	c.id = created.ID
+	return nil
+}
+
+// Update reports the progress of the check run
+func (c *Check) Update(ctx context.Context, output Output) error {
+	return c.update(ctx, map[string]any{"status": "in_progress", "output": output})
+}
+
+// Complete completes the check run with the conclusion. The annotations are
+// sent in batches of the size accepted by the API.
+func (c *Check) Complete(ctx context.Context, conclusion string, output Output) error {
+	annotations := output.Annotations
+	batch := func() []Annotation {
+		n := min(len(annotations), maxAnnotations)
+		b := annotations[:n]
+		annotations = annotations[n:]
+		return b
+	}
+	output.Annotations = batch()
+	body := map[string]any{
+		"status":       "completed",
+		"conclusion":   conclusion,
+		"completed_at": time.Now().UTC().Format(time.RFC3339),
+		"output":       output,
+	}
+	if err := c.update(ctx, body); err != nil {
+		return err
+	}
+	// annotations of further updates are appended to the ones of the check run
+	for len(annotations) > 0 {
+		output.Annotations = batch()
+		if err := c.update(ctx, map[string]any{"output": output}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Check) update(ctx context.Context, body map[string]any) error {
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/check-runs/%d", c.Repo, c.id), body, nil); err != nil {
+		return fmt.Errorf("unable to update check run %q on %s@%s: %w", c.Name, c.Repo, c.SHA, err)
+	}
+	return nil
+}
+
+// do sends the JSON body to the API and decodes the response into out
+func (c *Check) do(ctx context.Context, method, path string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.APIURL, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Conclusion returns the conclusion of the check run of a run with the status
+func Conclusion(status string) string {
+	switch status {
+	case notify.StatusPassed:
+		return "success"
+	case notify.StatusAborted:
+		return "cancelled"
+	default:
+		return "failure"
+	}
+}
+
+// Summary returns the output of the check run summarizing the run
+func Summary(p *notify.Payload, annotations []Annotation) Output {
+	title := fmt.Sprintf("%d passed, %d failed, %d skipped", p.Counts.Passed, p.Counts.Failed, p.Counts.Skipped)
+	if p.Status == notify.StatusAborted {
+		title = "Aborted: " + p.Reason
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Status | %s |\n", p.Status)
+	if p.ServerVersion != "" {
+		fmt.Fprintf(&b, "| Server version | %s |\n", p.ServerVersion)
+	}
+	if c := p.Cluster; c != nil {
+		if c.Name != "" {
+			fmt.Fprintf(&b, "| Cluster | %s |\n", c.Name)
+		}
+		if c.Provider != "" {
+			fmt.Fprintf(&b, "| Provider | %s |\n", c.Provider)
+		}
+		if len(c.CNI) != 0 {
+			fmt.Fprintf(&b, "| CNI | %s |\n", strings.Join(c.CNI, ", "))
+		}
+		nodes := strconv.Itoa(c.NodeCount)
+		if len(c.Nodes) != 0 {
+			var groups []string
+			for _, group := range c.Nodes {
+				groups = append(groups, group.String())
+			}
+			nodes += " (" + strings.Join(groups, ", ") + ")"
+		}
+		fmt.Fprintf(&b, "| Nodes | %s |\n", nodes)
+	}
+	fmt.Fprintf(&b, "| Duration | %s |\n", time.Duration(p.Duration)*time.Second)
+	if p.Artifacts.Push != "" {
+		fmt.Fprintf(&b, "| Artifacts | %s |\n", p.Artifacts.Push)
+	} else if p.Artifacts.Upload != "" {
+		fmt.Fprintf(&b, "| Artifacts | %s |\n", p.Artifacts.Upload)
+	}
+	if len(p.FailedTests) > 0 {
+		b.WriteString("\n### Failed tests\n\n")
+		for _, test := range p.FailedTests {
+			fmt.Fprintf(&b, "- %s\n", test)
+		}
+	}
+	if len(p.Leaked) > 0 {
+		b.WriteString("\n### Cluster hygiene\n\nResources the tests left behind:\n\n")
+		kinds := make([]string, 0, len(p.Leaked))
+		for kind := range p.Leaked {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			for _, name := range p.Leaked[kind] {
+				fmt.Fprintf(&b, "- %s %s\n", kind, name)
+			}
+		}
+	}
+	return Output{Title: truncate(title, maxTitle), Summary: truncate(b.String(), maxSummary), Annotations: annotations}
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
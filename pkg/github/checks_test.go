@@ -0,0 +1,141 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/notify"
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+func TestCheck(t *testing.T) {
+	var requests []string
+	var batches []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		var body struct {
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			Output     Output `json:"output"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		switch r.Method {
+		case http.MethodPost:
+			assert.Equal(t, "in_progress", body.Status)
+			fmt.Fprint(w, `{"id": 42}`)
+		case http.MethodPatch:
+			batches = append(batches, len(body.Output.Annotations))
+		}
+	}))
+	defer server.Close()
+
+	c := &Check{APIURL: server.URL, Token: "token", Repo: "example/clusters", SHA: "abc", Name: "conformance"}
+	require.NoError(t, c.Create(context.Background(), Output{Title: "Setting up"}))
+	require.NoError(t, c.Update(context.Background(), Output{Title: "Running"}))
+
+	p := &notify.Payload{Status: notify.StatusFailed}
+	var annotations []Annotation
+	for i := 0; i < 120; i++ {
+		name := fmt.Sprintf("test %d", i)
+		p.FailedTests = append(p.FailedTests, name)
+		annotations = append(annotations, FailureAnnotation(name, ""))
+	}
+	require.NoError(t, c.Complete(context.Background(), Conclusion(p.Status), Summary(p, annotations)))
+
+	assert.Equal(t, []string{
+		"POST /repos/example/clusters/check-runs",
+		"PATCH /repos/example/clusters/check-runs/42",
+		"PATCH /repos/example/clusters/check-runs/42",
+		"PATCH /repos/example/clusters/check-runs/42",
+		"PATCH /repos/example/clusters/check-runs/42",
+	}, requests)
+	assert.Equal(t, []int{0, 50, 50, 20}, batches)
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		name       string
+		p          *notify.Payload
+		conclusion string
+		title      string
+	}{
+		{
+			name:       "passed",
+			p:          &notify.Payload{Status: notify.StatusPassed, Counts: notify.Counts{Passed: 400, Skipped: 7000}},
+			conclusion: "success",
+			title:      "400 passed, 0 failed, 7000 skipped",
+		},
+		{
+			name:       "failed",
+			p:          &notify.Payload{Status: notify.StatusFailed, Counts: notify.Counts{Passed: 399, Failed: 1}, FailedTests: []string{"[sig-node] Pods"}},
+			conclusion: "failure",
+			title:      "399 passed, 1 failed, 0 skipped",
+		},
+		{
+			name:       "aborted",
+			p:          &notify.Payload{Status: notify.StatusAborted, Reason: "interrupted by interrupt"},
+			conclusion: "cancelled",
+			title:      "Aborted: interrupted by interrupt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.conclusion, Conclusion(tt.p.Status))
+			output := Summary(tt.p, nil)
+			assert.Equal(t, tt.title, output.Title)
+			for _, test := range tt.p.FailedTests {
+				assert.Contains(t, output.Summary, "- "+test+"\n")
+			}
+		})
+	}
+}
+
+func TestSummaryCluster(t *testing.T) {
+	p := &notify.Payload{
+		Status: notify.StatusPassed,
+		Cluster: &results.Cluster{
+			Name:      "prod",
+			Provider:  "aws",
+			CNI:       []string{"cilium"},
+			NodeCount: 3,
+			Nodes:     []results.NodeGroup{{OS: "linux", Arch: "amd64", KubeletVersion: "v1.30.0", Count: 3}},
+		},
+	}
+	summary := Summary(p, nil).Summary
+	assert.Contains(t, summary, "| Cluster | prod |\n")
+	assert.Contains(t, summary, "| Provider | aws |\n")
+	assert.Contains(t, summary, "| CNI | cilium |\n")
+	assert.Contains(t, summary, "| Nodes | 3 (3x linux/amd64 v1.30.0) |\n")
+}
+
+func TestSummaryHygiene(t *testing.T) {
+	p := &notify.Payload{
+		Status: notify.StatusPassed,
+		Leaked: map[string][]string{"persistentvolume": {"pv-1"}, "namespace": {"pods-1234"}},
+	}
+	assert.Contains(t, Summary(p, nil).Summary, "### Cluster hygiene\n\nResources the tests left behind:\n\n- namespace pods-1234\n- persistentvolume pv-1\n")
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxCommandMessage caps the failure message of a workflow command, the
+// whole message is in the junit report
+const maxCommandMessage = 4 * 1024
+
+var (
+	// dataEscaper escapes the message of a workflow command
+	dataEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	// propertyEscaper escapes the properties of a workflow command
+	propertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// InActions reports whether hydrophone runs in a GitHub Actions workflow
+func InActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// WriteError writes the ::error workflow command of a failed test with the
+// message it failed with, which GitHub Actions shows as an annotation of the
+// workflow run.
+func WriteError(w io.Writer, test, message string) error {
+	if message == "" {
+		message = "the test failed, see e2e.log"
+	}
+	_, err := fmt.Fprintf(w, "::error title=%s::%s\n",
+		propertyEscaper.Replace(truncate(test, maxTitle)), dataEscaper.Replace(truncate(message, maxCommandMessage)))
+	return err
+}
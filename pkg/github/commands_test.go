@@ -0,0 +1,40 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteError(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteError(&buf, "[sig-network] DNS should resolve: cluster, service", "timed out after 100%\nwaiting for the pod"))
+	require.NoError(t, WriteError(&buf, "[sig-node] Pods should run", ""))
+	assert.Equal(t, "::error title=[sig-network] DNS should resolve%3A cluster%2C service::timed out after 100%25%0Awaiting for the pod\n"+
+		"::error title=[sig-node] Pods should run::the test failed, see e2e.log\n", buf.String())
+}
+
+func TestInActions(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "")
+	assert.False(t, InActions())
+	t.Setenv("GITHUB_ACTIONS", "true")
+	assert.True(t, InActions())
+}
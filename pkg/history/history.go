@@ -0,0 +1,284 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+// SchemaVersion is the version of the layout of the archives written by
+// Export. Import refuses archives of a newer version.
+const SchemaVersion = 1
+
+// manifestName is the name of the first entry of an archive
+const manifestName = "manifest.json"
+
+// runIDFormat is the time format of the directory names of the runs
+const runIDFormat = "20060102T150405Z"
+
+// Files lists the artifacts of the output directory kept for every run.
+var Files = []string{results.MetadataFile, "junit_01.xml", "e2e.log", "e2e.log.gz"}
+
+// Manifest describes the content of an archive.
+type Manifest struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Created       time.Time `json:"created"`
+	Runs          []string  `json:"runs"`
+}
+
+// DefaultDir returns the directory of the history store.
+func DefaultDir() string {
+	return filepath.Join(xdg.DataHome, "hydrophone", "history")
+}
+
+// Runs returns the IDs of the runs in the store, oldest first.
+func Runs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var runs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			runs = append(runs, entry.Name())
+		}
+	}
+	sort.Strings(runs)
+	return runs, nil
+}
+
+// Record copies the artifacts of a run from the output directory to the
+// store and returns the ID of the run.
+func Record(dir, outputDir string, now time.Time) (string, error) {
+	id := now.UTC().Format(runIDFormat)
+	runDir := filepath.Join(dir, id)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(runDir); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		runDir = filepath.Join(dir, fmt.Sprintf("%s-%d", id, i))
+	}
+	if err := os.MkdirAll(runDir, 0700); err != nil {
+		return "", err
+	}
+	names := append([]string{}, Files...)
+	// the chunks of an e2e.log split with --max-log-size
+	chunks, err := filepath.Glob(filepath.Join(outputDir, "e2e.log.[0-9]*"))
+	if err != nil {
+		return "", err
+	}
+	for _, chunk := range chunks {
+		names = append(names, filepath.Base(chunk))
+	}
+	for _, name := range names {
+		if err := copyFile(filepath.Join(outputDir, name), filepath.Join(runDir, name)); errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return filepath.Base(runDir), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Export writes all runs of the store as a gzip compressed tar archive. The
+// archive starts with a manifest holding the schema version, the artifacts of
+// each run follow under runs/<id>/.
+func Export(dir string, w io.Writer, now time.Time) (int, error) {
+	runs, err := Runs(dir)
+	if err != nil {
+		return 0, err
+	}
+	gz, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return 0, err
+	}
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(Manifest{SchemaVersion: SchemaVersion, Created: now.UTC(), Runs: runs}, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := writeEntry(tw, manifestName, now, int64(len(manifest)), strings.NewReader(string(manifest))); err != nil {
+		return 0, err
+	}
+
+	for _, run := range runs {
+		entries, err := os.ReadDir(filepath.Join(dir, run))
+		if err != nil {
+			return 0, err
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			if err := exportFile(tw, filepath.Join(dir, run, entry.Name()), path.Join("runs", run, entry.Name())); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return 0, err
+	}
+	return len(runs), gz.Close()
+}
+
+func exportFile(tw *tar.Writer, src, name string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return writeEntry(tw, name, info.ModTime(), info.Size(), f)
+}
+
+func writeEntry(tw *tar.Writer, name string, modTime time.Time, size int64, r io.Reader) error {
+	hdr := &tar.Header{Name: name, Mode: 0600, Size: size, ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("error writing %s to archive: %w", name, err)
+	}
+	if _, err := io.Copy(tw, r); err != nil {
+		return fmt.Errorf("error writing %s to archive: %w", name, err)
+	}
+	return nil
+}
+
+// Import adds the runs of an archive written by Export to the store. Runs
+// already in the store are left untouched. It returns the number of imported
+// runs.
+func Import(dir string, r io.Reader) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("not a history archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return 0, fmt.Errorf("not a history archive: missing %s", manifestName)
+	}
+	manifest := Manifest{}
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return 0, fmt.Errorf("error decoding %s: %w", manifestName, err)
+	}
+	if manifest.SchemaVersion < 1 || manifest.SchemaVersion > SchemaVersion {
+		return 0, fmt.Errorf("unsupported history archive schema version %d, expected at most %d", manifest.SchemaVersion, SchemaVersion)
+	}
+
+	existing, err := Runs(dir)
+	if err != nil {
+		return 0, err
+	}
+	skip := map[string]bool{}
+	for _, run := range existing {
+		skip[run] = true
+	}
+
+	imported := map[string]bool{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return len(imported), fmt.Errorf("error reading history archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		run, name, ok := runFile(hdr.Name)
+		if !ok {
+			return len(imported), fmt.Errorf("unexpected entry %q in history archive", hdr.Name)
+		}
+		if skip[run] {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(dir, run), 0700); err != nil {
+			return len(imported), err
+		}
+		if err := writeFile(filepath.Join(dir, run, name), tr); err != nil {
+			return len(imported), err
+		}
+		imported[run] = true
+	}
+	return len(imported), nil
+}
+
+// runFile splits an archive entry of the form runs/<id>/<name>, rejecting
+// names that would escape the run directory
+func runFile(name string) (string, string, bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 || parts[0] != "runs" {
+		return "", "", false
+	}
+	for _, part := range parts[1:] {
+		if part == "" || part == "." || part == ".." || strings.ContainsAny(part, `\:`) {
+			return "", "", false
+		}
+	}
+	return parts[1], parts[2], true
+}
+
+func writeFile(dst string, r io.Reader) error {
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
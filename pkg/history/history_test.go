@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImport(t *testing.T) {
+	outputDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, "results.json"), []byte(`{"exitCode": 0}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, "junit_01.xml"), []byte("<testsuites/>"), 0600))
+
+	src := t.TempDir()
+	now := time.Date(2024, 2, 12, 15, 4, 5, 0, time.UTC)
+	id, err := Record(src, outputDir, now)
+	require.NoError(t, err)
+	assert.Equal(t, "20240212T150405Z", id)
+	id, err = Record(src, outputDir, now)
+	require.NoError(t, err)
+	assert.Equal(t, "20240212T150405Z-1", id)
+
+	archive := &bytes.Buffer{}
+	n, err := Export(src, archive, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	dst := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dst, "20240212T150405Z"), 0700))
+	n, err = Import(dst, bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	runs, err := Runs(dst)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20240212T150405Z", "20240212T150405Z-1"}, runs)
+	data, err := os.ReadFile(filepath.Join(dst, "20240212T150405Z-1", "junit_01.xml"))
+	require.NoError(t, err)
+	assert.Equal(t, "<testsuites/>", string(data))
+}
+
+func TestImportRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries map[string]string
+		wantErr string
+	}{
+		{
+			name:    "newer schema",
+			entries: map[string]string{manifestName: `{"schemaVersion": 2}`},
+			wantErr: "unsupported history archive schema version 2, expected at most 1",
+		},
+		{
+			name:    "path traversal",
+			entries: map[string]string{manifestName: `{"schemaVersion": 1}`, "runs/../../etc/passwd": ""},
+			wantErr: `unexpected entry "runs/../../etc/passwd" in history archive`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := &bytes.Buffer{}
+			gz := gzip.NewWriter(archive)
+			tw := tar.NewWriter(gz)
+			// the manifest has to come first
+			for _, name := range []string{manifestName, "runs/../../etc/passwd"} {
+				content, ok := tt.entries[name]
+				if !ok {
+					continue
+				}
+				require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+				_, err := tw.Write([]byte(content))
+				require.NoError(t, err)
+			}
+			require.NoError(t, tw.Close())
+			require.NoError(t, gz.Close())
+
+			_, err := Import(t.TempDir(), archive)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hydrophone runs conformance tests from Go programs.
+//
+// A Runner runs the tests with the flags of its options. By default the run is
+// in process, with the code of the hydrophone command from pkg/cli and
+// settings of its own, kept apart from the configuration of the program and
+// of the previous runs. The runs in process share the state of the command,
+// so they are serialized: WithExecutable runs a hydrophone process instead,
+// for several runs to be in progress at the same time.
+package hydrophone
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/cli"
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+// Progress counts the specs of a run as they complete
+type Progress struct {
+	Total     int64
+	Completed int64
+	Passed    int64
+	Failed    int64
+	Skipped   int64
+}
+
+// Result is the outcome of a run
+type Result struct {
+	// ExitCode is the exit code of the run, 0 when the tests passed
+	ExitCode int
+	// OutputDir is the directory the artifacts of the run were written to
+	OutputDir string
+	// Metadata is the content of results.json, nil when the run ended before
+	// writing it
+	Metadata *results.Metadata
+}
+
+// Runner runs conformance tests, in process or with a hydrophone process
+type Runner struct {
+	executable       string
+	kubeconfig       string
+	kubeContext      string
+	namespace        string
+	outputDir        string
+	conformance      bool
+	focus            string
+	skip             string
+	conformanceImage string
+	parallel         int
+	timeout          time.Duration
+	upload           string
+	args             []string
+	output           io.Writer
+	progress         func(Progress)
+}
+
+// Option configures a Runner
+type Option func(*Runner)
+
+// New returns a Runner configured with the options. Without WithExecutable,
+// the tests run in process.
+func New(opts ...Option) *Runner {
+	r := &Runner{outputDir: "."}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// WithExecutable runs the tests with the hydrophone executable at path
+// instead of in process, so that the run isn't serialized with the other runs
+// of the program
+func WithExecutable(path string) Option {
+	return func(r *Runner) { r.executable = path }
+}
+
+// WithKubeconfig sets the kubeconfig of the cluster under test
+func WithKubeconfig(path string) Option {
+	return func(r *Runner) { r.kubeconfig = path }
+}
+
+// WithContext sets the context of the kubeconfig, its current context by
+// default
+func WithContext(context string) Option {
+	return func(r *Runner) { r.kubeContext = context }
+}
+
+// WithNamespace sets the namespace the conformance pods are created in
+func WithNamespace(namespace string) Option {
+	return func(r *Runner) { r.namespace = namespace }
+}
+
+// WithOutputDir sets the directory the artifacts of the run are written to,
+// the working directory by default
+func WithOutputDir(dir string) Option {
+	return func(r *Runner) { r.outputDir = dir }
+}
+
+// WithConformance runs the conformance tests
+func WithConformance() Option {
+	return func(r *Runner) { r.conformance = true }
+}
+
+// WithFocus runs the tests matching the regular expression
+func WithFocus(focus string) Option {
+	return func(r *Runner) { r.focus = focus }
+}
+
+// WithSkip skips the tests matching the regular expression
+func WithSkip(skip string) Option {
+	return func(r *Runner) { r.skip = skip }
+}
+
+// WithConformanceImage sets the conformance image, by tag or by digest
+func WithConformanceImage(image string) Option {
+	return func(r *Runner) { r.conformanceImage = image }
+}
+
+// WithParallel sets the number of parallel test processes
+func WithParallel(parallel int) Option {
+	return func(r *Runner) { r.parallel = parallel }
+}
+
+// WithTimeout sets the deadline of the run, after which it's aborted with its
+// partial results collected
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Runner) { r.timeout = timeout }
+}
+
+// WithUpload uploads the artifacts of the run to remote storage, e.g.
+// s3://bucket/prefix
+func WithUpload(url string) Option {
+	return func(r *Runner) { r.upload = url }
+}
+
+// WithArgs appends flags of the hydrophone command not covered by the other
+// options, e.g. "--config", "hydrophone.yaml"
+func WithArgs(args ...string) Option {
+	return func(r *Runner) { r.args = append(r.args, args...) }
+}
+
+// WithOutput sets the writer the output and the log of the run are written
+// to, discarded by default
+func WithOutput(w io.Writer) Option {
+	return func(r *Runner) { r.output = w }
+}
+
+// WithProgress sets the function called as specs complete
+func WithProgress(progress func(Progress)) Option {
+	return func(r *Runner) { r.progress = progress }
+}
+
+// Args returns the arguments hydrophone is run with. The results are read
+// from the output directory, so hydrophone is kept from moving them to
+// another directory next to it.
+func (r *Runner) Args() []string {
+	args := []string{"--output-dir", r.outputDir, "--force"}
+	if r.kubeconfig != "" {
+		args = append(args, "--kubeconfig", r.kubeconfig)
+	}
+	if r.kubeContext != "" {
+		args = append(args, "--context", r.kubeContext)
+	}
+	if r.namespace != "" {
+		args = append(args, "--namespace", r.namespace)
+	}
+	if r.conformance {
+		args = append(args, "--conformance")
+	}
+	if r.focus != "" {
+		args = append(args, "--focus", r.focus)
+	}
+	if r.skip != "" {
+		args = append(args, "--skip", r.skip)
+	}
+	if r.conformanceImage != "" {
+		args = append(args, "--conformance-image", r.conformanceImage)
+	}
+	if r.parallel > 0 {
+		args = append(args, "--parallel", strconv.Itoa(r.parallel))
+	}
+	if r.timeout > 0 {
+		args = append(args, "--timeout", r.timeout.String())
+	}
+	if r.upload != "" {
+		args = append(args, "--upload", r.upload)
+	}
+	return append(args, r.args...)
+}
+
+// Run runs the tests until the run completes or ctx is canceled. On
+// cancellation the run is interrupted, so that it collects the partial
+// results and deletes its resources, and ctx.Err() is returned along with the
+// result. A run that completed with failed tests is not an error, its exit
+// code is reported in the result.
+func (r *Runner) Run(ctx context.Context) (*Result, error) {
+	outputDir, err := filepath.Abs(r.outputDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating output directory [%s] : %w", outputDir, err)
+	}
+
+	output := r.output
+	if output == nil {
+		output = io.Discard
+	}
+	run := *r
+	run.outputDir = outputDir
+	reader, writer := io.Pipe()
+	scanned := make(chan struct{})
+	go func() {
+		defer close(scanned)
+		var specs client.SpecProgress
+		scanner := bufio.NewScanner(io.TeeReader(reader, output))
+		for scanner.Scan() {
+			if r.progress == nil {
+				continue
+			}
+			specs.Add(scanner.Text())
+			r.progress(Progress{
+				Total:     specs.Total.Load(),
+				Completed: specs.Completed(),
+				Passed:    specs.Passed.Load(),
+				Failed:    specs.Failed.Load(),
+				Skipped:   specs.Skipped.Load(),
+			})
+		}
+		// keep the run from blocking on a line too long for the scanner
+		_, _ = io.Copy(output, reader)
+	}()
+
+	var exitCode int
+	if r.executable != "" {
+		exitCode, err = runProcess(ctx, r.executable, run.Args(), writer)
+	} else {
+		exitCode = cli.Run(ctx, run.Args(), writer)
+	}
+	writer.Close()
+	<-scanned
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{ExitCode: exitCode, OutputDir: outputDir}
+	if metadata, err := results.ReadMetadata(outputDir); err == nil {
+		result.Metadata = metadata
+	}
+	return result, ctx.Err()
+}
+
+// runProcess runs the hydrophone executable with the arguments and returns
+// its exit code. The process is interrupted when ctx is canceled.
+func runProcess(ctx context.Context, executable string, args []string, w io.Writer) (int, error) {
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout, cmd.Stderr = w, w
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := cmd.Process.Signal(os.Interrupt); err != nil {
+				_ = cmd.Process.Kill()
+			}
+		case <-exited:
+		}
+	}()
+	err := cmd.Wait()
+	close(exited)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hydrophone
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		args []string
+	}{
+		{
+			name: "defaults",
+			args: []string{"--output-dir", ".", "--force"},
+		},
+		{
+			name: "conformance",
+			opts: []Option{
+				WithOutputDir("results"),
+				WithKubeconfig("kubeconfig"),
+				WithContext("prod"),
+				WithNamespace("conformance"),
+				WithConformance(),
+				WithParallel(4),
+				WithTimeout(6 * time.Hour),
+			},
+			args: []string{"--output-dir", "results", "--force", "--kubeconfig", "kubeconfig", "--context", "prod", "--namespace", "conformance", "--conformance", "--parallel", "4", "--timeout", "6h0m0s"},
+		},
+		{
+			name: "focus",
+			opts: []Option{
+				WithFocus(`\[sig-node\]`),
+				WithSkip("Slow"),
+				WithConformanceImage("registry.k8s.io/conformance:v1.30.0"),
+				WithUpload("s3://bucket/prefix"),
+				WithArgs("--verbosity", "6"),
+			},
+			args: []string{"--output-dir", ".", "--force", "--focus", `\[sig-node\]`, "--skip", "Slow", "--conformance-image", "registry.k8s.io/conformance:v1.30.0", "--upload", "s3://bucket/prefix", "--verbosity", "6"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.args, New(tt.opts...).Args())
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake hydrophone is a shell script")
+	}
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "hydrophone")
+	script := `#!/bin/sh
+while [ "$1" != "--output-dir" ]; do shift; done
+echo "Will run 2 of 7000 specs"
+echo "• [1.234 seconds]"
+echo "• [FAILED] [2.345 seconds]"
+echo '{"schemaVersion": 1, "exitCode": 1}' > "$2/results.json"
+exit 1
+`
+	require.NoError(t, os.WriteFile(executable, []byte(script), 0755))
+
+	var output strings.Builder
+	var progress []Progress
+	result, err := New(
+		WithExecutable(executable),
+		WithOutputDir(filepath.Join(dir, "results")),
+		WithConformance(),
+		WithOutput(&output),
+		WithProgress(func(p Progress) { progress = append(progress, p) }),
+	).Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, filepath.Join(dir, "results"), result.OutputDir)
+	require.NotNil(t, result.Metadata)
+	assert.Equal(t, 1, result.Metadata.ExitCode)
+	assert.Contains(t, output.String(), "Will run 2 of 7000 specs")
+	require.NotEmpty(t, progress)
+	assert.Equal(t, Progress{Total: 2, Completed: 2, Passed: 1, Failed: 1}, progress[len(progress)-1])
+}
+
+func TestRunCanceled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake hydrophone is a shell script")
+	}
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "hydrophone")
+	script := `#!/bin/sh
+trap 'echo interrupted; exit 130' INT
+while true; do sleep 0.1; done
+`
+	require.NoError(t, os.WriteFile(executable, []byte(script), 0755))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	var output strings.Builder
+	result, err := New(WithExecutable(executable), WithOutputDir(dir), WithOutput(&output)).Run(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NotNil(t, result)
+	assert.Equal(t, 130, result.ExitCode)
+	assert.Nil(t, result.Metadata)
+	assert.Contains(t, output.String(), "interrupted")
+}
+
+func TestRunInProcess(t *testing.T) {
+	dir := t.TempDir()
+	run := func(outputDir string, opts ...Option) (*Result, string) {
+		t.Helper()
+		var output strings.Builder
+		opts = append(opts, WithOutputDir(filepath.Join(dir, outputDir)), WithOutput(&output))
+		result, err := New(opts...).Run(context.Background())
+		require.NoError(t, err)
+		return result, output.String()
+	}
+
+	result, output := run("nightly", WithConformance(), WithNamespace("e2e-nightly"),
+		WithConformanceImage("registry.k8s.io/conformance:v1.30.0"), WithArgs("--dry-run"))
+	assert.Equal(t, 0, result.ExitCode, output)
+	manifests, err := os.ReadFile(filepath.Join(dir, "nightly", "manifests.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(manifests), "namespace: e2e-nightly")
+	assert.Contains(t, output, "Manifests written to")
+
+	// the settings of the previous run don't carry over
+	result, output = run("weekly", WithConformance(), WithConformanceImage("registry.k8s.io/conformance:v1.30.0"), WithArgs("--dry-run"))
+	assert.Equal(t, 0, result.ExitCode, output)
+	manifests, err = os.ReadFile(filepath.Join(dir, "weekly", "manifests.yaml"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(manifests), "e2e-nightly")
+
+	// the failures of the run end the run, not the program
+	result, output = run("failed", WithConformance(), WithArgs("--dry-run"))
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, output, "--dry-run doesn't query the version of the cluster")
+	result, output = run("invalid", WithArgs("--no-such-flag"))
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Contains(t, output, "unknown flag: --no-such-flag")
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kind creates and deletes throwaway kind clusters to run the
+// conformance tests against, with the kind binary found in PATH.
+package kind
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// NodeImageRepository is the repository of the node images published for the
+// Kubernetes releases by kind
+const NodeImageRepository = "kindest/node"
+
+// Cluster is a kind cluster
+type Cluster struct {
+	// Name is the name of the cluster
+	Name string
+	// Workers is the number of worker nodes next to the control plane node
+	Workers int
+	// Image is the node image, the default one of the kind binary when empty
+	Image string
+	// Wait is how long to wait for the control plane to be ready
+	Wait time.Duration
+	// Kind is the path of the kind binary, looked up in PATH when empty
+	Kind string
+	// Output is the writer the output of kind is written to, discarded by
+	// default
+	Output io.Writer
+}
+
+// NodeImage returns the node image of the Kubernetes version, e.g. v1.29.1
+func NodeImage(version string) string {
+	return NodeImageRepository + ":v" + strings.TrimPrefix(version, "v")
+}
+
+// Config returns the kind configuration of the cluster, one control plane node
+// and the worker nodes.
+func (c *Cluster) Config() ([]byte, error) {
+	if c.Workers < 0 {
+		return nil, fmt.Errorf("expected a non-negative number of worker nodes, got %d", c.Workers)
+	}
+	type node struct {
+		Role  string `json:"role"`
+		Image string `json:"image,omitempty"`
+	}
+	nodes := []node{{Role: "control-plane", Image: c.Image}}
+	for i := 0; i < c.Workers; i++ {
+		nodes = append(nodes, node{Role: "worker", Image: c.Image})
+	}
+	return yaml.Marshal(map[string]interface{}{
+		"kind":       "Cluster",
+		"apiVersion": "kind.x-k8s.io/v1alpha4",
+		"nodes":      nodes,
+	})
+}
+
+// Create creates the cluster and writes its kubeconfig to the given path.
+func (c *Cluster) Create(ctx context.Context, kubeconfig string) error {
+	config, err := c.Config()
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "hydrophone-kind")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "kind.yaml")
+	if err := os.WriteFile(configFile, config, 0644); err != nil {
+		return err
+	}
+
+	args := []string{"create", "cluster",
+		"--name", c.Name,
+		"--config", configFile,
+		"--kubeconfig", kubeconfig}
+	if c.Wait > 0 {
+		args = append(args, "--wait", c.Wait.String())
+	}
+	if err := c.run(ctx, args...); err != nil {
+		return fmt.Errorf("error creating kind cluster %s: %w", c.Name, err)
+	}
+	return nil
+}
+
+// Delete deletes the cluster.
+func (c *Cluster) Delete(ctx context.Context) error {
+	if err := c.run(ctx, "delete", "cluster", "--name", c.Name); err != nil {
+		return fmt.Errorf("error deleting kind cluster %s: %w", c.Name, err)
+	}
+	return nil
+}
+
+func (c *Cluster) run(ctx context.Context, args ...string) error {
+	kind := c.Kind
+	if kind == "" {
+		path, err := exec.LookPath("kind")
+		if err != nil {
+			return fmt.Errorf("kind is required, see https://kind.sigs.k8s.io: %w", err)
+		}
+		kind = path
+	}
+	output := c.Output
+	if output == nil {
+		output = io.Discard
+	}
+	cmd := exec.CommandContext(ctx, kind, args...)
+	cmd.Stdout, cmd.Stderr = output, output
+	return cmd.Run()
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kind
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeImage(t *testing.T) {
+	assert.Equal(t, "kindest/node:v1.29.1", NodeImage("v1.29.1"))
+	assert.Equal(t, "kindest/node:v1.29.1", NodeImage("1.29.1"))
+}
+
+func TestConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster Cluster
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "control plane only",
+			cluster: Cluster{Name: "test"},
+			want: `apiVersion: kind.x-k8s.io/v1alpha4
+kind: Cluster
+nodes:
+- role: control-plane
+`,
+		},
+		{
+			name:    "workers with image",
+			cluster: Cluster{Name: "test", Workers: 2, Image: "kindest/node:v1.29.1"},
+			want: `apiVersion: kind.x-k8s.io/v1alpha4
+kind: Cluster
+nodes:
+- image: kindest/node:v1.29.1
+  role: control-plane
+- image: kindest/node:v1.29.1
+  role: worker
+- image: kindest/node:v1.29.1
+  role: worker
+`,
+		},
+		{
+			name:    "negative workers",
+			cluster: Cluster{Name: "test", Workers: -1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := tt.cluster.Config()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(config))
+		})
+	}
+}
+
+func TestCreateDelete(t *testing.T) {
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	kind := filepath.Join(dir, "kind")
+	require.NoError(t, os.WriteFile(kind, []byte("#!/bin/sh\necho \"$@\" >> "+calls+"\n"), 0755))
+
+	cluster := Cluster{Name: "hydrophone", Workers: 1, Wait: 5 * time.Minute, Kind: kind}
+	kubeconfig := filepath.Join(dir, "kubeconfig")
+	require.NoError(t, cluster.Create(context.Background(), kubeconfig))
+	require.NoError(t, cluster.Delete(context.Background()))
+
+	data, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^create cluster --name hydrophone --config \S+/kind.yaml --kubeconfig `+kubeconfig+` --wait 5m0s$`, lines[0])
+	assert.Equal(t, "delete cluster --name hydrophone", lines[1])
+
+	require.NoError(t, os.WriteFile(kind, []byte("#!/bin/sh\nexit 1\n"), 0755))
+	assert.ErrorContains(t, cluster.Create(context.Background(), kubeconfig), "error creating kind cluster hydrophone")
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"bytes"
+	"log/slog"
+	"sync"
+)
+
+// maxHistorySize is the size of the log records kept in memory, the oldest
+// records are dropped beyond it
+const maxHistorySize = 8 << 20
+
+// history keeps the log records of the process for the diagnostics of failed
+// runs
+var history = &historyBuffer{max: maxHistorySize}
+
+// KeepHistory keeps a copy of the log records in memory, returned by History.
+func KeepHistory() {
+	history.Reset()
+	AddHandler(slog.NewTextHandler(history, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+// History returns the log records since KeepHistory was called.
+func History() []byte {
+	return history.Bytes()
+}
+
+// historyBuffer is an io.Writer keeping the last max bytes written to it, at
+// line boundaries
+type historyBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	max int
+}
+
+func (h *historyBuffer) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.buf.Write(p)
+	if excess := h.buf.Len() - h.max; excess > 0 {
+		data := h.buf.Bytes()[excess:]
+		if i := bytes.IndexByte(data, '\n'); i != -1 {
+			data = data[i+1:]
+		}
+		data = bytes.Clone(data)
+		h.buf.Reset()
+		h.buf.Write(data)
+	}
+	return len(p), nil
+}
+
+func (h *historyBuffer) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buf.Reset()
+}
+
+func (h *historyBuffer) Bytes() []byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return bytes.Clone(h.buf.Bytes())
+}
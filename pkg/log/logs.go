@@ -17,51 +17,138 @@ limitations under the License.
 package log
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"runtime"
+	"sync"
 	"time"
 
 	"github.com/mattn/go-isatty"
 
 	"github.com/lmittmann/tint"
+
+	"sigs.k8s.io/hydrophone/pkg/redact"
+)
+
+var (
+	mu sync.Mutex
+	// logger is the logger of hydrophone, kept apart from the default logger
+	// of slog so that the programs running hydrophone from Go keep theirs
+	logger = slog.New(newTerminalHandler(os.Stderr))
+	// output is the writer of logger
+	output io.Writer = os.Stderr
+	// run is the run in process of RunInProcess, nil otherwise
+	run *inProcess
+	// embedded is set once hydrophone ran in process, Exit never ends the
+	// process of the program running it from then on
+	embedded bool
 )
 
-func init() {
-	// set global logger with custom options
-	slog.SetDefault(slog.New(
-		tint.NewHandler(os.Stderr, &tint.Options{
-			Level:      slog.LevelDebug,
-			TimeFormat: time.TimeOnly,
-			NoColor:    !isatty.IsTerminal(os.Stderr.Fd()),
-		}),
-	))
+// inProcess is the state of a run in process
+type inProcess struct {
+	exited   bool
+	exitCode int
+}
+
+// newTerminalHandler returns the handler writing the log records to w,
+// colored when w is a terminal
+func newTerminalHandler(w io.Writer) slog.Handler {
+	f, ok := w.(*os.File)
+	return tint.NewHandler(w, &tint.Options{
+		Level:      slog.LevelDebug,
+		TimeFormat: time.TimeOnly,
+		NoColor:    !ok || !isatty.IsTerminal(f.Fd()),
+	})
+}
+
+// RunInProcess calls f in a new goroutine with the log written to w and
+// returns the exit code passed to Exit, 0 when f returns. Exit and Fatal end
+// the goroutine calling them instead of the process, and the other goroutines
+// of the run end at their next log record, as they would with the process.
+// The log is written to the terminal again once f's goroutine ended. The runs
+// in process don't overlap, the callers serialize them.
+func RunInProcess(w io.Writer, f func()) int {
+	r := &inProcess{}
+	mu.Lock()
+	previous, previousOutput := logger, output
+	logger, output, run, embedded = slog.New(newTerminalHandler(w)), w, r, true
+	mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	logger, output, run = previous, previousOutput, nil
+	return r.exitCode
+}
+
+// Exit ends the process with the exit code. In a run in process, it ends the
+// run instead, see RunInProcess.
+func Exit(code int) {
+	mu.Lock()
+	if run != nil && !run.exited {
+		run.exited, run.exitCode = true, code
+	}
+	inProcess := embedded
+	mu.Unlock()
+	if !inProcess {
+		os.Exit(code)
+	}
+	runtime.Goexit()
+}
+
+// record logs the message, unless the run in process exited, in which case
+// the goroutine ends
+func record(level slog.Level, msg string) {
+	mu.Lock()
+	l, exited := logger, run != nil && run.exited
+	mu.Unlock()
+	if exited {
+		runtime.Goexit()
+	}
+	l.Log(context.Background(), level, redact.String(msg))
 }
 
 // Fatal logs an error message from the given arguments and exits the program.
 func Fatal(v ...any) {
-	slog.Error(fmt.Sprint(v...))
-	os.Exit(1)
+	record(slog.LevelError, fmt.Sprint(v...))
+	Exit(1)
 }
 
 // Fatalf logs an error message with formatted output and exits the program.
 func Fatalf(format string, v ...any) {
-	slog.Error(fmt.Sprintf(format, v...))
-	os.Exit(1)
+	record(slog.LevelError, fmt.Sprintf(format, v...))
+	Exit(1)
 }
 
 // Printf logs an info message with formatted output.
 func Printf(format string, v ...any) {
-	slog.Info(fmt.Sprintf(format, v...))
+	record(slog.LevelInfo, fmt.Sprintf(format, v...))
+}
+
+// Output returns the writer the log is written to, stderr unless in a run in
+// process.
+func Output() io.Writer {
+	mu.Lock()
+	defer mu.Unlock()
+	return output
 }
 
 // Print logs for API
 func PrintfAPI(format string, v ...interface{}) {
-	fmt.Print("\n")
-	slog.Info(fmt.Sprintf(format, v...))
+	fmt.Fprint(Output(), "\n")
+	record(slog.LevelInfo, fmt.Sprintf(format, v...))
 }
 
 // Println logs an info message from the given arguments.
 func Println(v ...any) {
-	slog.Info(fmt.Sprint(v...))
+	record(slog.LevelInfo, fmt.Sprint(v...))
 }
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunInProcess(t *testing.T) {
+	var output strings.Builder
+	exitCode := RunInProcess(&output, func() {
+		Printf("running %s", "nightly")
+		Fatal("run failed")
+		Println("not logged")
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, output.String(), "running nightly")
+	assert.Contains(t, output.String(), "run failed")
+	assert.NotContains(t, output.String(), "not logged")
+	assert.Equal(t, os.Stderr, Output())
+
+	assert.Equal(t, 0, RunInProcess(&output, func() { Println("done") }))
+
+	// the other goroutines of the run end at their next log record
+	output.Reset()
+	exitCode = RunInProcess(&output, func() {
+		exited := make(chan struct{})
+		go func() {
+			defer close(exited)
+			Exit(3)
+		}()
+		<-exited
+		Exit(0)
+	})
+	assert.Equal(t, 3, exitCode)
+	exitCode = RunInProcess(&output, func() {
+		exited := make(chan struct{})
+		go func() {
+			defer close(exited)
+			Exit(4)
+		}()
+		<-exited
+		Println("not logged")
+	})
+	assert.Equal(t, 4, exitCode)
+	assert.NotContains(t, output.String(), "not logged")
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hydrophone.log")
+	w := &rotatingFile{path: path, maxSize: 10, maxBackups: 2}
+	require.NoError(t, w.open())
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := w.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	for file, expected := range map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	} {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(data), file)
+	}
+	assert.NoFileExists(t, path+".3")
+}
+
+func TestHistoryBuffer(t *testing.T) {
+	h := &historyBuffer{max: 12}
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		_, err := h.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "third\n", string(h.Bytes()))
+}
@@ -0,0 +1,87 @@
+//go:build !windows && !plan9
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"log/syslog"
+	"net"
+	"strings"
+)
+
+// journaldSocket is the socket of the native journald protocol
+const journaldSocket = "/run/systemd/journal/socket"
+
+// NewSyslogHandler returns a handler sending records to syslog. An empty
+// network and address use the local syslog daemon.
+func NewSyslogHandler(network, address string) (slog.Handler, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, "hydrophone")
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to syslog: %w", err)
+	}
+	return &lineHandler{emit: func(level slog.Level, line string) error {
+		switch {
+		case level >= slog.LevelError:
+			return w.Err(line)
+		case level >= slog.LevelWarn:
+			return w.Warning(line)
+		case level >= slog.LevelInfo:
+			return w.Info(line)
+		default:
+			return w.Debug(line)
+		}
+	}}, nil
+}
+
+// NewJournaldHandler returns a handler sending records to the systemd journal
+// using its native protocol.
+func NewJournaldHandler() (slog.Handler, error) {
+	conn, err := net.Dial("unixgram", journaldSocket)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to journald: %w", err)
+	}
+	return &lineHandler{emit: func(level slog.Level, line string) error {
+		priority := syslog.LOG_INFO
+		switch {
+		case level >= slog.LevelError:
+			priority = syslog.LOG_ERR
+		case level >= slog.LevelWarn:
+			priority = syslog.LOG_WARNING
+		case level < slog.LevelInfo:
+			priority = syslog.LOG_DEBUG
+		}
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "PRIORITY=%d\nSYSLOG_IDENTIFIER=hydrophone\n", priority)
+		if strings.Contains(line, "\n") {
+			// values containing newlines are sent length-prefixed
+			buf.WriteString("MESSAGE\n")
+			binary.Write(&buf, binary.LittleEndian, uint64(len(line)))
+			buf.WriteString(line)
+			buf.WriteString("\n")
+		} else {
+			fmt.Fprintf(&buf, "MESSAGE=%s\n", line)
+		}
+		_, err := conn.Write(buf.Bytes())
+		return err
+	}}, nil
+}
@@ -0,0 +1,35 @@
+//go:build windows || plan9
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+)
+
+// NewSyslogHandler is not supported on this platform.
+func NewSyslogHandler(_, _ string) (slog.Handler, error) {
+	return nil, fmt.Errorf("syslog is not supported on %s", runtime.GOOS)
+}
+
+// NewJournaldHandler is not supported on this platform.
+func NewJournaldHandler() (slog.Handler, error) {
+	return nil, fmt.Errorf("journald is not supported on %s", runtime.GOOS)
+}
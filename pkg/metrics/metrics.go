@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics exposes metrics of runs in the Prometheus text format,
+// scraped from an HTTP endpoint or pushed to a Pushgateway.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Types of metrics
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+// contentType is the content type of the text exposition format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metric is a single metric whose value is read when it is exposed
+type metric struct {
+	name  string
+	help  string
+	typ   string
+	value func() float64
+}
+
+// Registry holds the metrics exposed
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// Gauge registers a gauge whose value is read from the function
+func (r *Registry) Gauge(name, help string, value func() float64) {
+	r.register(metric{name: name, help: help, typ: TypeGauge, value: value})
+}
+
+// Counter registers a counter whose value is read from the function
+func (r *Registry) Counter(name, help string, value func() float64) {
+	r.register(metric{name: name, help: help, typ: TypeCounter, value: value})
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes the metrics in the text exposition format
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var b bytes.Buffer
+	for _, m := range r.metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n", m.name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(m.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", m.name, m.typ)
+		fmt.Fprintf(&b, "%s %s\n", m.name, strconv.FormatFloat(m.value(), 'g', -1, 64))
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// ServeHTTP serves the metrics
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	_ = r.Write(w)
+}
+
+// Serve serves the metrics on /metrics of the address until the returned
+// function is called.
+func (r *Registry) Serve(addr string) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r)
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	return func() { server.Close() }, nil
+}
+
+// Push replaces the metrics of the job on the Pushgateway at the URL with
+// the ones of the registry.
+func (r *Registry) Push(ctx context.Context, gateway, job string) error {
+	var b bytes.Buffer
+	if err := r.Write(&b); err != nil {
+		return err
+	}
+	endpoint := strings.TrimSuffix(gateway, "/") + "/metrics/job/" + url.PathEscape(job)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, &b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, endpoint, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
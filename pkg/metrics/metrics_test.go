@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRegistry() *Registry {
+	r := &Registry{}
+	completed := 0.0
+	r.Gauge("hydrophone_specs_completed", "Specs that completed.", func() float64 {
+		completed++
+		return completed
+	})
+	r.Counter("hydrophone_reconnects_total", "Reconnects of the\nlog streams.", func() float64 { return 3 })
+	return r
+}
+
+func TestWrite(t *testing.T) {
+	var b strings.Builder
+	r := testRegistry()
+	require.NoError(t, r.Write(&b))
+	assert.Equal(t, `# HELP hydrophone_specs_completed Specs that completed.
+# TYPE hydrophone_specs_completed gauge
+hydrophone_specs_completed 1
+# HELP hydrophone_reconnects_total Reconnects of the\nlog streams.
+# TYPE hydrophone_reconnects_total counter
+hydrophone_reconnects_total 3
+`, b.String())
+
+	// the values are read again on every write
+	b.Reset()
+	require.NoError(t, r.Write(&b))
+	assert.Contains(t, b.String(), "hydrophone_specs_completed 2\n")
+}
+
+func TestPush(t *testing.T) {
+	var body, path, method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		body, path, method = string(data), r.URL.Path, r.Method
+	}))
+	defer server.Close()
+
+	require.NoError(t, testRegistry().Push(context.Background(), server.URL+"/", "hydrophone"))
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/metrics/job/hydrophone", path)
+	assert.Contains(t, body, "hydrophone_reconnects_total 3\n")
+}
+
+func TestServe(t *testing.T) {
+	r := testRegistry()
+	server := httptest.NewServer(r)
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, contentType, resp.Header.Get("Content-Type"))
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# TYPE hydrophone_specs_completed gauge\n")
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxFailedTests is the number of failed tests listed in chat messages,
+// the remaining ones are only counted
+const maxFailedTests = 10
+
+// statusEmoji highlights the status of the run in chat messages
+var statusEmoji = map[string]string{
+	StatusPassed:  "✅",
+	StatusFailed:  "❌",
+	StatusAborted: "⚠️",
+}
+
+// title returns the headline of the run summary
+func (p *Payload) title() string {
+	title := fmt.Sprintf("%s Conformance run %s", statusEmoji[p.Status], p.Status)
+	if p.ServerVersion != "" {
+		title += " on " + p.ServerVersion
+	}
+	return title
+}
+
+// facts returns the details of the run summary as name/value pairs
+func (p *Payload) facts() [][2]string {
+	facts := [][2]string{
+		{"Passed", fmt.Sprint(p.Counts.Passed)},
+		{"Failed", fmt.Sprint(p.Counts.Failed)},
+		{"Skipped", fmt.Sprint(p.Counts.Skipped)},
+		{"Duration", (time.Duration(p.Duration) * time.Second).String()},
+	}
+	if p.Reason != "" {
+		facts = append(facts, [2]string{"Reason", p.Reason})
+	}
+	artifacts := p.Artifacts.OutputDir
+	if p.Artifacts.Push != "" {
+		artifacts = p.Artifacts.Push
+	} else if p.Artifacts.Upload != "" {
+		artifacts = p.Artifacts.Upload
+	}
+	return append(facts, [2]string{"Artifacts", artifacts})
+}
+
+// failedTests returns the list of failed tests, truncated to maxFailedTests
+func (p *Payload) failedTests() []string {
+	if len(p.FailedTests) <= maxFailedTests {
+		return p.FailedTests
+	}
+	tests := append([]string{}, p.FailedTests[:maxFailedTests]...)
+	return append(tests, fmt.Sprintf("and %d more", len(p.FailedTests)-maxFailedTests))
+}
+
+// Slack encodes the payload as a message of a Slack incoming webhook
+func Slack(p *Payload) ([]byte, error) {
+	type text struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	type block struct {
+		Type   string `json:"type"`
+		Text   *text  `json:"text,omitempty"`
+		Fields []text `json:"fields,omitempty"`
+	}
+
+	blocks := []block{{Type: "header", Text: &text{Type: "plain_text", Text: p.title()}}}
+	var fields []text
+	for _, fact := range p.facts() {
+		fields = append(fields, text{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", fact[0], fact[1])})
+	}
+	blocks = append(blocks, block{Type: "section", Fields: fields})
+	if tests := p.failedTests(); len(tests) > 0 {
+		blocks = append(blocks, block{Type: "section", Text: &text{
+			Type: "mrkdwn",
+			Text: "*Failed tests*\n• " + strings.Join(tests, "\n• "),
+		}})
+	}
+	return json.Marshal(map[string]any{"text": p.title(), "blocks": blocks})
+}
+
+// Teams encodes the payload as an adaptive card posted to a Microsoft Teams
+// incoming webhook or workflow
+func Teams(p *Payload) ([]byte, error) {
+	type fact struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+	}
+	var facts []fact
+	for _, f := range p.facts() {
+		facts = append(facts, fact{Title: f[0], Value: f[1]})
+	}
+	color := "Good"
+	if p.Status != StatusPassed {
+		color = "Attention"
+	}
+	body := []map[string]any{
+		{"type": "TextBlock", "text": p.title(), "size": "Large", "weight": "Bolder", "color": color, "wrap": true},
+		{"type": "FactSet", "facts": facts},
+	}
+	if tests := p.failedTests(); len(tests) > 0 {
+		body = append(body,
+			map[string]any{"type": "TextBlock", "text": "Failed tests", "weight": "Bolder"},
+			map[string]any{"type": "TextBlock", "text": "- " + strings.Join(tests, "\n- "), "wrap": true},
+		)
+	}
+	return json.Marshal(map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	})
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func failedPayload(failures int) *Payload {
+	p := &Payload{
+		Status:        StatusFailed,
+		ExitCode:      1,
+		ServerVersion: "v1.30.2",
+		Counts:        Counts{Passed: 390, Failed: failures, Skipped: 7000},
+		Duration:      5530,
+		Artifacts:     Artifacts{OutputDir: "/results", Upload: "s3://bucket/1234"},
+	}
+	for i := 0; i < failures; i++ {
+		p.FailedTests = append(p.FailedTests, fmt.Sprintf("test %d", i))
+	}
+	return p
+}
+
+func TestSlack(t *testing.T) {
+	data, err := Slack(failedPayload(12))
+	require.NoError(t, err)
+	var msg struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Type string `json:"type"`
+			Text *struct {
+				Text string `json:"text"`
+			} `json:"text"`
+			Fields []struct {
+				Text string `json:"text"`
+			} `json:"fields"`
+		} `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal(data, &msg))
+	assert.Equal(t, "❌ Conformance run failed on v1.30.2", msg.Text)
+	require.Len(t, msg.Blocks, 3)
+	assert.Equal(t, "header", msg.Blocks[0].Type)
+	assert.Contains(t, msg.Blocks[1].Fields, struct {
+		Text string `json:"text"`
+	}{Text: "*Duration*\n1h32m10s"})
+	assert.Contains(t, msg.Blocks[1].Fields, struct {
+		Text string `json:"text"`
+	}{Text: "*Artifacts*\ns3://bucket/1234"})
+	assert.Contains(t, msg.Blocks[2].Text.Text, "• test 9\n• and 2 more")
+	assert.NotContains(t, msg.Blocks[2].Text.Text, "test 10")
+}
+
+func TestTeams(t *testing.T) {
+	tests := []struct {
+		name   string
+		p      *Payload
+		color  string
+		blocks int
+	}{
+		{name: "passed", p: &Payload{Status: StatusPassed, Counts: Counts{Passed: 400}}, color: "Good", blocks: 2},
+		{name: "failed", p: failedPayload(1), color: "Attention", blocks: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Teams(tt.p)
+			require.NoError(t, err)
+			var msg struct {
+				Type        string `json:"type"`
+				Attachments []struct {
+					ContentType string `json:"contentType"`
+					Content     struct {
+						Body []map[string]any `json:"body"`
+					} `json:"content"`
+				} `json:"attachments"`
+			}
+			require.NoError(t, json.Unmarshal(data, &msg))
+			assert.Equal(t, "message", msg.Type)
+			require.Len(t, msg.Attachments, 1)
+			assert.Equal(t, "application/vnd.microsoft.card.adaptive", msg.Attachments[0].ContentType)
+			body := msg.Attachments[0].Content.Body
+			assert.Len(t, body, tt.blocks)
+			assert.Equal(t, tt.color, body[0]["color"])
+		})
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notify posts the outcome of runs to webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+)
+
+// Status of a run
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusAborted = "aborted"
+)
+
+// SignatureHeader holds the HMAC-SHA256 of the payload keyed with the
+// secret of the webhook, formatted as sha256=<hex>
+const SignatureHeader = "X-Hydrophone-Signature-256"
+
+// Payload is the JSON document posted to the webhook
+type Payload struct {
+	Status string `json:"status"`
+	// Reason is the reason an aborted run was aborted
+	Reason        string `json:"reason,omitempty"`
+	ExitCode      int    `json:"exitCode"`
+	ServerVersion string `json:"serverVersion,omitempty"`
+	// Cluster describes the cluster the run tested
+	Cluster  *results.Cluster `json:"cluster,omitempty"`
+	Counts   Counts           `json:"counts"`
+	Started  string           `json:"started"`
+	Finished string           `json:"finished"`
+	Duration float64          `json:"durationSeconds"`
+	// Artifacts is where the artifacts of the run are found
+	Artifacts   Artifacts `json:"artifacts"`
+	FailedTests []string  `json:"failedTests,omitempty"`
+	// Leaked lists the resources the tests left behind by kind
+	Leaked map[string][]string `json:"leaked,omitempty"`
+}
+
+// Counts are the numbers of tests by outcome
+type Counts struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// Artifacts are the locations of the artifacts of a run
+type Artifacts struct {
+	OutputDir string `json:"outputDir"`
+	// Upload is the remote storage of --upload
+	Upload string `json:"upload,omitempty"`
+	// Push is the OCI artifact of --push
+	Push string `json:"push,omitempty"`
+}
+
+// SetTimes sets the start, end and duration of the run
+func (p *Payload) SetTimes(started, finished time.Time) {
+	p.Started = started.UTC().Format(time.RFC3339)
+	p.Finished = finished.UTC().Format(time.RFC3339)
+	p.Duration = finished.Sub(started).Round(time.Second).Seconds()
+}
+
+// Formatter encodes the payload in the format expected by a webhook
+type Formatter func(p *Payload) ([]byte, error)
+
+// JSON encodes the payload as is
+func JSON(p *Payload) ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// Webhook posts payloads to a URL
+type Webhook struct {
+	URL string
+	// Format encodes the payloads, JSON when nil
+	Format Formatter
+	// Secret signs the payloads in SignatureHeader when set
+	Secret string
+	// Retries is the number of times a failed post is retried, waiting
+	// Backoff before the first retry and doubling it for every following one
+	Retries int
+	Backoff time.Duration
+	Client  *http.Client
+}
+
+// Send posts the payload to the webhook, retrying on failures. Requests
+// rejected by the webhook with a client error aren't retried.
+func (w *Webhook) Send(ctx context.Context, p *Payload) error {
+	format := w.Format
+	if format == nil {
+		format = JSON
+	}
+	body, err := format(p)
+	if err != nil {
+		return err
+	}
+	delay := w.Backoff
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = w.post(ctx, body)
+		if err == nil || attempt >= w.Retries || !retry {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post posts the body once and returns whether a failure may be retried
+func (w *Webhook) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.Secret, body))
+	}
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+}
+
+// Sign returns the value of SignatureHeader of the body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
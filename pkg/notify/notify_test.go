@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		requests int
+		wantErr  bool
+	}{
+		{name: "delivered", statuses: []int{http.StatusNoContent}, requests: 1},
+		{name: "retried", statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK}, requests: 3},
+		{name: "retries exhausted", statuses: []int{500, 500, 500, 500}, requests: 3, wantErr: true},
+		{name: "rejected", statuses: []int{http.StatusBadRequest, http.StatusOK}, requests: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				assert.Equal(t, Sign("secret", body), r.Header.Get(SignatureHeader))
+				p := &Payload{}
+				require.NoError(t, json.Unmarshal(body, p))
+				assert.Equal(t, StatusFailed, p.Status)
+				assert.Equal(t, []string{"[sig-node] Pods"}, p.FailedTests)
+				w.WriteHeader(tt.statuses[requests])
+				requests++
+			}))
+			defer server.Close()
+
+			w := &Webhook{URL: server.URL, Secret: "secret", Retries: 2, Backoff: time.Millisecond}
+			err := w.Send(context.Background(), &Payload{Status: StatusFailed, ExitCode: 1, FailedTests: []string{"[sig-node] Pods"}})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.requests, requests)
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '{"status":"passed"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=ae6f094765d97d2843445efbc3454a58dfe562d4adc699e0b82b5f26d3b937b7", Sign("secret", []byte(`{"status":"passed"}`)))
+}
+
+func TestSetTimes(t *testing.T) {
+	p := &Payload{}
+	started := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	p.SetTimes(started, started.Add(90*time.Minute+400*time.Millisecond))
+	assert.Equal(t, "2024-05-01T10:00:00Z", p.Started)
+	assert.Equal(t, "2024-05-01T11:30:00Z", p.Finished)
+	assert.Equal(t, 5400.0, p.Duration)
+}
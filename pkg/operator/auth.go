@@ -0,0 +1,380 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+)
+
+// Actions of the RunControl service, authorized per identity
+const (
+	ActionStart  = "start"
+	ActionCancel = "cancel"
+	ActionFetch  = "fetch"
+)
+
+// errUnknownToken is returned by the authenticators for the tokens they don't
+// know of
+var errUnknownToken = errors.New("unknown token")
+
+// Identity is the user a request was authenticated as
+type Identity struct {
+	User   string
+	UID    string
+	Groups []string
+	Extra  map[string][]string
+}
+
+func (i *Identity) String() string {
+	if len(i.Groups) == 0 {
+		return i.User
+	}
+	return fmt.Sprintf("%s (groups %s)", i.User, strings.Join(i.Groups, ", "))
+}
+
+// Authenticator returns the identity of the bearer token of a request
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Identity, error)
+}
+
+// Authorizer tells whether the identity may take the action on the
+// ConformanceRun name of the namespace
+type Authorizer interface {
+	Authorize(ctx context.Context, identity *Identity, action, namespace, name string) (bool, error)
+}
+
+// Authenticators tries each authenticator in turn, the first one knowing the
+// token authenticates the request
+type Authenticators []Authenticator
+
+func (a Authenticators) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	for _, authenticator := range a {
+		identity, err := authenticator.Authenticate(ctx, token)
+		if errors.Is(err, errUnknownToken) {
+			continue
+		}
+		return identity, err
+	}
+	return nil, errUnknownToken
+}
+
+// StaticTokens authenticates the tokens of a static token file
+type StaticTokens map[string]*Identity
+
+// LoadStaticTokens reads a static token file in the format of the
+// --token-auth-file of the Kubernetes API server: a CSV file of lines
+// token,user,uid,"group1,group2", the groups being optional.
+func LoadStaticTokens(file string) (StaticTokens, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	tokens := StaticTokens{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid token file %s: %w", file, err)
+		}
+		line, _ := r.FieldPos(0)
+		if len(record) < 3 || record[0] == "" || record[1] == "" {
+			return nil, fmt.Errorf("invalid token file %s: line %d: expected token,user,uid and optionally \"group1,group2\"", file, line)
+		}
+		if _, ok := tokens[record[0]]; ok {
+			return nil, fmt.Errorf("invalid token file %s: line %d: duplicate token", file, line)
+		}
+		identity := &Identity{User: record[1], UID: record[2]}
+		if len(record) > 3 && record[3] != "" {
+			for _, group := range strings.Split(record[3], ",") {
+				identity.Groups = append(identity.Groups, strings.TrimSpace(group))
+			}
+		}
+		tokens[record[0]] = identity
+	}
+	return tokens, nil
+}
+
+func (s StaticTokens) Authenticate(_ context.Context, token string) (*Identity, error) {
+	identity, ok := s[token]
+	if !ok {
+		return nil, errUnknownToken
+	}
+	return identity, nil
+}
+
+// TokenReview authenticates the tokens with TokenReviews, sent to the API
+// server or to a token review webhook
+type TokenReview struct {
+	// Audiences the tokens must be issued for, the ones of the API server
+	// when empty
+	Audiences []string
+	review    func(ctx context.Context, review *authenticationv1.TokenReview) (*authenticationv1.TokenReview, error)
+}
+
+// NewTokenReview returns an authenticator creating TokenReviews with the API
+// server, like kube-rbac-proxy does. The operator needs to be allowed to
+// create tokenreviews, e.g. with the system:auth-delegator cluster role.
+func NewTokenReview(client kubernetes.Interface, audiences []string) *TokenReview {
+	return &TokenReview{
+		Audiences: audiences,
+		review: func(ctx context.Context, review *authenticationv1.TokenReview) (*authenticationv1.TokenReview, error) {
+			return client.AuthenticationV1().TokenReviews().Create(ctx, review, metav1.CreateOptions{})
+		},
+	}
+}
+
+// NewTokenReviewWebhook returns an authenticator posting TokenReviews to the
+// webhook of the kubeconfig file, in the format of the
+// --authentication-token-webhook-config-file of the Kubernetes API server.
+func NewTokenReviewWebhook(configFile string, audiences []string) (*TokenReview, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", configFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token review webhook config %s: %w", configFile, err)
+	}
+	client, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token review webhook config %s: %w", configFile, err)
+	}
+	return &TokenReview{
+		Audiences: audiences,
+		review: func(ctx context.Context, review *authenticationv1.TokenReview) (*authenticationv1.TokenReview, error) {
+			return postTokenReview(ctx, client, config.Host, review)
+		},
+	}, nil
+}
+
+// postTokenReview posts the review to the webhook and returns its answer
+func postTokenReview(ctx context.Context, client *http.Client, url string, review *authenticationv1.TokenReview) (*authenticationv1.TokenReview, error) {
+	review.APIVersion, review.Kind = authenticationv1.SchemeGroupVersion.String(), "TokenReview"
+	body, err := json.Marshal(review)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("unexpected status %d of the token review webhook: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	result := &authenticationv1.TokenReview{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("invalid answer of the token review webhook: %w", err)
+	}
+	return result, nil
+}
+
+func (t *TokenReview) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	review, err := t.review(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: t.Audiences},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to review the token: %w", err)
+	}
+	if !review.Status.Authenticated {
+		return nil, errUnknownToken
+	}
+	user := review.Status.User
+	identity := &Identity{User: user.Username, UID: user.UID, Groups: user.Groups}
+	if len(user.Extra) != 0 {
+		identity.Extra = map[string][]string{}
+		for key, value := range user.Extra {
+			identity.Extra[key] = value
+		}
+	}
+	return identity, nil
+}
+
+// SubjectAccessReview authorizes the actions with SubjectAccessReviews of
+// the verbs of the ConformanceRuns, so that the roles installed by
+// InstallRoles and their bindings apply to the requests: starting is
+// creating, canceling deleting, and fetching getting or watching. The
+// operator needs to be allowed to create subjectaccessreviews, e.g. with the
+// system:auth-delegator cluster role.
+type SubjectAccessReview struct {
+	Client kubernetes.Interface
+}
+
+// actionVerbs are the verbs of the ConformanceRuns authorizing the actions
+var actionVerbs = map[string]string{
+	ActionStart:  "create",
+	ActionCancel: "delete",
+	ActionFetch:  "get",
+}
+
+func (s *SubjectAccessReview) Authorize(ctx context.Context, identity *Identity, action, namespace, name string) (bool, error) {
+	verb, ok := actionVerbs[action]
+	if !ok {
+		return false, fmt.Errorf("unknown action %q", action)
+	}
+	spec := authorizationv1.SubjectAccessReviewSpec{
+		ResourceAttributes: &authorizationv1.ResourceAttributes{
+			Namespace: namespace,
+			Verb:      verb,
+			Group:     Resource.Group,
+			Version:   Resource.Version,
+			Resource:  Resource.Resource,
+			Name:      name,
+		},
+		User:   identity.User,
+		UID:    identity.UID,
+		Groups: identity.Groups,
+	}
+	// a new run has no name to authorize yet
+	if action == ActionStart {
+		spec.ResourceAttributes.Name = ""
+	}
+	if len(identity.Extra) != 0 {
+		spec.Extra = map[string]authorizationv1.ExtraValue{}
+		for key, value := range identity.Extra {
+			spec.Extra[key] = value
+		}
+	}
+	review, err := s.Client.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{Spec: spec}, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("unable to review the access: %w", err)
+	}
+	return review.Status.Allowed, nil
+}
+
+// PolicyRule allows users or groups to take actions on the ConformanceRuns
+// of namespaces
+type PolicyRule struct {
+	Users  []string `json:"users,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+	// Actions are start, cancel and fetch
+	Actions []string `json:"actions"`
+	// Namespaces are the namespaces of the ConformanceRuns, all of them when
+	// empty
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// Policy authorizes the actions with static rules, an action is allowed
+// when a rule allows it
+type Policy []PolicyRule
+
+// LoadPolicy reads the rules of a policy file, a YAML list of rules
+func LoadPolicy(file string) (Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var policy Policy
+	if err := yaml.UnmarshalStrict(data, &policy); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", file, err)
+	}
+	for i, rule := range policy {
+		if len(rule.Users) == 0 && len(rule.Groups) == 0 {
+			return nil, fmt.Errorf("invalid policy file %s: rule %d has no users or groups", file, i+1)
+		}
+		for _, action := range rule.Actions {
+			if _, ok := actionVerbs[action]; !ok {
+				return nil, fmt.Errorf("invalid policy file %s: rule %d: expected the actions to be %s, %s or %s, got %q", file, i+1, ActionStart, ActionCancel, ActionFetch, action)
+			}
+		}
+	}
+	return policy, nil
+}
+
+func (p Policy) Authorize(_ context.Context, identity *Identity, action, namespace, _ string) (bool, error) {
+	for _, rule := range p {
+		if !slices.Contains(rule.Actions, action) {
+			continue
+		}
+		if len(rule.Namespaces) != 0 && !slices.Contains(rule.Namespaces, namespace) {
+			continue
+		}
+		if slices.Contains(rule.Users, identity.User) || slices.ContainsFunc(rule.Groups, func(group string) bool {
+			return slices.Contains(identity.Groups, group)
+		}) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// authorize authenticates the bearer token of the metadata of the request and
+// checks that its identity may take the action on the ConformanceRun. It
+// allows everything when the server has no authenticator.
+func (s *ControlServer) authorize(ctx context.Context, action, namespace, name string) error {
+	if s.Authenticator == nil {
+		return nil
+	}
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get("authorization") {
+			if scheme, credentials, ok := strings.Cut(value, " "); ok && strings.EqualFold(scheme, "Bearer") {
+				token = strings.TrimSpace(credentials)
+			}
+		}
+	}
+	if token == "" {
+		return status.Error(codes.Unauthenticated, "a bearer token is required")
+	}
+	identity, err := s.Authenticator.Authenticate(ctx, token)
+	if errors.Is(err, errUnknownToken) {
+		return status.Error(codes.Unauthenticated, "invalid bearer token")
+	} else if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if s.Authorizer == nil {
+		return status.Errorf(codes.PermissionDenied, "%s isn't allowed to %s ConformanceRun %s/%s", identity, action, namespace, name)
+	}
+	allowed, err := s.Authorizer.Authorize(ctx, identity, action, namespace, name)
+	if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if !allowed {
+		return status.Errorf(codes.PermissionDenied, "%s isn't allowed to %s ConformanceRun %s/%s", identity, action, namespace, name)
+	}
+	return nil
+}
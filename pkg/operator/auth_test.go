@@ -0,0 +1,256 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/hydrophone/pkg/operator/controlpb"
+)
+
+// writeFile writes the content to a file of a temporary directory
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(file, []byte(content), 0600))
+	return file
+}
+
+func TestLoadStaticTokens(t *testing.T) {
+	file := writeFile(t, "tokens.csv", `# CI and auditors
+ci-token,ci,1001,"runners,auditors"
+audit-token,auditor,1002
+`)
+	tokens, err := LoadStaticTokens(file)
+	require.NoError(t, err)
+
+	identity, err := tokens.Authenticate(context.Background(), "ci-token")
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{User: "ci", UID: "1001", Groups: []string{"runners", "auditors"}}, identity)
+	identity, err = tokens.Authenticate(context.Background(), "audit-token")
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{User: "auditor", UID: "1002"}, identity)
+	_, err = tokens.Authenticate(context.Background(), "other")
+	assert.ErrorIs(t, err, errUnknownToken)
+
+	for content, expected := range map[string]string{
+		"ci-token\n":                        "line 1: expected token,user,uid",
+		"ci-token,ci,1\nci-token,other,2\n": "line 2: duplicate token",
+	} {
+		_, err := LoadStaticTokens(writeFile(t, "tokens.csv", content))
+		assert.ErrorContains(t, err, expected)
+	}
+}
+
+func TestTokenReview(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+	var reviewed *authenticationv1.TokenReview
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviewed = action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		review := reviewed.DeepCopy()
+		if review.Spec.Token == "valid" {
+			review.Status = authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User: authenticationv1.UserInfo{
+					Username: "system:serviceaccount:ci:pipeline",
+					UID:      "1234",
+					Groups:   []string{"system:serviceaccounts"},
+				},
+			}
+		}
+		return true, review, nil
+	})
+	authenticator := NewTokenReview(client, []string{"hydrophone"})
+
+	identity, err := authenticator.Authenticate(context.Background(), "valid")
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{User: "system:serviceaccount:ci:pipeline", UID: "1234", Groups: []string{"system:serviceaccounts"}}, identity)
+	assert.Equal(t, []string{"hydrophone"}, reviewed.Spec.Audiences)
+
+	_, err = authenticator.Authenticate(context.Background(), "invalid")
+	assert.ErrorIs(t, err, errUnknownToken)
+}
+
+func TestTokenReviewWebhook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		review := &authenticationv1.TokenReview{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(review))
+		assert.Equal(t, "TokenReview", review.Kind)
+		review.Status.Authenticated = review.Spec.Token == "valid"
+		if review.Status.Authenticated {
+			review.Status.User = authenticationv1.UserInfo{Username: "alice", Groups: []string{"conformance"}}
+		}
+		json.NewEncoder(w).Encode(review)
+	}))
+	defer server.Close()
+	config := writeFile(t, "webhook.yaml", fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: webhook
+  cluster:
+    server: %s
+users:
+- name: hydrophone
+contexts:
+- name: webhook
+  context:
+    cluster: webhook
+    user: hydrophone
+current-context: webhook
+`, server.URL))
+
+	authenticator, err := NewTokenReviewWebhook(config, nil)
+	require.NoError(t, err)
+	identity, err := authenticator.Authenticate(context.Background(), "valid")
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{User: "alice", Groups: []string{"conformance"}}, identity)
+	_, err = authenticator.Authenticate(context.Background(), "invalid")
+	assert.ErrorIs(t, err, errUnknownToken)
+}
+
+func TestSubjectAccessReview(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+	var reviewed []authorizationv1.ResourceAttributes
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview).DeepCopy()
+		reviewed = append(reviewed, *review.Spec.ResourceAttributes)
+		// alice may only read the runs
+		review.Status.Allowed = review.Spec.User == "alice" && review.Spec.ResourceAttributes.Verb == "get"
+		return true, review, nil
+	})
+	authorizer := &SubjectAccessReview{Client: client}
+	alice := &Identity{User: "alice", Groups: []string{"auditors"}}
+
+	for action, allowed := range map[string]bool{ActionFetch: true, ActionStart: false, ActionCancel: false} {
+		ok, err := authorizer.Authorize(context.Background(), alice, action, "conformance", "nightly")
+		require.NoError(t, err)
+		assert.Equal(t, allowed, ok, action)
+	}
+	verbs := map[string]string{}
+	for _, attributes := range reviewed {
+		assert.Equal(t, "hydrophone.k8s.io", attributes.Group)
+		assert.Equal(t, "conformanceruns", attributes.Resource)
+		assert.Equal(t, "conformance", attributes.Namespace)
+		verbs[attributes.Verb] = attributes.Name
+	}
+	assert.Equal(t, map[string]string{"get": "nightly", "create": "", "delete": "nightly"}, verbs)
+
+	_, err := authorizer.Authorize(context.Background(), alice, "restart", "conformance", "nightly")
+	assert.ErrorContains(t, err, `unknown action "restart"`)
+}
+
+func TestPolicy(t *testing.T) {
+	file := writeFile(t, "policy.yaml", `- groups: [runners]
+  actions: [start, cancel, fetch]
+  namespaces: [conformance]
+- users: [auditor]
+  actions: [fetch]
+`)
+	policy, err := LoadPolicy(file)
+	require.NoError(t, err)
+
+	runner := &Identity{User: "ci", Groups: []string{"runners"}}
+	auditor := &Identity{User: "auditor"}
+	tests := []struct {
+		identity  *Identity
+		action    string
+		namespace string
+		allowed   bool
+	}{
+		{identity: runner, action: ActionStart, namespace: "conformance", allowed: true},
+		{identity: runner, action: ActionCancel, namespace: "conformance", allowed: true},
+		{identity: runner, action: ActionStart, namespace: "default", allowed: false},
+		{identity: auditor, action: ActionFetch, namespace: "default", allowed: true},
+		{identity: auditor, action: ActionCancel, namespace: "conformance", allowed: false},
+		{identity: &Identity{User: "other"}, action: ActionFetch, namespace: "conformance", allowed: false},
+	}
+	for _, tt := range tests {
+		allowed, err := policy.Authorize(context.Background(), tt.identity, tt.action, tt.namespace, "nightly")
+		require.NoError(t, err)
+		assert.Equal(t, tt.allowed, allowed, "%s %s %s", tt.identity.User, tt.action, tt.namespace)
+	}
+
+	for content, expected := range map[string]string{
+		"- actions: [fetch]\n":                  "rule 1 has no users or groups",
+		"- users: [ci]\n  actions: [restart]\n": `got "restart"`,
+		"- users: [ci]\n  verbs: [get]\n":       "unknown field",
+	} {
+		_, err := LoadPolicy(writeFile(t, "policy.yaml", content))
+		assert.ErrorContains(t, err, expected)
+	}
+}
+
+func TestControlServerAuthorization(t *testing.T) {
+	c := newController(t, &fakeRunner{}, newRun(t, "nightly", Status{Phase: PhaseSucceeded, Artifacts: "/artifacts/default/nightly"}))
+	server := &ControlServer{
+		Client: c.Client,
+		Authenticator: Authenticators{StaticTokens{
+			"runner-token":  {User: "ci", Groups: []string{"runners"}},
+			"auditor-token": {User: "auditor"},
+		}},
+		Authorizer: Policy{
+			{Groups: []string{"runners"}, Actions: []string{ActionStart, ActionCancel, ActionFetch}},
+			{Users: []string{"auditor"}, Actions: []string{ActionFetch}},
+		},
+	}
+	client := newControlServerClient(t, server)
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+	fetch := &controlpb.GetArtifactsRequest{Namespace: "default", Name: "nightly"}
+
+	_, err := client.GetArtifacts(context.Background(), fetch)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.GetArtifacts(withToken("other-token"), fetch)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	artifacts, err := client.GetArtifacts(withToken("auditor-token"), fetch)
+	require.NoError(t, err)
+	assert.Equal(t, "/artifacts/default/nightly", artifacts.GetArtifacts())
+	_, err = client.StartRun(withToken("auditor-token"), &controlpb.StartRunRequest{Namespace: "default", Name: "weekly"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.CancelRun(withToken("auditor-token"), &controlpb.CancelRunRequest{Namespace: "default", Name: "nightly"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	stream, err := client.WatchRun(withToken("auditor-token"), &controlpb.WatchRunRequest{Namespace: "default", Name: "nightly"})
+	require.NoError(t, err)
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, PhaseSucceeded, event.GetRun().GetStatus().GetPhase())
+
+	_, err = client.StartRun(withToken("runner-token"), &controlpb.StartRunRequest{Namespace: "default", Name: "weekly"})
+	require.NoError(t, err)
+	_, err = client.CancelRun(withToken("runner-token"), &controlpb.CancelRunRequest{Namespace: "default", Name: "weekly"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// RunControl starts, watches and cancels the ConformanceRuns of a namespace,
+// so that Go programs can drive the runs of an operator with typed calls.
+type RunControl struct {
+	Client    dynamic.Interface
+	Namespace string
+}
+
+// RunEvent is a change of a watched ConformanceRun, Deleted is set once the
+// ConformanceRun went away
+type RunEvent struct {
+	Run     *ConformanceRun
+	Deleted bool
+}
+
+// StartRun creates the ConformanceRun name with spec, the operator starts it
+// once a slot is free.
+func (r *RunControl) StartRun(ctx context.Context, name string, spec Spec) (*ConformanceRun, error) {
+	run := &ConformanceRun{
+		TypeMeta:   metav1.TypeMeta{APIVersion: Resource.GroupVersion().String(), Kind: "ConformanceRun"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: r.Namespace, Name: name},
+		Spec:       spec,
+	}
+	u, err := toUnstructured(run)
+	if err != nil {
+		return nil, err
+	}
+	u, err = r.resource().Create(ctx, u, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create ConformanceRun %s: %w", name, err)
+	}
+	return fromUnstructured(u)
+}
+
+// GetRun returns the ConformanceRun name
+func (r *RunControl) GetRun(ctx context.Context, name string) (*ConformanceRun, error) {
+	u, err := r.resource().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get ConformanceRun %s: %w", name, err)
+	}
+	return fromUnstructured(u)
+}
+
+// CancelRun deletes the ConformanceRun name, the operator aborts the run and
+// deletes its resources before the ConformanceRun goes away. Canceling a run
+// that doesn't exist isn't an error.
+func (r *RunControl) CancelRun(ctx context.Context, name string) error {
+	err := r.resource().Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("unable to delete ConformanceRun %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetArtifacts returns the location of the artifacts of the ConformanceRun
+// name, which is only known once the run finished.
+func (r *RunControl) GetArtifacts(ctx context.Context, name string) (string, error) {
+	run, err := r.GetRun(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if !run.Status.Finished() {
+		return "", fmt.Errorf("ConformanceRun %s hasn't finished, its phase is %q", name, run.Status.Phase)
+	}
+	if run.Status.Artifacts == "" {
+		return "", fmt.Errorf("ConformanceRun %s has no artifacts: %s", name, run.Status.Message)
+	}
+	return run.Status.Artifacts, nil
+}
+
+// WatchRun streams the ConformanceRun name, starting with its current state,
+// until it finished, it's deleted or ctx is canceled. The channel is closed
+// then, and the error of a failed watch is sent on errs before.
+func (r *RunControl) WatchRun(ctx context.Context, name string) (<-chan RunEvent, <-chan error) {
+	events := make(chan RunEvent)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(events)
+		if err := r.watch(ctx, name, events); err != nil && ctx.Err() == nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+	return events, errs
+}
+
+func (r *RunControl) watch(ctx context.Context, name string, events chan<- RunEvent) error {
+	send := func(event RunEvent) bool {
+		select {
+		case events <- event:
+			return !event.Deleted && !event.Run.Status.Finished()
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for {
+		u, err := r.resource().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to get ConformanceRun %s: %w", name, err)
+		}
+		run, err := fromUnstructured(u)
+		if err != nil {
+			return err
+		}
+		if !send(RunEvent{Run: run}) {
+			return nil
+		}
+		w, err := r.resource().Watch(ctx, metav1.ListOptions{
+			FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+			ResourceVersion: u.GetResourceVersion(),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to watch ConformanceRun %s: %w", name, err)
+		}
+		done, err := r.forward(ctx, w, name, send)
+		w.Stop()
+		if done || err != nil {
+			return err
+		}
+		// the API server closed the watch, start again from the current state
+	}
+}
+
+// forward sends the events of the watch until send reports the run
+// finished, or the watch is closed
+func (r *RunControl) forward(ctx context.Context, w watch.Interface, name string, send func(RunEvent) bool) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return true, nil
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return false, nil
+			}
+			if event.Type == watch.Error {
+				return true, fmt.Errorf("unable to watch ConformanceRun %s: %w", name, apierrors.FromObject(event.Object))
+			}
+			u, ok := event.Object.(*unstructured.Unstructured)
+			if !ok || u.GetName() != name {
+				continue
+			}
+			run, err := fromUnstructured(u)
+			if err != nil {
+				return true, err
+			}
+			if !send(RunEvent{Run: run, Deleted: event.Type == watch.Deleted}) {
+				return true, nil
+			}
+		}
+	}
+}
+
+func (r *RunControl) resource() dynamic.ResourceInterface {
+	return r.Client.Resource(Resource).Namespace(r.Namespace)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRunControl(t *testing.T) {
+	c := newController(t, &fakeRunner{})
+	control := &RunControl{Client: c.Client, Namespace: "default"}
+	ctx := context.Background()
+
+	run, err := control.StartRun(ctx, "nightly", Spec{Conformance: true, Parallel: 4})
+	require.NoError(t, err)
+	assert.Equal(t, Spec{Conformance: true, Parallel: 4}, run.Spec)
+
+	_, err = control.GetArtifacts(ctx, "nightly")
+	assert.ErrorContains(t, err, "hasn't finished")
+
+	require.NoError(t, c.updateStatus(ctx, "default", "nightly", func(status *Status) {
+		status.Phase = PhaseSucceeded
+		status.Artifacts = "/artifacts/default/nightly"
+	}))
+	artifacts, err := control.GetArtifacts(ctx, "nightly")
+	require.NoError(t, err)
+	assert.Equal(t, "/artifacts/default/nightly", artifacts)
+
+	require.NoError(t, control.CancelRun(ctx, "nightly"))
+	_, err = control.GetRun(ctx, "nightly")
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.NoError(t, control.CancelRun(ctx, "nightly"))
+}
+
+func TestWatchRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []watch.EventType
+		phases []string
+	}{
+		{
+			name:   "finished",
+			events: []watch.EventType{watch.Modified, watch.Modified},
+			phases: []string{"", PhaseRunning, PhaseSucceeded},
+		},
+		{
+			name:   "deleted",
+			events: []watch.EventType{watch.Modified, watch.Deleted},
+			phases: []string{"", PhaseRunning, PhaseRunning},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newController(t, &fakeRunner{}, newRun(t, "nightly", Status{}), newRun(t, "other", Status{}))
+			watcher := watch.NewFake()
+			c.Client.(*fake.FakeDynamicClient).PrependWatchReactor("conformanceruns", k8stesting.DefaultWatchReactor(watcher, nil))
+			control := &RunControl{Client: c.Client, Namespace: "default"}
+
+			events, errs := control.WatchRun(context.Background(), "nightly")
+			go func() {
+				// the changes of other runs are left out
+				other, _ := toUnstructured(newRun(t, "other", Status{Phase: PhaseFailed}))
+				watcher.Modify(other)
+				running, _ := toUnstructured(newRun(t, "nightly", Status{Phase: PhaseRunning}))
+				watcher.Action(tt.events[0], running)
+				status := Status{Phase: PhaseRunning}
+				if tt.events[1] == watch.Modified {
+					status.Phase = PhaseSucceeded
+				}
+				last, _ := toUnstructured(newRun(t, "nightly", status))
+				watcher.Action(tt.events[1], last)
+			}()
+
+			var phases []string
+			var deleted bool
+			for event := range events {
+				phases = append(phases, event.Run.Status.Phase)
+				deleted = event.Deleted
+			}
+			assert.Equal(t, tt.phases, phases)
+			assert.Equal(t, tt.events[1] == watch.Deleted, deleted)
+			assert.NoError(t, <-errs)
+		})
+	}
+}
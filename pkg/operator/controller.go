@@ -0,0 +1,455 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+)
+
+const (
+	// resyncPeriod is the interval at which every ConformanceRun is reconciled again
+	resyncPeriod = 10 * time.Minute
+	// pendingRecheck is the delay before a run waiting for a free slot is reconciled again
+	pendingRecheck = 30 * time.Second
+	// defaultStatusInterval is the default interval of the progress updates
+	defaultStatusInterval = 10 * time.Second
+)
+
+// Result is the outcome of a run
+type Result struct {
+	ExitCode int
+	// Artifacts is the location of the artifacts of the run
+	Artifacts string
+}
+
+// Runner runs the tests of a ConformanceRun
+type Runner interface {
+	// Run runs the tests of run in namespace until they complete or ctx is
+	// canceled, calling progress as specs complete.
+	Run(ctx context.Context, run *ConformanceRun, namespace string, progress func(Progress)) (Result, error)
+}
+
+// Controller reconciles ConformanceRuns into conformance runs
+type Controller struct {
+	Client dynamic.Interface
+	Runner Runner
+	// Namespace restricts the ConformanceRuns watched, all namespaces when empty
+	Namespace string
+	// MaxRuns is the number of runs in progress at the same time
+	MaxRuns int
+	// StatusInterval is the interval at which the progress is written to the status
+	StatusInterval time.Duration
+
+	queue    workqueue.RateLimitingInterface
+	informer cache.SharedIndexInformer
+
+	mu      sync.Mutex
+	running map[string]*activeRun
+	wg      sync.WaitGroup
+}
+
+// activeRun is a run in progress in this operator
+type activeRun struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Run watches the ConformanceRuns and reconciles them until ctx is canceled,
+// then aborts the runs in progress.
+func (c *Controller) Run(ctx context.Context) error {
+	c.init()
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(c.Client, resyncPeriod, c.Namespace, nil)
+	c.informer = factory.ForResource(Resource).Informer()
+	enqueue := func(obj any) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err == nil {
+			c.queue.Add(key)
+		}
+	}
+	if _, err := c.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, obj any) { enqueue(obj) },
+		DeleteFunc: enqueue,
+	}); err != nil {
+		return err
+	}
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), c.informer.HasSynced) {
+		return fmt.Errorf("unable to sync the ConformanceRuns: %w", ctx.Err())
+	}
+
+	go func() {
+		<-ctx.Done()
+		c.queue.ShutDown()
+	}()
+	for c.processNext(ctx) {
+	}
+
+	c.mu.Lock()
+	for _, active := range c.running {
+		active.cancel()
+	}
+	c.mu.Unlock()
+	c.wg.Wait()
+	return nil
+}
+
+func (c *Controller) init() {
+	if c.MaxRuns < 1 {
+		c.MaxRuns = 1
+	}
+	if c.StatusInterval <= 0 {
+		c.StatusInterval = defaultStatusInterval
+	}
+	if c.queue == nil {
+		c.queue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	}
+	if c.running == nil {
+		c.running = map[string]*activeRun{}
+	}
+}
+
+func (c *Controller) processNext(ctx context.Context) bool {
+	item, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(item)
+	key := item.(string)
+	if err := c.reconcile(ctx, key); err != nil {
+		log.Printf("Failed to reconcile ConformanceRun %s: %v", key, err)
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+// reconcile brings the ConformanceRun named key to its desired state
+func (c *Controller) reconcile(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+	u, err := c.Client.Resource(Resource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		c.abort(key)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	run, err := fromUnstructured(u)
+	if err != nil {
+		return err
+	}
+
+	if run.DeletionTimestamp != nil {
+		c.abort(key)
+		return c.removeFinalizer(ctx, namespace, name)
+	}
+	if run.Status.Finished() {
+		return c.removeFinalizer(ctx, namespace, name)
+	}
+
+	c.mu.Lock()
+	_, active := c.running[key]
+	slots := c.MaxRuns - len(c.running)
+	c.mu.Unlock()
+	if active {
+		return nil
+	}
+	if run.Status.Phase == PhaseRunning {
+		// the run was started by a previous instance of the operator
+		return c.complete(ctx, run, Result{ExitCode: -1}, fmt.Errorf("the operator restarted while the run was in progress"))
+	}
+	if slots <= 0 {
+		c.queue.AddAfter(key, pendingRecheck)
+		return c.updateStatus(ctx, namespace, name, func(status *Status) {
+			status.Phase = PhasePending
+			status.Message = fmt.Sprintf("Waiting for one of the %d runs in progress to complete", c.MaxRuns)
+		})
+	}
+	return c.start(ctx, key, run)
+}
+
+// start sets the finalizer and the Running status of the run, and runs its tests
+func (c *Controller) start(ctx context.Context, key string, run *ConformanceRun) error {
+	if err := c.update(ctx, run.Namespace, run.Name, func(run *ConformanceRun) {
+		if !hasFinalizer(run) {
+			run.Finalizers = append(run.Finalizers, Finalizer)
+		}
+	}); err != nil {
+		return err
+	}
+
+	testNamespace := runNamespace(run)
+	now := metav1.Now()
+	if err := c.updateStatus(ctx, run.Namespace, run.Name, func(status *Status) {
+		status.Phase = PhaseRunning
+		status.Message = ""
+		status.ObservedGeneration = run.Generation
+		status.StartTime = &now
+		status.Namespace = testNamespace
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               ConditionRunning,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Started",
+			Message:            fmt.Sprintf("The tests are running in namespace %s", testNamespace),
+			ObservedGeneration: run.Generation,
+		})
+	}); err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	active := &activeRun{cancel: cancel, done: make(chan struct{})}
+	c.mu.Lock()
+	c.running[key] = active
+	c.mu.Unlock()
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer close(active.done)
+		c.execute(runCtx, key, run, testNamespace)
+	}()
+	return nil
+}
+
+// execute runs the tests and reports their progress and outcome in the status
+func (c *Controller) execute(ctx context.Context, key string, run *ConformanceRun, testNamespace string) {
+	var (
+		mu       sync.Mutex
+		progress Progress
+		reported Progress
+	)
+	ticker := time.NewTicker(c.StatusInterval)
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				mu.Lock()
+				current := progress
+				mu.Unlock()
+				if current == reported {
+					continue
+				}
+				if err := c.updateStatus(ctx, run.Namespace, run.Name, func(status *Status) {
+					status.Progress = current
+				}); err != nil {
+					log.Printf("Failed to update the progress of ConformanceRun %s: %v", key, err)
+					continue
+				}
+				reported = current
+			}
+		}
+	}()
+
+	result, err := c.Runner.Run(ctx, run, testNamespace, func(p Progress) {
+		mu.Lock()
+		progress = p
+		mu.Unlock()
+	})
+	ticker.Stop()
+	close(stop)
+
+	c.mu.Lock()
+	delete(c.running, key)
+	c.mu.Unlock()
+	if ctx.Err() != nil {
+		// the ConformanceRun is being deleted or the operator is stopping
+		return
+	}
+
+	mu.Lock()
+	final := progress
+	mu.Unlock()
+	if err := c.updateStatus(context.Background(), run.Namespace, run.Name, func(status *Status) {
+		status.Progress = final
+	}); err != nil {
+		log.Printf("Failed to update the progress of ConformanceRun %s: %v", key, err)
+	}
+	if err := c.complete(context.Background(), run, result, err); err != nil {
+		log.Printf("Failed to complete ConformanceRun %s: %v", key, err)
+	}
+
+	// a slot is free, reconcile the pending runs
+	for _, pending := range c.informer.GetStore().ListKeys() {
+		c.queue.Add(pending)
+	}
+}
+
+// complete sets the terminal status of the run and removes its finalizer
+func (c *Controller) complete(ctx context.Context, run *ConformanceRun, result Result, runErr error) error {
+	phase, reason, message := PhaseSucceeded, "TestsPassed", "The tests passed"
+	switch {
+	case runErr != nil:
+		phase, reason, message = PhaseFailed, "RunFailed", runErr.Error()
+	case result.ExitCode != 0:
+		phase, reason, message = PhaseFailed, "TestsFailed", fmt.Sprintf("The tests failed with exit code %d", result.ExitCode)
+	}
+	condition := metav1.ConditionTrue
+	if phase == PhaseFailed {
+		condition = metav1.ConditionFalse
+	}
+
+	now := metav1.Now()
+	if err := c.updateStatus(ctx, run.Namespace, run.Name, func(status *Status) {
+		status.Phase = phase
+		status.Message = message
+		status.CompletionTime = &now
+		if runErr == nil {
+			exitCode := result.ExitCode
+			status.ExitCode = &exitCode
+		}
+		if result.Artifacts != "" {
+			status.Artifacts = result.Artifacts
+		}
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               ConditionRunning,
+			Status:             metav1.ConditionFalse,
+			Reason:             "Completed",
+			Message:            "The run completed",
+			ObservedGeneration: run.Generation,
+		})
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               ConditionSucceeded,
+			Status:             condition,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: run.Generation,
+		})
+	}); err != nil {
+		return err
+	}
+	return c.removeFinalizer(ctx, run.Namespace, run.Name)
+}
+
+// abort cancels the run named key if it's in progress and waits for it to stop
+func (c *Controller) abort(key string) {
+	c.mu.Lock()
+	active, ok := c.running[key]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	active.cancel()
+	<-active.done
+}
+
+func (c *Controller) removeFinalizer(ctx context.Context, namespace, name string) error {
+	return c.update(ctx, namespace, name, func(run *ConformanceRun) {
+		finalizers := run.Finalizers[:0]
+		for _, f := range run.Finalizers {
+			if f != Finalizer {
+				finalizers = append(finalizers, f)
+			}
+		}
+		run.Finalizers = finalizers
+	})
+}
+
+// update applies mutate to the ConformanceRun, retrying on conflicts
+func (c *Controller) update(ctx context.Context, namespace, name string, mutate func(*ConformanceRun)) error {
+	return c.modify(ctx, namespace, name, func(run *ConformanceRun) bool {
+		finalizers := append([]string(nil), run.Finalizers...)
+		mutate(run)
+		return !equality.Semantic.DeepEqual(finalizers, run.Finalizers)
+	}, false)
+}
+
+// updateStatus applies mutate to the status of the ConformanceRun, retrying
+// on conflicts
+func (c *Controller) updateStatus(ctx context.Context, namespace, name string, mutate func(*Status)) error {
+	return c.modify(ctx, namespace, name, func(run *ConformanceRun) bool {
+		status := *run.Status.DeepCopy()
+		mutate(&run.Status)
+		return !equality.Semantic.DeepEqual(status, run.Status)
+	}, true)
+}
+
+func (c *Controller) modify(ctx context.Context, namespace, name string, mutate func(*ConformanceRun) bool, status bool) error {
+	resource := c.Client.Resource(Resource).Namespace(namespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		u, err := resource.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		run, err := fromUnstructured(u)
+		if err != nil {
+			return err
+		}
+		if !mutate(run) {
+			return nil
+		}
+		u, err = toUnstructured(run)
+		if err != nil {
+			return err
+		}
+		if status {
+			_, err = resource.UpdateStatus(ctx, u, metav1.UpdateOptions{})
+		} else {
+			_, err = resource.Update(ctx, u, metav1.UpdateOptions{})
+		}
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func hasFinalizer(run *ConformanceRun) bool {
+	for _, f := range run.Finalizers {
+		if f == Finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// runNamespace returns the namespace the tests of run are run in, unique
+// per ConformanceRun and a valid DNS label.
+func runNamespace(run *ConformanceRun) string {
+	ns := strings.ReplaceAll(fmt.Sprintf("hydrophone-%s-%s", run.Namespace, run.Name), ".", "-")
+	if len(ns) <= 63 {
+		return ns
+	}
+	sum := sha256.Sum256([]byte(run.Namespace + "/" + run.Name))
+	return strings.TrimRight(ns[:54], "-") + "-" + hex.EncodeToString(sum[:])[:8]
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+type fakeRunner struct {
+	release chan struct{}
+	result  Result
+	err     error
+}
+
+func (r *fakeRunner) Run(ctx context.Context, _ *ConformanceRun, _ string, progress func(Progress)) (Result, error) {
+	progress(Progress{Total: 2, Completed: 1, Passed: 1})
+	select {
+	case <-r.release:
+		progress(Progress{Total: 2, Completed: 2, Passed: 2})
+		return r.result, r.err
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	}
+}
+
+func newRun(t *testing.T, name string, status Status) *ConformanceRun {
+	t.Helper()
+	return &ConformanceRun{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "hydrophone.k8s.io/v1alpha1", Kind: "ConformanceRun"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Generation: 1},
+		Spec:       Spec{Conformance: true},
+		Status:     status,
+	}
+}
+
+func newController(t *testing.T, runner Runner, runs ...*ConformanceRun) *Controller {
+	t.Helper()
+	var objects []runtime.Object
+	for _, run := range runs {
+		u, err := toUnstructured(run)
+		require.NoError(t, err)
+		objects = append(objects, u)
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{Resource: "ConformanceRunList"}, objects...)
+	c := &Controller{Client: client, Runner: runner, StatusInterval: time.Millisecond}
+	c.init()
+	return c
+}
+
+func getRun(t *testing.T, c *Controller, name string) *ConformanceRun {
+	t.Helper()
+	u, err := c.Client.Resource(Resource).Namespace("default").Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	run, err := fromUnstructured(u)
+	require.NoError(t, err)
+	return run
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name      string
+		result    Result
+		err       error
+		phase     string
+		succeeded metav1.ConditionStatus
+		exitCode  *int
+	}{
+		{
+			name:      "passed",
+			result:    Result{Artifacts: "/artifacts/default/run"},
+			phase:     PhaseSucceeded,
+			succeeded: metav1.ConditionTrue,
+			exitCode:  new(int),
+		},
+		{
+			name:      "tests failed",
+			result:    Result{ExitCode: 1, Artifacts: "/artifacts/default/run"},
+			phase:     PhaseFailed,
+			succeeded: metav1.ConditionFalse,
+			exitCode:  func() *int { i := 1; return &i }(),
+		},
+		{
+			name:      "run failed",
+			err:       errors.New("unable to start"),
+			phase:     PhaseFailed,
+			succeeded: metav1.ConditionFalse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &fakeRunner{release: make(chan struct{}), result: tt.result, err: tt.err}
+			c := newController(t, runner, newRun(t, "run", Status{}))
+			c.informer = cache.NewSharedIndexInformer(&cache.ListWatch{}, nil, 0, cache.Indexers{})
+
+			require.NoError(t, c.reconcile(context.Background(), "default/run"))
+			run := getRun(t, c, "run")
+			assert.Equal(t, PhaseRunning, run.Status.Phase)
+			assert.Equal(t, "hydrophone-default-run", run.Status.Namespace)
+			assert.Equal(t, []string{Finalizer}, run.Finalizers)
+			assert.True(t, meta.IsStatusConditionTrue(run.Status.Conditions, ConditionRunning))
+
+			close(runner.release)
+			c.wg.Wait()
+			run = getRun(t, c, "run")
+			assert.Equal(t, tt.phase, run.Status.Phase)
+			assert.Equal(t, tt.exitCode, run.Status.ExitCode)
+			assert.Equal(t, tt.result.Artifacts, run.Status.Artifacts)
+			assert.Equal(t, Progress{Total: 2, Completed: 2, Passed: 2}, run.Status.Progress)
+			assert.NotNil(t, run.Status.CompletionTime)
+			assert.Empty(t, run.Finalizers)
+			condition := meta.FindStatusCondition(run.Status.Conditions, ConditionSucceeded)
+			require.NotNil(t, condition)
+			assert.Equal(t, tt.succeeded, condition.Status)
+		})
+	}
+}
+
+func TestReconcileMaxRuns(t *testing.T) {
+	runner := &fakeRunner{release: make(chan struct{})}
+	c := newController(t, runner, newRun(t, "first", Status{}), newRun(t, "second", Status{}))
+	defer func() {
+		close(runner.release)
+		c.wg.Wait()
+	}()
+	c.informer = cache.NewSharedIndexInformer(&cache.ListWatch{}, nil, 0, cache.Indexers{})
+
+	require.NoError(t, c.reconcile(context.Background(), "default/first"))
+	require.NoError(t, c.reconcile(context.Background(), "default/second"))
+	assert.Equal(t, PhaseRunning, getRun(t, c, "first").Status.Phase)
+	second := getRun(t, c, "second")
+	assert.Equal(t, PhasePending, second.Status.Phase)
+	assert.Empty(t, second.Finalizers)
+}
+
+func TestReconcileRestarted(t *testing.T) {
+	c := newController(t, &fakeRunner{}, newRun(t, "run", Status{Phase: PhaseRunning}))
+
+	require.NoError(t, c.reconcile(context.Background(), "default/run"))
+	run := getRun(t, c, "run")
+	assert.Equal(t, PhaseFailed, run.Status.Phase)
+	assert.Contains(t, run.Status.Message, "operator restarted")
+	assert.Nil(t, run.Status.ExitCode)
+}
+
+func TestReconcileDeleted(t *testing.T) {
+	runner := &fakeRunner{release: make(chan struct{})}
+	c := newController(t, runner, newRun(t, "run", Status{}))
+	c.informer = cache.NewSharedIndexInformer(&cache.ListWatch{}, nil, 0, cache.Indexers{})
+	require.NoError(t, c.reconcile(context.Background(), "default/run"))
+
+	// with the finalizer set, the deletion only sets the deletion timestamp
+	run := getRun(t, c, "run")
+	now := metav1.Now()
+	run.DeletionTimestamp = &now
+	u, err := toUnstructured(run)
+	require.NoError(t, err)
+	_, err = c.Client.Resource(Resource).Namespace("default").Update(context.Background(), u, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, c.reconcile(context.Background(), "default/run"))
+	c.wg.Wait()
+	run = getRun(t, c, "run")
+	assert.Empty(t, run.Finalizers)
+	assert.Equal(t, PhaseRunning, run.Status.Phase)
+	assert.Empty(t, c.running)
+}
+
+func TestRunNamespace(t *testing.T) {
+	assert.Equal(t, "hydrophone-team-a-nightly", runNamespace(&ConformanceRun{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "nightly"}}))
+	ns := runNamespace(newRun(t, strings.Repeat("a", 80), Status{}))
+	assert.Len(t, ns, 63)
+	assert.True(t, strings.HasPrefix(ns, "hydrophone-default-aaa"))
+	assert.NotEqual(t, ns, runNamespace(newRun(t, strings.Repeat("a", 81), Status{})))
+	assert.Equal(t, "hydrophone-default-v1-30", runNamespace(newRun(t, "v1.30", Status{})))
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+
+	"sigs.k8s.io/hydrophone/pkg/operator/controlpb"
+)
+
+// ControlServer serves the RunControl gRPC service of controlpb over the
+// ConformanceRuns of the cluster. Each call goes through a RunControl of the
+// namespace of the request. With an Authenticator, the calls need a bearer
+// token whose identity the Authorizer allows to take the action of the call:
+// StartRun starts a run, CancelRun cancels it, WatchRun and GetArtifacts
+// fetch it.
+type ControlServer struct {
+	controlpb.UnimplementedRunControlServer
+
+	Client        dynamic.Interface
+	Authenticator Authenticator
+	Authorizer    Authorizer
+}
+
+var _ controlpb.RunControlServer = &ControlServer{}
+
+// control returns the RunControl of the namespace of a request, after
+// checking that it names a run and that the caller may take the action
+func (s *ControlServer) control(ctx context.Context, action, namespace, name string) (*RunControl, error) {
+	if namespace == "" || name == "" {
+		return nil, status.Error(codes.InvalidArgument, "the namespace and the name of the ConformanceRun are required")
+	}
+	if err := s.authorize(ctx, action, namespace, name); err != nil {
+		return nil, err
+	}
+	return &RunControl{Client: s.Client, Namespace: namespace}, nil
+}
+
+// StartRun creates the ConformanceRun of the request
+func (s *ControlServer) StartRun(ctx context.Context, req *controlpb.StartRunRequest) (*controlpb.ConformanceRun, error) {
+	control, err := s.control(ctx, ActionStart, req.GetNamespace(), req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	run, err := control.StartRun(ctx, req.GetName(), specFromProto(req.GetSpec()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return runToProto(run), nil
+}
+
+// WatchRun streams the changes of the ConformanceRun of the request until it
+// finished, it was deleted or the client went away
+func (s *ControlServer) WatchRun(req *controlpb.WatchRunRequest, stream controlpb.RunControl_WatchRunServer) error {
+	control, err := s.control(stream.Context(), ActionFetch, req.GetNamespace(), req.GetName())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	events, errs := control.WatchRun(ctx, req.GetName())
+	for event := range events {
+		if err := stream.Send(&controlpb.RunEvent{Run: runToProto(event.Run), Deleted: event.Deleted}); err != nil {
+			// the watch stops with the canceled context
+			cancel()
+			for range events {
+			}
+			return err
+		}
+	}
+	if err := <-errs; err != nil {
+		return statusError(err)
+	}
+	return nil
+}
+
+// CancelRun deletes the ConformanceRun of the request
+func (s *ControlServer) CancelRun(ctx context.Context, req *controlpb.CancelRunRequest) (*controlpb.CancelRunResponse, error) {
+	control, err := s.control(ctx, ActionCancel, req.GetNamespace(), req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	if err := control.CancelRun(ctx, req.GetName()); err != nil {
+		return nil, statusError(err)
+	}
+	return &controlpb.CancelRunResponse{}, nil
+}
+
+// GetArtifacts returns the location of the artifacts of the ConformanceRun of
+// the request, a run that hasn't finished fails with FailedPrecondition
+func (s *ControlServer) GetArtifacts(ctx context.Context, req *controlpb.GetArtifactsRequest) (*controlpb.GetArtifactsResponse, error) {
+	control, err := s.control(ctx, ActionFetch, req.GetNamespace(), req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	run, err := control.GetRun(ctx, req.GetName())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	if !run.Status.Finished() {
+		return nil, status.Errorf(codes.FailedPrecondition, "ConformanceRun %s hasn't finished, its phase is %q", req.GetName(), run.Status.Phase)
+	}
+	if run.Status.Artifacts == "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "ConformanceRun %s has no artifacts: %s", req.GetName(), run.Status.Message)
+	}
+	return &controlpb.GetArtifactsResponse{Artifacts: run.Status.Artifacts}, nil
+}
+
+// statusError returns the gRPC status of an error of the API server
+func statusError(err error) error {
+	code := codes.Internal
+	var apiStatus apierrors.APIStatus
+	switch {
+	case apierrors.IsNotFound(err):
+		code = codes.NotFound
+	case apierrors.IsAlreadyExists(err):
+		code = codes.AlreadyExists
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		code = codes.InvalidArgument
+	case apierrors.IsForbidden(err):
+		code = codes.PermissionDenied
+	case apierrors.IsUnauthorized(err):
+		code = codes.Unauthenticated
+	case apierrors.IsConflict(err):
+		code = codes.Aborted
+	case apierrors.IsTooManyRequests(err), apierrors.IsServerTimeout(err), apierrors.IsTimeout(err), apierrors.IsServiceUnavailable(err):
+		code = codes.Unavailable
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case !errors.As(err, &apiStatus):
+		code = codes.Unknown
+	}
+	return status.Error(code, err.Error())
+}
+
+// specFromProto converts the spec of a request
+func specFromProto(spec *controlpb.Spec) Spec {
+	return Spec{
+		Conformance:      spec.GetConformance(),
+		Focus:            spec.GetFocus(),
+		Skip:             spec.GetSkip(),
+		ConformanceImage: spec.GetConformanceImage(),
+		Parallel:         int(spec.GetParallel()),
+		Timeout:          spec.GetTimeout(),
+		Upload:           spec.GetUpload(),
+	}
+}
+
+// runToProto converts the run to the message of the responses
+func runToProto(run *ConformanceRun) *controlpb.ConformanceRun {
+	pb := &controlpb.ConformanceRun{
+		Namespace: run.Namespace,
+		Name:      run.Name,
+		Uid:       string(run.UID),
+		Spec: &controlpb.Spec{
+			Conformance:      run.Spec.Conformance,
+			Focus:            run.Spec.Focus,
+			Skip:             run.Spec.Skip,
+			ConformanceImage: run.Spec.ConformanceImage,
+			Parallel:         int32(run.Spec.Parallel),
+			Timeout:          run.Spec.Timeout,
+			Upload:           run.Spec.Upload,
+		},
+		Status: &controlpb.Status{
+			Phase:          run.Status.Phase,
+			Message:        run.Status.Message,
+			StartTime:      timestampToProto(run.Status.StartTime),
+			CompletionTime: timestampToProto(run.Status.CompletionTime),
+			Namespace:      run.Status.Namespace,
+			Artifacts:      run.Status.Artifacts,
+			Progress: &controlpb.Progress{
+				Total:     run.Status.Progress.Total,
+				Completed: run.Status.Progress.Completed,
+				Passed:    run.Status.Progress.Passed,
+				Failed:    run.Status.Progress.Failed,
+				Skipped:   run.Status.Progress.Skipped,
+			},
+		},
+	}
+	if run.Status.ExitCode != nil {
+		exitCode := int32(*run.Status.ExitCode)
+		pb.Status.ExitCode = &exitCode
+	}
+	return pb
+}
+
+func timestampToProto(t *metav1.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(t.Time)
+}
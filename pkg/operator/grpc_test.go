@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/hydrophone/pkg/operator/controlpb"
+)
+
+// newControlClient serves the RunControl service of the controller over an
+// in-memory connection and returns its client
+func newControlClient(t *testing.T, c *Controller) controlpb.RunControlClient {
+	t.Helper()
+	return newControlServerClient(t, &ControlServer{Client: c.Client})
+}
+
+// newControlServerClient serves the RunControl service over an in-memory
+// connection and returns its client
+func newControlServerClient(t *testing.T, control *ControlServer) controlpb.RunControlClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	controlpb.RegisterRunControlServer(server, control)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return controlpb.NewRunControlClient(conn)
+}
+
+func TestControlServer(t *testing.T) {
+	c := newController(t, &fakeRunner{})
+	client := newControlClient(t, c)
+	ctx := context.Background()
+
+	run, err := client.StartRun(ctx, &controlpb.StartRunRequest{
+		Namespace: "default",
+		Name:      "nightly",
+		Spec:      &controlpb.Spec{Conformance: true, Parallel: 4, Timeout: "1d"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "nightly", run.GetName())
+	assert.True(t, run.GetSpec().GetConformance())
+	assert.Equal(t, int32(4), run.GetSpec().GetParallel())
+	assert.Equal(t, "1d", run.GetSpec().GetTimeout())
+
+	_, err = client.StartRun(ctx, &controlpb.StartRunRequest{Namespace: "default", Name: "nightly"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = client.GetArtifacts(ctx, &controlpb.GetArtifactsRequest{Namespace: "default", Name: "nightly"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	exitCode := 0
+	require.NoError(t, c.updateStatus(ctx, "default", "nightly", func(status *Status) {
+		status.Phase = PhaseSucceeded
+		status.ExitCode = &exitCode
+		status.Artifacts = "/artifacts/default/nightly"
+	}))
+	artifacts, err := client.GetArtifacts(ctx, &controlpb.GetArtifactsRequest{Namespace: "default", Name: "nightly"})
+	require.NoError(t, err)
+	assert.Equal(t, "/artifacts/default/nightly", artifacts.GetArtifacts())
+
+	_, err = client.CancelRun(ctx, &controlpb.CancelRunRequest{Namespace: "default", Name: "nightly"})
+	require.NoError(t, err)
+	_, err = client.GetArtifacts(ctx, &controlpb.GetArtifactsRequest{Namespace: "default", Name: "nightly"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.CancelRun(ctx, &controlpb.CancelRunRequest{Namespace: "default", Name: "nightly"})
+	assert.NoError(t, err)
+
+	_, err = client.CancelRun(ctx, &controlpb.CancelRunRequest{Name: "nightly"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestControlServerWatchRun(t *testing.T) {
+	c := newController(t, &fakeRunner{}, newRun(t, "nightly", Status{}))
+	watcher := watch.NewFake()
+	c.Client.(*fake.FakeDynamicClient).PrependWatchReactor("conformanceruns", k8stesting.DefaultWatchReactor(watcher, nil))
+	client := newControlClient(t, c)
+
+	stream, err := client.WatchRun(context.Background(), &controlpb.WatchRunRequest{Namespace: "default", Name: "nightly"})
+	require.NoError(t, err)
+	go func() {
+		running, _ := toUnstructured(newRun(t, "nightly", Status{Phase: PhaseRunning, Progress: Progress{Total: 10, Completed: 4}}))
+		watcher.Modify(running)
+		exitCode := 1
+		failed, _ := toUnstructured(newRun(t, "nightly", Status{Phase: PhaseFailed, ExitCode: &exitCode}))
+		watcher.Modify(failed)
+	}()
+
+	var events []*controlpb.RunEvent
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		events = append(events, event)
+	}
+	require.Len(t, events, 3)
+	assert.Equal(t, "", events[0].GetRun().GetStatus().GetPhase())
+	assert.Equal(t, PhaseRunning, events[1].GetRun().GetStatus().GetPhase())
+	assert.Equal(t, int64(4), events[1].GetRun().GetStatus().GetProgress().GetCompleted())
+	assert.Nil(t, events[1].GetRun().GetStatus().ExitCode)
+	assert.Equal(t, PhaseFailed, events[2].GetRun().GetStatus().GetPhase())
+	assert.Equal(t, int32(1), events[2].GetRun().GetStatus().GetExitCode())
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// CRD is the manifest of the ConformanceRun CustomResourceDefinition
+//
+//go:embed crd.yaml
+var CRD []byte
+
+// Roles is the manifest of the cluster roles authorizing the actions on
+// ConformanceRuns
+//
+//go:embed roles.yaml
+var Roles []byte
+
+// crdResource is the resource of CustomResourceDefinitions
+var crdResource = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// clusterRoleResource is the resource of ClusterRoles
+var clusterRoleResource = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+
+// establishTimeout is how long InstallCRD waits for the CRD to be served
+var establishTimeout = 30 * time.Second
+
+// InstallCRD creates or updates the ConformanceRun CustomResourceDefinition
+// and waits for it to be established.
+func InstallCRD(ctx context.Context, client dynamic.Interface) error {
+	crd := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(CRD, &crd.Object); err != nil {
+		return fmt.Errorf("invalid CRD manifest: %w", err)
+	}
+	crds := client.Resource(crdResource)
+	if err := apply(ctx, crds, "CRD", crd); err != nil {
+		return err
+	}
+
+	err := wait.PollUntilContextTimeout(ctx, time.Second, establishTimeout, true, func(ctx context.Context) (bool, error) {
+		current, err := crds.Get(ctx, crd.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		conditions, _, _ := unstructured.NestedSlice(current.Object, "status", "conditions")
+		for _, c := range conditions {
+			condition, _ := c.(map[string]any)
+			if condition["type"] == "Established" && condition["status"] == "True" {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("CRD %s wasn't established within %s: %w", crd.GetName(), establishTimeout, err)
+	}
+	return nil
+}
+
+// InstallRoles creates or updates the cluster roles authorizing the actions
+// on ConformanceRuns: hydrophone-conformancerun-runner starts, cancels and
+// fetches runs, hydrophone-conformancerun-viewer only fetches them.
+func InstallRoles(ctx context.Context, client dynamic.Interface) error {
+	roles := client.Resource(clusterRoleResource)
+	for _, doc := range bytes.Split(Roles, []byte("\n---\n")) {
+		role := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(doc, &role.Object); err != nil {
+			return fmt.Errorf("invalid roles manifest: %w", err)
+		}
+		if err := apply(ctx, roles, "cluster role", role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply creates the object of the kind, or updates it when it exists
+func apply(ctx context.Context, resource dynamic.ResourceInterface, kind string, obj *unstructured.Unstructured) error {
+	existing, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		if _, err := resource.Create(ctx, obj, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("unable to create %s %s: %w", kind, obj.GetName(), err)
+		}
+	case err != nil:
+		return fmt.Errorf("unable to get %s %s: %w", kind, obj.GetName(), err)
+	default:
+		obj.SetResourceVersion(existing.GetResourceVersion())
+		if _, err := resource.Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("unable to update %s %s: %w", kind, obj.GetName(), err)
+		}
+	}
+	return nil
+}
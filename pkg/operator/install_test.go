@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestInstallRoles(t *testing.T) {
+	outdated := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "rbac.authorization.k8s.io/v1",
+		"kind":       "ClusterRole",
+		"metadata":   map[string]any{"name": "hydrophone-conformancerun-viewer"},
+		"rules":      []any{},
+	}}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{clusterRoleResource: "ClusterRoleList"}, outdated)
+	ctx := context.Background()
+	require.NoError(t, InstallRoles(ctx, client))
+	// installing them again updates them
+	require.NoError(t, InstallRoles(ctx, client))
+
+	verbs := map[string][]any{}
+	for _, name := range []string{"hydrophone-conformancerun-runner", "hydrophone-conformancerun-viewer"} {
+		role, err := client.Resource(clusterRoleResource).Get(ctx, name, metav1.GetOptions{})
+		require.NoError(t, err)
+		rules, _, _ := unstructured.NestedSlice(role.Object, "rules")
+		require.NotEmpty(t, rules)
+		rule, _ := rules[0].(map[string]any)
+		verbs[name], _ = rule["verbs"].([]any)
+	}
+	assert.Equal(t, []any{"get", "list", "watch", "create", "delete"}, verbs["hydrophone-conformancerun-runner"])
+	assert.Equal(t, []any{"get", "list", "watch"}, verbs["hydrophone-conformancerun-viewer"])
+}
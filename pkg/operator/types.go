@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package operator reconciles ConformanceRun resources into conformance runs.
+package operator
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Resource is the resource of the ConformanceRun custom resources
+var Resource = schema.GroupVersionResource{Group: "hydrophone.k8s.io", Version: "v1alpha1", Resource: "conformanceruns"}
+
+// Finalizer is set on the ConformanceRuns in progress, so that the run is
+// aborted and its resources are deleted before the ConformanceRun is.
+const Finalizer = "hydrophone.k8s.io/cleanup"
+
+// Phases of a ConformanceRun
+const (
+	PhasePending   = "Pending"
+	PhaseRunning   = "Running"
+	PhaseSucceeded = "Succeeded"
+	PhaseFailed    = "Failed"
+)
+
+// Types of the conditions of a ConformanceRun
+const (
+	ConditionRunning   = "Running"
+	ConditionSucceeded = "Succeeded"
+)
+
+// ConformanceRun is a run of the e2e tests declared as a custom resource
+type ConformanceRun struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   Spec   `json:"spec,omitempty"`
+	Status Status `json:"status,omitempty"`
+}
+
+// Spec selects the tests of the run, the fields have the meaning of the
+// hydrophone flags of the same name
+type Spec struct {
+	Conformance      bool   `json:"conformance,omitempty"`
+	Focus            string `json:"focus,omitempty"`
+	Skip             string `json:"skip,omitempty"`
+	ConformanceImage string `json:"conformanceImage,omitempty"`
+	Parallel         int    `json:"parallel,omitempty"`
+	Timeout          string `json:"timeout,omitempty"`
+	Upload           string `json:"upload,omitempty"`
+}
+
+// Status reports the progress and the outcome of the run
+type Status struct {
+	Phase              string       `json:"phase,omitempty"`
+	Message            string       `json:"message,omitempty"`
+	ObservedGeneration int64        `json:"observedGeneration,omitempty"`
+	StartTime          *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime     *metav1.Time `json:"completionTime,omitempty"`
+	ExitCode           *int         `json:"exitCode,omitempty"`
+	// Namespace is the namespace the conformance pods run in
+	Namespace string `json:"namespace,omitempty"`
+	// Artifacts is the location of the artifacts of the run
+	Artifacts  string             `json:"artifacts,omitempty"`
+	Progress   Progress           `json:"progress,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// Progress counts the specs of the run
+type Progress struct {
+	Total     int64 `json:"total,omitempty"`
+	Completed int64 `json:"completed,omitempty"`
+	Passed    int64 `json:"passed,omitempty"`
+	Failed    int64 `json:"failed,omitempty"`
+	Skipped   int64 `json:"skipped,omitempty"`
+}
+
+// Finished reports whether the run reached a terminal phase
+func (s *Status) Finished() bool {
+	return s.Phase == PhaseSucceeded || s.Phase == PhaseFailed
+}
+
+// fromUnstructured converts the object of the dynamic client
+func fromUnstructured(u *unstructured.Unstructured) (*ConformanceRun, error) {
+	run := &ConformanceRun{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, run); err != nil {
+		return nil, fmt.Errorf("invalid ConformanceRun %s/%s: %w", u.GetNamespace(), u.GetName(), err)
+	}
+	return run, nil
+}
+
+// toUnstructured converts the run to an object of the dynamic client
+func toUnstructured(run *ConformanceRun) (*unstructured.Unstructured, error) {
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(run)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: object}, nil
+}
+
+// DeepCopy returns a copy of the status
+func (s *Status) DeepCopy() *Status {
+	out := *s
+	if s.StartTime != nil {
+		out.StartTime = s.StartTime.DeepCopy()
+	}
+	if s.CompletionTime != nil {
+		out.CompletionTime = s.CompletionTime.DeepCopy()
+	}
+	if s.ExitCode != nil {
+		exitCode := *s.ExitCode
+		out.ExitCode = &exitCode
+	}
+	if s.Conditions != nil {
+		out.Conditions = make([]metav1.Condition, len(s.Conditions))
+		for i := range s.Conditions {
+			s.Conditions[i].DeepCopyInto(&out.Conditions[i])
+		}
+	}
+	return &out
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package plugin describes test suites other than the e2e tests that
+// hydrophone runs with the same orchestration as the conformance tests.
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/registry"
+)
+
+// ResultsDir is the directory of the container shared with the output
+// container, the plugin writes its results to it
+const ResultsDir = "/tmp/results"
+
+// ResultsDirEnv is the environment variable holding ResultsDir
+const ResultsDirEnv = "HYDROPHONE_RESULTS_DIR"
+
+// Plugin is a test suite run in place of the e2e tests.
+//
+// Example:
+//
+//	name: storage-certification
+//	image: registry.example.com/vendor/storage-cert:1.2.0
+//	command: [/run.sh]
+//	args: [--profile=full]
+//	env:
+//	  - name: STORAGE_CLASS
+//	    value: fast
+//	artifacts: [report.json, logs/**]
+//	junit: junit.xml
+//	rbac:
+//	  - apiGroups: [""]
+//	    resources: [pods, persistentvolumeclaims]
+//	    verbs: ["*"]
+type Plugin struct {
+	// Name identifies the plugin in the logs
+	Name    string      `json:"name"`
+	Image   string      `json:"image"`
+	Command []string    `json:"command,omitempty"`
+	Args    []string    `json:"args,omitempty"`
+	Env     []v1.EnvVar `json:"env,omitempty"`
+	// Artifacts are the globs of the files of ResultsDir downloaded at the
+	// end of the run
+	Artifacts []string `json:"artifacts,omitempty"`
+	// JUnit is the path of the JUnit report of the plugin relative to
+	// ResultsDir, downloaded as junit_01.xml
+	JUnit string `json:"junit,omitempty"`
+	// RBAC are the rules of the cluster role of the plugin, full access to
+	// the cluster when empty as for the e2e tests
+	RBAC []rbac.PolicyRule `json:"rbac,omitempty"`
+}
+
+// Load reads and validates the plugin file at the given path.
+func Load(path string) (*Plugin, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Plugin{}
+	if err := yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, fmt.Errorf("error parsing plugin file %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid plugin file %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate checks that the plugin has a name and a valid image, and that its
+// results are within ResultsDir.
+func (p *Plugin) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("expected a name")
+	}
+	if p.Image == "" {
+		return fmt.Errorf("expected an image")
+	}
+	if _, err := registry.ParseReference(p.Image); err != nil {
+		return fmt.Errorf("invalid image: %w", err)
+	}
+	for _, pattern := range p.Artifacts {
+		if err := common.ValidateGlob(pattern); err != nil {
+			return fmt.Errorf("artifacts: %w", err)
+		}
+	}
+	if p.JUnit != "" && (path.IsAbs(p.JUnit) || strings.HasPrefix(path.Clean(p.JUnit), "..")) {
+		return fmt.Errorf("expected junit [%s] to be relative to %s", p.JUnit, ResultsDir)
+	}
+	for i, rule := range p.RBAC {
+		if len(rule.Verbs) == 0 {
+			return fmt.Errorf("rbac rule %d: expected verbs", i)
+		}
+	}
+	return nil
+}
+
+// Apply replaces the image, command and environment of the container with
+// the ones of the plugin. The volumes of the container are kept, ResultsDir
+// is the results directory of the e2e tests.
+func (p *Plugin) Apply(container *v1.Container) {
+	container.Image = p.Image
+	container.Command = p.Command
+	container.Args = p.Args
+	container.Env = append([]v1.EnvVar{{Name: ResultsDirEnv, Value: ResultsDir}}, p.Env...)
+}
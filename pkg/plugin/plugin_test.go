@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			content: `name: storage-certification
+image: registry.example.com/vendor/storage-cert:1.2.0
+command: [/run.sh]
+env:
+  - name: STORAGE_CLASS
+    value: fast
+artifacts: [report.json, "logs/**"]
+junit: reports/junit.xml
+rbac:
+  - apiGroups: [""]
+    resources: [pods]
+    verbs: [get, list]
+`,
+		},
+		{name: "no name", content: "image: registry.example.com/vendor/storage-cert:1.2.0\n", wantErr: true},
+		{name: "no image", content: "name: storage-certification\n", wantErr: true},
+		{name: "invalid image", content: "name: storage-certification\nimage: registry.example.com/cert@sha256:bad\n", wantErr: true},
+		{name: "unknown field", content: "name: storage-certification\nimage: busybox\nresults: [report.json]\n", wantErr: true},
+		{name: "junit outside results", content: "name: storage-certification\nimage: busybox\njunit: ../junit.xml\n", wantErr: true},
+		{name: "absolute junit", content: "name: storage-certification\nimage: busybox\njunit: /tmp/junit.xml\n", wantErr: true},
+		{name: "malformed artifacts", content: "name: storage-certification\nimage: busybox\nartifacts: [\"[\"]\n", wantErr: true},
+		{name: "rule without verbs", content: "name: storage-certification\nimage: busybox\nrbac:\n  - resources: [pods]\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "plugin.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+			p, err := Load(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "storage-certification", p.Name)
+			assert.Equal(t, []string{"report.json", "logs/**"}, p.Artifacts)
+			assert.Len(t, p.RBAC, 1)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	p := &Plugin{
+		Name:    "storage-certification",
+		Image:   "registry.example.com/vendor/storage-cert:1.2.0",
+		Command: []string{"/run.sh"},
+		Env:     []v1.EnvVar{{Name: "STORAGE_CLASS", Value: "fast"}},
+	}
+	container := v1.Container{
+		Image:        "registry.k8s.io/conformance:v1.30.0",
+		Env:          []v1.EnvVar{{Name: "E2E_FOCUS", Value: `\[Conformance\]`}},
+		VolumeMounts: []v1.VolumeMount{{Name: "output-volume", MountPath: ResultsDir}},
+	}
+	p.Apply(&container)
+	assert.Equal(t, p.Image, container.Image)
+	assert.Equal(t, []string{"/run.sh"}, container.Command)
+	assert.Equal(t, []v1.EnvVar{{Name: ResultsDirEnv, Value: ResultsDir}, {Name: "STORAGE_CLASS", Value: "fast"}}, container.Env)
+	assert.Len(t, container.VolumeMounts, 1)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redact
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Replacement replaces the secrets found in the output and the artifacts
+const Replacement = "[REDACTED]"
+
+// rule replaces the matches of a pattern with template
+type rule struct {
+	re       *regexp.Regexp
+	template string
+}
+
+// builtinRules scrub the bearer tokens, service account tokens and the
+// credentials of kubeconfigs and registry configs. The key preceding a secret
+// is kept.
+var builtinRules = []rule{
+	{regexp.MustCompile(`(?i)(\bbearer[ \t]+)[A-Za-z0-9\-._~+/]+=*`), "${1}" + Replacement},
+	{regexp.MustCompile(`(?i)(\bauthorization:[ \t]*basic[ \t]+)[A-Za-z0-9+/]+=*`), "${1}" + Replacement},
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), Replacement},
+	{regexp.MustCompile(`(?i)(\b(?:token|password|client-key-data|client-certificate-data|auth|identitytoken|registrytoken)"?[ \t]*[:=][ \t]*"?)[^\s"',<>{}]+`), "${1}" + Replacement},
+}
+
+// textExtensions are the extensions of the artifacts that are redacted, the
+// other artifacts may be binary or compressed
+var textExtensions = []string{".log", ".txt", ".xml", ".json", ".yaml", ".yml", ".csv"}
+
+var (
+	mu    sync.RWMutex
+	rules = builtinRules
+)
+
+// Configure adds the regular expressions of --redact-pattern to the builtin
+// rules. The whole match of a pattern is replaced.
+func Configure(patterns []string) error {
+	configured := append([]rule{}, builtinRules...)
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("expected --redact-pattern to be a regular expression, got %q: %w", pattern, err)
+		}
+		configured = append(configured, rule{re, Replacement})
+	}
+	mu.Lock()
+	rules = configured
+	mu.Unlock()
+	return nil
+}
+
+// String returns s with the secrets replaced by Replacement
+func String(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, r := range rules {
+		s = r.re.ReplaceAllString(s, r.template)
+	}
+	return s
+}
+
+// IsText reports whether the artifact is redacted, by its extension
+func IsText(name string) bool {
+	ext := path.Ext(name)
+	for _, text := range textExtensions {
+		if strings.EqualFold(ext, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// writer redacts what is written to it line by line, so that secrets aren't
+// missed at the boundaries of the writes
+type writer struct {
+	w   io.WriteCloser
+	buf []byte
+}
+
+// NewWriter returns a writer redacting the lines written to w. The last line
+// is written by Close even without a trailing newline.
+func NewWriter(w io.WriteCloser) io.WriteCloser {
+	return &writer{w: w}
+}
+
+func (r *writer) Write(p []byte) (int, error) {
+	r.buf = append(r.buf, p...)
+	end := bytes.LastIndexByte(r.buf, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+	if _, err := io.WriteString(r.w, String(string(r.buf[:end+1]))); err != nil {
+		return 0, err
+	}
+	r.buf = append(r.buf[:0], r.buf[end+1:]...)
+	return len(p), nil
+}
+
+func (r *writer) Close() error {
+	if len(r.buf) != 0 {
+		if _, err := io.WriteString(r.w, String(string(r.buf))); err != nil {
+			r.w.Close()
+			return err
+		}
+		r.buf = nil
+	}
+	return r.w.Close()
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redact
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestString(t *testing.T) {
+	defer Configure(nil)
+
+	tests := []struct {
+		in, out string
+	}{
+		{"no secret here", "no secret here"},
+		{"Authorization: Bearer abc.def-123=", "Authorization: Bearer [REDACTED]"},
+		{"authorization: Basic dXNlcjpwYXNz", "authorization: Basic [REDACTED]"},
+		{"token eyJhbGciOi.eyJzdWIiOi.c2lnbmF0dXJl end", "token [REDACTED] end"},
+		{"    token: 0123456789abcdef", "    token: [REDACTED]"},
+		{"    client-key-data: LS0tLS1CRUdJTg==", "    client-key-data: [REDACTED]"},
+		{`{"auths":{"registry.example.com":{"auth":"dXNlcjpwYXNz"}}}`, `{"auths":{"registry.example.com":{"auth":"[REDACTED]"}}}`},
+		{"<system-out>password=hunter2</system-out>", "<system-out>password=[REDACTED]</system-out>"},
+		{"token:\nnext line", "token:\nnext line"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.out, String(test.in), test.in)
+	}
+
+	require.NoError(t, Configure([]string{`ghp_[A-Za-z0-9]+`}))
+	assert.Equal(t, "pushed with [REDACTED]", String("pushed with ghp_abc123"))
+	require.NoError(t, Configure(nil))
+	assert.Equal(t, "pushed with ghp_abc123", String("pushed with ghp_abc123"))
+
+	assert.ErrorContains(t, Configure([]string{"("}), "expected --redact-pattern to be a regular expression")
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(nopCloser{&buf})
+	for _, chunk := range []string{"Authorization: Bea", "rer abc", "def\nok\npassword: se", "cret"} {
+		n, err := w.Write([]byte(chunk))
+		require.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+	assert.Equal(t, "Authorization: Bearer [REDACTED]\nok\n", buf.String())
+	require.NoError(t, w.Close())
+	assert.Equal(t, "Authorization: Bearer [REDACTED]\nok\npassword: [REDACTED]", buf.String())
+}
+
+func TestIsText(t *testing.T) {
+	for name, text := range map[string]bool{
+		"e2e.log":                 true,
+		"e2e.log.gz":              false,
+		"junit_01.xml":            true,
+		"hostlogs/node/kubelet":   false,
+		"diagnostics/events.txt":  true,
+		"screenshots/failure.png": false,
+	} {
+		assert.Equal(t, text, IsText(name), name)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// manifest is the subset of an image manifest or index needed to find the
+// platforms of an image
+type manifest struct {
+	Manifests []struct {
+		Platform *struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	} `json:"manifests"`
+	Config *struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+}
+
+// imageConfig is the subset of an image config holding its platform
+type imageConfig struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+}
+
+// Architectures returns the sorted linux architectures the image is built
+// for. Multi-arch images list them in their index, the architecture of a
+// single-arch image is read from its config.
+func (c *Checker) Architectures(image string) ([]string, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+	m := manifest{}
+	if err := c.getJSON(ref, "manifests/"+ref.Reference, &m); err != nil {
+		return nil, fmt.Errorf("error fetching the manifest of %s: %w", image, err)
+	}
+
+	archs := map[string]bool{}
+	for _, desc := range m.Manifests {
+		// attestations are listed with the unknown platform
+		if desc.Platform != nil && desc.Platform.OS == "linux" && desc.Platform.Architecture != "unknown" {
+			archs[desc.Platform.Architecture] = true
+		}
+	}
+	if len(m.Manifests) == 0 && m.Config != nil {
+		config := imageConfig{}
+		if err := c.getJSON(ref, "blobs/"+m.Config.Digest, &config); err != nil {
+			return nil, fmt.Errorf("error fetching the config of %s: %w", image, err)
+		}
+		if config.OS == "linux" || config.OS == "" {
+			archs[config.Architecture] = true
+		}
+	}
+
+	result := make([]string, 0, len(archs))
+	for arch := range archs {
+		result = append(result, arch)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func (c *Checker) getJSON(ref Reference, path string, v any) error {
+	resp, err := c.do(http.MethodGet, ref, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
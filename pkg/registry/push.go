@@ -0,0 +1,274 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	// manifestMediaType is the media type of the manifests of pushed artifacts
+	manifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	// emptyMediaType is the media type of the empty config of artifacts
+	emptyMediaType = "application/vnd.oci.empty.v1+json"
+	// TitleAnnotation names the file of a layer of an artifact
+	TitleAnnotation = "org.opencontainers.image.title"
+)
+
+// Descriptor describes a blob of an OCI manifest
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI image manifest describing an artifact
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// ArtifactFile is a file pushed as a layer of an artifact
+type ArtifactFile struct {
+	Path      string
+	MediaType string
+	// Title is the name of the file in the artifact
+	Title string
+}
+
+// PushArtifact pushes the files as an OCI artifact of the given type to the
+// image reference, the way ORAS does: the config is empty and every file is
+// a layer annotated with its title. It returns the digest of the manifest.
+// The registry is authenticated with the credentials of the docker config.
+func (c *Checker) PushArtifact(image, artifactType string, files []ArtifactFile, annotations map[string]string) (string, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(ref.Reference, ":") {
+		return "", fmt.Errorf("expected a tag to push %s to, got a digest", image)
+	}
+	s := &pushSession{c: c, ref: ref}
+
+	config := []byte("{}")
+	manifest := Manifest{
+		SchemaVersion: 2,
+		MediaType:     manifestMediaType,
+		ArtifactType:  artifactType,
+		Config:        Descriptor{MediaType: emptyMediaType, Digest: digestOf(config), Size: int64(len(config))},
+		Annotations:   annotations,
+	}
+	if err := s.pushBlob(manifest.Config.Digest, int64(len(config)), func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(config)), nil
+	}); err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		layer, err := s.pushFile(file)
+		if err != nil {
+			return "", fmt.Errorf("unable to push %s: %w", file.Path, err)
+		}
+		manifest.Layers = append(manifest.Layers, layer)
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.send(http.MethodPut, s.url("manifests/"+ref.Reference), manifestMediaType, int64(len(data)), func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", responseError("pushing the manifest of "+image, resp)
+	}
+	return digestOf(data), nil
+}
+
+// pushSession pushes to a repository, authenticated with the token or the
+// credentials the registry asked for on the first request
+type pushSession struct {
+	c             *Checker
+	ref           Reference
+	authorization string
+}
+
+// url returns the URL of the path below the repository
+func (s *pushSession) url(path string) string {
+	host := s.ref.Registry
+	if host == dockerHub {
+		host = dockerHubRegistry
+	}
+	scheme := "https"
+	if s.c.PlainHTTP != nil && s.c.PlainHTTP(s.ref.Registry) {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/%s", scheme, host, s.ref.Repository, path)
+}
+
+// send sends the request with the body returned by open, authenticating when
+// the registry asks for it. The caller has to close the body of the response.
+func (s *pushSession) send(method, requestURL, contentType string, size int64, open func() (io.ReadCloser, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		var body io.ReadCloser = http.NoBody
+		if open != nil {
+			var err error
+			if body, err = open(); err != nil {
+				return nil, err
+			}
+		}
+		req, err := http.NewRequest(method, requestURL, body)
+		if err != nil {
+			body.Close()
+			return nil, err
+		}
+		if open != nil {
+			req.ContentLength = size
+			req.Header.Set("Content-Type", contentType)
+		}
+		if s.authorization != "" {
+			req.Header.Set("Authorization", s.authorization)
+		}
+		resp, err := s.c.Client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+		resp.Body.Close()
+		if err := s.authenticate(resp.Header.Get("WWW-Authenticate")); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// authenticate answers the challenge of the registry with a push token, or
+// with the credentials of the docker config for basic authentication
+func (s *pushSession) authenticate(challenge string) error {
+	if scheme, _, _ := strings.Cut(challenge, " "); strings.EqualFold(scheme, "Basic") {
+		auth := dockerConfigAuth(s.ref.Registry)
+		if auth == "" {
+			return fmt.Errorf("no credentials of registry %s in the docker config", s.ref.Registry)
+		}
+		s.authorization = "Basic " + auth
+		return nil
+	}
+	token, err := s.c.token(challenge, s.ref, "pull,push")
+	if err != nil {
+		return err
+	}
+	s.authorization = "Bearer " + token
+	return nil
+}
+
+// pushFile pushes the file as a blob and returns its descriptor
+func (s *pushSession) pushFile(file ArtifactFile) (Descriptor, error) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	f.Close()
+	if err != nil {
+		return Descriptor{}, err
+	}
+	digest := "sha256:" + hex.EncodeToString(hash.Sum(nil))
+	if err := s.pushBlob(digest, size, func() (io.ReadCloser, error) { return os.Open(file.Path) }); err != nil {
+		return Descriptor{}, err
+	}
+	return Descriptor{
+		MediaType:   file.MediaType,
+		Digest:      digest,
+		Size:        size,
+		Annotations: map[string]string{TitleAnnotation: file.Title},
+	}, nil
+}
+
+// pushBlob uploads the blob in a single request unless the repository has
+// it already
+func (s *pushSession) pushBlob(digest string, size int64, open func() (io.ReadCloser, error)) error {
+	resp, err := s.send(http.MethodHead, s.url("blobs/"+digest), "", 0, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = s.send(http.MethodPost, s.url("blobs/uploads/"), "", 0, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return responseError("starting the upload of "+digest, resp)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return err
+	}
+	base, err := url.Parse(s.url(""))
+	if err != nil {
+		return err
+	}
+	upload := base.ResolveReference(location)
+	query := upload.Query()
+	query.Set("digest", digest)
+	upload.RawQuery = query.Encode()
+
+	resp, err = s.send(http.MethodPut, upload.String(), "application/octet-stream", size, open)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return responseError("uploading "+digest, resp)
+	}
+	return nil
+}
+
+// digestOf returns the sha256 digest of the data
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// responseError describes an unexpected response of the registry
+func responseError(action string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("error %s: %s %s", action, resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushArtifact(t *testing.T) {
+	blobs := map[string][]byte{}
+	manifests := map[string][]byte{}
+	var scope string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			scope = r.URL.Query().Get("scope")
+			w.Write([]byte(`{"token":"push-token"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer push-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/v2/conformance/results/blobs/"):
+			if _, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/conformance/results/blobs/")]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/conformance/results/blobs/uploads/":
+			w.Header().Set("Location", "/v2/conformance/results/blobs/uploads/1?state=abc")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPut && r.URL.Path == "/v2/conformance/results/blobs/uploads/1":
+			data, _ := io.ReadAll(r.Body)
+			sum := sha256.Sum256(data)
+			digest := r.URL.Query().Get("digest")
+			if digest != "sha256:"+hex.EncodeToString(sum[:]) || r.URL.Query().Get("state") != "abc" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			blobs[digest] = data
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && r.URL.Path == "/v2/conformance/results/manifests/v1.30.0":
+			manifests["v1.30.0"], _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "results.tar.gz")
+	require.NoError(t, os.WriteFile(bundle, []byte("bundle"), 0600))
+
+	c := &Checker{Client: server.Client(), PlainHTTP: func(string) bool { return true }}
+	image := strings.TrimPrefix(server.URL, "http://") + "/conformance/results:v1.30.0"
+	files := []ArtifactFile{{Path: bundle, MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Title: "results.tar.gz"}}
+	digest, err := c.PushArtifact(image, "application/vnd.test+tar", files, map[string]string{"created": "now"})
+	require.NoError(t, err)
+	assert.Equal(t, "repository:conformance/results:pull,push", scope)
+	assert.Equal(t, []byte("{}"), blobs[digestOf([]byte("{}"))])
+	assert.Equal(t, []byte("bundle"), blobs[digestOf([]byte("bundle"))])
+
+	var manifest Manifest
+	require.NoError(t, json.Unmarshal(manifests["v1.30.0"], &manifest))
+	assert.Equal(t, digestOf(manifests["v1.30.0"]), digest)
+	assert.Equal(t, "application/vnd.test+tar", manifest.ArtifactType)
+	assert.Equal(t, emptyMediaType, manifest.Config.MediaType)
+	require.Len(t, manifest.Layers, 1)
+	assert.Equal(t, Descriptor{
+		MediaType:   "application/vnd.oci.image.layer.v1.tar+gzip",
+		Digest:      digestOf([]byte("bundle")),
+		Size:        6,
+		Annotations: map[string]string{TitleAnnotation: "results.tar.gz"},
+	}, manifest.Layers[0])
+
+	// blobs the repository has are not uploaded again
+	delete(manifests, "v1.30.0")
+	blobs[digestOf([]byte("bundle"))] = []byte("kept")
+	_, err = c.PushArtifact(image, "application/vnd.test+tar", files, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("kept"), blobs[digestOf([]byte("bundle"))])
+}
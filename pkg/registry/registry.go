@@ -0,0 +1,321 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package registry queries container registries through the OCI distribution API.
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	dockerHub         = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+)
+
+// manifestTypes are the media types of manifests and indexes accepted when checking an image
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference is a parsed image reference
+type Reference struct {
+	// Registry is the host of the registry, e.g. registry.k8s.io
+	Registry string
+	// Repository is the path of the image in the registry
+	Repository string
+	// Reference is the tag or the digest of the image
+	Reference string
+}
+
+// digestRegexp matches the digest of an image reference
+var digestRegexp = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+
+// ParseReference parses an image reference such as
+// registry.k8s.io/e2e-test-images/agnhost:2.47 or busybox@sha256:... Images
+// without registry are looked up on Docker Hub. References with both a tag
+// and a digest are resolved by digest.
+func ParseReference(image string) (Reference, error) {
+	ref := Reference{Registry: dockerHub}
+	name := image
+	if first, rest, ok := strings.Cut(image, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry, name = first, rest
+	}
+
+	if repo, digest, ok := strings.Cut(name, "@"); ok {
+		if !digestRegexp.MatchString(digest) {
+			return Reference{}, fmt.Errorf("invalid digest in image reference %q", image)
+		}
+		name, ref.Reference = repo, digest
+		if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+			name = name[:i]
+		}
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Reference = name[:i], name[i+1:]
+	} else {
+		ref.Reference = "latest"
+	}
+	if name == "" || ref.Reference == "" {
+		return Reference{}, fmt.Errorf("invalid image reference %q", image)
+	}
+	if ref.Registry == dockerHub && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.Repository = name
+	return ref, nil
+}
+
+// Checker checks whether images exist in their registry
+type Checker struct {
+	Client *http.Client
+	// PlainHTTP reports whether the registry is reached over http instead of https.
+	// By default only registries on the loopback interface are.
+	PlainHTTP func(registry string) bool
+}
+
+// NewChecker returns a checker with default settings
+func NewChecker() *Checker {
+	return &Checker{
+		Client:    &http.Client{Timeout: 30 * time.Second},
+		PlainHTTP: isLoopback,
+	}
+}
+
+// Exists reports whether the manifest of the image exists in its registry.
+// Registries requiring a bearer token are authenticated anonymously, or with
+// the credentials of the docker config when it has some for the registry.
+func (c *Checker) Exists(image string) (bool, error) {
+	resp, err := c.manifest(image)
+	if err != nil {
+		return false, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("error checking %s: %s", image, resp.Status)
+	}
+}
+
+// Digest returns the digest of the manifest the image reference points to.
+func (c *Checker) Digest(image string) (string, error) {
+	resp, err := c.manifest(image)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error resolving the digest of %s: %s", image, resp.Status)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry returned no digest for %s", image)
+	}
+	return digest, nil
+}
+
+// PinDigest returns the image reference pinned to the digest, keeping the
+// tag for readability, e.g. registry.k8s.io/conformance:v1.29.0@sha256:...
+func PinDigest(image, digest string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	return image + "@" + digest
+}
+
+// manifest sends a HEAD request for the manifest of the image
+func (c *Checker) manifest(image string) (*http.Response, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(http.MethodHead, ref, "manifests/"+ref.Reference)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// do sends a request for the path below the repository of the reference,
+// authenticating with a bearer token if the registry asks for one. The caller
+// has to close the body of the response.
+func (c *Checker) do(method string, ref Reference, path string) (*http.Response, error) {
+	host := ref.Registry
+	if host == dockerHub {
+		host = dockerHubRegistry
+	}
+	scheme := "https"
+	if c.PlainHTTP != nil && c.PlainHTTP(ref.Registry) {
+		scheme = "http"
+	}
+	requestURL := fmt.Sprintf("%s://%s/v2/%s/%s", scheme, host, ref.Repository, path)
+
+	resp, err := c.send(method, requestURL, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		token, err := c.token(resp.Header.Get("WWW-Authenticate"), ref, "pull")
+		if err != nil {
+			return nil, err
+		}
+		return c.send(method, requestURL, token)
+	}
+	return resp, nil
+}
+
+func (c *Checker) send(method, requestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequest(method, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.Client.Do(req)
+}
+
+// token fetches a token for the actions on the repository, e.g. pull or
+// pull,push, from the realm of the bearer challenge
+func (c *Checker) token(challenge string, ref Reference, actions string) (string, error) {
+	params := parseChallenge(challenge)
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry %s requires unsupported authentication %q", ref.Registry, challenge)
+	}
+	query := url.Values{}
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:%s", ref.Repository, actions))
+
+	req, err := http.NewRequest(http.MethodGet, realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if auth := dockerConfigAuth(ref.Registry); auth != "" {
+		req.Header.Set("Authorization", "Basic "+auth)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error authenticating to %s: %s", ref.Registry, resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// parseChallenge parses the parameters of a Bearer WWW-Authenticate header
+func parseChallenge(challenge string) map[string]string {
+	params := map[string]string{}
+	scheme, rest, ok := strings.Cut(challenge, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return params
+	}
+	for _, part := range strings.Split(rest, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			params[key] = strings.Trim(value, `"`)
+		}
+	}
+	return params
+}
+
+// dockerConfigAuth returns the base64 encoded credentials of the registry
+// stored in the docker config, if any
+func dockerConfigAuth(registry string) string {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return ""
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return ""
+	}
+	keys := []string{registry, "https://" + registry}
+	if registry == dockerHub {
+		keys = append(keys, "https://index.docker.io/v1/")
+	}
+	for _, key := range keys {
+		if auth, ok := config.Auths[key]; ok {
+			if auth.Auth != "" {
+				return auth.Auth
+			}
+			if auth.Username != "" {
+				return base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+			}
+		}
+	}
+	return ""
+}
+
+// isLoopback reports whether the registry runs on the local machine
+func isLoopback(registry string) bool {
+	host := registry
+	if h, _, err := net.SplitHostPort(registry); err == nil {
+		host = h
+	}
+	// IPv6 registries without a port keep their brackets, e.g. [::1]
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
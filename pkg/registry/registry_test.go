@@ -0,0 +1,193 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image string
+		want  Reference
+	}{
+		{"registry.k8s.io/e2e-test-images/agnhost:2.47", Reference{"registry.k8s.io", "e2e-test-images/agnhost", "2.47"}},
+		{"localhost:5001/busybox", Reference{"localhost:5001", "busybox", "latest"}},
+		{"[fd00::10]:5000/busybox:1.36", Reference{"[fd00::10]:5000", "busybox", "1.36"}},
+		{"busybox:1.36", Reference{"docker.io", "library/busybox", "1.36"}},
+		{"registry.k8s.io/conformance@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", Reference{"registry.k8s.io", "conformance", "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}},
+		{"registry.k8s.io/conformance:v1.29.0@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", Reference{"registry.k8s.io", "conformance", "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseReference(tt.image)
+		require.NoError(t, err, tt.image)
+		assert.Equal(t, tt.want, got, tt.image)
+	}
+
+	_, err := ParseReference("registry.k8s.io/conformance:")
+	assert.Error(t, err)
+	_, err = ParseReference("registry.k8s.io/conformance@sha256:abcd")
+	assert.Error(t, err)
+}
+
+func TestIsLoopback(t *testing.T) {
+	for _, registry := range []string{"localhost", "localhost:5001", "127.0.0.1:5000", "[::1]:5000", "[::1]"} {
+		assert.True(t, isLoopback(registry), registry)
+	}
+	for _, registry := range []string{"registry.k8s.io", "10.0.0.10:5000", "[fd00::10]:5000", "[fd00::10]"} {
+		assert.False(t, isLoopback(registry), registry)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/conformance/manifests/v1.29.0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	checker := &Checker{Client: server.Client(), PlainHTTP: func(string) bool { return true }}
+
+	got, err := checker.Digest(host + "/conformance:v1.29.0")
+	require.NoError(t, err)
+	assert.Equal(t, digest, got)
+
+	_, err = checker.Digest(host + "/conformance:v0.0.0")
+	assert.Error(t, err)
+}
+
+func TestPinDigest(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	assert.Equal(t, "registry.k8s.io/conformance:v1.29.0@"+digest, PinDigest("registry.k8s.io/conformance:v1.29.0", digest))
+	assert.Equal(t, "registry.k8s.io/conformance@"+digest, PinDigest("registry.k8s.io/conformance@sha256:1234", digest))
+}
+
+func TestExists(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			assert.Equal(t, "repository:e2e-test-images/agnhost:pull", r.URL.Query().Get("scope"))
+			w.Write([]byte(`{"token": "secret"}`))
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/e2e-test-images/agnhost/manifests/2.47":
+			assert.Equal(t, http.MethodHead, r.Method)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := &Checker{Client: server.Client(), PlainHTTP: func(string) bool { return true }}
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	exists, err := c.Exists(host + "/e2e-test-images/agnhost:2.47")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = c.Exists(host + "/e2e-test-images/agnhost:2.48")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestArchitectures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/conformance/manifests/v1.29.0":
+			w.Write([]byte(`{"manifests": [
+				{"platform": {"os": "linux", "architecture": "arm64"}},
+				{"platform": {"os": "linux", "architecture": "amd64"}},
+				{"platform": {"os": "unknown", "architecture": "unknown"}},
+				{"platform": {"os": "windows", "architecture": "amd64"}}
+			]}`))
+		case "/v2/busybox/manifests/1.36":
+			w.Write([]byte(`{"config": {"digest": "sha256:1234"}}`))
+		case "/v2/busybox/blobs/sha256:1234":
+			w.Write([]byte(`{"os": "linux", "architecture": "s390x"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	checker := &Checker{Client: server.Client(), PlainHTTP: func(string) bool { return true }}
+
+	archs, err := checker.Architectures(host + "/conformance:v1.29.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"amd64", "arm64"}, archs)
+
+	archs, err = checker.Architectures(host + "/busybox:1.36")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s390x"}, archs)
+
+	_, err = checker.Architectures(host + "/missing:1.0")
+	assert.Error(t, err)
+}
+
+func TestTags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/conformance/tags/list", r.URL.Path)
+		if r.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</v2/conformance/tags/list?last=v1.29.0&n=2>; rel="next"`)
+			w.Write([]byte(`{"name": "conformance", "tags": ["v1.28.0", "v1.29.0"]}`))
+			return
+		}
+		w.Write([]byte(`{"name": "conformance", "tags": ["v1.29.1"]}`))
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	checker := &Checker{Client: server.Client(), PlainHTTP: func(string) bool { return true }}
+
+	tags, err := checker.Tags(host + "/conformance:v1.29.2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.28.0", "v1.29.0", "v1.29.1"}, tags)
+}
+
+func TestNearestTags(t *testing.T) {
+	tags := []string{"latest", "v1.27.3", "v1.28.0", "v1.28.4", "v1.29.0", "v1.29.1", "v1.30.0-alpha.1", "v1.30.0", "v2.0.0"}
+
+	assert.Equal(t, []string{"v1.29.1", "v1.29.0", "v1.30.0"}, NearestTags("v1.29.2", tags, 3))
+	assert.Equal(t, []string{"v1.30.0", "v1.29.1"}, NearestTags("v1.31.0", tags, 2))
+	assert.Equal(t, []string{"v1.30.0", "v1.30.0-alpha.1", "v1.29.1"}, NearestTags("v1.30.0-beta.0", tags, 3))
+	assert.Empty(t, NearestTags("latest", tags, 3))
+}
+
+func TestLatestPatch(t *testing.T) {
+	tags := []string{"latest", "v1.28.4", "v1.29.0", "v1.29.10", "v1.29.2", "v1.30.0-alpha.1", "v1.30.0-rc.0", "v2.29.3"}
+
+	assert.Equal(t, "v1.29.10", LatestPatch("v1.29.1", tags))
+	assert.Equal(t, "v1.28.4", LatestPatch("v1.28.6", tags))
+	assert.Equal(t, "", LatestPatch("v1.30.0", tags))
+	assert.Equal(t, "v1.30.0-rc.0", LatestPatch("v1.30.0-beta.0", tags))
+	assert.Equal(t, "", LatestPatch("v1.31.0", tags))
+	assert.Equal(t, "", LatestPatch("latest", tags))
+}
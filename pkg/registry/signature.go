@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+const (
+	// KubernetesReleaseIdentity is the identity signing the images of Kubernetes releases
+	KubernetesReleaseIdentity = "krel-trust@k8s-releng-prod.iam.gserviceaccount.com"
+	// KubernetesReleaseOIDCIssuer is the issuer of the identity signing Kubernetes releases
+	KubernetesReleaseOIDCIssuer = "https://accounts.google.com"
+)
+
+// VerifySignature verifies the keyless cosign signature of the image with the
+// cosign binary found in PATH. The image should be pinned to a digest so that
+// the verified image is the one that is run.
+func VerifySignature(image, identity, issuer string) error {
+	cosign, err := exec.LookPath("cosign")
+	if err != nil {
+		return fmt.Errorf("cosign is required to verify the signature of %s: %w", image, err)
+	}
+	cmd := exec.Command(cosign, "verify",
+		"--certificate-identity", identity,
+		"--certificate-oidc-issuer", issuer,
+		image)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("signature verification of %s failed: %v: %s", image, err, out)
+	}
+	return nil
+}
+
+// AttestBlob signs an in-toto attestation of the blob with the predicate of
+// the given type using the cosign binary found in PATH, and writes it to the
+// sigstore bundle, in the format of the sigstore bundle specification. The attestation is signed with the key, a file or a KMS
+// URI, or keyless with a certificate of the OIDC identity of the environment
+// when key is empty.
+func AttestBlob(blob, predicate, predicateType, key, bundle string) error {
+	cosign, err := exec.LookPath("cosign")
+	if err != nil {
+		return fmt.Errorf("cosign is required to attest %s: %w", blob, err)
+	}
+	args := []string{"attest-blob",
+		"--predicate", predicate,
+		"--type", predicateType,
+		"--bundle", bundle,
+		"--new-bundle-format",
+		"--yes"}
+	if key != "" {
+		args = append(args, "--key", key)
+	}
+	cmd := exec.Command(cosign, append(args, blob)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("attestation of %s failed: %v: %s", blob, err, out)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// maxTagPages bounds the number of pages of a paginated tag list
+const maxTagPages = 20
+
+// Tags returns the tags of the repository of the image.
+func (c *Checker) Tags(image string) ([]string, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	path := "tags/list"
+	for page := 0; path != "" && page < maxTagPages; page++ {
+		resp, err := c.do(http.MethodGet, ref, path)
+		if err != nil {
+			return nil, err
+		}
+		list := struct {
+			Tags []string `json:"tags"`
+		}{}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("error listing the tags of %s: %s", image, resp.Status)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error decoding the tags of %s: %w", image, err)
+		}
+		tags = append(tags, list.Tags...)
+		path = nextPage(resp.Header.Get("Link"), ref.Repository)
+	}
+	return tags, nil
+}
+
+// nextPage returns the path below the repository of the next page of a
+// paginated response, e.g. from the header
+//
+//	Link: </v2/conformance/tags/list?last=v1.29.0&n=100>; rel="next"
+func nextPage(link, repository string) string {
+	target, rest, ok := strings.Cut(link, ";")
+	if !ok || !strings.Contains(rest, `rel="next"`) {
+		return ""
+	}
+	u, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+	if err != nil {
+		return ""
+	}
+	path, ok := strings.CutPrefix(u.Path, "/v2/"+repository+"/")
+	if !ok {
+		return ""
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path
+}
+
+// NearestTags returns up to n semantic version tags closest to the tag, the
+// closest first. Tags of the same minor version are closest, then the
+// highest patch versions of the nearest minor versions. Pre-releases are
+// only considered when the tag is a pre-release itself.
+func NearestTags(tag string, tags []string, n int) []string {
+	want, err := semver.ParseTolerant(tag)
+	if err != nil {
+		return nil
+	}
+	type candidate struct {
+		tag     string
+		version semver.Version
+	}
+	var candidates []candidate
+	for _, t := range tags {
+		v, err := semver.ParseTolerant(t)
+		if err != nil || v.Major != want.Major || (len(v.Pre) != 0 && len(want.Pre) == 0) {
+			continue
+		}
+		candidates = append(candidates, candidate{t, v})
+	}
+	minorDistance := func(v semver.Version) uint64 {
+		if v.Minor > want.Minor {
+			return v.Minor - want.Minor
+		}
+		return want.Minor - v.Minor
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i].version, candidates[j].version
+		if da, db := minorDistance(a), minorDistance(b); da != db {
+			return da < db
+		}
+		return a.GT(b)
+	})
+	var nearest []string
+	for _, c := range candidates {
+		if len(nearest) == n {
+			break
+		}
+		nearest = append(nearest, c.tag)
+	}
+	return nearest
+}
+
+// LatestPatch returns the tag of the highest patch version of the minor
+// version of the tag, or "" when the tags have none. Pre-releases are only
+// considered when the tag is a pre-release itself.
+func LatestPatch(tag string, tags []string) string {
+	want, err := semver.ParseTolerant(tag)
+	if err != nil {
+		return ""
+	}
+	latest, latestVersion := "", semver.Version{}
+	for _, t := range tags {
+		v, err := semver.ParseTolerant(t)
+		if err != nil || v.Major != want.Major || v.Minor != want.Minor || len(v.Build) != 0 || (len(v.Pre) != 0 && len(want.Pre) == 0) {
+			continue
+		}
+		if latest == "" || v.GT(latestVersion) {
+			latest, latestVersion = t, v
+		}
+	}
+	return latest
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// AttestationFile is the name of the sigstore bundle holding the
+	// attestation of the results bundle written with --attest
+	AttestationFile = "attestation.sigstore.json"
+	// PredicateType is the type of the in-toto predicate attesting the results
+	PredicateType = "https://sigs.k8s.io/hydrophone/results/v1"
+	// inTotoPayloadType is the payload type of the DSSE envelopes holding an
+	// in-toto statement
+	inTotoPayloadType = "application/vnd.in-toto+json"
+)
+
+// Predicate is the in-toto predicate attesting the results bundle, it
+// records how the results were produced.
+type Predicate struct {
+	ServerVersion    string `json:"serverVersion,omitempty"`
+	ConformanceImage string `json:"conformanceImage,omitempty"`
+	// ImageDigest is the digest of the conformance image the tests ran
+	ImageDigest string `json:"imageDigest,omitempty"`
+	ExitCode    int    `json:"exitCode"`
+	// Args holds the command line arguments hydrophone was run with
+	Args []string `json:"args,omitempty"`
+}
+
+// NewPredicate returns the predicate of the run of the metadata
+func NewPredicate(m *Metadata, imageDigest string, args []string) *Predicate {
+	return &Predicate{
+		ServerVersion:    m.ServerVersion,
+		ConformanceImage: m.ConformanceImage,
+		ImageDigest:      imageDigest,
+		ExitCode:         m.ExitCode,
+		Args:             args,
+	}
+}
+
+// WritePredicate writes the predicate as JSON to a file of the directory and
+// returns its path.
+func WritePredicate(dir string, p *Predicate) (string, error) {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error encoding the predicate: %w", err)
+	}
+	path := filepath.Join(dir, "predicate.json")
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return "", fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// Statement is the in-toto statement signed by an attestation, binding the
+// predicate to the digests of its subjects.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Subject is an artifact attested by a statement, identified by its digests
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// sigstoreBundle is the part of a sigstore bundle holding the DSSE envelope
+// of an attestation
+type sigstoreBundle struct {
+	DSSEEnvelope *struct {
+		Payload     []byte `json:"payload"`
+		PayloadType string `json:"payloadType"`
+	} `json:"dsseEnvelope"`
+}
+
+// ReadStatement reads the in-toto statement of the attestation in the
+// sigstore bundle at path. The signature of the attestation isn't verified.
+func ReadStatement(path string) (*Statement, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var bundle sigstoreBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	if bundle.DSSEEnvelope == nil {
+		return nil, fmt.Errorf("%s holds no attestation", path)
+	}
+	if bundle.DSSEEnvelope.PayloadType != inTotoPayloadType {
+		return nil, fmt.Errorf("expected the attestation of %s to hold an in-toto statement, got %s", path, bundle.DSSEEnvelope.PayloadType)
+	}
+	statement := &Statement{}
+	if err := json.Unmarshal(bundle.DSSEEnvelope.Payload, statement); err != nil {
+		return nil, fmt.Errorf("error parsing the statement of %s: %w", path, err)
+	}
+	return statement, nil
+}
+
+// Check checks that the statement attests the file at path with the predicate
+// of hydrophone, and returns the SHA-256 digest of the file.
+func (s *Statement) Check(path string) (string, error) {
+	if s.PredicateType != PredicateType {
+		return "", fmt.Errorf("expected the predicate type %s, got %s", PredicateType, s.PredicateType)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+	for _, subject := range s.Subject {
+		if subject.Digest["sha256"] == digest {
+			return digest, nil
+		}
+	}
+	return "", fmt.Errorf("the statement doesn't attest %s with digest sha256:%s", filepath.Base(path), digest)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeAttestation writes a sigstore bundle holding the statement as cosign
+// attest-blob does, without a signature
+func writeAttestation(t *testing.T, path, payloadType string, statement any) {
+	payload, err := json.Marshal(statement)
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]any{
+		"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json",
+		"dsseEnvelope": map[string]any{
+			"payload":     payload,
+			"payloadType": payloadType,
+			"signatures":  []map[string]string{{"sig": "c2lnbmF0dXJl"}},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+}
+
+func TestWritePredicate(t *testing.T) {
+	metadata := &Metadata{ServerVersion: "v1.29.1", ConformanceImage: "registry.k8s.io/conformance:v1.29.1", ExitCode: 1}
+	path, err := WritePredicate(t.TempDir(), NewPredicate(metadata, "sha256:abc", []string{"--conformance"}))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"serverVersion": "v1.29.1", "conformanceImage": "registry.k8s.io/conformance:v1.29.1",
+		"imageDigest": "sha256:abc", "exitCode": 1, "args": ["--conformance"]}`, string(data))
+}
+
+func TestReadStatement(t *testing.T) {
+	dir := t.TempDir()
+	blob := filepath.Join(dir, BundleFile)
+	require.NoError(t, os.WriteFile(blob, []byte("results"), 0o644))
+	sum := sha256.Sum256([]byte("results"))
+	digest := hex.EncodeToString(sum[:])
+
+	metadata := &Metadata{ServerVersion: "v1.29.1", ConformanceImage: "registry.k8s.io/conformance:v1.29.1"}
+	predicate := NewPredicate(metadata, "sha256:abc", []string{"--conformance", "--compress=bundle", "--attest"})
+	statement := &Statement{
+		Type:          "https://in-toto.io/Statement/v1",
+		Subject:       []Subject{{Name: BundleFile, Digest: map[string]string{"sha256": digest}}},
+		PredicateType: PredicateType,
+		Predicate:     *predicate,
+	}
+
+	t.Run("attests the bundle", func(t *testing.T) {
+		path := filepath.Join(dir, "valid.sigstore.json")
+		writeAttestation(t, path, inTotoPayloadType, statement)
+
+		read, err := ReadStatement(path)
+		require.NoError(t, err)
+		assert.Equal(t, statement, read)
+		checked, err := read.Check(blob)
+		require.NoError(t, err)
+		assert.Equal(t, digest, checked)
+	})
+
+	t.Run("other subject", func(t *testing.T) {
+		other := *statement
+		other.Subject = []Subject{{Name: BundleFile, Digest: map[string]string{"sha256": "0000"}}}
+		path := filepath.Join(dir, "other.sigstore.json")
+		writeAttestation(t, path, inTotoPayloadType, &other)
+
+		read, err := ReadStatement(path)
+		require.NoError(t, err)
+		_, err = read.Check(blob)
+		assert.EqualError(t, err, "the statement doesn't attest results.tar.gz with digest sha256:"+digest)
+	})
+
+	t.Run("other predicate type", func(t *testing.T) {
+		other := *statement
+		other.PredicateType = "https://slsa.dev/provenance/v1"
+		path := filepath.Join(dir, "type.sigstore.json")
+		writeAttestation(t, path, inTotoPayloadType, &other)
+
+		read, err := ReadStatement(path)
+		require.NoError(t, err)
+		_, err = read.Check(blob)
+		assert.EqualError(t, err, "expected the predicate type "+PredicateType+", got https://slsa.dev/provenance/v1")
+	})
+
+	t.Run("other payload type", func(t *testing.T) {
+		path := filepath.Join(dir, "payload.sigstore.json")
+		writeAttestation(t, path, "text/plain", statement)
+
+		_, err := ReadStatement(path)
+		assert.ErrorContains(t, err, "to hold an in-toto statement, got text/plain")
+	})
+
+	t.Run("signature without attestation", func(t *testing.T) {
+		path := filepath.Join(dir, "signature.sigstore.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"messageSignature": {"signature": "c2ln"}}`), 0o644))
+
+		_, err := ReadStatement(path)
+		assert.ErrorContains(t, err, "holds no attestation")
+	})
+}
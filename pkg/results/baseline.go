@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"math"
+	"sort"
+)
+
+// MinSlowdown is the number of seconds a spec has to slow down by to be
+// reported as a regression. The durations of short specs vary by more than
+// any threshold from one run to the next.
+const MinSlowdown = 5
+
+// Regressions compares the durations of the specs of a run with the
+// durations of the same specs in a baseline run
+type Regressions struct {
+	// Baseline is the output directory of the baseline run
+	Baseline string `json:"baseline"`
+	// Threshold is the slowdown in percent beyond which a spec is reported
+	Threshold float64 `json:"threshold"`
+	// Seconds is the time the specs that ran in both runs took in this run
+	Seconds float64 `json:"seconds"`
+	// BaselineSeconds is the time the same specs took in the baseline run
+	BaselineSeconds float64 `json:"baselineSeconds"`
+	// Slower lists the specs that slowed down beyond the threshold, the
+	// largest slowdown first
+	Slower []Slowdown `json:"slower,omitempty"`
+}
+
+// Slowdown is a spec that took longer than in the baseline run
+type Slowdown struct {
+	Name            string  `json:"name"`
+	Seconds         float64 `json:"seconds"`
+	BaselineSeconds float64 `json:"baselineSeconds"`
+}
+
+// Percent returns how much longer the spec took than in the baseline run, in
+// percent
+func (s Slowdown) Percent() float64 {
+	return slowdown(s.Seconds, s.BaselineSeconds)
+}
+
+// Percent returns how much longer the specs took than in the baseline run,
+// in percent. It is negative when they got faster.
+func (r *Regressions) Percent() float64 {
+	return slowdown(r.Seconds, r.BaselineSeconds)
+}
+
+// Regressed reports whether the specs took longer than in the baseline run
+// beyond the threshold
+func (r *Regressions) Regressed() bool {
+	return r.Seconds-r.BaselineSeconds >= MinSlowdown && r.Percent() > r.Threshold
+}
+
+// CompareDurations compares the durations of the specs that ran in both the
+// report and the baseline report. A spec is reported when it took longer
+// than in the baseline by more than threshold percent and by at least
+// MinSlowdown seconds.
+func CompareDurations(report, baseline *JUnitTestSuites, threshold float64) *Regressions {
+	before := map[string]float64{}
+	for _, spec := range ranSpecs(baseline) {
+		before[spec.Name] += spec.Seconds
+	}
+	after := map[string]float64{}
+	for _, spec := range ranSpecs(report) {
+		after[spec.Name] += spec.Seconds
+	}
+
+	r := &Regressions{Threshold: threshold}
+	for name, seconds := range after {
+		baselineSeconds, ok := before[name]
+		if !ok {
+			continue
+		}
+		r.Seconds += seconds
+		r.BaselineSeconds += baselineSeconds
+		s := Slowdown{Name: name, Seconds: seconds, BaselineSeconds: baselineSeconds}
+		if seconds-baselineSeconds >= MinSlowdown && s.Percent() > threshold {
+			r.Slower = append(r.Slower, s)
+		}
+	}
+	sort.Slice(r.Slower, func(i, j int) bool {
+		a, b := r.Slower[i], r.Slower[j]
+		if a.Seconds-a.BaselineSeconds != b.Seconds-b.BaselineSeconds {
+			return a.Seconds-a.BaselineSeconds > b.Seconds-b.BaselineSeconds
+		}
+		return a.Name < b.Name
+	})
+	return r
+}
+
+// slowdown returns how much longer seconds is than baseline, in percent
+func slowdown(seconds, baseline float64) float64 {
+	if baseline == 0 {
+		if seconds == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (seconds - baseline) * 100 / baseline
+}
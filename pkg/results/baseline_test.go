@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareDurations(t *testing.T) {
+	baseline := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{TestCases: []JUnitTestCase{
+		{Name: "[SynchronizedBeforeSuite]", Status: StatusPassed, Time: 10},
+		{Name: "[It] [sig-apps] Deployment should proceed", Status: StatusPassed, Time: 100},
+		{Name: "[It] [sig-network] DNS should resolve", Status: StatusPassed, Time: 20},
+		{Name: "[It] [sig-network] Services should serve", Status: StatusPassed, Time: 60},
+		{Name: "[It] [sig-node] Pods should start", Status: StatusPassed, Time: 1},
+		{Name: "[It] [sig-storage] Volumes should mount", Status: StatusPassed, Time: 30},
+	}}}}
+	report := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{TestCases: []JUnitTestCase{
+		{Name: "[SynchronizedBeforeSuite]", Status: StatusPassed, Time: 100},
+		// slower by 30%, not beyond the threshold
+		{Name: "[It] [sig-apps] Deployment should proceed", Status: StatusPassed, Time: 130},
+		{Name: "[It] [sig-network] DNS should resolve", Status: StatusFailed, Time: 40},
+		{Name: "[It] [sig-network] Services should serve", Status: StatusPassed, Time: 150},
+		// slower by 200%, not by MinSlowdown
+		{Name: "[It] [sig-node] Pods should start", Status: StatusPassed, Time: 3},
+		{Name: "[It] [sig-storage] Volumes should mount", Status: StatusSkipped, Time: 0},
+		{Name: "[It] [sig-cli] Kubectl should apply", Status: StatusPassed, Time: 500},
+	}}}}
+
+	r := CompareDurations(report, baseline, 50)
+	assert.Equal(t, &Regressions{
+		Threshold:       50,
+		Seconds:         323,
+		BaselineSeconds: 181,
+		Slower: []Slowdown{
+			{Name: "[sig-network] Services should serve", Seconds: 150, BaselineSeconds: 60},
+			{Name: "[sig-network] DNS should resolve", Seconds: 40, BaselineSeconds: 20},
+		},
+	}, r)
+	assert.InDelta(t, 150, r.Slower[0].Percent(), 0.001)
+	assert.True(t, r.Regressed())
+
+	r = CompareDurations(report, baseline, 100)
+	assert.Len(t, r.Slower, 1)
+	assert.False(t, r.Regressed())
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// BundleFile is the name of the archive of the output directory the
+// artifacts of the run are bundled into with --compress=bundle
+const BundleFile = "results.tar.gz"
+
+// Bundle writes the named files and directories of the output directory to a
+// gzipped tarball named BundleFile and removes them once it is complete.
+// Missing names are skipped.
+func Bundle(outputDir string, names []string) error {
+	path := filepath.Join(outputDir, BundleFile)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := WriteBundle(f, outputDir, names); err != nil {
+		os.Remove(path)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := os.RemoveAll(filepath.Join(outputDir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteBundle writes the named files and directories of the output directory
+// to w as a gzipped tarball. Missing names are skipped.
+func WriteBundle(w io.Writer, outputDir string, names []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		root := filepath.Join(outputDir, name)
+		if _, err := os.Stat(root); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		err := filepath.Walk(root, func(file string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return addToTar(tw, outputDir, file, info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// addToTar adds the file or directory to the tarball, named after its path
+// relative to the output directory
+func addToTar(tw *tar.Writer, outputDir, file string, info fs.FileInfo) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	name, err := filepath.Rel(outputDir, file)
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(name)
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = io.Copy(tw, in)
+	return err
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundle(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, MetadataFile), []byte("{}"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "shard-0"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shard-0", "e2e.log"), []byte("log of shard 0"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, CheckpointFile), []byte("{}"), 0600))
+
+	require.NoError(t, Bundle(dir, []string{MetadataFile, "junit_01.xml", "shard-0"}))
+
+	f, err := os.Open(filepath.Join(dir, BundleFile))
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	content := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		content[header.Name] = string(data)
+	}
+	assert.Equal(t, map[string]string{
+		MetadataFile:      "{}",
+		"shard-0/":        "",
+		"shard-0/e2e.log": "log of shard 0",
+	}, content)
+
+	assert.NoFileExists(t, filepath.Join(dir, MetadataFile))
+	assert.NoDirExists(t, filepath.Join(dir, "shard-0"))
+	assert.FileExists(t, filepath.Join(dir, CheckpointFile))
+}
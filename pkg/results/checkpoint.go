@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// CheckpointFile is the name of the checkpoint of a paused run in the output directory
+	CheckpointFile = "checkpoint.json"
+	// CheckpointJUnit is the name the junit report of a paused run is kept under
+	CheckpointJUnit = "junit_checkpoint.xml"
+)
+
+// Checkpoint records the arguments of a paused run and the specs that
+// passed before it was paused, so that it can be resumed.
+type Checkpoint struct {
+	ConformanceImage string   `json:"conformanceImage"`
+	Namespace        string   `json:"namespace"`
+	RunID            string   `json:"runId,omitempty"`
+	Focus            string   `json:"focus"`
+	Skip             string   `json:"skip,omitempty"`
+	Parallel         string   `json:"parallel,omitempty"`
+	Verbosity        int      `json:"verbosity,omitempty"`
+	ExtraArgs        []string `json:"extraArgs,omitempty"`
+	ExtraGinkgoArgs  []string `json:"extraGinkgoArgs,omitempty"`
+	Passed           []string `json:"passed,omitempty"`
+}
+
+// WriteCheckpoint writes the checkpoint to the output directory.
+func WriteCheckpoint(outputDir string, c *Checkpoint) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding checkpoint: %w", err)
+	}
+	path := filepath.Join(outputDir, CheckpointFile)
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadCheckpoint reads the checkpoint of a paused run from the output directory.
+func ReadCheckpoint(outputDir string) (*Checkpoint, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, CheckpointFile))
+	if err != nil {
+		return nil, err
+	}
+	c := &Checkpoint{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", CheckpointFile, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CI identifies the CI job that ran hydrophone, so that archived results can
+// be traced back to the change that triggered them
+type CI struct {
+	// Provider is github-actions, gitlab or prow
+	Provider string `json:"provider"`
+	Job      string `json:"job,omitempty"`
+	BuildID  string `json:"buildId,omitempty"`
+	Commit   string `json:"commit,omitempty"`
+	Ref      string `json:"ref,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// DetectCI returns the CI job described by the environment of GitHub
+// Actions, GitLab CI or Prow, nil outside of them.
+func DetectCI(getenv func(string) string) *CI {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		ci := &CI{
+			Provider: "github-actions",
+			Job:      getenv("GITHUB_WORKFLOW"),
+			BuildID:  getenv("GITHUB_RUN_ID"),
+			Commit:   getenv("GITHUB_SHA"),
+			Ref:      getenv("GITHUB_REF"),
+		}
+		if server, repository := getenv("GITHUB_SERVER_URL"), getenv("GITHUB_REPOSITORY"); server != "" && repository != "" && ci.BuildID != "" {
+			ci.URL = fmt.Sprintf("%s/%s/actions/runs/%s", server, repository, ci.BuildID)
+		}
+		return ci
+	case getenv("GITLAB_CI") == "true":
+		return &CI{
+			Provider: "gitlab",
+			Job:      getenv("CI_JOB_NAME"),
+			BuildID:  getenv("CI_JOB_ID"),
+			Commit:   getenv("CI_COMMIT_SHA"),
+			Ref:      getenv("CI_COMMIT_REF_NAME"),
+			URL:      getenv("CI_PIPELINE_URL"),
+		}
+	case getenv("PROW_JOB_ID") != "":
+		ci := &CI{
+			Provider: "prow",
+			Job:      getenv("JOB_NAME"),
+			BuildID:  getenv("BUILD_ID"),
+			Commit:   getenv("PULL_PULL_SHA"),
+			Ref:      getenv("PULL_BASE_REF"),
+		}
+		// periodic jobs test the base of the repository
+		if ci.Commit == "" {
+			ci.Commit = getenv("PULL_BASE_SHA")
+		}
+		return ci
+	}
+	return nil
+}
+
+// String describes the CI job in a line
+func (ci *CI) String() string {
+	parts := []string{ci.Provider}
+	for _, field := range []struct{ name, value string }{
+		{"job", ci.Job}, {"build", ci.BuildID}, {"commit", ci.Commit}, {"ref", ci.Ref},
+	} {
+		if field.value != "" {
+			parts = append(parts, field.name+" "+field.value)
+		}
+	}
+	if ci.URL != "" {
+		parts = append(parts, ci.URL)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// IdentityProperties returns the properties identifying the run in the junit
+// report: the labels as label.<key> sorted by key, and the CI job
+func IdentityProperties(labels map[string]string, ci *CI) []JUnitProperty {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var properties []JUnitProperty
+	for _, key := range keys {
+		properties = append(properties, JUnitProperty{Name: "label." + key, Value: labels[key]})
+	}
+	if ci == nil {
+		return properties
+	}
+	for _, field := range []struct{ name, value string }{
+		{"ci.provider", ci.Provider}, {"ci.job", ci.Job}, {"ci.build-id", ci.BuildID},
+		{"ci.commit", ci.Commit}, {"ci.ref", ci.Ref}, {"ci.url", ci.URL},
+	} {
+		if field.value != "" {
+			properties = append(properties, JUnitProperty{Name: field.name, Value: field.value})
+		}
+	}
+	return properties
+}
+
+// ParseLabels parses the labels of the run given as key=value
+func ParseLabels(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	labels := map[string]string{}
+	for _, value := range values {
+		key, v, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected --label to be of key=value format, got %q", value)
+		}
+		labels[key] = v
+	}
+	return labels, nil
+}
+
+// FormatLabels formats the labels as key=value pairs sorted by key
+func FormatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectCI(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		ci   *CI
+	}{
+		{name: "none", env: map[string]string{}},
+		{
+			name: "github actions",
+			env: map[string]string{
+				"GITHUB_ACTIONS": "true", "GITHUB_WORKFLOW": "conformance", "GITHUB_RUN_ID": "123", "GITHUB_SHA": "0fb426",
+				"GITHUB_REF": "refs/heads/main", "GITHUB_SERVER_URL": "https://github.com", "GITHUB_REPOSITORY": "org/repo",
+			},
+			ci: &CI{Provider: "github-actions", Job: "conformance", BuildID: "123", Commit: "0fb426", Ref: "refs/heads/main", URL: "https://github.com/org/repo/actions/runs/123"},
+		},
+		{
+			name: "gitlab",
+			env: map[string]string{
+				"GITLAB_CI": "true", "CI_JOB_NAME": "conformance", "CI_JOB_ID": "42", "CI_COMMIT_SHA": "0fb426",
+				"CI_COMMIT_REF_NAME": "main", "CI_PIPELINE_URL": "https://gitlab.example.com/p/-/pipelines/7",
+			},
+			ci: &CI{Provider: "gitlab", Job: "conformance", BuildID: "42", Commit: "0fb426", Ref: "main", URL: "https://gitlab.example.com/p/-/pipelines/7"},
+		},
+		{
+			name: "prow periodic",
+			env:  map[string]string{"PROW_JOB_ID": "abc", "JOB_NAME": "ci-conformance", "BUILD_ID": "1789", "PULL_BASE_REF": "main", "PULL_BASE_SHA": "0fb426"},
+			ci:   &CI{Provider: "prow", Job: "ci-conformance", BuildID: "1789", Commit: "0fb426", Ref: "main"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.ci, DetectCI(func(key string) string { return tt.env[key] }))
+		})
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels([]string{"team=platform", "ticket=QA-1234", "empty="})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "platform", "ticket": "QA-1234", "empty": ""}, labels)
+	assert.Equal(t, "empty=, team=platform, ticket=QA-1234", FormatLabels(labels))
+
+	labels, err = ParseLabels(nil)
+	require.NoError(t, err)
+	assert.Nil(t, labels)
+
+	_, err = ParseLabels([]string{"=value"})
+	assert.ErrorContains(t, err, "expected --label to be of key=value format")
+}
+
+func TestIdentityProperties(t *testing.T) {
+	assert.Empty(t, IdentityProperties(nil, nil))
+	assert.Equal(t, []JUnitProperty{
+		{Name: "label.cluster", Value: "prod-eu-1"},
+		{Name: "label.team", Value: "platform"},
+		{Name: "ci.provider", Value: "prow"},
+		{Name: "ci.build-id", Value: "1789"},
+		{Name: "ci.commit", Value: "0fb426"},
+	}, IdentityProperties(map[string]string{"team": "platform", "cluster": "prod-eu-1"}, &CI{Provider: "prow", BuildID: "1789", Commit: "0fb426"}))
+}
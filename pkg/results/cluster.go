@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Cluster describes the cluster a run tested, captured when the run starts
+// so that archived results tell what they were produced on.
+type Cluster struct {
+	// Name is the name of the cluster in the kubeconfig
+	Name string `json:"name,omitempty"`
+	// Provider is the cloud provider of the nodes, the scheme of their
+	// provider IDs, e.g. aws or gce
+	Provider string `json:"provider,omitempty"`
+	// CNI lists the network plugins whose daemon sets run in the cluster
+	CNI       []string    `json:"cni,omitempty"`
+	NodeCount int         `json:"nodeCount"`
+	Nodes     []NodeGroup `json:"nodes,omitempty"`
+}
+
+// NodeGroup counts the nodes sharing an operating system, an architecture, a
+// kubelet version and a container runtime
+type NodeGroup struct {
+	OS               string `json:"os"`
+	Arch             string `json:"arch"`
+	KubeletVersion   string `json:"kubeletVersion"`
+	ContainerRuntime string `json:"containerRuntime,omitempty"`
+	Count            int    `json:"count"`
+}
+
+// String describes the group, e.g. 3x linux/amd64 v1.30.0 containerd://1.7.13
+func (g NodeGroup) String() string {
+	s := fmt.Sprintf("%dx %s/%s %s", g.Count, g.OS, g.Arch, g.KubeletVersion)
+	if g.ContainerRuntime != "" {
+		s += " " + g.ContainerRuntime
+	}
+	return s
+}
+
+// JUnitProperties returns the properties describing the cluster in the
+// junit report
+func (c *Cluster) JUnitProperties() []JUnitProperty {
+	properties := []JUnitProperty{{Name: "cluster.node-count", Value: strconv.Itoa(c.NodeCount)}}
+	if c.Name != "" {
+		properties = append(properties, JUnitProperty{Name: "cluster.name", Value: c.Name})
+	}
+	if c.Provider != "" {
+		properties = append(properties, JUnitProperty{Name: "cluster.provider", Value: c.Provider})
+	}
+	if len(c.CNI) != 0 {
+		properties = append(properties, JUnitProperty{Name: "cluster.cni", Value: strings.Join(c.CNI, ",")})
+	}
+	var nodes []string
+	for _, group := range c.Nodes {
+		nodes = append(nodes, group.String())
+	}
+	if len(nodes) != 0 {
+		properties = append(properties, JUnitProperty{Name: "cluster.nodes", Value: strings.Join(nodes, ", ")})
+	}
+	return properties
+}
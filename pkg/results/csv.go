@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CSVFile is the name of the file of --results-format=csv in the output directory
+const CSVFile = "results.csv"
+
+// maxExcerpt is the number of characters of the failure message kept in the
+// CSV results
+const maxExcerpt = 200
+
+// csvHeader is the first row of the CSV results
+var csvHeader = []string{"name", "sig", "status", "duration", "message"}
+
+// WriteCSV writes one row per test case of the report: its name, its SIG, its
+// status, its duration in seconds and the first line of its failure message.
+func WriteCSV(w io.Writer, suites *JUnitTestSuites) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, s := range suites.TestSuites {
+		for _, tc := range s.TestCases {
+			name := strings.TrimPrefix(tc.Name, "[It] ")
+			sig := ""
+			if match := sigRegexp.FindStringSubmatch(name); match != nil {
+				sig = match[1]
+			}
+			message := ""
+			for _, m := range []*JUnitMessage{tc.Failure, tc.Error} {
+				if m != nil && message == "" {
+					message = excerpt(m.Message)
+				}
+			}
+			duration := strconv.FormatFloat(tc.Time, 'f', 3, 64)
+			if err := cw.Write([]string{name, sig, tc.Status, duration, message}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteCSVFile writes the CSV results of the report to the output directory.
+func WriteCSVFile(outputDir string, suites *JUnitTestSuites) error {
+	path := filepath.Join(outputDir, CSVFile)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteCSV(f, suites); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// excerpt returns the first non-empty line of the message, capped to
+// maxExcerpt characters
+func excerpt(message string) string {
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if runes := []rune(line); len(runes) > maxExcerpt {
+			line = string(runes[:maxExcerpt]) + "..."
+		}
+		return line
+	}
+	return ""
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCSV(t *testing.T) {
+	report := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{TestCases: []JUnitTestCase{
+		{Name: "[It] [sig-apps] Deployment should proceed", Status: StatusPassed, Time: 12.3456},
+		{Name: "[It] [sig-network] DNS should resolve, \"quickly\"", Status: StatusFailed, Time: 300,
+			Failure: &JUnitMessage{Message: "\n  [FAILED] timed out\nwaiting for the pod\n"}},
+		{Name: "[It] Untagged", Status: StatusSkipped, Skipped: &JUnitMessage{Message: "skipped"}},
+		{Name: "[SynchronizedBeforeSuite]", Status: StatusFailed, Time: 1,
+			Error: &JUnitMessage{Message: strings.Repeat("x", maxExcerpt+1)}},
+	}}}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(&buf, report))
+	assert.Equal(t, `name,sig,status,duration,message
+[sig-apps] Deployment should proceed,sig-apps,passed,12.346,
+"[sig-network] DNS should resolve, ""quickly""",sig-network,failed,300.000,[FAILED] timed out
+Untagged,,skipped,0.000,
+[SynchronizedBeforeSuite],,failed,1.000,`+strings.Repeat("x", maxExcerpt)+`...
+`, buf.String())
+}
@@ -0,0 +1,349 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Status values used by ginkgo in the status attribute of a test case
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+	StatusPending = "pending"
+)
+
+// JUnitTestSuites is the root element of the junit report written by ginkgo
+type JUnitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Tests      int              `xml:"tests,attr"`
+	Disabled   int              `xml:"disabled,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Time       float64          `xml:"time,attr"`
+	TestSuites []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite is a single suite of the junit report
+type JUnitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Package    string           `xml:"package,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Disabled   int              `xml:"disabled,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Time       float64          `xml:"time,attr"`
+	Timestamp  string           `xml:"timestamp,attr"`
+	Properties *JUnitProperties `xml:"properties,omitempty"`
+	TestCases  []JUnitTestCase  `xml:"testcase"`
+}
+
+// JUnitProperties holds the properties of a suite
+type JUnitProperties struct {
+	Properties []JUnitProperty `xml:"property"`
+}
+
+// JUnitProperty is a single name/value property of a suite
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// JUnitTestCase is the result of a single spec
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Status    string        `xml:"status,attr"`
+	Time      float64       `xml:"time,attr"`
+	Skipped   *JUnitMessage `xml:"skipped,omitempty"`
+	Error     *JUnitMessage `xml:"error,omitempty"`
+	Failure   *JUnitMessage `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+// JUnitMessage is the skipped, error or failure element of a test case
+type JUnitMessage struct {
+	Message     string `xml:"message,attr"`
+	Type        string `xml:"type,attr,omitempty"`
+	Description string `xml:",chardata"`
+}
+
+// ReadJUnit parses the junit report at the given path.
+func ReadJUnit(path string) (*JUnitTestSuites, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	suites := &JUnitTestSuites{}
+	if err := xml.Unmarshal(data, suites); err != nil {
+		return nil, fmt.Errorf("error parsing junit report %s: %w", path, err)
+	}
+	return suites, nil
+}
+
+// WriteJUnit writes the junit report to the given path.
+func WriteJUnit(path string, suites *JUnitTestSuites) error {
+	data, err := encodeJUnit(suites)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// EncodeJUnit writes the junit report to w.
+func EncodeJUnit(w io.Writer, suites *JUnitTestSuites) error {
+	data, err := encodeJUnit(suites)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// encodeJUnit encodes the junit report as indented XML
+func encodeJUnit(suites *JUnitTestSuites) ([]byte, error) {
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding junit report: %w", err)
+	}
+	data = append([]byte(xml.Header), data...)
+	return append(data, '\n'), nil
+}
+
+// ParseJUnitProperties parses properties given as name=value.
+func ParseJUnitProperties(values []string) ([]JUnitProperty, error) {
+	var properties []JUnitProperty
+	for _, value := range values {
+		name, v, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("expected junit property [%s] to be of name=value format", value)
+		}
+		properties = append(properties, JUnitProperty{Name: name, Value: v})
+	}
+	return properties, nil
+}
+
+// AddProperties sets the properties on every suite of the report, replacing
+// existing properties with the same name.
+func AddProperties(suites *JUnitTestSuites, properties []JUnitProperty) {
+	for i := range suites.TestSuites {
+		s := &suites.TestSuites[i]
+		if s.Properties == nil {
+			s.Properties = &JUnitProperties{}
+		}
+		for _, p := range properties {
+			s.Properties.set(p)
+		}
+	}
+}
+
+func (p *JUnitProperties) set(property JUnitProperty) {
+	for i := range p.Properties {
+		if p.Properties[i].Name == property.Name {
+			p.Properties[i].Value = property.Value
+			return
+		}
+	}
+	p.Properties = append(p.Properties, property)
+}
+
+// Policies of MergeRetriedJUnit, which result of a spec that ran in more
+// than one report is kept
+const (
+	// MergeBest keeps a passed result over a failed one, e.g. to accept the
+	// specs that passed when retried
+	MergeBest = "best"
+	// MergeWorst keeps a failed result over a passed one
+	MergeWorst = "worst"
+	// MergeLast keeps the result of the last report the spec ran in
+	MergeLast = "last"
+)
+
+// MergeJUnit combines the reports of several runs of the same suite into a
+// single report. Sharded runs report every spec they didn't select as
+// skipped, so for specs present in more than one report the result of the
+// run that actually executed the spec wins.
+func MergeJUnit(reports ...*JUnitTestSuites) *JUnitTestSuites {
+	return mergeJUnit(func(kept, tc JUnitTestCase) bool {
+		return ran(tc) && !ran(kept)
+	}, reports)
+}
+
+// MergeRetriedJUnit combines the reports like MergeJUnit, the specs that ran
+// in more than one report, e.g. because they were retried, keep the result
+// chosen by the policy: MergeBest, MergeWorst or MergeLast.
+func MergeRetriedJUnit(policy string, reports ...*JUnitTestSuites) (*JUnitTestSuites, error) {
+	var better func(kept, tc JUnitTestCase) bool
+	switch policy {
+	case MergeBest:
+		better = func(kept, tc JUnitTestCase) bool { return failed(kept) && !failed(tc) }
+	case MergeWorst:
+		better = func(kept, tc JUnitTestCase) bool { return !failed(kept) && failed(tc) }
+	case MergeLast:
+		better = func(kept, tc JUnitTestCase) bool { return true }
+	default:
+		return nil, fmt.Errorf("expected the merge policy to be %s, %s or %s, got %q", MergeBest, MergeWorst, MergeLast, policy)
+	}
+	return mergeJUnit(func(kept, tc JUnitTestCase) bool {
+		if !ran(tc) || !ran(kept) {
+			return ran(tc) && !ran(kept)
+		}
+		return better(kept, tc)
+	}, reports), nil
+}
+
+// mergeJUnit combines the reports into a single report, replace reports
+// whether the result of a spec replaces the result kept from the previous
+// reports
+func mergeJUnit(replace func(kept, tc JUnitTestCase) bool, reports []*JUnitTestSuites) *JUnitTestSuites {
+	merged := &JUnitTestSuites{}
+	var suite *JUnitTestSuite
+	index := map[string]int{}
+
+	for _, report := range reports {
+		if report.Time > merged.Time {
+			merged.Time = report.Time
+		}
+		for _, s := range report.TestSuites {
+			if suite == nil {
+				suite = &JUnitTestSuite{
+					Name:       s.Name,
+					Package:    s.Package,
+					Timestamp:  s.Timestamp,
+					Properties: s.Properties,
+				}
+			}
+			if s.Time > suite.Time {
+				suite.Time = s.Time
+			}
+			for _, tc := range s.TestCases {
+				i, ok := index[tc.Name]
+				if !ok {
+					index[tc.Name] = len(suite.TestCases)
+					suite.TestCases = append(suite.TestCases, tc)
+					continue
+				}
+				if replace(suite.TestCases[i], tc) {
+					suite.TestCases[i] = tc
+				}
+			}
+		}
+	}
+
+	if suite != nil {
+		suite.updateCounts()
+		merged.TestSuites = []JUnitTestSuite{*suite}
+		merged.Tests = suite.Tests
+		merged.Disabled = suite.Disabled
+		merged.Errors = suite.Errors
+		merged.Failures = suite.Failures
+	}
+	return merged
+}
+
+// ran reports whether the spec was executed rather than skipped
+func ran(tc JUnitTestCase) bool {
+	return tc.Status != StatusSkipped && tc.Status != StatusPending
+}
+
+// failed reports whether the spec failed
+func failed(tc JUnitTestCase) bool {
+	return tc.Failure != nil || tc.Error != nil
+}
+
+// updateCounts recomputes the counters of the suite from its test cases
+func (s *JUnitTestSuite) updateCounts() {
+	s.Tests, s.Disabled, s.Skipped, s.Errors, s.Failures = len(s.TestCases), 0, 0, 0, 0
+	for _, tc := range s.TestCases {
+		switch {
+		case tc.Status == StatusPending:
+			s.Disabled++
+		case tc.Status == StatusSkipped:
+			s.Skipped++
+		case tc.Error != nil:
+			s.Errors++
+		case tc.Failure != nil:
+			s.Failures++
+		}
+	}
+}
+
+// SelectedTests returns the names of the specs of the report that were
+// selected to run, in the order of the report. This is the list of tests a
+// ginkgo dry-run would have run. Suite setup nodes are left out.
+func SelectedTests(suites *JUnitTestSuites) []string {
+	var names []string
+	for _, s := range suites.TestSuites {
+		for _, tc := range s.TestCases {
+			if name, ok := strings.CutPrefix(tc.Name, "[It] "); ok && ran(tc) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// AllTests returns the names of every spec of the report, whether it ran or
+// not. Ginkgo reports the specs it didn't select as skipped, so the report of
+// a run lists all the tests of the conformance image.
+func AllTests(suites *JUnitTestSuites) []string {
+	var names []string
+	for _, s := range suites.TestSuites {
+		for _, tc := range s.TestCases {
+			if name, ok := strings.CutPrefix(tc.Name, "[It] "); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// PassedTests returns the names of the specs of the report that passed.
+func PassedTests(suites *JUnitTestSuites) []string {
+	var names []string
+	for _, s := range suites.TestSuites {
+		for _, tc := range s.TestCases {
+			if name, ok := strings.CutPrefix(tc.Name, "[It] "); ok && tc.Status == StatusPassed {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// FailedTests returns the names of the specs of the report that failed.
+func FailedTests(suites *JUnitTestSuites) []string {
+	var names []string
+	for _, s := range suites.TestSuites {
+		for _, tc := range s.TestCases {
+			if failed(tc) {
+				names = append(names, strings.TrimPrefix(tc.Name, "[It] "))
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeJUnit(t *testing.T) {
+	shard0 := &JUnitTestSuites{
+		Time: 10,
+		TestSuites: []JUnitTestSuite{
+			{
+				Name: "Kubernetes e2e suite",
+				Time: 10,
+				TestCases: []JUnitTestCase{
+					{Name: "[sig-apps] a", Status: StatusPassed},
+					{Name: "[sig-network] b", Status: StatusSkipped, Skipped: &JUnitMessage{Message: "skipped"}},
+				},
+			},
+		},
+	}
+	shard1 := &JUnitTestSuites{
+		Time: 20,
+		TestSuites: []JUnitTestSuite{
+			{
+				Name: "Kubernetes e2e suite",
+				Time: 20,
+				TestCases: []JUnitTestCase{
+					{Name: "[sig-apps] a", Status: StatusSkipped, Skipped: &JUnitMessage{Message: "skipped"}},
+					{Name: "[sig-network] b", Status: StatusFailed, Failure: &JUnitMessage{Message: "boom"}},
+				},
+			},
+		},
+	}
+
+	merged := MergeJUnit(shard0, shard1)
+
+	assert.Equal(t, 20.0, merged.Time)
+	assert.Equal(t, 2, merged.Tests)
+	assert.Equal(t, 1, merged.Failures)
+	assert.Len(t, merged.TestSuites, 1)
+	suite := merged.TestSuites[0]
+	assert.Equal(t, 0, suite.Skipped)
+	assert.Equal(t, StatusPassed, suite.TestCases[0].Status)
+	assert.Equal(t, StatusFailed, suite.TestCases[1].Status)
+
+	// the merged report must survive a round trip through the file system
+	path := filepath.Join(t.TempDir(), "junit_01.xml")
+	assert.NoError(t, WriteJUnit(path, merged))
+	read, err := ReadJUnit(path)
+	assert.NoError(t, err)
+	assert.Equal(t, merged.TestSuites[0].TestCases, read.TestSuites[0].TestCases)
+}
+
+func TestMergeRetriedJUnit(t *testing.T) {
+	first := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{TestCases: []JUnitTestCase{
+		{Name: "[sig-apps] a", Status: StatusPassed, Time: 1},
+		{Name: "[sig-network] b", Status: StatusFailed, Failure: &JUnitMessage{Message: "boom"}, Time: 2},
+		{Name: "[sig-node] c", Status: StatusSkipped},
+	}}}}
+	retry := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{TestCases: []JUnitTestCase{
+		{Name: "[sig-apps] a", Status: StatusFailed, Failure: &JUnitMessage{Message: "flaky"}, Time: 3},
+		{Name: "[sig-network] b", Status: StatusPassed, Time: 4},
+		{Name: "[sig-node] c", Status: StatusPassed, Time: 5},
+	}}}}
+
+	tests := []struct {
+		policy string
+		times  []float64
+	}{
+		{policy: MergeBest, times: []float64{1, 4, 5}},
+		{policy: MergeWorst, times: []float64{3, 2, 5}},
+		{policy: MergeLast, times: []float64{3, 4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			merged, err := MergeRetriedJUnit(tt.policy, first, retry)
+			require.NoError(t, err)
+			var times []float64
+			for _, tc := range merged.TestSuites[0].TestCases {
+				times = append(times, tc.Time)
+			}
+			assert.Equal(t, tt.times, times)
+		})
+	}
+
+	merged, err := MergeRetriedJUnit(MergeBest, first, retry)
+	require.NoError(t, err)
+	assert.Equal(t, 0, merged.Failures)
+	assert.Equal(t, 3, merged.Tests)
+
+	_, err = MergeRetriedJUnit("first", first, retry)
+	assert.Error(t, err)
+}
+
+func TestTruncateJUnit(t *testing.T) {
+	output := strings.Repeat("a", 10) + strings.Repeat("b", 100) + strings.Repeat("c", 10)
+	suites := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{
+		TestCases: []JUnitTestCase{
+			{Name: "noisy", Status: StatusFailed, SystemErr: output, Failure: &JUnitMessage{Description: output}},
+			{Name: "quiet", Status: StatusPassed, SystemErr: "short"},
+		},
+	}}}
+
+	assert.Equal(t, 1, TruncateJUnit(suites, 20))
+
+	noisy := suites.TestSuites[0].TestCases[0]
+	expected := "aaaaaaaaaa\n\n... [100 bytes truncated by hydrophone] ...\n\ncccccccccc"
+	assert.Equal(t, expected, noisy.SystemErr)
+	assert.Equal(t, expected, noisy.Failure.Description)
+	assert.Equal(t, "short", suites.TestSuites[0].TestCases[1].SystemErr)
+	assert.Equal(t, []JUnitProperty{{Name: TruncatedSpecsProperty, Value: "1"}}, suites.TestSuites[0].Properties.Properties)
+}
+
+func TestTruncateString(t *testing.T) {
+	// the cuts move to the closest rune boundary inside the kept parts
+	truncated := truncateString("ééééé", 4)
+	assert.True(t, utf8.ValidString(truncated))
+	assert.Equal(t, "é\n\n... [6 bytes truncated by hydrophone] ...\n\né", truncated)
+}
+
+func TestAddProperties(t *testing.T) {
+	properties, err := ParseJUnitProperties([]string{"cluster=prod-eu-1", "url=https://ci.example.com/?run=1", "empty="})
+	assert.NoError(t, err)
+
+	suites := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{
+		Properties: &JUnitProperties{Properties: []JUnitProperty{{Name: "cluster", Value: "unknown"}, {Name: "SuiteSucceeded", Value: "true"}}},
+	}}}
+	AddProperties(suites, properties)
+	assert.Equal(t, []JUnitProperty{
+		{Name: "cluster", Value: "prod-eu-1"},
+		{Name: "SuiteSucceeded", Value: "true"},
+		{Name: "url", Value: "https://ci.example.com/?run=1"},
+		{Name: "empty", Value: ""},
+	}, suites.TestSuites[0].Properties.Properties)
+
+	_, err = ParseJUnitProperties([]string{"cluster"})
+	assert.Error(t, err)
+	_, err = ParseJUnitProperties([]string{"=value"})
+	assert.Error(t, err)
+}
+
+func TestSelectedTests(t *testing.T) {
+	suites := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{
+		TestCases: []JUnitTestCase{
+			{Name: "[SynchronizedBeforeSuite]", Status: StatusPassed},
+			{Name: "[It] [sig-node] selected test", Status: StatusPassed},
+			{Name: "[It] [sig-node] other test", Status: StatusSkipped},
+			{Name: "[It] [sig-apps] pending test", Status: StatusPending},
+		},
+	}}}
+	assert.Equal(t, []string{"[sig-node] selected test"}, SelectedTests(suites))
+}
+
+func TestAllTests(t *testing.T) {
+	suites := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{
+		TestCases: []JUnitTestCase{
+			{Name: "[SynchronizedBeforeSuite]", Status: StatusPassed},
+			{Name: "[It] [sig-node] selected test", Status: StatusPassed},
+			{Name: "[It] [sig-node] other test", Status: StatusSkipped},
+		},
+	}}}
+	assert.Equal(t, []string{"[sig-node] selected test", "[sig-node] other test"}, AllTests(suites))
+}
+
+func TestFailedTests(t *testing.T) {
+	suites := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{
+		TestCases: []JUnitTestCase{
+			{Name: "[It] [sig-node] passed test", Status: StatusPassed},
+			{Name: "[It] [sig-node] failed test", Status: StatusFailed, Failure: &JUnitMessage{}},
+			{Name: "[It] [sig-apps] errored test", Status: StatusFailed, Error: &JUnitMessage{}},
+		},
+	}}}
+	assert.Equal(t, []string{"[sig-node] failed test", "[sig-apps] errored test"}, FailedTests(suites))
+}
+
+func TestPassedTests(t *testing.T) {
+	suites := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{
+		TestCases: []JUnitTestCase{
+			{Name: "[SynchronizedBeforeSuite]", Status: StatusPassed},
+			{Name: "[It] [sig-node] passed test", Status: StatusPassed},
+			{Name: "[It] [sig-node] failed test", Status: StatusFailed, Failure: &JUnitMessage{}},
+			{Name: "[It] [sig-apps] skipped test", Status: StatusSkipped},
+		},
+	}}}
+	assert.Equal(t, []string{"[sig-node] passed test"}, PassedTests(suites))
+}
+
+func TestMergeJUnitResumed(t *testing.T) {
+	paused := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{
+		TestCases: []JUnitTestCase{
+			{Name: "[It] passed before the pause", Status: StatusPassed},
+			{Name: "[It] interrupted", Status: StatusFailed, Failure: &JUnitMessage{Message: "interrupted by user"}},
+			{Name: "[It] not reached", Status: StatusSkipped},
+		},
+	}}}
+	resumed := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{
+		TestCases: []JUnitTestCase{
+			{Name: "[It] passed before the pause", Status: StatusSkipped},
+			{Name: "[It] interrupted", Status: StatusPassed},
+			{Name: "[It] not reached", Status: StatusPassed},
+		},
+	}}}
+
+	merged := MergeJUnit(resumed, paused)
+	for _, tc := range merged.TestSuites[0].TestCases {
+		assert.Equal(t, StatusPassed, tc.Status, tc.Name)
+	}
+	assert.Equal(t, 0, merged.Failures)
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Status of the phases of a suite
+const (
+	PhasePassed = "passed"
+	PhaseFailed = "failed"
+	PhaseNotRun = "not-run"
+)
+
+// MetadataFile is the name of the file holding the run metadata in the output directory
+const MetadataFile = "results.json"
+
+// Metadata describes a single hydrophone run and is written next to the
+// downloaded test artifacts.
+type Metadata struct {
+	// SchemaVersion is the version of the schema of the file, see Schema
+	SchemaVersion int `json:"schemaVersion"`
+	// ServerVersion is the version reported by the cluster, including the
+	// suffix of the distribution, e.g. v1.28.6-eks-1234
+	ServerVersion string `json:"serverVersion,omitempty"`
+	// RunID is the ID of --run-id isolating the run from the other runs on
+	// the cluster
+	RunID string `json:"runId,omitempty"`
+	// Labels are the key=value pairs of --label
+	Labels map[string]string `json:"labels,omitempty"`
+	// CI is the CI job the run was made by
+	CI *CI `json:"ci,omitempty"`
+	// Cluster describes the cluster at the start of the run
+	Cluster          *Cluster `json:"cluster,omitempty"`
+	ConformanceImage string   `json:"conformanceImage,omitempty"`
+	// BusyboxImage is the image of the output container, pinned to its
+	// digest when the registry could be queried
+	BusyboxImage string `json:"busyboxImage,omitempty"`
+	// RuntimeClass is the runtime class of the conformance pods given with
+	// --runtime-class
+	RuntimeClass string `json:"runtimeClass,omitempty"`
+	Focus        string `json:"focus,omitempty"`
+	Skip         string `json:"skip,omitempty"`
+	// Certified is set when the run was made with --certified, which
+	// enforces the configuration of a CNCF Certified Kubernetes submission
+	Certified bool `json:"certified,omitempty"`
+	// Restricted is set when the run was made with --restricted, without
+	// cluster-scoped permissions
+	Restricted *Restricted `json:"restricted,omitempty"`
+	// Seed is the random seed ginkgo used to order the specs. Passing it back
+	// through --seed reproduces the same ordering.
+	Seed int64 `json:"seed,omitempty"`
+	// Parallel is the number of parallel test processes, ParallelAuto is set
+	// when it was derived from the size of the cluster.
+	Parallel     int  `json:"parallel,omitempty"`
+	ParallelAuto bool `json:"parallelAuto,omitempty"`
+	ExitCode     int  `json:"exitCode"`
+	// Paused is set when the run was paused with hydrophone pause
+	Paused bool `json:"paused,omitempty"`
+	// Aborted holds the reason the run was aborted before the tests completed
+	Aborted string `json:"aborted,omitempty"`
+	// TimedOut is set when the run was aborted because it exceeded
+	// --startup-timeout or --timeout
+	TimedOut bool `json:"timedOut,omitempty"`
+	// Partial is set when the artifacts of a conformance pod couldn't be
+	// fetched and only its log was recovered from the log API
+	Partial bool `json:"partial,omitempty"`
+	// Reconnects counts how often watches and log streams were re-established
+	// after the API server closed them
+	Reconnects int64 `json:"reconnects,omitempty"`
+	// PodRestarts counts the conformance pods that were lost and replaced by
+	// their job with --workload=job, the tests started over in each replacement
+	PodRestarts int64 `json:"podRestarts,omitempty"`
+	// Phases holds the outcome of each phase when running a suite file
+	Phases []PhaseResult `json:"phases,omitempty"`
+	// Nodes holds the outcome of the node conformance tests of each node
+	// given with --node
+	Nodes []PhaseResult `json:"nodes,omitempty"`
+	// Failures lists the failed tests
+	Failures []Failure `json:"failures,omitempty"`
+	// Skipped counts the skipped tests by the reason they were skipped, the
+	// tests are listed in skipped.json
+	Skipped map[string]int `json:"skipped,omitempty"`
+	// Usage estimates the compute consumed by the run
+	Usage *Usage `json:"usage,omitempty"`
+	// Timing is the time the specs of the run took
+	Timing *Timing `json:"timing,omitempty"`
+	// Regressions compares the durations of the specs with the run given
+	// with --baseline
+	Regressions *Regressions `json:"regressions,omitempty"`
+	// Hygiene holds the resources the tests left behind in the cluster
+	Hygiene *Hygiene `json:"hygiene,omitempty"`
+}
+
+// Hygiene is what the e2e tests left behind in the cluster once the run
+// completed, resources they should have cleaned up
+type Hygiene struct {
+	// Leaked lists the names of the leaked resources by kind, e.g.
+	// persistentvolume, namespaced resources as namespace/name
+	Leaked map[string][]string `json:"leaked,omitempty"`
+	// Deleted is set when the leaked resources were deleted after the run
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// Usage is the compute consumed by the pods of a run, integrated over the
+// samples of the metrics API
+type Usage struct {
+	Samples          int     `json:"samples"`
+	CPUCoreSeconds   float64 `json:"cpuCoreSeconds"`
+	MemoryGiBSeconds float64 `json:"memoryGiBSeconds"`
+	PeakCPUCores     float64 `json:"peakCpuCores"`
+	PeakMemoryBytes  int64   `json:"peakMemoryBytes"`
+	PeakPods         int     `json:"peakPods"`
+	// Cost is estimated from --cost-per-cpu-hour and --cost-per-gib-hour
+	Cost float64 `json:"cost,omitempty"`
+}
+
+// Estimate sets the cost of the usage from the prices of a CPU core and of a
+// GiB of memory per hour.
+func (u *Usage) Estimate(perCPUHour, perGiBHour float64) {
+	u.Cost = u.CPUCoreSeconds/3600*perCPUHour + u.MemoryGiBSeconds/3600*perGiBHour
+}
+
+// Add sums the usage of consecutive runs, e.g. the phases of a suite. Peaks
+// are the highest peak of both.
+func (u *Usage) Add(other *Usage) {
+	if other == nil {
+		return
+	}
+	u.Samples += other.Samples
+	u.CPUCoreSeconds += other.CPUCoreSeconds
+	u.MemoryGiBSeconds += other.MemoryGiBSeconds
+	u.Cost += other.Cost
+	u.PeakCPUCores = max(u.PeakCPUCores, other.PeakCPUCores)
+	u.PeakMemoryBytes = max(u.PeakMemoryBytes, other.PeakMemoryBytes)
+	u.PeakPods = max(u.PeakPods, other.PeakPods)
+}
+
+// Failure is a test that failed in the run
+type Failure struct {
+	Name string `json:"name"`
+	// UpstreamRuns and UpstreamFailures count the recent runs and failures of
+	// the test in the upstream TestGrid tab given with --upstream-flakes
+	UpstreamRuns     int `json:"upstreamRuns,omitempty"`
+	UpstreamFailures int `json:"upstreamFailures,omitempty"`
+}
+
+// PhaseResult is the outcome of a single phase of a suite file, or of the
+// node conformance tests of a single node
+type PhaseResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	ExitCode int    `json:"exitCode"`
+}
+
+// WriteMetadata writes the metadata as indented JSON to the output directory.
+func WriteMetadata(outputDir string, m *Metadata) error {
+	m.SchemaVersion = SchemaVersion
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding run metadata: %w", err)
+	}
+	path := filepath.Join(outputDir, MetadataFile)
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadMetadata reads the metadata of a previous run from the given directory.
+func ReadMetadata(dir string) (*Metadata, error) {
+	data, err := os.ReadFile(filepath.Join(dir, MetadataFile))
+	if err != nil {
+		return nil, err
+	}
+	m := &Metadata{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", MetadataFile, err)
+	}
+	return m, nil
+}
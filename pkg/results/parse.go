@@ -0,0 +1,232 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// sonobuoyResultsDir is where sonobuoy keeps the artifacts of the e2e plugin
+// in its results tarball once extracted
+var sonobuoyResultsDir = filepath.Join("plugins", "e2e", "results", "global")
+
+const (
+	// e2eSuiteName is the name of the suite of the reports parsed from e2e.log
+	e2eSuiteName = "Kubernetes e2e suite"
+	// specSeparator is printed by ginkgo between the output of two specs
+	specSeparator = "------------------------------"
+)
+
+var (
+	// specEndRegexp matches the line ginkgo prints when a spec completed
+	// along with its state and its duration, e.g. "• [12.345 seconds]" or
+	// "• [FAILED] [1.234 seconds]"
+	specEndRegexp = regexp.MustCompile(`^[•SP] \[(?:(FAILED|PANICKED|TIMEDOUT|INTERRUPTED|SKIPPED|PENDING)\])?`)
+	// specDurationRegexp matches the duration of the line of specEndRegexp
+	specDurationRegexp = regexp.MustCompile(`\[([0-9.]+) seconds\]`)
+	// failureRegexp matches the first line of the failure message of a spec
+	failureRegexp = regexp.MustCompile(`^\[(FAILED|PANICKED|TIMEDOUT|INTERRUPTED)\] (.*)$`)
+	// ranRegexp matches the line ginkgo prints at the end of the run
+	ranRegexp = regexp.MustCompile(`^Ran \d+ of \d+ Specs in ([0-9.]+) seconds`)
+	// countsRegexp matches the counts ginkgo prints at the end of the run
+	countsRegexp = regexp.MustCompile(`(?:SUCCESS|FAIL)! .*-- (\d+) Passed \| (\d+) Failed \| (\d+) Pending \| (\d+) Skipped`)
+)
+
+// LoadReport reads the results of the specs of a run from the given path:
+// a junit report, an e2e.log, or a directory holding either, e.g. the output
+// directory of hydrophone, bundled or not, or the extracted results of
+// sonobuoy. The junit report is preferred over e2e.log. The file the results
+// were read from is returned along with them.
+func LoadReport(path string) (*JUnitTestSuites, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", err
+		}
+		report, err := parseReport(path, data)
+		return report, path, err
+	}
+
+	for _, dir := range []string{path, filepath.Join(path, sonobuoyResultsDir)} {
+		files, err := readResults(dir, "junit_01.xml", "e2e.log", "e2e.log.gz")
+		if err != nil {
+			return nil, "", err
+		}
+		for _, name := range []string{"junit_01.xml", "e2e.log", "e2e.log.gz"} {
+			if data, ok := files[name]; ok {
+				report, err := parseReport(name, data)
+				return report, filepath.Join(dir, name), err
+			}
+		}
+	}
+	return nil, "", fmt.Errorf("neither junit_01.xml nor e2e.log found in %s", path)
+}
+
+// parseReport parses the junit report or the e2e.log of the named file
+func parseReport(name string, data []byte) (*JUnitTestSuites, error) {
+	switch {
+	case strings.HasSuffix(name, ".xml"):
+		report := &JUnitTestSuites{}
+		if err := xml.Unmarshal(data, report); err != nil {
+			return nil, fmt.Errorf("error parsing junit report %s: %w", name, err)
+		}
+		return report, nil
+	case strings.HasSuffix(name, ".gz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", name, err)
+		}
+		defer gz.Close()
+		return ParseE2ELog(gz)
+	default:
+		return ParseE2ELog(bytes.NewReader(data))
+	}
+}
+
+// ParseE2ELog reads the results of the specs from the output of the e2e
+// tests, for runs whose junit report is missing. The specs are named when
+// ginkgo printed their names, which it does for the specs that didn't pass
+// or were slow, and for all specs in verbose mode. The counts of the suite
+// are the ones ginkgo prints at the end of the run, when it got there.
+func ParseE2ELog(r io.Reader) (*JUnitTestSuites, error) {
+	suite := JUnitTestSuite{Name: e2eSuiteName}
+	var spec *JUnitTestCase
+	// separated is set when the last line was a separator
+	separated := false
+	// name is the name of the spec whose output follows the separator
+	name := ""
+	// message is set while reading the failure message of the spec
+	message := false
+	// section is set while reading a section of the report of a failed
+	// spec, e.g. its timeline, which holds failure markers of its own
+	section := false
+	done := func() {
+		if spec != nil && spec.Name != "" {
+			if spec.Failure != nil {
+				spec.Failure.Message = strings.TrimSpace(spec.Failure.Message)
+			}
+			suite.TestCases = append(suite.TestCases, *spec)
+		}
+		spec, name, message, section = nil, "", false, false
+	}
+	var passed, failed, pending, skipped int
+	counted := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, specSeparator):
+			done()
+			separated = true
+			continue
+		case specEndRegexp.MatchString(line):
+			started := name
+			done()
+			match := specEndRegexp.FindStringSubmatch(line)
+			spec = &JUnitTestCase{Name: started, Status: specStatus(match[1])}
+			if duration := specDurationRegexp.FindStringSubmatch(line); duration != nil {
+				spec.Time, _ = strconv.ParseFloat(duration[1], 64)
+			}
+			if spec.Status == StatusFailed {
+				spec.Failure = &JUnitMessage{Type: "failed"}
+			}
+		case spec != nil && spec.Name == "" && strings.HasPrefix(line, "["):
+			spec.Name = specName(line)
+		case spec != nil && strings.HasSuffix(line, " >>"):
+			section = true
+		case section:
+			section = !strings.HasPrefix(line, "<< ")
+		case spec != nil && spec.Failure != nil && spec.Failure.Message == "" && failureRegexp.MatchString(line):
+			spec.Failure.Message = failureRegexp.FindStringSubmatch(line)[2]
+			message = true
+		case message:
+			if line == "" || strings.HasPrefix(line, "In [") {
+				message = false
+			} else {
+				spec.Failure.Message += "\n" + line
+			}
+		case separated && spec == nil && strings.HasPrefix(line, "["):
+			name = specName(line)
+		case ranRegexp.MatchString(line):
+			seconds, _ := strconv.ParseFloat(ranRegexp.FindStringSubmatch(line)[1], 64)
+			suite.Time += seconds
+		case countsRegexp.MatchString(line):
+			match := countsRegexp.FindStringSubmatch(line)
+			counts := make([]int, 4)
+			for i := range counts {
+				counts[i], _ = strconv.Atoi(match[i+1])
+			}
+			passed, failed, pending, skipped = passed+counts[0], failed+counts[1], pending+counts[2], skipped+counts[3]
+			counted = true
+		}
+		separated = false
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading the e2e log: %w", err)
+	}
+	done()
+
+	suite.updateCounts()
+	if counted {
+		suite.Tests = passed + failed + pending + skipped
+		suite.Failures, suite.Errors, suite.Disabled, suite.Skipped = failed, 0, pending, skipped
+	}
+	return &JUnitTestSuites{
+		Tests:      suite.Tests,
+		Disabled:   suite.Disabled,
+		Failures:   suite.Failures,
+		Time:       suite.Time,
+		TestSuites: []JUnitTestSuite{suite},
+	}, nil
+}
+
+// specStatus returns the status of a spec from the state ginkgo reports
+// when it completes
+func specStatus(state string) string {
+	switch state {
+	case "":
+		return StatusPassed
+	case "SKIPPED":
+		return StatusSkipped
+	case "PENDING":
+		return StatusPending
+	default:
+		return StatusFailed
+	}
+}
+
+// specName returns the name of a spec of the junit report from the
+// hierarchy ginkgo prints, e.g. "[sig-network] DNS [It] should resolve"
+func specName(line string) string {
+	return "[It] " + strings.Replace(line, "[It] ", "", 1)
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// e2eLog is the output of a run of ginkgo, naming the failed and slow specs only
+const e2eLog = `Running Suite: Kubernetes e2e suite - /usr/local/bin
+Will run 3 of 7000 specs
+SSSSSS•
+------------------------------
+• [FAILED] [302.112 seconds]
+[sig-network] DNS [It] should provide DNS for the cluster  [Conformance]
+test/e2e/network/dns.go:50
+
+  Timeline >>
+  STEP: Creating a kubernetes client @ 01/02/24 10:00:00.000
+  [FAILED] in [It] - test/e2e/network/dns.go:75 @ 01/02/24 10:05:00.000
+  << Timeline
+
+  [FAILED] Unexpected error:
+      timed out waiting for the condition
+  In [It] at: test/e2e/network/dns.go:75 @ 01/02/24 10:05:00.000
+------------------------------
+SSSS
+------------------------------
+• [SLOW TEST] [65.002 seconds]
+[sig-apps] Deployment [It] should proceed
+test/e2e/apps/deployment.go:10
+------------------------------
+SSS
+
+Ran 3 of 7000 Specs in 420.5 seconds
+FAIL! -- 2 Passed | 1 Failed | 0 Pending | 6997 Skipped
+`
+
+func TestParseE2ELog(t *testing.T) {
+	report, err := ParseE2ELog(strings.NewReader(e2eLog))
+	require.NoError(t, err)
+	require.Len(t, report.TestSuites, 1)
+	suite := report.TestSuites[0]
+	assert.Equal(t, []JUnitTestCase{
+		{
+			Name:    "[It] [sig-network] DNS should provide DNS for the cluster  [Conformance]",
+			Status:  StatusFailed,
+			Time:    302.112,
+			Failure: &JUnitMessage{Type: "failed", Message: "Unexpected error:\ntimed out waiting for the condition"},
+		},
+		{Name: "[It] [sig-apps] Deployment should proceed", Status: StatusPassed, Time: 65.002},
+	}, suite.TestCases)
+	assert.Equal(t, 7000, suite.Tests)
+	assert.Equal(t, 1, suite.Failures)
+	assert.Equal(t, 6997, suite.Skipped)
+	assert.Equal(t, 420.5, suite.Time)
+}
+
+func TestParseE2ELogVerbose(t *testing.T) {
+	log := `------------------------------
+[sig-node] Pods should be submitted and removed [Conformance]
+test/e2e/common/node/pods.go:226
+  STEP: Creating a kubernetes client @ 01/02/24 10:00:00.000
+• [5.120 seconds]
+------------------------------
+S [SKIPPED] [0.001 seconds]
+[sig-storage] Volumes [It] should mount
+test/e2e/storage/volumes.go:10
+------------------------------
+`
+	report, err := ParseE2ELog(strings.NewReader(log))
+	require.NoError(t, err)
+	assert.Equal(t, []JUnitTestCase{
+		{Name: "[It] [sig-node] Pods should be submitted and removed [Conformance]", Status: StatusPassed, Time: 5.12},
+		{Name: "[It] [sig-storage] Volumes should mount", Status: StatusSkipped, Time: 0.001},
+	}, report.TestSuites[0].TestCases)
+	// without the counts of the end of the run the specs are counted
+	assert.Equal(t, 2, report.TestSuites[0].Tests)
+	assert.Equal(t, 1, report.TestSuites[0].Skipped)
+}
+
+func TestLoadReport(t *testing.T) {
+	dir := t.TempDir()
+	_, _, err := LoadReport(dir)
+	assert.Error(t, err)
+
+	sonobuoy := filepath.Join(dir, sonobuoyResultsDir)
+	require.NoError(t, os.MkdirAll(sonobuoy, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sonobuoy, "e2e.log"), []byte(e2eLog), 0600))
+	report, source, err := LoadReport(dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(sonobuoy, "e2e.log"), source)
+	assert.Len(t, report.TestSuites[0].TestCases, 2)
+
+	// the junit report is preferred over e2e.log
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "e2e.log"), []byte(e2eLog), 0600))
+	require.NoError(t, WriteJUnit(filepath.Join(dir, "junit_01.xml"), &JUnitTestSuites{TestSuites: []JUnitTestSuite{{
+		TestCases: []JUnitTestCase{{Name: "[It] [sig-apps] Deployment should proceed", Status: StatusPassed, Time: 60}},
+	}}}))
+	report, source, err = LoadReport(dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "junit_01.xml"), source)
+	assert.Len(t, report.TestSuites[0].TestCases, 1)
+
+	report, source, err = LoadReport(filepath.Join(dir, "e2e.log"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "e2e.log"), source)
+	assert.Len(t, report.TestSuites[0].TestCases, 2)
+}
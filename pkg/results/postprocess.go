@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+const (
+	// FormatCSV and FormatHTML are the built-in formats of --results-format
+	FormatCSV  = "csv"
+	FormatHTML = "html"
+	// HTMLFile is the name of the file of --results-format=html in the output
+	// directory
+	HTMLFile = "summary.html"
+)
+
+// PostProcessor processes the results of a run in its output directory once
+// the tests completed, e.g. to convert them to another format or to upload
+// them. The formats of --results-format are built-in post-processors, the
+// binaries of --post-process are run through ExecProcessor.
+type PostProcessor interface {
+	// Name identifies the post-processor in the logs
+	Name() string
+	// Process processes the results of the output directory
+	Process(dir string) error
+}
+
+// JUnitProcessor rewrites the junit report of the directory, capping the
+// output of each spec to MaxSpecOutput bytes and adding Properties.
+type JUnitProcessor struct {
+	MaxSpecOutput int
+	Properties    []JUnitProperty
+	// OnTruncate, when set, is told how many outputs were truncated
+	OnTruncate func(count int)
+}
+
+func (p *JUnitProcessor) Name() string { return "junit" }
+
+func (p *JUnitProcessor) Process(dir string) error {
+	if p.MaxSpecOutput <= 0 && len(p.Properties) == 0 {
+		return nil
+	}
+	path := filepath.Join(dir, "junit_01.xml")
+	report, err := ReadJUnit(path)
+	if err != nil {
+		return err
+	}
+	if count := TruncateJUnit(report, p.MaxSpecOutput); count > 0 && p.OnTruncate != nil {
+		p.OnTruncate(count)
+	}
+	AddProperties(report, p.Properties)
+	return WriteJUnit(path, report)
+}
+
+// CSVProcessor writes the results of the tests of the junit report of the
+// directory to CSVFile
+type CSVProcessor struct{}
+
+func (CSVProcessor) Name() string { return FormatCSV }
+
+func (CSVProcessor) Process(dir string) error {
+	report, err := ReadJUnit(filepath.Join(dir, "junit_01.xml"))
+	if err != nil {
+		return err
+	}
+	return WriteCSVFile(dir, report)
+}
+
+// HTMLProcessor writes the summary of the junit report of the directory to
+// HTMLFile as a standalone page, listing the Slowest specs
+type HTMLProcessor struct {
+	Slowest int
+}
+
+func (HTMLProcessor) Name() string { return FormatHTML }
+
+func (p HTMLProcessor) Process(dir string) error {
+	source := filepath.Join(dir, "junit_01.xml")
+	report, err := ReadJUnit(source)
+	if err != nil {
+		return err
+	}
+	summary, err := Summarize(report, source, p.Slowest)
+	if err != nil {
+		return err
+	}
+	if metadata, err := ReadMetadata(dir); err == nil {
+		summary.Labels, summary.CI = metadata.Labels, metadata.CI
+	}
+	path := filepath.Join(dir, HTMLFile)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := summary.WriteHTML(f); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// ExecProcessor runs a binary with the output directory as argument, also
+// given in HYDROPHONE_RESULTS_DIR, so that custom converters and uploaders
+// can be plugged in without changing hydrophone. Env is added to the
+// environment of the binary. A non-zero exit code is an error.
+type ExecProcessor struct {
+	Path   string
+	Env    []string
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+func (p *ExecProcessor) Name() string { return p.Path }
+
+func (p *ExecProcessor) Process(dir string) error {
+	cmd := exec.Command(p.Path, dir)
+	cmd.Env = append(append(os.Environ(), "HYDROPHONE_RESULTS_DIR="+dir), p.Env...)
+	cmd.Stdout, cmd.Stderr = p.Stdout, p.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error running %s: %w", p.Path, err)
+	}
+	return nil
+}
+
+// FormatProcessor returns the built-in post-processor of the format of
+// --results-format
+func FormatProcessor(format string, slowest int) (PostProcessor, error) {
+	switch format {
+	case FormatCSV:
+		return CSVProcessor{}, nil
+	case FormatHTML:
+		return HTMLProcessor{Slowest: slowest}, nil
+	}
+	return nil, fmt.Errorf("unknown results format %q", format)
+}
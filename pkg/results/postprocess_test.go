@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestReport writes a junit report with a passed and a failed spec to
+// the directory
+func writeTestReport(t *testing.T, dir string) {
+	t.Helper()
+	report := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{Tests: 2, Failures: 1, TestCases: []JUnitTestCase{
+		{Name: "[It] [sig-apps] Deployment should proceed", Status: StatusPassed, Time: 12},
+		{Name: "[It] [sig-network] DNS should resolve", Status: StatusFailed, Time: 300,
+			Failure: &JUnitMessage{Message: "timed out"}, SystemOut: strings.Repeat("x", 100)},
+	}}}}
+	require.NoError(t, WriteJUnit(filepath.Join(dir, "junit_01.xml"), report))
+}
+
+func TestJUnitProcessor(t *testing.T) {
+	dir := t.TempDir()
+	writeTestReport(t, dir)
+
+	truncated := 0
+	processor := &JUnitProcessor{
+		MaxSpecOutput: 10,
+		Properties:    []JUnitProperty{{Name: "cluster", Value: "kind"}},
+		OnTruncate:    func(count int) { truncated = count },
+	}
+	require.NoError(t, processor.Process(dir))
+	assert.Equal(t, 1, truncated)
+	report, err := ReadJUnit(filepath.Join(dir, "junit_01.xml"))
+	require.NoError(t, err)
+	assert.Less(t, len(report.TestSuites[0].TestCases[1].SystemOut), 100)
+	assert.Contains(t, report.TestSuites[0].Properties.Properties, JUnitProperty{Name: "cluster", Value: "kind"})
+
+	// nothing to do without a report
+	assert.NoError(t, (&JUnitProcessor{}).Process(t.TempDir()))
+}
+
+func TestFormatProcessors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestReport(t, dir)
+
+	for format, file := range map[string]string{FormatCSV: CSVFile, FormatHTML: HTMLFile} {
+		processor, err := FormatProcessor(format, 10)
+		require.NoError(t, err)
+		assert.Equal(t, format, processor.Name())
+		require.NoError(t, processor.Process(dir))
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "DNS should resolve")
+	}
+
+	_, err := FormatProcessor("xml", 10)
+	assert.Error(t, err)
+	processor, _ := FormatProcessor(FormatCSV, 10)
+	assert.Error(t, processor.Process(t.TempDir()), "the junit report is missing")
+}
+
+func TestExecProcessor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the post-processor is a shell script")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(t.TempDir(), "post-process.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho \"$1 $HYDROPHONE_RESULTS_DIR $HYDROPHONE_EXIT_CODE\"\nexit $HYDROPHONE_EXIT_CODE\n"), 0755))
+
+	var stdout bytes.Buffer
+	processor := &ExecProcessor{Path: script, Env: []string{"HYDROPHONE_EXIT_CODE=0"}, Stdout: &stdout}
+	assert.Equal(t, script, processor.Name())
+	require.NoError(t, processor.Process(dir))
+	assert.Equal(t, dir+" "+dir+" 0\n", stdout.String())
+
+	processor.Env = []string{"HYDROPHONE_EXIT_CODE=1"}
+	assert.ErrorContains(t, processor.Process(dir), "exit status 1")
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ProgressFile is the name of the snapshot of the progress of a running run
+// in the output directory
+const ProgressFile = "progress.json"
+
+// Progress is a snapshot of the progress of a run, rewritten while it runs
+// so that it can be polled without parsing the logs.
+type Progress struct {
+	// Phase is the step of the run, e.g. setup, stream logs or cleanup, and
+	// finished once the run completed
+	Phase string `json:"phase"`
+	// CurrentTest is the spec that started last, empty between specs
+	CurrentTest    string  `json:"currentTest,omitempty"`
+	Total          int64   `json:"total"`
+	Completed      int64   `json:"completed"`
+	Passed         int64   `json:"passed"`
+	Failed         int64   `json:"failed"`
+	Skipped        int64   `json:"skipped"`
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+	// ExitCode is set once the run finished
+	ExitCode *int `json:"exitCode,omitempty"`
+}
+
+// WriteProgress writes the snapshot to the output directory. It is written to
+// a temporary file renamed over ProgressFile, so that readers never see a
+// partial snapshot.
+func WriteProgress(outputDir string, p *Progress) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding progress: %w", err)
+	}
+	tmp, err := os.CreateTemp(outputDir, "."+ProgressFile+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// the snapshot is meant to be read by other processes
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	path := filepath.Join(outputDir, ProgressFile)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadProgress reads the snapshot of the progress of a run from the output
+// directory.
+func ReadProgress(outputDir string) (*Progress, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, ProgressFile))
+	if err != nil {
+		return nil, err
+	}
+	p := &Progress{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", ProgressFile, err)
+	}
+	return p, nil
+}
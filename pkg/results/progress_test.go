@@ -0,0 +1,47 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteProgress(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, WriteProgress(dir, &Progress{Phase: "stream logs", CurrentTest: "[sig-node] Pods should run", Total: 10, Completed: 3, Passed: 2, Failed: 1, ElapsedSeconds: 42}))
+	p, err := ReadProgress(dir)
+	require.NoError(t, err)
+	assert.Equal(t, &Progress{Phase: "stream logs", CurrentTest: "[sig-node] Pods should run", Total: 10, Completed: 3, Passed: 2, Failed: 1, ElapsedSeconds: 42}, p)
+
+	exitCode := 1
+	require.NoError(t, WriteProgress(dir, &Progress{Phase: "finished", Total: 10, Completed: 10, ExitCode: &exitCode}))
+	p, err = ReadProgress(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "finished", p.Phase)
+	assert.Empty(t, p.CurrentTest)
+	assert.Equal(t, &exitCode, p.ExitCode)
+
+	// the temporary files are renamed over the snapshot
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, ProgressFile, entries[0].Name())
+}
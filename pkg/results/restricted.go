@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// forbiddenRegexp matches the error of the API server denying a
+// cluster-scoped request, e.g. `User "system:serviceaccount:ns:sa" cannot
+// create resource "namespaces" in API group "" at the cluster scope`. The
+// quotes may be escaped in the failure messages.
+var forbiddenRegexp = regexp.MustCompile(`cannot (\S+) resource \\?"([^"\\]+)\\?" in API group \\?"([^"\\]*)\\?" at the cluster scope`)
+
+// Restricted describes a run made with --restricted, without cluster-scoped
+// permissions
+type Restricted struct {
+	// Denied lists the specs that failed because a cluster-scoped request
+	// was denied, they can't run with the permissions of the run
+	Denied []DeniedSpec `json:"denied,omitempty"`
+}
+
+// DeniedSpec is a spec that failed because of cluster-scoped requests the
+// service account of the run isn't allowed to make
+type DeniedSpec struct {
+	Name string `json:"name"`
+	// Permissions are the denied requests, e.g. "create namespaces" or
+	// "list clusterroles.rbac.authorization.k8s.io"
+	Permissions []string `json:"permissions"`
+}
+
+// DeniedSpecs returns the failed specs of the report whose failure message
+// has cluster-scoped requests denied by the API server
+func DeniedSpecs(suites *JUnitTestSuites) []DeniedSpec {
+	var denied []DeniedSpec
+	for _, s := range suites.TestSuites {
+		for _, tc := range s.TestCases {
+			var permissions []string
+			for _, m := range []*JUnitMessage{tc.Failure, tc.Error} {
+				if m == nil {
+					continue
+				}
+				for _, match := range forbiddenRegexp.FindAllStringSubmatch(m.Message, -1) {
+					resource := match[2]
+					if match[3] != "" {
+						resource += "." + match[3]
+					}
+					permission := fmt.Sprintf("%s %s", match[1], resource)
+					if !slices.Contains(permissions, permission) {
+						permissions = append(permissions, permission)
+					}
+				}
+			}
+			if len(permissions) != 0 {
+				denied = append(denied, DeniedSpec{Name: strings.TrimPrefix(tc.Name, "[It] "), Permissions: permissions})
+			}
+		}
+	}
+	return denied
+}
+
+// DeniedPermissions counts the denied specs by permission
+func DeniedPermissions(denied []DeniedSpec) map[string]int {
+	counts := map[string]int{}
+	for _, spec := range denied {
+		for _, permission := range spec.Permissions {
+			counts[permission]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeniedSpecs(t *testing.T) {
+	namespaces := `namespaces is forbidden: User "system:serviceaccount:team-a:e2e" cannot create resource "namespaces" in API group "" at the cluster scope`
+	report := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{TestCases: []JUnitTestCase{
+		{Name: "[It] [sig-apps] Deployment should proceed", Status: StatusFailed, Failure: &JUnitMessage{Message: "failed to create namespace: " + namespaces}},
+		{Name: "[It] [sig-auth] ServiceAccounts should mount a token", Status: StatusFailed, Failure: &JUnitMessage{
+			Message: namespaces + "\n" + namespaces + "\n" + `clusterroles.rbac.authorization.k8s.io is forbidden: User \"system:serviceaccount:team-a:e2e\" cannot list resource \"clusterroles\" in API group \"rbac.authorization.k8s.io\" at the cluster scope`,
+		}},
+		{Name: "[It] [sig-network] DNS should resolve", Status: StatusFailed, Failure: &JUnitMessage{
+			Message: `pods is forbidden: User "system:serviceaccount:team-a:e2e" cannot list resource "pods" in API group "" in the namespace "kube-system"`,
+		}},
+		{Name: "[It] [sig-node] Pods should run", Status: StatusPassed},
+	}}}}
+
+	denied := DeniedSpecs(report)
+	assert.Equal(t, []DeniedSpec{
+		{Name: "[sig-apps] Deployment should proceed", Permissions: []string{"create namespaces"}},
+		{Name: "[sig-auth] ServiceAccounts should mount a token", Permissions: []string{"create namespaces", "list clusterroles.rbac.authorization.k8s.io"}},
+	}, denied)
+	assert.Equal(t, map[string]int{"create namespaces": 2, "list clusterroles.rbac.authorization.k8s.io": 1}, DeniedPermissions(denied))
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// SchemaVersion is the version of the schema of results.json, incremented
+// on incompatible changes
+const SchemaVersion = 1
+
+// Schema is the JSON schema of results.json
+//
+//go:embed results.schema.json
+var Schema []byte
+
+// phaseStatuses are the valid statuses of a phase
+var phaseStatuses = []string{PhasePassed, PhaseFailed, PhaseNotRun}
+
+// skipReasons are the valid reasons a spec was skipped for
+var skipReasons = []string{SkipReasonNotFocused, SkipReasonSkipExpression, SkipReasonRuntime, SkipReasonUnknown}
+
+// ValidateMetadata checks encoded metadata against the schema and returns
+// the problems found.
+func ValidateMetadata(data []byte) []string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	var problems []string
+	for _, name := range []string{"schemaVersion", "exitCode"} {
+		if _, ok := fields[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s is missing", name))
+		}
+	}
+
+	m := &Metadata{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(m); err != nil {
+		return append(problems, err.Error())
+	}
+	if _, ok := fields["schemaVersion"]; ok && m.SchemaVersion != SchemaVersion {
+		problems = append(problems, fmt.Sprintf("unsupported schemaVersion %d, expected %d", m.SchemaVersion, SchemaVersion))
+	}
+	for name, value := range map[string]int64{"parallel": int64(m.Parallel), "reconnects": m.Reconnects, "podRestarts": m.PodRestarts} {
+		if value < 0 {
+			problems = append(problems, fmt.Sprintf("%s is negative", name))
+		}
+	}
+	for _, list := range []struct {
+		field  string
+		phases []PhaseResult
+	}{{"phases", m.Phases}, {"nodes", m.Nodes}} {
+		field := list.field
+		for i, phase := range list.phases {
+			if phase.Name == "" {
+				problems = append(problems, fmt.Sprintf("%s[%d].name is empty", field, i))
+			}
+			if !slices.Contains(phaseStatuses, phase.Status) {
+				problems = append(problems, fmt.Sprintf("%s[%d].status %q is not one of %v", field, i, phase.Status, phaseStatuses))
+			}
+		}
+	}
+	for i, failure := range m.Failures {
+		if failure.Name == "" {
+			problems = append(problems, fmt.Sprintf("failures[%d].name is empty", i))
+		}
+		if failure.UpstreamRuns < 0 || failure.UpstreamFailures < 0 {
+			problems = append(problems, fmt.Sprintf("failures[%d] has negative upstream counts", i))
+		}
+	}
+	for reason, count := range m.Skipped {
+		if !slices.Contains(skipReasons, reason) {
+			problems = append(problems, fmt.Sprintf("skipped reason %q is not one of %v", reason, skipReasons))
+		}
+		if count < 0 {
+			problems = append(problems, fmt.Sprintf("skipped[%s] is negative", reason))
+		}
+	}
+	if c := m.Cluster; c != nil {
+		if c.NodeCount < 0 {
+			problems = append(problems, "cluster.nodeCount is negative")
+		}
+		for i, group := range c.Nodes {
+			if group.Count < 1 {
+				problems = append(problems, fmt.Sprintf("cluster.nodes[%d].count is not positive", i))
+			}
+		}
+	}
+	if timing := m.Timing; timing != nil {
+		if timing.Seconds < 0 {
+			problems = append(problems, "timing.seconds is negative")
+		}
+		for i, spec := range timing.Slowest {
+			if spec.Name == "" {
+				problems = append(problems, fmt.Sprintf("timing.slowest[%d].name is empty", i))
+			}
+			if spec.Seconds < 0 {
+				problems = append(problems, fmt.Sprintf("timing.slowest[%d].seconds is negative", i))
+			}
+		}
+	}
+	if r := m.Regressions; r != nil {
+		if r.Threshold < 0 || r.Seconds < 0 || r.BaselineSeconds < 0 {
+			problems = append(problems, "regressions has negative values")
+		}
+		for i, spec := range r.Slower {
+			if spec.Name == "" {
+				problems = append(problems, fmt.Sprintf("regressions.slower[%d].name is empty", i))
+			}
+			if spec.Seconds < 0 || spec.BaselineSeconds < 0 {
+				problems = append(problems, fmt.Sprintf("regressions.slower[%d] has negative values", i))
+			}
+		}
+	}
+	if u := m.Usage; u != nil {
+		if u.Samples < 0 || u.CPUCoreSeconds < 0 || u.MemoryGiBSeconds < 0 || u.PeakCPUCores < 0 || u.PeakMemoryBytes < 0 || u.PeakPods < 0 || u.Cost < 0 {
+			problems = append(problems, "usage has negative values")
+		}
+	}
+	slices.Sort(problems)
+	return problems
+}
+
+// Validate checks the results of a run in the directory, bundled into
+// BundleFile or not, and returns the problems found. results.json is
+// checked against the schema, junit_01.xml and skipped.json are checked to
+// be readable when present.
+func Validate(dir string) ([]string, error) {
+	files, err := readResults(dir, MetadataFile, "junit_01.xml", SkippedFile)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := files[MetadataFile]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in %s", MetadataFile, dir)
+	}
+	var problems []string
+	for _, problem := range ValidateMetadata(data) {
+		problems = append(problems, fmt.Sprintf("%s: %s", MetadataFile, problem))
+	}
+	if data, ok := files["junit_01.xml"]; ok {
+		if err := xml.Unmarshal(data, &JUnitTestSuites{}); err != nil {
+			problems = append(problems, fmt.Sprintf("junit_01.xml: %v", err))
+		}
+	}
+	if data, ok := files[SkippedFile]; ok {
+		var skipped []SkippedSpec
+		if err := json.Unmarshal(data, &skipped); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", SkippedFile, err))
+		}
+	}
+	return problems, nil
+}
+
+// readResults reads the named files of the directory, from the results
+// bundle when the directory holds one. Missing files are left out.
+func readResults(dir string, names ...string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	f, err := os.Open(filepath.Join(dir, BundleFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		for _, name := range names {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			files[name] = data
+		}
+		return files, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", BundleFile, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", BundleFile, err)
+		}
+		if !slices.Contains(names, header.Name) {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", BundleFile, err)
+		}
+		files[header.Name] = data
+	}
+}
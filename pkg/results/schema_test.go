@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		problems []string
+	}{
+		{
+			name: "valid",
+			data: `{"schemaVersion": 1, "exitCode": 1, "phases": [{"name": "conformance", "status": "failed", "exitCode": 1}],
+				"failures": [{"name": "[sig-node] Pods"}], "skipped": {"not-focused": 3}}`,
+		},
+		{
+			name:     "missing fields",
+			data:     `{"serverVersion": "v1.30.0"}`,
+			problems: []string{"exitCode is missing", "schemaVersion is missing"},
+		},
+		{
+			name:     "unknown field",
+			data:     `{"schemaVersion": 1, "exitCode": 0, "verdict": "passed"}`,
+			problems: []string{`json: unknown field "verdict"`},
+		},
+		{
+			name:     "newer schema",
+			data:     `{"schemaVersion": 2, "exitCode": 0}`,
+			problems: []string{"unsupported schemaVersion 2, expected 1"},
+		},
+		{
+			name: "invalid values",
+			data: `{"schemaVersion": 1, "exitCode": 0, "reconnects": -1, "phases": [{"name": "", "status": "done", "exitCode": 0}],
+				"nodes": [{"name": "node-1", "status": "ok", "exitCode": 0}], "skipped": {"flaky": 1}}`,
+			problems: []string{
+				`nodes[0].status "ok" is not one of [passed failed not-run]`,
+				"phases[0].name is empty",
+				`phases[0].status "done" is not one of [passed failed not-run]`,
+				"reconnects is negative",
+				`skipped reason "flaky" is not one of [not-focused skip-expression runtime unknown]`,
+			},
+		},
+		{
+			name:     "invalid JSON",
+			data:     `{`,
+			problems: []string{"invalid JSON: unexpected end of JSON input"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.problems, ValidateMetadata([]byte(tt.data)))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, WriteMetadata(dir, &Metadata{ExitCode: 0, Skipped: map[string]int{SkipReasonRuntime: 1}}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "junit_01.xml"), []byte("<testsuites"), 0600))
+
+	problems, err := Validate(dir)
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	assert.True(t, strings.HasPrefix(problems[0], "junit_01.xml: "))
+
+	// the files are read from the bundle once the results are bundled
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "junit_01.xml"), []byte("<testsuites></testsuites>"), 0600))
+	require.NoError(t, Bundle(dir, []string{MetadataFile, "junit_01.xml"}))
+	problems, err = Validate(dir)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	_, err = Validate(t.TempDir())
+	assert.ErrorContains(t, err, "results.json not found")
+}
+
+// TestSchemaProperties keeps the schema in sync with the fields of Metadata
+func TestSchemaProperties(t *testing.T) {
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(Schema, &schema))
+
+	var fields []string
+	typ := reflect.TypeOf(Metadata{})
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		fields = append(fields, name)
+	}
+	var properties []string
+	for name := range schema.Properties {
+		properties = append(properties, name)
+	}
+	assert.ElementsMatch(t, fields, properties)
+}
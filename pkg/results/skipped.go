@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SkippedFile is the name of the file listing the skipped specs in the output directory
+const SkippedFile = "skipped.json"
+
+// Reasons a spec was skipped
+const (
+	// SkipReasonNotFocused is set for specs not matching the focus of the run
+	SkipReasonNotFocused = "not-focused"
+	// SkipReasonSkipExpression is set for specs matching a skip expression
+	SkipReasonSkipExpression = "skip-expression"
+	// SkipReasonRuntime is set for specs that skipped themselves while
+	// running, e.g. because the cluster lacks a capability they need
+	SkipReasonRuntime = "runtime"
+	// SkipReasonUnknown is set for specs skipped for none of the other reasons
+	SkipReasonUnknown = "unknown"
+)
+
+// SkipRule is a skip expression of the run along with where it comes from,
+// e.g. --skip or a skip file
+type SkipRule struct {
+	Source     string `json:"source"`
+	Expression string `json:"expression"`
+}
+
+// SkippedSpec is a spec that was skipped and why
+type SkippedSpec struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+	// Source is where the skip expression matching the spec comes from
+	Source string `json:"source,omitempty"`
+	// Detail is the skip expression matching the spec or the message the
+	// spec skipped itself with
+	Detail string `json:"detail,omitempty"`
+}
+
+// ClassifySkipped returns the skipped specs of the report along with the
+// reason they were skipped. The rules are checked in order, the first one
+// matching a spec is reported.
+func ClassifySkipped(suites *JUnitTestSuites, focus string, rules []SkipRule) ([]SkippedSpec, error) {
+	var focusRegexp *regexp.Regexp
+	if focus != "" {
+		var err error
+		if focusRegexp, err = regexp.Compile(focus); err != nil {
+			return nil, fmt.Errorf("invalid focus expression [%s]: %w", focus, err)
+		}
+	}
+	ruleRegexps := make([]*regexp.Regexp, len(rules))
+	for i, rule := range rules {
+		var err error
+		if ruleRegexps[i], err = regexp.Compile(rule.Expression); err != nil {
+			return nil, fmt.Errorf("invalid skip expression [%s] of %s: %w", rule.Expression, rule.Source, err)
+		}
+	}
+
+	var skipped []SkippedSpec
+	for _, s := range suites.TestSuites {
+		for _, tc := range s.TestCases {
+			name, ok := strings.CutPrefix(tc.Name, "[It] ")
+			if !ok || tc.Status != StatusSkipped {
+				continue
+			}
+			skipped = append(skipped, classify(name, tc.Skipped, focusRegexp, rules, ruleRegexps))
+		}
+	}
+	return skipped, nil
+}
+
+// classify returns why the spec was skipped
+func classify(name string, message *JUnitMessage, focus *regexp.Regexp, rules []SkipRule, ruleRegexps []*regexp.Regexp) SkippedSpec {
+	// ginkgo reports the message of specs that skipped themselves after
+	// "skipped - ", specs filtered out before the run only say "skipped"
+	if message != nil {
+		if detail, ok := strings.CutPrefix(message.Message, "skipped - "); ok && strings.TrimSpace(detail) != "" {
+			return SkippedSpec{Name: name, Reason: SkipReasonRuntime, Detail: strings.TrimSpace(detail)}
+		}
+	}
+	if focus != nil && !focus.MatchString(name) {
+		return SkippedSpec{Name: name, Reason: SkipReasonNotFocused}
+	}
+	for i, rule := range rules {
+		if ruleRegexps[i].MatchString(name) {
+			return SkippedSpec{Name: name, Reason: SkipReasonSkipExpression, Source: rule.Source, Detail: rule.Expression}
+		}
+	}
+	return SkippedSpec{Name: name, Reason: SkipReasonUnknown}
+}
+
+// CountSkipped returns the number of skipped specs of each reason
+func CountSkipped(skipped []SkippedSpec) map[string]int {
+	counts := map[string]int{}
+	for _, spec := range skipped {
+		counts[spec.Reason]++
+	}
+	return counts
+}
+
+// WriteSkipped writes the skipped specs as indented JSON to the output directory.
+func WriteSkipped(outputDir string, skipped []SkippedSpec) error {
+	data, err := json.MarshalIndent(skipped, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding skipped specs: %w", err)
+	}
+	path := filepath.Join(outputDir, SkippedFile)
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
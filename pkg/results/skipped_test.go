@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifySkipped(t *testing.T) {
+	report := &JUnitTestSuites{
+		TestSuites: []JUnitTestSuite{
+			{
+				TestCases: []JUnitTestCase{
+					{Name: "[It] [sig-apps] Deployment should run [Conformance]", Status: StatusPassed},
+					{Name: "[It] [sig-node] Pods should be windows only [Conformance]", Status: StatusSkipped, Skipped: &JUnitMessage{Message: "skipped"}},
+					{Name: "[It] [sig-network] Services should be flaky [Conformance]", Status: StatusSkipped, Skipped: &JUnitMessage{Message: "skipped"}},
+					{Name: "[It] [sig-storage] CSI should mount", Status: StatusSkipped, Skipped: &JUnitMessage{Message: "skipped"}},
+					{Name: "[It] [sig-network] Services should use IPv6 [Conformance]", Status: StatusSkipped, Skipped: &JUnitMessage{Message: "skipped - Only supported for IPv6 clusters"}},
+					{Name: "[It] [sig-apps] Job should run [Conformance]", Status: StatusSkipped, Skipped: &JUnitMessage{Message: "skipped"}},
+					{Name: "[SynchronizedBeforeSuite]", Status: StatusSkipped},
+				},
+			},
+		},
+	}
+	rules := []SkipRule{
+		{Source: "--skip", Expression: `flaky`},
+		{Source: "skip.txt", Expression: `windows only|flaky`},
+	}
+
+	skipped, err := ClassifySkipped(report, `\[Conformance\]`, rules)
+	require.NoError(t, err)
+	assert.Equal(t, []SkippedSpec{
+		{Name: "[sig-node] Pods should be windows only [Conformance]", Reason: SkipReasonSkipExpression, Source: "skip.txt", Detail: `windows only|flaky`},
+		{Name: "[sig-network] Services should be flaky [Conformance]", Reason: SkipReasonSkipExpression, Source: "--skip", Detail: `flaky`},
+		{Name: "[sig-storage] CSI should mount", Reason: SkipReasonNotFocused},
+		{Name: "[sig-network] Services should use IPv6 [Conformance]", Reason: SkipReasonRuntime, Detail: "Only supported for IPv6 clusters"},
+		{Name: "[sig-apps] Job should run [Conformance]", Reason: SkipReasonUnknown},
+	}, skipped)
+	assert.Equal(t, map[string]int{
+		SkipReasonSkipExpression: 2,
+		SkipReasonNotFocused:     1,
+		SkipReasonRuntime:        1,
+		SkipReasonUnknown:        1,
+	}, CountSkipped(skipped))
+
+	_, err = ClassifySkipped(report, "", []SkipRule{{Source: "--skip", Expression: "("}})
+	assert.Error(t, err)
+}
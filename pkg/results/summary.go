@@ -0,0 +1,189 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// Summary is the outcome of the specs of a junit report
+type Summary struct {
+	// Source is the file the report was read from
+	Source string
+	// Labels and CI identify the run, from the results.json next to the
+	// report when there is one
+	Labels  map[string]string
+	CI      *CI
+	Passed  int
+	Failed  int
+	Skipped int
+	// Failures lists the failed specs along with the first line of their
+	// failure message
+	Failures []SpecFailure
+	Timing   *Timing
+	// skipped are the skipped specs of the report by reason
+	skipped map[string]int
+}
+
+// SpecFailure is a failed spec and the first line of its failure message
+type SpecFailure struct {
+	Name    string
+	Message string
+}
+
+// Summarize returns the outcome of the specs of the report, listing the
+// slowest n specs. The specs are counted from the counters of the suites,
+// e2e.log doesn't name all of them.
+func Summarize(suites *JUnitTestSuites, source string, n int) (*Summary, error) {
+	s := &Summary{Source: source, Timing: Timings(suites, n)}
+	for _, suite := range suites.TestSuites {
+		failed := suite.Failures + suite.Errors
+		skipped := suite.Skipped + suite.Disabled
+		s.Failed += failed
+		s.Skipped += skipped
+		s.Passed += suite.Tests - failed - skipped
+		for _, tc := range suite.TestCases {
+			for _, m := range []*JUnitMessage{tc.Failure, tc.Error} {
+				if m != nil {
+					s.Failures = append(s.Failures, SpecFailure{Name: strings.TrimPrefix(tc.Name, "[It] "), Message: excerpt(m.Message)})
+					break
+				}
+			}
+		}
+	}
+	skipped, err := ClassifySkipped(suites, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	s.skipped = CountSkipped(skipped)
+	// e2e.log only counts the specs it doesn't name
+	if named := len(skipped); named < s.Skipped {
+		s.skipped[SkipReasonUnknown] += s.Skipped - named
+	}
+	return s, nil
+}
+
+// Metadata returns the results of the summary in the format of results.json,
+// the exit code is 1 when specs failed
+func (s *Summary) Metadata() *Metadata {
+	m := &Metadata{SchemaVersion: SchemaVersion, Labels: s.Labels, CI: s.CI, Timing: s.Timing}
+	if s.Failed > 0 {
+		m.ExitCode = 1
+	}
+	for _, failure := range s.Failures {
+		m.Failures = append(m.Failures, Failure{Name: failure.Name})
+	}
+	if len(s.skipped) > 0 {
+		m.Skipped = s.skipped
+	}
+	return m
+}
+
+// WriteText writes the summary as the text hydrophone logs at the end of a run
+func (s *Summary) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Results of %s\n", s.Source)
+	if len(s.Labels) > 0 {
+		fmt.Fprintf(&b, "Labels: %s\n", FormatLabels(s.Labels))
+	}
+	if s.CI != nil {
+		fmt.Fprintf(&b, "CI: %s\n", s.CI)
+	}
+	fmt.Fprintf(&b, "%d passed, %d failed, %d skipped\n", s.Passed, s.Failed, s.Skipped)
+	for _, failure := range s.Failures {
+		fmt.Fprintf(&b, "FAILED %s\n", failure.Name)
+		if failure.Message != "" {
+			fmt.Fprintf(&b, "  %s\n", failure.Message)
+		}
+	}
+	if s.Timing != nil && len(s.Timing.Slowest) > 0 {
+		fmt.Fprintf(&b, "Time spent in the specs: %s\n", FormatSeconds(s.Timing.Seconds))
+		fmt.Fprintln(&b, "Slowest specs:")
+		for _, spec := range s.Timing.Slowest {
+			fmt.Fprintf(&b, "  %8s %s\n", FormatSeconds(spec.Seconds), spec.Name)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// summaryTemplate is the HTML page of a summary
+var summaryTemplate = template.Must(template.New("summary").Funcs(template.FuncMap{
+	"seconds": FormatSeconds,
+	"labels":  FormatLabels,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Conformance results</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.failed { color: #b00020; }
+</style>
+</head>
+<body>
+<h1>Conformance results</h1>
+<p>Results of <code>{{.Source}}</code></p>
+{{- if .Labels}}
+<p>Labels: <code>{{labels .Labels}}</code></p>
+{{- end}}
+{{- if .CI}}
+<p>CI: {{.CI.Provider}}{{with .CI.Job}}, job {{.}}{{end}}{{with .CI.BuildID}}, build {{.}}{{end}}{{with .CI.Commit}}, commit <code>{{.}}</code>{{end}}{{with .CI.URL}}, <a href="{{.}}">{{.}}</a>{{end}}</p>
+{{- end}}
+<table>
+<tr><th>Passed</th><td>{{.Passed}}</td></tr>
+<tr><th>Failed</th><td{{if .Failed}} class="failed"{{end}}>{{.Failed}}</td></tr>
+<tr><th>Skipped</th><td>{{.Skipped}}</td></tr>
+</table>
+{{- if .Failures}}
+<h2>Failed tests</h2>
+<table>
+<tr><th>Test</th><th>Message</th></tr>
+{{- range .Failures}}
+<tr><td>{{.Name}}</td><td><code>{{.Message}}</code></td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if and .Timing .Timing.Slowest}}
+<h2>Slowest tests</h2>
+<p>Time spent in the specs: {{seconds .Timing.Seconds}}</p>
+<table>
+<tr><th>Test</th><th>Duration</th></tr>
+{{- range .Timing.Slowest}}
+<tr><td>{{.Name}}</td><td>{{seconds .Seconds}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the summary as a standalone HTML page
+func (s *Summary) WriteHTML(w io.Writer) error {
+	return summaryTemplate.Execute(w, s)
+}
+
+// FormatSeconds formats a number of seconds as a duration rounded to the second
+func FormatSeconds(s float64) string {
+	return (time.Duration(s * float64(time.Second))).Round(time.Second).String()
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarize(t *testing.T) {
+	report, err := ParseE2ELog(strings.NewReader(e2eLog))
+	require.NoError(t, err)
+	summary, err := Summarize(report, "e2e.log", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Passed)
+	assert.Equal(t, 1, summary.Failed)
+	assert.Equal(t, 6997, summary.Skipped)
+	assert.Equal(t, []SpecFailure{{Name: "[sig-network] DNS should provide DNS for the cluster  [Conformance]", Message: "Unexpected error:"}}, summary.Failures)
+
+	var text bytes.Buffer
+	require.NoError(t, summary.WriteText(&text))
+	assert.Contains(t, text.String(), "2 passed, 1 failed, 6997 skipped\n")
+	assert.Contains(t, text.String(), "FAILED [sig-network] DNS should provide DNS for the cluster  [Conformance]\n  Unexpected error:\n")
+	assert.Contains(t, text.String(), "      5m2s [sig-network] DNS")
+
+	var html bytes.Buffer
+	require.NoError(t, summary.WriteHTML(&html))
+	assert.Contains(t, html.String(), `<tr><th>Failed</th><td class="failed">1</td></tr>`)
+
+	summary.Labels = map[string]string{"team": "platform", "cluster": "prod-eu-1"}
+	summary.CI = &CI{Provider: "gitlab", BuildID: "42", Commit: "0fb426", URL: "https://gitlab.example.com/p/-/pipelines/7"}
+	text.Reset()
+	require.NoError(t, summary.WriteText(&text))
+	assert.Contains(t, text.String(), "Results of e2e.log\nLabels: cluster=prod-eu-1, team=platform\nCI: gitlab, build 42, commit 0fb426, https://gitlab.example.com/p/-/pipelines/7\n")
+	html.Reset()
+	require.NoError(t, summary.WriteHTML(&html))
+	assert.Contains(t, html.String(), `<p>CI: gitlab, build 42, commit <code>0fb426</code>, <a href="https://gitlab.example.com/p/-/pipelines/7">https://gitlab.example.com/p/-/pipelines/7</a></p>`)
+
+	m := summary.Metadata()
+	assert.Equal(t, 1, m.ExitCode)
+	assert.Equal(t, map[string]int{SkipReasonUnknown: 6997}, m.Skipped)
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.Empty(t, ValidateMetadata(data))
+}
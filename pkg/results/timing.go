@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// sigRegexp matches the SIG tag of a spec name, e.g. [sig-network]
+var sigRegexp = regexp.MustCompile(`\[(sig-[a-z0-9-]+)\]`)
+
+// NoSIG is the SIG of the timing report of the specs without a SIG tag
+const NoSIG = "none"
+
+// Timing is the time the specs of a run took, to find the specs worth
+// parallelizing or skipping
+type Timing struct {
+	// Seconds is the time all specs that ran took, summed
+	Seconds float64 `json:"seconds"`
+	// Slowest lists the slowest specs, slowest first
+	Slowest []SpecTiming `json:"slowest,omitempty"`
+	// SIGs sums the time the specs took by SIG, e.g. sig-network
+	SIGs map[string]float64 `json:"sigs,omitempty"`
+}
+
+// SpecTiming is the time a spec took
+type SpecTiming struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Timings returns the time the specs of the report that ran took, listing
+// the slowest n specs. The time of the suite setup and teardown nodes isn't
+// counted.
+func Timings(suites *JUnitTestSuites, n int) *Timing {
+	timing := &Timing{SIGs: map[string]float64{}}
+	specs := ranSpecs(suites)
+	for _, spec := range specs {
+		timing.Seconds += spec.Seconds
+		sig := NoSIG
+		if match := sigRegexp.FindStringSubmatch(spec.Name); match != nil {
+			sig = match[1]
+		}
+		timing.SIGs[sig] += spec.Seconds
+	}
+	sort.SliceStable(specs, func(i, j int) bool {
+		return specs[i].Seconds > specs[j].Seconds
+	})
+	if len(specs) > n {
+		specs = specs[:n]
+	}
+	timing.Slowest = specs
+	return timing
+}
+
+// ranSpecs returns the time each spec of the report that ran took, the
+// suite setup and teardown nodes aside
+func ranSpecs(suites *JUnitTestSuites) []SpecTiming {
+	var specs []SpecTiming
+	for _, s := range suites.TestSuites {
+		for _, tc := range s.TestCases {
+			name, ok := strings.CutPrefix(tc.Name, "[It] ")
+			if !ok || !ran(tc) {
+				continue
+			}
+			specs = append(specs, SpecTiming{Name: name, Seconds: tc.Time})
+		}
+	}
+	return specs
+}
+
+// SIGsByTime returns the SIGs of the timing report, the slowest first
+func (t *Timing) SIGsByTime() []string {
+	sigs := make([]string, 0, len(t.SIGs))
+	for sig := range t.SIGs {
+		sigs = append(sigs, sig)
+	}
+	sort.Slice(sigs, func(i, j int) bool {
+		if t.SIGs[sigs[i]] != t.SIGs[sigs[j]] {
+			return t.SIGs[sigs[i]] > t.SIGs[sigs[j]]
+		}
+		return sigs[i] < sigs[j]
+	})
+	return sigs
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimings(t *testing.T) {
+	report := &JUnitTestSuites{TestSuites: []JUnitTestSuite{{TestCases: []JUnitTestCase{
+		{Name: "[SynchronizedBeforeSuite]", Status: StatusPassed, Time: 30},
+		{Name: "[It] [sig-apps] Deployment should proceed", Status: StatusPassed, Time: 120},
+		{Name: "[It] [sig-network] DNS should resolve", Status: StatusFailed, Time: 300},
+		{Name: "[It] [sig-network] Services should serve", Status: StatusPassed, Time: 60},
+		{Name: "[It] [sig-storage] Volumes should mount", Status: StatusSkipped, Time: 0.01},
+		{Name: "[It] Conformance should be untagged", Status: StatusPassed, Time: 5},
+	}}}}
+
+	timing := Timings(report, 2)
+	assert.Equal(t, &Timing{
+		Seconds: 485,
+		Slowest: []SpecTiming{
+			{Name: "[sig-network] DNS should resolve", Seconds: 300},
+			{Name: "[sig-apps] Deployment should proceed", Seconds: 120},
+		},
+		SIGs: map[string]float64{"sig-apps": 120, "sig-network": 360, NoSIG: 5},
+	}, timing)
+	assert.Equal(t, []string{"sig-network", "sig-apps", NoSIG}, timing.SIGsByTime())
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// TruncatedSpecsProperty is the suite property recording how many specs had
+// their output truncated
+const TruncatedSpecsProperty = "hydrophone.truncated-specs"
+
+// TruncateJUnit caps the captured output and the failure messages of every
+// spec to maxBytes, keeping the beginning and the end of the output where the
+// context and the actual error usually are. It returns the number of specs
+// that were truncated and records it as a property of the suite.
+func TruncateJUnit(suites *JUnitTestSuites, maxBytes int) int {
+	if maxBytes <= 0 {
+		return 0
+	}
+	total := 0
+	for i := range suites.TestSuites {
+		s := &suites.TestSuites[i]
+		count := 0
+		for j := range s.TestCases {
+			if s.TestCases[j].truncate(maxBytes) {
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		if s.Properties == nil {
+			s.Properties = &JUnitProperties{}
+		}
+		s.Properties.Properties = append(s.Properties.Properties, JUnitProperty{
+			Name:  TruncatedSpecsProperty,
+			Value: strconv.Itoa(count),
+		})
+		total += count
+	}
+	return total
+}
+
+// truncate caps the fields of the test case and reports whether any was truncated
+func (tc *JUnitTestCase) truncate(maxBytes int) bool {
+	fields := []*string{&tc.SystemOut, &tc.SystemErr}
+	for _, m := range []*JUnitMessage{tc.Skipped, tc.Error, tc.Failure} {
+		if m != nil {
+			fields = append(fields, &m.Message, &m.Description)
+		}
+	}
+	truncated := false
+	for _, field := range fields {
+		if len(*field) > maxBytes {
+			*field = truncateString(*field, maxBytes)
+			truncated = true
+		}
+	}
+	return truncated
+}
+
+// truncateString keeps the head and the tail of s, each half of maxBytes, and
+// replaces the middle with a note. Cuts never split a UTF-8 sequence.
+func truncateString(s string, maxBytes int) string {
+	head := maxBytes / 2
+	for head > 0 && !utf8.RuneStart(s[head]) {
+		head--
+	}
+	tail := len(s) - maxBytes/2
+	for tail < len(s) && !utf8.RuneStart(s[tail]) {
+		tail++
+	}
+	return fmt.Sprintf("%s\n\n... [%d bytes truncated by hydrophone] ...\n\n%s", s[:head], tail-head, s[tail:])
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/registry"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// ResolveArchitecture makes sure the pods created by hydrophone land on a
+// node whose architecture the conformance and busybox images are built for.
+// When some nodes of the cluster can't run the images, --arch is set to the
+// architecture of the nodes that can, which adds a nodeSelector to the pods.
+func ResolveArchitecture(clientset kubernetes.Interface) error {
+	// the nodes can't be listed, --arch is applied as given
+	if common.Restricted() {
+		return nil
+	}
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing nodes to pick the architecture: %w", err)
+	}
+	nodeArchs := nodeArchitectures(nodes.Items)
+
+	if arch := settings.GetString("arch"); arch != "" {
+		if nodeArchs[arch] == 0 {
+			return fmt.Errorf("no ready node with architecture %s, the nodes are %s", arch, formatArchitectures(nodeArchs))
+		}
+		return nil
+	}
+	if len(nodeArchs) == 0 {
+		return nil
+	}
+
+	checker := registry.NewChecker()
+	images := map[string][]string{}
+	runImages := []string{settings.GetString("conformance-image")}
+	if common.ArtifactContainer() == common.OutputContainer {
+		runImages = append(runImages, settings.GetString("busybox-image"))
+	}
+	for _, image := range runImages {
+		archs, err := checker.Architectures(image)
+		if err != nil {
+			log.Printf("unable to find the architectures of %s, not selecting nodes by architecture: %v", image, err)
+			return nil
+		}
+		images[image] = archs
+	}
+
+	arch, err := pickArchitecture(nodeArchs, images)
+	if err != nil {
+		return err
+	}
+	if arch != "" {
+		log.Printf("Not all nodes can run the images, running on nodes with architecture '%s'", arch)
+		settings.Set("arch", arch)
+	}
+	return nil
+}
+
+// nodeArchitectures counts the ready linux nodes by architecture. Taints are
+// not considered as the conformance pod tolerates all of them.
+func nodeArchitectures(nodes []v1.Node) map[string]int {
+	archs := map[string]int{}
+	for _, node := range nodes {
+		if node.Spec.Unschedulable || !nodeReady(node) || !linuxNode(node) {
+			continue
+		}
+		arch := node.Labels[v1.LabelArchStable]
+		if arch == "" {
+			arch = node.Status.NodeInfo.Architecture
+		}
+		if arch != "" {
+			archs[arch]++
+		}
+	}
+	return archs
+}
+
+// pickArchitecture returns the architecture the pods have to be pinned to,
+// or an empty string if the images can run on every node. Among the
+// architectures all images are built for, the one with most nodes is picked.
+func pickArchitecture(nodeArchs map[string]int, images map[string][]string) (string, error) {
+	var supported []string
+	for arch := range nodeArchs {
+		ok := true
+		for _, archs := range images {
+			if !contains(archs, arch) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			supported = append(supported, arch)
+		}
+	}
+
+	if len(supported) == 0 {
+		var built []string
+		for image, archs := range images {
+			built = append(built, fmt.Sprintf("%s is built for %s", image, strings.Join(archs, ", ")))
+		}
+		sort.Strings(built)
+		return "", fmt.Errorf("no node can run the images, the nodes are %s but %s", formatArchitectures(nodeArchs), strings.Join(built, " and "))
+	}
+	if len(supported) == len(nodeArchs) {
+		return "", nil
+	}
+	sort.Slice(supported, func(i, j int) bool {
+		if nodeArchs[supported[i]] != nodeArchs[supported[j]] {
+			return nodeArchs[supported[i]] > nodeArchs[supported[j]]
+		}
+		return supported[i] < supported[j]
+	})
+	return supported[0], nil
+}
+
+// formatArchitectures lists the architectures with their number of nodes
+func formatArchitectures(nodeArchs map[string]int) string {
+	if len(nodeArchs) == 0 {
+		return "none"
+	}
+	var parts []string
+	for arch, count := range nodeArchs {
+		parts = append(parts, fmt.Sprintf("%s (%d)", arch, count))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// linuxNode reports whether the node runs linux, nodes without the os label
+// are assumed to
+func linuxNode(node v1.Node) bool {
+	os := node.Labels[v1.LabelOSStable]
+	return os == "" || os == common.NodeOSLinux
+}
+
+// nodeSelector returns the node selector of the pods created by hydrophone
+func nodeSelector() map[string]string {
+	selector := map[string]string{}
+	if arch := settings.GetString("arch"); arch != "" {
+		selector[v1.LabelArchStable] = arch
+	}
+	// the images of hydrophone only run on linux, but the pods tolerate the
+	// taints usually keeping other workloads off the windows nodes
+	if settings.GetString("node-os") == common.NodeOSWindows {
+		selector[v1.LabelOSStable] = common.NodeOSLinux
+	}
+	if len(selector) == 0 {
+		return nil
+	}
+	return selector
+}
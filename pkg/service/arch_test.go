@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestNodeArchitectures(t *testing.T) {
+	ready := v1.NodeStatus{
+		Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+	}
+	withArch := func(arch string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Labels: map[string]string{v1.LabelArchStable: arch}}
+	}
+
+	nodes := []v1.Node{
+		{ObjectMeta: withArch("amd64"), Status: ready},
+		{ObjectMeta: withArch("arm64"), Status: ready},
+		{ObjectMeta: withArch("arm64"), Status: ready, Spec: v1.NodeSpec{Taints: []v1.Taint{{Key: "example.com/arm", Effect: v1.TaintEffectNoSchedule}}}},
+		{ObjectMeta: withArch("s390x")},
+		{ObjectMeta: withArch("ppc64le"), Status: ready, Spec: v1.NodeSpec{Unschedulable: true}},
+		{Status: v1.NodeStatus{Conditions: ready.Conditions, NodeInfo: v1.NodeSystemInfo{Architecture: "amd64"}}},
+		{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{v1.LabelArchStable: "amd64", v1.LabelOSStable: "windows"}}, Status: ready},
+	}
+
+	assert.Equal(t, map[string]int{"amd64": 2, "arm64": 2}, nodeArchitectures(nodes))
+}
+
+func TestPickArchitecture(t *testing.T) {
+	tests := []struct {
+		name      string
+		nodeArchs map[string]int
+		images    map[string][]string
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "multi-arch images",
+			nodeArchs: map[string]int{"amd64": 2, "arm64": 3},
+			images:    map[string][]string{"conformance": {"amd64", "arm64"}, "busybox": {"amd64", "arm64", "s390x"}},
+			want:      "",
+		},
+		{
+			name:      "single-arch conformance image",
+			nodeArchs: map[string]int{"amd64": 2, "arm64": 3},
+			images:    map[string][]string{"conformance": {"amd64"}, "busybox": {"amd64", "arm64"}},
+			want:      "amd64",
+		},
+		{
+			name:      "most nodes",
+			nodeArchs: map[string]int{"amd64": 2, "arm64": 3, "s390x": 1},
+			images:    map[string][]string{"conformance": {"amd64", "arm64"}, "busybox": {"amd64", "arm64"}},
+			want:      "arm64",
+		},
+		{
+			name:      "no compatible node",
+			nodeArchs: map[string]int{"arm64": 3},
+			images:    map[string][]string{"conformance": {"amd64"}, "busybox": {"amd64", "arm64"}},
+			wantErr:   "no node can run the images, the nodes are arm64 (3) but busybox is built for amd64, arm64 and conformance is built for amd64",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickArchitecture(tt.nodeArchs, tt.images)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConformancePodNodeSelector(t *testing.T) {
+	settings.Set("arch", "arm64")
+	defer settings.Set("arch", "")
+
+	pod := ConformancePod("conformance")
+	assert.Equal(t, map[string]string{v1.LabelArchStable: "arm64"}, pod.Spec.NodeSelector)
+}
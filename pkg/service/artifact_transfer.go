@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+const (
+	// goRunner is the entrypoint of the conformance image
+	goRunner = "/gorunner"
+	// artifactsFetchTimeout is how long the conformance container waits for
+	// the artifacts to be fetched with --artifact-transfer=exec before exiting
+	artifactsFetchTimeout = time.Hour
+)
+
+// applyArtifactTransfer removes the output container with
+// --artifact-transfer=exec. The conformance container then runs the
+// go-runner through a shell and, once the tests completed, prints
+// common.TestsDoneMarker and keeps running until the artifacts were fetched,
+// so that they can be streamed from it. It exits with the exit code of the
+// tests.
+func applyArtifactTransfer(pod *v1.Pod) {
+	if settings.GetString("artifact-transfer") != common.ArtifactTransferExec {
+		return
+	}
+	pod.Spec.Containers = slices.DeleteFunc(pod.Spec.Containers, func(container v1.Container) bool {
+		return container.Name == common.OutputContainer
+	})
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == common.ConformanceContainer {
+			pod.Spec.Containers[i].Command = []string{"/bin/sh", "-c", execTransferScript()}
+		}
+	}
+}
+
+// execTransferScript returns the shell script of the conformance container
+// with --artifact-transfer=exec
+func execTransferScript() string {
+	fetched := path.Join(resultsDir, common.ArtifactsFetchedFile)
+	return fmt.Sprintf(`%s; code=$?; echo %q; n=0; while [ ! -e %s ] && [ $n -lt %d ]; do sleep 1; n=$((n+1)); done; exit $code`,
+		goRunner, common.TestsDoneMarker, fetched, int(artifactsFetchTimeout.Seconds()))
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestApplyArtifactTransfer(t *testing.T) {
+	defer settings.Set("artifact-transfer", "")
+
+	pod := ConformancePod("conformance")
+	applyArtifactTransfer(pod)
+	require.Len(t, pod.Spec.Containers, 2)
+	assert.Empty(t, pod.Spec.Containers[0].Command)
+
+	settings.Set("artifact-transfer", common.ArtifactTransferExec)
+	pod = ConformancePod("conformance")
+	applyArtifactTransfer(pod)
+	require.Len(t, pod.Spec.Containers, 1)
+	container := pod.Spec.Containers[0]
+	assert.Equal(t, common.ConformanceContainer, container.Name)
+	assert.Equal(t, []string{"/bin/sh", "-c",
+		`/gorunner; code=$?; echo "hydrophone: tests completed, waiting for the artifacts to be fetched"; n=0; while [ ! -e /tmp/results/.artifacts-fetched ] && [ $n -lt 3600 ]; do sleep 1; n=$((n+1)); done; exit $code`,
+	}, container.Command)
+}
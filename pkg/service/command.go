@@ -0,0 +1,167 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+)
+
+// Defaults of the go-runner of the conformance image
+const (
+	ginkgoBinary  = "/usr/local/bin/ginkgo"
+	testBinary    = "/usr/local/bin/e2e.test"
+	resultsDir    = "/tmp/results"
+	serialTests   = `\[Serial\]|\[Disruptive\]`
+	ginkgoTimeout = "24h"
+)
+
+// ConformanceCommand returns the command line the go-runner of the conformance
+// image runs in the conformance container of the pod, resolved from the
+// environment of the container the way the go-runner does: ginkgo with its
+// flags, the e2e test binary and, after --, the flags of the e2e test binary.
+// Values the container reads from secrets or config maps are not resolved.
+func ConformanceCommand(pod *v1.Pod) []string {
+	env := map[string]string{}
+	for _, container := range pod.Spec.Containers {
+		if container.Name != common.ConformanceContainer {
+			continue
+		}
+		for _, e := range container.Env {
+			env[e.Name] = e.Value
+		}
+	}
+	getenv := func(name, def string) string {
+		if value, ok := env[name]; ok && value != "" {
+			return value
+		}
+		return def
+	}
+
+	var ginkgoArgs []string
+	skip := env["E2E_SKIP"]
+	switch parallel := env["E2E_PARALLEL"]; parallel {
+	case "y", "Y", "true":
+		ginkgoArgs = append(ginkgoArgs, "--p")
+		if skip == "" {
+			skip = serialTests
+		}
+	default:
+		if n, err := strconv.Atoi(parallel); err == nil && n > 1 {
+			ginkgoArgs = append(ginkgoArgs, fmt.Sprintf("--procs=%d", n))
+			if skip == "" {
+				skip = serialTests
+			}
+		}
+	}
+	ginkgoArgs = append(ginkgoArgs, "--focus="+env["E2E_FOCUS"], "--skip="+skip, "--no-color=true")
+
+	e2eArgs := []string{
+		"--provider=" + getenv("E2E_PROVIDER", "skeleton"),
+		"--report-dir=" + getenv("RESULTS_DIR", resultsDir),
+		"--kubeconfig=" + env["KUBECONFIG"],
+		"-v=" + getenv("E2E_VERBOSITY", "4"),
+	}
+	separator := getenv("E2E_EXTRA_ARGS_SEP", " ")
+	if extra := env["E2E_EXTRA_ARGS"]; extra != "" {
+		e2eArgs = append(e2eArgs, strings.Split(extra, separator)...)
+	}
+	if extra := env["E2E_EXTRA_GINKGO_ARGS"]; extra != "" {
+		ginkgoArgs = append(ginkgoArgs, strings.Split(extra, separator)...)
+	}
+	if env["E2E_DRYRUN"] != "" {
+		ginkgoArgs = append(ginkgoArgs, "--dry-run=true")
+	}
+	ginkgoArgs = append(ginkgoArgs, "--timeout="+ginkgoTimeout)
+
+	command := append([]string{getenv("GINKGO_BIN", ginkgoBinary)}, ginkgoArgs...)
+	command = append(command, getenv("TEST_BIN", testBinary), "--")
+	return append(command, e2eArgs...)
+}
+
+// ConformanceEnv returns the environment of the conformance container of the
+// pod as NAME=VALUE, with the values quoted like ShellQuote and the source of
+// the values read from secrets, config maps or fields of the pod.
+func ConformanceEnv(pod *v1.Pod) []string {
+	var env []string
+	for _, container := range pod.Spec.Containers {
+		if container.Name != common.ConformanceContainer {
+			continue
+		}
+		for _, source := range container.EnvFrom {
+			switch {
+			case source.SecretRef != nil:
+				env = append(env, fmt.Sprintf("%s* from secret %s", source.Prefix, source.SecretRef.Name))
+			case source.ConfigMapRef != nil:
+				env = append(env, fmt.Sprintf("%s* from config map %s", source.Prefix, source.ConfigMapRef.Name))
+			}
+		}
+		for _, e := range container.Env {
+			value := e.Value
+			switch from := e.ValueFrom; {
+			case from == nil:
+			case from.SecretKeyRef != nil:
+				value = fmt.Sprintf("<key %s of secret %s>", from.SecretKeyRef.Key, from.SecretKeyRef.Name)
+			case from.ConfigMapKeyRef != nil:
+				value = fmt.Sprintf("<key %s of config map %s>", from.ConfigMapKeyRef.Key, from.ConfigMapKeyRef.Name)
+			case from.FieldRef != nil:
+				value = fmt.Sprintf("<field %s>", from.FieldRef.FieldPath)
+			case from.ResourceFieldRef != nil:
+				value = fmt.Sprintf("<resource %s>", from.ResourceFieldRef.Resource)
+			}
+			if e.ValueFrom == nil {
+				value = ShellQuote([]string{value})
+			}
+			env = append(env, e.Name+"="+value)
+		}
+	}
+	return env
+}
+
+// LogConformanceCommands logs the command line and the environment of the
+// conformance container of each pod.
+func LogConformanceCommands(pods []*v1.Pod) {
+	for _, pod := range pods {
+		log.Printf("Command run in the conformance container of pod %s:\n  %s", pod.Name, ShellQuote(ConformanceCommand(pod)))
+		log.Printf("Environment of the conformance container of pod %s:\n  %s", pod.Name, strings.Join(ConformanceEnv(pod), "\n  "))
+	}
+}
+
+// ShellQuote quotes the arguments so that they can be pasted into bash.
+// Arguments holding control characters, e.g. newlines, are quoted with $'...'.
+func ShellQuote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case arg != "" && !strings.ContainsAny(arg, " \t\n\r'\"\\$`|&;<>()[]*?!#~{}"):
+			quoted[i] = arg
+		case strings.ContainsFunc(arg, unicode.IsControl):
+			escaped := strconv.Quote(arg)
+			quoted[i] = "$'" + strings.ReplaceAll(escaped[1:len(escaped)-1], "'", `\'`) + "'"
+		default:
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
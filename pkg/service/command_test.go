@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestConformanceCommand(t *testing.T) {
+	settings.Set("focus", `\[sig-network\]`)
+	settings.Set("skip", "")
+	settings.Set("parallel", "4")
+	settings.Set("verbosity", 4)
+	settings.Set("seed", 42)
+	settings.Set("extra-args", []string{"--allowed-not-ready-nodes=1", "--dns-domain", "cluster local"})
+	defer settings.Set("focus", "")
+	defer settings.Set("parallel", "")
+	defer settings.Set("verbosity", nil)
+	defer settings.Set("seed", 0)
+	defer settings.Set("extra-args", []string{})
+
+	pod := ConformancePod("conformance")
+
+	assert.Equal(t, []string{
+		"/usr/local/bin/ginkgo", "--procs=4", `--focus=\[sig-network\]`, `--skip=\[Serial\]|\[Disruptive\]`, "--no-color=true", "--seed=42", "--timeout=24h",
+		"/usr/local/bin/e2e.test", "--",
+		"--provider=skeleton", "--report-dir=/tmp/results", "--kubeconfig=", "-v=4", "--allowed-not-ready-nodes=1", "--dns-domain", "cluster local",
+	}, ConformanceCommand(pod))
+}
+
+func TestConformanceEnv(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{
+		Name: common.ConformanceContainer,
+		EnvFrom: []v1.EnvFromSource{
+			{SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "credentials"}}},
+		},
+		Env: []v1.EnvVar{
+			{Name: "E2E_SKIP", Value: `\[Slow\]`},
+			{Name: "TOKEN", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "api"}, Key: "token"}}},
+		},
+	}}}}
+
+	assert.Equal(t, []string{
+		"* from secret credentials",
+		`E2E_SKIP='\[Slow\]'`,
+		"TOKEN=<key token of secret api>",
+	}, ConformanceEnv(pod))
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"--clean-start", "--v=4"}, want: "--clean-start --v=4"},
+		{args: []string{"--kubeconfig=", ""}, want: "--kubeconfig= ''"},
+		{args: []string{"--dns-domain", "cluster local"}, want: "--dns-domain 'cluster local'"},
+		{args: []string{`--skip=it's`}, want: `'--skip=it'\''s'`},
+		{args: []string{"a\nb's"}, want: `$'a\nb\'s'`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ShellQuote(tt.args))
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+)
+
+// Credential is a credential hydrophone authenticates to the cluster with
+type Credential struct {
+	// Kind describes the credential, e.g. client certificate
+	Kind string
+	// Expiry is the time the credential expires, zero when it is unknown or
+	// the credential doesn't expire
+	Expiry time.Time
+	// Refreshable reports whether client-go obtains a new credential on its
+	// own, e.g. through an exec plugin, so that the expiry doesn't limit the run
+	Refreshable bool
+}
+
+// Credentials returns the credentials of the config along with their expiry.
+func Credentials(config *rest.Config) ([]Credential, error) {
+	var creds []Credential
+	if config.ExecProvider != nil {
+		creds = append(creds, Credential{Kind: fmt.Sprintf("exec plugin %s", config.ExecProvider.Command), Refreshable: true})
+	}
+	if config.AuthProvider != nil {
+		creds = append(creds, authProviderCredential(config.AuthProvider))
+	}
+
+	certData := config.CertData
+	if len(certData) == 0 && config.CertFile != "" {
+		data, err := os.ReadFile(config.CertFile)
+		if err != nil {
+			return nil, err
+		}
+		certData = data
+	}
+	if len(certData) != 0 {
+		expiry, err := certificateExpiry(certData)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, Credential{Kind: "client certificate", Expiry: expiry})
+	}
+
+	if config.BearerTokenFile != "" {
+		// client-go re-reads the file, e.g. projected service account tokens
+		// are rotated by the kubelet
+		cred := Credential{Kind: "bearer token file " + config.BearerTokenFile, Refreshable: true}
+		if data, err := os.ReadFile(config.BearerTokenFile); err == nil {
+			cred.Expiry = tokenExpiry(strings.TrimSpace(string(data)))
+		}
+		creds = append(creds, cred)
+	} else if config.BearerToken != "" {
+		creds = append(creds, Credential{Kind: "bearer token", Expiry: tokenExpiry(config.BearerToken)})
+	}
+	return creds, nil
+}
+
+// authProviderCredential returns the credential of the auth provider. The oidc
+// provider only refreshes its ID token with a refresh token, without one the
+// run fails once the ID token expires.
+func authProviderCredential(provider *clientcmdapi.AuthProviderConfig) Credential {
+	cred := Credential{Kind: fmt.Sprintf("auth provider %s", provider.Name), Refreshable: true}
+	if provider.Name == "oidc" {
+		cred.Expiry = tokenExpiry(provider.Config["id-token"])
+		cred.Refreshable = provider.Config["refresh-token"] != ""
+	}
+	return cred
+}
+
+// CheckCredentials prints when the credentials of the config expire and fails
+// when one that can't be refreshed expires before the expected end of the
+// run, which would otherwise make the run fail late with authentication errors.
+func CheckCredentials(config *rest.Config, expected time.Duration, now time.Time) error {
+	creds, err := Credentials(config)
+	if err != nil {
+		return fmt.Errorf("error reading the cluster credentials: %w", err)
+	}
+	for _, cred := range creds {
+		switch {
+		case cred.Expiry.IsZero() && cred.Refreshable:
+			log.Printf("Credentials: %s, refreshed when needed", cred.Kind)
+		case cred.Expiry.IsZero():
+			log.Printf("Credentials: %s, no expiry", cred.Kind)
+		case cred.Refreshable:
+			log.Printf("Credentials: %s, expires at %s (in %s), refreshed when needed", cred.Kind, cred.Expiry.Format(time.RFC3339), cred.Expiry.Sub(now).Round(time.Minute))
+		default:
+			log.Printf("Credentials: %s, expires at %s (in %s)", cred.Kind, cred.Expiry.Format(time.RFC3339), cred.Expiry.Sub(now).Round(time.Minute))
+		}
+	}
+
+	for _, cred := range creds {
+		if cred.Refreshable || cred.Expiry.IsZero() {
+			continue
+		}
+		if !cred.Expiry.After(now) {
+			return fmt.Errorf("the %s expired at %s, renew the credentials of the kubeconfig", cred.Kind, cred.Expiry.Format(time.RFC3339))
+		}
+		if expected > 0 && cred.Expiry.Before(now.Add(expected)) {
+			return fmt.Errorf("the %s expires in %s, before the expected end of the run in %s. renew the credentials of the kubeconfig or adjust --expected-duration",
+				cred.Kind, cred.Expiry.Sub(now).Round(time.Minute), expected)
+		}
+	}
+	return nil
+}
+
+// certificateExpiry returns the expiry of the first certificate of the PEM data
+func certificateExpiry(data []byte) (time.Time, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return time.Time{}, fmt.Errorf("client certificate is not a PEM encoded certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing the client certificate: %w", err)
+	}
+	return cert.NotAfter, nil
+}
+
+// tokenExpiry returns the exp claim of a JWT, zero for opaque tokens and
+// tokens without expiry
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func testCertificate(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "hydrophone"},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func testToken(exp time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"hydrophone","exp":%d}`, exp.Unix())))
+	return "eyJhbGciOiJSUzI1NiJ9." + payload + ".c2lnbmF0dXJl"
+}
+
+func TestCheckCredentials(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		config   *rest.Config
+		expected time.Duration
+		wantErr  string
+	}{
+		{
+			name:     "certificate outliving the run",
+			config:   &rest.Config{TLSClientConfig: rest.TLSClientConfig{CertData: testCertificate(t, now.Add(24*time.Hour))}},
+			expected: 2 * time.Hour,
+		},
+		{
+			name:     "certificate expiring during the run",
+			config:   &rest.Config{TLSClientConfig: rest.TLSClientConfig{CertData: testCertificate(t, now.Add(time.Hour))}},
+			expected: 2 * time.Hour,
+			wantErr:  "the client certificate expires in 1h0m0s, before the expected end of the run in 2h0m0s",
+		},
+		{
+			name:    "expired certificate",
+			config:  &rest.Config{TLSClientConfig: rest.TLSClientConfig{CertData: testCertificate(t, now.Add(-time.Hour))}},
+			wantErr: "the client certificate expired at 2024-03-01T11:00:00Z",
+		},
+		{
+			name:     "token expiring during the run",
+			config:   &rest.Config{BearerToken: testToken(now.Add(30 * time.Minute))},
+			expected: time.Hour,
+			wantErr:  "the bearer token expires in 30m0s",
+		},
+		{
+			name:     "opaque token",
+			config:   &rest.Config{BearerToken: "opaque"},
+			expected: time.Hour,
+		},
+		{
+			name:     "exec plugin",
+			config:   &rest.Config{ExecProvider: &clientcmdapi.ExecConfig{Command: "gke-gcloud-auth-plugin"}},
+			expected: time.Hour,
+		},
+		{
+			name: "oidc with refresh token",
+			config: &rest.Config{AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "oidc", Config: map[string]string{
+				"id-token":      testToken(now.Add(10 * time.Minute)),
+				"refresh-token": "refresh",
+			}}},
+			expected: 6 * time.Hour,
+		},
+		{
+			name: "oidc without refresh token",
+			config: &rest.Config{AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "oidc", Config: map[string]string{
+				"id-token": testToken(now.Add(10 * time.Minute)),
+			}}},
+			expected: 6 * time.Hour,
+			wantErr:  "the auth provider oidc expires in 10m0s",
+		},
+		{
+			name:     "certificate expiring during the run without expected duration",
+			config:   &rest.Config{TLSClientConfig: rest.TLSClientConfig{CertData: testCertificate(t, now.Add(time.Hour))}},
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCredentials(tt.config, tt.expected, now)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	exp := time.Unix(1709294400, 0)
+	assert.Equal(t, exp, tokenExpiry(testToken(exp)))
+	assert.True(t, tokenExpiry("opaque").IsZero())
+	assert.True(t, tokenExpiry("a.b.c").IsZero())
+}
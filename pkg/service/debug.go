@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// debugContainerPrefix prefixes the names of the ephemeral containers
+// DebugPod attaches, numbered as they can't be removed from the pod
+const debugContainerPrefix = "hydrophone-debug-"
+
+var (
+	// debugPollInterval is how often DebugPod checks whether the command
+	// completed
+	debugPollInterval = 2 * time.Second
+	// debugTimeout is how long DebugPod waits for the command to complete
+	debugTimeout = 2 * time.Minute
+)
+
+// DebugPod attaches an ephemeral container running the busybox image to the
+// conformance container of the pod, sharing its processes, runs the shell
+// command in it and returns its output once it completed.
+func DebugPod(clientset kubernetes.Interface, podName, command string) ([]byte, error) {
+	pods := clientset.CoreV1().Pods(settings.GetString("namespace"))
+	pod, err := pods.Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s%d", debugContainerPrefix, len(pod.Spec.EphemeralContainers)+1)
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, v1.EphemeralContainer{
+		EphemeralContainerCommon: v1.EphemeralContainerCommon{
+			Name:    name,
+			Image:   settings.GetString("busybox-image"),
+			Command: []string{"/bin/sh", "-c", command},
+		},
+		TargetContainerName: common.ConformanceContainer,
+	})
+	if _, err := pods.UpdateEphemeralContainers(ctx, podName, pod, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("error attaching an ephemeral container to pod %s: %w", podName, err)
+	}
+
+	err = wait.PollUntilContextTimeout(ctx, debugPollInterval, debugTimeout, true, func(ctx context.Context) (bool, error) {
+		pod, err := pods.Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name == name && status.State.Terminated != nil {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for container %s of pod %s to complete: %w", name, podName, err)
+	}
+
+	stream, err := pods.GetLogs(podName, &v1.PodLogOptions{Container: name}).Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	return io.ReadAll(stream)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestDebugPod(t *testing.T) {
+	debugPollInterval = time.Millisecond
+	defer func() { debugPollInterval = 2 * time.Second }()
+	settings.Set("namespace", "conformance")
+	settings.Set("busybox-image", "registry.k8s.io/e2e-test-images/busybox:1.36.1-1")
+	defer settings.Set("namespace", "")
+	defer settings.Set("busybox-image", "")
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "e2e-conformance-test", Namespace: "conformance"}}
+	clientset := fake.NewSimpleClientset(pod)
+	// the ephemeral container completes as soon as it is attached
+	clientset.PrependReactor("update", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		update := action.(k8stesting.UpdateAction)
+		if update.GetSubresource() == "ephemeralcontainers" {
+			pod := update.GetObject().(*v1.Pod)
+			for _, container := range pod.Spec.EphemeralContainers {
+				pod.Status.EphemeralContainerStatuses = append(pod.Status.EphemeralContainerStatuses, v1.ContainerStatus{
+					Name:  container.Name,
+					State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{}},
+				})
+			}
+		}
+		return false, nil, nil
+	})
+
+	output, err := DebugPod(clientset, "e2e-conformance-test", "ps")
+	require.NoError(t, err)
+	assert.Equal(t, "fake logs", string(output))
+	_, err = DebugPod(clientset, "e2e-conformance-test", "ps")
+	require.NoError(t, err)
+
+	updated, err := clientset.CoreV1().Pods("conformance").Get(ctx, "e2e-conformance-test", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Len(t, updated.Spec.EphemeralContainers, 2)
+	container := updated.Spec.EphemeralContainers[1]
+	assert.Equal(t, "hydrophone-debug-2", container.Name)
+	assert.Equal(t, common.ConformanceContainer, container.TargetContainerName)
+	assert.Equal(t, "registry.k8s.io/e2e-test-images/busybox:1.36.1-1", container.Image)
+	assert.Equal(t, []string{"/bin/sh", "-c", "ps"}, container.Command)
+
+	_, err = DebugPod(clientset, "missing", "ps")
+	assert.Error(t, err)
+}
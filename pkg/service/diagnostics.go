@@ -0,0 +1,293 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/redact"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+const (
+	// DiagnosticsDir is the directory of the output directory the
+	// diagnostics of failed runs are written to
+	DiagnosticsDir = "diagnostics"
+	// DiagnosticsLogFile is the file of the diagnostics holding the log of
+	// hydrophone
+	DiagnosticsLogFile = "hydrophone.log"
+)
+
+// CollectDiagnostics writes what is needed to triage a failed run to dir: the
+// description and the events of the pods of the namespace of the run, the
+// events of the namespace, the conditions of the nodes, and the events of the
+// cluster reported by the scheduler and the kubelets or about the nodes. What
+// can be collected is written even when some of it can't.
+func CollectDiagnostics(clientset kubernetes.Interface, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	namespace := settings.GetString("namespace")
+	var errs []error
+
+	events, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error listing the events of namespace %s: %w", namespace, err))
+		events = &v1.EventList{}
+	}
+	sortEvents(events.Items)
+	if err := writeDiagnostics(dir, "events.txt", func(w io.Writer) error {
+		return writeEvents(w, events.Items)
+	}); err != nil {
+		errs = append(errs, err)
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error listing the pods of namespace %s: %w", namespace, err))
+	} else if err := writeDiagnostics(dir, "pods.txt", func(w io.Writer) error {
+		for i := range pods.Items {
+			if err := describePod(w, &pods.Items[i], events.Items); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		errs = append(errs, err)
+	}
+
+	// the nodes and the events of the cluster are cluster-scoped
+	if common.Restricted() {
+		return errors.Join(errs...)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error listing nodes: %w", err))
+	} else if err := writeDiagnostics(dir, "nodes.txt", func(w io.Writer) error {
+		for i := range nodes.Items {
+			if err := describeNode(w, &nodes.Items[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		errs = append(errs, err)
+	}
+
+	clusterEvents, err := clientset.CoreV1().Events(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error listing the events of the cluster: %w", err))
+	} else {
+		var relevant []v1.Event
+		for _, event := range clusterEvents.Items {
+			if infrastructureEvent(&event) {
+				relevant = append(relevant, event)
+			}
+		}
+		sortEvents(relevant)
+		if err := writeDiagnostics(dir, "cluster-events.txt", func(w io.Writer) error {
+			return writeEvents(w, relevant)
+		}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeDiagnostics writes the file of the diagnostics with write
+func writeDiagnostics(dir, name string, write func(w io.Writer) error) error {
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	// the environment of the pods may hold credentials
+	w := redact.NewWriter(f)
+	if err := write(w); err != nil {
+		w.Close()
+		return fmt.Errorf("error writing %s: %w", name, err)
+	}
+	return w.Close()
+}
+
+// infrastructureEvent reports whether the event tells about the cluster
+// rather than a workload: it is about a node, or it is a warning of the
+// scheduler or a kubelet
+func infrastructureEvent(event *v1.Event) bool {
+	if event.InvolvedObject.Kind == "Node" {
+		return true
+	}
+	if event.Type != v1.EventTypeWarning {
+		return false
+	}
+	component := event.Source.Component
+	if component == "" {
+		component = event.ReportingController
+	}
+	return component == "kubelet" || component == "default-scheduler"
+}
+
+// eventTime returns the last time the event was seen
+func eventTime(event *v1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+// sortEvents sorts the events by the last time they were seen
+func sortEvents(events []v1.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(&events[i]).Before(eventTime(&events[j]))
+	})
+}
+
+// writeEvents writes the events as a table, like kubectl get events
+func writeEvents(w io.Writer, events []v1.Event) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "LAST SEEN\tTYPE\tREASON\tOBJECT\tCOUNT\tMESSAGE")
+	for _, event := range events {
+		object := strings.ToLower(event.InvolvedObject.Kind) + "/" + event.InvolvedObject.Name
+		if event.InvolvedObject.Namespace != "" {
+			object = event.InvolvedObject.Namespace + "/" + object
+		}
+		count := event.Count
+		if count == 0 {
+			count = 1
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", eventTime(&event).UTC().Format(time.RFC3339), event.Type,
+			event.Reason, object, count, strings.TrimSpace(event.Message))
+	}
+	return tw.Flush()
+}
+
+// describePod writes the status of the pod, its conditions, the states of
+// its containers and its events
+func describePod(w io.Writer, pod *v1.Pod, events []v1.Event) error {
+	fmt.Fprintf(w, "Name:       %s\n", pod.Name)
+	fmt.Fprintf(w, "Namespace:  %s\n", pod.Namespace)
+	fmt.Fprintf(w, "Node:       %s\n", pod.Spec.NodeName)
+	fmt.Fprintf(w, "Phase:      %s\n", pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		fmt.Fprintf(w, "Reason:     %s\n", pod.Status.Reason)
+	}
+	if pod.Status.Message != "" {
+		fmt.Fprintf(w, "Message:    %s\n", pod.Status.Message)
+	}
+	fmt.Fprintln(w, "Conditions:")
+	for _, condition := range pod.Status.Conditions {
+		fmt.Fprintf(w, "  %s=%s", condition.Type, condition.Status)
+		if condition.Reason != "" || condition.Message != "" {
+			fmt.Fprintf(w, " %s %s", condition.Reason, condition.Message)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "Containers:")
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		fmt.Fprintf(w, "  %s: %s, ready=%t, restarts=%d\n", status.Name, containerState(status.State), status.Ready, status.RestartCount)
+		if status.LastTerminationState.Terminated != nil {
+			fmt.Fprintf(w, "    last state: %s\n", containerState(status.LastTerminationState))
+		}
+	}
+	fmt.Fprintln(w, "Events:")
+	var podEvents []v1.Event
+	for _, event := range events {
+		if event.InvolvedObject.Kind == "Pod" && event.InvolvedObject.Name == pod.Name {
+			podEvents = append(podEvents, event)
+		}
+	}
+	if err := writeEvents(w, podEvents); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// containerState describes the state of a container
+func containerState(state v1.ContainerState) string {
+	switch {
+	case state.Running != nil:
+		return fmt.Sprintf("running since %s", state.Running.StartedAt.UTC().Format(time.RFC3339))
+	case state.Terminated != nil:
+		terminated := state.Terminated
+		description := fmt.Sprintf("terminated with exit code %d", terminated.ExitCode)
+		if terminated.Reason != "" {
+			description += ", " + terminated.Reason
+		}
+		if terminated.Message != "" {
+			description += ": " + strings.TrimSpace(terminated.Message)
+		}
+		return description
+	case state.Waiting != nil:
+		description := "waiting"
+		if state.Waiting.Reason != "" {
+			description += ", " + state.Waiting.Reason
+		}
+		if state.Waiting.Message != "" {
+			description += ": " + state.Waiting.Message
+		}
+		return description
+	default:
+		return "unknown"
+	}
+}
+
+// describeNode writes the schedulability, the taints, the conditions and
+// the allocatable resources of the node
+func describeNode(w io.Writer, node *v1.Node) error {
+	fmt.Fprintf(w, "Name:           %s\n", node.Name)
+	fmt.Fprintf(w, "Kubelet:        %s\n", node.Status.NodeInfo.KubeletVersion)
+	fmt.Fprintf(w, "Unschedulable:  %t\n", node.Spec.Unschedulable)
+	fmt.Fprintln(w, "Taints:")
+	for _, taint := range node.Spec.Taints {
+		fmt.Fprintf(w, "  %s\n", taint.ToString())
+	}
+	fmt.Fprintln(w, "Conditions:")
+	for _, condition := range node.Status.Conditions {
+		fmt.Fprintf(w, "  %s=%s %s %s (last transition %s)\n", condition.Type, condition.Status, condition.Reason,
+			condition.Message, condition.LastTransitionTime.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintln(w, "Allocatable:")
+	names := make([]string, 0, len(node.Status.Allocatable))
+	for name := range node.Status.Allocatable {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		quantity := node.Status.Allocatable[v1.ResourceName(name)]
+		fmt.Fprintf(w, "  %s: %s\n", name, quantity.String())
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
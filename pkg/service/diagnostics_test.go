@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestCollectDiagnostics(t *testing.T) {
+	settings.Set("namespace", "conformance")
+	defer settings.Set("namespace", "")
+
+	seen := metav1.NewTime(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	event := func(namespace, name, kind, object, component, eventType, reason, message string) *v1.Event {
+		return &v1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: namespace},
+			InvolvedObject: v1.ObjectReference{Kind: kind, Name: object, Namespace: namespace},
+			Source:         v1.EventSource{Component: component},
+			Type:           eventType,
+			Reason:         reason,
+			Message:        message,
+			LastTimestamp:  seen,
+		}
+	}
+	clientset := fake.NewSimpleClientset(
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "e2e-conformance-test", Namespace: "conformance"},
+			Spec:       v1.PodSpec{NodeName: "node-1"},
+			Status: v1.PodStatus{
+				Phase: v1.PodFailed,
+				ContainerStatuses: []v1.ContainerStatus{{
+					Name:  "conformance-container",
+					State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}},
+				}},
+			},
+		},
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Spec:       v1.NodeSpec{Taints: []v1.Taint{{Key: "node.kubernetes.io/memory-pressure", Effect: v1.TaintEffectNoSchedule}}},
+			Status: v1.NodeStatus{Conditions: []v1.NodeCondition{{
+				Type: v1.NodeMemoryPressure, Status: v1.ConditionTrue, Reason: "KubeletHasInsufficientMemory",
+			}}},
+		},
+		event("conformance", "oom", "Pod", "e2e-conformance-test", "kubelet", v1.EventTypeWarning, "OOMKilling", "out of memory"),
+		event("default", "pressure", "Node", "node-1", "kubelet", v1.EventTypeNormal, "NodeHasInsufficientMemory", "memory pressure"),
+		event("shop", "scale", "Deployment", "web", "deployment-controller", v1.EventTypeNormal, "ScalingReplicaSet", "scaled up"),
+	)
+
+	dir := filepath.Join(t.TempDir(), DiagnosticsDir)
+	require.NoError(t, CollectDiagnostics(clientset, dir))
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+	pods := read("pods.txt")
+	assert.Contains(t, pods, "Node:       node-1")
+	assert.Contains(t, pods, "conformance-container: terminated with exit code 137, OOMKilled")
+	assert.Contains(t, pods, "OOMKilling")
+	assert.Contains(t, read("events.txt"), "conformance/pod/e2e-conformance-test")
+	nodes := read("nodes.txt")
+	assert.Contains(t, nodes, "node.kubernetes.io/memory-pressure:NoSchedule")
+	assert.Contains(t, nodes, "MemoryPressure=True KubeletHasInsufficientMemory")
+	clusterEvents := read("cluster-events.txt")
+	assert.Contains(t, clusterEvents, "NodeHasInsufficientMemory")
+	assert.Contains(t, clusterEvents, "OOMKilling")
+	assert.NotContains(t, clusterEvents, "ScalingReplicaSet")
+}
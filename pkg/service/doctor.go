@@ -0,0 +1,319 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	authorization "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sversion "k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/registry"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// maxClockSkew is the difference between the clocks of the machine and of
+// the API server beyond which credentials can appear expired or not yet valid
+const maxClockSkew = 30 * time.Second
+
+// DoctorCheck is the outcome of a check of the environment hydrophone runs in
+type DoctorCheck struct {
+	Name string
+	// Problem is empty when the check passed
+	Problem string
+	// Remedy tells how to fix the problem
+	Remedy string
+	// Skipped is set when the check couldn't run because an earlier one failed
+	Skipped bool
+}
+
+// Failed reports whether the check found a problem
+func (c DoctorCheck) Failed() bool {
+	return c.Problem != ""
+}
+
+// doctorPermission is a permission hydrophone needs in the cluster
+type doctorPermission struct {
+	verb, group, resource, subresource string
+	// namespaced permissions are checked in the namespace of the run
+	namespaced bool
+}
+
+// managedPermissions are needed to create the namespace, the service account
+// and the RBAC resources of the run
+var managedPermissions = []doctorPermission{
+	{verb: "create", resource: "namespaces"},
+	{verb: "delete", resource: "namespaces"},
+	{verb: "create", resource: "serviceaccounts", namespaced: true},
+	{verb: "create", group: "rbac.authorization.k8s.io", resource: "clusterroles"},
+	{verb: "delete", group: "rbac.authorization.k8s.io", resource: "clusterroles"},
+	{verb: "create", group: "rbac.authorization.k8s.io", resource: "clusterrolebindings"},
+	{verb: "delete", group: "rbac.authorization.k8s.io", resource: "clusterrolebindings"},
+}
+
+// runPermissions are needed to run the conformance pods and collect their
+// results
+var runPermissions = []doctorPermission{
+	{verb: "list", resource: "nodes"},
+	{verb: "create", resource: "pods", namespaced: true},
+	{verb: "watch", resource: "pods", namespaced: true},
+	{verb: "get", resource: "pods", subresource: "log", namespaced: true},
+	{verb: "create", resource: "pods", subresource: "exec", namespaced: true},
+}
+
+// Doctor runs the diagnostics of the environment hydrophone runs in: the
+// kubeconfig, the API server, the permissions of the user, the registries of
+// the conformance and busybox images, the output directory and the clock.
+// The checks of the cluster are skipped when the kubeconfig can't be loaded
+// or the API server can't be reached.
+func Doctor(kubeconfig string) []DoctorCheck {
+	config, check := doctorKubeconfig(kubeconfig)
+	checks := []DoctorCheck{check}
+	var clientset kubernetes.Interface
+	if config != nil {
+		clientset, check = doctorAPIServer(config)
+		checks = append(checks, check)
+	} else {
+		checks = append(checks, skippedCheck("api-server"))
+	}
+	if clientset != nil {
+		checks = append(checks, doctorPermissions(clientset), doctorClock(config))
+	} else {
+		checks = append(checks, skippedCheck("permissions"), skippedCheck("clock"))
+	}
+	common.SetDefaultImages("")
+	checker := registry.NewChecker()
+	for _, flag := range []string{"conformance-image", "busybox-image"} {
+		if settings.GetString(flag) == "" {
+			// the conformance image depends on the version of the cluster
+			checks = append(checks, skippedCheck(flag))
+			continue
+		}
+		checks = append(checks, doctorImage(checker, flag))
+	}
+	return append(checks, doctorOutputDir(settings.GetString("output-dir")))
+}
+
+func skippedCheck(name string) DoctorCheck {
+	return DoctorCheck{Name: name, Skipped: true}
+}
+
+// doctorKubeconfig loads the kubeconfig the way Init does, without failing
+func doctorKubeconfig(kubeconfig string) (*rest.Config, DoctorCheck) {
+	check := DoctorCheck{Name: "kubeconfig"}
+	kubeContext, cluster := settings.GetString("context"), settings.GetString("cluster")
+	var config *rest.Config
+	if kubeContext == "" && cluster == "" {
+		config, _ = rest.InClusterConfig()
+	}
+	if config == nil {
+		var err error
+		if config, err = client.LoadConfig(kubeconfig, kubeContext, cluster); err != nil {
+			check.Problem = fmt.Sprintf("the kubeconfig can't be loaded: %v", err)
+			check.Remedy = "point --kubeconfig or KUBECONFIG at a valid kubeconfig and check the context with kubectl config get-contexts"
+			return nil, check
+		}
+	}
+	if err := configureClient(config); err != nil {
+		check.Problem = err.Error()
+		check.Remedy = "fix the client flags, e.g. --as, --proxy-url or the TLS flags"
+		return nil, check
+	}
+	return config, check
+}
+
+// doctorAPIServer checks that the API server can be reached
+func doctorAPIServer(config *rest.Config) (kubernetes.Interface, DoctorCheck) {
+	check := DoctorCheck{Name: "api-server"}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err == nil {
+		var version *k8sversion.Info
+		if version, err = clientset.Discovery().ServerVersion(); err == nil {
+			// the default conformance image matches the version of the cluster
+			if _, err := common.SetServerVersion(version.String()); err != nil {
+				log.Printf("WARNING: %v", err)
+			}
+		}
+	}
+	if err != nil {
+		check.Problem = fmt.Sprintf("the API server %s can't be reached: %v", config.Host, err)
+		check.Remedy = "check the server of the kubeconfig, that it can be reached from this machine, e.g. through a VPN or --proxy-url, and that the credentials haven't expired"
+		return nil, check
+	}
+	return clientset, check
+}
+
+// doctorPermissions checks that the user of the kubeconfig has the
+// permissions hydrophone needs
+func doctorPermissions(clientset kubernetes.Interface) DoctorCheck {
+	check := DoctorCheck{Name: "permissions"}
+	permissions := runPermissions
+	if ManagedRBAC() {
+		permissions = append(append([]doctorPermission{}, managedPermissions...), runPermissions...)
+	}
+	var denied []string
+	for _, p := range permissions {
+		attributes := &authorization.ResourceAttributes{Verb: p.verb, Group: p.group, Resource: p.resource, Subresource: p.subresource}
+		if p.namespaced {
+			attributes.Namespace = settings.GetString("namespace")
+		}
+		review, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorization.SelfSubjectAccessReview{
+			Spec: authorization.SelfSubjectAccessReviewSpec{ResourceAttributes: attributes},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			check.Problem = fmt.Sprintf("the permissions can't be reviewed: %v", err)
+			check.Remedy = "grant the user of the kubeconfig the permission to create selfsubjectaccessreviews"
+			return check
+		}
+		if !review.Status.Allowed {
+			resource := p.resource
+			if p.subresource != "" {
+				resource += "/" + p.subresource
+			}
+			denied = append(denied, fmt.Sprintf("%s %s", p.verb, resource))
+		}
+	}
+	if len(denied) != 0 {
+		check.Problem = fmt.Sprintf("the user of the kubeconfig isn't allowed to %s", strings.Join(denied, ", "))
+		check.Remedy = "run hydrophone as a cluster administrator, or have one create the namespace and the service account of the run and pass them with --namespace and --service-account"
+	}
+	return check
+}
+
+// doctorClock compares the clock of the machine with the one of the API
+// server, read from the Date header of its responses
+func doctorClock(config *rest.Config) DoctorCheck {
+	check := DoctorCheck{Name: "clock"}
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		check.Problem = err.Error()
+		return check
+	}
+	resp, err := httpClient.Get(strings.TrimSuffix(config.Host, "/") + "/version")
+	if err != nil {
+		check.Problem = fmt.Sprintf("the time of the API server can't be read: %v", err)
+		return check
+	}
+	resp.Body.Close()
+	server, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		// nothing to compare with
+		return check
+	}
+	if problem := clockSkew(time.Now(), server); problem != "" {
+		check.Problem = problem
+		check.Remedy = "synchronize the clock of this machine, e.g. with NTP. skewed clocks make credentials and certificates appear expired or not yet valid"
+	}
+	return check
+}
+
+// clockSkew describes the difference between the clocks of the machine and
+// of the API server when it exceeds maxClockSkew
+func clockSkew(local, server time.Time) string {
+	skew := local.Sub(server)
+	switch {
+	case skew > maxClockSkew:
+		return fmt.Sprintf("the clock of this machine is %s ahead of the API server", skew.Round(time.Second))
+	case skew < -maxClockSkew:
+		return fmt.Sprintf("the clock of this machine is %s behind the API server", (-skew).Round(time.Second))
+	}
+	return ""
+}
+
+// doctorImage checks that the registry of the image of the flag can be
+// reached and has the image
+func doctorImage(checker *registry.Checker, flag string) DoctorCheck {
+	image := settings.GetString(flag)
+	check := DoctorCheck{Name: flag}
+	exists, err := checker.Exists(image)
+	switch {
+	case err != nil:
+		check.Problem = fmt.Sprintf("the registry of %s can't be reached: %v", image, err)
+		check.Remedy = fmt.Sprintf("check that the registry can be reached from this machine, e.g. through HTTPS_PROXY, or mirror the image and pass the mirror with --%s. the nodes may still pull it", flag)
+	case !exists:
+		check.Problem = fmt.Sprintf("%s doesn't exist", image)
+		check.Remedy = fmt.Sprintf("pick an existing image with --%s", flag)
+	}
+	return check
+}
+
+// doctorOutputDir checks that files can be written to the output directory,
+// or to the directory it will be created in
+func doctorOutputDir(outputDir string) DoctorCheck {
+	check := DoctorCheck{Name: "output-dir"}
+	dir, err := filepath.Abs(outputDir)
+	for err == nil {
+		var info fs.FileInfo
+		info, err = os.Stat(dir)
+		if err == nil && !info.IsDir() {
+			err = fmt.Errorf("%s is not a directory", dir)
+		}
+		if !errors.Is(err, fs.ErrNotExist) || filepath.Dir(dir) == dir {
+			break
+		}
+		dir, err = filepath.Dir(dir), nil
+	}
+	if err == nil {
+		var f *os.File
+		if f, err = os.CreateTemp(dir, ".hydrophone-doctor-*"); err == nil {
+			f.Close()
+			err = os.Remove(f.Name())
+		}
+	}
+	if err != nil {
+		check.Problem = fmt.Sprintf("the output directory %s isn't writable: %v", outputDir, err)
+		check.Remedy = "pass a writable directory with --output-dir"
+	}
+	return check
+}
+
+// WriteDoctorChecks writes the outcome of each check, with the remedy of the
+// problems found
+func WriteDoctorChecks(w io.Writer, checks []DoctorCheck) error {
+	for _, check := range checks {
+		var err error
+		switch {
+		case check.Skipped:
+			_, err = fmt.Fprintf(w, "skip  %s\n", check.Name)
+		case !check.Failed():
+			_, err = fmt.Fprintf(w, "ok    %s\n", check.Name)
+		default:
+			_, err = fmt.Fprintf(w, "FAIL  %s: %s\n", check.Name, check.Problem)
+			if err == nil && check.Remedy != "" {
+				_, err = fmt.Fprintf(w, "      fix: %s\n", check.Remedy)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
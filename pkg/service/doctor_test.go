@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorization "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestDoctorPermissions(t *testing.T) {
+	defer settings.Set("service-account", "")
+	defer settings.Set("namespace", "")
+	settings.Set("namespace", "conformance")
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorization.SelfSubjectAccessReview)
+		// namespaced permissions only
+		review.Status.Allowed = review.Spec.ResourceAttributes.Namespace != "" || review.Spec.ResourceAttributes.Resource == "nodes"
+		return true, review, nil
+	})
+
+	check := doctorPermissions(clientset)
+	assert.Equal(t, "the user of the kubeconfig isn't allowed to create namespaces, delete namespaces, create clusterroles, delete clusterroles, create clusterrolebindings, delete clusterrolebindings", check.Problem)
+	assert.NotEmpty(t, check.Remedy)
+
+	settings.Set("service-account", "conformance")
+	assert.False(t, doctorPermissions(clientset).Failed())
+}
+
+func TestClockSkew(t *testing.T) {
+	server := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	assert.Empty(t, clockSkew(server.Add(10*time.Second), server))
+	assert.Equal(t, "the clock of this machine is 2m0s ahead of the API server", clockSkew(server.Add(2*time.Minute), server))
+	assert.Equal(t, "the clock of this machine is 45s behind the API server", clockSkew(server.Add(-45*time.Second), server))
+}
+
+func TestDoctorOutputDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.False(t, doctorOutputDir(dir).Failed())
+	assert.False(t, doctorOutputDir(filepath.Join(dir, "results", "{cluster}")).Failed())
+
+	file := filepath.Join(dir, "results.json")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+	check := doctorOutputDir(file)
+	assert.True(t, check.Failed())
+	assert.Equal(t, "pass a writable directory with --output-dir", check.Remedy)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the probe file is removed")
+}
+
+func TestWriteDoctorChecks(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteDoctorChecks(&buf, []DoctorCheck{
+		{Name: "kubeconfig"},
+		{Name: "api-server", Problem: "the API server https://10.0.0.1:6443 can't be reached: timeout", Remedy: "check the server of the kubeconfig"},
+		{Name: "permissions", Skipped: true},
+	}))
+	assert.Equal(t, `ok    kubeconfig
+FAIL  api-server: the API server https://10.0.0.1:6443 can't be reached: timeout
+      fix: check the server of the kubeconfig
+skip  permissions
+`, buf.String())
+}
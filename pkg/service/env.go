@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// applyEnv adds the environment variables of --env, --env-from-secret and
+// --env-from-configmap to the conformance container. The variables set by
+// hydrophone itself, e.g. E2E_FOCUS, can't be overridden, they are set
+// through their flags.
+func applyEnv(pod *v1.Pod) error {
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		if container.Name != common.ConformanceContainer {
+			continue
+		}
+
+		managed := map[string]bool{}
+		for _, env := range container.Env {
+			managed[env.Name] = true
+		}
+		for _, value := range settings.GetStringSlice("env") {
+			name, v, ok := strings.Cut(value, "=")
+			if !ok || name == "" {
+				return fmt.Errorf("expected --env to be of KEY=VALUE format, got %q", value)
+			}
+			if managed[name] {
+				return fmt.Errorf("environment variable %s given with --env is set by hydrophone", name)
+			}
+			setEnv(container, name, v)
+		}
+
+		for _, name := range settings.GetStringSlice("env-from-secret") {
+			container.EnvFrom = append(container.EnvFrom, v1.EnvFromSource{
+				SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: name}},
+			})
+		}
+		for _, name := range settings.GetStringSlice("env-from-configmap") {
+			container.EnvFrom = append(container.EnvFrom, v1.EnvFromSource{
+				ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: name}},
+			})
+		}
+	}
+	return nil
+}
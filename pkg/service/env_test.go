@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestApplyEnv(t *testing.T) {
+	settings.Set("env", []string{"AWS_REGION=eu-west-1", "PROVIDER_CONFIG=a=b"})
+	settings.Set("env-from-secret", []string{"cloud-credentials"})
+	settings.Set("env-from-configmap", []string{"provider"})
+	defer func() {
+		settings.Set("env", []string{})
+		settings.Set("env-from-secret", []string{})
+		settings.Set("env-from-configmap", []string{})
+	}()
+
+	pod := ConformancePod("conformance")
+	require.NoError(t, applyEnv(pod))
+	container := pod.Spec.Containers[0]
+	assert.Contains(t, container.Env, v1.EnvVar{Name: "AWS_REGION", Value: "eu-west-1"})
+	assert.Contains(t, container.Env, v1.EnvVar{Name: "PROVIDER_CONFIG", Value: "a=b"})
+	assert.Equal(t, []v1.EnvFromSource{
+		{SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "cloud-credentials"}}},
+		{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "provider"}}},
+	}, container.EnvFrom)
+	assert.Empty(t, pod.Spec.Containers[1].Env)
+
+	settings.Set("env", []string{"E2E_FOCUS=sig-network"})
+	assert.Error(t, applyEnv(ConformancePod("conformance")))
+
+	settings.Set("env", []string{"AWS_REGION"})
+	assert.Error(t, applyEnv(ConformancePod("conformance")))
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// Garbage is a resource of a past run found by FindGarbage
+type Garbage struct {
+	Kind      string
+	Namespace string
+	Name      string
+	RunID     string
+	Age       time.Duration
+}
+
+// String returns the kind and the name of the resource, namespace/name for
+// namespaced resources
+func (g Garbage) String() string {
+	if g.Namespace != "" {
+		return fmt.Sprintf("%s %s/%s", g.Kind, g.Namespace, g.Name)
+	}
+	return fmt.Sprintf("%s %s", g.Kind, g.Name)
+}
+
+// gcKind lists and deletes the resources of a kind hydrophone creates
+type gcKind struct {
+	name string
+	list func(opts metav1.ListOptions) ([]metav1.Object, error)
+	del  func(namespace, name string) error
+}
+
+// objects returns the items of a list as objects
+func objects[T any, PT interface {
+	*T
+	metav1.Object
+}](items []T) []metav1.Object {
+	objs := make([]metav1.Object, len(items))
+	for i := range items {
+		objs[i] = PT(&items[i])
+	}
+	return objs
+}
+
+// gcKinds returns the kinds of the resources hydrophone creates in the order
+// they are deleted in: the jobs before their pods, the namespaces last.
+func gcKinds(clientset kubernetes.Interface) []gcKind {
+	propagation := metav1.DeletePropagationBackground
+	core, batch, rbac := clientset.CoreV1(), clientset.BatchV1(), clientset.RbacV1()
+	return []gcKind{
+		{"job", func(opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := batch.Jobs("").List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return objects(list.Items), nil
+		}, func(namespace, name string) error {
+			return batch.Jobs(namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		}},
+		{"pod", func(opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := core.Pods("").List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return objects(list.Items), nil
+		}, func(namespace, name string) error {
+			return core.Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		}},
+		{"configmap", func(opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := core.ConfigMaps("").List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return objects(list.Items), nil
+		}, func(namespace, name string) error {
+			return core.ConfigMaps(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		}},
+		{"secret", func(opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := core.Secrets("").List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return objects(list.Items), nil
+		}, func(namespace, name string) error {
+			return core.Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		}},
+		{"serviceaccount", func(opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := core.ServiceAccounts("").List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return objects(list.Items), nil
+		}, func(namespace, name string) error {
+			return core.ServiceAccounts(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		}},
+		{"clusterrolebinding", func(opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := rbac.ClusterRoleBindings().List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return objects(list.Items), nil
+		}, func(_, name string) error {
+			return rbac.ClusterRoleBindings().Delete(ctx, name, metav1.DeleteOptions{})
+		}},
+		{"clusterrole", func(opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := rbac.ClusterRoles().List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return objects(list.Items), nil
+		}, func(_, name string) error {
+			return rbac.ClusterRoles().Delete(ctx, name, metav1.DeleteOptions{})
+		}},
+		{"namespace", func(opts metav1.ListOptions) ([]metav1.Object, error) {
+			list, err := core.Namespaces().List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return objects(list.Items), nil
+		}, func(_, name string) error {
+			return core.Namespaces().Delete(ctx, name, metav1.DeleteOptions{})
+		}},
+	}
+}
+
+// GCSelector returns the label selector of the resources hydrophone gc
+// deletes: the resources of every run, or of the run of --run-id.
+func GCSelector() string {
+	selector := common.ManagedByLabel + "=" + common.ManagedBy
+	if id := settings.GetString("run-id"); id != "" {
+		selector += "," + common.RunIDLabel + "=" + id
+	}
+	return selector
+}
+
+// FindGarbage finds the resources labeled by hydrophone across the cluster
+// that were created more than olderThan before now, in the order they are to
+// be deleted in. The resources of a namespace found are left out, they are
+// deleted with it.
+func FindGarbage(clientset kubernetes.Interface, olderThan time.Duration, now time.Time) ([]Garbage, error) {
+	opts := metav1.ListOptions{LabelSelector: GCSelector()}
+	var garbage []Garbage
+	namespaces := map[string]bool{}
+	for _, kind := range gcKinds(clientset) {
+		objs, err := kind.list(opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list the %ss: %w", kind.name, err)
+		}
+		var found []Garbage
+		for _, obj := range objs {
+			age := now.Sub(obj.GetCreationTimestamp().Time)
+			if age < olderThan || obj.GetDeletionTimestamp() != nil {
+				continue
+			}
+			found = append(found, Garbage{
+				Kind:      kind.name,
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+				RunID:     obj.GetLabels()[common.RunIDLabel],
+				Age:       age,
+			})
+			if kind.name == "namespace" {
+				namespaces[obj.GetName()] = true
+			}
+		}
+		sort.Slice(found, func(i, j int) bool { return found[i].String() < found[j].String() })
+		garbage = append(garbage, found...)
+	}
+
+	// the namespaces are listed last
+	kept := garbage[:0]
+	for _, g := range garbage {
+		if !namespaces[g.Namespace] {
+			kept = append(kept, g)
+		}
+	}
+	return kept, nil
+}
+
+// DeleteGarbage deletes the resources found by FindGarbage in their order and
+// returns the errors of the resources that couldn't be deleted. Resources
+// that are already gone are skipped.
+func DeleteGarbage(clientset kubernetes.Interface, garbage []Garbage) error {
+	kinds := map[string]gcKind{}
+	for _, kind := range gcKinds(clientset) {
+		kinds[kind.name] = kind
+	}
+	var errs []error
+	for _, g := range garbage {
+		kind, ok := kinds[g.Kind]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown kind", g))
+			continue
+		}
+		if err := kind.del(g.Namespace, g.Name); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("%s: %w", g, err))
+			continue
+		}
+		log.Printf("%s deleted", g)
+	}
+	return errors.Join(errs...)
+}
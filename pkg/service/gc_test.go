@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestGarbage(t *testing.T) {
+	now := time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+	meta := func(namespace, name, runID string, age time.Duration) metav1.ObjectMeta {
+		labels := map[string]string{common.ManagedByLabel: common.ManagedBy}
+		if runID != "" {
+			labels[common.RunIDLabel] = runID
+		}
+		return metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels, CreationTimestamp: metav1.NewTime(now.Add(-age))}
+	}
+	clientset := fake.NewSimpleClientset(
+		// a run of hydrophone managing its namespace, its resources are
+		// deleted with the namespace
+		&v1.Namespace{ObjectMeta: meta("", "conformance-old", "old", 48*time.Hour)},
+		&v1.Pod{ObjectMeta: meta("conformance-old", "e2e-conformance-test", "old", 48*time.Hour)},
+		&rbacv1.ClusterRole{ObjectMeta: meta("", "conformance-serviceaccount-old", "old", 48*time.Hour)},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: meta("", "conformance-serviceaccount-role-old", "old", 48*time.Hour)},
+		// a run with --service-account in a namespace of the user
+		&batchv1.Job{ObjectMeta: meta("ci", "e2e-conformance-test", "ci", 30*time.Hour)},
+		&v1.ConfigMap{ObjectMeta: meta("ci", "repo-list", "ci", 30*time.Hour)},
+		&v1.Secret{ObjectMeta: meta("ci", "hydrophone-pull-secret", "ci", 30*time.Hour)},
+		// a run in progress
+		&v1.Namespace{ObjectMeta: meta("", "conformance-new", "new", time.Hour)},
+		&rbacv1.ClusterRole{ObjectMeta: meta("", "conformance-serviceaccount-new", "new", time.Hour)},
+		// resources not created by hydrophone
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ci", CreationTimestamp: metav1.NewTime(now.Add(-100 * time.Hour))}},
+		&v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "conformance", CreationTimestamp: metav1.NewTime(now.Add(-100 * time.Hour))}},
+	)
+
+	garbage, err := FindGarbage(clientset, 24*time.Hour, now)
+	require.NoError(t, err)
+	var names []string
+	for _, g := range garbage {
+		names = append(names, g.String())
+	}
+	assert.Equal(t, []string{
+		"job ci/e2e-conformance-test",
+		"configmap ci/repo-list",
+		"secret ci/hydrophone-pull-secret",
+		"clusterrolebinding conformance-serviceaccount-role-old",
+		"clusterrole conformance-serviceaccount-old",
+		"namespace conformance-old",
+	}, names)
+	assert.Equal(t, "ci", garbage[0].RunID)
+	assert.Equal(t, 30*time.Hour, garbage[0].Age)
+
+	settings.Set("run-id", "old")
+	defer settings.Set("run-id", "")
+	garbage, err = FindGarbage(clientset, 24*time.Hour, now)
+	require.NoError(t, err)
+	assert.Len(t, garbage, 3)
+
+	require.NoError(t, DeleteGarbage(clientset, garbage))
+	_, err = clientset.CoreV1().Namespaces().Get(ctx, "conformance-old", metav1.GetOptions{})
+	assert.Error(t, err)
+	_, err = clientset.RbacV1().ClusterRoles().Get(ctx, "conformance-serviceaccount-new", metav1.GetOptions{})
+	assert.NoError(t, err)
+	_, err = clientset.BatchV1().Jobs("ci").Get(ctx, "e2e-conformance-test", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	// deleting resources that are already gone succeeds
+	assert.NoError(t, DeleteGarbage(clientset, garbage))
+}
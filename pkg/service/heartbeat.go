@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+const (
+	// HeartbeatAnnotation holds the time hydrophone last reported the run
+	// alive, in RFC 3339 format
+	HeartbeatAnnotation = "hydrophone.k8s.io/heartbeat"
+	// HeartbeatIntervalAnnotation holds the interval of the heartbeats, a run
+	// whose heartbeat is several intervals old lost its hydrophone process
+	HeartbeatIntervalAnnotation = "hydrophone.k8s.io/heartbeat-interval"
+	// ProgressAnnotation holds the progress snapshot of the run as JSON
+	ProgressAnnotation = "hydrophone.k8s.io/progress"
+)
+
+// Heartbeat records in the cluster that hydrophone is alive, with the
+// progress of the run, for observers that only have access to the cluster.
+// The annotations are set on the namespace of the run when hydrophone manages
+// it, and on the HeartbeatConfigMapName config map of the namespace
+// otherwise. Nothing is recorded while the namespace doesn't exist.
+func Heartbeat(clientset kubernetes.Interface, progress *results.Progress, interval time.Duration, now time.Time) error {
+	namespace := settings.GetString("namespace")
+	if namespace == "" {
+		return nil
+	}
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	annotations := map[string]string{
+		HeartbeatAnnotation:         now.UTC().Format(time.RFC3339),
+		HeartbeatIntervalAnnotation: interval.String(),
+		ProgressAnnotation:          string(data),
+	}
+	patch, err := json.Marshal(map[string]any{"metadata": map[string]any{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+
+	if ManagedRBAC() {
+		_, err = clientset.CoreV1().Namespaces().Patch(ctx, namespace, types.MergePatchType, patch, metav1.PatchOptions{})
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	_, err = clientset.CoreV1().ConfigMaps(namespace).Patch(ctx, common.HeartbeatConfigMapName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if !errors.IsNotFound(err) {
+		return err
+	}
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        common.HeartbeatConfigMapName,
+			Namespace:   namespace,
+			Labels:      common.ConformanceLabels(),
+			Annotations: annotations,
+		},
+	}
+	_, err = clientset.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{})
+	if errors.IsNotFound(err) || errors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestHeartbeat(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	progress := &results.Progress{Phase: "stream logs", CurrentTest: "[sig-node] Pods should run", Total: 400, Completed: 12, Passed: 12}
+	defer settings.Set("namespace", "")
+	defer settings.Set("service-account", "")
+
+	// nothing is recorded before the namespace is known or exists
+	clientset := fake.NewSimpleClientset()
+	require.NoError(t, Heartbeat(clientset, progress, 10*time.Second, now))
+	settings.Set("namespace", "conformance")
+	require.NoError(t, Heartbeat(clientset, progress, 10*time.Second, now))
+
+	clientset = fake.NewSimpleClientset(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "conformance", Annotations: map[string]string{"owner": "platform"}}})
+	require.NoError(t, Heartbeat(clientset, progress, 10*time.Second, now))
+	ns, err := clientset.CoreV1().Namespaces().Get(ctx, "conformance", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "platform", ns.Annotations["owner"])
+	assert.Equal(t, "2024-03-01T12:00:00Z", ns.Annotations[HeartbeatAnnotation])
+	assert.Equal(t, "10s", ns.Annotations[HeartbeatIntervalAnnotation])
+	recorded := &results.Progress{}
+	require.NoError(t, json.Unmarshal([]byte(ns.Annotations[ProgressAnnotation]), recorded))
+	assert.Equal(t, progress, recorded)
+
+	// the namespace of --service-account isn't changed
+	settings.Set("service-account", "conformance")
+	require.NoError(t, Heartbeat(clientset, progress, 10*time.Second, now))
+	require.NoError(t, Heartbeat(clientset, progress, 10*time.Second, now.Add(10*time.Second)))
+	cm, err := clientset.CoreV1().ConfigMaps("conformance").Get(ctx, common.HeartbeatConfigMapName, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-01T12:00:10Z", cm.Annotations[HeartbeatAnnotation])
+	assert.Equal(t, common.ManagedBy, cm.Labels[common.ManagedByLabel])
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// impactStrikes is the number of consecutive checks the cluster has to look
+// degraded before the run is aborted, so that a short spike doesn't abort it
+const impactStrikes = 3
+
+// ImpactLimits are the degradation of the workloads sharing the cluster
+// tolerated by the impact guard, relative to the state before the run.
+type ImpactLimits struct {
+	// Pending is the number of additional pending pods
+	Pending int
+	// Restarts is the number of container restarts
+	Restarts int
+}
+
+// clusterHealth summarizes the pods and nodes outside of the test namespaces
+type clusterHealth struct {
+	pending  int
+	restarts int
+	notReady int
+}
+
+// WatchImpact compares the health of the workloads sharing the cluster with
+// their health before the run every interval. When the limits are exceeded
+// for several consecutive checks, abort is called with the reason and the
+// watch ends. It returns when the context is done.
+func WatchImpact(ctx context.Context, clientset kubernetes.Interface, limits ImpactLimits, interval time.Duration, abort func(reason string)) error {
+	baseline, err := currentHealth(ctx, clientset)
+	if err != nil {
+		return fmt.Errorf("error reading the health of the cluster for the impact guard: %w", err)
+	}
+	log.Printf("Impact guard: %d pending pods, %d container restarts and %d not ready nodes before the run",
+		baseline.pending, baseline.restarts, baseline.notReady)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		strikes := 0
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			health, err := currentHealth(ctx, clientset)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("impact guard: unable to read the health of the cluster: %v", err)
+				}
+				continue
+			}
+			reason := health.exceeds(baseline, limits)
+			if reason == "" {
+				if strikes != 0 {
+					log.Printf("impact guard: the cluster recovered")
+				}
+				strikes = 0
+				continue
+			}
+			strikes++
+			log.Printf("impact guard: %s (%d/%d)", reason, strikes, impactStrikes)
+			if strikes == impactStrikes {
+				abort(reason)
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// currentHealth reads the health of the pods and nodes outside of the
+// namespace of hydrophone and of the namespaces created by the tests
+func currentHealth(ctx context.Context, clientset kubernetes.Interface) (clusterHealth, error) {
+	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: common.E2ERunLabel})
+	if err != nil {
+		return clusterHealth{}, err
+	}
+	excluded := map[string]bool{settings.GetString("namespace"): true}
+	for _, ns := range namespaces.Items {
+		excluded[ns.Name] = true
+	}
+
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return clusterHealth{}, err
+	}
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return clusterHealth{}, err
+	}
+	return health(pods.Items, nodes.Items, excluded), nil
+}
+
+func health(pods []v1.Pod, nodes []v1.Node, excluded map[string]bool) clusterHealth {
+	h := clusterHealth{}
+	for _, pod := range pods {
+		if excluded[pod.Namespace] {
+			continue
+		}
+		if pod.Status.Phase == v1.PodPending {
+			h.pending++
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			h.restarts += int(status.RestartCount)
+		}
+	}
+	for _, node := range nodes {
+		if !nodeReady(node) {
+			h.notReady++
+		}
+	}
+	return h
+}
+
+// exceeds describes how the health is degraded beyond the limits compared to
+// the baseline, or returns an empty string
+func (h clusterHealth) exceeds(baseline clusterHealth, limits ImpactLimits) string {
+	var reasons []string
+	if pending := h.pending - baseline.pending; pending > limits.Pending {
+		reasons = append(reasons, fmt.Sprintf("%d more pending pods", pending))
+	}
+	if restarts := h.restarts - baseline.restarts; restarts > limits.Restarts {
+		reasons = append(reasons, fmt.Sprintf("%d container restarts", restarts))
+	}
+	if notReady := h.notReady - baseline.notReady; notReady > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d more not ready nodes", notReady))
+	}
+	return strings.Join(reasons, ", ")
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHealth(t *testing.T) {
+	pod := func(namespace string, phase v1.PodPhase, restarts int32) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			Status: v1.PodStatus{
+				Phase:             phase,
+				ContainerStatuses: []v1.ContainerStatus{{RestartCount: restarts}},
+			},
+		}
+	}
+	pods := []v1.Pod{
+		pod("default", v1.PodPending, 0),
+		pod("default", v1.PodRunning, 2),
+		pod("kube-system", v1.PodRunning, 1),
+		pod("conformance", v1.PodPending, 0),
+		pod("pods-1234", v1.PodRunning, 5),
+	}
+	nodes := []v1.Node{
+		{Status: v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}}},
+		{Status: v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionUnknown}}}},
+	}
+
+	got := health(pods, nodes, map[string]bool{"conformance": true, "pods-1234": true})
+	assert.Equal(t, clusterHealth{pending: 1, restarts: 3, notReady: 1}, got)
+}
+
+func TestHealthExceeds(t *testing.T) {
+	baseline := clusterHealth{pending: 2, restarts: 10, notReady: 1}
+	limits := ImpactLimits{Pending: 5, Restarts: 3}
+
+	tests := []struct {
+		name   string
+		health clusterHealth
+		want   string
+	}{
+		{
+			name:   "within limits",
+			health: clusterHealth{pending: 7, restarts: 13, notReady: 1},
+			want:   "",
+		},
+		{
+			name:   "pending pods",
+			health: clusterHealth{pending: 8, restarts: 10, notReady: 1},
+			want:   "6 more pending pods",
+		},
+		{
+			name:   "everything degraded",
+			health: clusterHealth{pending: 10, restarts: 20, notReady: 2},
+			want:   "8 more pending pods, 10 container restarts, 1 more not ready nodes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.health.exceeds(baseline, limits))
+		})
+	}
+}
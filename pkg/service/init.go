@@ -18,36 +18,63 @@ package service
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
-	"github.com/spf13/viper"
 	v1 "k8s.io/api/core/v1"
-	rbac "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 
+	"sigs.k8s.io/hydrophone/pkg/client"
 	"sigs.k8s.io/hydrophone/pkg/common"
 	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
 )
 
 var (
 	ctx = context.Background()
 )
 
-// Init Initializes the kube config clientset
+// InClusterName is the name of the cluster when hydrophone runs inside of it
+const InClusterName = "in-cluster"
+
+// Init Initializes the kube config clientset. The in-cluster configuration
+// is used unless --context or --cluster select a cluster of the kubeconfig.
 func Init(kubeconfig string) (*rest.Config, *kubernetes.Clientset) {
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	kubeContext, cluster := settings.GetString("context"), settings.GetString("cluster")
+	var config *rest.Config
+	var err error
+	if kubeContext == "" && cluster == "" {
+		config, _ = rest.InClusterConfig()
+	}
+	// the name of the cluster may be part of the output directory
+	clusterName := InClusterName
+	if config == nil {
+		config, err = client.LoadConfig(kubeconfig, kubeContext, cluster)
 		if err != nil {
 			log.Fatalf("kubeconfig can't be loaded: %v\n", err)
 		}
+		if clusterName, err = client.ClusterName(kubeconfig, kubeContext, cluster); err != nil {
+			log.Fatalf("kubeconfig can't be loaded: %v\n", err)
+		}
+	}
+	settings.Set("cluster-name", clusterName)
+	if err := configureClient(config); err != nil {
+		log.Fatal(err)
+	}
+	if kubeContext != "" {
+		log.Printf("Using context : '%s'", kubeContext)
+	}
+	if cluster != "" {
+		log.Printf("Using cluster : '%s'", cluster)
 	}
 
 	clientset, err := kubernetes.NewForConfig(config)
@@ -58,7 +85,86 @@ func Init(kubeconfig string) (*rest.Config, *kubernetes.Clientset) {
 	return config, clientset
 }
 
-// GetKubeConfig returns the path to the Kubernetes configuration file
+// configureClient applies the client flags to the config: the rate limit of
+// --kube-api-qps and --kube-api-burst, the timeout of --request-timeout, the
+// proxy of --proxy-url, which replaces the one of HTTPS_PROXY and NO_PROXY,
+// the TLS settings of configureTLS and the user, groups and UID of --as, --as-group and --as-uid to
+// impersonate.
+func configureClient(config *rest.Config) error {
+	user := settings.GetString("as")
+	if user == "" && (settings.GetString("as-uid") != "" || len(settings.GetStringSlice("as-group")) != 0) {
+		return fmt.Errorf("--as-group and --as-uid require --as")
+	}
+	config.QPS = float32(settings.GetFloat64("kube-api-qps"))
+	config.Burst = settings.GetInt("kube-api-burst")
+	timeout, err := common.GetDuration("request-timeout")
+	if err != nil {
+		return err
+	}
+	config.Timeout = timeout
+	if proxyURL := settings.GetString("proxy-url"); proxyURL != "" {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid --proxy-url: %w", err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("expected --proxy-url to be an http, https or socks5 URL, got %q", proxy.Redacted())
+		}
+		config.Proxy = http.ProxyURL(proxy)
+		log.Printf("Using proxy : '%s'", proxy.Redacted())
+	}
+	if err := configureTLS(config); err != nil {
+		return err
+	}
+	if user != "" {
+		config.Impersonate = rest.ImpersonationConfig{
+			UserName: user,
+			UID:      settings.GetString("as-uid"),
+			Groups:   settings.GetStringSlice("as-group"),
+		}
+		log.Printf("Impersonating user : '%s'", user)
+	}
+	return nil
+}
+
+// configureTLS applies the TLS flags to the config: the CA bundle of
+// --certificate-authority verifying the certificate of the API server, the
+// name of --tls-server-name it is verified against, or
+// --insecure-skip-tls-verify not verifying it at all.
+func configureTLS(config *rest.Config) error {
+	caFile := settings.GetString("certificate-authority")
+	insecure := settings.GetBool("insecure-skip-tls-verify")
+	if caFile != "" && insecure {
+		return fmt.Errorf("--certificate-authority and --insecure-skip-tls-verify can't be combined")
+	}
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("error reading --certificate-authority: %w", err)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(data) {
+			return fmt.Errorf("--certificate-authority %s holds no PEM encoded certificate", caFile)
+		}
+		config.TLSClientConfig.CAFile, config.TLSClientConfig.CAData = "", data
+		log.Printf("Using certificate authority : '%s'", caFile)
+	}
+	if serverName := settings.GetString("tls-server-name"); serverName != "" {
+		config.TLSClientConfig.ServerName = serverName
+		log.Printf("Using TLS server name : '%s'", serverName)
+	}
+	if insecure {
+		// client-go refuses a CA combined with skipping the verification
+		config.TLSClientConfig.Insecure = true
+		config.TLSClientConfig.CAFile, config.TLSClientConfig.CAData = "", nil
+		log.Printf("WARNING: the certificate of the API server isn't verified, don't use --insecure-skip-tls-verify outside of test clusters")
+	}
+	return nil
+}
+
+// GetKubeConfig returns the path to the Kubernetes configuration file, or the
+// list of paths of KUBECONFIG
 func GetKubeConfig(kubeconfig string) string {
 	homeDir := os.Getenv("HOME")
 	if kubeconfig == "" {
@@ -77,227 +183,94 @@ func GetKubeConfig(kubeconfig string) string {
 }
 
 // RunE2E sets up the necessary resources and runs E2E conformance tests.
-func RunE2E(clientset *kubernetes.Clientset) {
-	conformanceNS := v1.Namespace{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: viper.GetString("namespace"),
-		},
-	}
+func RunE2E(clientset kubernetes.Interface) {
+	Setup(clientset)
+	CreatePods(clientset)
+}
 
-	conformanceSA := v1.ServiceAccount{
-		ObjectMeta: metav1.ObjectMeta{
-			Labels: map[string]string{
-				"component": "conformance",
-			},
-			Name:      common.ServiceAccountName,
-			Namespace: conformanceNS.Name,
-		},
+// Setup creates the namespace, the RBAC resources, the pull secret and the
+// config maps used by the conformance pods. With --service-account the
+// namespace and the service account are expected to exist and only the pull
+// secret and the config maps are created.
+func Setup(clientset kubernetes.Interface) {
+	conformanceNS, err := Namespace()
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	conformanceClusterRole := rbac.ClusterRole{
-		ObjectMeta: metav1.ObjectMeta{
-			Labels: map[string]string{
-				"component": "conformance",
-			},
-			Name: common.ClusterRoleName,
-		},
-		Rules: []rbac.PolicyRule{
-			{
-				APIGroups: []string{"*"},
-				Resources: []string{"*"},
-				Verbs:     []string{"*"},
-			},
-			{
-				NonResourceURLs: []string{"/metrics", "/logs", "/logs/*"},
-				Verbs:           []string{"get"},
-			},
-		},
+	ns := conformanceNS
+	if ManagedRBAC() {
+		ns, err = clientset.CoreV1().Namespaces().Create(ctx, conformanceNS, metav1.CreateOptions{})
+		if err != nil {
+			if errors.IsAlreadyExists(err) {
+				log.Fatalf("namespace already exist %s. Please run cleanup first", conformanceNS.ObjectMeta.Name)
+			} else {
+				log.Fatal(err)
+			}
+		}
+		log.Printf("namespace created %s\n", ns.Name)
 	}
 
-	conformanceClusterRoleBinding := rbac.ClusterRoleBinding{
-		ObjectMeta: metav1.ObjectMeta{
-			Labels: map[string]string{
-				"component": "conformance",
-			},
-			Name: common.ClusterRoleBindingName,
-		},
-		RoleRef: rbac.RoleRef{
-			APIGroup: "rbac.authorization.k8s.io",
-			Kind:     "ClusterRole",
-			Name:     "conformance-serviceaccount",
-		},
-		Subjects: []rbac.Subject{
-			{
-				Kind:      "ServiceAccount",
-				Name:      "conformance-serviceaccount",
-				Namespace: conformanceNS.Name,
-			},
-		},
+	if err := SetupRBAC(clientset, ns.Name); err != nil {
+		log.Fatal(err)
 	}
 
-	conformancePod := v1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "e2e-conformance-test",
-			Namespace: conformanceNS.Name,
-		},
-		Spec: v1.PodSpec{
-			Containers: []v1.Container{
-				{
-					Name:            common.ConformanceContainer,
-					Image:           viper.GetString("conformance-image"),
-					ImagePullPolicy: v1.PullIfNotPresent,
-					Env: []v1.EnvVar{
-						{
-							Name:  "E2E_FOCUS",
-							Value: fmt.Sprintf("%s", viper.Get("focus")),
-						},
-						{
-							Name:  "E2E_SKIP",
-							Value: fmt.Sprintf("%s", viper.Get("skip")),
-						},
-						{
-							Name:  "E2E_PROVIDER",
-							Value: "skeleton",
-						},
-						{
-							Name:  "E2E_PARALLEL",
-							Value: fmt.Sprintf("%d", viper.Get("parallel")),
-						},
-						{
-							Name:  "E2E_VERBOSITY",
-							Value: fmt.Sprintf("%d", viper.Get("verbosity")),
-						},
-						{
-							Name:  "E2E_USE_GO_RUNNER",
-							Value: "true",
-						},
-						{
-							Name:  "E2E_EXTRA_ARGS",
-							Value: fmt.Sprintf("%s", strings.Join(viper.GetStringSlice("extra-args"), " ")),
-						},
-					},
-					VolumeMounts: []v1.VolumeMount{
-						{
-							Name:      "output-volume",
-							MountPath: "/tmp/results",
-						},
-					},
-				},
-				{
-					Name:    common.OutputContainer,
-					Image:   viper.GetString("busybox-image"),
-					Command: []string{"/bin/sh", "-c", "sleep infinity"},
-					VolumeMounts: []v1.VolumeMount{
-						{
-							Name:      "output-volume",
-							MountPath: "/tmp/results",
-						},
-					},
-				},
-			},
-			Volumes: []v1.Volume{
-				{
-					Name: "output-volume",
-					VolumeSource: v1.VolumeSource{
-						EmptyDir: &v1.EmptyDirVolumeSource{},
-					},
-				},
-			},
-			RestartPolicy:      v1.RestartPolicyNever,
-			ServiceAccountName: "conformance-serviceaccount",
-			Tolerations: []v1.Toleration{
-				{
-					// An empty key with operator Exists matches all keys,
-					// values and effects which means this will tolerate everything.
-					// As noted in https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration/
-					Operator: "Exists",
-				},
-			},
-		},
-	}
+	if settings.GetString("docker-config") != "" {
+		pullSecret, err := PullSecret(ns.Name)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	if viper.GetBool("dry-run") {
-		conformancePod.Spec.Containers[0].Env = append(conformancePod.Spec.Containers[0].Env, DryRun())
+		secret, err := clientset.CoreV1().Secrets(ns.Name).Create(ctx, pullSecret, metav1.CreateOptions{})
+		if err != nil {
+			if errors.IsAlreadyExists(err) {
+				log.Fatalf("secret already exists %s. Please run cleanup first", pullSecret.ObjectMeta.Name)
+			} else {
+				log.Fatal(err)
+			}
+		}
+		log.Printf("secret created %s\n", secret.Name)
 	}
 
-	ns, err := clientset.CoreV1().Namespaces().Create(ctx, &conformanceNS, metav1.CreateOptions{})
-	if err != nil {
-		if errors.IsAlreadyExists(err) {
-			log.Fatalf("namespace already exist %s. Please run cleanup first", conformanceNS.ObjectMeta.Name)
-		} else {
+	if providerSecretNeeded() {
+		providerSecret, err := ProviderSecret(ns.Name)
+		if err != nil {
 			log.Fatal(err)
 		}
-	}
-	log.Printf("namespace created %s\n", ns.Name)
 
-	sa, err := clientset.CoreV1().ServiceAccounts(ns.Name).Create(ctx, &conformanceSA, metav1.CreateOptions{})
-	if err != nil {
-		if errors.IsAlreadyExists(err) {
-			log.Fatalf("serviceaccount already exist %s. Please run cleanup first", conformanceSA.ObjectMeta.Name)
-		} else {
-			log.Fatal(err)
+		secret, err := clientset.CoreV1().Secrets(ns.Name).Create(ctx, providerSecret, metav1.CreateOptions{})
+		if err != nil {
+			if errors.IsAlreadyExists(err) {
+				log.Fatalf("secret already exists %s. Please run cleanup first", providerSecret.ObjectMeta.Name)
+			} else {
+				log.Fatal(err)
+			}
 		}
+		log.Printf("secret created %s\n", secret.Name)
 	}
-	log.Printf("serviceaccount created %s\n", sa.Name)
 
-	clusterRole, err := clientset.RbacV1().ClusterRoles().Create(ctx, &conformanceClusterRole, metav1.CreateOptions{})
-	if err != nil {
-		if errors.IsAlreadyExists(err) {
-			log.Printf("clusterrole already exist %s", conformanceClusterRole.ObjectMeta.Name)
-		} else {
+	if settings.GetString("test-repo-list") != "" {
+		configMap, err := RepoListConfigMap(ns.Name)
+		if err != nil {
 			log.Fatal(err)
 		}
-	}
-	log.Printf("clusterrole created %s\n", clusterRole.Name)
 
-	clusterRoleBinding, err := clientset.RbacV1().ClusterRoleBindings().Create(ctx, &conformanceClusterRoleBinding, metav1.CreateOptions{})
-	if err != nil {
-		if errors.IsAlreadyExists(err) {
-			log.Printf("clusterrolebinding already exist %s", conformanceClusterRoleBinding.ObjectMeta.Name)
-		} else {
-			log.Fatal(err)
+		cm, err := clientset.CoreV1().ConfigMaps(ns.Name).Create(ctx, configMap, metav1.CreateOptions{})
+		if err != nil {
+			if errors.IsAlreadyExists(err) {
+				log.Fatalf("configmap already exists %s. Please run cleanup first", configMap.ObjectMeta.Name)
+			} else {
+				log.Fatal(err)
+			}
 		}
+		log.Printf("configmap created %s\n", cm.Name)
 	}
-	log.Printf("clusterrolebinding created %s\n", clusterRoleBinding.Name)
 
-	if viper.GetString("test-repo-list") != "" {
-		RepoListData, err := os.ReadFile(viper.GetString("test-repo-list"))
+	if settings.GetString("storage-testdriver") != "" {
+		configMap, err := StorageTestDriverConfigMap(ns.Name)
 		if err != nil {
 			log.Fatal(err)
 		}
-		configMap := &v1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      "repo-list-config",
-				Namespace: ns.Name,
-			},
-			Data: map[string]string{
-				"repo-list.yaml": string(RepoListData),
-			},
-		}
-
-		conformancePod.Spec.Volumes = append(conformancePod.Spec.Volumes,
-			v1.Volume{
-				Name: "repo-list-volume",
-				VolumeSource: v1.VolumeSource{
-					ConfigMap: &v1.ConfigMapVolumeSource{
-						LocalObjectReference: v1.LocalObjectReference{
-							Name: "repo-list-config",
-						},
-					},
-				},
-			})
-
-		conformancePod.Spec.Containers[0].VolumeMounts = append(conformancePod.Spec.Containers[0].VolumeMounts,
-			v1.VolumeMount{
-				Name:      "repo-list-volume",
-				MountPath: "/tmp/repo-list",
-				ReadOnly:  true,
-			})
-
-		conformancePod.Spec.Containers[0].Env = append(conformancePod.Spec.Containers[0].Env, v1.EnvVar{
-			Name:  "KUBE_TEST_REPO_LIST",
-			Value: "/tmp/repo-list/repo-list.yaml",
-		})
 
 		cm, err := clientset.CoreV1().ConfigMaps(ns.Name).Create(ctx, configMap, metav1.CreateOptions{})
 		if err != nil {
@@ -309,69 +282,133 @@ func RunE2E(clientset *kubernetes.Clientset) {
 		}
 		log.Printf("configmap created %s\n", cm.Name)
 	}
+}
 
-	if viper.GetString("test-repo") != "" {
-		conformancePod.Spec.Containers[0].Env = append(conformancePod.Spec.Containers[0].Env, v1.EnvVar{
-			Name:  "KUBE_TEST_REPO",
-			Value: viper.GetString("test-repo"),
-		})
-	}
+// podSecurityLabels let the conformance pod and the pods of the tests run in
+// the namespace on clusters enforcing a restricted Pod Security Standard by
+// default
+var podSecurityLabels = map[string]string{
+	"pod-security.kubernetes.io/enforce": "privileged",
+	"pod-security.kubernetes.io/audit":   "privileged",
+	"pod-security.kubernetes.io/warn":    "privileged",
+}
 
-	pod, err := clientset.CoreV1().Pods(ns.Name).Create(ctx, &conformancePod, metav1.CreateOptions{})
+// Namespace returns the definition of the namespace of the run, with the
+// labels and annotations of --namespace-label and --namespace-annotation. The
+// given labels override the default Pod Security admission labels.
+func Namespace() (*v1.Namespace, error) {
+	labels, err := parseKeyValues("namespace-label", settings.GetStringSlice("namespace-label"))
 	if err != nil {
-		if errors.IsAlreadyExists(err) {
-			log.Fatalf("pod already exist %s. Please run cleanup first", conformancePod.ObjectMeta.Name)
-		} else {
-			log.Fatal(err)
+		return nil, err
+	}
+	for key, value := range podSecurityLabels {
+		if _, ok := labels[key]; !ok {
+			labels[key] = value
 		}
 	}
-	log.Printf("pod created %s\n", pod.Name)
+	labels[common.ManagedByLabel] = common.ManagedBy
+	if id := settings.GetString("run-id"); id != "" {
+		labels[common.RunIDLabel] = id
+	}
+	annotations, err := parseKeyValues("namespace-annotation", settings.GetStringSlice("namespace-annotation"))
+	if err != nil {
+		return nil, err
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	return &v1.Namespace{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        settings.GetString("namespace"),
+			Labels:      labels,
+			Annotations: annotations,
+		},
+	}, nil
+}
+
+// RepoListConfigMap returns the definition of the config map holding the
+// file of --test-repo-list.
+func RepoListConfigMap(namespace string) (*v1.ConfigMap, error) {
+	RepoListData, err := os.ReadFile(settings.GetString("test-repo-list"))
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      common.RepoListConfigMapName,
+			Namespace: namespace,
+			Labels:    common.ConformanceLabels(),
+		},
+		Data: map[string]string{
+			path.Base(common.RepoListPath): string(RepoListData),
+		},
+	}, nil
 }
 
-// Cleanup removes all resources created during E2E tests.
-func Cleanup(clientset *kubernetes.Clientset) {
-	namespace := viper.GetString("namespace")
+// Cleanup removes all resources created during E2E tests. The namespace and
+// service account given with --service-account are kept.
+func Cleanup(clientset kubernetes.Interface) {
+	namespace := settings.GetString("namespace")
 	log.Printf("using namespace: %v", namespace)
 
-	err := clientset.CoreV1().Pods(namespace).Delete(ctx, common.PodName, metav1.DeleteOptions{})
+	// the jobs are deleted first, otherwise they replace the deleted pods
+	jobs, err := clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: common.ConformanceSelector(),
+	})
 	if err != nil {
-		if errors.IsNotFound(err) {
-			log.Printf("pod %s doesn't exist\n", common.PodName)
-		} else {
-			log.Fatal(err)
-		}
+		log.Fatal(err)
 	}
-	log.Printf("pod deleted %s\n", common.PodName)
-
-	err = clientset.RbacV1().ClusterRoleBindings().Delete(ctx, common.ClusterRoleBindingName, metav1.DeleteOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			log.Printf("clusterrolebinding %s doesn't exist\n", common.ClusterRoleBindingName)
-		} else {
+	propagation := metav1.DeletePropagationBackground
+	for _, job := range jobs.Items {
+		err = clientset.BatchV1().Jobs(namespace).Delete(ctx, job.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !errors.IsNotFound(err) {
 			log.Fatal(err)
 		}
+		log.Printf("job deleted %s\n", job.Name)
 	}
-	log.Printf("clusterrolebinding deleted %s\n", common.ClusterRoleBindingName)
 
-	err = clientset.RbacV1().ClusterRoles().Delete(ctx, common.ClusterRoleName, metav1.DeleteOptions{})
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: common.ConformanceSelector(),
+	})
 	if err != nil {
-		if errors.IsNotFound(err) {
-			log.Printf("clusterrole %s doesn't exist\n", common.ClusterRoleName)
-		} else {
-			log.Fatal(err)
+		log.Fatal(err)
+	}
+	if len(pods.Items) == 0 {
+		log.Printf("pod %s doesn't exist\n", common.PodName)
+	}
+	for _, pod := range pods.Items {
+		err = clientset.CoreV1().Pods(namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				log.Printf("pod %s doesn't exist\n", pod.Name)
+			} else {
+				log.Fatal(err)
+			}
 		}
+		log.Printf("pod deleted %s\n", pod.Name)
 	}
-	log.Printf("clusterrole deleted %s\n", common.ClusterRoleName)
 
-	err = clientset.CoreV1().ServiceAccounts(namespace).Delete(ctx, common.ServiceAccountName, metav1.DeleteOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			log.Printf("serviceaccount %s doesn't exist\n", common.ServiceAccountName)
-		} else {
-			log.Fatal(err)
+	if !ManagedRBAC() {
+		// the namespace isn't owned by hydrophone, only remove what the run
+		// added, the pull and provider secrets hold credentials
+		for _, name := range []string{common.RepoListConfigMapName, common.StorageTestDriverConfigMapName, common.HeartbeatConfigMapName} {
+			err = clientset.CoreV1().ConfigMaps(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				log.Fatal(err)
+			}
 		}
+		for _, name := range []string{common.PullSecretName, common.ProviderSecretName} {
+			err = clientset.CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				log.Fatal(err)
+			}
+		}
+		return
 	}
-	log.Printf("serviceaccount deleted %s\n", common.ServiceAccountName)
+
+	CleanupRBAC(clientset, namespace)
 
 	err = clientset.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{})
 	if err != nil {
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// Jobs returns the definitions of the jobs running the conformance pods, one
+// for each shard. Each job is named after the pod it replaces and recreates
+// it when it is lost, e.g. because its node was recycled.
+func Jobs(namespace string) ([]*batchv1.Job, error) {
+	backoffLimit := int32(settings.GetInt("job-backoff-limit"))
+	if backoffLimit < 0 {
+		return nil, fmt.Errorf("expected --job-backoff-limit not to be negative, got %d", backoffLimit)
+	}
+	pods, err := Pods(namespace)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []*batchv1.Job
+	for _, pod := range pods {
+		job := &batchv1.Job{
+			TypeMeta: metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: namespace,
+				Labels:    pod.Labels,
+			},
+			Spec: batchv1.JobSpec{
+				BackoffLimit: &backoffLimit,
+				Template: v1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels:      pod.Labels,
+						Annotations: pod.Annotations,
+					},
+					Spec: pod.Spec,
+				},
+			},
+		}
+		if deadline, _ := common.GetDuration("job-active-deadline"); deadline > 0 {
+			seconds := int64(deadline.Seconds())
+			job.Spec.ActiveDeadlineSeconds = &seconds
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// CreateJobs creates the jobs running the conformance pods, one for each shard.
+func CreateJobs(clientset kubernetes.Interface) {
+	namespace := settings.GetString("namespace")
+	jobs, err := Jobs(namespace)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, shardJob := range jobs {
+		job, err := clientset.BatchV1().Jobs(namespace).Create(ctx, shardJob, metav1.CreateOptions{})
+		if err != nil {
+			if errors.IsAlreadyExists(err) {
+				log.Fatalf("job already exist %s. Please run cleanup first", shardJob.ObjectMeta.Name)
+			} else {
+				log.Fatal(err)
+			}
+		}
+		log.Printf("job created %s\n", job.Name)
+	}
+}
+
+// DeleteJobs deletes the conformance jobs along with their pods and waits
+// until they are gone, so that jobs with the same names can be created again.
+func DeleteJobs(clientset kubernetes.Interface) {
+	namespace := settings.GetString("namespace")
+	for _, jobName := range common.PodNames() {
+		// foreground deletion waits for the pods of the job to be gone
+		propagation := metav1.DeletePropagationForeground
+		err := clientset.BatchV1().Jobs(namespace).Delete(ctx, jobName, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !errors.IsNotFound(err) {
+			log.Fatal(err)
+		}
+		err = wait.PollUntilContextTimeout(ctx, time.Second, 5*time.Minute, true, func(ctx context.Context) (bool, error) {
+			_, err := clientset.BatchV1().Jobs(namespace).Get(ctx, jobName, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		})
+		if err != nil {
+			log.Fatalf("error waiting for job %s to be deleted: %v", jobName, err)
+		}
+		log.Printf("job deleted %s\n", jobName)
+	}
+}
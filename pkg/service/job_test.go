@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestJobs(t *testing.T) {
+	settings.Set("shards", 2)
+	settings.Set("job-backoff-limit", 3)
+	settings.Set("job-active-deadline", 6*time.Hour)
+	defer func() {
+		settings.Set("shards", 1)
+		settings.Set("job-backoff-limit", 0)
+		settings.Set("job-active-deadline", time.Duration(0))
+	}()
+
+	jobs, err := Jobs("conformance")
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	for i, job := range jobs {
+		assert.Equal(t, common.PodNames()[i], job.Name)
+		assert.Equal(t, "conformance", job.Labels["component"])
+		assert.Equal(t, "conformance", job.Spec.Template.Labels["component"])
+		assert.Equal(t, int32(3), *job.Spec.BackoffLimit)
+		assert.Equal(t, int64(6*60*60), *job.Spec.ActiveDeadlineSeconds)
+		assert.Equal(t, v1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+	}
+	// each job runs the tests of its shard
+	assert.NotEqual(t, jobs[0].Spec.Template.Spec.Containers[0].Env, jobs[1].Spec.Template.Spec.Containers[0].Env)
+
+	settings.Set("job-active-deadline", time.Duration(0))
+	jobs, err = Jobs("conformance")
+	require.NoError(t, err)
+	assert.Nil(t, jobs[0].Spec.ActiveDeadlineSeconds)
+
+	settings.Set("job-backoff-limit", -1)
+	_, err = Jobs("conformance")
+	assert.Error(t, err)
+}
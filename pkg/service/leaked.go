@@ -0,0 +1,341 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+)
+
+// testNamespacePattern matches the names the e2e framework gives the
+// namespaces of the tests, the base name of the test and a random number
+var testNamespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?-[0-9]{1,5}$`)
+
+// LeakedResources are the resources created by the e2e tests that aborted
+// runs left behind. Namespaced resources are given as namespace/name.
+type LeakedResources struct {
+	Namespaces                      []string
+	PersistentVolumeClaims          []string
+	PersistentVolumes               []string
+	ClusterRoleBindings             []string
+	ClusterRoles                    []string
+	ValidatingWebhookConfigurations []string
+	MutatingWebhookConfigurations   []string
+}
+
+// leakedKind is a kind of leaked resources and their names
+type leakedKind struct {
+	name  string
+	names []string
+}
+
+// kinds returns the leaked resources by kind
+func (r *LeakedResources) kinds() []leakedKind {
+	return []leakedKind{
+		{"namespace", r.Namespaces},
+		{"persistentvolumeclaim", r.PersistentVolumeClaims},
+		{"persistentvolume", r.PersistentVolumes},
+		{"clusterrolebinding", r.ClusterRoleBindings},
+		{"clusterrole", r.ClusterRoles},
+		{"validatingwebhookconfiguration", r.ValidatingWebhookConfigurations},
+		{"mutatingwebhookconfiguration", r.MutatingWebhookConfigurations},
+	}
+}
+
+// Empty reports whether no leaked resource was found
+func (r *LeakedResources) Empty() bool {
+	for _, kind := range r.kinds() {
+		if len(kind.names) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ByKind returns the names of the leaked resources by kind, e.g.
+// persistentvolume, for the report of the run
+func (r *LeakedResources) ByKind() map[string][]string {
+	byKind := map[string][]string{}
+	for _, kind := range r.kinds() {
+		if len(kind.names) != 0 {
+			byKind[kind.name] = kind.names
+		}
+	}
+	return byKind
+}
+
+// Print logs the leaked resources
+func (r *LeakedResources) Print() {
+	for _, kind := range r.kinds() {
+		for _, name := range kind.names {
+			log.Printf("leaked %s %s", kind.name, name)
+		}
+	}
+}
+
+// FindLeakedResources finds the resources left behind by the e2e tests: the
+// test namespaces and their persistent volume claims, the persistent volumes
+// claimed from a test namespace, the cluster roles and bindings of test
+// namespaces, and the admission webhooks calling a service of a test
+// namespace. A namespace counts as a test namespace when it is labelled by
+// the e2e framework, or when it is gone and was named by the framework.
+func FindLeakedResources(clientset kubernetes.Interface) (*LeakedResources, error) {
+	leaked := &LeakedResources{}
+	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: common.E2ERunLabel})
+	if err != nil {
+		return nil, err
+	}
+	testNamespaces := map[string]bool{}
+	for _, ns := range namespaces.Items {
+		testNamespaces[ns.Name] = true
+		leaked.Namespaces = append(leaked.Namespaces, ns.Name)
+	}
+
+	// gone caches whether the namespaces referenced by cluster-scoped
+	// resources still exist
+	gone := map[string]bool{}
+	isTestNamespace := func(name string) (bool, error) {
+		if testNamespaces[name] {
+			return true, nil
+		}
+		if !testNamespacePattern.MatchString(name) {
+			return false, nil
+		}
+		if _, ok := gone[name]; !ok {
+			_, err := clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return false, err
+			}
+			gone[name] = errors.IsNotFound(err)
+		}
+		return gone[name], nil
+	}
+
+	for _, ns := range leaked.Namespaces {
+		claims, err := clientset.CoreV1().PersistentVolumeClaims(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, claim := range claims.Items {
+			leaked.PersistentVolumeClaims = append(leaked.PersistentVolumeClaims, ns+"/"+claim.Name)
+		}
+	}
+
+	volumes, err := clientset.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, volume := range volumes.Items {
+		claim := volume.Spec.ClaimRef
+		if claim == nil {
+			continue
+		}
+		// volumes still bound to a claim of a gone namespace are being released
+		if !testNamespaces[claim.Namespace] && volume.Status.Phase != v1.VolumeReleased && volume.Status.Phase != v1.VolumeFailed {
+			continue
+		}
+		test, err := isTestNamespace(claim.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		if test {
+			leaked.PersistentVolumes = append(leaked.PersistentVolumes, volume.Name)
+		}
+	}
+
+	bindings, err := clientset.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, binding := range bindings.Items {
+		for _, subject := range binding.Subjects {
+			if subject.Namespace == "" {
+				continue
+			}
+			test, err := isTestNamespace(subject.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			if test {
+				leaked.ClusterRoleBindings = append(leaked.ClusterRoleBindings, binding.Name)
+				break
+			}
+		}
+	}
+
+	roles, err := clientset.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// cluster roles are named after the namespace of the test creating them
+	var names []string
+	for ns := range testNamespaces {
+		names = append(names, ns)
+	}
+	for ns, test := range gone {
+		if test {
+			names = append(names, ns)
+		}
+	}
+	for _, role := range roles.Items {
+		for _, ns := range names {
+			if strings.Contains(role.Name, ns) {
+				leaked.ClusterRoles = append(leaked.ClusterRoles, role.Name)
+				break
+			}
+		}
+	}
+
+	// the webhooks of the tests call a service of their namespace
+	webhookNamespace := func(config *admissionregistrationv1.WebhookClientConfig) (bool, error) {
+		if config.Service == nil {
+			return false, nil
+		}
+		return isTestNamespace(config.Service.Namespace)
+	}
+	validating, err := clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, configuration := range validating.Items {
+		for _, webhook := range configuration.Webhooks {
+			test, err := webhookNamespace(&webhook.ClientConfig)
+			if err != nil {
+				return nil, err
+			}
+			if test {
+				leaked.ValidatingWebhookConfigurations = append(leaked.ValidatingWebhookConfigurations, configuration.Name)
+				break
+			}
+		}
+	}
+	mutating, err := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, configuration := range mutating.Items {
+		for _, webhook := range configuration.Webhooks {
+			test, err := webhookNamespace(&webhook.ClientConfig)
+			if err != nil {
+				return nil, err
+			}
+			if test {
+				leaked.MutatingWebhookConfigurations = append(leaked.MutatingWebhookConfigurations, configuration.Name)
+				break
+			}
+		}
+	}
+
+	for _, kind := range leaked.kinds() {
+		sort.Strings(kind.names)
+	}
+	return leaked, nil
+}
+
+// DeleteLeakedResources deletes the leaked resources. The persistent volume
+// claims are deleted before the namespaces, up to concurrency namespaces at
+// the same time, and the cluster-scoped resources after them.
+func DeleteLeakedResources(clientset kubernetes.Interface, leaked *LeakedResources, concurrency int) error {
+	var failed []string
+	for _, claim := range leaked.PersistentVolumeClaims {
+		ns, name, _ := strings.Cut(claim, "/")
+		err := clientset.CoreV1().PersistentVolumeClaims(ns).Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			failed = append(failed, fmt.Sprintf("persistentvolumeclaim %s: %v", claim, err))
+		}
+	}
+	if len(leaked.Namespaces) != 0 {
+		log.Printf("deleting %d test namespaces, %d at a time", len(leaked.Namespaces), concurrency)
+		if err := deleteAll(leaked.Namespaces, concurrency, progressInterval, func(name string) error {
+			return deleteNamespace(clientset, name)
+		}); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	for _, kind := range []struct {
+		name  string
+		names []string
+		del   func(name string) error
+	}{
+		{"persistentvolume", leaked.PersistentVolumes, func(name string) error {
+			return clientset.CoreV1().PersistentVolumes().Delete(ctx, name, metav1.DeleteOptions{})
+		}},
+		{"clusterrolebinding", leaked.ClusterRoleBindings, func(name string) error {
+			return clientset.RbacV1().ClusterRoleBindings().Delete(ctx, name, metav1.DeleteOptions{})
+		}},
+		{"clusterrole", leaked.ClusterRoles, func(name string) error {
+			return clientset.RbacV1().ClusterRoles().Delete(ctx, name, metav1.DeleteOptions{})
+		}},
+		{"validatingwebhookconfiguration", leaked.ValidatingWebhookConfigurations, func(name string) error {
+			return clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations().Delete(ctx, name, metav1.DeleteOptions{})
+		}},
+		{"mutatingwebhookconfiguration", leaked.MutatingWebhookConfigurations, func(name string) error {
+			return clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Delete(ctx, name, metav1.DeleteOptions{})
+		}},
+	} {
+		for _, name := range kind.names {
+			if err := kind.del(name); err != nil && !errors.IsNotFound(err) {
+				failed = append(failed, fmt.Sprintf("%s %s: %v", kind.name, name, err))
+				continue
+			}
+			log.Printf("%s deleted %s", kind.name, name)
+		}
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("unable to delete %d leaked resources:\n%s", len(failed), strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// WaitForTestNamespaces waits up to timeout for the test namespaces being
+// deleted to be gone, so that the namespaces the tests are still cleaning up
+// aren't taken for leaked ones. It returns the number of namespaces still
+// being deleted when the timeout expires.
+func WaitForTestNamespaces(clientset kubernetes.Interface, timeout time.Duration) (int, error) {
+	terminating := 0
+	err := wait.PollUntilContextTimeout(ctx, time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: common.E2ERunLabel})
+		if err != nil {
+			return false, err
+		}
+		terminating = 0
+		for _, ns := range namespaces.Items {
+			if ns.DeletionTimestamp != nil {
+				terminating++
+			}
+		}
+		return terminating == 0, nil
+	})
+	if wait.Interrupted(err) {
+		return terminating, nil
+	}
+	return terminating, err
+}
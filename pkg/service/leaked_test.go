@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+)
+
+func TestLeakedResources(t *testing.T) {
+	namespace := func(name string, labels map[string]string) *v1.Namespace {
+		return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	volume := func(name, claimNamespace string, phase v1.PersistentVolumePhase) *v1.PersistentVolume {
+		return &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1.PersistentVolumeSpec{ClaimRef: &v1.ObjectReference{Namespace: claimNamespace, Name: "data"}},
+			Status:     v1.PersistentVolumeStatus{Phase: phase},
+		}
+	}
+	binding := func(name, subjectNamespace string) *rbacv1.ClusterRoleBinding {
+		return &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "default", Namespace: subjectNamespace}},
+		}
+	}
+	role := func(name string) *rbacv1.ClusterRole {
+		return &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	webhook := func(serviceNamespace string) []admissionregistrationv1.ValidatingWebhook {
+		return []admissionregistrationv1.ValidatingWebhook{{
+			Name:         "deny.example.com",
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{Service: &admissionregistrationv1.ServiceReference{Namespace: serviceNamespace, Name: "e2e-test-webhook"}},
+		}}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		namespace("pods-1234", map[string]string{common.E2ERunLabel: "0b2c"}),
+		namespace("prod-42", nil),
+		&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "pods-1234"}},
+		volume("pv-bound", "pods-1234", v1.VolumeBound),
+		volume("pv-released", "volume-9876", v1.VolumeReleased),
+		volume("pv-prod", "prod-42", v1.VolumeReleased),
+		volume("pv-retained", "database", v1.VolumeReleased),
+		binding("pods-1234-binding", "pods-1234"),
+		binding("volume-9876--e2e-test-privileged", "volume-9876"),
+		binding("cluster-admin", "kube-system"),
+		role("pods-1234-role"),
+		role("e2e-volume-9876"),
+		role("cluster-admin"),
+		&admissionregistrationv1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: "deny-webhook-pods-1234"}, Webhooks: webhook("pods-1234")},
+		&admissionregistrationv1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: "gatekeeper"}, Webhooks: webhook("gatekeeper-system")},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "mutating-webhook-9876"},
+			Webhooks: []admissionregistrationv1.MutatingWebhook{{
+				Name:         "mutate.example.com",
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{Service: &admissionregistrationv1.ServiceReference{Namespace: "webhook-9876", Name: "e2e-test-webhook"}},
+			}},
+		},
+	)
+
+	leaked, err := FindLeakedResources(clientset)
+	require.NoError(t, err)
+	assert.Equal(t, &LeakedResources{
+		Namespaces:                      []string{"pods-1234"},
+		PersistentVolumeClaims:          []string{"pods-1234/data"},
+		PersistentVolumes:               []string{"pv-bound", "pv-released"},
+		ClusterRoleBindings:             []string{"pods-1234-binding", "volume-9876--e2e-test-privileged"},
+		ClusterRoles:                    []string{"e2e-volume-9876", "pods-1234-role"},
+		ValidatingWebhookConfigurations: []string{"deny-webhook-pods-1234"},
+		MutatingWebhookConfigurations:   []string{"mutating-webhook-9876"},
+	}, leaked)
+	assert.Equal(t, []string{"pods-1234/data"}, leaked.ByKind()["persistentvolumeclaim"])
+
+	require.NoError(t, DeleteLeakedResources(clientset, leaked, 2))
+	leaked, err = FindLeakedResources(clientset)
+	require.NoError(t, err)
+	assert.True(t, leaked.Empty())
+	_, err = clientset.CoreV1().PersistentVolumes().Get(ctx, "pv-prod", metav1.GetOptions{})
+	assert.NoError(t, err)
+	_, err = clientset.RbacV1().ClusterRoles().Get(ctx, "cluster-admin", metav1.GetOptions{})
+	assert.NoError(t, err)
+	_, err = clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "gatekeeper", metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+func TestWaitForTestNamespaces(t *testing.T) {
+	deleted := metav1.Now()
+	clientset := fake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "pods-1234", Labels: map[string]string{common.E2ERunLabel: "0b2c"}, DeletionTimestamp: &deleted}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "pods-5678", Labels: map[string]string{common.E2ERunLabel: "0b2c"}}},
+	)
+	terminating, err := WaitForTestNamespaces(clientset, 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 1, terminating)
+
+	require.NoError(t, clientset.CoreV1().Namespaces().Delete(ctx, "pods-1234", metav1.DeleteOptions{}))
+	terminating, err = WaitForTestNamespaces(clientset, time.Second)
+	require.NoError(t, err)
+	assert.Zero(t, terminating)
+}
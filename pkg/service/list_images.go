@@ -21,13 +21,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
-	"github.com/spf13/viper"
 	"sigs.k8s.io/hydrophone/pkg/common"
 	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -36,7 +37,19 @@ import (
 
 // PrintListImages creates and runs a conformance image with the --list-images flag
 // This will print a list of all the images used by the conformance image.
-func PrintListImages(clientSet *kubernetes.Clientset) {
+func PrintListImages(clientSet kubernetes.Interface) {
+	images, err := ListImages(clientSet)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, image := range images {
+		fmt.Println(image)
+	}
+}
+
+// ListImages runs the conformance image with the --list-images flag in a
+// short-lived pod and returns the sorted images used by the tests.
+func ListImages(clientSet kubernetes.Interface) ([]string, error) {
 	// Create a pod object definition
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -47,15 +60,18 @@ func PrintListImages(clientSet *kubernetes.Clientset) {
 			},
 		},
 		Spec: corev1.PodSpec{
-			RestartPolicy: corev1.RestartPolicyOnFailure,
+			RestartPolicy:   corev1.RestartPolicyOnFailure,
+			NodeSelector:    nodeSelector(),
+			SecurityContext: restrictedPodSecurityContext(),
 			Containers: []corev1.Container{
 				{
 					Name:  common.ConformanceContainer,
-					Image: viper.GetString("conformance-image"),
+					Image: settings.GetString("conformance-image"),
 					Command: []string{
 						"/usr/local/bin/e2e.test",
 						"--list-images",
 					},
+					SecurityContext: restrictedSecurityContext(nobodyUser, nil),
 				},
 			},
 		},
@@ -64,8 +80,13 @@ func PrintListImages(clientSet *kubernetes.Clientset) {
 	// Create the pod in the cluster
 	pod, err := clientSet.CoreV1().Pods("default").Create(context.TODO(), pod, metav1.CreateOptions{})
 	if err != nil {
-		panic(fmt.Errorf("failed to create pod: %w", err))
+		return nil, fmt.Errorf("failed to create pod: %w", err)
 	}
+	defer func() {
+		if err := clientSet.CoreV1().Pods("default").Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil {
+			log.Printf("unable to delete pod %s: %v", pod.Name, err)
+		}
+	}()
 
 	log.Printf("Pod created successfully")
 
@@ -74,7 +95,7 @@ func PrintListImages(clientSet *kubernetes.Clientset) {
 		FieldSelector: "metadata.name=" + pod.Name,
 	})
 	if err != nil {
-		log.Fatal("failed to watch pod events: %w", err)
+		return nil, fmt.Errorf("failed to watch pod events: %w", err)
 	}
 	defer watcher.Stop()
 
@@ -84,7 +105,7 @@ func PrintListImages(clientSet *kubernetes.Clientset) {
 		select {
 		case event, ok := <-watcher.ResultChan():
 			if !ok {
-				return
+				return nil, fmt.Errorf("watch of pod %s closed before it completed", pod.Name)
 			}
 
 			// Handle pod event
@@ -95,35 +116,21 @@ func PrintListImages(clientSet *kubernetes.Clientset) {
 
 			// Check if the pod is in a terminal state
 			if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
-				// Trigger desired action (e.g., fetching and printing logs)
 				log.Printf("Pod completed: %s", pod.Status.Phase)
 
 				// Fetch the logs
 				req := clientSet.CoreV1().Pods("default").GetLogs(pod.Name, &corev1.PodLogOptions{})
 				podLogs, err := req.Stream(context.TODO())
 				if err != nil {
-					log.Fatal("failed to fetch pod logs: %w", err)
+					return nil, fmt.Errorf("failed to fetch pod logs: %w", err)
 				}
 				defer podLogs.Close()
 
-				// Read and print the logs
 				buf := new(bytes.Buffer)
-				_, err = io.Copy(buf, podLogs)
-				if err != nil {
-					log.Fatal("failed to read pod logs: %w", err)
+				if _, err = io.Copy(buf, podLogs); err != nil {
+					return nil, fmt.Errorf("failed to read pod logs: %w", err)
 				}
-
-				lines := strings.Split(buf.String(), "\n")
-				sort.Strings(lines)
-				for _, line := range lines {
-					fmt.Println(line)
-				}
-
-				err = clientSet.CoreV1().Pods("default").Delete(ctx, pod.Name, metav1.DeleteOptions{})
-				if err != nil {
-					log.Fatal("unable to delet pod : %w", err)
-				}
-				return
+				return parseImages(buf.String()), nil
 			}
 
 		case <-time.After(2 * time.Second):
@@ -131,3 +138,27 @@ func PrintListImages(clientSet *kubernetes.Clientset) {
 		}
 	}
 }
+
+// parseImages returns the sorted unique images listed one per line
+func parseImages(output string) []string {
+	var images []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			images = append(images, line)
+		}
+	}
+	sort.Strings(images)
+	return slices.Compact(images)
+}
+
+// MirrorImage returns the reference of the image in the given registry. The
+// registry of the image is replaced and its repository path is kept, e.g.
+// registry.k8s.io/e2e-test-images/agnhost:2.47 becomes
+// mirror.example.com/e2e-test-images/agnhost:2.47.
+func MirrorImage(image, registry string) string {
+	path := image
+	if first, rest, ok := strings.Cut(image, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		path = rest
+	}
+	return strings.TrimSuffix(registry, "/") + "/" + path
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImages(t *testing.T) {
+	output := "registry.k8s.io/e2e-test-images/nginx:1.14-4\n\nregistry.k8s.io/e2e-test-images/agnhost:2.47\nregistry.k8s.io/e2e-test-images/nginx:1.14-4\n"
+	assert.Equal(t, []string{
+		"registry.k8s.io/e2e-test-images/agnhost:2.47",
+		"registry.k8s.io/e2e-test-images/nginx:1.14-4",
+	}, parseImages(output))
+}
+
+func TestMirrorImage(t *testing.T) {
+	tests := []struct {
+		image    string
+		registry string
+		want     string
+	}{
+		{"registry.k8s.io/e2e-test-images/agnhost:2.47", "mirror.example.com", "mirror.example.com/e2e-test-images/agnhost:2.47"},
+		{"localhost:5000/busybox:1.36", "mirror.example.com/k8s/", "mirror.example.com/k8s/busybox:1.36"},
+		{"localhost/pause:3.9", "mirror.example.com", "mirror.example.com/pause:3.9"},
+		{"library/busybox:1.36", "mirror.example.com", "mirror.example.com/library/busybox:1.36"},
+		{"busybox", "mirror.example.com", "mirror.example.com/busybox"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MirrorImage(tt.image, tt.registry), tt.image)
+	}
+}
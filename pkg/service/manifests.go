@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"io"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// Manifests returns the resources created for a run, in the order they are
+// created: the namespace, the service account, the RBAC resources, the pull
+// secret of --docker-config, the provider secret, the config maps of the
+// test repo list and the storage test driver and the conformance pods, or
+// the jobs running them with --workload=job, or the pods running the node
+// conformance tests of --node.
+func Manifests() ([]runtime.Object, error) {
+	namespace := settings.GetString("namespace")
+	var objects []runtime.Object
+	// with --service-account the namespace and its service account exist
+	if ManagedRBAC() {
+		ns, err := Namespace()
+		if err != nil {
+			return nil, err
+		}
+		clusterRole, err := ClusterRole()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects,
+			ns,
+			ServiceAccount(namespace),
+			clusterRole,
+			ClusterRoleBinding(namespace),
+		)
+	}
+	if settings.GetString("docker-config") != "" {
+		secret, err := PullSecret(namespace)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, secret)
+	}
+	if providerSecretNeeded() {
+		secret, err := ProviderSecret(namespace)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, secret)
+	}
+	if settings.GetString("test-repo-list") != "" {
+		configMap, err := RepoListConfigMap(namespace)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, configMap)
+	}
+	if settings.GetString("storage-testdriver") != "" {
+		configMap, err := StorageTestDriverConfigMap(namespace)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, configMap)
+	}
+	if nodes := settings.GetStringSlice("node"); len(nodes) != 0 {
+		for _, node := range nodes {
+			objects = append(objects, NodeConformancePod(namespace, node))
+		}
+		return objects, nil
+	}
+	if settings.GetString("workload") == common.WorkloadJob {
+		jobs, err := Jobs(namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, job := range jobs {
+			objects = append(objects, job)
+		}
+		return objects, nil
+	}
+	pods, err := Pods(namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		objects = append(objects, pod)
+	}
+	return objects, nil
+}
+
+// WriteManifests writes the objects as a multi-document YAML stream.
+func WriteManifests(w io.Writer, objects []runtime.Object) error {
+	for _, object := range objects {
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "---\n"); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
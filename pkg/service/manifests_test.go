@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestManifests(t *testing.T) {
+	settings.Set("namespace", "conformance")
+	settings.Set("shards", 2)
+	defer settings.Set("namespace", "")
+	defer settings.Set("shards", 1)
+
+	objects, err := Manifests()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteManifests(&buf, objects))
+
+	var kinds []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if kind, ok := strings.CutPrefix(line, "kind: "); ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	assert.Equal(t, []string{"Namespace", "ServiceAccount", "ClusterRole", "ClusterRoleBinding", "Pod", "Pod"}, kinds)
+	assert.Equal(t, 6, strings.Count(buf.String(), "---\n"))
+	assert.Contains(t, buf.String(), "name: e2e-conformance-test-1\n  namespace: conformance\n")
+}
+
+func TestManifestsServiceAccount(t *testing.T) {
+	settings.Set("namespace", "conformance")
+	settings.Set("service-account", "e2e")
+	defer settings.Set("namespace", "")
+	defer settings.Set("service-account", "")
+
+	objects, err := Manifests()
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+
+	pod, ok := objects[0].(*v1.Pod)
+	require.True(t, ok)
+	assert.Equal(t, "e2e", pod.Spec.ServiceAccountName)
+}
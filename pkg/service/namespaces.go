@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+const (
+	// namespaceDeleteTimeout bounds the wait for a single namespace to be gone
+	namespaceDeleteTimeout = 10 * time.Minute
+	// progressInterval is the interval of the progress reports of the cleanup
+	progressInterval = 5 * time.Second
+)
+
+// CleanupTestNamespaces deletes the namespaces created by the e2e framework,
+// which are left behind when a run is aborted. Up to concurrency namespaces
+// are deleted at the same time and the progress is reported until they are
+// all gone. With --run-id they are kept.
+func CleanupTestNamespaces(clientset kubernetes.Interface, concurrency int) error {
+	if settings.GetString("run-id") != "" {
+		// the namespaces of the e2e framework don't carry the ID of the run
+		log.Printf("keeping the test namespaces with --run-id, they may belong to concurrent runs")
+		return nil
+	}
+	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: common.E2ERunLabel})
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, ns := range namespaces.Items {
+		names = append(names, ns.Name)
+	}
+	if len(names) == 0 {
+		log.Printf("no test namespaces to delete")
+		return nil
+	}
+	log.Printf("deleting %d test namespaces, %d at a time", len(names), concurrency)
+
+	return deleteAll(names, concurrency, progressInterval, func(name string) error {
+		return deleteNamespace(clientset, name)
+	})
+}
+
+// deleteNamespace deletes the namespace and waits until it is gone
+func deleteNamespace(clientset kubernetes.Interface, name string) error {
+	err := clientset.CoreV1().Namespaces().Delete(ctx, name, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return wait.PollUntilContextTimeout(ctx, time.Second, namespaceDeleteTimeout, true, func(ctx context.Context) (bool, error) {
+		_, err := clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+// deleteAll calls del for every name with at most workers calls at a time,
+// logging the progress every interval, and returns an error listing the
+// names that couldn't be deleted.
+func deleteAll(names []string, workers int, interval time.Duration, del func(name string) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	var done atomic.Int64
+	stop := make(chan struct{})
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				log.Printf("deleted %d/%d test namespaces", done.Load(), len(names))
+			}
+		}
+	}()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []string
+	)
+	work := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range work {
+				if err := del(name); err != nil {
+					mu.Lock()
+					failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+					mu.Unlock()
+					continue
+				}
+				done.Add(1)
+			}
+		}()
+	}
+	for _, name := range names {
+		work <- name
+	}
+	close(work)
+	wg.Wait()
+	close(stop)
+	<-reported
+
+	log.Printf("deleted %d/%d test namespaces", done.Load(), len(names))
+	if len(failed) != 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("unable to delete %d test namespaces:\n%s", len(failed), strings.Join(failed, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteAll(t *testing.T) {
+	var names []string
+	for i := 0; i < 20; i++ {
+		names = append(names, fmt.Sprintf("e2e-%d", i))
+	}
+
+	var (
+		running, peak atomic.Int64
+		mu            sync.Mutex
+		deleted       []string
+	)
+	err := deleteAll(names, 4, time.Millisecond, func(name string) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		if name == "e2e-3" || name == "e2e-11" {
+			return errors.New("forbidden")
+		}
+		mu.Lock()
+		deleted = append(deleted, name)
+		mu.Unlock()
+		return nil
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, "unable to delete 2 test namespaces:\ne2e-11: forbidden\ne2e-3: forbidden", err.Error())
+	assert.Len(t, deleted, 18)
+	assert.LessOrEqual(t, peak.Load(), int64(4))
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// applyNetwork applies --host-network, --dns-policy, --dns-nameserver,
+// --dns-search and --dns-option to the pod. On the host network the pod
+// resolves names through the cluster DNS unless another policy is given.
+func applyNetwork(pod *v1.Pod) error {
+	pod.Spec.HostNetwork = settings.GetBool("host-network")
+	if pod.Spec.HostNetwork {
+		pod.Spec.DNSPolicy = v1.DNSClusterFirstWithHostNet
+	}
+
+	if policy := settings.GetString("dns-policy"); policy != "" {
+		switch v1.DNSPolicy(policy) {
+		case v1.DNSClusterFirst, v1.DNSClusterFirstWithHostNet, v1.DNSDefault, v1.DNSNone:
+			pod.Spec.DNSPolicy = v1.DNSPolicy(policy)
+		default:
+			return fmt.Errorf("invalid --dns-policy %q, expected %s, %s, %s or %s", policy,
+				v1.DNSClusterFirst, v1.DNSClusterFirstWithHostNet, v1.DNSDefault, v1.DNSNone)
+		}
+	}
+
+	nameservers := settings.GetStringSlice("dns-nameserver")
+	searches := settings.GetStringSlice("dns-search")
+	options := settings.GetStringSlice("dns-option")
+	for _, nameserver := range nameservers {
+		if net.ParseIP(nameserver) == nil {
+			return fmt.Errorf("expected --dns-nameserver to be an IPv4 or IPv6 address, got %q", nameserver)
+		}
+	}
+	if len(nameservers) != 0 || len(searches) != 0 || len(options) != 0 {
+		config := &v1.PodDNSConfig{Nameservers: nameservers, Searches: searches}
+		for _, option := range options {
+			config.Options = append(config.Options, parseDNSOption(option))
+		}
+		pod.Spec.DNSConfig = config
+	}
+
+	if pod.Spec.DNSPolicy == v1.DNSNone && len(nameservers) == 0 {
+		return fmt.Errorf("--dns-policy=%s requires at least one --dns-nameserver", v1.DNSNone)
+	}
+	return nil
+}
+
+// parseDNSOption parses a resolver option in the name[:value] format of
+// resolv.conf, e.g. ndots:2
+func parseDNSOption(value string) v1.PodDNSConfigOption {
+	name, optionValue, ok := strings.Cut(value, ":")
+	option := v1.PodDNSConfigOption{Name: name}
+	if ok {
+		option.Value = &optionValue
+	}
+	return option
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestApplyNetwork(t *testing.T) {
+	defer func() {
+		settings.Set("host-network", false)
+		settings.Set("dns-policy", "")
+		settings.Set("dns-nameserver", []string{})
+		settings.Set("dns-search", []string{})
+		settings.Set("dns-option", []string{})
+	}()
+
+	pod := ConformancePod("conformance")
+	require.NoError(t, applyNetwork(pod))
+	assert.False(t, pod.Spec.HostNetwork)
+	assert.Empty(t, pod.Spec.DNSPolicy)
+	assert.Nil(t, pod.Spec.DNSConfig)
+
+	settings.Set("host-network", true)
+	pod = ConformancePod("conformance")
+	require.NoError(t, applyNetwork(pod))
+	assert.True(t, pod.Spec.HostNetwork)
+	assert.Equal(t, v1.DNSClusterFirstWithHostNet, pod.Spec.DNSPolicy)
+
+	settings.Set("dns-policy", "None")
+	settings.Set("dns-nameserver", []string{"10.0.0.10"})
+	settings.Set("dns-search", []string{"corp.example.com"})
+	settings.Set("dns-option", []string{"ndots:2", "edns0"})
+	pod = ConformancePod("conformance")
+	require.NoError(t, applyNetwork(pod))
+	assert.Equal(t, v1.DNSNone, pod.Spec.DNSPolicy)
+	ndots := "2"
+	assert.Equal(t, &v1.PodDNSConfig{
+		Nameservers: []string{"10.0.0.10"},
+		Searches:    []string{"corp.example.com"},
+		Options:     []v1.PodDNSConfigOption{{Name: "ndots", Value: &ndots}, {Name: "edns0"}},
+	}, pod.Spec.DNSConfig)
+
+	settings.Set("dns-nameserver", []string{"fd00::10"})
+	pod = ConformancePod("conformance")
+	require.NoError(t, applyNetwork(pod))
+	assert.Equal(t, []string{"fd00::10"}, pod.Spec.DNSConfig.Nameservers)
+
+	settings.Set("dns-nameserver", []string{"dns.corp.example.com"})
+	assert.Error(t, applyNetwork(ConformancePod("conformance")))
+
+	settings.Set("dns-nameserver", []string{})
+	assert.Error(t, applyNetwork(ConformancePod("conformance")))
+
+	settings.Set("dns-policy", "ClusterLast")
+	assert.Error(t, applyNetwork(ConformancePod("conformance")))
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// NodeConformanceFocus selects the node conformance tests
+const NodeConformanceFocus = `\[NodeConformance\]`
+
+// nodeResultsDir is the directory the node test image writes its results to
+const nodeResultsDir = "/var/result"
+
+// NodeConformancePod returns the definition of the pod running the node
+// conformance tests of --node-test-image on the node. The node e2e tests
+// exercise the kubelet of the node they run on, the pod is privileged, uses
+// the network of the host and mounts its root filesystem at /rootfs.
+func NodeConformancePod(namespace, node string) *v1.Pod {
+	privileged := true
+	focus := settings.GetString("focus")
+	if focus == "" {
+		focus = NodeConformanceFocus
+	}
+	return &v1.Pod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    common.ConformanceLabels(),
+			Name:      common.PodName,
+			Namespace: namespace,
+		},
+		Spec: v1.PodSpec{
+			NodeName:    node,
+			HostNetwork: true,
+			Containers: []v1.Container{
+				{
+					Name:            common.ConformanceContainer,
+					Image:           settings.GetString("node-test-image"),
+					ImagePullPolicy: v1.PullIfNotPresent,
+					Env: []v1.EnvVar{
+						{Name: "FOCUS", Value: focus},
+						{Name: "SKIP", Value: settings.GetString("skip")},
+						{Name: "PARALLELISM", Value: settings.GetString("parallel")},
+					},
+					SecurityContext: &v1.SecurityContext{Privileged: &privileged},
+					VolumeMounts: []v1.VolumeMount{
+						{Name: "output-volume", MountPath: nodeResultsDir},
+						{Name: "rootfs", MountPath: "/rootfs"},
+					},
+				},
+				{
+					Name:    common.OutputContainer,
+					Image:   settings.GetString("busybox-image"),
+					Command: []string{"/bin/sh", "-c", "sleep infinity"},
+					VolumeMounts: []v1.VolumeMount{
+						{Name: "output-volume", MountPath: "/tmp/results"},
+					},
+				},
+			},
+			Volumes: []v1.Volume{
+				{
+					Name:         "output-volume",
+					VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+				},
+				{
+					Name:         "rootfs",
+					VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/"}},
+				},
+			},
+			RestartPolicy:      v1.RestartPolicyNever,
+			ServiceAccountName: ServiceAccountName(),
+			ImagePullSecrets:   imagePullSecrets(),
+			// the pod is bound to the node, tolerate its taints
+			Tolerations: []v1.Toleration{{Operator: v1.TolerationOpExists}},
+		},
+	}
+}
+
+// CheckNodes fails when one of the nodes doesn't exist
+func CheckNodes(clientset kubernetes.Interface, nodes []string) error {
+	for _, node := range nodes {
+		if _, err := clientset.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{}); err != nil {
+			return fmt.Errorf("node %s of --node: %w", node, err)
+		}
+	}
+	return nil
+}
+
+// CreateNodeConformancePod creates the pod running the node conformance
+// tests on the node.
+func CreateNodeConformancePod(clientset kubernetes.Interface, node string) {
+	namespace := settings.GetString("namespace")
+	pod, err := createPod(clientset, NodeConformancePod(namespace, node))
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			log.Fatalf("pod already exist %s. Please run cleanup first", common.PodName)
+		}
+		log.Fatal(err)
+	}
+	log.Printf("pod created %s on node %s\n", pod.Name, node)
+}
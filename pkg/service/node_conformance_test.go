@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestNodeConformancePod(t *testing.T) {
+	settings.Set("node-test-image", common.NodeTestImage)
+	defer settings.Set("node-test-image", "")
+
+	pod := NodeConformancePod("conformance", "worker-1")
+	assert.Equal(t, "worker-1", pod.Spec.NodeName)
+	assert.True(t, pod.Spec.HostNetwork)
+	container := pod.Spec.Containers[0]
+	assert.Equal(t, common.ConformanceContainer, container.Name)
+	assert.Equal(t, common.NodeTestImage, container.Image)
+	assert.True(t, *container.SecurityContext.Privileged)
+	assert.Contains(t, container.Env, v1.EnvVar{Name: "FOCUS", Value: NodeConformanceFocus})
+	assert.Contains(t, container.VolumeMounts, v1.VolumeMount{Name: "output-volume", MountPath: nodeResultsDir})
+	assert.Equal(t, common.OutputContainer, pod.Spec.Containers[1].Name)
+
+	settings.Set("focus", `\[sig-node\].*Pods`)
+	defer settings.Set("focus", "")
+	pod = NodeConformancePod("conformance", "worker-1")
+	assert.Contains(t, pod.Spec.Containers[0].Env, v1.EnvVar{Name: "FOCUS", Value: `\[sig-node\].*Pods`})
+}
+
+func TestCheckNodes(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}})
+	require.NoError(t, CheckNodes(clientset, []string{"worker-1"}))
+	assert.ErrorContains(t, CheckNodes(clientset, []string{"worker-1", "worker-2"}), "node worker-2 of --node")
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// CheckNodeOS makes sure the cluster has ready nodes for the pods of
+// hydrophone, which run on linux, and for the tests when they target the
+// windows nodes with --node-os=windows.
+func CheckNodeOS(clientset kubernetes.Interface) error {
+	if settings.GetString("node-os") != common.NodeOSWindows {
+		return nil
+	}
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing nodes: %w", err)
+	}
+	linux, windows := countNodeOS(nodes.Items)
+	if windows == 0 {
+		return fmt.Errorf("--node-os=%s requires ready windows nodes, none found", common.NodeOSWindows)
+	}
+	if linux == 0 {
+		return fmt.Errorf("--node-os=%s requires a ready linux node to run the conformance pod, none found", common.NodeOSWindows)
+	}
+	log.Printf("Running the tests against %d windows nodes from %d linux nodes", windows, linux)
+	return nil
+}
+
+// countNodeOS counts the ready linux and windows nodes
+func countNodeOS(nodes []v1.Node) (linux, windows int) {
+	for _, node := range nodes {
+		if node.Spec.Unschedulable || !nodeReady(node) {
+			continue
+		}
+		if linuxNode(node) {
+			linux++
+		} else if node.Labels[v1.LabelOSStable] == common.NodeOSWindows {
+			windows++
+		}
+	}
+	return linux, windows
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCountNodeOS(t *testing.T) {
+	ready := v1.NodeStatus{
+		Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+	}
+	withOS := func(os string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Labels: map[string]string{v1.LabelOSStable: os}}
+	}
+
+	nodes := []v1.Node{
+		{ObjectMeta: withOS("linux"), Status: ready},
+		{Status: ready},
+		{ObjectMeta: withOS("windows"), Status: ready},
+		{ObjectMeta: withOS("windows")},
+		{ObjectMeta: withOS("windows"), Status: ready, Spec: v1.NodeSpec{Unschedulable: true}},
+	}
+
+	linux, windows := countNodeOS(nodes)
+	assert.Equal(t, 2, linux)
+	assert.Equal(t, 1, windows)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// ResolveParallel replaces --parallel=auto with a parallelism derived from the
+// number of schedulable worker nodes of the cluster.
+func ResolveParallel(clientset kubernetes.Interface) error {
+	if settings.GetString("parallel") != common.ParallelAuto {
+		return nil
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing nodes to pick parallel: %w", err)
+	}
+	count := schedulableNodes(nodes.Items)
+
+	parallel := count * common.ParallelPerNode
+	if parallel < 1 {
+		parallel = 1
+	}
+	if parallel > common.MaxAutoParallel {
+		parallel = common.MaxAutoParallel
+	}
+	settings.Set("parallel", parallel)
+	settings.Set("parallel-auto", true)
+	log.Printf("Found %d schedulable nodes, using parallel '%d'", count, parallel)
+	return nil
+}
+
+// schedulableNodes returns the number of ready nodes that accept regular workloads
+func schedulableNodes(nodes []v1.Node) int {
+	count := 0
+	for _, node := range nodes {
+		if node.Spec.Unschedulable || !nodeReady(node) {
+			continue
+		}
+		tainted := false
+		for _, taint := range node.Spec.Taints {
+			if taint.Effect == v1.TaintEffectNoSchedule || taint.Effect == v1.TaintEffectNoExecute {
+				tainted = true
+				break
+			}
+		}
+		if !tainted {
+			count++
+		}
+	}
+	return count
+}
+
+// nodeReady reports whether the node has the Ready condition set to true
+func nodeReady(node v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestSchedulableNodes(t *testing.T) {
+	ready := v1.NodeStatus{
+		Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+	}
+	notReady := v1.NodeStatus{
+		Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}},
+	}
+
+	nodes := []v1.Node{
+		{Status: ready},
+		{Status: ready},
+		{Status: notReady},
+		{Status: ready, Spec: v1.NodeSpec{Unschedulable: true}},
+		{Status: ready, Spec: v1.NodeSpec{Taints: []v1.Taint{{Key: "node-role.kubernetes.io/control-plane", Effect: v1.TaintEffectNoSchedule}}}},
+		{Status: ready, Spec: v1.NodeSpec{Taints: []v1.Taint{{Key: "example.com/prefer-not", Effect: v1.TaintEffectPreferNoSchedule}}}},
+	}
+
+	assert.Equal(t, 3, schedulableNodes(nodes))
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/plugin"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// Plugin returns the plugin of --plugin, nil when the e2e tests are run.
+func Plugin() (*plugin.Plugin, error) {
+	if path := settings.GetString("plugin"); path != "" {
+		return plugin.Load(path)
+	}
+	return nil, nil
+}
+
+// applyPlugin runs the plugin of --plugin in the conformance container in
+// place of the e2e tests.
+func applyPlugin(pod *v1.Pod) error {
+	p, err := Plugin()
+	if err != nil || p == nil {
+		return err
+	}
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == common.ConformanceContainer {
+			p.Apply(&pod.Spec.Containers[i])
+		}
+	}
+	return nil
+}
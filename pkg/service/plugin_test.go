@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rbac "k8s.io/api/rbac/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestPlugin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugin.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`name: storage-certification
+image: registry.example.com/vendor/storage-cert:1.2.0
+command: [/run.sh]
+rbac:
+  - apiGroups: [""]
+    resources: [pods]
+    verbs: [get, list]
+`), 0644))
+
+	clusterRole, err := ClusterRole()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*"}, clusterRole.Rules[0].Verbs)
+
+	settings.Set("plugin", path)
+	defer settings.Set("plugin", "")
+
+	pods, err := Pods("conformance")
+	require.NoError(t, err)
+	container := pods[0].Spec.Containers[0]
+	assert.Equal(t, common.ConformanceContainer, container.Name)
+	assert.Equal(t, "registry.example.com/vendor/storage-cert:1.2.0", container.Image)
+	assert.Equal(t, []string{"/run.sh"}, container.Command)
+	for _, env := range container.Env {
+		assert.NotEqual(t, "E2E_FOCUS", env.Name)
+	}
+	assert.Len(t, container.VolumeMounts, 1)
+
+	clusterRole, err = ClusterRole()
+	require.NoError(t, err)
+	assert.Equal(t, []rbac.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}}}, clusterRole.Rules)
+}
@@ -0,0 +1,466 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// ConformancePod returns the definition of the conformance pod created in the given namespace.
+func ConformancePod(namespace string) *v1.Pod {
+	extraArgs, ginkgoArgs := e2eExtraArgs(), extraGinkgoArgs()
+	separator := argsSeparator(extraArgs, ginkgoArgs)
+	conformancePod := v1.Pod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    common.ConformanceLabels(),
+			Name:      common.PodName,
+			Namespace: namespace,
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name:            common.ConformanceContainer,
+					Image:           settings.GetString("conformance-image"),
+					ImagePullPolicy: v1.PullIfNotPresent,
+					Env: []v1.EnvVar{
+						{
+							Name:  "E2E_FOCUS",
+							Value: fmt.Sprintf("%s", settings.Get("focus")),
+						},
+						{
+							Name:  "E2E_SKIP",
+							Value: fmt.Sprintf("%s", settings.Get("skip")),
+						},
+						{
+							Name:  "E2E_PROVIDER",
+							Value: "skeleton",
+						},
+						{
+							Name:  "E2E_PARALLEL",
+							Value: settings.GetString("parallel"),
+						},
+						{
+							Name:  "E2E_VERBOSITY",
+							Value: fmt.Sprintf("%d", settings.Get("verbosity")),
+						},
+						{
+							Name:  "E2E_USE_GO_RUNNER",
+							Value: "true",
+						},
+						{
+							Name:  "E2E_EXTRA_ARGS",
+							Value: strings.Join(extraArgs, separator),
+						},
+					},
+					VolumeMounts: []v1.VolumeMount{
+						{
+							Name:      "output-volume",
+							MountPath: "/tmp/results",
+						},
+					},
+				},
+				{
+					Name:    common.OutputContainer,
+					Image:   settings.GetString("busybox-image"),
+					Command: []string{"/bin/sh", "-c", "sleep infinity"},
+					VolumeMounts: []v1.VolumeMount{
+						{
+							Name:      "output-volume",
+							MountPath: "/tmp/results",
+						},
+					},
+				},
+			},
+			Volumes: []v1.Volume{
+				{
+					Name: "output-volume",
+					VolumeSource: v1.VolumeSource{
+						EmptyDir: &v1.EmptyDirVolumeSource{},
+					},
+				},
+			},
+			RestartPolicy:      v1.RestartPolicyNever,
+			ServiceAccountName: ServiceAccountName(),
+			ImagePullSecrets:   imagePullSecrets(),
+			NodeSelector:       nodeSelector(),
+			Tolerations: []v1.Toleration{
+				{
+					// An empty key with operator Exists matches all keys,
+					// values and effects which means this will tolerate everything.
+					// As noted in https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration/
+					Operator: "Exists",
+				},
+			},
+		},
+	}
+
+	if settings.GetBool("ginkgo-dry-run") {
+		conformancePod.Spec.Containers[0].Env = append(conformancePod.Spec.Containers[0].Env, DryRun())
+	}
+
+	if settings.GetString("test-repo-list") != "" {
+		mountVolume(&conformancePod, common.ConformanceContainer,
+			v1.Volume{
+				Name: "repo-list-volume",
+				VolumeSource: v1.VolumeSource{
+					ConfigMap: &v1.ConfigMapVolumeSource{
+						LocalObjectReference: v1.LocalObjectReference{
+							Name: common.RepoListConfigMapName,
+						},
+					},
+				},
+			},
+			v1.VolumeMount{
+				MountPath: path.Dir(common.RepoListPath),
+				ReadOnly:  true,
+			})
+
+		conformancePod.Spec.Containers[0].Env = append(conformancePod.Spec.Containers[0].Env, v1.EnvVar{
+			Name:  "KUBE_TEST_REPO_LIST",
+			Value: common.RepoListPath,
+		})
+	}
+
+	if settings.GetString("test-repo") != "" {
+		conformancePod.Spec.Containers[0].Env = append(conformancePod.Spec.Containers[0].Env, v1.EnvVar{
+			Name:  "KUBE_TEST_REPO",
+			Value: settings.GetString("test-repo"),
+		})
+	}
+
+	if len(ginkgoArgs) != 0 {
+		conformancePod.Spec.Containers[0].Env = append(conformancePod.Spec.Containers[0].Env, v1.EnvVar{
+			Name:  "E2E_EXTRA_GINKGO_ARGS",
+			Value: strings.Join(ginkgoArgs, separator),
+		})
+	}
+
+	if separator != " " {
+		conformancePod.Spec.Containers[0].Env = append(conformancePod.Spec.Containers[0].Env, v1.EnvVar{
+			Name:  "E2E_EXTRA_ARGS_SEP",
+			Value: separator,
+		})
+	}
+
+	return &conformancePod
+}
+
+// Pods returns the definitions of the conformance pods, one for each shard,
+// with the provider, storage test driver, plugin, environment, scheduling, network, volumes, artifact transfer,
+// security and resources flags and the patch of --pod-patch applied.
+func Pods(namespace string) ([]*v1.Pod, error) {
+	conformancePod := ConformancePod(namespace)
+	applyProvider(conformancePod)
+	applyStorage(conformancePod)
+	if err := applyPlugin(conformancePod); err != nil {
+		return nil, err
+	}
+	if err := applyEnv(conformancePod); err != nil {
+		return nil, err
+	}
+	if err := applyScheduling(conformancePod); err != nil {
+		return nil, err
+	}
+	if err := applyNetwork(conformancePod); err != nil {
+		return nil, err
+	}
+	if err := applyVolumes(conformancePod); err != nil {
+		return nil, err
+	}
+	applyArtifactTransfer(conformancePod)
+	if err := applySecurity(conformancePod); err != nil {
+		return nil, err
+	}
+	if err := applyResources(conformancePod); err != nil {
+		return nil, err
+	}
+	if patchFile := settings.GetString("pod-patch"); patchFile != "" {
+		patch, err := ReadPodPatch(patchFile)
+		if err != nil {
+			return nil, err
+		}
+		if conformancePod, err = patch.Apply(conformancePod); err != nil {
+			return nil, fmt.Errorf("%s: %w", patchFile, err)
+		}
+	}
+
+	var pods []*v1.Pod
+	shards := settings.GetInt("shards")
+	for shard, podName := range common.PodNames() {
+		shardPod := conformancePod.DeepCopy()
+		shardPod.Name = podName
+		if shards > 1 {
+			setEnv(&shardPod.Spec.Containers[0], "E2E_SKIP", shardSkip(shard, shards))
+		}
+		pods = append(pods, shardPod)
+	}
+	return pods, nil
+}
+
+// CreatePods creates the conformance pods, one for each shard. With
+// --workload=job the pods are created by jobs. With --verbose the command run
+// in each pod is logged first.
+func CreatePods(clientset kubernetes.Interface) {
+	namespace := settings.GetString("namespace")
+	pods, err := Pods(namespace)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if settings.GetBool("verbose") {
+		LogConformanceCommands(pods)
+	}
+	if settings.GetString("workload") == common.WorkloadJob {
+		CreateJobs(clientset)
+		return
+	}
+	for _, shardPod := range pods {
+		pod, err := createPod(clientset, shardPod)
+		if err != nil {
+			if errors.IsAlreadyExists(err) {
+				log.Fatalf("pod already exist %s. Please run cleanup first", shardPod.ObjectMeta.Name)
+			} else {
+				log.Fatal(err)
+			}
+		}
+		log.Printf("pod created %s\n", pod.Name)
+	}
+}
+
+// createPod creates the pod, retrying on transient errors. A pod found to
+// exist when the request is retried was created by a request that failed
+// after reaching the API server.
+func createPod(clientset kubernetes.Interface, pod *v1.Pod) (*v1.Pod, error) {
+	pods := clientset.CoreV1().Pods(pod.Namespace)
+	var created *v1.Pod
+	attempts := 0
+	err := client.Retry(fmt.Sprintf("creating pod %s", pod.Name), func() error {
+		var err error
+		attempts++
+		created, err = pods.Create(ctx, pod, metav1.CreateOptions{})
+		if errors.IsAlreadyExists(err) && attempts > 1 {
+			created, err = pods.Get(ctx, pod.Name, metav1.GetOptions{})
+		}
+		return err
+	})
+	return created, err
+}
+
+// DeletePods deletes the conformance pods and waits until they are gone, so
+// that pods with the same names can be created again.
+func DeletePods(clientset kubernetes.Interface) {
+	if settings.GetString("workload") == common.WorkloadJob {
+		DeleteJobs(clientset)
+		return
+	}
+	namespace := settings.GetString("namespace")
+	for _, podName := range common.PodNames() {
+		err := clientset.CoreV1().Pods(namespace).Delete(ctx, podName, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			log.Fatal(err)
+		}
+		err = wait.PollUntilContextTimeout(ctx, time.Second, 5*time.Minute, true, func(ctx context.Context) (bool, error) {
+			_, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		})
+		if err != nil {
+			log.Fatalf("error waiting for pod %s to be deleted: %v", podName, err)
+		}
+		log.Printf("pod deleted %s\n", podName)
+	}
+}
+
+// serialTags are the tags of the specs which can't run concurrently with
+// other specs
+var serialTags = []string{"Serial", "Disruptive"}
+
+// shardSkip returns the skip expression of the given shard. On top of the
+// user provided skip, each shard skips the specs whose first SIG tag is
+// assigned to another shard. The first shard also runs the specs without the
+// tag of a known SIG and all the [Serial] and [Disruptive] specs, which the
+// other shards skip, so that each spec runs in exactly one shard and the
+// serial specs never run concurrently with each other.
+func shardSkip(shard, shards int) string {
+	var sigTags, others []string
+	for i, sig := range common.SIGs {
+		sigTags = append(sigTags, "sig-"+sig)
+		if i%shards != shard {
+			others = append(others, "sig-"+sig)
+		}
+	}
+	var expr string
+	if shard == 0 {
+		// the specs of the other shards unless they are serial
+		expr = "^" + avoidingTags(append(sigTags, serialTags...)) + tagExpr(others) + avoidingTags(serialTags) + "$"
+	} else {
+		// the serial specs, the specs of the other shards and the specs
+		// without the tag of a known SIG
+		expr = tagExpr(serialTags) + "|^" + avoidingTags(sigTags) + "(?:" + tagExpr(others) + "|$)"
+	}
+	if skip := settings.GetString("skip"); skip != "" {
+		return skip + "|" + expr
+	}
+	return expr
+}
+
+// tagExpr returns an expression matching any of the tags, e.g. [Serial]
+func tagExpr(tags []string) string {
+	quoted := make([]string, len(tags))
+	for i, tag := range tags {
+		quoted[i] = regexp.QuoteMeta(tag)
+	}
+	return `\[(?:` + strings.Join(quoted, "|") + `)\]`
+}
+
+// avoidingTags returns an expression matching the text up to the next [ or
+// the end, which holds none of the tags. Go regular expressions can't negate
+// a match, the expression is built from a trie of the tags instead: the text
+// after each [ must deviate from every tag before the tag is complete.
+func avoidingTags(tags []string) string {
+	root := &tagTrie{}
+	for _, tag := range tags {
+		node := root
+		for _, c := range tag + "]" {
+			if node.children == nil {
+				node.children = map[rune]*tagTrie{}
+			}
+			if node.children[c] == nil {
+				node.children[c] = &tagTrie{}
+			}
+			node = node.children[c]
+		}
+		node.complete = true
+	}
+	return `[^\[]*(?:\[` + root.deviation() + `)*`
+}
+
+// tagTrie is a node of the trie of the tags of avoidingTags
+type tagTrie struct {
+	children map[rune]*tagTrie
+	complete bool
+}
+
+// deviation returns an expression matching the text up to the next [ or the
+// end which doesn't continue into a complete tag from the node
+func (t *tagTrie) deviation() string {
+	var chars []rune
+	for c := range t.children {
+		chars = append(chars, c)
+	}
+	slices.Sort(chars)
+	var alternatives []string
+	class := `[^\[`
+	for _, c := range chars {
+		escaped := regexp.QuoteMeta(string(c))
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			escaped = `\` + string(c)
+		}
+		class += escaped
+		if child := t.children[c]; !child.complete {
+			alternatives = append(alternatives, escaped+child.deviation())
+		}
+	}
+	alternatives = append(alternatives, class+`][^\[]*`)
+	return "(?:" + strings.Join(alternatives, "|") + ")?"
+}
+
+// setEnv sets the environment variable of the container, replacing any existing value.
+func setEnv(container *v1.Container, name, value string) {
+	for i := range container.Env {
+		if container.Env[i].Name == name {
+			container.Env[i].Value = value
+			return
+		}
+	}
+	container.Env = append(container.Env, v1.EnvVar{Name: name, Value: value})
+}
+
+// e2eExtraArgs returns the arguments passed to the e2e test binary, the
+// --extra-args and the ones implied by other flags of hydrophone
+func e2eExtraArgs() []string {
+	args := append(append(providerArgs(), storageArgs()...), settings.GetStringSlice("extra-args")...)
+	if settings.GetString("node-os") == common.NodeOSWindows && !hasArg(args, "--node-os-distro") {
+		args = append(args, "--node-os-distro="+common.NodeOSWindows)
+	}
+	return args
+}
+
+// argsSeparator returns the separator of the arguments in E2E_EXTRA_ARGS and
+// E2E_EXTRA_GINKGO_ARGS, which the conformance image splits at
+// E2E_EXTRA_ARGS_SEP, a space by default. Arguments holding a space are
+// separated by newlines instead.
+func argsSeparator(argLists ...[]string) string {
+	for _, args := range argLists {
+		for _, arg := range args {
+			if strings.Contains(arg, " ") {
+				return "\n"
+			}
+		}
+	}
+	return " "
+}
+
+// hasArg reports whether the --key=value arguments set the key
+func hasArg(args []string, key string) bool {
+	for _, arg := range args {
+		if k, _, _ := strings.Cut(arg, "="); k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// extraGinkgoArgs returns the arguments hydrophone passes to the ginkgo runner
+// inside the conformance container, followed by --extra-ginkgo-args.
+func extraGinkgoArgs() []string {
+	var args []string
+	if seed := settings.GetInt64("seed"); seed != 0 {
+		args = append(args, fmt.Sprintf("--seed=%d", seed))
+	}
+	// ginkgo reports the specs running longer than --progress-report, and
+	// reports them again at the same interval while they run
+	if interval, _ := common.GetDuration("progress-report"); interval > 0 {
+		args = append(args, "--poll-progress-after="+interval.String(), "--poll-progress-interval="+interval.String())
+	}
+	// ginkgo stops at the first failure of each process, hydrophone stops
+	// the other shards and enforces larger budgets itself
+	if common.FailureBudget() == 1 {
+		args = append(args, "--fail-fast")
+	}
+	return append(args, settings.GetStringSlice("extra-ginkgo-args")...)
+}
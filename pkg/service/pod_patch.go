@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+)
+
+// PodPatch is a patch applied to the conformance pod before it is created
+type PodPatch struct {
+	// JSON6902 reports whether the patch is a list of RFC 6902 operations
+	// rather than a strategic merge patch
+	JSON6902 bool
+	// Data is the patch in JSON
+	Data []byte
+}
+
+// ReadPodPatch reads a patch in YAML or JSON. A list is read as JSON6902
+// operations and an object as a strategic merge patch.
+func ReadPodPatch(path string) (*PodPatch, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing pod patch %s: %w", path, err)
+	}
+	patch = bytes.TrimSpace(patch)
+	switch {
+	case bytes.HasPrefix(patch, []byte("[")):
+		return &PodPatch{JSON6902: true, Data: patch}, nil
+	case bytes.HasPrefix(patch, []byte("{")):
+		return &PodPatch{Data: patch}, nil
+	default:
+		return nil, fmt.Errorf("expected pod patch %s to be a strategic merge patch object or a list of JSON6902 operations", path)
+	}
+}
+
+// Apply returns the pod with the patch applied.
+func (p *PodPatch) Apply(pod *v1.Pod) (*v1.Pod, error) {
+	original, err := json.Marshal(pod)
+	if err != nil {
+		return nil, err
+	}
+	var patched []byte
+	if p.JSON6902 {
+		var patch jsonpatch.Patch
+		if patch, err = jsonpatch.DecodePatch(p.Data); err == nil {
+			patched, err = patch.Apply(original)
+		}
+	} else {
+		patched, err = strategicpatch.StrategicMergePatch(original, p.Data, v1.Pod{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error applying pod patch: %w", err)
+	}
+	result := &v1.Pod{}
+	if err := json.Unmarshal(patched, result); err != nil {
+		return nil, fmt.Errorf("error applying pod patch: %w", err)
+	}
+	return result, nil
+}
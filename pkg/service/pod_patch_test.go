@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+)
+
+func TestPodPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{
+			name: "strategic merge",
+			patch: `
+metadata:
+  annotations:
+    sidecar.istio.io/inject: "false"
+spec:
+  runtimeClassName: gvisor
+  containers:
+  - name: conformance-container
+    env:
+    - name: E2E_PROVIDER
+      value: gce
+`,
+		},
+		{
+			name: "json6902",
+			patch: `
+- op: add
+  path: /metadata/annotations
+  value:
+    sidecar.istio.io/inject: "false"
+- op: add
+  path: /spec/runtimeClassName
+  value: gvisor
+- op: test
+  path: /spec/containers/0/env/2/name
+  value: E2E_PROVIDER
+- op: replace
+  path: /spec/containers/0/env/2/value
+  value: gce
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "patch.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.patch), 0644))
+			patch, err := ReadPodPatch(path)
+			require.NoError(t, err)
+
+			pod, err := patch.Apply(ConformancePod("conformance"))
+			require.NoError(t, err)
+
+			assert.Equal(t, map[string]string{"sidecar.istio.io/inject": "false"}, pod.Annotations)
+			assert.Equal(t, "gvisor", *pod.Spec.RuntimeClassName)
+			assert.Equal(t, common.ConformanceContainer, pod.Spec.Containers[0].Name)
+			assert.Contains(t, pod.Spec.Containers[0].Env, v1.EnvVar{Name: "E2E_PROVIDER", Value: "gce"})
+			assert.Len(t, pod.Spec.Containers[0].Env, len(ConformancePod("conformance").Spec.Containers[0].Env))
+		})
+	}
+}
+
+func TestPodPatchJSON6902Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		wantErr string
+	}{
+		{
+			name:    "failed test",
+			patch:   `[{"op":"test","path":"/spec/restartPolicy","value":"Always"}]`,
+			wantErr: "testing value /spec/restartPolicy failed",
+		},
+		{
+			name:    "remove of a missing value",
+			patch:   `[{"op":"remove","path":"/spec/hostname"}]`,
+			wantErr: "nonexistent key: hostname",
+		},
+		{
+			name:    "unknown operation",
+			patch:   `[{"op":"merge","path":"/spec","value":{}}]`,
+			wantErr: "Unexpected kind: merge",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch := &PodPatch{JSON6902: true, Data: []byte(tt.patch)}
+			_, err := patch.Apply(ConformancePod("conformance"))
+			assert.ErrorContains(t, err, "error applying pod patch")
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestConformancePodTestRepoList(t *testing.T) {
+	settings.Set("test-repo-list", "repo-list.yaml")
+	defer settings.Set("test-repo-list", "")
+
+	pod := ConformancePod("conformance")
+	container := pod.Spec.Containers[0]
+
+	assert.Contains(t, container.Env, v1.EnvVar{Name: "KUBE_TEST_REPO_LIST", Value: common.RepoListPath})
+	assert.Contains(t, container.VolumeMounts, v1.VolumeMount{Name: "repo-list-volume", MountPath: "/tmp/repo-list", ReadOnly: true})
+	assert.Contains(t, pod.Spec.Volumes, v1.Volume{
+		Name: "repo-list-volume",
+		VolumeSource: v1.VolumeSource{
+			ConfigMap: &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: common.RepoListConfigMapName},
+			},
+		},
+	})
+}
+
+func TestConformancePodWindows(t *testing.T) {
+	settings.Set("node-os", common.NodeOSWindows)
+	settings.Set("extra-args", []string{"--allowed-not-ready-nodes=1"})
+	defer settings.Set("node-os", "")
+	defer settings.Set("extra-args", []string{})
+
+	pod := ConformancePod("conformance")
+
+	assert.Equal(t, map[string]string{v1.LabelOSStable: common.NodeOSLinux}, pod.Spec.NodeSelector)
+	assert.Contains(t, pod.Spec.Containers[0].Env, v1.EnvVar{Name: "E2E_EXTRA_ARGS", Value: "--allowed-not-ready-nodes=1 --node-os-distro=windows"})
+}
+
+func TestConformancePodExtraArgsSeparator(t *testing.T) {
+	settings.Set("extra-args", []string{"--clean-start", "--dns-domain", "cluster local"})
+	defer settings.Set("extra-args", []string{})
+
+	pod := ConformancePod("conformance")
+
+	assert.Contains(t, pod.Spec.Containers[0].Env, v1.EnvVar{Name: "E2E_EXTRA_ARGS", Value: "--clean-start\n--dns-domain\ncluster local"})
+	assert.Contains(t, pod.Spec.Containers[0].Env, v1.EnvVar{Name: "E2E_EXTRA_ARGS_SEP", Value: "\n"})
+}
+
+func TestConformancePodExtraGinkgoArgs(t *testing.T) {
+	settings.Set("seed", 42)
+	settings.Set("extra-ginkgo-args", []string{"--flake-attempts=2"})
+	defer settings.Set("seed", 0)
+	defer settings.Set("extra-ginkgo-args", []string{})
+
+	pod := ConformancePod("conformance")
+
+	assert.Contains(t, pod.Spec.Containers[0].Env, v1.EnvVar{Name: "E2E_EXTRA_GINKGO_ARGS", Value: "--seed=42 --flake-attempts=2"})
+}
+
+func TestConformancePodFailFast(t *testing.T) {
+	settings.Set("max-failures", 1)
+	defer settings.Set("max-failures", 0)
+
+	pod := ConformancePod("conformance")
+
+	assert.Contains(t, pod.Spec.Containers[0].Env, v1.EnvVar{Name: "E2E_EXTRA_GINKGO_ARGS", Value: "--fail-fast"})
+}
+
+func TestConformancePodProgressReport(t *testing.T) {
+	settings.Set("progress-report", 5*time.Minute)
+	defer settings.Set("progress-report", 0)
+
+	pod := ConformancePod("conformance")
+
+	assert.Contains(t, pod.Spec.Containers[0].Env, v1.EnvVar{Name: "E2E_EXTRA_GINKGO_ARGS", Value: "--poll-progress-after=5m0s --poll-progress-interval=5m0s"})
+}
+
+func TestShardSkip(t *testing.T) {
+	names := []string{
+		"[sig-apps] Deployment should run the lifecycle of a Deployment [Conformance]",
+		"[sig-node] Pods should be submitted and removed [NodeConformance] [Conformance]",
+		"[sig-network] DNS should provide DNS for services [Conformance]",
+		"[sig-storage] CSI mock volume should expand volume [sig-windows]",
+		"[sig-cloud-provider-gcp] Nodes should be recreated",
+		"[sig-api-machinery-extra] unknown SIG sharing a prefix",
+		"Kubectl client should print the version",
+		"[sig-apps] Daemon set [Serial] should rollback without unnecessary restarts [Conformance]",
+		"[sig-scheduling] SchedulerPredicates [Serial] validates resource limits of pods [Conformance]",
+		"[Disruptive] [sig-node] NodeLease should be recreated",
+		"[sig-windows] [Feature:Windows] Density [Serial] [Slow] create a batch of pods",
+		"[sig-cli] Kubectl logs [Slow] should be filtered",
+	}
+	serial := regexp.MustCompile(`\[(Serial|Disruptive)\]`)
+	for _, shards := range []int{2, 3, common.MaxShards} {
+		t.Run(fmt.Sprintf("%d shards", shards), func(t *testing.T) {
+			skips := make([]*regexp.Regexp, shards)
+			for shard := range skips {
+				skips[shard] = regexp.MustCompile(shardSkip(shard, shards))
+			}
+			for _, name := range names {
+				var runs []int
+				for shard, skip := range skips {
+					// ginkgo matches the description of the suite and the text of the spec
+					if !skip.MatchString("Kubernetes e2e suite " + name) {
+						runs = append(runs, shard)
+					}
+				}
+				if assert.Len(t, runs, 1, name) && serial.MatchString(name) {
+					assert.Equal(t, 0, runs[0], name)
+				}
+			}
+		})
+	}
+
+	settings.Set("skip", `\[Slow\]`)
+	defer settings.Set("skip", "")
+	for shard := 0; shard < 3; shard++ {
+		assert.Regexp(t, shardSkip(shard, 3), "Kubernetes e2e suite [sig-cli] Kubectl logs [Slow] should be filtered")
+	}
+}
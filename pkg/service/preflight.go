@@ -0,0 +1,256 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// podSecurityEnforceLabel sets the Pod Security level enforced in a namespace
+const podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+
+// minFreeSpace is the free space the output directory needs for the logs
+// and reports of a shard at the default verbosity
+var minFreeSpace = resource.MustParse("100Mi")
+
+// estimatedOutputSize estimates the space the artifacts of the run take in
+// the output directory: minFreeSpace for each shard, doubled for every level
+// of --verbosity above the default, as the tests log more, and doubled again
+// with --compress=bundle, which keeps the artifacts until their tarball is
+// complete.
+func estimatedOutputSize() *resource.Quantity {
+	size := minFreeSpace.Value()
+	if shards := settings.GetInt("shards"); shards > 1 {
+		size *= int64(shards)
+	}
+	for level := 5; level <= min(settings.GetInt("verbosity"), 10); level++ {
+		size *= 2
+	}
+	if settings.GetString("compress") == common.CompressBundle {
+		size *= 2
+	}
+	return resource.NewQuantity(size, resource.BinarySI)
+}
+
+// PreflightProblem is a problem of the cluster or of the environment found by
+// a preflight check, which would make the run fail or its results misleading.
+type PreflightProblem struct {
+	Check   string
+	Message string
+}
+
+func (p PreflightProblem) String() string {
+	return fmt.Sprintf("%s: %s", p.Check, p.Message)
+}
+
+// preflightCheck returns the problems found by a check
+type preflightCheck func(clientset kubernetes.Interface) []string
+
+// Preflight runs the preflight checks and returns all problems they found.
+// The other checks are skipped when the API server can't be reached.
+func Preflight(clientset kubernetes.Interface) []PreflightProblem {
+	if messages := checkAPIServer(clientset); len(messages) != 0 {
+		return preflightProblems("api-server", messages)
+	}
+	var problems []PreflightProblem
+	for _, check := range []struct {
+		name string
+		run  preflightCheck
+		// cluster is set for the checks listing cluster-scoped resources,
+		// skipped with --restricted
+		cluster bool
+	}{
+		{"nodes", checkNodes, true},
+		{"version", checkVersion, false},
+		{"version-skew", checkVersionSkew, true},
+		{"output-dir", checkOutputDir, false},
+		{"namespace", checkNamespace, false},
+	} {
+		if check.cluster && common.Restricted() {
+			log.Printf("preflight %s: skipped with --restricted", check.name)
+			continue
+		}
+		problems = append(problems, preflightProblems(check.name, check.run(clientset))...)
+	}
+	return problems
+}
+
+func preflightProblems(check string, messages []string) []PreflightProblem {
+	var problems []PreflightProblem
+	for _, message := range messages {
+		problems = append(problems, PreflightProblem{Check: check, Message: message})
+	}
+	return problems
+}
+
+// checkAPIServer checks that the API server can be reached
+func checkAPIServer(clientset kubernetes.Interface) []string {
+	if _, err := clientset.Discovery().ServerVersion(); err != nil {
+		return []string{fmt.Sprintf("error reaching the API server: %v", err)}
+	}
+	return nil
+}
+
+// checkNodes checks that all nodes are ready and that enough nodes are
+// schedulable for --parallel
+func checkNodes(clientset kubernetes.Interface) []string {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return []string{fmt.Sprintf("error listing nodes: %v", err)}
+	}
+	var messages []string
+	for _, node := range nodes.Items {
+		if !nodeReady(node) {
+			messages = append(messages, fmt.Sprintf("node %s is not ready", node.Name))
+		}
+	}
+	schedulable := schedulableNodes(nodes.Items)
+	if schedulable == 0 {
+		return append(messages, "no node is schedulable")
+	}
+	// --parallel=auto is derived from the schedulable nodes
+	if parallel, err := strconv.Atoi(settings.GetString("parallel")); err == nil {
+		required := (parallel + common.ParallelPerNode - 1) / common.ParallelPerNode
+		if schedulable < required {
+			messages = append(messages, fmt.Sprintf("--parallel=%d needs at least %d schedulable nodes, found %d", parallel, required, schedulable))
+		}
+	}
+	return messages
+}
+
+// checkVersion checks the version of the conformance image against the
+// version of the cluster, according to --version-mismatch
+func checkVersion(_ kubernetes.Interface) []string {
+	if err := common.ValidateVersionMismatch(); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// checkVersionSkew checks that the kubelets of the nodes are within the
+// supported version skew of the control plane, results of skewed clusters are
+// frequently misleading. The skew is only a warning unless --strict-skew is set.
+func checkVersionSkew(clientset kubernetes.Interface) []string {
+	serverVersion := settings.GetString("server-version")
+	if serverVersion == "" {
+		return nil
+	}
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return []string{fmt.Sprintf("error listing nodes: %v", err)}
+	}
+	// nodes are upgraded in batches, report each kubelet version once
+	nodeNames := map[string][]string{}
+	var kubeletVersions []string
+	for _, node := range nodes.Items {
+		kubeletVersion := node.Status.NodeInfo.KubeletVersion
+		if _, ok := nodeNames[kubeletVersion]; !ok {
+			kubeletVersions = append(kubeletVersions, kubeletVersion)
+		}
+		nodeNames[kubeletVersion] = append(nodeNames[kubeletVersion], node.Name)
+	}
+
+	var messages []string
+	for _, kubeletVersion := range kubeletVersions {
+		skew, err := common.KubeletSkew(serverVersion, kubeletVersion)
+		if err != nil {
+			log.Printf("WARNING: unable to check the version skew of node %s: %v", nodeNames[kubeletVersion][0], err)
+			continue
+		}
+		if skew == "" {
+			continue
+		}
+		message := fmt.Sprintf("%d nodes, e.g. %s: %s", len(nodeNames[kubeletVersion]), nodeNames[kubeletVersion][0], skew)
+		if settings.GetBool("strict-skew") {
+			messages = append(messages, message)
+		} else {
+			log.Printf("WARNING: %s, use --strict-skew to refuse the run", message)
+		}
+	}
+	return messages
+}
+
+// checkOutputDir checks that the output directory, or the directory it will
+// be created in, has enough free space for the estimated size of the artifacts
+func checkOutputDir(_ kubernetes.Interface) []string {
+	dir, err := filepath.Abs(settings.GetString("output-dir"))
+	if err != nil {
+		return []string{err.Error()}
+	}
+	// the output directory is created by the run
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	free, err := freeSpace(dir)
+	if err != nil {
+		log.Printf("WARNING: unable to check the free space of %s: %v", dir, err)
+		return nil
+	}
+	if needed := estimatedOutputSize(); free < uint64(needed.Value()) {
+		return []string{fmt.Sprintf("%s has %s free, the artifacts of the run are estimated to take %s",
+			dir, resource.NewQuantity(int64(free), resource.BinarySI), needed)}
+	}
+	return nil
+}
+
+// checkNamespace checks that the namespace of the run can be used: without
+// --service-account hydrophone creates it, with it the namespace has to exist.
+// The Pod Security level it enforces has to allow the conformance pods. With
+// --restricted only the service account is checked.
+func checkNamespace(clientset kubernetes.Interface) []string {
+	name := settings.GetString("namespace")
+	// the namespace is cluster-scoped, its service account isn't
+	if common.Restricted() {
+		if _, err := clientset.CoreV1().ServiceAccounts(name).Get(ctx, ServiceAccountName(), metav1.GetOptions{}); err != nil {
+			return []string{fmt.Sprintf("service account %s of --service-account in namespace %s: %v", ServiceAccountName(), name, err)}
+		}
+		return nil
+	}
+	ns, err := clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		if !ManagedRBAC() {
+			return []string{fmt.Sprintf("namespace %s of --service-account doesn't exist", name)}
+		}
+		if ns, err = Namespace(); err != nil {
+			return []string{err.Error()}
+		}
+	case err != nil:
+		return []string{fmt.Sprintf("error getting namespace %s: %v", name, err)}
+	case ManagedRBAC():
+		return []string{fmt.Sprintf("namespace %s already exists, run cleanup first", name)}
+	}
+	if level, ok := ns.Labels[podSecurityEnforceLabel]; ok && level != podSecurityLabels[podSecurityEnforceLabel] {
+		return []string{fmt.Sprintf("namespace %s enforces the %s Pod Security level, the conformance pods may be rejected", name, level)}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestPreflight(t *testing.T) {
+	settings.Set("namespace", "conformance")
+	settings.Set("parallel", "8")
+	settings.Set("output-dir", t.TempDir())
+	defer func() {
+		settings.Set("namespace", "")
+		settings.Set("parallel", "1")
+		settings.Set("output-dir", "")
+	}()
+	node := func(name string, ready v1.ConditionStatus) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: ready}}},
+		}
+	}
+
+	clientset := fake.NewSimpleClientset(node("worker-1", v1.ConditionTrue), node("worker-2", v1.ConditionTrue),
+		node("worker-3", v1.ConditionTrue), node("worker-4", v1.ConditionTrue))
+	assert.Empty(t, Preflight(clientset))
+
+	clientset = fake.NewSimpleClientset(node("worker-1", v1.ConditionTrue), node("worker-2", v1.ConditionFalse),
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "conformance"}})
+	assert.Equal(t, []PreflightProblem{
+		{Check: "nodes", Message: "node worker-2 is not ready"},
+		{Check: "nodes", Message: "--parallel=8 needs at least 4 schedulable nodes, found 1"},
+		{Check: "namespace", Message: "namespace conformance already exists, run cleanup first"},
+	}, Preflight(clientset))
+}
+
+func TestCheckNamespace(t *testing.T) {
+	settings.Set("namespace", "conformance")
+	defer settings.Set("namespace", "")
+	defer settings.Set("service-account", "")
+	defer settings.Set("namespace-label", []string{})
+
+	settings.Set("namespace-label", []string{"pod-security.kubernetes.io/enforce=restricted"})
+	assert.Equal(t, []string{"namespace conformance enforces the restricted Pod Security level, the conformance pods may be rejected"},
+		checkNamespace(fake.NewSimpleClientset()))
+	settings.Set("namespace-label", []string{})
+
+	settings.Set("service-account", "e2e")
+	assert.Equal(t, []string{"namespace conformance of --service-account doesn't exist"}, checkNamespace(fake.NewSimpleClientset()))
+	assert.Empty(t, checkNamespace(fake.NewSimpleClientset(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "conformance"}})))
+}
+
+func TestPreflightRestricted(t *testing.T) {
+	settings.Set("namespace", "team-a")
+	settings.Set("service-account", "e2e")
+	settings.Set("restricted", true)
+	settings.Set("output-dir", t.TempDir())
+	defer func() {
+		settings.Set("namespace", "")
+		settings.Set("service-account", "")
+		settings.Set("restricted", false)
+		settings.Set("output-dir", "")
+	}()
+
+	// the nodes and the namespace can't be read, the service account can
+	clientset := fake.NewSimpleClientset(&v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "e2e", Namespace: "team-a"}})
+	clientset.PrependReactor("*", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("nodes is forbidden")
+	})
+	clientset.PrependReactor("*", "namespaces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("namespaces is forbidden")
+	})
+	assert.Empty(t, Preflight(clientset))
+
+	assert.Equal(t, []PreflightProblem{
+		{Check: "namespace", Message: `service account e2e of --service-account in namespace team-a: serviceaccounts "e2e" not found`},
+	}, Preflight(fake.NewSimpleClientset()))
+}
+
+func TestCheckVersionSkew(t *testing.T) {
+	settings.Set("server-version", "v1.29.2")
+	defer settings.Set("server-version", "")
+	defer settings.Set("strict-skew", false)
+	node := func(name, kubeletVersion string) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{KubeletVersion: kubeletVersion}},
+		}
+	}
+	clientset := fake.NewSimpleClientset(node("worker-1", "v1.29.2"), node("worker-2", "v1.25.0"), node("worker-3", "v1.25.0"))
+
+	assert.Empty(t, checkVersionSkew(clientset))
+
+	settings.Set("strict-skew", true)
+	assert.Equal(t, []string{"2 nodes, e.g. worker-2: kubelet v1.25.0 is 4 minor versions older than API server v1.29.2, at most 3 are supported"},
+		checkVersionSkew(clientset))
+}
+
+func TestEstimatedOutputSize(t *testing.T) {
+	defer func() {
+		settings.Set("shards", 0)
+		settings.Set("verbosity", nil)
+		settings.Set("compress", "")
+	}()
+
+	settings.Set("verbosity", 4)
+	assert.Equal(t, "100Mi", estimatedOutputSize().String())
+
+	settings.Set("shards", 2)
+	settings.Set("verbosity", 6)
+	settings.Set("compress", common.CompressBundle)
+	assert.Equal(t, "1600Mi", estimatedOutputSize().String())
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"os"
+	"path"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// Keys of the provider secret and the directory it is mounted at in the
+// conformance container
+const (
+	providerCloudConfigKey = "cloud-config"
+	providerCredentialsKey = "credentials"
+	providerDir            = "/etc/hydrophone/provider"
+)
+
+// credentialsEnv are the environment variables pointing the SDKs of the
+// providers to the file of --provider-credentials
+var credentialsEnv = map[string]string{
+	"gce": "GOOGLE_APPLICATION_CREDENTIALS",
+	"gke": "GOOGLE_APPLICATION_CREDENTIALS",
+	"aws": "AWS_SHARED_CREDENTIALS_FILE",
+	"eks": "AWS_SHARED_CREDENTIALS_FILE",
+}
+
+// providerSecretNeeded reports whether the run creates the provider secret
+func providerSecretNeeded() bool {
+	return settings.GetString("cloud-config-file") != "" || settings.GetString("provider-credentials") != ""
+}
+
+// ProviderSecret returns the definition of the secret holding the files of
+// --cloud-config-file and --provider-credentials.
+func ProviderSecret(namespace string) (*v1.Secret, error) {
+	data := map[string][]byte{}
+	for key, flag := range map[string]string{
+		providerCloudConfigKey: "cloud-config-file",
+		providerCredentialsKey: "provider-credentials",
+	} {
+		file := settings.GetString(flag)
+		if file == "" {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		data[key] = content
+	}
+	return &v1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      common.ProviderSecretName,
+			Namespace: namespace,
+			Labels:    common.ConformanceLabels(),
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: data,
+	}, nil
+}
+
+// providerArgs returns the arguments of the e2e test binary selecting the
+// cloud provider of --provider and its settings
+func providerArgs() []string {
+	var args []string
+	for _, flag := range []string{"provider", "gce-project", "gce-zone", "gce-region"} {
+		if value := settings.GetString(flag); value != "" {
+			args = append(args, "--"+flag+"="+value)
+		}
+	}
+	if settings.GetString("cloud-config-file") != "" {
+		args = append(args, "--cloud-config-file="+path.Join(providerDir, providerCloudConfigKey))
+	}
+	return args
+}
+
+// applyProvider mounts the provider secret into the conformance container and
+// points the SDK of the provider to the credentials file.
+func applyProvider(pod *v1.Pod) {
+	if !providerSecretNeeded() {
+		return
+	}
+	mountVolume(pod, common.ConformanceContainer,
+		v1.Volume{
+			Name: "provider-volume",
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{SecretName: common.ProviderSecretName},
+			},
+		},
+		v1.VolumeMount{
+			MountPath: providerDir,
+			ReadOnly:  true,
+		})
+
+	name, ok := credentialsEnv[settings.GetString("provider")]
+	if !ok || settings.GetString("provider-credentials") == "" {
+		return
+	}
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == common.ConformanceContainer {
+			setEnv(&pod.Spec.Containers[i], name, path.Join(providerDir, providerCredentialsKey))
+		}
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestProvider(t *testing.T) {
+	dir := t.TempDir()
+	cloudConfig := filepath.Join(dir, "gce.conf")
+	credentials := filepath.Join(dir, "key.json")
+	require.NoError(t, os.WriteFile(cloudConfig, []byte("[global]\n"), 0600))
+	require.NoError(t, os.WriteFile(credentials, []byte("{}"), 0600))
+	settings.Set("provider", "gce")
+	settings.Set("gce-zone", "europe-west1-b")
+	settings.Set("cloud-config-file", cloudConfig)
+	settings.Set("provider-credentials", credentials)
+	defer func() {
+		for _, flag := range []string{"provider", "gce-zone", "cloud-config-file", "provider-credentials"} {
+			settings.Set(flag, "")
+		}
+	}()
+
+	secret, err := ProviderSecret("conformance")
+	require.NoError(t, err)
+	assert.Equal(t, common.ProviderSecretName, secret.Name)
+	assert.Equal(t, map[string][]byte{
+		providerCloudConfigKey: []byte("[global]\n"),
+		providerCredentialsKey: []byte("{}"),
+	}, secret.Data)
+
+	assert.Equal(t, []string{
+		"--provider=gce",
+		"--gce-zone=europe-west1-b",
+		"--cloud-config-file=/etc/hydrophone/provider/cloud-config",
+	}, providerArgs())
+
+	pods, err := Pods("conformance")
+	require.NoError(t, err)
+	container := pods[0].Spec.Containers[0]
+	assert.Contains(t, container.VolumeMounts, v1.VolumeMount{Name: "provider-volume", MountPath: providerDir, ReadOnly: true})
+	assert.Contains(t, container.Env, v1.EnvVar{Name: "GOOGLE_APPLICATION_CREDENTIALS", Value: "/etc/hydrophone/provider/credentials"})
+	assert.Contains(t, container.Env, v1.EnvVar{Name: "E2E_EXTRA_ARGS", Value: "--provider=gce --gce-zone=europe-west1-b --cloud-config-file=/etc/hydrophone/provider/cloud-config"})
+
+	settings.Set("provider-credentials", filepath.Join(dir, "missing.json"))
+	_, err = ProviderSecret("conformance")
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// PullSecret returns the definition of the image pull secret holding the
+// docker config file of --docker-config.
+func PullSecret(namespace string) (*v1.Secret, error) {
+	path := settings.GetString("docker-config")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing docker config file %s: %w", path, err)
+	}
+	if len(config.Auths) == 0 {
+		return nil, fmt.Errorf("docker config file %s has no auths", path)
+	}
+	return &v1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      common.PullSecretName,
+			Namespace: namespace,
+			Labels:    common.ConformanceLabels(),
+		},
+		Type: v1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			v1.DockerConfigJsonKey: data,
+		},
+	}, nil
+}
+
+// imagePullSecrets returns the secrets of --image-pull-secret, along with the
+// secret created from --docker-config
+func imagePullSecrets() []v1.LocalObjectReference {
+	var secrets []v1.LocalObjectReference
+	for _, name := range settings.GetStringSlice("image-pull-secret") {
+		secrets = append(secrets, v1.LocalObjectReference{Name: name})
+	}
+	if settings.GetString("docker-config") != "" {
+		secrets = append(secrets, v1.LocalObjectReference{Name: common.PullSecretName})
+	}
+	return secrets
+}
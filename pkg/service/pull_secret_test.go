@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestPullSecret(t *testing.T) {
+	dockerConfig := filepath.Join(t.TempDir(), "config.json")
+	data := []byte(`{"auths":{"mirror.example.com":{"auth":"dXNlcjpwYXNz"}}}`)
+	require.NoError(t, os.WriteFile(dockerConfig, data, 0600))
+	settings.Set("docker-config", dockerConfig)
+	settings.Set("image-pull-secret", []string{"mirror"})
+	defer settings.Set("docker-config", "")
+	defer settings.Set("image-pull-secret", []string{})
+
+	secret, err := PullSecret("conformance")
+	require.NoError(t, err)
+	assert.Equal(t, v1.SecretTypeDockerConfigJson, secret.Type)
+	assert.Equal(t, data, secret.Data[v1.DockerConfigJsonKey])
+
+	pod := ConformancePod("conformance")
+	assert.Equal(t, []v1.LocalObjectReference{{Name: "mirror"}, {Name: common.PullSecretName}}, pod.Spec.ImagePullSecrets)
+
+	require.NoError(t, os.WriteFile(dockerConfig, []byte(`{"credsStore":"desktop"}`), 0600))
+	_, err = PullSecret("conformance")
+	assert.Error(t, err)
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// ManagedRBAC reports whether hydrophone creates the namespace, the service
+// account and the RBAC resources of the run, i.e. --service-account wasn't given.
+func ManagedRBAC() bool {
+	return settings.GetString("service-account") == ""
+}
+
+// ServiceAccountName returns the name of the service account the conformance
+// pods run as.
+func ServiceAccountName() string {
+	if name := settings.GetString("service-account"); name != "" {
+		return name
+	}
+	return common.ServiceAccountName
+}
+
+// SetupRBAC creates the service account of the conformance pods and binds the
+// cluster role of the conformance tests to it. With --service-account it
+// checks that the given service account exists in the namespace instead, the
+// permissions granted to it are up to the cluster administrator.
+func SetupRBAC(clientset kubernetes.Interface, namespace string) error {
+	if !ManagedRBAC() {
+		// the namespace is checked through its service account with
+		// --restricted
+		if !common.Restricted() {
+			if _, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err != nil {
+				return fmt.Errorf("namespace %s of --service-account: %w", namespace, err)
+			}
+		}
+		sa, err := clientset.CoreV1().ServiceAccounts(namespace).Get(ctx, ServiceAccountName(), metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("service account of --service-account: %w", err)
+		}
+		log.Printf("using serviceaccount %s/%s\n", sa.Namespace, sa.Name)
+		return nil
+	}
+
+	conformanceSA := ServiceAccount(namespace)
+	conformanceClusterRole, err := ClusterRole()
+	if err != nil {
+		return err
+	}
+	conformanceClusterRoleBinding := ClusterRoleBinding(namespace)
+	// the cluster role and its binding are garbage collected with the
+	// namespace when the run doesn't delete them, e.g. once it was killed
+	ns, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	conformanceClusterRole.OwnerReferences = namespaceOwner(ns)
+	conformanceClusterRoleBinding.OwnerReferences = namespaceOwner(ns)
+
+	sa, err := clientset.CoreV1().ServiceAccounts(namespace).Create(ctx, conformanceSA, metav1.CreateOptions{})
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			return fmt.Errorf("serviceaccount already exist %s. Please run cleanup first", conformanceSA.ObjectMeta.Name)
+		}
+		return err
+	}
+	log.Printf("serviceaccount created %s\n", sa.Name)
+
+	clusterRole, err := clientset.RbacV1().ClusterRoles().Create(ctx, conformanceClusterRole, metav1.CreateOptions{})
+	if err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return err
+		}
+		log.Printf("clusterrole already exist %s", conformanceClusterRole.ObjectMeta.Name)
+	} else {
+		log.Printf("clusterrole created %s\n", clusterRole.Name)
+	}
+
+	clusterRoleBinding, err := clientset.RbacV1().ClusterRoleBindings().Create(ctx, conformanceClusterRoleBinding, metav1.CreateOptions{})
+	if err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return err
+		}
+		log.Printf("clusterrolebinding already exist %s", conformanceClusterRoleBinding.ObjectMeta.Name)
+	} else {
+		log.Printf("clusterrolebinding created %s\n", clusterRoleBinding.Name)
+	}
+	return nil
+}
+
+// namespaceOwner returns the owner references making the namespace of the run
+// the owner of a resource, so that the garbage collector of the cluster
+// deletes the resource with the namespace.
+func namespaceOwner(ns *v1.Namespace) []metav1.OwnerReference {
+	return []metav1.OwnerReference{{
+		APIVersion: "v1",
+		Kind:       "Namespace",
+		Name:       ns.Name,
+		UID:        ns.UID,
+	}}
+}
+
+// CleanupRBAC deletes the cluster role binding, the cluster role and the
+// service account created by SetupRBAC.
+func CleanupRBAC(clientset kubernetes.Interface, namespace string) {
+	clusterRoleBindingName := common.WithRunID(common.ClusterRoleBindingName)
+	clusterRoleName := common.WithRunID(common.ClusterRoleName)
+	err := clientset.RbacV1().ClusterRoleBindings().Delete(ctx, clusterRoleBindingName, metav1.DeleteOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Printf("clusterrolebinding %s doesn't exist\n", clusterRoleBindingName)
+		} else {
+			log.Fatal(err)
+		}
+	}
+	log.Printf("clusterrolebinding deleted %s\n", clusterRoleBindingName)
+
+	err = clientset.RbacV1().ClusterRoles().Delete(ctx, clusterRoleName, metav1.DeleteOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Printf("clusterrole %s doesn't exist\n", clusterRoleName)
+		} else {
+			log.Fatal(err)
+		}
+	}
+	log.Printf("clusterrole deleted %s\n", clusterRoleName)
+
+	err = clientset.CoreV1().ServiceAccounts(namespace).Delete(ctx, common.ServiceAccountName, metav1.DeleteOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Printf("serviceaccount %s doesn't exist\n", common.ServiceAccountName)
+		} else {
+			log.Fatal(err)
+		}
+	}
+	log.Printf("serviceaccount deleted %s\n", common.ServiceAccountName)
+}
+
+// ServiceAccount returns the definition of the service account the
+// conformance pods run as.
+func ServiceAccount(namespace string) *v1.ServiceAccount {
+	return &v1.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    common.ConformanceLabels(),
+			Name:      common.ServiceAccountName,
+			Namespace: namespace,
+		},
+	}
+}
+
+// ClusterRole returns the definition of the cluster role granted to the
+// conformance pods, with the rules of the plugin of --plugin when it has any.
+// Its name carries the ID of --run-id, like the one of the binding.
+func ClusterRole() (*rbac.ClusterRole, error) {
+	clusterRole := &rbac.ClusterRole{
+		TypeMeta: metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"},
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: common.ConformanceLabels(),
+			Name:   common.WithRunID(common.ClusterRoleName),
+		},
+		// the conformance tests exercise every API group and the RBAC tests
+		// can only grant the permissions the service account holds itself
+		Rules: []rbac.PolicyRule{
+			{
+				APIGroups: []string{"*"},
+				Resources: []string{"*"},
+				Verbs:     []string{"*"},
+			},
+			{
+				NonResourceURLs: []string{"/metrics", "/logs", "/logs/*"},
+				Verbs:           []string{"get"},
+			},
+		},
+	}
+	p, err := Plugin()
+	if err != nil {
+		return nil, err
+	}
+	if p != nil && len(p.RBAC) != 0 {
+		clusterRole.Rules = p.RBAC
+	}
+	return clusterRole, nil
+}
+
+// ClusterRoleBinding returns the definition of the binding of the cluster
+// role to the service account of the namespace.
+func ClusterRoleBinding(namespace string) *rbac.ClusterRoleBinding {
+	return &rbac.ClusterRoleBinding{
+		TypeMeta: metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding"},
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: common.ConformanceLabels(),
+			Name:   common.WithRunID(common.ClusterRoleBindingName),
+		},
+		RoleRef: rbac.RoleRef{
+			APIGroup: "rbac.authorization.k8s.io",
+			Kind:     "ClusterRole",
+			Name:     common.WithRunID(common.ClusterRoleName),
+		},
+		Subjects: []rbac.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      common.ServiceAccountName,
+				Namespace: namespace,
+			},
+		},
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// RecreatePod replaces a lost conformance pod with a new pod of the same name
+// that isn't scheduled to the node the lost pod ran on. The replacement
+// additionally skips the tests matching skip, e.g. the ones that completed in
+// the lost pod. When the combined skip doesn't fit in an environment
+// variable, the replacement runs the tests of the lost pod again instead.
+func RecreatePod(clientset kubernetes.Interface, podName, skip string) error {
+	namespace := settings.GetString("namespace")
+	pods := clientset.CoreV1().Pods(namespace)
+	lost, err := pods.Get(ctx, podName, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		// the kubelet of a failed node never confirms the deletion
+		var gracePeriod int64
+		if err := pods.Delete(ctx, podName, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		err = wait.PollUntilContextTimeout(ctx, time.Second, 5*time.Minute, true, func(ctx context.Context) (bool, error) {
+			_, err := pods.Get(ctx, podName, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		})
+		if err != nil {
+			return fmt.Errorf("error waiting for pod %s to be deleted: %w", podName, err)
+		}
+	}
+
+	definitions, err := Pods(namespace)
+	if err != nil {
+		return err
+	}
+	for _, pod := range definitions {
+		if pod.Name != podName {
+			continue
+		}
+		if skip != "" {
+			// the container can't be started with a longer variable
+			if joined := joinSkip(envValue(pod.Spec.Containers[0], "E2E_SKIP"), skip); len(joined) > common.MaxFocusLength {
+				log.Printf("skipping the completed specs in pod %s takes %d bytes, more than %d bytes, running all its specs again", podName, len(joined), common.MaxFocusLength)
+			} else {
+				setEnv(&pod.Spec.Containers[0], "E2E_SKIP", joined)
+			}
+		}
+		if lost != nil && lost.Spec.NodeName != "" {
+			avoidNode(pod, lost.Spec.NodeName)
+		}
+		if _, err := createPod(clientset, pod); err != nil {
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("pod %s is not a conformance pod of the run", podName)
+}
+
+// avoidNode keeps the pod from being scheduled to the node. The conformance
+// pods tolerate every taint, including the ones of nodes that aren't ready.
+func avoidNode(pod *v1.Pod, nodeName string) {
+	requirement := v1.NodeSelectorRequirement{
+		Key:      "metadata.name",
+		Operator: v1.NodeSelectorOpNotIn,
+		Values:   []string{nodeName},
+	}
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &v1.Affinity{}
+	}
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &v1.NodeAffinity{}
+	}
+	required := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil || len(required.NodeSelectorTerms) == 0 {
+		pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &v1.NodeSelector{
+			NodeSelectorTerms: []v1.NodeSelectorTerm{{MatchFields: []v1.NodeSelectorRequirement{requirement}}},
+		}
+		return
+	}
+	// the terms are ORed, each of them has to exclude the node
+	for i := range required.NodeSelectorTerms {
+		required.NodeSelectorTerms[i].MatchFields = append(required.NodeSelectorTerms[i].MatchFields, requirement)
+	}
+}
+
+// envValue returns the value of the environment variable of the container
+func envValue(container v1.Container, name string) string {
+	for _, env := range container.Env {
+		if env.Name == name {
+			return env.Value
+		}
+	}
+	return ""
+}
+
+// joinSkip combines two skip expressions into one matching either
+func joinSkip(skip, other string) string {
+	if skip == "" {
+		return other
+	}
+	return skip + "|" + other
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestAvoidNode(t *testing.T) {
+	pod := &v1.Pod{}
+	avoidNode(pod, "node-1")
+	assert.Equal(t, []v1.NodeSelectorTerm{{MatchFields: []v1.NodeSelectorRequirement{
+		{Key: "metadata.name", Operator: v1.NodeSelectorOpNotIn, Values: []string{"node-1"}},
+	}}}, pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms)
+
+	zone := v1.NodeSelectorRequirement{Key: "topology.kubernetes.io/zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a"}}
+	pod = &v1.Pod{Spec: v1.PodSpec{Affinity: &v1.Affinity{NodeAffinity: &v1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{
+			{MatchExpressions: []v1.NodeSelectorRequirement{zone}},
+		}},
+	}}}}
+	avoidNode(pod, "node-1")
+	terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	require.Len(t, terms, 1)
+	assert.Equal(t, []v1.NodeSelectorRequirement{zone}, terms[0].MatchExpressions)
+	assert.Equal(t, "node-1", terms[0].MatchFields[0].Values[0])
+}
+
+func TestRecreatePod(t *testing.T) {
+	settings.Set("namespace", "conformance")
+	settings.Set("skip", `\[Slow\]`)
+	defer settings.Set("namespace", "")
+	defer settings.Set("skip", "")
+
+	lost := ConformancePod("conformance")
+	lost.Name = common.PodNames()[0]
+	lost.Spec.NodeName = "node-1"
+	lost.Status.Phase = v1.PodFailed
+	clientset := fake.NewSimpleClientset(lost)
+
+	require.NoError(t, RecreatePod(clientset, lost.Name, `^(a)$`))
+
+	pod, err := clientset.CoreV1().Pods("conformance").Get(ctx, lost.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, v1.PodPhase(""), pod.Status.Phase)
+	assert.Equal(t, `\[Slow\]|^(a)$`, envValue(pod.Spec.Containers[0], "E2E_SKIP"))
+	assert.Equal(t, "node-1", pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchFields[0].Values[0])
+}
+
+func TestRecreatePodSkipTooLong(t *testing.T) {
+	settings.Set("namespace", "conformance")
+	settings.Set("skip", `\[Slow\]`)
+	defer settings.Set("namespace", "")
+	defer settings.Set("skip", "")
+
+	lost := ConformancePod("conformance")
+	lost.Name = common.PodNames()[0]
+	lost.Spec.NodeName = "node-1"
+	clientset := fake.NewSimpleClientset(lost)
+
+	// the specs that completed on a long run
+	skip := `^(` + strings.Repeat("a", common.MaxFocusLength) + `)$`
+	require.NoError(t, RecreatePod(clientset, lost.Name, skip))
+
+	pod, err := clientset.CoreV1().Pods("conformance").Get(ctx, lost.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, `\[Slow\]`, envValue(pod.Spec.Containers[0], "E2E_SKIP"))
+	assert.Equal(t, "node-1", pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchFields[0].Values[0])
+}
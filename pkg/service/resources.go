@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// applyResources sets the requests and limits of --conformance-requests,
+// --conformance-limits, --output-requests and --output-limits on the
+// containers of the pod.
+func applyResources(pod *v1.Pod) error {
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		var prefix string
+		switch container.Name {
+		case common.ConformanceContainer:
+			prefix = "conformance"
+		case common.OutputContainer:
+			prefix = "output"
+		default:
+			continue
+		}
+
+		requests, err := parseResources(prefix+"-requests", settings.GetStringSlice(prefix+"-requests"))
+		if err != nil {
+			return err
+		}
+		limits, err := parseResources(prefix+"-limits", settings.GetStringSlice(prefix+"-limits"))
+		if err != nil {
+			return err
+		}
+		for name, quantity := range limits {
+			if request, ok := requests[name]; ok && request.Cmp(quantity) > 0 {
+				return fmt.Errorf("--%s-requests %s=%s exceeds --%s-limits %s=%s", prefix, name, request.String(), prefix, name, quantity.String())
+			}
+		}
+		if len(requests) != 0 {
+			container.Resources.Requests = requests
+		}
+		if len(limits) != 0 {
+			container.Resources.Limits = limits
+		}
+	}
+	return nil
+}
+
+// parseResources parses name=quantity values, e.g. cpu=500m or memory=1Gi
+func parseResources(flag string, values []string) (v1.ResourceList, error) {
+	resources := v1.ResourceList{}
+	for _, value := range values {
+		name, q, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("expected --%s to be of name=quantity format, e.g. cpu=500m, got %q", flag, value)
+		}
+		quantity, err := resource.ParseQuantity(q)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity in --%s %q: %w", flag, value, err)
+		}
+		resources[v1.ResourceName(name)] = quantity
+	}
+	return resources, nil
+}
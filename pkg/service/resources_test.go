@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestApplyResources(t *testing.T) {
+	reset := func() {
+		for _, key := range []string{"conformance-requests", "conformance-limits", "output-requests", "output-limits"} {
+			settings.Set(key, []string{})
+		}
+	}
+	defer reset()
+
+	settings.Set("conformance-requests", []string{"cpu=1", "memory=2Gi"})
+	settings.Set("conformance-limits", []string{"memory=4Gi"})
+	settings.Set("output-requests", []string{"cpu=10m", "memory=16Mi"})
+
+	pod := ConformancePod("conformance")
+	require.NoError(t, applyResources(pod))
+
+	assert.Equal(t, v1.ResourceRequirements{
+		Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("2Gi")},
+		Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("4Gi")},
+	}, pod.Spec.Containers[0].Resources)
+	assert.Equal(t, v1.ResourceRequirements{
+		Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("10m"), v1.ResourceMemory: resource.MustParse("16Mi")},
+	}, pod.Spec.Containers[1].Resources)
+
+	settings.Set("conformance-limits", []string{"memory=1Gi"})
+	assert.EqualError(t, applyResources(ConformancePod("conformance")), "--conformance-requests memory=2Gi exceeds --conformance-limits memory=1Gi")
+
+	reset()
+	settings.Set("output-limits", []string{"cpu"})
+	assert.Error(t, applyResources(ConformancePod("conformance")))
+	settings.Set("output-limits", []string{"cpu=lots"})
+	assert.Error(t, applyResources(ConformancePod("conformance")))
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// CheckPriorityClass fails early when the priority class of --priority-class
+// doesn't exist, which would otherwise leave the pods rejected by admission
+// after the other resources of the run were created.
+func CheckPriorityClass(clientset kubernetes.Interface) error {
+	name := settings.GetString("priority-class")
+	if name == "" || common.Restricted() {
+		return nil
+	}
+	class, err := clientset.SchedulingV1().PriorityClasses().Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("priority class %s given with --priority-class doesn't exist", name)
+	}
+	if err != nil {
+		return fmt.Errorf("error getting priority class %s: %w", name, err)
+	}
+	policy := v1.PreemptLowerPriority
+	if class.PreemptionPolicy != nil {
+		policy = *class.PreemptionPolicy
+	}
+	log.Printf("Conformance pods run with priority class %s, priority %d, preemption policy %s", class.Name, class.Value, policy)
+	return nil
+}
+
+// CheckRuntimeClass fails early when the runtime class of --runtime-class
+// doesn't exist, which would otherwise leave the pods rejected by admission
+// after the other resources of the run were created.
+func CheckRuntimeClass(clientset kubernetes.Interface) error {
+	name := settings.GetString("runtime-class")
+	if name == "" || common.Restricted() {
+		return nil
+	}
+	class, err := clientset.NodeV1().RuntimeClasses().Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("runtime class %s given with --runtime-class doesn't exist", name)
+	}
+	if err != nil {
+		return fmt.Errorf("error getting runtime class %s: %w", name, err)
+	}
+	log.Printf("Conformance pods run with runtime class %s, handler %s", class.Name, class.Handler)
+	return nil
+}
+
+// applyScheduling applies --node-selector, --toleration, --affinity-file,
+// --priority-class and --runtime-class to the pod. The given tolerations
+// replace the default one tolerating every taint.
+func applyScheduling(pod *v1.Pod) error {
+	selector, err := parseNodeSelector(settings.GetStringSlice("node-selector"))
+	if err != nil {
+		return err
+	}
+	for key, value := range selector {
+		if pod.Spec.NodeSelector == nil {
+			pod.Spec.NodeSelector = map[string]string{}
+		}
+		pod.Spec.NodeSelector[key] = value
+	}
+
+	if values := settings.GetStringSlice("toleration"); len(values) != 0 {
+		var tolerations []v1.Toleration
+		for _, value := range values {
+			toleration, err := parseToleration(value)
+			if err != nil {
+				return err
+			}
+			tolerations = append(tolerations, toleration)
+		}
+		pod.Spec.Tolerations = tolerations
+	}
+
+	if affinityFile := settings.GetString("affinity-file"); affinityFile != "" {
+		data, err := os.ReadFile(affinityFile)
+		if err != nil {
+			return err
+		}
+		affinity := &v1.Affinity{}
+		if err := yaml.UnmarshalStrict(data, affinity); err != nil {
+			return fmt.Errorf("error parsing affinity file %s: %w", affinityFile, err)
+		}
+		pod.Spec.Affinity = affinity
+	}
+
+	pod.Spec.PriorityClassName = settings.GetString("priority-class")
+	if runtimeClass := settings.GetString("runtime-class"); runtimeClass != "" {
+		pod.Spec.RuntimeClassName = &runtimeClass
+	}
+	return nil
+}
+
+// parseNodeSelector parses key=value labels
+func parseNodeSelector(values []string) (map[string]string, error) {
+	return parseKeyValues("node-selector", values)
+}
+
+// parseKeyValues parses the key=value pairs of the flag
+func parseKeyValues(flag string, values []string) (map[string]string, error) {
+	pairs := map[string]string{}
+	for _, value := range values {
+		k, v, ok := strings.Cut(value, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("expected --%s to be of key=value format, got %q", flag, value)
+		}
+		pairs[k] = v
+	}
+	return pairs, nil
+}
+
+// parseToleration parses a toleration in the format of the taints of kubectl,
+// key[=value][:effect]. Without value the toleration matches any value of the
+// key, without effect it matches all effects.
+func parseToleration(value string) (v1.Toleration, error) {
+	toleration := v1.Toleration{Operator: v1.TolerationOpExists}
+	spec := value
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		toleration.Effect = v1.TaintEffect(spec[i+1:])
+		spec = spec[:i]
+		switch toleration.Effect {
+		case v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+		default:
+			return v1.Toleration{}, fmt.Errorf("invalid effect in --toleration %q, expected %s, %s or %s",
+				value, v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute)
+		}
+	}
+	key, tolerated, hasValue := strings.Cut(spec, "=")
+	if key == "" {
+		return v1.Toleration{}, fmt.Errorf("expected --toleration to be of key[=value][:effect] format, got %q", value)
+	}
+	toleration.Key = key
+	if hasValue {
+		toleration.Operator = v1.TolerationOpEqual
+		toleration.Value = tolerated
+	}
+	return toleration, nil
+}
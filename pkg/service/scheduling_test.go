@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestParseToleration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    v1.Toleration
+		wantErr bool
+	}{
+		{
+			value: "dedicated=conformance:NoSchedule",
+			want:  v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "conformance", Effect: v1.TaintEffectNoSchedule},
+		},
+		{
+			value: "node-role.kubernetes.io/control-plane:NoSchedule",
+			want:  v1.Toleration{Key: "node-role.kubernetes.io/control-plane", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+		},
+		{
+			value: "dedicated=conformance",
+			want:  v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "conformance"},
+		},
+		{
+			value: "gpu",
+			want:  v1.Toleration{Key: "gpu", Operator: v1.TolerationOpExists},
+		},
+		{value: "dedicated=conformance:Never", wantErr: true},
+		{value: ":NoSchedule", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseToleration(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestApplyScheduling(t *testing.T) {
+	affinityFile := filepath.Join(t.TempDir(), "affinity.yaml")
+	require.NoError(t, os.WriteFile(affinityFile, []byte(`
+nodeAffinity:
+  requiredDuringSchedulingIgnoredDuringExecution:
+    nodeSelectorTerms:
+    - matchExpressions:
+      - key: pool
+        operator: In
+        values: [conformance]
+`), 0644))
+
+	settings.Set("arch", "arm64")
+	settings.Set("node-selector", []string{"pool=conformance"})
+	settings.Set("toleration", []string{"dedicated=conformance:NoSchedule"})
+	settings.Set("affinity-file", affinityFile)
+	settings.Set("priority-class", "conformance")
+	settings.Set("runtime-class", "gvisor")
+	defer func() {
+		settings.Set("arch", "")
+		settings.Set("node-selector", []string{})
+		settings.Set("toleration", []string{})
+		settings.Set("affinity-file", "")
+		settings.Set("priority-class", "")
+		settings.Set("runtime-class", "")
+	}()
+
+	pod := ConformancePod("conformance")
+	require.NoError(t, applyScheduling(pod))
+
+	assert.Equal(t, map[string]string{v1.LabelArchStable: "arm64", "pool": "conformance"}, pod.Spec.NodeSelector)
+	assert.Equal(t, []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "conformance", Effect: v1.TaintEffectNoSchedule}}, pod.Spec.Tolerations)
+	require.NotNil(t, pod.Spec.Affinity.NodeAffinity)
+	assert.Equal(t, "pool", pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0].Key)
+	assert.Equal(t, "conformance", pod.Spec.PriorityClassName)
+	require.NotNil(t, pod.Spec.RuntimeClassName)
+	assert.Equal(t, "gvisor", *pod.Spec.RuntimeClassName)
+
+	require.NoError(t, os.WriteFile(affinityFile, []byte("nodeAffinty: {}\n"), 0644))
+	assert.Error(t, applyScheduling(ConformancePod("conformance")))
+}
+
+func TestCheckRuntimeClass(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&nodev1.RuntimeClass{ObjectMeta: metav1.ObjectMeta{Name: "gvisor"}, Handler: "runsc"})
+	defer settings.Set("runtime-class", "")
+
+	assert.NoError(t, CheckRuntimeClass(clientset))
+	settings.Set("runtime-class", "gvisor")
+	assert.NoError(t, CheckRuntimeClass(clientset))
+	settings.Set("runtime-class", "kata")
+	assert.EqualError(t, CheckRuntimeClass(clientset), "runtime class kata given with --runtime-class doesn't exist")
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// nobodyUser is the user of the containers that don't need to run as root
+const nobodyUser int64 = 65534
+
+// restrictedPodSecurityContext returns the security context of the pods
+// created by hydrophone, with the default seccomp profile of the runtime
+func restrictedPodSecurityContext() *v1.PodSecurityContext {
+	return &v1.PodSecurityContext{
+		SeccompProfile: &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+	}
+}
+
+// restrictedSecurityContext returns the security context of a container
+// without privilege escalation and capabilities besides the given ones. With
+// a user other than root the container is required to run as non-root.
+func restrictedSecurityContext(user int64, capabilities []v1.Capability) *v1.SecurityContext {
+	allowPrivilegeEscalation := false
+	securityContext := &v1.SecurityContext{
+		AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		Capabilities: &v1.Capabilities{
+			Drop: []v1.Capability{"ALL"},
+			Add:  capabilities,
+		},
+	}
+	if user != 0 {
+		runAsNonRoot := true
+		securityContext.RunAsUser = &user
+		securityContext.RunAsNonRoot = &runAsNonRoot
+	}
+	return securityContext
+}
+
+// applySecurity sets the restricted security contexts on the pod unless
+// --security-profile is unrestricted. The output container runs as nobody,
+// the conformance container as the user of --run-as-user, by default the
+// user of the image, with the capabilities of --add-capability.
+func applySecurity(pod *v1.Pod) error {
+	if settings.GetString("security-profile") == common.SecurityUnrestricted {
+		return nil
+	}
+	user := settings.GetInt64("run-as-user")
+	if user < 0 {
+		return fmt.Errorf("expected --run-as-user not to be negative, got %d", user)
+	}
+	var capabilities []v1.Capability
+	for _, capability := range settings.GetStringSlice("add-capability") {
+		capabilities = append(capabilities, v1.Capability(strings.TrimPrefix(strings.ToUpper(capability), "CAP_")))
+	}
+
+	pod.Spec.SecurityContext = restrictedPodSecurityContext()
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		switch container.Name {
+		case common.ConformanceContainer:
+			container.SecurityContext = restrictedSecurityContext(user, capabilities)
+		case common.OutputContainer:
+			container.SecurityContext = restrictedSecurityContext(nobodyUser, nil)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestApplySecurity(t *testing.T) {
+	defer func() {
+		settings.Set("security-profile", "restricted")
+		settings.Set("run-as-user", 0)
+		settings.Set("add-capability", []string{})
+	}()
+
+	settings.Set("security-profile", "restricted")
+	pod := ConformancePod("conformance")
+	require.NoError(t, applySecurity(pod))
+	assert.Equal(t, v1.SeccompProfileTypeRuntimeDefault, pod.Spec.SecurityContext.SeccompProfile.Type)
+	conformance := pod.Spec.Containers[0].SecurityContext
+	assert.False(t, *conformance.AllowPrivilegeEscalation)
+	assert.Equal(t, []v1.Capability{"ALL"}, conformance.Capabilities.Drop)
+	assert.Nil(t, conformance.RunAsNonRoot)
+	output := pod.Spec.Containers[1].SecurityContext
+	assert.True(t, *output.RunAsNonRoot)
+	assert.Equal(t, nobodyUser, *output.RunAsUser)
+
+	settings.Set("run-as-user", 1000)
+	settings.Set("add-capability", []string{"cap_net_raw"})
+	pod = ConformancePod("conformance")
+	require.NoError(t, applySecurity(pod))
+	conformance = pod.Spec.Containers[0].SecurityContext
+	assert.Equal(t, int64(1000), *conformance.RunAsUser)
+	assert.True(t, *conformance.RunAsNonRoot)
+	assert.Equal(t, []v1.Capability{"NET_RAW"}, conformance.Capabilities.Add)
+
+	settings.Set("run-as-user", -1)
+	assert.Error(t, applySecurity(ConformancePod("conformance")))
+
+	settings.Set("security-profile", "unrestricted")
+	pod = ConformancePod("conformance")
+	require.NoError(t, applySecurity(pod))
+	assert.Nil(t, pod.Spec.SecurityContext)
+	assert.Nil(t, pod.Spec.Containers[0].SecurityContext)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"io"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+)
+
+// DryRunResult is the outcome of the server-side dry run of a resource of
+// the run
+type DryRunResult struct {
+	// Kind and Name identify the resource, Name is namespace/name for the
+	// namespaced resources
+	Kind string
+	Name string
+	// Err is the error the API server rejected the resource with
+	Err error
+}
+
+// DryRunServer submits the resources of the run to the API server with the
+// server-side dry run option, so that they go through validation and the
+// admission webhooks and policies of the cluster without being persisted.
+// The API server can't dry run resources in a namespace that doesn't
+// exist, so the namespace of the run is created once its dry run passed and
+// deleted after the namespaced resources were submitted.
+func DryRunServer(clientset kubernetes.Interface, objects []runtime.Object) []DryRunResult {
+	var results []DryRunResult
+	var namespaced []runtime.Object
+	for _, object := range objects {
+		if _, ok := object.(*v1.Namespace); !ok && isNamespaced(object) {
+			namespaced = append(namespaced, object)
+			continue
+		}
+		results = append(results, dryRunCreate(clientset, object))
+		ns, ok := object.(*v1.Namespace)
+		if !ok || results[len(results)-1].Err != nil {
+			continue
+		}
+		if _, err := clientset.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{}); err != nil {
+			results[len(results)-1].Err = fmt.Errorf("passed the dry run, but creating it to dry run the resources in it failed: %w", err)
+			continue
+		}
+		defer func() {
+			if err := clientset.CoreV1().Namespaces().Delete(ctx, ns.Name, metav1.DeleteOptions{}); err != nil {
+				log.Printf("unable to delete namespace %s: %v", ns.Name, err)
+			}
+		}()
+	}
+	for _, object := range namespaced {
+		results = append(results, dryRunCreate(clientset, object))
+	}
+	return results
+}
+
+// isNamespaced reports whether the resource lives in a namespace
+func isNamespaced(object runtime.Object) bool {
+	switch object.(type) {
+	case *v1.Namespace, *rbac.ClusterRole, *rbac.ClusterRoleBinding:
+		return false
+	}
+	return true
+}
+
+// dryRunCreate submits the creation of the resource with the server-side dry
+// run option
+func dryRunCreate(clientset kubernetes.Interface, object runtime.Object) DryRunResult {
+	result := DryRunResult{Kind: object.GetObjectKind().GroupVersionKind().Kind}
+	if accessor, err := meta.Accessor(object); err == nil {
+		result.Name = accessor.GetName()
+		if accessor.GetNamespace() != "" {
+			result.Name = accessor.GetNamespace() + "/" + result.Name
+		}
+	}
+	opts := metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}}
+	switch o := object.(type) {
+	case *v1.Namespace:
+		_, result.Err = clientset.CoreV1().Namespaces().Create(ctx, o, opts)
+	case *v1.ServiceAccount:
+		_, result.Err = clientset.CoreV1().ServiceAccounts(o.Namespace).Create(ctx, o, opts)
+	case *v1.Secret:
+		_, result.Err = clientset.CoreV1().Secrets(o.Namespace).Create(ctx, o, opts)
+	case *v1.ConfigMap:
+		_, result.Err = clientset.CoreV1().ConfigMaps(o.Namespace).Create(ctx, o, opts)
+	case *v1.Pod:
+		_, result.Err = clientset.CoreV1().Pods(o.Namespace).Create(ctx, o, opts)
+	case *batchv1.Job:
+		_, result.Err = clientset.BatchV1().Jobs(o.Namespace).Create(ctx, o, opts)
+	case *rbac.ClusterRole:
+		_, result.Err = clientset.RbacV1().ClusterRoles().Create(ctx, o, opts)
+	case *rbac.ClusterRoleBinding:
+		_, result.Err = clientset.RbacV1().ClusterRoleBindings().Create(ctx, o, opts)
+	default:
+		result.Err = fmt.Errorf("unsupported resource %T", object)
+	}
+	return result
+}
+
+// WriteDryRunResults writes the outcome of the dry run of each resource.
+func WriteDryRunResults(w io.Writer, results []DryRunResult) error {
+	for _, result := range results {
+		var err error
+		if result.Err == nil {
+			_, err = fmt.Fprintf(w, "ok    %s %s\n", result.Kind, result.Name)
+		} else {
+			_, err = fmt.Fprintf(w, "FAIL  %s %s: %v\n", result.Kind, result.Name, result.Err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDryRunServer(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	var creates []string
+	clientset.PrependReactor("create", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		resource := action.GetResource().Resource
+		creates = append(creates, resource)
+		switch {
+		case resource == "pods":
+			return true, nil, errors.New(`admission webhook "validation.gatekeeper.sh" denied the request`)
+		case resource == "namespaces" && len(creates) > 1:
+			// the namespace is created for real after its dry run
+			return false, nil, nil
+		}
+		return true, action.(k8stesting.CreateAction).GetObject(), nil
+	})
+
+	objects := []runtime.Object{
+		&v1.Namespace{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"}, ObjectMeta: metav1.ObjectMeta{Name: "conformance"}},
+		ServiceAccount("conformance"),
+		&rbac.ClusterRole{TypeMeta: metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"}, ObjectMeta: metav1.ObjectMeta{Name: "conformance-serviceaccount"}},
+		ConformancePod("conformance"),
+	}
+	results := DryRunServer(clientset, objects)
+
+	// the namespaced resources are submitted once the namespace exists
+	assert.Equal(t, []string{"namespaces", "namespaces", "clusterroles", "serviceaccounts", "pods"}, creates)
+	require.Len(t, results, 4)
+	assert.Equal(t, DryRunResult{Kind: "Namespace", Name: "conformance"}, results[0])
+	assert.Equal(t, "ServiceAccount", results[2].Kind)
+	assert.NoError(t, results[2].Err)
+	assert.Equal(t, "conformance/e2e-conformance-test", results[3].Name)
+	assert.Error(t, results[3].Err)
+
+	// the namespace created for the dry run is deleted
+	_, err := clientset.CoreV1().Namespaces().Get(ctx, "conformance", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	var out bytes.Buffer
+	require.NoError(t, WriteDryRunResults(&out, results))
+	assert.Equal(t, `ok    Namespace conformance
+ok    ClusterRole conformance-serviceaccount
+ok    ServiceAccount conformance/conformance-serviceaccount
+FAIL  Pod conformance/e2e-conformance-test: admission webhook "validation.gatekeeper.sh" denied the request
+`, out.String())
+}
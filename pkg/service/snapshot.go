@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// cniDaemonSets maps the prefixes of the names of the daemon sets of network
+// plugins to the plugin
+var cniDaemonSets = []struct {
+	prefix string
+	cni    string
+}{
+	{"aws-node", "aws-vpc-cni"},
+	{"azure-cni", "azure-cni"},
+	{"antrea", "antrea"},
+	{"calico", "calico"},
+	{"canal", "canal"},
+	{"cilium", "cilium"},
+	{"flannel", "flannel"},
+	{"kube-flannel", "flannel"},
+	{"kindnet", "kindnet"},
+	{"kube-router", "kube-router"},
+	{"multus", "multus"},
+	{"ovnkube", "ovn-kubernetes"},
+	{"weave-net", "weave"},
+}
+
+// ClusterSnapshot describes the cluster: its name in the kubeconfig, the
+// cloud provider of its nodes, its network plugins and its nodes grouped by
+// operating system, architecture, kubelet version and container runtime.
+func ClusterSnapshot(clientset kubernetes.Interface) (*results.Cluster, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing nodes: %w", err)
+	}
+	daemonSets, err := clientset.AppsV1().DaemonSets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing daemon sets: %w", err)
+	}
+
+	cluster := &results.Cluster{Name: settings.GetString("cluster-name"), NodeCount: len(nodes.Items)}
+	var providers []string
+	groups := map[results.NodeGroup]int{}
+	for _, node := range nodes.Items {
+		if provider := nodeProvider(&node); provider != "" && !slices.Contains(providers, provider) {
+			providers = append(providers, provider)
+		}
+		info := node.Status.NodeInfo
+		groups[results.NodeGroup{
+			OS:               info.OperatingSystem,
+			Arch:             info.Architecture,
+			KubeletVersion:   info.KubeletVersion,
+			ContainerRuntime: info.ContainerRuntimeVersion,
+		}]++
+	}
+	sort.Strings(providers)
+	cluster.Provider = strings.Join(providers, ",")
+	for group, count := range groups {
+		group.Count = count
+		cluster.Nodes = append(cluster.Nodes, group)
+	}
+	// the largest groups first
+	sort.Slice(cluster.Nodes, func(i, j int) bool {
+		if cluster.Nodes[i].Count != cluster.Nodes[j].Count {
+			return cluster.Nodes[i].Count > cluster.Nodes[j].Count
+		}
+		return cluster.Nodes[i].String() < cluster.Nodes[j].String()
+	})
+
+	for _, ds := range daemonSets.Items {
+		for _, known := range cniDaemonSets {
+			if strings.HasPrefix(ds.Name, known.prefix) && !slices.Contains(cluster.CNI, known.cni) {
+				cluster.CNI = append(cluster.CNI, known.cni)
+			}
+		}
+	}
+	sort.Strings(cluster.CNI)
+	return cluster, nil
+}
+
+// nodeProvider returns the cloud provider of the node, the scheme of its
+// provider ID, e.g. aws for aws:///us-east-1a/i-0123
+func nodeProvider(node *v1.Node) string {
+	provider, _, ok := strings.Cut(node.Spec.ProviderID, "://")
+	if !ok {
+		return ""
+	}
+	return provider
+}
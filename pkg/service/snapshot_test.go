@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestClusterSnapshot(t *testing.T) {
+	settings.Set("cluster-name", "prod")
+	defer settings.Set("cluster-name", "")
+
+	node := func(name, os, arch, providerID string) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1.NodeSpec{ProviderID: providerID},
+			Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{
+				OperatingSystem: os, Architecture: arch, KubeletVersion: "v1.30.0", ContainerRuntimeVersion: "containerd://1.7.13",
+			}},
+		}
+	}
+	daemonSet := func(namespace, name string) *appsv1.DaemonSet {
+		return &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	}
+	clientset := fake.NewSimpleClientset(
+		node("node-1", "linux", "amd64", "aws:///us-east-1a/i-1"),
+		node("node-2", "linux", "amd64", "aws:///us-east-1b/i-2"),
+		node("node-3", "windows", "amd64", "aws:///us-east-1a/i-3"),
+		daemonSet("kube-system", "cilium"),
+		daemonSet("kube-system", "kube-proxy"),
+		daemonSet("kube-flannel", "kube-flannel-ds"),
+	)
+
+	cluster, err := ClusterSnapshot(clientset)
+	require.NoError(t, err)
+	assert.Equal(t, &results.Cluster{
+		Name:      "prod",
+		Provider:  "aws",
+		CNI:       []string{"cilium", "flannel"},
+		NodeCount: 3,
+		Nodes: []results.NodeGroup{
+			{OS: "linux", Arch: "amd64", KubeletVersion: "v1.30.0", ContainerRuntime: "containerd://1.7.13", Count: 2},
+			{OS: "windows", Arch: "amd64", KubeletVersion: "v1.30.0", ContainerRuntime: "containerd://1.7.13", Count: 1},
+		},
+	}, cluster)
+
+	assert.Contains(t, cluster.JUnitProperties(), results.JUnitProperty{
+		Name: "cluster.nodes", Value: "2x linux/amd64 v1.30.0 containerd://1.7.13, 1x windows/amd64 v1.30.0 containerd://1.7.13",
+	})
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"os"
+	"path"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// storageTestDriverPath is the path the test driver manifest of
+// --storage-testdriver is mounted at in the conformance container
+const storageTestDriverPath = "/tmp/storage/testdriver.yaml"
+
+// StorageTestDriverConfigMap returns the definition of the config map holding
+// the test driver manifest of --storage-testdriver.
+func StorageTestDriverConfigMap(namespace string) (*v1.ConfigMap, error) {
+	data, err := os.ReadFile(settings.GetString("storage-testdriver"))
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      common.StorageTestDriverConfigMapName,
+			Namespace: namespace,
+			Labels:    common.ConformanceLabels(),
+		},
+		Data: map[string]string{
+			path.Base(storageTestDriverPath): string(data),
+		},
+	}, nil
+}
+
+// storageArgs returns the arguments of the e2e test binary pointing the
+// external storage tests to the test driver manifest
+func storageArgs() []string {
+	if settings.GetString("storage-testdriver") == "" {
+		return nil
+	}
+	return []string{"--storage.testdriver=" + storageTestDriverPath}
+}
+
+// applyStorage mounts the test driver manifest of --storage-testdriver into
+// the conformance container.
+func applyStorage(pod *v1.Pod) {
+	if settings.GetString("storage-testdriver") == "" {
+		return
+	}
+	mountVolume(pod, common.ConformanceContainer,
+		v1.Volume{
+			Name: "storage-testdriver-volume",
+			VolumeSource: v1.VolumeSource{
+				ConfigMap: &v1.ConfigMapVolumeSource{
+					LocalObjectReference: v1.LocalObjectReference{Name: common.StorageTestDriverConfigMapName},
+				},
+			},
+		},
+		v1.VolumeMount{
+			MountPath: path.Dir(storageTestDriverPath),
+			ReadOnly:  true,
+		})
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestStorageTestDriver(t *testing.T) {
+	testDriver := filepath.Join(t.TempDir(), "hostpath.yaml")
+	require.NoError(t, os.WriteFile(testDriver, []byte("DriverInfo:\n  Name: hostpath.csi.k8s.io\n"), 0644))
+	settings.Set("storage-testdriver", testDriver)
+	defer settings.Set("storage-testdriver", "")
+
+	configMap, err := StorageTestDriverConfigMap("conformance")
+	require.NoError(t, err)
+	assert.Equal(t, common.StorageTestDriverConfigMapName, configMap.Name)
+	assert.Equal(t, map[string]string{"testdriver.yaml": "DriverInfo:\n  Name: hostpath.csi.k8s.io\n"}, configMap.Data)
+
+	assert.Equal(t, []string{"--storage.testdriver=/tmp/storage/testdriver.yaml"}, storageArgs())
+
+	pods, err := Pods("conformance")
+	require.NoError(t, err)
+	container := pods[0].Spec.Containers[0]
+	assert.Contains(t, container.VolumeMounts, v1.VolumeMount{Name: "storage-testdriver-volume", MountPath: "/tmp/storage", ReadOnly: true})
+	assert.Contains(t, container.Env, v1.EnvVar{Name: "E2E_EXTRA_ARGS", Value: "--storage.testdriver=/tmp/storage/testdriver.yaml"})
+
+	settings.Set("storage-testdriver", "")
+	assert.Empty(t, storageArgs())
+}
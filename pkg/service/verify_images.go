@@ -0,0 +1,170 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/log"
+	"sigs.k8s.io/hydrophone/pkg/registry"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// verifyWorkers is the number of images checked concurrently
+const verifyWorkers = 8
+
+// nearestTagCount is the number of available tags suggested for a missing image
+const nearestTagCount = 3
+
+// CheckConformanceImage fails when the registry reports that the conformance
+// image doesn't exist, suggesting the nearest available tags, so that a
+// missing image is not only discovered through an ImagePullBackOff. When the
+// registry can't be queried the check is skipped with a warning.
+func CheckConformanceImage() error {
+	image := settings.GetString("conformance-image")
+	checker := registry.NewChecker()
+	exists, err := checker.Exists(image)
+	if err != nil {
+		log.Printf("WARNING: unable to check that %s exists: %v", image, err)
+		return nil
+	}
+	if exists {
+		return nil
+	}
+
+	msg := fmt.Sprintf("conformance image %s doesn't exist", image)
+	tag := common.ImageVersion(image)
+	if tag == "" {
+		return errors.New(msg)
+	}
+	tags, err := checker.Tags(image)
+	if err != nil {
+		log.Printf("unable to list the tags of %s: %v", image, err)
+		return errors.New(msg)
+	}
+	if nearest := registry.NearestTags(tag, tags, nearestTagCount); len(nearest) != 0 {
+		msg += fmt.Sprintf(", available tags close to %s are %s. new releases can take a while to be published, use --version-policy=%s or --conformance-image to pick another tag", tag, strings.Join(nearest, ", "), common.VersionPolicyLatestPatch)
+	}
+	return errors.New(msg)
+}
+
+// CheckBusyboxImage fails when the registry reports that the busybox image
+// of the output container doesn't exist, so that a mirror missing it is
+// discovered before the tests instead of when the artifacts are fetched. The
+// image is pinned to the digest the registry serves, so that the run records
+// exactly which image it used. When the registry can't be queried the check
+// is skipped with a warning.
+func CheckBusyboxImage() error {
+	return checkBusyboxImage(registry.NewChecker())
+}
+
+func checkBusyboxImage(checker *registry.Checker) error {
+	image := settings.GetString("busybox-image")
+	if version, tested := common.ImageVersion(image), common.ImageVersion(common.DefaultBusyboxImage); version != "" && version != tested {
+		log.Printf("WARNING: busybox image %s has version %s, hydrophone is tested with %s", image, version, tested)
+	}
+	exists, err := checker.Exists(image)
+	if err != nil {
+		log.Printf("WARNING: unable to check that %s exists: %v", image, err)
+		return nil
+	}
+	if !exists {
+		return fmt.Errorf("busybox image %s doesn't exist, mirror %s or use --busybox-image to pick another image", image, common.DefaultBusyboxImage)
+	}
+	digest, err := checker.Digest(image)
+	if err != nil {
+		log.Printf("WARNING: unable to resolve the digest of %s: %v", image, err)
+		return nil
+	}
+	if pinned := registry.PinDigest(image, digest); pinned != image {
+		log.Printf("Pinning the busybox image to %s", pinned)
+		settings.Set("busybox-image", pinned)
+	}
+	return nil
+}
+
+// VerifyImages checks that the manifests of the conformance, busybox and test
+// images exist in their registries, after applying the test repo list, and
+// returns an error listing the missing images.
+func VerifyImages(clientSet kubernetes.Interface) error {
+	testImages, err := ListImages(clientSet)
+	if err != nil {
+		return err
+	}
+	repoList := map[string]string{}
+	if path := settings.GetString("test-repo-list"); path != "" {
+		if repoList, err = common.ReadTestRepoList(path); err != nil {
+			return err
+		}
+	}
+
+	images := []string{settings.GetString("conformance-image"), settings.GetString("busybox-image")}
+	for _, image := range testImages {
+		if mirrored, pullable := common.MirrorTestImage(image, repoList); pullable {
+			images = append(images, mirrored)
+		}
+	}
+	log.Printf("Verifying that %d images exist", len(images))
+
+	checker := registry.NewChecker()
+	var mu sync.Mutex
+	var missing, failed []string
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < verifyWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for image := range work {
+				exists, err := checker.Exists(image)
+				mu.Lock()
+				if err != nil {
+					failed = append(failed, fmt.Sprintf("%s: %v", image, err))
+				} else if !exists {
+					missing = append(missing, image)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, image := range images {
+		work <- image
+	}
+	close(work)
+	wg.Wait()
+
+	sort.Strings(missing)
+	sort.Strings(failed)
+	for _, f := range failed {
+		log.Printf("unable to verify %s", f)
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("%d images are missing:\n%s", len(missing), strings.Join(missing, "\n"))
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("%d images could not be verified", len(failed))
+	}
+	log.Printf("All %d images exist", len(images))
+	return nil
+}
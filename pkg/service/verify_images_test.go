@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/registry"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestCheckBusyboxImage(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/e2e-test-images/busybox/manifests/1.36.1-1", "/v2/e2e-test-images/busybox/manifests/" + digest:
+			w.Header().Set("Docker-Content-Digest", digest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	defer settings.Set("busybox-image", "")
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	checker := &registry.Checker{Client: server.Client(), PlainHTTP: func(string) bool { return true }}
+
+	settings.Set("busybox-image", host+"/e2e-test-images/busybox:1.36.1-1")
+	require.NoError(t, checkBusyboxImage(checker))
+	assert.Equal(t, host+"/e2e-test-images/busybox:1.36.1-1@"+digest, settings.GetString("busybox-image"))
+
+	settings.Set("busybox-image", host+"/e2e-test-images/busybox@"+digest)
+	require.NoError(t, checkBusyboxImage(checker))
+	assert.Equal(t, host+"/e2e-test-images/busybox@"+digest, settings.GetString("busybox-image"))
+
+	settings.Set("busybox-image", host+"/e2e-test-images/busybox:1.35")
+	assert.ErrorContains(t, checkBusyboxImage(checker), "doesn't exist")
+}
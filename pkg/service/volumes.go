@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+// Types of the volumes of --volume
+const (
+	volumeConfigMap = "configmap"
+	volumeSecret    = "secret"
+	volumeHostPath  = "hostpath"
+	volumeEmptyDir  = "emptydir"
+)
+
+// mountVolume adds the volume to the pod and mounts it into the container
+func mountVolume(pod *v1.Pod, container string, volume v1.Volume, mount v1.VolumeMount) {
+	mount.Name = volume.Name
+	pod.Spec.Volumes = append(pod.Spec.Volumes, volume)
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == container {
+			pod.Spec.Containers[i].VolumeMounts = append(pod.Spec.Containers[i].VolumeMounts, mount)
+		}
+	}
+}
+
+// applyVolumes mounts the volumes of --volume into the conformance container,
+// e.g. to provide the cloud provider config or the certificates some tests
+// need. A volume can't be mounted where the pod already mounts one.
+func applyVolumes(pod *v1.Pod) error {
+	for i, value := range settings.GetStringSlice("volume") {
+		volume, mount, err := parseVolume(value)
+		if err != nil {
+			return err
+		}
+		volume.Name = fmt.Sprintf("extra-volume-%d", i)
+		for _, container := range pod.Spec.Containers {
+			if container.Name != common.ConformanceContainer {
+				continue
+			}
+			for _, existing := range container.VolumeMounts {
+				if path.Clean(existing.MountPath) == mount.MountPath {
+					return fmt.Errorf("--volume %q is mounted at %s, where %s is already mounted", value, mount.MountPath, existing.Name)
+				}
+			}
+		}
+		mountVolume(pod, common.ConformanceContainer, volume, mount)
+	}
+	return nil
+}
+
+// parseVolume parses a volume in the TYPE:SOURCE:PATH[:ro] format, where the
+// source is the name of the config map or secret, the path on the node, or
+// the optional medium of an empty dir, e.g. Memory.
+func parseVolume(value string) (v1.Volume, v1.VolumeMount, error) {
+	parts := strings.Split(value, ":")
+	mount := v1.VolumeMount{}
+	if len(parts) == 4 && parts[3] == "ro" {
+		mount.ReadOnly = true
+		parts = parts[:3]
+	}
+	if len(parts) != 3 {
+		return v1.Volume{}, v1.VolumeMount{}, fmt.Errorf("expected --volume to be of TYPE:SOURCE:PATH[:ro] format, got %q", value)
+	}
+	volumeType, source, mountPath := parts[0], parts[1], parts[2]
+	if !path.IsAbs(mountPath) {
+		return v1.Volume{}, v1.VolumeMount{}, fmt.Errorf("expected the path of --volume %q to be absolute", value)
+	}
+	mount.MountPath = path.Clean(mountPath)
+
+	volume := v1.Volume{}
+	switch volumeType {
+	case volumeConfigMap:
+		volume.ConfigMap = &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: source}}
+	case volumeSecret:
+		volume.Secret = &v1.SecretVolumeSource{SecretName: source}
+	case volumeHostPath:
+		if !path.IsAbs(source) {
+			return v1.Volume{}, v1.VolumeMount{}, fmt.Errorf("expected the host path of --volume %q to be absolute", value)
+		}
+		volume.HostPath = &v1.HostPathVolumeSource{Path: source}
+	case volumeEmptyDir:
+		volume.EmptyDir = &v1.EmptyDirVolumeSource{Medium: v1.StorageMedium(source)}
+		return volume, mount, nil
+	default:
+		return v1.Volume{}, v1.VolumeMount{}, fmt.Errorf("invalid type of --volume %q, expected %s, %s, %s or %s",
+			value, volumeConfigMap, volumeSecret, volumeHostPath, volumeEmptyDir)
+	}
+	if source == "" {
+		return v1.Volume{}, v1.VolumeMount{}, fmt.Errorf("expected --volume %q to name its %s", value, volumeType)
+	}
+	return volume, mount, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestParseVolume(t *testing.T) {
+	tests := []struct {
+		value   string
+		volume  v1.VolumeSource
+		mount   v1.VolumeMount
+		wantErr bool
+	}{
+		{
+			value:  "configmap:cloud-config:/etc/kubernetes/cloud",
+			volume: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: "cloud-config"}}},
+			mount:  v1.VolumeMount{MountPath: "/etc/kubernetes/cloud"},
+		},
+		{
+			value:  "secret:certs:/etc/ssl/custom/:ro",
+			volume: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "certs"}},
+			mount:  v1.VolumeMount{MountPath: "/etc/ssl/custom", ReadOnly: true},
+		},
+		{
+			value:  "hostpath:/etc/ssl/certs:/etc/ssl/certs:ro",
+			volume: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/etc/ssl/certs"}},
+			mount:  v1.VolumeMount{MountPath: "/etc/ssl/certs", ReadOnly: true},
+		},
+		{
+			value:  "emptydir::/scratch",
+			volume: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+			mount:  v1.VolumeMount{MountPath: "/scratch"},
+		},
+		{
+			value:  "emptydir:Memory:/scratch",
+			volume: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{Medium: v1.StorageMediumMemory}},
+			mount:  v1.VolumeMount{MountPath: "/scratch"},
+		},
+		{value: "configmap:cloud-config", wantErr: true},
+		{value: "configmap::/etc/kubernetes/cloud", wantErr: true},
+		{value: "configmap:cloud-config:etc/kubernetes/cloud", wantErr: true},
+		{value: "hostpath:certs:/etc/ssl/certs", wantErr: true},
+		{value: "pvc:data:/data", wantErr: true},
+		{value: "secret:certs:/etc/ssl/custom:rw", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			volume, mount, err := parseVolume(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.volume, volume.VolumeSource)
+			assert.Equal(t, tt.mount, mount)
+		})
+	}
+}
+
+func TestApplyVolumes(t *testing.T) {
+	settings.Set("volume", []string{"configmap:cloud-config:/etc/kubernetes/cloud", "secret:certs:/etc/ssl/custom:ro"})
+	defer settings.Set("volume", []string{})
+
+	pod := ConformancePod("conformance")
+	require.NoError(t, applyVolumes(pod))
+	assert.Len(t, pod.Spec.Volumes, 3)
+	assert.Equal(t, "extra-volume-1", pod.Spec.Volumes[2].Name)
+	assert.Contains(t, pod.Spec.Containers[0].VolumeMounts,
+		v1.VolumeMount{Name: "extra-volume-1", MountPath: "/etc/ssl/custom", ReadOnly: true})
+	assert.Len(t, pod.Spec.Containers[1].VolumeMounts, 1)
+
+	settings.Set("volume", []string{"emptydir::/tmp/results/"})
+	assert.Error(t, applyVolumes(ConformancePod("conformance")))
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package settings holds the settings of hydrophone, read from its flags, its
+// HYDROPHONE_ environment variables and its config file.
+//
+// The settings are kept apart from the global configuration of viper, so that
+// the programs running hydrophone from Go keep their own configuration, and
+// every run in process starts from fresh settings with Isolate.
+package settings
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var (
+	current atomic.Pointer[viper.Viper]
+
+	// flags are the flags bound to the settings, bound to the fresh settings
+	// of Isolate as well
+	mu    sync.Mutex
+	flags = map[string]*pflag.Flag{}
+)
+
+func init() {
+	current.Store(viper.New())
+}
+
+func v() *viper.Viper {
+	return current.Load()
+}
+
+// Isolate replaces the settings with fresh ones bound to the same flags, until
+// the returned function restores the previous settings.
+func Isolate() (restore func()) {
+	fresh := viper.New()
+	mu.Lock()
+	for key, flag := range flags {
+		_ = fresh.BindPFlag(key, flag)
+	}
+	mu.Unlock()
+	previous := current.Swap(fresh)
+	return func() { current.Store(previous) }
+}
+
+// Reset discards the settings and the flags bound to them, for the tests.
+func Reset() {
+	mu.Lock()
+	flags = map[string]*pflag.Flag{}
+	mu.Unlock()
+	current.Store(viper.New())
+}
+
+// BindPFlag binds the setting to the flag, which takes precedence over the
+// environment and the config file when set.
+func BindPFlag(key string, flag *pflag.Flag) error {
+	if err := v().BindPFlag(key, flag); err != nil {
+		return err
+	}
+	mu.Lock()
+	flags[strings.ToLower(key)] = flag
+	mu.Unlock()
+	return nil
+}
+
+// SetEnvPrefix sets the prefix of the environment variables of the settings.
+func SetEnvPrefix(prefix string) { v().SetEnvPrefix(prefix) }
+
+// SetEnvKeyReplacer sets the replacer mapping the settings to their
+// environment variables.
+func SetEnvKeyReplacer(r *strings.Replacer) { v().SetEnvKeyReplacer(r) }
+
+// AutomaticEnv reads the settings from the environment variables.
+func AutomaticEnv() { v().AutomaticEnv() }
+
+// SetConfigFile sets the config file of the settings.
+func SetConfigFile(file string) { v().SetConfigFile(file) }
+
+// SetConfigName sets the name of the config file looked up in the paths of
+// AddConfigPath.
+func SetConfigName(name string) { v().SetConfigName(name) }
+
+// SetConfigType sets the format of the config file.
+func SetConfigType(format string) { v().SetConfigType(format) }
+
+// AddConfigPath adds a directory the config file is looked up in.
+func AddConfigPath(dir string) { v().AddConfigPath(dir) }
+
+// ReadInConfig reads the config file.
+func ReadInConfig() error { return v().ReadInConfig() }
+
+// ReadConfig reads the config from r.
+func ReadConfig(r io.Reader) error { return v().ReadConfig(r) }
+
+// ConfigFileUsed returns the config file that was read.
+func ConfigFileUsed() string { return v().ConfigFileUsed() }
+
+// MergeConfigMap merges the settings over the ones of the config file.
+func MergeConfigMap(cfg map[string]any) error { return v().MergeConfigMap(cfg) }
+
+// AllSettings returns the settings by name.
+func AllSettings() map[string]any { return v().AllSettings() }
+
+// IsSet reports whether the setting has a value.
+func IsSet(key string) bool { return v().IsSet(key) }
+
+// Set overrides the setting.
+func Set(key string, value any) { v().Set(key, value) }
+
+// Get returns the setting.
+func Get(key string) any { return v().Get(key) }
+
+// GetString returns the setting as a string.
+func GetString(key string) string { return v().GetString(key) }
+
+// GetBool returns the setting as a bool.
+func GetBool(key string) bool { return v().GetBool(key) }
+
+// GetInt returns the setting as an int.
+func GetInt(key string) int { return v().GetInt(key) }
+
+// GetInt64 returns the setting as an int64.
+func GetInt64(key string) int64 { return v().GetInt64(key) }
+
+// GetFloat64 returns the setting as a float64.
+func GetFloat64(key string) float64 { return v().GetFloat64(key) }
+
+// GetStringSlice returns the setting as a slice of strings.
+func GetStringSlice(key string) []string { return v().GetStringSlice(key) }
+
+// GetStringMap returns the setting as a map.
+func GetStringMap(key string) map[string]any { return v().GetStringMap(key) }
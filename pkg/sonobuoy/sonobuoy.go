@@ -0,0 +1,183 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sonobuoy converts Sonobuoy run configurations to hydrophone configurations.
+package sonobuoy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// e2ePlugin is the name of the Sonobuoy plugin running the conformance image
+const e2ePlugin = "e2e"
+
+// Config is the part of a Sonobuoy run configuration hydrophone has an
+// equivalent for. Both the options of sonobuoy gen/run, e.g. Mode and
+// E2EConfig, and the fields of config.json, e.g. Namespace, are read.
+type Config struct {
+	Mode                 string
+	KubeConformanceImage string
+	E2EConfig            *E2EConfig
+	PluginEnvOverrides   map[string]map[string]string
+	Namespace            string
+	Plugins              []struct {
+		Name string `json:"name"`
+	}
+	// Config holds config.json when it is nested in the run configuration
+	Config *struct {
+		Namespace string
+	}
+}
+
+// E2EConfig is the configuration of the e2e plugin
+type E2EConfig struct {
+	Focus    string
+	Skip     string
+	Parallel string
+}
+
+// mode is the focus and skip a Sonobuoy mode runs the e2e plugin with
+type mode struct {
+	focus, skip string
+}
+
+// modes maps the Sonobuoy modes to their focus and skip. conformance-lite
+// skips a list of slow tests on top, which hydrophone approximates with tags.
+var modes = map[string]mode{
+	"non-disruptive-conformance": {focus: `\[Conformance\]`, skip: `\[Disruptive\]|NoExecuteTaintManager`},
+	"certified-conformance":      {focus: `\[Conformance\]`},
+	"conformance-lite":           {focus: `\[Conformance\]`, skip: `\[Disruptive\]|NoExecuteTaintManager|\[Serial\]|\[Slow\]`},
+	"quick":                      {focus: "Pods should be submitted and removed"},
+}
+
+// Convert converts a Sonobuoy run configuration in JSON or YAML to the keys
+// of hydrophone.yaml. The returned warnings list the settings without
+// equivalent in hydrophone.
+func Convert(data []byte) (map[string]any, []string, error) {
+	var sc Config
+	if err := yaml.Unmarshal(data, &sc); err != nil {
+		return nil, nil, fmt.Errorf("error parsing Sonobuoy configuration: %w", err)
+	}
+
+	var warnings []string
+	config := map[string]any{}
+	set := func(key, value string) {
+		if value != "" {
+			config[key] = value
+		}
+	}
+
+	modeName := sc.Mode
+	if modeName == "" {
+		modeName = "non-disruptive-conformance"
+	}
+	m, ok := modes[modeName]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown Sonobuoy mode %q", sc.Mode)
+	}
+	if modeName == "conformance-lite" {
+		warnings = append(warnings, "mode conformance-lite is approximated by skipping [Serial] and [Slow] tests")
+	}
+	set("focus", m.focus)
+	set("skip", m.skip)
+	if sc.E2EConfig != nil {
+		set("focus", sc.E2EConfig.Focus)
+		set("skip", sc.E2EConfig.Skip)
+		set("parallel", parallel(sc.E2EConfig.Parallel))
+	}
+
+	set("conformance-image", sc.KubeConformanceImage)
+	set("namespace", sc.Namespace)
+	if sc.Config != nil {
+		set("namespace", sc.Config.Namespace)
+	}
+
+	for _, plugin := range sc.Plugins {
+		if plugin.Name != e2ePlugin {
+			warnings = append(warnings, fmt.Sprintf("plugin %s is not supported, hydrophone only runs the e2e tests", plugin.Name))
+		}
+	}
+
+	plugins := make([]string, 0, len(sc.PluginEnvOverrides))
+	for plugin := range sc.PluginEnvOverrides {
+		plugins = append(plugins, plugin)
+	}
+	sort.Strings(plugins)
+	for _, plugin := range plugins {
+		env := sc.PluginEnvOverrides[plugin]
+		if plugin != e2ePlugin {
+			warnings = append(warnings, fmt.Sprintf("environment of plugin %s is ignored", plugin))
+			continue
+		}
+		names := make([]string, 0, len(env))
+		for name := range env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if w := convertEnv(config, name, env[name]); w != "" {
+				warnings = append(warnings, w)
+			}
+		}
+	}
+	return config, warnings, nil
+}
+
+// convertEnv converts an environment variable of the e2e plugin to its
+// hydrophone key and returns a warning if it has no equivalent
+func convertEnv(config map[string]any, name, value string) string {
+	switch name {
+	case "E2E_FOCUS":
+		config["focus"] = value
+	case "E2E_SKIP":
+		config["skip"] = value
+	case "E2E_PARALLEL":
+		if p := parallel(value); p != "" {
+			config["parallel"] = p
+		}
+	case "E2E_EXTRA_ARGS":
+		config["extra-args"] = strings.Fields(value)
+	case "E2E_DRYRUN":
+		if dryRun, _ := strconv.ParseBool(value); dryRun {
+			config["ginkgo-dry-run"] = true
+		}
+	case "KUBE_TEST_REPO":
+		config["test-repo"] = value
+	case "E2E_PROVIDER", "E2E_USE_GO_RUNNER", "RESULTS_DIR", "SONOBUOY", "SONOBUOY_CONFIG_DIR", "SONOBUOY_K8S_VERSION", "SONOBUOY_PROGRESS_PORT", "SONOBUOY_RESULTS_DIR":
+		// set by hydrophone or specific to Sonobuoy
+	default:
+		return fmt.Sprintf("environment variable %s of the e2e plugin is ignored", name)
+	}
+	return ""
+}
+
+// parallel converts the E2E_PARALLEL of Sonobuoy, which is either a boolean
+// or a number of processes, to --parallel. Running in parallel without number
+// of processes lets hydrophone pick it from the size of the cluster.
+func parallel(value string) string {
+	if b, err := strconv.ParseBool(value); err == nil {
+		if b {
+			return "auto"
+		}
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sonobuoy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		want         map[string]any
+		wantWarnings []string
+		wantErr      bool
+	}{
+		{
+			name:   "default mode",
+			config: `{"Namespace": "sonobuoy"}`,
+			want: map[string]any{
+				"focus":     `\[Conformance\]`,
+				"skip":      `\[Disruptive\]|NoExecuteTaintManager`,
+				"namespace": "sonobuoy",
+			},
+		},
+		{
+			name: "certified conformance with overrides",
+			config: `{
+  "Mode": "certified-conformance",
+  "KubeConformanceImage": "registry.example.com/conformance:v1.29.1",
+  "E2EConfig": {"Focus": "\\[sig-network\\]", "Parallel": "true"},
+  "PluginEnvOverrides": {
+    "e2e": {"E2E_EXTRA_ARGS": "--allowed-not-ready-nodes=1 --non-blocking-taints=gpu", "E2E_SKIP": "Flaky", "CUSTOM": "x"},
+    "systemd-logs": {"CHROOT_DIR": "/node"}
+  },
+  "Config": {"Namespace": "conformance"},
+  "Plugins": [{"name": "e2e"}, {"name": "systemd-logs"}]
+}`,
+			want: map[string]any{
+				"focus":             `\[sig-network\]`,
+				"skip":              "Flaky",
+				"parallel":          "auto",
+				"conformance-image": "registry.example.com/conformance:v1.29.1",
+				"namespace":         "conformance",
+				"extra-args":        []string{"--allowed-not-ready-nodes=1", "--non-blocking-taints=gpu"},
+			},
+			wantWarnings: []string{
+				"plugin systemd-logs is not supported, hydrophone only runs the e2e tests",
+				"environment variable CUSTOM of the e2e plugin is ignored",
+				"environment of plugin systemd-logs is ignored",
+			},
+		},
+		{
+			name:   "yaml",
+			config: "mode: quick\npluginEnvOverrides:\n  e2e:\n    E2E_PARALLEL: \"4\"\n",
+			want: map[string]any{
+				"focus":    "Pods should be submitted and removed",
+				"parallel": "4",
+			},
+		},
+		{
+			name:    "unknown mode",
+			config:  `{"Mode": "everything"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := Convert([]byte(tt.config))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantWarnings, warnings)
+		})
+	}
+}
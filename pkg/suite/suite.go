@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package suite
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Names of the phases of a run gated by a smoke phase
+const (
+	SmokePhase = "smoke"
+	FullPhase  = "full"
+)
+
+// phaseNameRegexp restricts phase names to values usable as directory names
+var phaseNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Suite is an ordered list of phases executed one after another in the same namespace.
+//
+// Example:
+//
+//	phases:
+//	  - name: smoke
+//	    focus: '\[sig-node\].*Pods should be submitted and removed'
+//	  - name: conformance
+//	    focus: '\[Conformance\]'
+//	    skip: '\[Serial\]'
+//	    extra-args:
+//	      - --allowed-not-ready-nodes=1
+type Suite struct {
+	Phases []Phase `json:"phases"`
+}
+
+// Phase is a single run of the conformance image with its own test selection.
+type Phase struct {
+	// Name identifies the phase, its artifacts are written to a directory of that name
+	Name string `json:"name"`
+	// Focus, Skip and ExtraArgs replace --focus, --skip and --extra-args for the phase
+	Focus     string   `json:"focus,omitempty"`
+	Skip      string   `json:"skip,omitempty"`
+	ExtraArgs []string `json:"extra-args,omitempty"`
+	// ContinueOnFailure runs the next phases even if this one fails
+	ContinueOnFailure bool `json:"continue-on-failure,omitempty"`
+}
+
+// Load reads and validates the suite file at the given path.
+func Load(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Suite{}
+	if err := yaml.UnmarshalStrict(data, s); err != nil {
+		return nil, fmt.Errorf("error parsing suite file %s: %w", path, err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid suite file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Validate checks that the suite has at least one phase and that phase names are unique.
+func (s *Suite) Validate() error {
+	if len(s.Phases) == 0 {
+		return fmt.Errorf("expected at least one phase")
+	}
+	names := map[string]bool{}
+	for i, phase := range s.Phases {
+		if !phaseNameRegexp.MatchString(phase.Name) {
+			return fmt.Errorf("phase %d: expected name [%s] to consist of lower case alphanumeric characters or '-'", i, phase.Name)
+		}
+		if names[phase.Name] {
+			return fmt.Errorf("phase %d: duplicate name [%s]", i, phase.Name)
+		}
+		names[phase.Name] = true
+	}
+	return nil
+}
+
+// Focus returns the expression focusing the tests of all phases, empty when a
+// phase runs every test.
+func (s *Suite) Focus() string {
+	focus := make([]string, 0, len(s.Phases))
+	for _, phase := range s.Phases {
+		if phase.Focus == "" {
+			return ""
+		}
+		focus = append(focus, phase.Focus)
+	}
+	return strings.Join(focus, "|")
+}
+
+// FromFocus returns a suite running each focus expression in its own phase.
+// It is used when the tests to run can't be selected with a single expression.
+func FromFocus(focus []string) *Suite {
+	s := &Suite{}
+	for i, f := range focus {
+		s.Phases = append(s.Phases, Phase{
+			Name:              fmt.Sprintf("chunk-%d", i+1),
+			Focus:             f,
+			ContinueOnFailure: true,
+		})
+	}
+	return s
+}
+
+// WithSmokeGate returns a suite running the tests matching smokeFocus in a
+// smoke phase first, and the phases of s, or the tests matching focus when s
+// is nil, only if the smoke phase passed. The next phases skip the smoke
+// tests, so that each test runs once.
+func WithSmokeGate(s *Suite, smokeFocus, focus string) *Suite {
+	if s == nil {
+		s = &Suite{Phases: []Phase{{Name: FullPhase, Focus: focus}}}
+	}
+	gated := &Suite{Phases: []Phase{{Name: SmokePhase, Focus: smokeFocus}}}
+	for _, phase := range s.Phases {
+		if phase.Skip == "" {
+			phase.Skip = smokeFocus
+		} else {
+			phase.Skip += "|" + smokeFocus
+		}
+		gated.Phases = append(gated.Phases, phase)
+	}
+	return gated
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package suite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name        string
+		content     string
+		expected    []Phase
+		expectedErr string
+	}{
+		{
+			name: "phases",
+			content: `phases:
+  - name: smoke
+    focus: Pods
+  - name: conformance
+    focus: '\[Conformance\]'
+    skip: '\[Serial\]'
+    extra-args:
+      - --allowed-not-ready-nodes=1
+    continue-on-failure: true
+`,
+			expected: []Phase{
+				{Name: "smoke", Focus: "Pods"},
+				{Name: "conformance", Focus: `\[Conformance\]`, Skip: `\[Serial\]`, ExtraArgs: []string{"--allowed-not-ready-nodes=1"}, ContinueOnFailure: true},
+			},
+		},
+		{
+			name:        "unknown field",
+			content:     "phases:\n  - name: smoke\n    focuss: Pods\n",
+			expectedErr: "error parsing suite file",
+		},
+		{
+			name:        "no phases",
+			content:     "phases: []\n",
+			expectedErr: "expected at least one phase",
+		},
+		{
+			name:        "invalid name",
+			content:     "phases:\n  - name: Smoke Tests\n",
+			expectedErr: "phase 0: expected name [Smoke Tests] to consist of lower case alphanumeric characters or '-'",
+		},
+		{
+			name:        "duplicate name",
+			content:     "phases:\n  - name: smoke\n  - name: smoke\n",
+			expectedErr: "phase 1: duplicate name [smoke]",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "suite.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o644))
+			s, err := Load(path)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, s.Phases)
+		})
+	}
+
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestFromFocus(t *testing.T) {
+	s := FromFocus([]string{"a", "b", "c"})
+	require.NoError(t, s.Validate())
+	assert.Equal(t, []Phase{
+		{Name: "chunk-1", Focus: "a", ContinueOnFailure: true},
+		{Name: "chunk-2", Focus: "b", ContinueOnFailure: true},
+		{Name: "chunk-3", Focus: "c", ContinueOnFailure: true},
+	}, s.Phases)
+	assert.Equal(t, "a|b|c", s.Focus())
+}
+
+func TestFocus(t *testing.T) {
+	s := &Suite{Phases: []Phase{{Name: "smoke", Focus: "Pods"}, {Name: "conformance", Focus: `\[Conformance\]`}}}
+	assert.Equal(t, `Pods|\[Conformance\]`, s.Focus())
+
+	s.Phases = append(s.Phases, Phase{Name: "all"})
+	assert.Empty(t, s.Focus())
+}
+
+func TestWithSmokeGate(t *testing.T) {
+	s := WithSmokeGate(nil, "Pods", `\[Conformance\]`)
+	require.NoError(t, s.Validate())
+	assert.Equal(t, []Phase{
+		{Name: SmokePhase, Focus: "Pods"},
+		{Name: FullPhase, Focus: `\[Conformance\]`, Skip: "Pods"},
+	}, s.Phases)
+
+	s = WithSmokeGate(FromFocus([]string{"a", "b"}), "Pods", "")
+	require.NoError(t, s.Validate())
+	assert.Equal(t, []Phase{
+		{Name: SmokePhase, Focus: "Pods"},
+		{Name: "chunk-1", Focus: "a", Skip: "Pods", ContinueOnFailure: true},
+		{Name: "chunk-2", Focus: "b", Skip: "Pods", ContinueOnFailure: true},
+	}, s.Phases)
+
+	s = WithSmokeGate(&Suite{Phases: []Phase{{Name: "conformance", Skip: "Slow"}}}, "Pods", "")
+	assert.Equal(t, "Slow|Pods", s.Phases[1].Skip)
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testgrid reads the results of upstream test jobs from TestGrid.
+package testgrid
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the data API of the Kubernetes TestGrid
+const DefaultURL = "https://testgrid-data.k8s.io"
+
+// Test results of TestGrid cells, see TestStatus in the TestGrid protos
+const (
+	statusPass            = 1
+	statusPassWithErrors  = 2
+	statusPassWithSkips   = 3
+	statusTimedOut        = 9
+	statusCategorizedFail = 10
+	statusFail            = 12
+	statusFlaky           = 13
+)
+
+// FailureRate is how often a test failed in the recent upstream runs
+type FailureRate struct {
+	Runs     int `json:"runs"`
+	Failures int `json:"failures"`
+}
+
+// Rate returns the share of failed runs between 0 and 1
+func (f FailureRate) Rate() float64 {
+	if f.Runs == 0 {
+		return 0
+	}
+	return float64(f.Failures) / float64(f.Runs)
+}
+
+// Client reads the data API of TestGrid
+type Client struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the Kubernetes TestGrid
+func NewClient() *Client {
+	return &Client{URL: DefaultURL, HTTPClient: &http.Client{Timeout: time.Minute}}
+}
+
+type rowsResponse struct {
+	Rows []struct {
+		Name  string `json:"name"`
+		Cells []struct {
+			Result int `json:"result"`
+		} `json:"cells"`
+	} `json:"rows"`
+}
+
+// FailureRates returns the failure rate of every test of the tab, keyed by
+// the test name as printed by the e2e framework.
+func (c *Client) FailureRates(dashboard, tab string) (map[string]FailureRate, error) {
+	u := fmt.Sprintf("%s/api/v1/dashboards/%s/tabs/%s/rows", strings.TrimSuffix(c.URL, "/"),
+		url.PathEscape(dashboard), url.PathEscape(tab))
+	resp, err := c.HTTPClient.Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching TestGrid tab %s/%s: %w", dashboard, tab, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching TestGrid tab %s/%s: %s", dashboard, tab, resp.Status)
+	}
+	rows := rowsResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("error decoding TestGrid tab %s/%s: %w", dashboard, tab, err)
+	}
+
+	rates := map[string]FailureRate{}
+	for _, row := range rows.Rows {
+		rate := FailureRate{}
+		for _, cell := range row.Cells {
+			switch cell.Result {
+			case statusPass, statusPassWithErrors, statusPassWithSkips:
+				rate.Runs++
+			case statusTimedOut, statusCategorizedFail, statusFail, statusFlaky:
+				rate.Runs++
+				rate.Failures++
+			}
+		}
+		if rate.Runs != 0 {
+			rates[TestName(row.Name)] = rate
+		}
+	}
+	return rates, nil
+}
+
+// TestName strips the suite and ginkgo node prefixes TestGrid and junit
+// reports add to the name of e2e tests.
+func TestName(name string) string {
+	name = strings.TrimPrefix(name, "Kubernetes e2e suite.")
+	name = strings.TrimPrefix(name, "Kubernetes e2e suite ")
+	return strings.TrimPrefix(name, "[It] ")
+}
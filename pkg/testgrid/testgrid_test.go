@@ -0,0 +1,47 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testgrid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/dashboards/sig-release-1.29-blocking/tabs/conformance/rows", r.URL.Path)
+		w.Write([]byte(`{"rows": [
+			{"name": "Kubernetes e2e suite.[It] [sig-node] flaky test", "cells": [{"result": 1}, {"result": 12}, {"result": 0}, {"result": 1}, {"result": 13}]},
+			{"name": "Kubernetes e2e suite.[It] [sig-apps] stable test", "cells": [{"result": 1}, {"result": 1}]},
+			{"name": "Kubernetes e2e suite.[It] [sig-cli] never run", "cells": [{"result": 0}]}
+		]}`))
+	}))
+	defer server.Close()
+
+	c := &Client{URL: server.URL, HTTPClient: server.Client()}
+	rates, err := c.FailureRates("sig-release-1.29-blocking", "conformance")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]FailureRate{
+		"[sig-node] flaky test":  {Runs: 4, Failures: 2},
+		"[sig-apps] stable test": {Runs: 2},
+	}, rates)
+	assert.Equal(t, 0.5, rates["[sig-node] flaky test"].Rate())
+}
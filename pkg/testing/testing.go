@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testing provides fakes and fixtures for the integration tests of
+// programs embedding hydrophone, so that they run without a live cluster.
+package testing
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/hydrophone/pkg/client"
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/service"
+)
+
+// FakeLogs is the content of every log stream of the fake clientset
+const FakeLogs = "fake logs"
+
+// NewClient returns a hydrophone client backed by a fake clientset holding
+// the given objects, along with the fake clientset to inspect the actions of
+// the client or to change the objects.
+func NewClient(objects ...runtime.Object) (*client.Client, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(objects...)
+	c := client.NewClient()
+	c.ClientSet = clientset
+	return c, clientset
+}
+
+// ConformancePod returns the conformance pod hydrophone creates in the
+// namespace, with the given name and both of its containers running.
+func ConformancePod(namespace, name string) *v1.Pod {
+	pod := service.ConformancePod(namespace)
+	pod.Name = name
+	pod.CreationTimestamp = metav1.Now()
+	pod.Status.Phase = v1.PodRunning
+	for _, container := range pod.Spec.Containers {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, v1.ContainerStatus{
+			Name:  container.Name,
+			Image: container.Image,
+			Ready: true,
+			State: v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: metav1.Now()}},
+		})
+	}
+	return pod
+}
+
+// Terminate marks the conformance container of the pod as terminated with
+// the exit code, as it is once the tests completed. The pod keeps running
+// so that the results can be downloaded from the output container.
+func Terminate(pod *v1.Pod, exitCode int32) *v1.Pod {
+	for i := range pod.Status.ContainerStatuses {
+		status := &pod.Status.ContainerStatuses[i]
+		if status.Name == common.ConformanceContainer {
+			status.Ready = false
+			status.State = v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: exitCode, FinishedAt: metav1.Now()}}
+		}
+	}
+	return pod
+}
+
+// TestCase returns a test case of a junit report, the status is one of the
+// Status constants of the results package
+func TestCase(name, status string) results.JUnitTestCase {
+	tc := results.JUnitTestCase{Name: "[It] " + name, Status: status}
+	switch status {
+	case results.StatusFailed:
+		tc.Failure = &results.JUnitMessage{Message: "failed", Type: "failed"}
+	case results.StatusSkipped:
+		tc.Skipped = &results.JUnitMessage{Message: "skipped"}
+	}
+	return tc
+}
+
+// JUnitReport returns a junit report as written by ginkgo holding the test
+// cases, with the counts of the report set accordingly.
+func JUnitReport(testCases ...results.JUnitTestCase) *results.JUnitTestSuites {
+	suite := &results.JUnitTestSuites{
+		TestSuites: []results.JUnitTestSuite{
+			{Name: "Kubernetes e2e suite", Package: "/usr/local/bin", TestCases: testCases},
+		},
+	}
+	// merging a single report sets the counts of the suites
+	return results.MergeJUnit(suite)
+}
+
+// WriteOutputDir writes the files of the output directory of a run to dir:
+// junit_01.xml, results.json and an e2e.log holding the lines.
+func WriteOutputDir(t testing.TB, dir string, report *results.JUnitTestSuites, metadata *results.Metadata, lines ...string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := results.WriteJUnit(filepath.Join(dir, "junit_01.xml"), report); err != nil {
+		t.Fatal(err)
+	}
+	if err := results.WriteMetadata(dir, metadata); err != nil {
+		t.Fatal(err)
+	}
+	log := strings.Join(lines, "\n")
+	if len(lines) != 0 {
+		log += "\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, "e2e.log"), []byte(log), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
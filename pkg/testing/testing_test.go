@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/results"
+	"sigs.k8s.io/hydrophone/pkg/settings"
+)
+
+func TestFetchExitCode(t *testing.T) {
+	settings.Set("namespace", "conformance")
+	defer settings.Reset()
+
+	pod := Terminate(ConformancePod("conformance", common.PodName), 1)
+	c, clientset := NewClient(pod)
+	c.FetchExitCode()
+	assert.Equal(t, 1, c.ExitCode)
+	assert.NotEmpty(t, clientset.Actions())
+}
+
+func TestWriteOutputDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "output")
+	report := JUnitReport(
+		TestCase("passes", results.StatusPassed),
+		TestCase("fails", results.StatusFailed),
+		TestCase("skipped", results.StatusSkipped),
+	)
+	WriteOutputDir(t, dir, report, &results.Metadata{Focus: "passes"}, "line")
+
+	junit, err := results.ReadJUnit(filepath.Join(dir, "junit_01.xml"))
+	require.NoError(t, err)
+	require.Len(t, junit.TestSuites, 1)
+	suite := junit.TestSuites[0]
+	assert.Equal(t, 3, suite.Tests)
+	assert.Equal(t, 1, suite.Failures)
+	assert.Equal(t, 1, suite.Skipped)
+
+	metadata, err := results.ReadMetadata(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "passes", metadata.Focus)
+}
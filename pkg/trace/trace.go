@@ -0,0 +1,284 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package trace records spans of the lifecycle of runs and exports them to
+// an OpenTelemetry collector with OTLP over HTTP, encoded as JSON.
+//
+// A nil *Tracer records nothing, so that code can be instrumented without
+// checking whether tracing is enabled.
+package trace
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tracesPath is the path of the OTLP endpoint receiving spans
+const tracesPath = "/v1/traces"
+
+// Status codes of OTLP
+const (
+	statusUnset = 0
+	statusError = 2
+)
+
+// Tracer records the spans of a trace and exports them
+type Tracer struct {
+	// Endpoint is the URL of the OTLP traces endpoint
+	Endpoint string
+	// Headers are sent with the exports, e.g. to authenticate
+	Headers map[string]string
+	// Resource holds the attributes of the resource of the spans
+	Resource map[string]string
+	Client   *http.Client
+
+	mu      sync.Mutex
+	traceID [16]byte
+	spans   []*Span
+}
+
+// New returns a tracer exporting to the OTLP endpoint, either the URL of the
+// collector, to which /v1/traces is appended, or of its traces endpoint.
+func New(endpoint string, headers map[string]string, resource map[string]string) (*Tracer, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("expected an http or https URL, got %q", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = tracesPath
+	}
+	t := &Tracer{Endpoint: u.String(), Headers: headers, Resource: resource}
+	if _, err := rand.Read(t.traceID[:]); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ParseHeaders parses headers in the format of OTEL_EXPORTER_OTLP_HEADERS,
+// e.g. api-key=secret,tenant=ci
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected key=value, got %q", pair)
+		}
+		value, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		headers[strings.TrimSpace(key)] = value
+	}
+	return headers, nil
+}
+
+// Span is an operation of the trace
+type Span struct {
+	tracer     *Tracer
+	id         [8]byte
+	parent     [8]byte
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	err        string
+}
+
+// Start starts a span as a child of the parent, a root span when the parent
+// is nil.
+func (t *Tracer) Start(name string, parent *Span) *Span {
+	return t.StartAt(name, parent, time.Now())
+}
+
+// StartAt starts a span at the given time, e.g. for operations observed
+// after they started.
+func (t *Tracer) StartAt(name string, parent *Span, start time.Time) *Span {
+	if t == nil {
+		return nil
+	}
+	s := &Span{tracer: t, name: name, start: start, attributes: map[string]string{}}
+	_, _ = rand.Read(s.id[:])
+	if parent != nil {
+		s.parent = parent.id
+	}
+	return s
+}
+
+// SetAttribute sets an attribute of the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.attributes[key] = value
+}
+
+// End ends the span, failed with the error if it isn't nil
+func (s *Span) End(err error) {
+	s.EndAt(time.Now(), err)
+}
+
+// EndAt ends the span at the given time, failed with the error if it isn't nil
+func (s *Span) EndAt(end time.Time, err error) {
+	if s == nil {
+		return
+	}
+	s.end = end
+	if err != nil {
+		s.err = err.Error()
+	}
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, s)
+}
+
+// Flush exports the spans that ended since the last flush
+func (t *Tracer) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(t.request(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.Headers {
+		req.Header.Set(key, value)
+	}
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, t.Endpoint, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// OTLP JSON encoding of the spans, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type (
+	exportRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+	resourceSpans struct {
+		Resource   resource     `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+	resource struct {
+		Attributes []attribute `json:"attributes"`
+	}
+	scopeSpans struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		Spans []span `json:"spans"`
+	}
+	span struct {
+		TraceID           string      `json:"traceId"`
+		SpanID            string      `json:"spanId"`
+		ParentSpanID      string      `json:"parentSpanId,omitempty"`
+		Name              string      `json:"name"`
+		Kind              int         `json:"kind"`
+		StartTimeUnixNano string      `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+		Attributes        []attribute `json:"attributes,omitempty"`
+		Status            status      `json:"status"`
+	}
+	attribute struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+		} `json:"value"`
+	}
+	status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// request returns the export request of the spans
+func (t *Tracer) request(spans []*Span) *exportRequest {
+	scope := scopeSpans{}
+	scope.Scope.Name = "sigs.k8s.io/hydrophone"
+	for _, s := range spans {
+		encoded := span{
+			TraceID:           hex.EncodeToString(t.traceID[:]),
+			SpanID:            hex.EncodeToString(s.id[:]),
+			Name:              s.name,
+			Kind:              1, // internal
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        attributes(s.attributes),
+			Status:            status{Code: statusUnset},
+		}
+		if s.parent != [8]byte{} {
+			encoded.ParentSpanID = hex.EncodeToString(s.parent[:])
+		}
+		if s.err != "" {
+			encoded.Status = status{Code: statusError, Message: s.err}
+		}
+		scope.Spans = append(scope.Spans, encoded)
+	}
+	return &exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: attributes(t.Resource)},
+		ScopeSpans: []scopeSpans{scope},
+	}}}
+}
+
+// attributes encodes the attributes sorted by key
+func attributes(m map[string]string) []attribute {
+	var attrs []attribute
+	for key, value := range m {
+		a := attribute{Key: key}
+		a.Value.StringValue = value
+		attrs = append(attrs, a)
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+		wantErr  bool
+	}{
+		{endpoint: "http://collector:4318", want: "http://collector:4318/v1/traces"},
+		{endpoint: "https://otlp.example.com/otlp/v1/traces", want: "https://otlp.example.com/otlp/v1/traces"},
+		{endpoint: "collector:4318", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			tracer, err := New(tt.endpoint, nil, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tracer.Endpoint)
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders("api-key=secret%3D, tenant=ci")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"api-key": "secret=", "tenant": "ci"}, headers)
+
+	_, err = ParseHeaders("api-key")
+	assert.Error(t, err)
+}
+
+func TestFlush(t *testing.T) {
+	var received exportRequest
+	var apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		apiKey = r.Header.Get("api-key")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	tracer, err := New(server.URL, map[string]string{"api-key": "secret"}, map[string]string{"service.name": "hydrophone"})
+	require.NoError(t, err)
+	start := time.Unix(1714557600, 0)
+	root := tracer.StartAt("run", nil, start)
+	child := tracer.StartAt("cleanup", root, start.Add(time.Second))
+	child.SetAttribute("namespace", "conformance")
+	child.EndAt(start.Add(2*time.Second), errors.New("timed out"))
+	root.EndAt(start.Add(3*time.Second), nil)
+	require.NoError(t, tracer.Flush(context.Background()))
+
+	assert.Equal(t, "secret", apiKey)
+	require.Len(t, received.ResourceSpans, 1)
+	assert.Equal(t, "service.name", received.ResourceSpans[0].Resource.Attributes[0].Key)
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	assert.Equal(t, "cleanup", spans[0].Name)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+	assert.Equal(t, spans[1].TraceID, spans[0].TraceID)
+	assert.Empty(t, spans[1].ParentSpanID)
+	assert.Equal(t, status{Code: statusError, Message: "timed out"}, spans[0].Status)
+	assert.Equal(t, "1714557601000000000", spans[0].StartTimeUnixNano)
+	assert.Equal(t, "conformance", spans[0].Attributes[0].Value.StringValue)
+
+	// the exported spans aren't exported again
+	received = exportRequest{}
+	require.NoError(t, tracer.Flush(context.Background()))
+	assert.Empty(t, received.ResourceSpans)
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.Start("run", nil)
+	span.SetAttribute("key", "value")
+	span.End(nil)
+	assert.NoError(t, tracer.Flush(context.Background()))
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/hydrophone/pkg/log"
+)
+
+const (
+	// LatestReleaseURL is the GitHub API endpoint describing the latest hydrophone release
+	LatestReleaseURL = "https://api.github.com/repos/kubernetes-sigs/hydrophone/releases/latest"
+	// DefaultCertificateIdentity matches the identity of the workflows signing the release checksums
+	DefaultCertificateIdentity = "^https://github.com/kubernetes-sigs/hydrophone/"
+	// DefaultCertificateOIDCIssuer is the issuer of the identity signing the release checksums
+	DefaultCertificateOIDCIssuer = "https://token.actions.githubusercontent.com"
+)
+
+// Options configures the self-update
+type Options struct {
+	// CurrentVersion is the version of the running binary
+	CurrentVersion string
+	// Force replaces the binary even if it is already at the latest version
+	Force bool
+	// InsecureSkipSignature installs the release without verifying the
+	// signature of its checksums
+	InsecureSkipSignature bool
+	// CertificateIdentity is the regular expression the signing identity has to match
+	CertificateIdentity string
+	// CertificateOIDCIssuer is the issuer of the signing identity
+	CertificateOIDCIssuer string
+}
+
+// release is the subset of the GitHub release API response used by the update
+type release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []asset `json:"assets"`
+}
+
+type asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+var httpClient = &http.Client{Timeout: 5 * time.Minute}
+
+// lookPath finds the cosign binary, replaced in tests
+var lookPath = exec.LookPath
+
+// SelfUpdate replaces the running executable with the binary of the latest
+// release for the current platform, after verifying the archive checksum
+// and the signature of the checksums file.
+func SelfUpdate(opts Options) error {
+	rel, err := latestRelease()
+	if err != nil {
+		return err
+	}
+	if rel.TagName == opts.CurrentVersion && !opts.Force {
+		log.Printf("hydrophone is already at the latest version %s", rel.TagName)
+		return nil
+	}
+	log.Printf("Updating hydrophone from %s to %s", opts.CurrentVersion, rel.TagName)
+
+	archiveName := ArchiveName(runtime.GOOS, runtime.GOARCH)
+	checksumsName := fmt.Sprintf("hydrophone_%s_checksums.txt", strings.TrimPrefix(rel.TagName, "v"))
+
+	archive, err := download(rel, archiveName)
+	if err != nil {
+		return err
+	}
+	checksums, err := download(rel, checksumsName)
+	if err != nil {
+		return err
+	}
+	if err := verifySignature(rel, checksumsName, checksums, opts); err != nil {
+		return err
+	}
+	if err := verifyChecksum(archiveName, archive, checksums); err != nil {
+		return err
+	}
+
+	binary, err := extractBinary(archiveName, archive)
+	if err != nil {
+		return err
+	}
+	if err := replaceExecutable(binary); err != nil {
+		return err
+	}
+	log.Printf("hydrophone updated to %s", rel.TagName)
+	return nil
+}
+
+// ArchiveName returns the name of the release archive for the platform, following
+// the name template of .goreleaser.yaml
+func ArchiveName(goos, goarch string) string {
+	arch := goarch
+	if goarch == "amd64" {
+		arch = "x86_64"
+	}
+	ext := "tar.gz"
+	if goos == "windows" {
+		ext = "zip"
+	}
+	return fmt.Sprintf("hydrophone_%s_%s.%s", strings.ToUpper(goos[:1])+goos[1:], arch, ext)
+}
+
+func latestRelease() (*release, error) {
+	resp, err := httpClient.Get(LatestReleaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching latest release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching latest release: %s", resp.Status)
+	}
+	rel := &release{}
+	if err := json.NewDecoder(resp.Body).Decode(rel); err != nil {
+		return nil, fmt.Errorf("error decoding latest release: %w", err)
+	}
+	return rel, nil
+}
+
+// download fetches the named asset of the release into memory
+func download(rel *release, name string) ([]byte, error) {
+	for _, a := range rel.Assets {
+		if a.Name != name {
+			continue
+		}
+		log.Printf("downloading %s", a.URL)
+		resp, err := httpClient.Get(a.URL)
+		if err != nil {
+			return nil, fmt.Errorf("error downloading %s: %w", name, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("error downloading %s: %s", name, resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	}
+	return nil, fmt.Errorf("release %s has no asset %s", rel.TagName, name)
+}
+
+// verifyChecksum checks the archive against its entry in the checksums file
+func verifyChecksum(name string, archive, checksums []byte) error {
+	sum := sha256.Sum256(archive)
+	actual := hex.EncodeToString(sum[:])
+
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == name {
+			if fields[0] != actual {
+				return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, fields[0], actual)
+			}
+			log.Printf("checksum of %s verified", name)
+			return nil
+		}
+	}
+	return fmt.Errorf("no checksum found for %s", name)
+}
+
+// verifySignature verifies the keyless cosign signature of the checksums file
+// with the cosign binary found in PATH. The update fails without cosign
+// unless the signature is skipped explicitly.
+func verifySignature(rel *release, checksumsName string, checksums []byte, opts Options) error {
+	if opts.InsecureSkipSignature {
+		log.Printf("WARNING: skipping signature verification of %s, only checksums are verified", checksumsName)
+		return nil
+	}
+	cosign, err := lookPath("cosign")
+	if err != nil {
+		return fmt.Errorf("cosign is required to verify the signature of %s, install it or pass --insecure-skip-signature: %w", checksumsName, err)
+	}
+
+	signature, err := download(rel, checksumsName+".sig")
+	if err != nil {
+		return err
+	}
+	certificate, err := download(rel, checksumsName+".pem")
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "hydrophone-update")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	files := map[string][]byte{
+		checksumsName:          checksums,
+		checksumsName + ".sig": signature,
+		checksumsName + ".pem": certificate,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			return err
+		}
+	}
+
+	cmd := exec.Command(cosign, "verify-blob",
+		"--certificate", filepath.Join(dir, checksumsName+".pem"),
+		"--signature", filepath.Join(dir, checksumsName+".sig"),
+		"--certificate-identity-regexp", opts.CertificateIdentity,
+		"--certificate-oidc-issuer", opts.CertificateOIDCIssuer,
+		filepath.Join(dir, checksumsName))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("signature verification of %s failed: %v: %s", checksumsName, err, out)
+	}
+	log.Printf("signature of %s verified", checksumsName)
+	return nil
+}
+
+// extractBinary returns the hydrophone binary contained in the release archive
+func extractBinary(archiveName string, archive []byte) ([]byte, error) {
+	if strings.HasSuffix(archiveName, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if f.Name == "hydrophone.exe" {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(rc)
+			}
+		}
+		return nil, fmt.Errorf("hydrophone.exe not found in %s", archiveName)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name == "hydrophone" {
+			return io.ReadAll(tr)
+		}
+	}
+	return nil, fmt.Errorf("hydrophone not found in %s", archiveName)
+}
+
+// replaceExecutable atomically replaces the running executable with the binary
+func replaceExecutable(binary []byte) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		return err
+	}
+
+	tmp := executable + ".new"
+	if err := os.WriteFile(tmp, binary, 0755); err != nil {
+		return fmt.Errorf("error writing new binary: %w", err)
+	}
+	// windows doesn't allow replacing a running executable, but allows renaming it
+	old := executable + ".old"
+	os.Remove(old)
+	if err := os.Rename(executable, old); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error replacing %s: %w", executable, err)
+	}
+	if err := os.Rename(tmp, executable); err != nil {
+		os.Rename(old, executable)
+		return fmt.Errorf("error replacing %s: %w", executable, err)
+	}
+	os.Remove(old)
+	return nil
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveName(t *testing.T) {
+	tests := []struct {
+		goos, goarch string
+		name         string
+	}{
+		{goos: "linux", goarch: "amd64", name: "hydrophone_Linux_x86_64.tar.gz"},
+		{goos: "linux", goarch: "arm64", name: "hydrophone_Linux_arm64.tar.gz"},
+		{goos: "darwin", goarch: "arm64", name: "hydrophone_Darwin_arm64.tar.gz"},
+		{goos: "windows", goarch: "amd64", name: "hydrophone_Windows_x86_64.zip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos+"/"+tt.goarch, func(t *testing.T) {
+			assert.Equal(t, tt.name, ArchiveName(tt.goos, tt.goarch))
+		})
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	archive := []byte("archive")
+	sum := sha256.Sum256(archive)
+	checksum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name      string
+		checksums string
+		err       string
+	}{
+		{
+			name:      "matches",
+			checksums: fmt.Sprintf("0000  hydrophone_Darwin_arm64.tar.gz\n%s  hydrophone_Linux_x86_64.tar.gz\n", checksum),
+		},
+		{
+			name:      "mismatch",
+			checksums: "0000  hydrophone_Linux_x86_64.tar.gz\n",
+			err:       "checksum mismatch",
+		},
+		{
+			name:      "missing",
+			checksums: fmt.Sprintf("%s  hydrophone_Darwin_arm64.tar.gz\n", checksum),
+			err:       "no checksum found",
+		},
+		{
+			name: "empty",
+			err:  "no checksum found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyChecksum("hydrophone_Linux_x86_64.tar.gz", archive, []byte(tt.checksums))
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestExtractBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive string
+		files   map[string]string
+		binary  string
+		err     bool
+	}{
+		{
+			name:    "tar.gz",
+			archive: "hydrophone_Linux_x86_64.tar.gz",
+			files:   map[string]string{"LICENSE": "license", "hydrophone": "binary"},
+			binary:  "binary",
+		},
+		{
+			name:    "tar.gz without binary",
+			archive: "hydrophone_Linux_x86_64.tar.gz",
+			files:   map[string]string{"LICENSE": "license"},
+			err:     true,
+		},
+		{
+			name:    "zip",
+			archive: "hydrophone_Windows_x86_64.zip",
+			files:   map[string]string{"README.md": "readme", "hydrophone.exe": "binary"},
+			binary:  "binary",
+		},
+		{
+			name:    "zip without binary",
+			archive: "hydrophone_Windows_x86_64.zip",
+			files:   map[string]string{"hydrophone": "binary"},
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := tarGz(t, tt.files)
+			if tt.archive == "hydrophone_Windows_x86_64.zip" {
+				archive = zipArchive(t, tt.files)
+			}
+			binary, err := extractBinary(tt.archive, archive)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.binary, string(binary))
+		})
+	}
+
+	_, err := extractBinary("hydrophone_Linux_x86_64.tar.gz", []byte("not an archive"))
+	assert.Error(t, err)
+}
+
+func TestVerifySignatureWithoutCosign(t *testing.T) {
+	defer func(lp func(string) (string, error)) { lookPath = lp }(lookPath)
+	lookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+	rel := &release{TagName: "v0.7.0"}
+
+	tests := []struct {
+		name string
+		opts Options
+		err  bool
+	}{
+		{name: "signature required", err: true},
+		{name: "signature skipped", opts: Options{InsecureSkipSignature: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySignature(rel, "hydrophone_0.7.0_checksums.txt", []byte("checksums"), tt.opts)
+			if tt.err {
+				assert.True(t, errors.Is(err, exec.ErrNotFound))
+				assert.ErrorContains(t, err, "--insecure-skip-signature")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
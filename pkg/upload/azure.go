@@ -0,0 +1,174 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upload
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureAPIVersion is the version of the Blob Storage API of the requests
+const azureAPIVersion = "2021-08-06"
+
+// Azure uploads block blobs to a container of Azure Blob Storage,
+// authenticated with a SAS token or the shared key of the account.
+type Azure struct {
+	// Endpoint is the blob endpoint of the storage account
+	Endpoint  string
+	Account   string
+	Container string
+	// Key is the base64 encoded shared key of the account
+	Key string
+	// SAS is the shared access signature appended to the URL of the blobs
+	SAS    string
+	Client *http.Client
+	now    func() time.Time
+}
+
+// NewAzure returns a backend for the container of the storage account of
+// AZURE_STORAGE_ACCOUNT authenticated with AZURE_STORAGE_SAS_TOKEN or
+// AZURE_STORAGE_KEY, or of the account of AZURE_STORAGE_CONNECTION_STRING.
+func NewAzure(container string) (*Azure, error) {
+	a := &Azure{
+		Account:   os.Getenv("AZURE_STORAGE_ACCOUNT"),
+		Container: container,
+		Key:       os.Getenv("AZURE_STORAGE_KEY"),
+		SAS:       strings.TrimPrefix(os.Getenv("AZURE_STORAGE_SAS_TOKEN"), "?"),
+		Client:    &http.Client{Timeout: 30 * time.Minute},
+		now:       time.Now,
+	}
+	if connectionString := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); connectionString != "" {
+		for _, part := range strings.Split(connectionString, ";") {
+			name, value, _ := strings.Cut(part, "=")
+			switch name {
+			case "AccountName":
+				a.Account = value
+			case "AccountKey":
+				a.Key = value
+			case "BlobEndpoint":
+				a.Endpoint = strings.TrimSuffix(value, "/")
+			case "SharedAccessSignature":
+				a.SAS = value
+			}
+		}
+	}
+	if a.Account == "" {
+		return nil, fmt.Errorf("no Azure storage account, set AZURE_STORAGE_ACCOUNT or AZURE_STORAGE_CONNECTION_STRING")
+	}
+	if a.Key == "" && a.SAS == "" {
+		return nil, fmt.Errorf("no credentials of Azure storage account %s, set AZURE_STORAGE_SAS_TOKEN or AZURE_STORAGE_KEY", a.Account)
+	}
+	if a.Endpoint == "" {
+		a.Endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", a.Account)
+	}
+	return a, nil
+}
+
+// Put uploads the blob with a single Put Blob request
+func (a *Azure) Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error {
+	u := fmt.Sprintf("%s/%s/%s", a.Endpoint, a.Container, escapePath(key))
+	if a.SAS != "" {
+		u += "?" + a.SAS
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, io.NopCloser(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	req.Header.Set("x-ms-date", a.now().UTC().Format(http.TimeFormat))
+	if a.SAS == "" {
+		if err := a.sign(req, size); err != nil {
+			return err
+		}
+	}
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return statusError(resp)
+	}
+	return nil
+}
+
+// sign adds the Shared Key authorization of the account to the request
+func (a *Azure) sign(req *http.Request, size int64) error {
+	key, err := base64.StdEncoding.DecodeString(a.Key)
+	if err != nil {
+		return fmt.Errorf("invalid key of Azure storage account %s: %w", a.Account, err)
+	}
+	contentLength := ""
+	if size > 0 {
+		contentLength = strconv.FormatInt(size, 10)
+	}
+
+	var msHeaders []string
+	for name, values := range req.Header {
+		if name := strings.ToLower(name); strings.HasPrefix(name, "x-ms-") {
+			msHeaders = append(msHeaders, name+":"+strings.TrimSpace(strings.Join(values, ",")))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	resource := "/" + a.Account + req.URL.EscapedPath()
+	query := req.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := query[name]
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(name) + ":" + strings.Join(values, ",")
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, x-ms-date is set instead
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		strings.Join(msHeaders, "\n"),
+		resource,
+	}, "\n")
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", a.Account, base64.StdEncoding.EncodeToString(mac.Sum(nil))))
+	return nil
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upload
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// gcsEndpoint is the JSON API of Google Cloud Storage
+	gcsEndpoint = "https://storage.googleapis.com"
+	// gcsScope is the OAuth scope of the tokens uploading objects
+	gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+	// metadataTokenURL returns the token of the service account of the
+	// instance on Google Cloud
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// GCS uploads objects to a bucket of Google Cloud Storage with the JSON API.
+type GCS struct {
+	Endpoint string
+	Bucket   string
+	Client   *http.Client
+	// Token returns the OAuth access token of the requests
+	Token func(ctx context.Context) (string, error)
+}
+
+// NewGCS returns a backend for the bucket authenticated with the access
+// token of GOOGLE_OAUTH_ACCESS_TOKEN, or with the application default
+// credentials: the file of GOOGLE_APPLICATION_CREDENTIALS or of gcloud auth
+// application-default login, or the service account of the instance.
+func NewGCS(bucket string) *GCS {
+	client := &http.Client{Timeout: 30 * time.Minute}
+	return &GCS{
+		Endpoint: gcsEndpoint,
+		Bucket:   bucket,
+		Client:   client,
+		Token:    (&googleTokenSource{client: client}).token,
+	}
+}
+
+// Put uploads the object with a single media upload
+func (g *GCS) Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error {
+	token, err := g.Token(ctx)
+	if err != nil {
+		return err
+	}
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s", g.Endpoint, url.PathEscape(g.Bucket), url.QueryEscape(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, io.NopCloser(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+	return nil
+}
+
+// googleCredentials is the content of a credentials file of Google Cloud
+type googleCredentials struct {
+	Type string `json:"type"`
+	// service_account
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// authorized_user
+	RefreshToken string `json:"refresh_token"`
+}
+
+// googleTokenSource returns access tokens of the application default
+// credentials, cached until shortly before they expire
+type googleTokenSource struct {
+	client *http.Client
+
+	mu      sync.Mutex
+	current string
+	expiry  time.Time
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (s *googleTokenSource) token(ctx context.Context) (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current != "" && time.Until(s.expiry) > time.Minute {
+		return s.current, nil
+	}
+
+	var resp *tokenResponse
+	credentials, err := readGoogleCredentials()
+	switch {
+	case err != nil:
+		return "", err
+	case credentials == nil:
+		resp, err = s.metadataToken(ctx)
+	case credentials.Type == "service_account":
+		resp, err = s.serviceAccountToken(ctx, credentials, time.Now())
+	case credentials.Type == "authorized_user":
+		resp, err = s.postToken(ctx, "https://oauth2.googleapis.com/token", url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {credentials.ClientID},
+			"client_secret": {credentials.ClientSecret},
+			"refresh_token": {credentials.RefreshToken},
+		})
+	default:
+		return "", fmt.Errorf("unsupported type %q of the Google credentials", credentials.Type)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to get a Google access token: %w", err)
+	}
+	s.current = resp.AccessToken
+	s.expiry = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	return s.current, nil
+}
+
+// readGoogleCredentials reads the file of GOOGLE_APPLICATION_CREDENTIALS, or
+// the one written by gcloud auth application-default login. It returns nil
+// when there is none.
+func readGoogleCredentials() (*googleCredentials, error) {
+	file := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	explicit := file != ""
+	if !explicit {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, nil
+		}
+		file = filepath.Join(configDir, "gcloud", "application_default_credentials.json")
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	credentials := &googleCredentials{}
+	if err := json.Unmarshal(data, credentials); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", file, err)
+	}
+	return credentials, nil
+}
+
+// serviceAccountToken exchanges a JWT signed with the key of the service
+// account for an access token
+func (s *googleTokenSource) serviceAccountToken(ctx context.Context, credentials *googleCredentials, now time.Time) (*tokenResponse, error) {
+	tokenURI := credentials.TokenURI
+	if tokenURI == "" {
+		tokenURI = "https://oauth2.googleapis.com/token"
+	}
+	assertion, err := signJWT(credentials.PrivateKey, map[string]any{
+		"iss":   credentials.ClientEmail,
+		"scope": gcsScope,
+		"aud":   tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.postToken(ctx, tokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+}
+
+// signJWT returns the claims as a JWT signed with RS256 and the PEM encoded
+// private key
+func signJWT(privateKey string, claims map[string]any) (string, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return "", errors.New("invalid private key of the service account")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return "", fmt.Errorf("invalid private key of the service account: %w", err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("the private key of the service account isn't an RSA key")
+	}
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// postToken requests an access token from the OAuth token endpoint
+func (s *googleTokenSource) postToken(ctx context.Context, tokenURI string, form url.Values) (*tokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return s.doToken(req)
+}
+
+// metadataToken requests the access token of the service account of the
+// instance from the metadata server
+func (s *googleTokenSource) metadataToken(ctx context.Context) (*tokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataTokenURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	return s.doToken(req)
+}
+
+func (s *googleTokenSource) doToken(req *http.Request) (*tokenResponse, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	token := &tokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
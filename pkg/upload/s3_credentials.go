@@ -0,0 +1,463 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upload
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// containerCredentialsHost serves the credentials of the task role of
+	// Amazon ECS to AWS_CONTAINER_CREDENTIALS_RELATIVE_URI
+	containerCredentialsHost = "http://169.254.170.2"
+	// instanceMetadataEndpoint is the instance metadata service of Amazon EC2
+	instanceMetadataEndpoint = "http://169.254.169.254"
+	// awsCredentialsRefresh is how long before they expire the credentials
+	// are looked up again
+	awsCredentialsRefresh = 5 * time.Minute
+)
+
+// AWSCredentials are the credentials requests to S3 are signed with.
+// Temporary credentials expire at Expiration, static ones have none.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// awsCredentialSource returns the credentials of the first provider of the
+// default chain of the AWS SDKs that has some, cached until shortly before
+// they expire:
+//
+//   - AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+//   - the profile AWS_PROFILE of the shared credentials and config files,
+//     with static keys or a credential_process
+//   - the web identity token of AWS_WEB_IDENTITY_TOKEN_FILE exchanged for the
+//     role AWS_ROLE_ARN, e.g. IAM roles for service accounts of Amazon EKS
+//   - the container credentials of AWS_CONTAINER_CREDENTIALS_RELATIVE_URI or
+//     AWS_CONTAINER_CREDENTIALS_FULL_URI, e.g. Amazon ECS task roles and EKS
+//     Pod Identity
+//   - the role of the instance from the instance metadata service of EC2,
+//     unless AWS_EC2_METADATA_DISABLED is true
+type awsCredentialSource struct {
+	client *http.Client
+	// region selects the regional endpoint of STS
+	region string
+
+	mu      sync.Mutex
+	current AWSCredentials
+}
+
+// awsCredentialsResponse holds the credentials returned by the container and
+// the instance metadata endpoints and by a credential_process
+type awsCredentialsResponse struct {
+	Version         int       `json:"Version"`
+	Code            string    `json:"Code"`
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	SessionToken    string    `json:"SessionToken"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+func (r *awsCredentialsResponse) credentials() (AWSCredentials, error) {
+	if r.AccessKeyID == "" || r.SecretAccessKey == "" {
+		return AWSCredentials{}, errors.New("no access key in the response")
+	}
+	token := r.Token
+	if token == "" {
+		token = r.SessionToken
+	}
+	return AWSCredentials{
+		AccessKeyID:     r.AccessKeyID,
+		SecretAccessKey: r.SecretAccessKey,
+		SessionToken:    token,
+		Expiration:      r.Expiration,
+	}, nil
+}
+
+func (s *awsCredentialSource) credentials(ctx context.Context) (AWSCredentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current.AccessKeyID != "" && (s.current.Expiration.IsZero() || time.Until(s.current.Expiration) > awsCredentialsRefresh) {
+		return s.current, nil
+	}
+	credentials, err := s.lookup(ctx)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	s.current = credentials
+	return credentials, nil
+}
+
+// lookup returns the credentials of the first provider that has some
+func (s *awsCredentialSource) lookup(ctx context.Context) (AWSCredentials, error) {
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return AWSCredentials{
+			AccessKeyID:     id,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+	credentials, found, err := s.profileCredentials(ctx)
+	if err != nil || found {
+		return credentials, err
+	}
+	if tokenFile, role := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"), os.Getenv("AWS_ROLE_ARN"); tokenFile != "" && role != "" {
+		credentials, err := s.webIdentityCredentials(ctx, tokenFile, role)
+		if err != nil {
+			return AWSCredentials{}, fmt.Errorf("unable to assume role %s with the web identity token of %s: %w", role, tokenFile, err)
+		}
+		return credentials, nil
+	}
+	if uri := containerCredentialsURI(); uri != "" {
+		credentials, err := s.containerCredentials(ctx, uri)
+		if err != nil {
+			return AWSCredentials{}, fmt.Errorf("unable to get the AWS credentials of the container from %s: %w", uri, err)
+		}
+		return credentials, nil
+	}
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return AWSCredentials{}, errors.New("no AWS credentials in the environment, the shared files, a web identity or the container credentials")
+	}
+	credentials, err = s.instanceCredentials(ctx)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("no AWS credentials in the environment, the shared files, a web identity or the container credentials, and unable to get the ones of the instance: %w", err)
+	}
+	return credentials, nil
+}
+
+// profileCredentials returns the credentials of the profile of the shared
+// credentials file or of the shared config file, the credentials file taking
+// precedence. The profile is AWS_PROFILE or default. The files are the ones of
+// AWS_SHARED_CREDENTIALS_FILE and AWS_CONFIG_FILE, or credentials and config
+// of ~/.aws, which may be missing.
+func (s *awsCredentialSource) profileCredentials(ctx context.Context) (AWSCredentials, bool, error) {
+	profile := os.Getenv("AWS_PROFILE")
+	explicit := profile != ""
+	if !explicit {
+		profile = "default"
+	}
+	// the sections of the config file are prefixed with profile but the
+	// default one
+	configSection := "profile " + profile
+	if profile == "default" {
+		configSection = "default"
+	}
+	values := map[string]string{}
+	for _, file := range []struct {
+		env, name, section string
+	}{
+		{env: "AWS_CONFIG_FILE", name: "config", section: configSection},
+		{env: "AWS_SHARED_CREDENTIALS_FILE", name: "credentials", section: profile},
+	} {
+		path := os.Getenv(file.env)
+		explicitPath := path != ""
+		if !explicitPath {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				continue
+			}
+			path = filepath.Join(home, ".aws", file.name)
+		}
+		section, err := readINISection(path, file.section)
+		if errors.Is(err, os.ErrNotExist) && !explicitPath {
+			continue
+		} else if err != nil {
+			return AWSCredentials{}, false, fmt.Errorf("unable to read the AWS %s file %s: %w", file.name, path, err)
+		}
+		for key, value := range section {
+			values[key] = value
+		}
+	}
+
+	switch {
+	case values["aws_access_key_id"] != "":
+		return AWSCredentials{
+			AccessKeyID:     values["aws_access_key_id"],
+			SecretAccessKey: values["aws_secret_access_key"],
+			SessionToken:    values["aws_session_token"],
+		}, true, nil
+	case values["credential_process"] != "":
+		credentials, err := processCredentials(ctx, values["credential_process"])
+		if err != nil {
+			return AWSCredentials{}, false, fmt.Errorf("unable to get the AWS credentials of profile %s from its credential_process: %w", profile, err)
+		}
+		return credentials, true, nil
+	case explicit:
+		return AWSCredentials{}, false, fmt.Errorf("no AWS credentials in profile %s of the shared credentials and config files", profile)
+	}
+	return AWSCredentials{}, false, nil
+}
+
+// processCredentials runs the command of a credential_process with the shell
+// and reads the credentials it prints
+func processCredentials(ctx context.Context, command string) (AWSCredentials, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd.exe", "/C", command)
+	}
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	response := &awsCredentialsResponse{}
+	if err := json.Unmarshal(output, response); err != nil {
+		return AWSCredentials{}, fmt.Errorf("unable to parse its output: %w", err)
+	}
+	if response.Version != 1 {
+		return AWSCredentials{}, fmt.Errorf("unsupported version %d of its output, expected 1", response.Version)
+	}
+	return response.credentials()
+}
+
+// assumeRoleWithWebIdentityResponse is the response of the
+// AssumeRoleWithWebIdentity action of STS
+type assumeRoleWithWebIdentityResponse struct {
+	Credentials struct {
+		AccessKeyID     string    `xml:"AccessKeyId"`
+		SecretAccessKey string    `xml:"SecretAccessKey"`
+		SessionToken    string    `xml:"SessionToken"`
+		Expiration      time.Time `xml:"Expiration"`
+	} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+}
+
+// webIdentityCredentials exchanges the token of the file, read again each
+// time as it is rotated, for the temporary credentials of the role. The
+// endpoint of STS is the one of the region, or AWS_ENDPOINT_URL_STS.
+func (s *awsCredentialSource) webIdentityCredentials(ctx context.Context, tokenFile, role string) (AWSCredentials, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	sessionName := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("hydrophone-%d", time.Now().UnixNano())
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_STS")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com", s.region)
+	}
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {role},
+		"RoleSessionName":  {sessionName},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return AWSCredentials{}, statusError(resp)
+	}
+	response := &assumeRoleWithWebIdentityResponse{}
+	if err := xml.NewDecoder(resp.Body).Decode(response); err != nil {
+		return AWSCredentials{}, fmt.Errorf("unable to parse the response of STS: %w", err)
+	}
+	if response.Credentials.AccessKeyID == "" {
+		return AWSCredentials{}, errors.New("no credentials in the response of STS")
+	}
+	return AWSCredentials{
+		AccessKeyID:     response.Credentials.AccessKeyID,
+		SecretAccessKey: response.Credentials.SecretAccessKey,
+		SessionToken:    response.Credentials.SessionToken,
+		Expiration:      response.Credentials.Expiration,
+	}, nil
+}
+
+// containerCredentialsURI returns the URL of the container credentials,
+// AWS_CONTAINER_CREDENTIALS_RELATIVE_URI taking precedence
+func containerCredentialsURI() string {
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		return containerCredentialsHost + uri
+	}
+	return os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+}
+
+// containerCredentials requests the credentials from the endpoint of the
+// container, authorized with the token of
+// AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE or AWS_CONTAINER_AUTHORIZATION_TOKEN.
+// Like the SDKs, only the endpoints of ECS and EKS or of the loopback
+// interface are requested over plain HTTP.
+func (s *awsCredentialSource) containerCredentials(ctx context.Context, uri string) (AWSCredentials, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	if u.Scheme != "https" && !allowedContainerHost(u.Hostname()) {
+		return AWSCredentials{}, fmt.Errorf("plain HTTP is only allowed for the loopback interface and the endpoints of ECS and EKS, got host %s", u.Hostname())
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if tokenFile := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return AWSCredentials{}, err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return s.doCredentials(req)
+}
+
+// allowedContainerHost tells whether the container credentials may be
+// requested from the host over plain HTTP
+func allowedContainerHost(host string) bool {
+	switch host {
+	case "169.254.170.2", "169.254.170.23", "fd00:ec2::23", "localhost":
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// instanceCredentials requests the credentials of the role of the instance
+// from the instance metadata service, with a session token of IMDSv2. The
+// service is AWS_EC2_METADATA_SERVICE_ENDPOINT, when set.
+func (s *awsCredentialSource) instanceCredentials(ctx context.Context) (AWSCredentials, error) {
+	// the service only answers on EC2, elsewhere the lookup gives up soon
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	endpoint := os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT")
+	if endpoint == "" {
+		endpoint = instanceMetadataEndpoint
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	token, err := s.doMetadata(req)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("unable to get a session token of the instance metadata service: %w", err)
+	}
+
+	credentialsURL := endpoint + "/latest/meta-data/iam/security-credentials/"
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, credentialsURL, nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	roles, err := s.doMetadata(req)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("unable to get the role of the instance: %w", err)
+	}
+	role, _, _ := strings.Cut(strings.TrimSpace(roles), "\n")
+	if role == "" {
+		return AWSCredentials{}, errors.New("the instance has no role")
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, credentialsURL+url.PathEscape(role), nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	return s.doCredentials(req)
+}
+
+// doMetadata returns the body of a request to the instance metadata service
+func (s *awsCredentialSource) doMetadata(req *http.Request) (string, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError(resp)
+	}
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+// doCredentials returns the credentials of the response of a container or
+// instance metadata endpoint
+func (s *awsCredentialSource) doCredentials(req *http.Request) (AWSCredentials, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return AWSCredentials{}, statusError(resp)
+	}
+	response := &awsCredentialsResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return AWSCredentials{}, err
+	}
+	if response.Code != "" && response.Code != "Success" {
+		return AWSCredentials{}, fmt.Errorf("unexpected code %s of the credentials", response.Code)
+	}
+	return response.credentials()
+}
+
+// readINISection returns the keys of the section of an ini file
+func readINISection(file, section string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	values := map[string]string{}
+	current := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			current = strings.TrimSpace(line[1 : len(line)-1])
+		case current == section:
+			if key, value, ok := strings.Cut(line, "="); ok {
+				values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+	}
+	return values, scanner.Err()
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upload
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// awsEnv clears the environment variables of the AWS credentials, the home
+// directory holding no shared files, and sets the given ones
+func awsEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, name := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE",
+		"AWS_SHARED_CREDENTIALS_FILE", "AWS_CONFIG_FILE", "AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN",
+		"AWS_ROLE_SESSION_NAME", "AWS_ENDPOINT_URL_STS", "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI",
+		"AWS_CONTAINER_CREDENTIALS_FULL_URI", "AWS_CONTAINER_AUTHORIZATION_TOKEN",
+		"AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE", "AWS_EC2_METADATA_SERVICE_ENDPOINT",
+	} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+}
+
+// credentialsJSON returns the credentials returned by the container and the
+// instance metadata endpoints
+func credentialsJSON(id string, expiration time.Time) string {
+	return fmt.Sprintf(`{"Code": "Success", "AccessKeyId": %q, "SecretAccessKey": "secret", "Token": "session", "Expiration": %q}`,
+		id, expiration.UTC().Format(time.RFC3339))
+}
+
+func TestAWSCredentialsEnvironment(t *testing.T) {
+	awsEnv(t, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "id",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_SESSION_TOKEN":     "session",
+	})
+	credentials, err := (&awsCredentialSource{client: http.DefaultClient}).credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, AWSCredentials{AccessKeyID: "id", SecretAccessKey: "secret", SessionToken: "session"}, credentials)
+}
+
+func TestAWSCredentialsProfile(t *testing.T) {
+	dir := t.TempDir()
+	credentialsFile := filepath.Join(dir, "credentials")
+	require.NoError(t, os.WriteFile(credentialsFile, []byte(`[default]
+aws_access_key_id = default-id
+aws_secret_access_key = default-secret
+
+[ci]
+aws_access_key_id = ci-id
+aws_secret_access_key = ci-secret
+aws_session_token = ci-session
+`), 0600))
+
+	tests := []struct {
+		name        string
+		profile     string
+		credentials AWSCredentials
+		err         string
+	}{
+		{
+			name:        "default",
+			credentials: AWSCredentials{AccessKeyID: "default-id", SecretAccessKey: "default-secret"},
+		},
+		{
+			name:        "profile",
+			profile:     "ci",
+			credentials: AWSCredentials{AccessKeyID: "ci-id", SecretAccessKey: "ci-secret", SessionToken: "ci-session"},
+		},
+		{
+			name:    "missing profile",
+			profile: "prod",
+			err:     "no AWS credentials in profile prod",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			awsEnv(t, map[string]string{"AWS_SHARED_CREDENTIALS_FILE": credentialsFile, "AWS_PROFILE": tt.profile})
+			credentials, err := (&awsCredentialSource{client: http.DefaultClient}).credentials(context.Background())
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.credentials, credentials)
+		})
+	}
+}
+
+func TestAWSCredentialsProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the credential_process is a shell command")
+	}
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(configFile, []byte(`[profile sso]
+credential_process = echo '{"Version": 1, "AccessKeyId": "process-id", "SecretAccessKey": "process-secret", "SessionToken": "process-session", "Expiration": "2030-01-01T00:00:00Z"}'
+
+[profile broken]
+credential_process = exit 1
+`), 0600))
+
+	awsEnv(t, map[string]string{"AWS_CONFIG_FILE": configFile, "AWS_PROFILE": "sso"})
+	credentials, err := (&awsCredentialSource{client: http.DefaultClient}).credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, AWSCredentials{
+		AccessKeyID:     "process-id",
+		SecretAccessKey: "process-secret",
+		SessionToken:    "process-session",
+		Expiration:      time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, credentials)
+
+	awsEnv(t, map[string]string{"AWS_CONFIG_FILE": configFile, "AWS_PROFILE": "broken"})
+	_, err = (&awsCredentialSource{client: http.DefaultClient}).credentials(context.Background())
+	assert.ErrorContains(t, err, "unable to get the AWS credentials of profile broken from its credential_process")
+}
+
+func TestAWSCredentialsWebIdentity(t *testing.T) {
+	var form map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = map[string]string{}
+		for name := range r.PostForm {
+			form[name] = r.PostForm.Get(name)
+		}
+		fmt.Fprint(w, `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ASIAEXAMPLE</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>session</SessionToken>
+      <Expiration>2030-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`)
+	}))
+	defer server.Close()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("eyJhbGciOiJSUzI1NiJ9.e30.c2ln\n"), 0600))
+
+	awsEnv(t, map[string]string{
+		"AWS_WEB_IDENTITY_TOKEN_FILE": tokenFile,
+		"AWS_ROLE_ARN":                "arn:aws:iam::123456789012:role/conformance",
+		"AWS_ROLE_SESSION_NAME":       "ci",
+		"AWS_ENDPOINT_URL_STS":        server.URL,
+	})
+	credentials, err := (&awsCredentialSource{client: server.Client()}).credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, AWSCredentials{
+		AccessKeyID:     "ASIAEXAMPLE",
+		SecretAccessKey: "secret",
+		SessionToken:    "session",
+		Expiration:      time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, credentials)
+	assert.Equal(t, map[string]string{
+		"Action":           "AssumeRoleWithWebIdentity",
+		"Version":          "2011-06-15",
+		"RoleArn":          "arn:aws:iam::123456789012:role/conformance",
+		"RoleSessionName":  "ci",
+		"WebIdentityToken": "eyJhbGciOiJSUzI1NiJ9.e30.c2ln",
+	}, form)
+}
+
+func TestAWSCredentialsContainer(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if r.URL.Path != "/v2/credentials" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, credentialsJSON("container-id", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+	}))
+	defer server.Close()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("pod-identity-token\n"), 0600))
+
+	awsEnv(t, map[string]string{
+		"AWS_CONTAINER_CREDENTIALS_FULL_URI":     server.URL + "/v2/credentials",
+		"AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE": tokenFile,
+	})
+	credentials, err := (&awsCredentialSource{client: server.Client()}).credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, AWSCredentials{
+		AccessKeyID:     "container-id",
+		SecretAccessKey: "secret",
+		SessionToken:    "session",
+		Expiration:      time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, credentials)
+	assert.Equal(t, "pod-identity-token", auth)
+
+	// plain HTTP is refused for other hosts than the ones of ECS and EKS
+	awsEnv(t, map[string]string{"AWS_CONTAINER_CREDENTIALS_FULL_URI": "http://credentials.example.com/v2/credentials"})
+	_, err = (&awsCredentialSource{client: server.Client()}).credentials(context.Background())
+	assert.ErrorContains(t, err, "plain HTTP is only allowed")
+}
+
+func TestAWSCredentialsInstance(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/latest/api/token" {
+			if r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, "imds-token")
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "imds-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			fmt.Fprint(w, "conformance-node")
+		case "/latest/meta-data/iam/security-credentials/conformance-node":
+			requests++
+			// the first credentials are about to expire
+			expiration := time.Now().Add(time.Minute)
+			if requests > 1 {
+				expiration = time.Now().Add(time.Hour)
+			}
+			fmt.Fprint(w, credentialsJSON(fmt.Sprintf("instance-id-%d", requests), expiration))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	awsEnv(t, map[string]string{
+		"AWS_EC2_METADATA_DISABLED":         "",
+		"AWS_EC2_METADATA_SERVICE_ENDPOINT": server.URL,
+	})
+	source := &awsCredentialSource{client: server.Client()}
+	credentials, err := source.credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "instance-id-1", credentials.AccessKeyID)
+	assert.Equal(t, "session", credentials.SessionToken)
+
+	// the credentials about to expire are requested again, the new ones are
+	// cached
+	for i := 0; i < 2; i++ {
+		credentials, err = source.credentials(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "instance-id-2", credentials.AccessKeyID)
+	}
+	assert.Equal(t, 2, requests)
+}
+
+func TestAWSCredentialsNone(t *testing.T) {
+	awsEnv(t, nil)
+	_, err := (&awsCredentialSource{client: http.DefaultClient}).credentials(context.Background())
+	assert.EqualError(t, err, "no AWS credentials in the environment, the shared files, a web identity or the container credentials")
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package upload pushes the artifacts of runs to remote storage: Amazon S3,
+// Google Cloud Storage and Azure Blob Storage. The credentials are read from
+// the environment the way the SDKs of the providers do.
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Schemes of the URLs of --upload
+const (
+	SchemeS3    = "s3"
+	SchemeGCS   = "gs"
+	SchemeAzure = "azblob"
+)
+
+// Backend stores objects in a bucket of remote storage
+type Backend interface {
+	// Put stores the body of the given size under the key
+	Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error
+}
+
+// StatusError is returned by backends for unexpected responses of the storage
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.Code, e.Body)
+}
+
+// retryable reports whether the upload failing with the error may succeed
+// when retried. Requests rejected by the storage, e.g. for lack of
+// permissions, are not.
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code == http.StatusTooManyRequests || statusErr.Code >= 500
+	}
+	return true
+}
+
+// statusError reads the body of a failed response into a StatusError
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return &StatusError{Code: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+}
+
+// Target is the location the artifacts are uploaded to, e.g.
+// s3://bucket/prefix
+type Target struct {
+	Scheme string
+	// Bucket is the bucket, or the container of Azure Blob Storage
+	Bucket string
+	Prefix string
+}
+
+// ParseTarget parses a URL of --upload
+func ParseTarget(s string) (Target, error) {
+	scheme, rest, ok := strings.Cut(s, "://")
+	if !ok {
+		return Target{}, fmt.Errorf("invalid upload URL %q: expected %s://, %s:// or %s://bucket/prefix", s, SchemeS3, SchemeGCS, SchemeAzure)
+	}
+	switch scheme {
+	case SchemeS3, SchemeGCS, SchemeAzure:
+	default:
+		return Target{}, fmt.Errorf("invalid upload URL %q: unsupported scheme %s, expected %s, %s or %s", s, scheme, SchemeS3, SchemeGCS, SchemeAzure)
+	}
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return Target{}, fmt.Errorf("invalid upload URL %q: missing bucket", s)
+	}
+	return Target{Scheme: scheme, Bucket: bucket, Prefix: strings.Trim(prefix, "/")}, nil
+}
+
+func (t Target) String() string {
+	return fmt.Sprintf("%s://%s/%s", t.Scheme, t.Bucket, t.Prefix)
+}
+
+// Key returns the key of the file under the prefix of the target
+func (t Target) Key(name string) string {
+	return path.Join(t.Prefix, name)
+}
+
+// NewBackend returns the backend of the target, authenticated with the
+// credentials of the environment
+func NewBackend(target Target) (Backend, error) {
+	switch target.Scheme {
+	case SchemeS3:
+		return NewS3(target.Bucket)
+	case SchemeGCS:
+		return NewGCS(target.Bucket), nil
+	case SchemeAzure:
+		return NewAzure(target.Bucket)
+	}
+	return nil, fmt.Errorf("unsupported scheme %s", target.Scheme)
+}
+
+// Uploader uploads files to a target, retrying failed uploads with an
+// exponential backoff
+type Uploader struct {
+	Backend Backend
+	Target  Target
+	// Retries is the number of times a failed upload is retried
+	Retries int
+	// Backoff is the delay before the first retry, doubled for every
+	// following one
+	Backoff time.Duration
+}
+
+// Upload uploads the named files and directories of dir to the prefix of the
+// target, keeping their path relative to dir. It returns the keys of the
+// uploaded files.
+func (u *Uploader) Upload(ctx context.Context, dir string, names []string) ([]string, error) {
+	var keys []string
+	for _, name := range names {
+		err := filepath.WalkDir(filepath.Join(dir, name), func(file string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, file)
+			if err != nil {
+				return err
+			}
+			key := u.Target.Key(filepath.ToSlash(rel))
+			if err := u.put(ctx, key, file); err != nil {
+				return fmt.Errorf("unable to upload %s to %s: %w", rel, u.Target, err)
+			}
+			keys = append(keys, key)
+			return nil
+		})
+		if err != nil {
+			return keys, err
+		}
+	}
+	return keys, nil
+}
+
+// put uploads the file, retrying on failures
+func (u *Uploader) put(ctx context.Context, key, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	delay := u.Backoff
+	for attempt := 0; ; attempt++ {
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		err = u.Backend.Put(ctx, key, f, info.Size())
+		if err == nil || attempt >= u.Retries || !retryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wizard
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/hydrophone/pkg/common"
+	"sigs.k8s.io/hydrophone/pkg/service"
+)
+
+// DefaultOutputDir is the output directory proposed by the wizard
+const DefaultOutputDir = "results"
+
+var versionRegexp = regexp.MustCompile(`^v\d+\.\d+\.\d+`)
+
+// Wizard asks the questions of config init on its input and collects the
+// answers as the keys of hydrophone.yaml.
+type Wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// Options are the choices the wizard offers.
+type Options struct {
+	// Contexts are the contexts of the kubeconfig
+	Contexts []string
+	// CurrentContext is the current context of the kubeconfig, proposed by default
+	CurrentContext string
+}
+
+// New returns a wizard reading the answers from in and writing the questions to out.
+func New(in io.Reader, out io.Writer) *Wizard {
+	return &Wizard{in: bufio.NewReader(in), out: out}
+}
+
+// Run asks about the cluster context, the output directory, the tests to run
+// and the mirror registry of an air-gapped cluster, and returns the keys of
+// hydrophone.yaml. Invalid answers are asked again.
+func (w *Wizard) Run(opts Options) (map[string]any, error) {
+	config := map[string]any{}
+
+	if len(opts.Contexts) > 0 {
+		context, err := w.choose("Context of the cluster to test", opts.Contexts, opts.CurrentContext)
+		if err != nil {
+			return nil, err
+		}
+		config["context"] = context
+	}
+
+	outputDir, err := w.ask("Output directory of the results", DefaultOutputDir, nil)
+	if err != nil {
+		return nil, err
+	}
+	config["output-dir"] = outputDir
+
+	tests, err := w.choose("Tests to run", []string{"conformance", "focus"}, "conformance")
+	if err != nil {
+		return nil, err
+	}
+	if tests == "conformance" {
+		config["conformance"] = true
+	} else {
+		focus, err := w.ask("Regular expression of the tests to run", "", func(answer string) error {
+			if err := required(answer); err != nil {
+				return err
+			}
+			return validateRegexp(answer)
+		})
+		if err != nil {
+			return nil, err
+		}
+		config["focus"] = focus
+	}
+	skip, err := w.ask("Regular expression of the tests to skip, empty for none", "", validateRegexp)
+	if err != nil {
+		return nil, err
+	}
+	if skip != "" {
+		config["skip"] = skip
+	}
+
+	airGapped, err := w.confirm("Is the cluster air-gapped, pulling the images from a mirror registry?", false)
+	if err != nil {
+		return nil, err
+	}
+	if !airGapped {
+		return config, nil
+	}
+	registry, err := w.ask("Mirror registry, e.g. mirror.example.com", "", required)
+	if err != nil {
+		return nil, err
+	}
+	version, err := w.ask("Kubernetes version of the cluster, e.g. v1.29.0", "", validateVersion)
+	if err != nil {
+		return nil, err
+	}
+	config["conformance-image"] = service.MirrorImage("registry.k8s.io/conformance:"+version, registry)
+	config["busybox-image"] = service.MirrorImage(common.DefaultBusyboxImage, registry)
+	config["test-repo"] = service.MirrorImage("registry.k8s.io/e2e-test-images", registry)
+	dockerConfig, err := w.ask("Docker config file with the credentials of the mirror, empty for none", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if dockerConfig != "" {
+		config["docker-config"] = dockerConfig
+	}
+	return config, nil
+}
+
+// ask asks a question until the answer is valid. An empty answer is the
+// default.
+func (w *Wizard) ask(question, def string, validate func(string) error) (string, error) {
+	prompt := question
+	if def != "" {
+		prompt = fmt.Sprintf("%s [%s]", question, def)
+	}
+	for {
+		fmt.Fprintf(w.out, "%s: ", prompt)
+		line, err := w.in.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			if errors.Is(err, io.EOF) {
+				return "", errors.New("input ended before the configuration was complete")
+			}
+			return "", err
+		}
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = def
+		}
+		if validate == nil {
+			return answer, nil
+		}
+		if err := validate(answer); err != nil {
+			fmt.Fprintf(w.out, "%v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// choose asks to pick one of the options, by number or by name.
+func (w *Wizard) choose(question string, options []string, def string) (string, error) {
+	fmt.Fprintf(w.out, "%s:\n", question)
+	for i, option := range options {
+		fmt.Fprintf(w.out, "  %d) %s\n", i+1, option)
+	}
+	var choice string
+	_, err := w.ask("Choice", def, func(answer string) error {
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(options) {
+			choice = options[i-1]
+			return nil
+		}
+		if slices.Contains(options, answer) {
+			choice = answer
+			return nil
+		}
+		return fmt.Errorf("choose a number between 1 and %d", len(options))
+	})
+	return choice, err
+}
+
+// confirm asks a yes or no question.
+func (w *Wizard) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	var yes bool
+	_, err := w.ask(fmt.Sprintf("%s (%s)", question, hint), "", func(answer string) error {
+		switch strings.ToLower(answer) {
+		case "":
+			yes = def
+		case "y", "yes":
+			yes = true
+		case "n", "no":
+			yes = false
+		default:
+			return errors.New("answer yes or no")
+		}
+		return nil
+	})
+	return yes, err
+}
+
+func required(answer string) error {
+	if answer == "" {
+		return errors.New("an answer is required")
+	}
+	return nil
+}
+
+func validateRegexp(answer string) error {
+	if _, err := regexp.Compile(answer); err != nil {
+		return fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return nil
+}
+
+func validateVersion(answer string) error {
+	if !versionRegexp.MatchString(answer) {
+		return errors.New("the version must look like v1.29.0")
+	}
+	return nil
+}
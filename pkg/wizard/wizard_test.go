@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wizard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		input   string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			opts:  Options{Contexts: []string{"dev", "prod"}, CurrentContext: "dev"},
+			input: "\n\n\n\n\n",
+			want: map[string]any{
+				"context":     "dev",
+				"output-dir":  "results",
+				"conformance": true,
+			},
+		},
+		{
+			name:  "focus and skip without kubeconfig",
+			input: "out\n2\n\n[sig-storage\n\\[sig-storage\\]\nSlow\nno\n",
+			want: map[string]any{
+				"output-dir": "out",
+				"focus":      `\[sig-storage\]`,
+				"skip":       "Slow",
+			},
+		},
+		{
+			name:  "air-gapped",
+			opts:  Options{Contexts: []string{"dev", "prod"}, CurrentContext: "dev"},
+			input: "3\nprod\n\n1\n\nmaybe\ny\nmirror.example.com\n1.29\nv1.29.0\n~/.docker/config.json\n",
+			want: map[string]any{
+				"context":           "prod",
+				"output-dir":        "results",
+				"conformance":       true,
+				"conformance-image": "mirror.example.com/conformance:v1.29.0",
+				"busybox-image":     "mirror.example.com/e2e-test-images/busybox:1.36.1-1",
+				"test-repo":         "mirror.example.com/e2e-test-images",
+				"docker-config":     "~/.docker/config.json",
+			},
+		},
+		{
+			name:    "input ends",
+			input:   "\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := New(strings.NewReader(tt.input), &out).Run(tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}